@@ -0,0 +1,45 @@
+// Command btcatomicswapenv runs the btcatomicswap tool of decred/atomicswap, passing it the RPC password
+// of the bitcoind node read from the BTCATOMICSWAP_RPCPASS environment variable, as the tool itself
+// only accepts the password as its --rpcpass argument. It is the tool used by default by goldchainc
+// for BTC atomic swaps, such that the password is never visible in the arguments given by goldchainc.
+//
+// All arguments are passed as-is to the btcatomicswap tool, which is looked up in the PATH,
+// unless another one is defined using the BTCATOMICSWAP_BIN environment variable.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/nbh-digital/goldchain/pkg/atomicswap"
+)
+
+func main() {
+	binary := os.Getenv(atomicswap.BTCToolEnv)
+	if binary == "" {
+		binary = "btcatomicswap"
+	}
+	var env []string
+	args := os.Args[1:]
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, atomicswap.BTCRPCPasswordEnv+"=") {
+			// the flags of the tool have to precede its command
+			args = append([]string{"--rpcpass=" + strings.TrimPrefix(kv, atomicswap.BTCRPCPasswordEnv+"=")}, args...)
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	cmd := exec.Command(binary, args...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "failed to run %s: %v\n", binary, err)
+		os.Exit(1)
+	}
+}
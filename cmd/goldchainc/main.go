@@ -9,7 +9,10 @@ import (
 
 	"github.com/nbh-digital/goldchain/pkg/config"
 
+	aecli "github.com/nbh-digital/goldchain/extensions/authexpiry/client"
 	cfcli "github.com/nbh-digital/goldchain/extensions/custodyfees/client"
+	porcli "github.com/nbh-digital/goldchain/extensions/proofofreserve/client"
+	rdcli "github.com/nbh-digital/goldchain/extensions/redemption/client"
 	gccli "github.com/nbh-digital/goldchain/pkg/client"
 	"github.com/nbh-digital/goldchain/pkg/types"
 	authcointxcli "github.com/threefoldtech/rivine/extensions/authcointx/client"
@@ -30,6 +33,12 @@ func main() {
 	exitIfError(err)
 	err = cfcli.CreateExplorerSubCmds(cliClient.CommandLineClient)
 	exitIfError(err)
+	err = porcli.CreateExplorerSubCmds(cliClient.CommandLineClient)
+	exitIfError(err)
+	err = rdcli.CreateExplorerSubCmds(cliClient.CommandLineClient)
+	exitIfError(err)
+	err = aecli.CreateExplorerSubCmds(cliClient.CommandLineClient)
+	exitIfError(err)
 	err = mintingcli.CreateConsensusCmd(cliClient.CommandLineClient)
 	exitIfError(err)
 	err = cfcli.CreateConsensusSubCmds(cliClient.CommandLineClient)
@@ -38,6 +47,8 @@ func main() {
 	// add cli wallet extension commands
 	err = gccli.CreateMintingWalletCmds(cliClient.CommandLineClient)
 	exitIfError(err)
+	err = gccli.CreateAuthCoinWalletCmds(cliClient.CommandLineClient)
+	exitIfError(err)
 
 	err = authcointxcli.CreateConsensusAuthCoinInfoCmd(cliClient.CommandLineClient)
 	exitIfError(err)
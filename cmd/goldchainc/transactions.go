@@ -54,4 +54,5 @@ func registerTransactions(bc client.BaseClient) {
 func registerConditionTypes(bc client.BaseClient) {
 	types.RegisterUnlockConditionType(cftypes.ConditionTypeCustodyFee,
 		func() types.MarshalableUnlockCondition { return &cftypes.CustodyFeeCondition{} })
+	goldchaintypes.RegisterMultiSignatureAtomicSwapTypes()
 }
@@ -21,10 +21,15 @@ import (
 	"github.com/threefoldtech/rivine/extensions/authcointx"
 	authcointxapi "github.com/threefoldtech/rivine/extensions/authcointx/api"
 
+	aeplugin "github.com/nbh-digital/goldchain/extensions/authexpiry"
+	aeapi "github.com/nbh-digital/goldchain/extensions/authexpiry/api"
 	cfplugin "github.com/nbh-digital/goldchain/extensions/custodyfees"
 	cfapi "github.com/nbh-digital/goldchain/extensions/custodyfees/api"
 	cfexplorer "github.com/nbh-digital/goldchain/extensions/custodyfees/modules/explorer"
-	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	porplugin "github.com/nbh-digital/goldchain/extensions/proofofreserve"
+	porapi "github.com/nbh-digital/goldchain/extensions/proofofreserve/api"
+	rdplugin "github.com/nbh-digital/goldchain/extensions/redemption"
+	rdapi "github.com/nbh-digital/goldchain/extensions/redemption/api"
 	goldchainmodules "github.com/nbh-digital/goldchain/modules"
 	"github.com/nbh-digital/goldchain/modules/wallet"
 	goldchainapi "github.com/nbh-digital/goldchain/pkg/api"
@@ -96,6 +101,10 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 			return
 		}
 
+		// register the multisig atomic swap condition and fulfillment types,
+		// which are only accepted by the consensus as of their activation height
+		goldchaintypes.RegisterMultiSignatureAtomicSwapTypes()
+
 		fmt.Println("Setting up root HTTP API handler...")
 
 		// handle all our endpoints over a router,
@@ -160,7 +169,10 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 
 		var mintingPlugin *minting.Plugin
 		var authCoinTxPlugin *authcointx.Plugin
+		var authExpiryPlugin *aeplugin.Plugin
 		var custodyFeesPlugin *cfplugin.Plugin
+		var proofOfReservePlugin *porplugin.Plugin
+		var redemptionPlugin *rdplugin.Plugin
 
 		if moduleIdentifiers.Contains(daemon.ConsensusSetModule.Identifier()) {
 			printModuleIsLoading("consensus set")
@@ -181,9 +193,9 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 				}
 			}()
 
-			cs.SetTransactionValidators(setupNetworkCfg.Validators...)
+			cs.SetTransactionValidators(gcconsensus.TransactionValidationFunctions(setupNetworkCfg.Validators)...)
 			for txVersion, validators := range setupNetworkCfg.MappedValidators {
-				cs.SetTransactionVersionMappedValidators(txVersion, validators...)
+				cs.SetTransactionVersionMappedValidators(txVersion, gcconsensus.TransactionValidationFunctions(validators)...)
 			}
 		}
 
@@ -198,7 +210,7 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 				cancel()
 				return
 			}
-			rivineapi.RegisterTransactionPoolHTTPHandlers(router, cs, tpool, cfg.APIPassword)
+			// HTTP handlers are registered once all validators and plugins are known
 			defer func() {
 				fmt.Println("Closing transaction pool...")
 				err := tpool.Close()
@@ -228,16 +240,8 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 				goldchaintypes.TransactionVersionAuthAddressUpdate,
 				goldchaintypes.TransactionVersionAuthConditionUpdate,
 				&authcointx.PluginOpts{
-					UnauthorizedCoinTransactionExceptionCallback: func(tx modules.ConsensusTransaction, dedupAddresses []types.UnlockHash, ctx types.TransactionValidationContext) (bool, error) {
-						if tx.Version != types.TransactionVersionZero && tx.Version != types.TransactionVersionOne {
-							return false, nil
-						}
-						return (len(dedupAddresses) == 1 && len(tx.CoinOutputs) <= 2), nil
-					},
-					UnlockHashFilter: func(uh types.UnlockHash) bool {
-						return uh.Type != types.UnlockTypeNil &&
-							uh.Type != types.UnlockTypeAtomicSwap && uh.Type != cftypes.UnlockTypeCustodyFee
-					},
+					UnauthorizedCoinTransactionExceptionCallback: goldchaintypes.UnauthorizedCoinTransactionExceptionCallback,
+					UnlockHashFilter: goldchaintypes.AuthCoinUnlockHashFilter,
 				},
 			)
 			// add the HTTP handlers for the auth coin tx extension as well
@@ -253,6 +257,19 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 					goldchaintypes.TransactionVersionAuthAddressUpdate)
 			}
 
+			// create the auth expiry plugin, enforcing the expiry of authorizations
+			// on top of the auth coin tx plugin, using the same authorization rules
+			authExpiryPlugin = aeplugin.NewPlugin(
+				goldchaintypes.TransactionVersionAuthAddressUpdate,
+				&aeplugin.PluginOpts{
+					UnauthorizedCoinTransactionExceptionCallback: goldchaintypes.UnauthorizedCoinTransactionExceptionCallback,
+					UnlockHashFilter: goldchaintypes.AuthCoinUnlockHashFilter,
+					ActivationHeight: setupNetworkCfg.ActivationHeights.AuthorizationExpiry,
+				},
+			)
+			// add the HTTP handlers for the auth expiry extension as well
+			aeapi.RegisterConsensusAuthExpiryHTTPHandlers(router, authExpiryPlugin, cs)
+
 			// register the custody fees plugin
 			custodyFeesPlugin = cfplugin.NewPlugin(
 				setupNetworkCfg.CustodyFeeConfig.MaxAllowedComputationTimeAdvance,
@@ -261,6 +278,19 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 			// add the HTTP handlers for the custody fees extension as well
 			cfapi.RegisterConsensusCustodyFeesHTTPHandlers(router, cs, custodyFeesPlugin)
 
+			// create the proof-of-reserve plugin
+			proofOfReservePlugin = porplugin.NewPlugin(
+				goldchaintypes.TransactionVersionCoinCreation,
+				goldchaintypes.TransactionVersionCoinDestruction,
+				setupNetworkCfg.ActivationHeights.ExtendedArbitraryData,
+			)
+
+			// create the redemption plugin
+			redemptionPlugin = rdplugin.NewPlugin(
+				goldchaintypes.TransactionVersionCoinDestruction,
+				setupNetworkCfg.ActivationHeights.ExtendedArbitraryData,
+			)
+
 			// register the minting extension plugin
 			err = cs.RegisterPlugin(ctx, "minting", mintingPlugin)
 			if err != nil {
@@ -285,6 +315,18 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 				return
 			}
 
+			// register the AuthExpiry extension plugin
+			err = cs.RegisterPlugin(ctx, "authexpiry", authExpiryPlugin)
+			if err != nil {
+				servErrs <- fmt.Errorf("failed to register the authexpiry extension: %v", err)
+				err = authExpiryPlugin.Close() //make sure any resources are released
+				if err != nil {
+					fmt.Println("Error during closing of the authExpiryPlugin :", err)
+				}
+				cancel()
+				return
+			}
+
 			// register the CustodyFees extension plugin
 			err = cs.RegisterPlugin(ctx, "custodyfees", custodyFeesPlugin)
 			if err != nil {
@@ -296,6 +338,42 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 				cancel()
 				return
 			}
+
+			// register the ProofOfReserve extension plugin
+			err = cs.RegisterPlugin(ctx, "proofofreserve", proofOfReservePlugin)
+			if err != nil {
+				servErrs <- fmt.Errorf("failed to register the proofofreserve extension: %v", err)
+				err = proofOfReservePlugin.Close() //make sure any resources are released
+				if err != nil {
+					fmt.Println("Error during closing of the proofOfReservePlugin :", err)
+				}
+				cancel()
+				return
+			}
+
+			// register the Redemption extension plugin
+			err = cs.RegisterPlugin(ctx, "redemption", redemptionPlugin)
+			if err != nil {
+				servErrs <- fmt.Errorf("failed to register the redemption extension: %v", err)
+				err = redemptionPlugin.Close() //make sure any resources are released
+				if err != nil {
+					fmt.Println("Error during closing of the redemptionPlugin :", err)
+				}
+				cancel()
+				return
+			}
+
+			// add the transaction pool HTTP handlers, now that all validators and plugins are known
+			if tpool != nil {
+				goldchainapi.RegisterTransactionPoolHTTPHandlers(router, cs, tpool, goldchainapi.TransactionSimulationConfig{
+					ChainConstants:    networkCfg.Constants,
+					Validators:        setupNetworkCfg.Validators,
+					MappedValidators:  setupNetworkCfg.MappedValidators,
+					CustodyFeesPlugin: custodyFeesPlugin,
+					AuthCoinTxPlugin:  authCoinTxPlugin,
+					AuthExpiryPlugin:  authExpiryPlugin,
+				}, cfg.APIPassword)
+			}
 		}
 
 		var w goldchainmodules.Wallet
@@ -377,6 +455,9 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 			}()
 
 			mintingapi.RegisterExplorerMintingHTTPHandlers(router, mintingPlugin)
+			porapi.RegisterExplorerProofOfReserveHTTPHandlers(router, proofOfReservePlugin)
+			rdapi.RegisterExplorerRedemptionHTTPHandlers(router, redemptionPlugin, cs, tpool)
+			aeapi.RegisterExplorerAuthExpiryHTTPHandlers(router, authExpiryPlugin, cs)
 			if tpool != nil {
 				authcointxapi.RegisterExplorerAuthCoinHTTPHandlers(
 					router, authCoinTxPlugin,
@@ -427,8 +508,9 @@ type setupNetworkConfig struct {
 	GenesisMintCondition types.UnlockConditionProxy
 	GenesisAuthCondition types.UnlockConditionProxy
 	CustodyFeeConfig     custodyFeeConfig
-	Validators           []modules.TransactionValidationFunction
-	MappedValidators     map[types.TransactionVersion][]modules.TransactionValidationFunction
+	ActivationHeights    config.FeatureActivationHeights
+	Validators           []gcconsensus.NamedTransactionValidator
+	MappedValidators     map[types.TransactionVersion][]gcconsensus.NamedTransactionValidator
 }
 
 type custodyFeeConfig struct {
@@ -465,8 +547,9 @@ func setupNetwork(cfg ExtendedDaemonConfig) (setupNetworkConfig, error) {
 				MaxAllowedComputationTimeAdvance: types.Timestamp(constants.BlockFrequency) * 10,
 				MaxFallbackBlocksInThePast:       5,
 			},
-			Validators:       gcconsensus.GetDevnetTransactionValidators(),
-			MappedValidators: gcconsensus.GetDevnetTransactionVersionMappedValidators(),
+			ActivationHeights: config.GetDevnetFeatureActivationHeights(),
+			Validators:        gcconsensus.GetDevnetTransactionValidators(),
+			MappedValidators:  gcconsensus.GetDevnetTransactionVersionMappedValidators(),
 		}, nil
 
 	case config.NetworkNameTestnet:
@@ -489,8 +572,9 @@ func setupNetwork(cfg ExtendedDaemonConfig) (setupNetworkConfig, error) {
 				MaxAllowedComputationTimeAdvance: types.Timestamp(constants.BlockFrequency) * 5,
 				MaxFallbackBlocksInThePast:       3,
 			},
-			Validators:       gcconsensus.GetTestnetTransactionValidators(),
-			MappedValidators: gcconsensus.GetTestnetTransactionVersionMappedValidators(),
+			ActivationHeights: config.GetTestnetFeatureActivationHeights(),
+			Validators:        gcconsensus.GetTestnetTransactionValidators(),
+			MappedValidators:  gcconsensus.GetTestnetTransactionVersionMappedValidators(),
 		}, nil
 
 	default:
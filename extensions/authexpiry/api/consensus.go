@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nbh-digital/goldchain/extensions/authexpiry"
+	"github.com/threefoldtech/rivine/modules"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// RegisterConsensusAuthExpiryHTTPHandlers registers the default consensus HTTP handlers specific to the authexpiry package.
+func RegisterConsensusAuthExpiryHTTPHandlers(router rapi.Router, plugin *authexpiry.Plugin, cs modules.ConsensusSet) {
+	router.GET("/consensus/authexpiries/expired", NewExpiredAuthExpiriesGetHandler(plugin, cs))
+}
+
+// NewExpiredAuthExpiriesGetHandler creates a handler to handle the API calls to
+// /consensus/authexpiries/expired?addr=&addr=.
+// Only the authorization expiries of the given addresses which are expired as of the current block are returned.
+func NewExpiredAuthExpiriesGetHandler(plugin *authexpiry.Plugin, cs modules.ConsensusSet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		addressStrings := req.URL.Query()["addr"]
+		if len(addressStrings) == 0 {
+			rapi.WriteError(w, rapi.Error{Message: "no address given as query parameter while at least one is required"}, http.StatusBadRequest)
+			return
+		}
+		addresses := make([]types.UnlockHash, len(addressStrings))
+		for idx, addressStr := range addressStrings {
+			err := addresses[idx].LoadString(addressStr)
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: fmt.Sprintf("invalid address %s (q#%d) given: %v", addressStr, idx, err)}, http.StatusBadRequest)
+				return
+			}
+		}
+		height, timestamp := currentBlock(cs)
+		records, err := plugin.GetExpiredAddresses(addresses, height, timestamp)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		expiries := make([]AuthExpiry, 0, len(records))
+		for _, record := range records {
+			expiries = append(expiries, AuthExpiry{
+				ExpiryRecord: record,
+				Expired:      true,
+			})
+		}
+		rapi.WriteJSON(w, AuthExpiriesGet{
+			Height:    height,
+			Timestamp: timestamp,
+			Expiries:  expiries,
+		})
+	}
+}
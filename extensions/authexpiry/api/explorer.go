@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nbh-digital/goldchain/extensions/authexpiry"
+	"github.com/threefoldtech/rivine/modules"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/types"
+)
+
+type (
+	// AuthExpiriesGet is the response of the authorization expiries Get explorer endpoint,
+	// listing the expiries relative to the current block
+	AuthExpiriesGet struct {
+		Height    types.BlockHeight `json:"height"`
+		Timestamp types.Timestamp   `json:"timestamp"`
+		Expiries  []AuthExpiry      `json:"expiries"`
+	}
+
+	// AuthExpiryGet is the response of the authorization expiry Get explorer endpoint,
+	// returning the expiry of a single address relative to the current block
+	AuthExpiryGet struct {
+		Height    types.BlockHeight `json:"height"`
+		Timestamp types.Timestamp   `json:"timestamp"`
+		Expiry    AuthExpiry        `json:"expiry"`
+	}
+
+	// AuthExpiry is the authorization expiry of an address,
+	// flagged as expired if it is expired as of the current block.
+	AuthExpiry struct {
+		authexpiry.ExpiryRecord
+		Expired bool `json:"expired"`
+	}
+)
+
+// RegisterExplorerAuthExpiryHTTPHandlers registers the default explorer HTTP handlers specific to the authexpiry package.
+func RegisterExplorerAuthExpiryHTTPHandlers(router rapi.Router, plugin *authexpiry.Plugin, cs modules.ConsensusSet) {
+	router.GET("/explorer/authexpiries", NewAuthExpiriesGetHandler(plugin, cs))
+	router.GET("/explorer/authexpiries/:address", NewAuthExpiryGetHandler(plugin, cs))
+}
+
+// NewAuthExpiriesGetHandler creates a handler to handle the API calls to
+// /explorer/authexpiries?withinblocks=&within=.
+// All authorizations which expire are listed, unless the authorizations expiring within the given
+// amount of blocks and/or the given duration (e.g. 720h) of the current block are requested,
+// including the authorizations which are already expired.
+func NewAuthExpiriesGetHandler(plugin *authexpiry.Plugin, cs modules.ConsensusSet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		height, timestamp := currentBlock(cs)
+		var filter authexpiry.ExpiryFilter
+		q := req.URL.Query()
+		if str := q.Get("withinblocks"); str != "" {
+			blocks, err := strconv.ParseUint(str, 10, 64)
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: "invalid withinblocks query param: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			filter.Height = height + types.BlockHeight(blocks)
+		}
+		if str := q.Get("within"); str != "" {
+			duration, err := time.ParseDuration(str)
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: "invalid within query param: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			if duration < 0 {
+				rapi.WriteError(w, rapi.Error{Message: "invalid within query param: duration cannot be negative"}, http.StatusBadRequest)
+				return
+			}
+			filter.Time = timestamp + types.Timestamp(duration/time.Second)
+		}
+		records, err := plugin.GetExpiries(filter)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		expiries := make([]AuthExpiry, 0, len(records))
+		for _, record := range records {
+			expiries = append(expiries, AuthExpiry{
+				ExpiryRecord: record,
+				Expired:      record.Expiry.Expired(height, timestamp),
+			})
+		}
+		rapi.WriteJSON(w, AuthExpiriesGet{
+			Height:    height,
+			Timestamp: timestamp,
+			Expiries:  expiries,
+		})
+	}
+}
+
+// NewAuthExpiryGetHandler creates a handler to handle the API calls to /explorer/authexpiries/:address.
+func NewAuthExpiryGetHandler(plugin *authexpiry.Plugin, cs modules.ConsensusSet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var address types.UnlockHash
+		err := address.LoadString(ps.ByName("address"))
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: "invalid address: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		record, err := plugin.GetExpiry(address)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err == authexpiry.ErrExpiryNotFound {
+				status = http.StatusNotFound
+			}
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, status)
+			return
+		}
+		height, timestamp := currentBlock(cs)
+		rapi.WriteJSON(w, AuthExpiryGet{
+			Height:    height,
+			Timestamp: timestamp,
+			Expiry: AuthExpiry{
+				ExpiryRecord: record,
+				Expired:      record.Expiry.Expired(height, timestamp),
+			},
+		})
+	}
+}
+
+// currentBlock returns the height and timestamp of the current block.
+func currentBlock(cs modules.ConsensusSet) (types.BlockHeight, types.Timestamp) {
+	block := cs.CurrentBlock()
+	height, _ := cs.BlockHeightOfBlock(block)
+	return height, block.Timestamp
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/pkg/client"
+	rivinecli "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateExplorerSubCmds adds the explorer cli subcommands for the authexpiry plugin
+func CreateExplorerSubCmds(ccli *rivinecli.CommandLineClient) error {
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(ccli)
+	if err != nil {
+		return err
+	}
+
+	explorerSubCmds := &explorerSubCmds{
+		cli:      ccli,
+		aeClient: NewPluginExplorerClient(bc),
+	}
+
+	// define commands
+	getAuthExpiriesCmd := &cobra.Command{
+		Use:   "authexpiries [address]",
+		Short: "Get the authorizations which expire",
+		Long: `Get the authorization expiries attached to auth address update transactions,
+listing for each address until which block height and/or block time it is authorized,
+the transaction which authorized it, and whether its authorization is already expired.
+
+All authorizations which expire are listed, unless an address is given,
+in which case only the expiry of that address is returned.
+Use the --within and --within-blocks flags to only list the authorizations
+which expire soon (or already expired), e.g. to renew them in time.
+`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  explorerSubCmds.getAuthExpiries,
+	}
+
+	// add commands as explorer sub commands
+	ccli.ExploreCmd.AddCommand(getAuthExpiriesCmd)
+
+	// register flags
+	getAuthExpiriesCmd.Flags().DurationVar(
+		&explorerSubCmds.getAuthExpiriesCfg.Within, "within", 0,
+		"only list the authorizations expiring within the given duration of the current block time (e.g. 720h)")
+	getAuthExpiriesCmd.Flags().Uint64Var(
+		&explorerSubCmds.getAuthExpiriesCfg.WithinBlocks, "within-blocks", 0,
+		"only list the authorizations expiring within the given amount of blocks of the current block height")
+	getAuthExpiriesCmd.Flags().Var(
+		cli.NewEncodingTypeFlag(0, &explorerSubCmds.getAuthExpiriesCfg.EncodingType, 0), "encoding",
+		cli.EncodingTypeFlagDescription(0))
+
+	return nil
+}
+
+type explorerSubCmds struct {
+	cli                *rivinecli.CommandLineClient
+	aeClient           *PluginClient
+	getAuthExpiriesCfg struct {
+		Within       time.Duration
+		WithinBlocks uint64
+		EncodingType cli.EncodingType
+	}
+}
+
+func (explorerSubCmds *explorerSubCmds) getAuthExpiries(cmd *cobra.Command, args []string) {
+	var (
+		result interface{}
+		err    error
+	)
+	if len(args) == 1 {
+		var address types.UnlockHash
+		err = address.LoadString(args[0])
+		if err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.DieWithError("error while string-decoding address", err)
+			return
+		}
+		result, err = explorerSubCmds.aeClient.GetExpiry(address)
+	} else {
+		if explorerSubCmds.getAuthExpiriesCfg.Within < 0 {
+			cmd.UsageFunc()(cmd)
+			cli.DieWithError("invalid --within flag", fmt.Errorf("duration cannot be negative"))
+			return
+		}
+		result, err = explorerSubCmds.aeClient.GetExpiries(
+			types.BlockHeight(explorerSubCmds.getAuthExpiriesCfg.WithinBlocks),
+			explorerSubCmds.getAuthExpiriesCfg.Within)
+	}
+	if err != nil {
+		cli.DieWithError("error while getting authorization expiries from explorer", err)
+		return
+	}
+
+	// encode depending on the encoding flag
+	var encode func(interface{}) error
+	switch explorerSubCmds.getAuthExpiriesCfg.EncodingType {
+	case cli.EncodingTypeHuman:
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		encode = e.Encode
+	case cli.EncodingTypeJSON:
+		encode = json.NewEncoder(os.Stdout).Encode
+	case cli.EncodingTypeHex:
+		encode = func(v interface{}) error {
+			b, err := rivbin.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Println(hex.EncodeToString(b))
+			return nil
+		}
+	}
+	err = encode(result)
+	if err != nil {
+		cli.DieWithError("failed to encode authorization expiries", err)
+	}
+}
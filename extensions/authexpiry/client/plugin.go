@@ -0,0 +1,94 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nbh-digital/goldchain/extensions/authexpiry/api"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// PluginClient is used to be able to get the authorization expiries
+// attached to auth address update transactions.
+type PluginClient struct {
+	client       client.BaseClient
+	rootEndpoint string
+}
+
+// NewPluginExplorerClient creates a new PluginClient,
+// that can be used for easy interaction with the AuthExpiry Extension API exposed via the Explorer endpoints
+func NewPluginExplorerClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client:       cli,
+		rootEndpoint: "/explorer",
+	}
+}
+
+// NewPluginConsensusClient creates a new PluginClient,
+// that can be used for easy interaction with the AuthExpiry Extension API exposed via the Consensus endpoints
+func NewPluginConsensusClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client:       cli,
+		rootEndpoint: "/consensus",
+	}
+}
+
+// GetExpiries returns the authorization expiries which expire within the given amount of blocks
+// or the given duration of the current block, including those already expired.
+// All authorization expiries are returned if neither is defined.
+func (cli *PluginClient) GetExpiries(withinBlocks types.BlockHeight, within time.Duration) (api.AuthExpiriesGet, error) {
+	query := url.Values{}
+	if withinBlocks != 0 {
+		query.Set("withinblocks", strconv.FormatUint(uint64(withinBlocks), 10))
+	}
+	if within != 0 {
+		query.Set("within", within.String())
+	}
+	endpoint := cli.rootEndpoint + "/authexpiries"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var result api.AuthExpiriesGet
+	err := cli.client.HTTP().GetWithResponse(endpoint, &result)
+	if err != nil {
+		return api.AuthExpiriesGet{}, fmt.Errorf("failed to get authorization expiries from daemon: %v", err)
+	}
+	return result, nil
+}
+
+// GetExpiry returns the authorization expiry of the given address.
+func (cli *PluginClient) GetExpiry(address types.UnlockHash) (api.AuthExpiryGet, error) {
+	var result api.AuthExpiryGet
+	err := cli.client.HTTP().GetWithResponse(
+		fmt.Sprintf("%s/authexpiries/%s", cli.rootEndpoint, address.String()),
+		&result)
+	if err != nil {
+		return api.AuthExpiryGet{}, fmt.Errorf(
+			"failed to get authorization expiry of %s from daemon: %v", address.String(), err)
+	}
+	return result, nil
+}
+
+// GetExpiredAddresses returns the authorization expiries of the given addresses,
+// which are expired as of the current block. Only available using the consensus endpoints.
+func (cli *PluginClient) GetExpiredAddresses(addresses []types.UnlockHash) (api.AuthExpiriesGet, error) {
+	query := url.Values{}
+	for _, address := range addresses {
+		query.Add("addr", address.String())
+	}
+	var result api.AuthExpiriesGet
+	err := cli.client.HTTP().GetWithResponse(cli.rootEndpoint+"/authexpiries/expired?"+query.Encode(), &result)
+	if err != nil {
+		return api.AuthExpiriesGet{}, fmt.Errorf("failed to get expired authorizations from daemon: %v", err)
+	}
+	return result, nil
+}
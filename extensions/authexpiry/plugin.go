@@ -0,0 +1,592 @@
+package authexpiry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/threefoldtech/rivine/extensions/authcointx"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	aetypes "github.com/nbh-digital/goldchain/extensions/authexpiry/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+const (
+	pluginDBVersion = "1.0.0.0"
+	pluginDBHeader  = "authExpiryPlugin"
+)
+
+var (
+	// authorization expiry history, stored by address
+	bucketExpiries = []byte("expiries")
+)
+
+// ErrExpiryNotFound is returned when the authorization of an address does not expire.
+var ErrExpiryNotFound = errors.New("no authorization expiry found for address")
+
+type (
+	// Plugin is a struct that defines the authorization expiry plugin,
+	// enforcing the expiry attached to auth address update transactions on top of the auth coin tx plugin:
+	// addresses of which the authorization expired can no longer send or receive coins.
+	Plugin struct {
+		authAddressUpdateTransactionVersion          types.TransactionVersion
+		unauthorizedCoinTransactionExceptionCallback authcointx.UnauthorizedCoinTransactionExceptionCallback
+		unlockHashFilter                             func(types.UnlockHash) bool
+		activationHeight                             types.BlockHeight
+
+		storage            modules.PluginViewStorage
+		unregisterCallback modules.PluginUnregisterCallback
+	}
+
+	// PluginOpts are extra optional configurations of the authorization expiry plugin,
+	// these should be the same as the ones given to the auth coin tx plugin.
+	PluginOpts struct {
+		// UnauthorizedCoinTransactionExceptionCallback defines which coin transactions
+		// do not require their addresses to be authorized, and thus do not check their expiry either
+		UnauthorizedCoinTransactionExceptionCallback authcointx.UnauthorizedCoinTransactionExceptionCallback
+		// UnlockHashFilter returns true for the unlock hashes which require authorization
+		UnlockHashFilter func(types.UnlockHash) bool
+		// ActivationHeight is the block height as of which authorization expiries are validated and enforced,
+		// the arbitrary data of auth address update transactions in prior blocks is never interpreted as an expiry
+		ActivationHeight types.BlockHeight
+	}
+
+	// ExpiryRecord is the authorization expiry of an address,
+	// as defined by the auth address update transaction which authorized it.
+	ExpiryRecord struct {
+		Address       types.UnlockHash    `json:"address"`
+		Expiry        aetypes.Expiry      `json:"expiry"`
+		TransactionID types.TransactionID `json:"transactionid"`
+		// BlockHeight and BlockTime define when the address was authorized
+		BlockHeight types.BlockHeight `json:"blockheight"`
+		BlockTime   types.Timestamp   `json:"blocktime"`
+	}
+
+	// ExpiryFilter can be used to only list the authorizations which are expired
+	// at the given block height or block time (whichever is defined),
+	// an empty filter matches all authorizations which expire.
+	ExpiryFilter struct {
+		Height types.BlockHeight
+		Time   types.Timestamp
+	}
+
+	// expiryEntry is a single (de)authorization of an address, as stored in its history.
+	// The expiry is undefined for a deauthorization or an authorization which does not expire.
+	expiryEntry struct {
+		TransactionID types.TransactionID
+		BlockHeight   types.BlockHeight
+		BlockTime     types.Timestamp
+		Expiry        aetypes.Expiry
+	}
+)
+
+// NewPlugin creates a new authorization expiry Plugin,
+// handling the auth address update transactions of the given version.
+func NewPlugin(authAddressUpdateTransactionVersion types.TransactionVersion, opts *PluginOpts) *Plugin {
+	p := &Plugin{
+		authAddressUpdateTransactionVersion: authAddressUpdateTransactionVersion,
+	}
+	if opts != nil && opts.UnauthorizedCoinTransactionExceptionCallback != nil {
+		p.unauthorizedCoinTransactionExceptionCallback = opts.UnauthorizedCoinTransactionExceptionCallback
+	} else {
+		p.unauthorizedCoinTransactionExceptionCallback = authcointx.DefaultUnauthorizedCoinTransactionExceptionCallback
+	}
+	if opts != nil && opts.UnlockHashFilter != nil {
+		p.unlockHashFilter = opts.UnlockHashFilter
+	} else {
+		p.unlockHashFilter = func(uh types.UnlockHash) bool {
+			return uh.Type != types.UnlockTypeNil && uh.Type != types.UnlockTypeAtomicSwap
+		}
+	}
+	if opts != nil {
+		p.activationHeight = opts.ActivationHeight
+	}
+	return p
+}
+
+// InitPlugin initializes the Bucket for the first time
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+	p.storage = storage
+	p.unregisterCallback = unregisterCallback
+	if metadata == nil {
+		if bucket.Bucket(bucketExpiries) == nil {
+			_, err := bucket.CreateBucket(bucketExpiries)
+			if err != nil {
+				return persist.Metadata{}, fmt.Errorf("failed to create %s bucket for authexpiry plugin: %v", string(bucketExpiries), err)
+			}
+		}
+
+		metadata = &persist.Metadata{
+			Version: pluginDBVersion,
+			Header:  pluginDBHeader,
+		}
+	} else if metadata.Version != pluginDBVersion {
+		return persist.Metadata{}, errors.New("There is only 1 version of this plugin, version mismatch")
+	}
+	return *metadata, nil
+}
+
+// ApplyBlock applies the authorization expiries of a block to the expiry bucket.
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("authexpiry bucket does not exist")
+	}
+	for idx, txn := range block.Transactions {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            txn,
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err := p.ApplyTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBlockHeader applies nothing and has no effect on this plugin.
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+	return nil
+}
+
+// ApplyTransaction applies the authorization expiry of an auth address update transaction to the expiry bucket.
+// Addresses authorized without an expiry, as well as deauthorized addresses, no longer expire.
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("authexpiry bucket does not exist")
+	}
+	if txn.Version != p.authAddressUpdateTransactionVersion || txn.BlockHeight < p.activationHeight {
+		return nil // nothing to do
+	}
+	autx, err := authcointx.AuthAddressUpdateTransactionFromTransaction(txn.Transaction, p.authAddressUpdateTransactionVersion)
+	if err != nil {
+		return fmt.Errorf("failed to use tx %s as an auth address update tx: %v", txn.ID().String(), err)
+	}
+	var expiry aetypes.Expiry
+	if aetypes.IsExpiryArbitraryData(autx.ArbitraryData) {
+		expiry, err = aetypes.UnmarshalExpiryArbitraryData(autx.ArbitraryData)
+		if err != nil {
+			// expiries are validated as of the activation height,
+			// arbitrary data which cannot be decoded is simply not an expiry
+			expiry = aetypes.Expiry{}
+		}
+	}
+	expiriesBucket, err := getExpiriesBucket(bucket)
+	if err != nil {
+		return err
+	}
+	entry := expiryEntry{
+		TransactionID: txn.ID(),
+		BlockHeight:   txn.BlockHeight,
+		BlockTime:     txn.BlockTime,
+		Expiry:        expiry,
+	}
+	for _, addr := range autx.AuthAddresses {
+		err = appendExpiryEntry(expiriesBucket, addr, entry)
+		if err != nil {
+			return err
+		}
+	}
+	entry.Expiry = aetypes.Expiry{}
+	for _, addr := range autx.DeauthAddresses {
+		err = appendExpiryEntry(expiriesBucket, addr, entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBlock reverts the authorization expiries of a block from the expiry bucket.
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("authexpiry bucket does not exist")
+	}
+	// revert all transactions in reverse order
+	for idx := len(block.Transactions) - 1; idx >= 0; idx-- {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            block.Transactions[idx],
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err := p.RevertTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBlockHeader reverts nothing and has no effect on this plugin.
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+	return nil
+}
+
+// RevertTransaction reverts the authorization expiry of an auth address update transaction from the expiry bucket,
+// restoring the previous authorization expiry of its addresses.
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("authexpiry bucket does not exist")
+	}
+	if txn.Version != p.authAddressUpdateTransactionVersion || txn.BlockHeight < p.activationHeight {
+		return nil // nothing to do
+	}
+	autx, err := authcointx.AuthAddressUpdateTransactionFromTransaction(txn.Transaction, p.authAddressUpdateTransactionVersion)
+	if err != nil {
+		return fmt.Errorf("failed to use tx %s as an auth address update tx: %v", txn.ID().String(), err)
+	}
+	expiriesBucket, err := getExpiriesBucket(bucket)
+	if err != nil {
+		return err
+	}
+	txnID := txn.ID()
+	for _, addresses := range [][]types.UnlockHash{autx.AuthAddresses, autx.DeauthAddresses} {
+		for _, addr := range addresses {
+			err = popExpiryEntry(expiriesBucket, addr, txnID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetExpiry returns the authorization expiry of the given address,
+// returning ErrExpiryNotFound if the address is not authorized or its authorization does not expire.
+func (p *Plugin) GetExpiry(address types.UnlockHash) (ExpiryRecord, error) {
+	var record ExpiryRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		expiriesBucket := bucket.Bucket(bucketExpiries)
+		if expiriesBucket == nil {
+			return errors.New("corrupt authexpiry plugin: did not find any expiries")
+		}
+		var (
+			ok  bool
+			err error
+		)
+		record, ok, err = getExpiryRecord(expiriesBucket, address)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrExpiryNotFound
+		}
+		return nil
+	})
+	return record, err
+}
+
+// GetExpiries returns the authorization expiries of all authorized addresses which match the given filter,
+// ordered by address.
+func (p *Plugin) GetExpiries(filter ExpiryFilter) ([]ExpiryRecord, error) {
+	var records []ExpiryRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		expiriesBucket := bucket.Bucket(bucketExpiries)
+		if expiriesBucket == nil {
+			return errors.New("corrupt authexpiry plugin: did not find any expiries")
+		}
+		return expiriesBucket.ForEach(func(k, v []byte) error {
+			var address types.UnlockHash
+			err := rivbin.Unmarshal(k, &address)
+			if err != nil {
+				return fmt.Errorf("failed to rivbin unmarshal address: %v", err)
+			}
+			record, ok, err := expiryRecordFromHistory(address, v)
+			if err != nil {
+				return err
+			}
+			if ok && filter.Match(record) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
+
+// GetExpiredAddresses returns the authorization expiries of the given addresses
+// which are expired at the given block height and time.
+func (p *Plugin) GetExpiredAddresses(addresses []types.UnlockHash, height types.BlockHeight, timestamp types.Timestamp) ([]ExpiryRecord, error) {
+	var records []ExpiryRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		expiriesBucket := bucket.Bucket(bucketExpiries)
+		if expiriesBucket == nil {
+			return errors.New("corrupt authexpiry plugin: did not find any expiries")
+		}
+		var err error
+		records, err = expiredAddresses(expiriesBucket, addresses, height, timestamp)
+		return err
+	})
+	return records, err
+}
+
+// Match returns true if the given record matches the filter.
+func (filter ExpiryFilter) Match(record ExpiryRecord) bool {
+	if filter.Height == 0 && filter.Time == 0 {
+		return true
+	}
+	return record.Expiry.Expired(filter.Height, filter.Time)
+}
+
+// TransactionValidatorVersionFunctionMapping returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidatorVersionFunctionMapping() map[types.TransactionVersion][]modules.PluginTransactionValidationFunction {
+	return map[types.TransactionVersion][]modules.PluginTransactionValidationFunction{
+		p.authAddressUpdateTransactionVersion: {
+			p.validateExpiry,
+		},
+	}
+}
+
+// TransactionValidators returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFunction {
+	return []modules.PluginTransactionValidationFunction{
+		p.validateAuthorizationNotExpired,
+	}
+}
+
+// validateExpiry validates the authorization expiry attached to an auth address update transaction,
+// which has to be in the future and requires at least one address to be authorized.
+// Arbitrary data that does not carry an expiry is not validated by this plugin.
+func (p *Plugin) validateExpiry(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+	if ctx.BlockHeight < p.activationHeight || !aetypes.IsExpiryArbitraryData(tx.ArbitraryData) {
+		return nil // nothing to do
+	}
+	autx, err := authcointx.AuthAddressUpdateTransactionFromTransaction(tx.Transaction, p.authAddressUpdateTransactionVersion)
+	if err != nil {
+		return fmt.Errorf("failed to use tx as a auth address update tx: %v", err)
+	}
+	expiry, err := aetypes.UnmarshalExpiryArbitraryData(autx.ArbitraryData)
+	if err == nil {
+		err = expiry.Validate()
+	}
+	if err == nil && expiry.Expired(ctx.BlockHeight, ctx.BlockTime) {
+		err = aetypes.ErrExpiryInThePast
+	}
+	if err == nil && len(autx.AuthAddresses) == 0 {
+		err = aetypes.ErrNoAuthAddresses
+	}
+	if err != nil {
+		return gctypes.NewCodedError(gctypes.ErrorCodeInvalidAuthorizationExpiry, err, nil)
+	}
+	return nil
+}
+
+// validateAuthorizationNotExpired ensures that none of the addresses used by a coin transaction
+// have an expired authorization, using the same addresses as checked by the auth coin tx plugin.
+func (p *Plugin) validateAuthorizationNotExpired(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+	if ctx.BlockHeight < p.activationHeight {
+		return nil // authorizations do not expire prior to the activation height
+	}
+	// collect all dedupAddresses
+	var (
+		dedupAddresses []types.UnlockHash
+		seen           = make(map[types.UnlockHash]struct{})
+	)
+	addAddress := func(uh types.UnlockHash) {
+		if _, ok := seen[uh]; ok {
+			return
+		}
+		seen[uh] = struct{}{}
+		dedupAddresses = append(dedupAddresses, uh)
+	}
+	for _, co := range tx.CoinOutputs {
+		addAddress(co.Condition.UnlockHash())
+	}
+	for _, ci := range tx.CoinInputs {
+		co, ok := tx.SpentCoinOutputs[ci.ParentID]
+		if !ok {
+			return fmt.Errorf(
+				"unable to find parent ID %s as an unspent coin output in the current consensus transaction at block height %d",
+				ci.ParentID.String(), ctx.BlockHeight)
+		}
+		addAddress(co.Condition.UnlockHash())
+	}
+	if len(dedupAddresses) == 0 {
+		return nil // nothing to do
+	}
+	allowedToBeUnauthorized, err := p.unauthorizedCoinTransactionExceptionCallback(tx, dedupAddresses, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if transaction is allowed to be a potential unauthorized coin transfer: %v", err)
+	}
+	if allowedToBeUnauthorized {
+		return nil // nothing to validate, whether it is authorized or not is no longer important
+	}
+	addresses := dedupAddresses[:0]
+	for _, uh := range dedupAddresses {
+		if p.unlockHashFilter(uh) {
+			addresses = append(addresses, uh)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	if bucket == nil {
+		return errors.New("authexpiry bucket does not exist")
+	}
+	expiriesBucket, err := getExpiriesBucket(bucket)
+	if err != nil {
+		return err
+	}
+	records, err := expiredAddresses(expiriesBucket, addresses, ctx.BlockHeight, ctx.BlockTime)
+	if err != nil {
+		return err
+	}
+	return ExpiredAuthorizationError(records)
+}
+
+// ExpiredAuthorizationError returns the coded error for the given expired authorizations,
+// nil is returned if no authorizations are given.
+func ExpiredAuthorizationError(records []ExpiryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	addresses := make([]string, 0, len(records))
+	for _, record := range records {
+		addresses = append(addresses, record.Address.String())
+	}
+	return gctypes.NewCodedError(gctypes.ErrorCodeAuthorizationExpired,
+		fmt.Errorf("authorization of address(es) %s expired (as of %s)", strings.Join(addresses, ", "), records[0].Expiry.String()),
+		map[string]string{"address": strings.Join(addresses, ",")})
+}
+
+// Close releases any resources held by the plugin like the PluginViewStorage
+func (p *Plugin) Close() error {
+	if p.storage == nil {
+		return nil
+	}
+	return p.storage.Close()
+}
+
+func getExpiriesBucket(bucket *persist.LazyBoltBucket) (*bolt.Bucket, error) {
+	expiriesBucket, err := bucket.Bucket(bucketExpiries)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt authexpiry plugin: did not find any expiries: %v", err)
+	}
+	return expiriesBucket, nil
+}
+
+// expiredAddresses returns the authorization expiries of the given addresses,
+// which are expired at the given block height and time.
+func expiredAddresses(expiriesBucket *bolt.Bucket, addresses []types.UnlockHash, height types.BlockHeight, timestamp types.Timestamp) ([]ExpiryRecord, error) {
+	var records []ExpiryRecord
+	for _, addr := range addresses {
+		record, ok, err := getExpiryRecord(expiriesBucket, addr)
+		if err != nil {
+			return nil, err
+		}
+		if ok && record.Expiry.Expired(height, timestamp) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// getExpiryRecord returns the current authorization expiry of the given address,
+// false is returned if the address is not authorized or its authorization does not expire.
+func getExpiryRecord(expiriesBucket *bolt.Bucket, address types.UnlockHash) (ExpiryRecord, bool, error) {
+	key, err := rivbin.Marshal(address)
+	if err != nil {
+		return ExpiryRecord{}, false, fmt.Errorf("failed to rivbin marshal address: %v", err)
+	}
+	return expiryRecordFromHistory(address, expiriesBucket.Get(key))
+}
+
+func expiryRecordFromHistory(address types.UnlockHash, b []byte) (ExpiryRecord, bool, error) {
+	if len(b) == 0 {
+		return ExpiryRecord{}, false, nil
+	}
+	var history []expiryEntry
+	err := rivbin.Unmarshal(b, &history)
+	if err != nil {
+		return ExpiryRecord{}, false, fmt.Errorf("failed to rivbin unmarshal authorization expiry history of %s: %v", address.String(), err)
+	}
+	if len(history) == 0 {
+		return ExpiryRecord{}, false, nil
+	}
+	entry := history[len(history)-1]
+	if !entry.Expiry.Defined() {
+		return ExpiryRecord{}, false, nil
+	}
+	return ExpiryRecord{
+		Address:       address,
+		Expiry:        entry.Expiry,
+		TransactionID: entry.TransactionID,
+		BlockHeight:   entry.BlockHeight,
+		BlockTime:     entry.BlockTime,
+	}, true, nil
+}
+
+// appendExpiryEntry appends the given entry to the history of the given address.
+// No history is created for an entry without expiry, as such an address never expired before.
+func appendExpiryEntry(expiriesBucket *bolt.Bucket, address types.UnlockHash, entry expiryEntry) error {
+	key, err := rivbin.Marshal(address)
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal address: %v", err)
+	}
+	var history []expiryEntry
+	if b := expiriesBucket.Get(key); len(b) != 0 {
+		err = rivbin.Unmarshal(b, &history)
+		if err != nil {
+			return fmt.Errorf("failed to rivbin unmarshal authorization expiry history of %s: %v", address.String(), err)
+		}
+	} else if !entry.Expiry.Defined() {
+		return nil
+	}
+	b, err := rivbin.Marshal(append(history, entry))
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal authorization expiry history of %s: %v", address.String(), err)
+	}
+	err = expiriesBucket.Put(key, b)
+	if err != nil {
+		return fmt.Errorf("failed to store authorization expiry history of %s: %v", address.String(), err)
+	}
+	return nil
+}
+
+// popExpiryEntry removes the last entry of the history of the given address,
+// given it was added by the transaction with the given ID.
+func popExpiryEntry(expiriesBucket *bolt.Bucket, address types.UnlockHash, txnID types.TransactionID) error {
+	key, err := rivbin.Marshal(address)
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal address: %v", err)
+	}
+	b := expiriesBucket.Get(key)
+	if len(b) == 0 {
+		return nil
+	}
+	var history []expiryEntry
+	err = rivbin.Unmarshal(b, &history)
+	if err != nil {
+		return fmt.Errorf("failed to rivbin unmarshal authorization expiry history of %s: %v", address.String(), err)
+	}
+	if len(history) == 0 || history[len(history)-1].TransactionID != txnID {
+		return nil
+	}
+	history = history[:len(history)-1]
+	if len(history) == 0 {
+		err = expiriesBucket.Delete(key)
+	} else {
+		b, err = rivbin.Marshal(history)
+		if err == nil {
+			err = expiriesBucket.Put(key, b)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update authorization expiry history of %s: %v", address.String(), err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package authexpiry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/threefoldtech/rivine/extensions/authcointx"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	aetypes "github.com/nbh-digital/goldchain/extensions/authexpiry/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+func TestAuthorizationExpiry(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "authexpiry.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	p := NewPlugin(gctypes.TransactionVersionAuthAddressUpdate, &PluginOpts{
+		UnauthorizedCoinTransactionExceptionCallback: gctypes.UnauthorizedCoinTransactionExceptionCallback,
+		UnlockHashFilter: gctypes.AuthCoinUnlockHashFilter,
+		ActivationHeight: 5,
+	})
+	addresses := make([]types.UnlockHash, 3)
+	for idx := range addresses {
+		addresses[idx].Type = types.UnlockTypePubKey
+		addresses[idx].Hash[0] = byte(idx + 1)
+	}
+	expiry := aetypes.Expiry{ValidUntilHeight: 100}
+	expiryData, err := expiry.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authTxn := func(auth, deauth []types.UnlockHash, data []byte) modules.ConsensusTransaction {
+		autx := authcointx.AuthAddressUpdateTransaction{
+			Nonce:           types.RandomTransactionNonce(),
+			AuthAddresses:   auth,
+			DeauthAddresses: deauth,
+			ArbitraryData:   data,
+		}
+		return modules.ConsensusTransaction{
+			Transaction: autx.Transaction(gctypes.TransactionVersionAuthAddressUpdate),
+			BlockHeight: 10,
+		}
+	}
+	coinTxn := modules.ConsensusTransaction{
+		Transaction: types.Transaction{
+			Version:     types.TransactionVersionOne,
+			CoinInputs:  []types.CoinInput{{ParentID: types.CoinOutputID{1}}},
+			CoinOutputs: []types.CoinOutput{{Value: types.NewCurrency64(1), Condition: types.NewCondition(types.NewUnlockHashCondition(addresses[1]))}},
+		},
+		SpentCoinOutputs: map[types.CoinOutputID]types.CoinOutput{
+			{1}: {Value: types.NewCurrency64(1), Condition: types.NewCondition(types.NewUnlockHashCondition(addresses[0]))},
+		},
+	}
+	ctxAt := func(height types.BlockHeight) types.TransactionValidationContext {
+		return types.TransactionValidationContext{ValidationContext: types.ValidationContext{BlockHeight: height}}
+	}
+	expectExpired := func(bucket *persist.LazyBoltBucket, height types.BlockHeight, expired bool) {
+		t.Helper()
+		err := p.validateAuthorizationNotExpired(coinTxn, ctxAt(height), bucket)
+		if !expired {
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			return
+		}
+		if cErr, ok := gctypes.AsCodedError(err); !ok || cErr.Code != gctypes.ErrorCodeAuthorizationExpired {
+			t.Fatal("unexpected error for expired authorization:", err)
+		}
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucket([]byte("authexpiry"))
+		if err != nil {
+			return err
+		}
+		_, err = p.InitPlugin(nil, root, nil, nil)
+		if err != nil {
+			return err
+		}
+		bucket := persist.NewLazyBoltBucket(func() (*bolt.Bucket, error) { return root, nil })
+		expiriesBucket := root.Bucket(bucketExpiries)
+
+		// authorize the first two addresses with an expiry, the third one without
+		authorization := authTxn(addresses[:2], nil, expiryData)
+		if err = p.validateExpiry(authorization, ctxAt(10), bucket); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if err = p.ApplyTransaction(authorization, bucket); err != nil {
+			t.Fatal(err)
+		}
+		if err = p.ApplyTransaction(authTxn(addresses[2:], nil, nil), bucket); err != nil {
+			t.Fatal(err)
+		}
+		record, ok, err := getExpiryRecord(expiriesBucket, addresses[0])
+		if err != nil || !ok || record.Expiry != expiry || record.TransactionID != authorization.ID() {
+			t.Fatalf("unexpected expiry record: %v (%v, %v)", record, ok, err)
+		}
+		if _, ok, _ = getExpiryRecord(expiriesBucket, addresses[2]); ok {
+			t.Fatal("expected authorization without expiry not to expire")
+		}
+		expectExpired(bucket, 99, false)
+		expectExpired(bucket, 100, true)
+
+		// deauthorizing an address removes its expiry, reverting the deauthorization restores it
+		deauthorization := authTxn(nil, addresses[:1], nil)
+		if err = p.ApplyTransaction(deauthorization, bucket); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, _ = getExpiryRecord(expiriesBucket, addresses[0]); ok {
+			t.Fatal("expected deauthorized address not to expire")
+		}
+		if err = p.RevertTransaction(deauthorization, bucket); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, _ = getExpiryRecord(expiriesBucket, addresses[0]); !ok {
+			t.Fatal("expected expiry to be restored")
+		}
+
+		// reverting the authorization removes the expiry history altogether
+		if err = p.RevertTransaction(authorization, bucket); err != nil {
+			t.Fatal(err)
+		}
+		if b := expiriesBucket.Get(mustMarshal(t, addresses[0])); b != nil {
+			t.Fatal("expected expiry history to be deleted")
+		}
+		expectExpired(bucket, 100, false)
+
+		// arbitrary data prior to the activation height is never interpreted as an expiry
+		early := authTxn(addresses[:1], nil, []byte("not an expiry"))
+		copy(early.ArbitraryData, expiryData[:len(expiryData)-1])
+		early.BlockHeight = 4
+		if err = p.validateExpiry(early, ctxAt(4), bucket); err != nil {
+			t.Fatal("unexpected error prior to activation:", err)
+		}
+		if err = p.ApplyTransaction(early, bucket); err != nil {
+			t.Fatal(err)
+		}
+		if b := expiriesBucket.Get(mustMarshal(t, addresses[0])); b != nil {
+			t.Fatal("expected no expiry history prior to activation")
+		}
+		if err = p.RevertTransaction(early, bucket); err != nil {
+			t.Fatal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateExpiry(t *testing.T) {
+	p := NewPlugin(gctypes.TransactionVersionAuthAddressUpdate, nil)
+	validators := p.TransactionValidatorVersionFunctionMapping()
+	if len(validators[gctypes.TransactionVersionAuthAddressUpdate]) != 1 {
+		t.Fatalf("expected one validator for transaction version %d", gctypes.TransactionVersionAuthAddressUpdate)
+	}
+
+	var address types.UnlockHash
+	address.Type = types.UnlockTypePubKey
+	marshal := func(e aetypes.Expiry) []byte {
+		data, err := e.MarshalArbitraryData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	testCases := []struct {
+		Deauthorize   bool
+		ArbitraryData []byte
+		Valid         bool
+	}{
+		{false, nil, true},
+		{false, []byte("KYC batch 2026-10"), true},
+		{false, marshal(aetypes.Expiry{ValidUntilHeight: 11}), true},
+		{false, marshal(aetypes.Expiry{ValidUntilTime: 1001}), true},
+		{false, marshal(aetypes.Expiry{ValidUntilHeight: 10}), false},
+		{false, marshal(aetypes.Expiry{ValidUntilHeight: 11, ValidUntilTime: 1000}), false},
+		{false, marshal(aetypes.Expiry{}), false},
+		{false, append(append([]byte{}, aetypes.ExpiryPrefix...), aetypes.ExpiryVersion+1), false},
+		{true, marshal(aetypes.Expiry{ValidUntilHeight: 11}), false},
+	}
+	for idx, testCase := range testCases {
+		autx := authcointx.AuthAddressUpdateTransaction{
+			Nonce:         types.RandomTransactionNonce(),
+			ArbitraryData: testCase.ArbitraryData,
+		}
+		if testCase.Deauthorize {
+			autx.DeauthAddresses = []types.UnlockHash{address}
+		} else {
+			autx.AuthAddresses = []types.UnlockHash{address}
+		}
+		txn := autx.Transaction(gctypes.TransactionVersionAuthAddressUpdate)
+		err := p.validateExpiry(modules.ConsensusTransaction{Transaction: txn}, types.TransactionValidationContext{
+			ValidationContext: types.ValidationContext{BlockHeight: 10, BlockTime: 1000},
+		}, nil)
+		if testCase.Valid {
+			if err != nil {
+				t.Error(idx+1, "unexpected error:", err)
+			}
+			continue
+		}
+		if cErr, ok := gctypes.AsCodedError(err); !ok || cErr.Code != gctypes.ErrorCodeInvalidAuthorizationExpiry {
+			t.Error(idx+1, "unexpected error:", err)
+		}
+	}
+}
+
+func TestExpiryFilter(t *testing.T) {
+	record := ExpiryRecord{Expiry: aetypes.Expiry{ValidUntilHeight: 100, ValidUntilTime: 1000}}
+	testCases := []struct {
+		Filter ExpiryFilter
+		Match  bool
+	}{
+		{ExpiryFilter{}, true},
+		{ExpiryFilter{Height: 99}, false},
+		{ExpiryFilter{Height: 100}, true},
+		{ExpiryFilter{Time: 999}, false},
+		{ExpiryFilter{Height: 99, Time: 1000}, true},
+	}
+	for idx, testCase := range testCases {
+		if match := testCase.Filter.Match(record); match != testCase.Match {
+			t.Error(idx+1, "unexpected match:", match)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, address types.UnlockHash) []byte {
+	b, err := rivbin.Marshal(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// ExpiryVersion is the version of the (binary) authorization expiry format,
+// encoded right after the expiry prefix in the arbitrary data of a transaction.
+const ExpiryVersion uint8 = 1
+
+// ExpiryPrefix is the prefix of the arbitrary data of an auth address update transaction,
+// identifying that the arbitrary data carries a (binary encoded) authorization expiry.
+var ExpiryPrefix = []byte("GFTAEX")
+
+// Errors returned for authorization expiries which do not have a valid shape.
+var (
+	ErrNoExpiry        = errors.New("authorization expiry defines neither a block height nor a timestamp")
+	ErrUnknownVersion  = errors.New("unknown authorization expiry version")
+	ErrExpiryInThePast = errors.New("authorization expiry is not in the future")
+	ErrNoAuthAddresses = errors.New("authorization expiry requires at least one address to be authorized")
+)
+
+// Expiry defines until when the addresses authorized by an auth address update transaction remain authorized.
+// An authorization expires as soon as the block height or the block time (whichever is defined) is reached,
+// if both are defined the authorization expires at whichever comes first.
+type Expiry struct {
+	// ValidUntilHeight is the block height as of which the authorization is expired, 0 if not defined
+	ValidUntilHeight types.BlockHeight `json:"validuntilheight,omitempty"`
+	// ValidUntilTime is the block time as of which the authorization is expired, 0 if not defined
+	ValidUntilTime types.Timestamp `json:"validuntiltime,omitempty"`
+}
+
+// Defined returns true if the expiry defines a block height or timestamp.
+func (e Expiry) Defined() bool {
+	return e.ValidUntilHeight != 0 || e.ValidUntilTime != 0
+}
+
+// Validate ensures the expiry has a valid shape.
+func (e Expiry) Validate() error {
+	if !e.Defined() {
+		return ErrNoExpiry
+	}
+	return nil
+}
+
+// String returns the expiry in a human-readable format.
+func (e Expiry) String() string {
+	var strs []string
+	if e.ValidUntilHeight != 0 {
+		strs = append(strs, fmt.Sprintf("block height %d", e.ValidUntilHeight))
+	}
+	if e.ValidUntilTime != 0 {
+		strs = append(strs, "block time "+time.Unix(int64(e.ValidUntilTime), 0).UTC().Format(time.RFC3339))
+	}
+	if len(strs) == 0 {
+		return "never"
+	}
+	return strings.Join(strs, " or ")
+}
+
+// Expired returns true if the authorization is expired at the given block height and time.
+// An undefined expiry never expires.
+func (e Expiry) Expired(height types.BlockHeight, timestamp types.Timestamp) bool {
+	return (e.ValidUntilHeight != 0 && height >= e.ValidUntilHeight) ||
+		(e.ValidUntilTime != 0 && timestamp >= e.ValidUntilTime)
+}
+
+// MarshalArbitraryData encodes the expiry as the arbitrary data of a transaction.
+func (e Expiry) MarshalArbitraryData() ([]byte, error) {
+	b, err := rivbin.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rivbin marshal authorization expiry: %v", err)
+	}
+	data := make([]byte, 0, len(ExpiryPrefix)+1+len(b))
+	data = append(data, ExpiryPrefix...)
+	data = append(data, ExpiryVersion)
+	return append(data, b...), nil
+}
+
+// IsExpiryArbitraryData returns true if the given arbitrary data carries an authorization expiry,
+// which does not mean the expiry can be decoded or has a valid shape.
+func IsExpiryArbitraryData(data []byte) bool {
+	return bytes.HasPrefix(data, ExpiryPrefix)
+}
+
+// UnmarshalExpiryArbitraryData decodes the authorization expiry carried by the given arbitrary data.
+func UnmarshalExpiryArbitraryData(data []byte) (Expiry, error) {
+	if !IsExpiryArbitraryData(data) {
+		return Expiry{}, errors.New("arbitrary data does not carry an authorization expiry")
+	}
+	data = data[len(ExpiryPrefix):]
+	if len(data) == 0 || data[0] != ExpiryVersion {
+		return Expiry{}, ErrUnknownVersion
+	}
+	reader := bytes.NewReader(data[1:])
+	var e Expiry
+	err := rivbin.NewDecoder(reader).Decode(&e)
+	if err != nil {
+		return Expiry{}, fmt.Errorf("failed to rivbin unmarshal authorization expiry: %v", err)
+	}
+	if reader.Len() != 0 {
+		return Expiry{}, fmt.Errorf("authorization expiry is followed by %d unexpected bytes", reader.Len())
+	}
+	return e, nil
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+func TestExpiryArbitraryDataRoundTrip(t *testing.T) {
+	expiry := Expiry{
+		ValidUntilHeight: 525600,
+		ValidUntilTime:   1798761600,
+	}
+	data, err := expiry.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an expiry has to fit within the arbitrary data size limit of the chain
+	if len(data) > 83 {
+		t.Fatalf("expiry (%d bytes) exceeds the arbitrary data size limit of the chain", len(data))
+	}
+	if !IsExpiryArbitraryData(data) {
+		t.Fatal("expected arbitrary data to carry an authorization expiry")
+	}
+	decoded, err := UnmarshalExpiryArbitraryData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != expiry {
+		t.Fatalf("%v != %v", expiry, decoded)
+	}
+
+	if IsExpiryArbitraryData([]byte("KYC batch 2026-10")) {
+		t.Error("expected description not to carry an authorization expiry")
+	}
+	for idx, invalid := range [][]byte{
+		append([]byte(nil), data[:len(data)-1]...),
+		append(append([]byte(nil), data...), 0),
+		append(append([]byte(nil), ExpiryPrefix...), ExpiryVersion+1),
+		append([]byte(nil), ExpiryPrefix...),
+	} {
+		if _, err = UnmarshalExpiryArbitraryData(invalid); err == nil {
+			t.Error(idx+1, "expected decoding to fail")
+		}
+	}
+}
+
+func TestExpiryExpired(t *testing.T) {
+	testCases := []struct {
+		Expiry  Expiry
+		Height  types.BlockHeight
+		Time    types.Timestamp
+		Expired bool
+	}{
+		{Expiry{}, 1e6, 1e10, false},
+		{Expiry{ValidUntilHeight: 100}, 99, 1e10, false},
+		{Expiry{ValidUntilHeight: 100}, 100, 0, true},
+		{Expiry{ValidUntilTime: 1000}, 1e6, 999, false},
+		{Expiry{ValidUntilTime: 1000}, 0, 1000, true},
+		{Expiry{ValidUntilHeight: 100, ValidUntilTime: 1000}, 100, 999, true},
+		{Expiry{ValidUntilHeight: 100, ValidUntilTime: 1000}, 99, 1000, true},
+		{Expiry{ValidUntilHeight: 100, ValidUntilTime: 1000}, 99, 999, false},
+	}
+	for idx, testCase := range testCases {
+		if expired := testCase.Expiry.Expired(testCase.Height, testCase.Time); expired != testCase.Expired {
+			t.Error(idx+1, "unexpected expired state:", expired)
+		}
+	}
+	if err := (Expiry{}).Validate(); err != ErrNoExpiry {
+		t.Error("unexpected error for undefined expiry:", err)
+	}
+}
@@ -59,6 +59,23 @@ func SpendableAmountAfterXSeconds(c types.Currency, seconds types.Timestamp) typ
 	return types.NewCurrency(x)
 }
 
+// AmountRequiredForSpendableAmountAfterXSeconds computes the value required to have at least
+// the given spendable amount left over, after removing the custody fee to be paid for the given x seconds.
+// It is the inverse of `SpendableAmountAfterXSeconds`, and can be used to pre-fund the custody fee
+// of a coin output which is to be spent only after the given x seconds.
+func AmountRequiredForSpendableAmountAfterXSeconds(spendable types.Currency, seconds types.Timestamp) types.Currency {
+	// approach the required value from below, adding the amount still missing in each step,
+	// which converges quickly given the custody fee is only a small fraction of the value
+	c := spendable
+	for {
+		value := SpendableAmountAfterXSeconds(c, seconds)
+		if value.Cmp(spendable) >= 0 {
+			return c
+		}
+		c = c.Add(spendable.Sub(value))
+	}
+}
+
 var (
 	// ratio for seconds accuracy
 	ratioSecDenom = big.NewInt(3456000000)
@@ -48,6 +48,36 @@ func TestAmountCustodyFeePairAfterXSeconds(t *testing.T) {
 	}
 }
 
+func TestAmountRequiredForSpendableAmountAfterXSeconds(t *testing.T) {
+	testCases := []struct {
+		SpendableValue types.Currency
+		Duration       types.Timestamp
+	}{
+		{gft("0"), 500},
+		{gft("1"), 0},
+		{gft("1"), 50},
+		{gft("100"), 24 * 60 * 60},
+		{gft("40000"), 48 * 60 * 60},
+		{gft("35000.853"), 5404},
+		{gft("500000000000"), 365 * 24 * 60 * 60},
+	}
+	for testIndex, testCase := range testCases {
+		value := AmountRequiredForSpendableAmountAfterXSeconds(testCase.SpendableValue, testCase.Duration)
+		if spendable := SpendableAmountAfterXSeconds(value, testCase.Duration); spendable.Cmp(testCase.SpendableValue) < 0 {
+			t.Errorf("test case #%d: required value %s leaves only %s spendable, while %s was expected",
+				testIndex+1, gfts(value), gfts(spendable), gfts(testCase.SpendableValue))
+		}
+		if value.IsZero() {
+			continue
+		}
+		// one unit less should not suffice
+		if spendable := SpendableAmountAfterXSeconds(value.Sub(types.NewCurrency64(1)), testCase.Duration); spendable.Cmp(testCase.SpendableValue) >= 0 {
+			t.Errorf("test case #%d: required value %s is not the minimum value required, as %s is spendable for one unit less",
+				testIndex+1, gfts(value), gfts(spendable))
+		}
+	}
+}
+
 func BenchmarkAmountCustodyFeePairAfterXSeconds(b *testing.B) {
 	var (
 		c                 = gft("987432348584948439232921.493929483")
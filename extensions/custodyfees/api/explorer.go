@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -24,12 +25,36 @@ type (
 		SpentTokens     types.Currency `json:"spenttokens"`
 		PaidCustodyFees types.Currency `json:"paidcustodyfees"`
 	}
+
+	// SupplyGet is the response of the supply Get explorer endpoint,
+	// reconciling the supply components of the chain at a given height
+	SupplyGet struct {
+		Height types.BlockHeight `json:"height"`
+		Time   types.Timestamp   `json:"time"`
+
+		GenesisTokens     types.Currency `json:"genesistokens"`
+		MintedTokens      types.Currency `json:"mintedtokens"`
+		BlockRewardTokens types.Currency `json:"blockrewardtokens"`
+		BurnedTokens      types.Currency `json:"burnedtokens"`
+		IssuedTokens      types.Currency `json:"issuedtokens"`
+
+		CustodyFeeTokens      types.Currency `json:"custodyfeetokens"`
+		PaidCustodyFees       types.Currency `json:"paidcustodyfees"`
+		TotalCustodyFeeDebt   types.Currency `json:"totalcustodyfeedebt"`
+		SpendableTokens       types.Currency `json:"spendabletokens"`
+		SpendableLockedTokens types.Currency `json:"spendablelockedtokens"`
+		CirculatingTokens     types.Currency `json:"circulatingtokens"`
+
+		InvariantValid bool   `json:"invariantvalid"`
+		InvariantError string `json:"invarianterror,omitempty"`
+	}
 )
 
 // RegisterExplorerCustodyFeesHTTPHandlers registers the default explorer HTTP handlers specific to the custodyfees package.
 func RegisterExplorerCustodyFeesHTTPHandlers(router rapi.Router, cs modules.ConsensusSet, plugin *custodyfees.Plugin, explorer *cfexplorer.Explorer) {
 	router.GET("/explorer/custodyfees/coinoutput/:id", NewCoinOutputInfoGetHandler(cs, plugin))
 	router.GET("/explorer/custodyfees/metrics/chain", NewChainFactsGetHandler(explorer))
+	router.GET("/explorer/supply", NewSupplyGetHandler(explorer))
 }
 
 // NewChainFactsGetHandler creates a handler to handle the API calls to /explorer/custodyfees/metrics/chain.
@@ -53,3 +78,58 @@ func NewChainFactsGetHandler(explorer *cfexplorer.Explorer) httprouter.Handle {
 		})
 	}
 }
+
+// NewSupplyGetHandler creates a handler to handle the API calls to /explorer/supply?height=0,
+// reporting the supply of the latest block if no height is given.
+func NewSupplyGetHandler(explorer *cfexplorer.Explorer) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var (
+			supply cfexplorer.SupplyFacts
+			facts  cfexplorer.ChainFacts
+			err    error
+		)
+		if heightStr := req.URL.Query().Get("height"); heightStr != "" {
+			var height types.BlockHeight
+			_, err = fmt.Sscan(heightStr, &height)
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: "failed to parse height query param: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			supply, facts, err = explorer.SupplyFactsAt(height)
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusNotFound)
+				return
+			}
+		} else {
+			supply, facts, err = explorer.LatestSupplyFacts()
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+				return
+			}
+		}
+		resp := SupplyGet{
+			Height: supply.Height,
+			Time:   supply.Time,
+
+			GenesisTokens:     supply.GenesisTokens,
+			MintedTokens:      supply.MintedTokens,
+			BlockRewardTokens: supply.BlockRewardTokens,
+			BurnedTokens:      supply.BurnedTokens,
+			IssuedTokens:      supply.IssuedTokens(),
+
+			CustodyFeeTokens:      supply.CustodyFeeTokens,
+			PaidCustodyFees:       facts.PaidCustodyFees,
+			TotalCustodyFeeDebt:   facts.TotalCustodyFeeDebt,
+			SpendableTokens:       facts.SpendableTokens,
+			SpendableLockedTokens: facts.SpendableLockedTokens,
+			CirculatingTokens:     facts.SpendableTokens.Add(facts.SpendableLockedTokens),
+
+			InvariantValid: true,
+		}
+		if err = cfexplorer.CheckSupplyInvariant(supply, facts); err != nil {
+			resp.InvariantValid = false
+			resp.InvariantError = err.Error()
+		}
+		rapi.WriteJSON(w, resp)
+	}
+}
@@ -40,12 +40,28 @@ func CreateExplorerSubCmds(ccli *rivinecli.CommandLineClient) error {
 			Short: "Get the latest Chain Facts",
 			Run:   rivinecli.Wrap(explorerSubCmds.getChainFacts),
 		}
+		getSupplyCmd = &cobra.Command{
+			Use:   "supply",
+			Short: "Get the supply reconciliation report of the latest block, or a given block height",
+			Long: `Get the supply reconciliation report of the latest block, or a given block height.
+
+The report lists the tokens issued as part of the genesis block, minted by coin creation transactions
+and rewarded to block creators, the tokens burned by coin destruction transactions,
+the custody fees paid and locked in custody fee outputs, the custody fee debt of all unspent coin outputs,
+and the circulating (spendable) supply.
+
+The report is validated by checking that the issued tokens (minus the burned tokens) equal the sum of the
+spendable tokens, custody fee debt and paid custody fees, exiting with a non-zero exit code if the check fails.
+`,
+			Run: rivinecli.Wrap(explorerSubCmds.getSupply),
+		}
 	)
 
 	// add commands as explorer sub commands
 	ccli.ExploreCmd.AddCommand(
 		getCoinOutputInfoCmd,
 		getChainFactsCmd,
+		getSupplyCmd,
 	)
 
 	// register flags
@@ -64,6 +80,12 @@ func CreateExplorerSubCmds(ccli *rivinecli.CommandLineClient) error {
 	getChainFactsCmd.Flags().Var(
 		cli.NewEncodingTypeFlag(0, &explorerSubCmds.getChainFactsCfg.EncodingType, 0), "encoding",
 		cli.EncodingTypeFlagDescription(0))
+	getSupplyCmd.Flags().Int64Var(
+		&explorerSubCmds.getSupplyCfg.Height, "height", -1,
+		"get the supply reconciliation report of a specific block height, instead of the latest block")
+	getSupplyCmd.Flags().Var(
+		cli.NewEncodingTypeFlag(0, &explorerSubCmds.getSupplyCfg.EncodingType, 0), "encoding",
+		cli.EncodingTypeFlagDescription(0))
 
 	return nil
 }
@@ -80,6 +102,10 @@ type explorerSubCmds struct {
 	getChainFactsCfg struct {
 		EncodingType cli.EncodingType
 	}
+	getSupplyCfg struct {
+		Height       int64
+		EncodingType cli.EncodingType
+	}
 }
 
 func (explorerSubCmds *explorerSubCmds) getCoinOutputInfo(str string) {
@@ -164,3 +190,43 @@ func (explorerSubCmds *explorerSubCmds) getChainFacts() {
 		cli.DieWithError("failed to encode coin output info", err)
 	}
 }
+
+func (explorerSubCmds *explorerSubCmds) getSupply() {
+	endpoint := "/explorer/supply"
+	if explorerSubCmds.getSupplyCfg.Height >= 0 {
+		endpoint += fmt.Sprintf("?height=%d", explorerSubCmds.getSupplyCfg.Height)
+	}
+	var result api.SupplyGet
+	err := explorerSubCmds.cli.GetWithResponse(endpoint, &result)
+	if err != nil {
+		cli.DieWithError("failed to get supply reconciliation report", err)
+		return
+	}
+
+	// encode depending on the encoding flag
+	var encode func(interface{}) error
+	switch explorerSubCmds.getSupplyCfg.EncodingType {
+	case cli.EncodingTypeHuman:
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		encode = e.Encode
+	case cli.EncodingTypeJSON:
+		encode = json.NewEncoder(os.Stdout).Encode
+	case cli.EncodingTypeHex:
+		encode = func(v interface{}) error {
+			b, err := rivbin.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Println(hex.EncodeToString(b))
+			return nil
+		}
+	}
+	err = encode(result)
+	if err != nil {
+		cli.DieWithError("failed to encode supply reconciliation report", err)
+	}
+	if !result.InvariantValid {
+		cli.DieWithExitCode(cli.ExitCodeGeneral, "supply invariant check failed:", result.InvariantError)
+	}
+}
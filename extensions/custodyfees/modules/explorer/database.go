@@ -1,6 +1,8 @@
 package explorer
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
@@ -14,6 +16,8 @@ var (
 	// keys for bucketInternal
 	internalBlockHeight  = []byte("BlockHeight")
 	internalRecentChange = []byte("RecentChange")
+	// the version of the supply and chain facts history, explorers synced using an older version are resynced
+	internalHistoryVersion = []byte("HistoryVersion")
 
 	bucketMetrics = []byte("Metrics")
 
@@ -21,8 +25,16 @@ var (
 
 	bucketUnspentCoinOutputs = []byte("UnspentCoinOutputs")
 	bucketSpentCoinOutputs   = []byte("SpentCoinOutputs")
+
+	// supply facts and chain facts, stored by block height
+	bucketSupplyHistory     = []byte("SupplyHistory")
+	bucketChainFactsHistory = []byte("ChainFactsHistory")
 )
 
+// historyVersion is the current version of the supply and chain facts history,
+// version 1 being the first version recording the chain facts of every block.
+const historyVersion uint64 = 1
+
 // dbSetInternal sets the specified key of bucketInternal to the encoded value.
 func dbSetInternal(key []byte, val interface{}) func(*bolt.Tx) error {
 	return func(tx *bolt.Tx) error {
@@ -154,6 +166,32 @@ func dbGetChainFactsData(bucket *bolt.Bucket, facts *ChainFacts) error {
 	return rivbin.Unmarshal(bucket.Get(keyMetricChainFacts), facts)
 }
 
+func dbSetHistory(bucket *bolt.Bucket, height types.BlockHeight, v interface{}) error {
+	b, err := rivbin.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to (rivbin) marshal facts of block height %d: %v", height, err)
+	}
+	return bucket.Put(encodeBlockHeight(height), b)
+}
+
+func dbGetHistory(bucket *bolt.Bucket, height types.BlockHeight, v interface{}) error {
+	b := bucket.Get(encodeBlockHeight(height))
+	if len(b) == 0 {
+		return errors.New("not found")
+	}
+	return rivbin.Unmarshal(b, v)
+}
+
+func dbDeleteHistory(bucket *bolt.Bucket, height types.BlockHeight) error {
+	return bucket.Delete(encodeBlockHeight(height))
+}
+
+func encodeBlockHeight(height types.BlockHeight) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(height))
+	return b
+}
+
 // ChainFacts collects all chain facts as one structure.
 type ChainFacts struct {
 	Height types.BlockHeight
@@ -42,6 +42,33 @@ func (e *Explorer) initPersist(verbose bool) error {
 
 	// Initialize the database
 	err = e.db.Update(func(tx *bolt.Tx) error {
+		// an explorer synced prior to the (complete) supply and chain facts history being recorded
+		// is reset, such that it resyncs and records the history of all blocks
+		if internalBucket := tx.Bucket(bucketInternal); internalBucket != nil {
+			var version uint64
+			if b := internalBucket.Get(internalHistoryVersion); b != nil {
+				err := rivbin.Unmarshal(b, &version)
+				if err != nil {
+					return err
+				}
+			}
+			if version < historyVersion {
+				e.log.Println("[INFO] resetting the explorer database, as it was synced without (complete) supply and chain facts history")
+				for _, bucket := range [][]byte{
+					bucketInternal, bucketMetrics, bucketUnspentCoinOutputs, bucketSpentCoinOutputs,
+					bucketSupplyHistory, bucketChainFactsHistory,
+				} {
+					if tx.Bucket(bucket) == nil {
+						continue
+					}
+					err := tx.DeleteBucket(bucket)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+
 		internalBucket, err := tx.CreateBucketIfNotExists(bucketInternal)
 		if err != nil {
 			return err
@@ -56,11 +83,16 @@ func (e *Explorer) initPersist(verbose bool) error {
 		if err != nil {
 			return err
 		}
+		historyVersionBytes, err := rivbin.Marshal(historyVersion)
+		if err != nil {
+			return err
+		}
 		internalDefaults := []struct {
 			key, val []byte
 		}{
 			{internalBlockHeight, blockHeightBytes},
 			{internalRecentChange, consensusChangeIDBytes},
+			{internalHistoryVersion, historyVersionBytes},
 		}
 		for _, d := range internalDefaults {
 			if internalBucket.Get(d.key) != nil {
@@ -107,6 +139,16 @@ func (e *Explorer) initPersist(verbose bool) error {
 			return err
 		}
 
+		_, err = tx.CreateBucketIfNotExists(bucketSupplyHistory)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(bucketChainFactsHistory)
+		if err != nil {
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -0,0 +1,174 @@
+package explorer
+
+import (
+	"errors"
+	"fmt"
+
+	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// ErrNoChainFacts is returned when no chain facts are recorded for a block height,
+// which is the case for heights that are not synced yet.
+var ErrNoChainFacts = errors.New("no chain facts recorded at block height")
+
+// SupplyFacts collects the cumulative supply components of the chain, up to and including a block height.
+type SupplyFacts struct {
+	Height types.BlockHeight
+	Time   types.Timestamp
+
+	// GenesisTokens is the value of all coin outputs of the genesis block
+	GenesisTokens types.Currency
+	// MintedTokens is the value of all coin outputs and miner fees of coin creation transactions
+	MintedTokens types.Currency
+	// BlockRewardTokens is the value of all miner payouts, minus the transaction fees they pay out
+	BlockRewardTokens types.Currency
+	// BurnedTokens is the value destroyed by coin destruction transactions
+	BurnedTokens types.Currency
+	// CustodyFeeTokens is the value of all custody fee outputs, locked forever as paid custody fees
+	CustodyFeeTokens types.Currency
+}
+
+// IssuedTokens returns the value of all tokens ever issued, minus the value of all tokens burned.
+func (supply SupplyFacts) IssuedTokens() types.Currency {
+	return supply.GenesisTokens.Add(supply.MintedTokens).Add(supply.BlockRewardTokens).Sub(supply.BurnedTokens)
+}
+
+// CheckSupplyInvariant ensures the supply components of the given supply facts sum up to the
+// spendable supply (liquid and locked), custody fee debt and paid custody fees of the given chain facts,
+// both recorded for the same block height.
+func CheckSupplyInvariant(supply SupplyFacts, facts ChainFacts) error {
+	if supply.Height != facts.Height {
+		return fmt.Errorf("supply facts of height %d cannot be checked against chain facts of height %d", supply.Height, facts.Height)
+	}
+	unspent := facts.SpendableTokens.Add(facts.SpendableLockedTokens).Add(facts.TotalCustodyFeeDebt)
+	if issued := supply.IssuedTokens(); !issued.Equals(unspent.Add(supply.CustodyFeeTokens)) {
+		return fmt.Errorf(
+			"issued tokens (%s) do not equal the sum of spendable tokens, custody fee debt and custody fees (%s)",
+			issued.String(), unspent.Add(supply.CustodyFeeTokens).String())
+	}
+	if !facts.PaidCustodyFees.Equals(supply.CustodyFeeTokens) {
+		return fmt.Errorf(
+			"paid custody fees (%s) do not equal the value locked in custody fee outputs (%s)",
+			facts.PaidCustodyFees.String(), supply.CustodyFeeTokens.String())
+	}
+	return nil
+}
+
+// SupplyFactsAt returns the supply facts and chain facts recorded for the given block height.
+func (e *Explorer) SupplyFactsAt(height types.BlockHeight) (supply SupplyFacts, facts ChainFacts, err error) {
+	err = e.db.View(func(tx *bolt.Tx) error {
+		err := dbGetHistory(tx.Bucket(bucketSupplyHistory), height, &supply)
+		if err != nil {
+			return fmt.Errorf("no supply facts recorded at block height %d: %v", height, err)
+		}
+		err = dbGetHistory(tx.Bucket(bucketChainFactsHistory), height, &facts)
+		if err != nil {
+			return fmt.Errorf("%v %d: %v", ErrNoChainFacts, height, err)
+		}
+		return nil
+	})
+	return
+}
+
+// LatestSupplyFacts returns the supply facts and chain facts recorded for the latest block.
+func (e *Explorer) LatestSupplyFacts() (SupplyFacts, ChainFacts, error) {
+	facts, err := e.LatestChainFacts()
+	if err != nil {
+		return SupplyFacts{}, ChainFacts{}, err
+	}
+	return e.SupplyFactsAt(facts.Height)
+}
+
+// updateSupplyHistory removes the supply facts of all reverted blocks,
+// and adds the supply facts of all applied blocks, given the amount of blocks prior to the consensus change.
+func (e *Explorer) updateSupplyHistory(tx *bolt.Tx, cc modules.ConsensusChange, blockheight types.BlockHeight) error {
+	supplyBucket := tx.Bucket(bucketSupplyHistory)
+	if supplyBucket == nil {
+		return fmt.Errorf("corrupt Custody Fee Explorer: did not find bucket %s", string(bucketSupplyHistory))
+	}
+	for range cc.RevertedBlocks {
+		blockheight--
+		err := dbDeleteHistory(supplyBucket, blockheight)
+		if err != nil {
+			return err
+		}
+	}
+
+	var supply SupplyFacts
+	if blockheight > 0 {
+		err := dbGetHistory(supplyBucket, blockheight-1, &supply)
+		if err != nil {
+			return fmt.Errorf("failed to get supply facts of block height %d: %v", blockheight-1, err)
+		}
+	}
+	return e.plugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+		for _, block := range cc.AppliedBlocks {
+			err := applyBlockSupply(view, &supply, block, blockheight)
+			if err != nil {
+				return err
+			}
+			err = dbSetHistory(supplyBucket, blockheight, supply)
+			if err != nil {
+				return err
+			}
+			blockheight++
+		}
+		return nil
+	})
+}
+
+// applyBlockSupply adds the supply components of the given block, applied at the given height, to the supply facts.
+func applyBlockSupply(view custodyfees.CoinOutputInfoView, supply *SupplyFacts, block types.Block, height types.BlockHeight) error {
+	supply.Height = height
+	supply.Time = block.Timestamp
+
+	var payouts, minerFees types.Currency
+	for _, mp := range block.MinerPayouts {
+		payouts = payouts.Add(mp.Value)
+	}
+	for _, txn := range block.Transactions {
+		var outputs, fees types.Currency
+		for _, co := range txn.CoinOutputs {
+			outputs = outputs.Add(co.Value)
+			if co.Condition.ConditionType() == cftypes.ConditionTypeCustodyFee {
+				supply.CustodyFeeTokens = supply.CustodyFeeTokens.Add(co.Value)
+			}
+		}
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+		minerFees = minerFees.Add(fees)
+
+		switch {
+		case height == 0:
+			supply.GenesisTokens = supply.GenesisTokens.Add(outputs)
+		case txn.Version == gctypes.TransactionVersionCoinCreation:
+			supply.MintedTokens = supply.MintedTokens.Add(outputs).Add(fees)
+		case txn.Version == gctypes.TransactionVersionCoinDestruction:
+			var inputs types.Currency
+			for _, ci := range txn.CoinInputs {
+				info, err := view.GetCoinOutputInfoPreComputation(ci.ParentID)
+				if err != nil {
+					return fmt.Errorf("failed to get info for burned coin output %s: %v", ci.ParentID.String(), err)
+				}
+				inputs = inputs.Add(info.CreationValue)
+			}
+			if inputs.Cmp(outputs.Add(fees)) < 0 {
+				return fmt.Errorf("coin destruction transaction %s creates more value than it spends", txn.ID().String())
+			}
+			supply.BurnedTokens = supply.BurnedTokens.Add(inputs.Sub(outputs).Sub(fees))
+		}
+	}
+	// miner fees are paid out as part of the miner payouts, and are thus no block reward
+	if payouts.Cmp(minerFees) < 0 {
+		return fmt.Errorf("miner payouts of block %s do not pay out all miner fees", block.ID().String())
+	}
+	supply.BlockRewardTokens = supply.BlockRewardTokens.Add(payouts.Sub(minerFees))
+	return nil
+}
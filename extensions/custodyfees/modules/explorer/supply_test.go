@@ -0,0 +1,140 @@
+package explorer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+type stubCoinOutputInfoView map[types.CoinOutputID]types.Currency
+
+// GetCoinOutputInfo returns the info of a coin output, which pays a custody fee of a single unit
+func (view stubCoinOutputInfoView) GetCoinOutputInfo(id types.CoinOutputID, chainTime types.Timestamp) (custodyfees.CoinOutputInfo, error) {
+	value, ok := view[id]
+	if !ok {
+		return custodyfees.CoinOutputInfo{}, fmt.Errorf("unknown coin output %s", id.String())
+	}
+	return custodyfees.CoinOutputInfo{
+		CreationValue:      value,
+		FeeComputationTime: chainTime,
+		CustodyFee:         types.NewCurrency64(1),
+		SpendableValue:     value.Sub(types.NewCurrency64(1)),
+	}, nil
+}
+
+func (view stubCoinOutputInfoView) GetCoinOutputInfoPreComputation(id types.CoinOutputID) (custodyfees.CoinOutputInfoPreComputation, error) {
+	value, ok := view[id]
+	if !ok {
+		return custodyfees.CoinOutputInfoPreComputation{}, fmt.Errorf("unknown coin output %s", id.String())
+	}
+	return custodyfees.CoinOutputInfoPreComputation{CreationValue: value, Spent: true}, nil
+}
+
+func TestApplyBlockSupply(t *testing.T) {
+	co := func(value uint64) types.CoinOutput {
+		return types.CoinOutput{Value: types.NewCurrency64(value), Condition: types.NewCondition(&types.NilCondition{})}
+	}
+	cfo := func(value uint64) types.CoinOutput {
+		return types.CoinOutput{Value: types.NewCurrency64(value), Condition: types.NewCondition(&cftypes.CustodyFeeCondition{})}
+	}
+	view := stubCoinOutputInfoView{
+		types.CoinOutputID{1}: types.NewCurrency64(100),
+		types.CoinOutputID{2}: types.NewCurrency64(50),
+	}
+
+	var supply SupplyFacts
+	// genesis block
+	err := applyBlockSupply(view, &supply, types.Block{
+		Timestamp: 1,
+		Transactions: []types.Transaction{
+			{Version: types.TransactionVersionOne, CoinOutputs: []types.CoinOutput{co(1000)}},
+		},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// block with a coin creation, coin destruction and regular transaction
+	err = applyBlockSupply(view, &supply, types.Block{
+		Timestamp:    2,
+		MinerPayouts: []types.MinerPayout{{Value: types.NewCurrency64(13)}},
+		Transactions: []types.Transaction{
+			{
+				Version:     gctypes.TransactionVersionCoinCreation,
+				CoinOutputs: []types.CoinOutput{co(200), co(300)},
+				MinerFees:   []types.Currency{types.NewCurrency64(1)},
+			},
+			{
+				Version:     gctypes.TransactionVersionCoinDestruction,
+				CoinInputs:  []types.CoinInput{{ParentID: types.CoinOutputID{1}}},
+				CoinOutputs: []types.CoinOutput{cfo(2), co(20)},
+				MinerFees:   []types.Currency{types.NewCurrency64(1)},
+			},
+			{
+				Version:     types.TransactionVersionOne,
+				CoinInputs:  []types.CoinInput{{ParentID: types.CoinOutputID{2}}},
+				CoinOutputs: []types.CoinOutput{cfo(1), co(48)},
+				MinerFees:   []types.Currency{types.NewCurrency64(1)},
+			},
+		},
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if supply.Height != 1 || supply.Time != 2 {
+		t.Fatalf("unexpected height %d and time %d", supply.Height, supply.Time)
+	}
+	for idx, pair := range []struct {
+		Value    types.Currency
+		Expected uint64
+	}{
+		{supply.GenesisTokens, 1000},
+		{supply.MintedTokens, 501},
+		{supply.BlockRewardTokens, 10},
+		{supply.BurnedTokens, 77},
+		{supply.CustodyFeeTokens, 3},
+	} {
+		if !pair.Value.Equals64(pair.Expected) {
+			t.Errorf("%d: %s != %d", idx+1, pair.Value.String(), pair.Expected)
+		}
+	}
+	if issued := supply.IssuedTokens(); !issued.Equals64(1434) {
+		t.Fatalf("unexpected issued tokens: %s", issued.String())
+	}
+}
+
+func TestCheckSupplyInvariant(t *testing.T) {
+	supply := SupplyFacts{
+		Height:           3,
+		GenesisTokens:    types.NewCurrency64(1000),
+		MintedTokens:     types.NewCurrency64(500),
+		BurnedTokens:     types.NewCurrency64(100),
+		CustodyFeeTokens: types.NewCurrency64(10),
+	}
+	facts := ChainFacts{
+		Height:                3,
+		SpendableTokens:       types.NewCurrency64(1200),
+		SpendableLockedTokens: types.NewCurrency64(180),
+		TotalCustodyFeeDebt:   types.NewCurrency64(10),
+		PaidCustodyFees:       types.NewCurrency64(10),
+	}
+	if err := CheckSupplyInvariant(supply, facts); err != nil {
+		t.Fatal(err)
+	}
+	for idx, modify := range []func(*SupplyFacts, *ChainFacts){
+		func(s *SupplyFacts, f *ChainFacts) { f.Height = 2 },
+		func(s *SupplyFacts, f *ChainFacts) { s.BurnedTokens = types.NewCurrency64(99) },
+		func(s *SupplyFacts, f *ChainFacts) { f.SpendableTokens = types.NewCurrency64(1199) },
+		func(s *SupplyFacts, f *ChainFacts) { f.PaidCustodyFees = types.NewCurrency64(9) },
+	} {
+		invalidSupply, invalidFacts := supply, facts
+		modify(&invalidSupply, &invalidFacts)
+		if err := CheckSupplyInvariant(invalidSupply, invalidFacts); err == nil {
+			t.Error(idx+1, "expected supply invariant check to fail")
+		}
+	}
+}
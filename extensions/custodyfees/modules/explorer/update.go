@@ -33,6 +33,12 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			return err
 		}
 
+		// update the supply history, prior to updating the block height
+		err = e.updateSupplyHistory(tx, cc, blockheight)
+		if err != nil {
+			return err
+		}
+
 		// get unspentCoinOutputsBucket and bucketSpentCoinOutputs to update it
 		ucoBucket := tx.Bucket(bucketUnspentCoinOutputs)
 		if ucoBucket == nil {
@@ -43,14 +49,20 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			return fmt.Errorf("corrupt Custody Fee Explorer: did not find bucket %s", string(bucketSpentCoinOutputs))
 		}
 
+		// get bucketMetrics and bucketChainFactsHistory to update them
+		metricsBucket := tx.Bucket(bucketMetrics)
+		if metricsBucket == nil {
+			return fmt.Errorf("corrupt Custody Fee Explorer: did not find bucket %s", string(bucketMetrics))
+		}
+		historyBucket := tx.Bucket(bucketChainFactsHistory)
+		if historyBucket == nil {
+			return fmt.Errorf("corrupt Custody Fee Explorer: did not find bucket %s", string(bucketChainFactsHistory))
+		}
+
 		var coid types.CoinOutputID
-		revertedCoinInputIDs := map[types.CoinOutputID]types.Timestamp{}
-		appliedCoinInputIDs := map[types.CoinOutputID]types.Timestamp{}
-		var blocktime types.Timestamp
 
-		// Update cumulative stats for reverted blocks.
+		// Revert the unspent coin outputs of reverted blocks.
 		for _, block := range cc.RevertedBlocks {
-			blocktime = block.Timestamp
 			for idx := range block.MinerPayouts {
 				coid = block.MinerPayoutID(uint64(idx))
 				err = dbDeleteUnspentCoinOutput(ucoBucket, coid)
@@ -64,7 +76,6 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 					if err != nil {
 						return err
 					}
-					revertedCoinInputIDs[ci.ParentID] = blocktime
 				}
 				for idx := range txn.CoinOutputs {
 					coid = txn.CoinOutputID(uint64(idx))
@@ -72,32 +83,65 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 				}
 			}
 			blockheight--
+			err = dbDeleteHistory(historyBucket, blockheight)
+			if err != nil {
+				return err
+			}
 		}
 
-		// Update cumulative stats for applied blocks.
-		for _, block := range cc.AppliedBlocks {
-			blocktime = block.Timestamp
-			for idx := range block.MinerPayouts {
-				coid = block.MinerPayoutID(uint64(idx))
-				err = dbSetUnspentCoinOutputWithLockTime(ucoBucket, coid, uint64(blockheight+e.chainCts.MaturityDelay))
-				if err != nil {
-					return err
-				}
+		// get the chain facts of the block prior to the first applied block,
+		// recorded in the history as chain facts are recorded for every applied block
+		var facts ChainFacts
+		if len(cc.RevertedBlocks) == 0 {
+			err = dbGetChainFactsData(metricsBucket, &facts)
+		} else if blockheight > 0 {
+			err = dbGetHistory(historyBucket, blockheight-1, &facts)
+			if err != nil {
+				err = fmt.Errorf("%v %d: %v", ErrNoChainFacts, blockheight-1, err)
 			}
-			for _, txn := range block.Transactions {
-				for _, ci := range txn.CoinInputs {
-					err = dbMarkCoinOutputSpent(ucoBucket, scoBucket, ci.ParentID)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Update cumulative stats for applied blocks, recording the chain facts of each block.
+		err = e.plugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+			for _, block := range cc.AppliedBlocks {
+				appliedCoinInputIDs := map[types.CoinOutputID]types.Timestamp{}
+				for idx := range block.MinerPayouts {
+					coid = block.MinerPayoutID(uint64(idx))
+					err = dbSetUnspentCoinOutputWithLockTime(ucoBucket, coid, uint64(blockheight+e.chainCts.MaturityDelay))
 					if err != nil {
 						return err
 					}
-					appliedCoinInputIDs[ci.ParentID] = blocktime
 				}
-				for idx, co := range txn.CoinOutputs {
-					coid = txn.CoinOutputID(uint64(idx))
-					err = dbSetUnspentCoinOutput(ucoBucket, coid, co)
+				for _, txn := range block.Transactions {
+					for _, ci := range txn.CoinInputs {
+						err = dbMarkCoinOutputSpent(ucoBucket, scoBucket, ci.ParentID)
+						if err != nil {
+							return err
+						}
+						appliedCoinInputIDs[ci.ParentID] = block.Timestamp
+					}
+					for idx, co := range txn.CoinOutputs {
+						coid = txn.CoinOutputID(uint64(idx))
+						err = dbSetUnspentCoinOutput(ucoBucket, coid, co)
+					}
 				}
+				err = e.applyBlockChainFacts(view, ucoBucket, &facts, blockheight, block.Timestamp, appliedCoinInputIDs)
+				if err != nil {
+					return err
+				}
+				err = dbSetHistory(historyBucket, blockheight, facts)
+				if err != nil {
+					return err
+				}
+				blockheight++
 			}
-			blockheight++
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		// set final blockheight
@@ -112,101 +156,69 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			return err
 		}
 
-		// get bucketMetrics to update it
-		metricsBucket := tx.Bucket(bucketMetrics)
-		if metricsBucket == nil {
-			return fmt.Errorf("corrupt Custody Fee Explorer: did not find bucket %s", string(bucketMetrics))
+		// set update chain stats
+		err = dbSetChainFactsData(metricsBucket, facts)
+		if err != nil {
+			return err
 		}
 
-		// get current chain stats
-		var facts ChainFacts
-		err = dbGetChainFactsData(metricsBucket, &facts)
+		// all good
+		return nil
+	})
+	if err != nil {
+		build.Critical("explorer update failed:", err)
+	}
+}
+
+// applyBlockChainFacts updates the chain facts of the previous block to the chain facts of the block
+// applied at the given height and time, given the coin outputs spent by the block and the unspent coin outputs after it.
+func (e *Explorer) applyBlockChainFacts(view custodyfees.CoinOutputInfoView, ucoBucket *bolt.Bucket, facts *ChainFacts, blockheight types.BlockHeight, blocktime types.Timestamp, appliedCoinInputIDs map[types.CoinOutputID]types.Timestamp) error {
+	// set height/time info
+	facts.Height = blockheight
+	facts.Time = blocktime
+	// add all aggregated spent/paid values
+	for coid, chainTime := range appliedCoinInputIDs {
+		info, err := view.GetCoinOutputInfo(coid, chainTime)
 		if err != nil {
 			return err
 		}
-
-		// compute new chain facts
-		// ... set height/time info
-		facts.Height = blockheight
-		if facts.Height > 0 {
-			facts.Height-- // we want latest block height, not amount of blocks
+		if info.FeeComputationTime != chainTime {
+			e.log.Printf("[WARN] unexpected fee computation time for applied spent coin output %s: %d != %d", coid.String(), info.FeeComputationTime, chainTime)
 		}
-		facts.Time = blocktime
-		// ... set aggregated currency info
-		err = e.plugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
-			var info custodyfees.CoinOutputInfo
-			// first subtract all aggregated spent/paid values
-			for coid, chainTime := range revertedCoinInputIDs {
-				info, err = view.GetCoinOutputInfo(coid, chainTime)
-				if err != nil {
-					return err
-				}
-				if info.FeeComputationTime != chainTime {
-					e.log.Printf("[WARN] unexpected fee computation time for reverted spent coin output %s: %d != %d", coid.String(), info.FeeComputationTime, chainTime)
-				}
-				facts.SpentTokens = facts.SpentTokens.Sub(info.SpendableValue)
-				facts.PaidCustodyFees = facts.PaidCustodyFees.Sub(info.CustodyFee)
-			}
-			// add all aggregated spent/paid values
-			for coid, chainTime := range appliedCoinInputIDs {
-				info, err = view.GetCoinOutputInfo(coid, chainTime)
-				if err != nil {
-					return err
-				}
-				if info.FeeComputationTime != chainTime {
-					e.log.Printf("[WARN] unexpected fee computation time for applied spent coin output %s: %d != %d", coid.String(), info.FeeComputationTime, chainTime)
-				}
-				facts.SpentTokens = facts.SpentTokens.Add(info.SpendableValue)
-				facts.PaidCustodyFees = facts.PaidCustodyFees.Add(info.CustodyFee)
+		facts.SpentTokens = facts.SpentTokens.Add(info.SpendableValue)
+		facts.PaidCustodyFees = facts.PaidCustodyFees.Add(info.CustodyFee)
+	}
+	// recalculate the liquid, locked (both spendable) and fee debt
+	facts.SpendableTokens = types.Currency{}
+	facts.SpendableLockedTokens = types.Currency{}
+	facts.TotalCustodyFeeDebt = types.Currency{}
+	return dbUnspentCoinOutputValidatorMap(ucoBucket, func(coid types.CoinOutputID, lockValue uint64) error {
+		// get locked state, the block height being the amount of blocks including the applied block
+		var locked bool
+		if lockValue > 0 {
+			if lockValue < types.LockTimeMinTimestampValue {
+				locked = types.BlockHeight(lockValue) > blockheight+1
+			} else {
+				locked = types.Timestamp(lockValue) > blocktime
 			}
-			// recalculate the liquid, locked (both spendable) and fee debt
-			facts.SpendableTokens = types.Currency{}
-			facts.SpendableLockedTokens = types.Currency{}
-			facts.TotalCustodyFeeDebt = types.Currency{}
-			return dbUnspentCoinOutputValidatorMap(ucoBucket, func(coid types.CoinOutputID, lockValue uint64) error {
-				// get locked state
-				var locked bool
-				if lockValue > 0 {
-					if lockValue < types.LockTimeMinTimestampValue {
-						locked = types.BlockHeight(lockValue) > blockheight
-					} else {
-						locked = types.Timestamp(lockValue) > blocktime
-					}
-				}
-				// get spendable and custody fee
-				info, err = view.GetCoinOutputInfo(coid, blocktime)
-				if err != nil {
-					return fmt.Errorf("failed to get info for unspent coin output %s at block time %d: %v", coid.String(), blocktime, err)
-				}
-				// log some stuff that are not critical but might slightly mess up the aggregated sats
-				if info.Spent {
-					e.log.Printf("[WARN] unexpected spent sate for coin output %s: will still be counted as unspent with wrong values for now", coid.String())
-				}
-				// update aggregated sats
-				facts.TotalCustodyFeeDebt = facts.TotalCustodyFeeDebt.Add(info.CustodyFee)
-				if locked {
-					facts.SpendableLockedTokens = facts.SpendableLockedTokens.Add(info.SpendableValue)
-				} else {
-					facts.SpendableTokens = facts.SpendableTokens.Add(info.SpendableValue)
-				}
-				// all good
-				return nil
-			})
-		})
-		if err != nil {
-			return err
 		}
-
-		// set update chain stats
-		err = dbSetChainFactsData(metricsBucket, facts)
+		// get spendable and custody fee
+		info, err := view.GetCoinOutputInfo(coid, blocktime)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to get info for unspent coin output %s at block time %d: %v", coid.String(), blocktime, err)
+		}
+		// log some stuff that are not critical but might slightly mess up the aggregated sats
+		if info.Spent {
+			e.log.Printf("[WARN] unexpected spent sate for coin output %s: will still be counted as unspent with wrong values for now", coid.String())
+		}
+		// update aggregated sats
+		facts.TotalCustodyFeeDebt = facts.TotalCustodyFeeDebt.Add(info.CustodyFee)
+		if locked {
+			facts.SpendableLockedTokens = facts.SpendableLockedTokens.Add(info.SpendableValue)
+		} else {
+			facts.SpendableTokens = facts.SpendableTokens.Add(info.SpendableValue)
 		}
-
 		// all good
 		return nil
 	})
-	if err != nil {
-		build.Critical("explorer update failed:", err)
-	}
 }
@@ -0,0 +1,178 @@
+package explorer
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/types"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+func newTestExplorer(t *testing.T) *Explorer {
+	e := &Explorer{
+		persistDir: build.TempDir("custodyfees", "explorer", t.Name()),
+		bcInfo:     types.DefaultBlockchainInfo(),
+	}
+	err := e.initPersist(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func (e *Explorer) closeTestPersist(t *testing.T) {
+	err := e.log.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equalChainFacts(a, b ChainFacts) bool {
+	return a.Height == b.Height && a.Time == b.Time &&
+		a.SpendableTokens.Equals(b.SpendableTokens) && a.SpendableLockedTokens.Equals(b.SpendableLockedTokens) &&
+		a.TotalCustodyFeeDebt.Equals(b.TotalCustodyFeeDebt) && a.SpentTokens.Equals(b.SpentTokens) &&
+		a.PaidCustodyFees.Equals(b.PaidCustodyFees)
+}
+
+// TestApplyBlockChainFacts checks that the chain facts are computed for each applied block,
+// rather than only for the last block of a consensus change.
+func TestApplyBlockChainFacts(t *testing.T) {
+	e := newTestExplorer(t)
+	defer e.closeTestPersist(t)
+
+	view := stubCoinOutputInfoView{
+		types.CoinOutputID{1}: types.NewCurrency64(100),
+		types.CoinOutputID{2}: types.NewCurrency64(50),
+		types.CoinOutputID{3}: types.NewCurrency64(30),
+	}
+	err := e.db.Update(func(tx *bolt.Tx) error {
+		ucoBucket, scoBucket := tx.Bucket(bucketUnspentCoinOutputs), tx.Bucket(bucketSpentCoinOutputs)
+		// unlocked, locked until block height 3 and locked until timestamp 2000
+		for coid, lockValue := range map[types.CoinOutputID]uint64{{1}: 0, {2}: 3, {3}: 2000} {
+			err := dbSetUnspentCoinOutputWithLockTime(ucoBucket, coid, lockValue)
+			if err != nil {
+				return err
+			}
+		}
+
+		var facts ChainFacts
+		err := e.applyBlockChainFacts(view, ucoBucket, &facts, 1, 1000, nil)
+		if err != nil {
+			return err
+		}
+		expected := ChainFacts{
+			Height:                1,
+			Time:                  1000,
+			SpendableTokens:       types.NewCurrency64(99),
+			SpendableLockedTokens: types.NewCurrency64(78),
+			TotalCustodyFeeDebt:   types.NewCurrency64(3),
+		}
+		if !equalChainFacts(facts, expected) {
+			t.Errorf("unexpected chain facts of the first block: %+v", facts)
+		}
+
+		// spend the unlocked coin output in the next block, which unlocks the coin output locked until block height 3
+		err = dbMarkCoinOutputSpent(ucoBucket, scoBucket, types.CoinOutputID{1})
+		if err != nil {
+			return err
+		}
+		err = e.applyBlockChainFacts(view, ucoBucket, &facts, 2, 1500, map[types.CoinOutputID]types.Timestamp{{1}: 1500})
+		if err != nil {
+			return err
+		}
+		expected = ChainFacts{
+			Height:                2,
+			Time:                  1500,
+			SpendableTokens:       types.NewCurrency64(49),
+			SpendableLockedTokens: types.NewCurrency64(29),
+			TotalCustodyFeeDebt:   types.NewCurrency64(2),
+			SpentTokens:           types.NewCurrency64(99),
+			PaidCustodyFees:       types.NewCurrency64(1),
+		}
+		if !equalChainFacts(facts, expected) {
+			t.Errorf("unexpected chain facts of the second block: %+v", facts)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInitPersistResetsHistory checks that an explorer synced without the complete
+// supply and chain facts history is reset, such that it resyncs all blocks.
+func TestInitPersistResetsHistory(t *testing.T) {
+	e := newTestExplorer(t)
+	populate := func(tx *bolt.Tx) error {
+		err := dbSetInternal(internalBlockHeight, types.BlockHeight(2))(tx)
+		if err != nil {
+			return err
+		}
+		err = dbSetUnspentCoinOutputWithLockTime(tx.Bucket(bucketUnspentCoinOutputs), types.CoinOutputID{1}, 0)
+		if err != nil {
+			return err
+		}
+		return dbSetHistory(tx.Bucket(bucketChainFactsHistory), 1, ChainFacts{Height: 1})
+	}
+	synced := func(tx *bolt.Tx) bool {
+		var height types.BlockHeight
+		err := dbGetInternal(internalBlockHeight, &height)(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var facts ChainFacts
+		return height == 2 && tx.Bucket(bucketUnspentCoinOutputs).Stats().KeyN == 1 &&
+			dbGetHistory(tx.Bucket(bucketChainFactsHistory), 1, &facts) == nil
+	}
+
+	// an explorer synced using the current history version is kept as is
+	err := e.db.Update(populate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.closeTestPersist(t)
+	if err = e.initPersist(false); err != nil {
+		t.Fatal(err)
+	}
+	err = e.db.View(func(tx *bolt.Tx) error {
+		if !synced(tx) {
+			t.Error("expected explorer not to be reset")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an explorer synced using an older history version is reset
+	err = e.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketInternal).Delete(internalHistoryVersion)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.closeTestPersist(t)
+	if err = e.initPersist(false); err != nil {
+		t.Fatal(err)
+	}
+	defer e.closeTestPersist(t)
+	err = e.db.View(func(tx *bolt.Tx) error {
+		if synced(tx) || tx.Bucket(bucketUnspentCoinOutputs).Stats().KeyN != 0 {
+			t.Error("expected explorer to be reset")
+		}
+		var version uint64
+		err := dbGetInternal(internalHistoryVersion, &version)(tx)
+		if err != nil || version != historyVersion {
+			t.Errorf("unexpected history version %d: %v", version, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/threefoldtech/rivine/types"
 
 	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 
 	bolt "github.com/rivine/bbolt"
 )
@@ -472,7 +473,42 @@ func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFu
 	}
 }
 
+// ValidateTransaction validates, against the current state of the plugin, that the given transaction pays
+// the required custody fee, using the same validator as the one applied by the consensus set.
+// Coin outputs created by the given unconfirmed transactions, which can be spent by the transaction,
+// are considered to be created at the block time of the transaction.
+func (p *Plugin) ValidateTransaction(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, unconfirmedCoinOutputs map[types.CoinOutputID]types.CoinOutput) error {
+	return p.storage.View(func(rootBucket *bolt.Bucket) error {
+		bucket := persist.NewLazyBoltBucket(func() (*bolt.Bucket, error) {
+			return rootBucket, nil
+		})
+		return p.validateCustodyFee(tx, bucket, func(coBucket *bolt.Bucket, id types.CoinOutputID, chainTime types.Timestamp) (CoinOutputInfo, error) {
+			if co, ok := unconfirmedCoinOutputs[id]; ok {
+				return UnconfirmedCoinOutputInfo(co, tx.BlockTime, chainTime), nil
+			}
+			return getCoinOutputInfo(coBucket, id, chainTime)
+		})
+	})
+}
+
+// UnconfirmedCoinOutputInfo returns the custody fee related coin output information for a coin output,
+// which is not yet confirmed, as it would be once created in a block with the given block time.
+func UnconfirmedCoinOutputInfo(co types.CoinOutput, blockTime, chainTime types.Timestamp) CoinOutputInfo {
+	_, isCustodyFee := co.Condition.Condition.(*cftypes.CustodyFeeCondition)
+	return computeCoinOutputInfo(CoinOutputInfoPreComputation{
+		CreationTime:  blockTime,
+		CreationValue: co.Value,
+		IsCustodyFee:  isCustodyFee,
+	}, chainTime)
+}
+
 func (p *Plugin) validateCustodyFeePresent(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+	return p.validateCustodyFee(tx, bucket, getCoinOutputInfo)
+}
+
+// validateCustodyFee validates that the given transaction pays the required custody fee,
+// using the given function to look up the info of the coin outputs spent by the transaction.
+func (p *Plugin) validateCustodyFee(tx modules.ConsensusTransaction, bucket *persist.LazyBoltBucket, getInfo func(coBucket *bolt.Bucket, id types.CoinOutputID, chainTime types.Timestamp) (CoinOutputInfo, error)) error {
 	if len(tx.CoinInputs) == 0 {
 		return nil // nothing to do
 	}
@@ -492,13 +528,15 @@ func (p *Plugin) validateCustodyFeePresent(tx modules.ConsensusTransaction, ctx
 			return fmt.Errorf("unexpected unlock condition for condition type %d", co.Condition.ConditionType())
 		}
 		if computationTime != 0 {
-			return errors.New("only one custody fee condition per Tx is allowed")
+			return gctypes.NewCodedError(gctypes.ErrorCodeCustodyFeeDuplicate,
+				errors.New("only one custody fee condition per Tx is allowed"), nil)
 		}
 		computationTime = cfc.ComputationTime
 		custodyFeeValue = co.Value
 	}
 	if computationTime == 0 {
-		return errors.New("tx does not contain the required coin output for the custody fee, while coin inputs are spent")
+		return gctypes.NewCodedError(gctypes.ErrorCodeCustodyFeeMissing,
+			errors.New("tx does not contain the required coin output for the custody fee, while coin inputs are spent"), nil)
 	}
 	if diff := tx.BlockTime - computationTime; tx.BlockTime > computationTime && diff > p.maxAllowedComputationTimeAdvance {
 		// try to go back in time and see if we can find a block with the matching timestamp,
@@ -528,9 +566,14 @@ func (p *Plugin) validateCustodyFeePresent(tx modules.ConsensusTransaction, ctx
 		if !matchingBlockFound {
 			// no matching block found within the allowed range,
 			// returning an error due to invalid computation time
-			return fmt.Errorf(
+			return gctypes.NewCodedError(gctypes.ErrorCodeComputationTimeTooOld, fmt.Errorf(
 				"custody fee is paid, computated based on a timestamp too far in the past: %ds too late and no matching block found",
-				diff-p.maxAllowedComputationTimeAdvance)
+				diff-p.maxAllowedComputationTimeAdvance), map[string]string{
+				"computationtime":   fmt.Sprintf("%d", computationTime),
+				"blocktime":         fmt.Sprintf("%d", tx.BlockTime),
+				"maxallowedadvance": fmt.Sprintf("%d", p.maxAllowedComputationTimeAdvance),
+				"maxfallbackblocks": fmt.Sprintf("%d", p.maxFallbackBlocksInThePast),
+			})
 		}
 	}
 
@@ -546,21 +589,27 @@ func (p *Plugin) validateCustodyFeePresent(tx modules.ConsensusTransaction, ctx
 	// ... look up each coin input in our plugin DB,
 	//     to check how much the fee will cost
 	for _, ci := range tx.CoinInputs {
-		info, err := getCoinOutputInfo(coBucket, ci.ParentID, computationTime)
+		info, err := getInfo(coBucket, ci.ParentID, computationTime)
 		if err != nil {
 			return err
 		}
 		if info.Spent {
-			return fmt.Errorf("coin output %s is already marked as spent in the custody fees DB: cannot be spend again", ci.ParentID.String())
+			return gctypes.NewCodedError(gctypes.ErrorCodeCoinOutputSpent, fmt.Errorf(
+				"coin output %s is already marked as spent in the custody fees DB: cannot be spend again", ci.ParentID.String()),
+				map[string]string{"coinoutputid": ci.ParentID.String()})
 		}
 		requiredCustodyFee = requiredCustodyFee.Add(info.CustodyFee)
 	}
 
 	// ensure the custody fee is exactly as expected
 	if !requiredCustodyFee.Equals(custodyFeeValue) {
-		return fmt.Errorf(
+		return gctypes.NewCodedError(gctypes.ErrorCodeCustodyFeeMismatch, fmt.Errorf(
 			"unexpected custody fee of value %s expected %s",
-			custodyFeeValue.String(), requiredCustodyFee.String())
+			custodyFeeValue.String(), requiredCustodyFee.String()), map[string]string{
+			"expected":        requiredCustodyFee.String(),
+			"provided":        custodyFeeValue.String(),
+			"computationtime": fmt.Sprintf("%d", computationTime),
+		})
 	}
 
 	// transaction is valid
@@ -596,16 +645,22 @@ func getCoinOutputInfoPreComputation(coBucket *bolt.Bucket, id types.CoinOutputI
 }
 
 func getCoinOutputInfo(coBucket *bolt.Bucket, id types.CoinOutputID, chainTime types.Timestamp) (CoinOutputInfo, error) {
-	var info CoinOutputInfo
 	preComputationInfo, err := getCoinOutputInfoPreComputation(coBucket, id)
 	if err != nil {
-		return info, err
+		return CoinOutputInfo{}, err
 	}
+	return computeCoinOutputInfo(preComputationInfo, chainTime), nil
+}
+
+// computeCoinOutputInfo computes the custody fee and spendable value of a coin output,
+// at the given chain time unless the coin output is already spent.
+func computeCoinOutputInfo(preComputationInfo CoinOutputInfoPreComputation, chainTime types.Timestamp) CoinOutputInfo {
+	var info CoinOutputInfo
 	info.CreationTime = preComputationInfo.CreationTime
 	info.CreationValue = preComputationInfo.CreationValue
 	info.IsCustodyFee = preComputationInfo.IsCustodyFee
 	if info.IsCustodyFee {
-		return info, err // no fee is required, and nothing of it is spendable
+		return info // no fee is required, and nothing of it is spendable
 	}
 	if preComputationInfo.FeeComputationTime == 0 {
 		if info.CreationTime > chainTime {
@@ -622,7 +677,7 @@ func getCoinOutputInfo(coBucket *bolt.Bucket, id types.CoinOutputID, chainTime t
 	} else {
 		info.SpendableValue = info.CreationValue
 	}
-	return info, nil
+	return info
 }
 
 // Close unregisters the plugin from the consensus
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nbh-digital/goldchain/extensions/proofofreserve"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/types"
+)
+
+type (
+	// AttestationsGet is the response of the minting attestations Get explorer endpoint
+	AttestationsGet struct {
+		Attestations []proofofreserve.AttestationRecord `json:"attestations"`
+	}
+
+	// AttestationGet is the response of the minting attestation Get explorer endpoint,
+	// returning the attestation of a single transaction
+	AttestationGet struct {
+		Attestation proofofreserve.AttestationRecord `json:"attestation"`
+	}
+)
+
+// RegisterExplorerProofOfReserveHTTPHandlers registers the default explorer HTTP handlers specific to the proofofreserve package.
+func RegisterExplorerProofOfReserveHTTPHandlers(router rapi.Router, plugin *proofofreserve.Plugin) {
+	router.GET("/explorer/mintingattestations", NewAttestationsGetHandler(plugin))
+	router.GET("/explorer/mintingattestations/:id", NewAttestationGetHandler(plugin))
+}
+
+// NewAttestationsGetHandler creates a handler to handle the API calls to /explorer/mintingattestations?vaultid=&type=.
+func NewAttestationsGetHandler(plugin *proofofreserve.Plugin) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		q := req.URL.Query()
+		filter := proofofreserve.AttestationFilter{
+			VaultID:         q.Get("vaultid"),
+			TransactionType: q.Get("type"),
+		}
+		switch filter.TransactionType {
+		case "", proofofreserve.TransactionTypeCoinCreation, proofofreserve.TransactionTypeCoinDestruction:
+		default:
+			rapi.WriteError(w, rapi.Error{Message: "invalid type query param '" + filter.TransactionType + "'"}, http.StatusBadRequest)
+			return
+		}
+		records, err := plugin.GetAttestations(filter)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []proofofreserve.AttestationRecord{}
+		}
+		rapi.WriteJSON(w, AttestationsGet{Attestations: records})
+	}
+}
+
+// NewAttestationGetHandler creates a handler to handle the API calls to /explorer/mintingattestations/:id.
+func NewAttestationGetHandler(plugin *proofofreserve.Plugin) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var txnID types.TransactionID
+		err := txnID.LoadString(ps.ByName("id"))
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: "failed to parse id param: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		record, err := plugin.GetAttestation(txnID)
+		if err != nil {
+			if err == proofofreserve.ErrAttestationNotFound {
+				rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusNotFound)
+				return
+			}
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		rapi.WriteJSON(w, AttestationGet{Attestation: record})
+	}
+}
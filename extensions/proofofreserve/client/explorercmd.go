@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/pkg/client"
+	rivinecli "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/proofofreserve"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateExplorerSubCmds adds the explorer cli subcommands for the proof-of-reserve plugin
+func CreateExplorerSubCmds(ccli *rivinecli.CommandLineClient) error {
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(ccli)
+	if err != nil {
+		return err
+	}
+
+	explorerSubCmds := &explorerSubCmds{
+		cli:       ccli,
+		porClient: NewPluginExplorerClient(bc),
+	}
+
+	// define commands
+	getAttestationsCmd := &cobra.Command{
+		Use:   "mintingattestations [txid]",
+		Short: "Get the proof-of-reserve attestations of coin creation and coin destruction transactions",
+		Long: `Get the proof-of-reserve attestations attached to coin creation and coin destruction transactions,
+listing the vault, bar serials, weight (in milligrams of fine gold) and assayer signature hash of each attestation.
+
+All attestations found on the chain are listed, unless a transaction ID is given,
+in which case only the attestation of that transaction is returned.
+The listed attestations can be filtered by vault and transaction type.
+`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  explorerSubCmds.getAttestations,
+	}
+
+	// add commands as explorer sub commands
+	ccli.ExploreCmd.AddCommand(getAttestationsCmd)
+
+	// register flags
+	getAttestationsCmd.Flags().StringVar(
+		&explorerSubCmds.getAttestationsCfg.VaultID, "vault", "",
+		"only list the attestations of the given vault")
+	getAttestationsCmd.Flags().StringVar(
+		&explorerSubCmds.getAttestationsCfg.TransactionType, "type", "",
+		fmt.Sprintf("only list the attestations of the given transaction type (%s or %s)",
+			proofofreserve.TransactionTypeCoinCreation, proofofreserve.TransactionTypeCoinDestruction))
+	getAttestationsCmd.Flags().Var(
+		cli.NewEncodingTypeFlag(0, &explorerSubCmds.getAttestationsCfg.EncodingType, 0), "encoding",
+		cli.EncodingTypeFlagDescription(0))
+
+	return nil
+}
+
+type explorerSubCmds struct {
+	cli                *rivinecli.CommandLineClient
+	porClient          *PluginClient
+	getAttestationsCfg struct {
+		VaultID         string
+		TransactionType string
+		EncodingType    cli.EncodingType
+	}
+}
+
+func (explorerSubCmds *explorerSubCmds) getAttestations(cmd *cobra.Command, args []string) {
+	var (
+		result interface{}
+		err    error
+	)
+	if len(args) == 1 {
+		var txnID types.TransactionID
+		err = txnID.LoadString(args[0])
+		if err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.DieWithError("error while string-decoding transaction ID", err)
+			return
+		}
+		result, err = explorerSubCmds.porClient.GetAttestation(txnID)
+	} else {
+		result, err = explorerSubCmds.porClient.GetAttestations(proofofreserve.AttestationFilter{
+			VaultID:         explorerSubCmds.getAttestationsCfg.VaultID,
+			TransactionType: explorerSubCmds.getAttestationsCfg.TransactionType,
+		})
+	}
+	if err != nil {
+		cli.DieWithError("error while getting minting attestations from explorer", err)
+		return
+	}
+
+	// encode depending on the encoding flag
+	var encode func(interface{}) error
+	switch explorerSubCmds.getAttestationsCfg.EncodingType {
+	case cli.EncodingTypeHuman:
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		encode = e.Encode
+	case cli.EncodingTypeJSON:
+		encode = json.NewEncoder(os.Stdout).Encode
+	case cli.EncodingTypeHex:
+		encode = func(v interface{}) error {
+			b, err := rivbin.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Println(hex.EncodeToString(b))
+			return nil
+		}
+	}
+	err = encode(result)
+	if err != nil {
+		cli.DieWithError("failed to encode minting attestations", err)
+	}
+}
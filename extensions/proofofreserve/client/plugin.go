@@ -0,0 +1,64 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/nbh-digital/goldchain/extensions/proofofreserve"
+	"github.com/nbh-digital/goldchain/extensions/proofofreserve/api"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	types "github.com/threefoldtech/rivine/types"
+)
+
+// PluginClient is used to be able to get the proof-of-reserve attestations
+// attached to coin creation and coin destruction transactions.
+type PluginClient struct {
+	client       client.BaseClient
+	rootEndpoint string
+}
+
+// NewPluginExplorerClient creates a new PluginClient,
+// that can be used for easy interaction with the Proof-of-Reserve Extension API exposed via the Explorer endpoints
+func NewPluginExplorerClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client:       cli,
+		rootEndpoint: "/explorer",
+	}
+}
+
+// GetAttestations returns all attestations found on the chain which match the given filter.
+func (cli *PluginClient) GetAttestations(filter proofofreserve.AttestationFilter) ([]proofofreserve.AttestationRecord, error) {
+	query := url.Values{}
+	if filter.VaultID != "" {
+		query.Set("vaultid", filter.VaultID)
+	}
+	if filter.TransactionType != "" {
+		query.Set("type", filter.TransactionType)
+	}
+	endpoint := cli.rootEndpoint + "/mintingattestations"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var result api.AttestationsGet
+	err := cli.client.HTTP().GetWithResponse(endpoint, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minting attestations from daemon: %v", err)
+	}
+	return result.Attestations, nil
+}
+
+// GetAttestation returns the attestation attached to the transaction with the given ID.
+func (cli *PluginClient) GetAttestation(id types.TransactionID) (proofofreserve.AttestationRecord, error) {
+	var result api.AttestationGet
+	err := cli.client.HTTP().GetWithResponse(
+		fmt.Sprintf("%s/mintingattestations/%s", cli.rootEndpoint, id.String()),
+		&result)
+	if err != nil {
+		return proofofreserve.AttestationRecord{}, fmt.Errorf(
+			"failed to get minting attestation of transaction %s from daemon: %v", id.String(), err)
+	}
+	return result.Attestation, nil
+}
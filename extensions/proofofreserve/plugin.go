@@ -0,0 +1,369 @@
+package proofofreserve
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	portypes "github.com/nbh-digital/goldchain/extensions/proofofreserve/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+const (
+	pluginDBVersion = "1.0.0.0"
+	pluginDBHeader  = "proofOfReservePlugin"
+)
+
+var (
+	// attestations, stored by block height and sequence ID,
+	// such that they can be listed in the order they were applied
+	bucketAttestations = []byte("attestations")
+	// the key in the attestations bucket of each transaction that carries an attestation
+	bucketTransactions = []byte("transactions")
+
+	allBuckets = [][]byte{
+		bucketAttestations,
+		bucketTransactions,
+	}
+)
+
+// ErrAttestationNotFound is returned when no attestation is known for a transaction.
+var ErrAttestationNotFound = errors.New("no attestation found for transaction")
+
+// Transaction types an attestation can be attached to.
+const (
+	TransactionTypeCoinCreation    = "coincreation"
+	TransactionTypeCoinDestruction = "coindestruction"
+)
+
+type (
+	// Plugin is a struct that defines the proof-of-reserve plugin,
+	// validating and indexing the attestations attached to coin creation and coin destruction transactions.
+	Plugin struct {
+		coinCreationTransactionVersion    types.TransactionVersion
+		coinDestructionTransactionVersion types.TransactionVersion
+		activationHeight                  types.BlockHeight
+
+		storage            modules.PluginViewStorage
+		unregisterCallback modules.PluginUnregisterCallback
+	}
+
+	// AttestationRecord is an attestation, as found on the chain.
+	AttestationRecord struct {
+		TransactionID   types.TransactionID  `json:"transactionid"`
+		TransactionType string               `json:"transactiontype"`
+		BlockHeight     types.BlockHeight    `json:"blockheight"`
+		BlockTime       types.Timestamp      `json:"blocktime"`
+		Attestation     portypes.Attestation `json:"attestation"`
+	}
+
+	// AttestationFilter can be used to only list the attestations that match it,
+	// empty fields match all attestations.
+	AttestationFilter struct {
+		VaultID         string
+		TransactionType string
+	}
+)
+
+// NewPlugin creates a new proof-of-reserve Plugin,
+// handling the coin creation and coin destruction transactions of the given versions.
+// Attestations are only validated and indexed as of the given activation height,
+// the arbitrary data of transactions in prior blocks is never interpreted as an attestation.
+func NewPlugin(coinCreationTransactionVersion, coinDestructionTransactionVersion types.TransactionVersion, activationHeight types.BlockHeight) *Plugin {
+	return &Plugin{
+		coinCreationTransactionVersion:    coinCreationTransactionVersion,
+		coinDestructionTransactionVersion: coinDestructionTransactionVersion,
+		activationHeight:                  activationHeight,
+	}
+}
+
+// InitPlugin initializes the Bucket for the first time
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+	p.storage = storage
+	p.unregisterCallback = unregisterCallback
+	if metadata == nil {
+		for _, bucketName := range allBuckets {
+			if bucket.Bucket(bucketName) == nil {
+				_, err := bucket.CreateBucket(bucketName)
+				if err != nil {
+					return persist.Metadata{}, fmt.Errorf("failed to create %s bucket for proof-of-reserve plugin: %v", string(bucketName), err)
+				}
+			}
+		}
+
+		metadata = &persist.Metadata{
+			Version: pluginDBVersion,
+			Header:  pluginDBHeader,
+		}
+	} else if metadata.Version != pluginDBVersion {
+		return persist.Metadata{}, errors.New("There is only 1 version of this plugin, version mismatch")
+	}
+	return *metadata, nil
+}
+
+// ApplyBlock applies the attestations of a block to the proof-of-reserve bucket.
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("proof-of-reserve bucket does not exist")
+	}
+	for idx, txn := range block.Transactions {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            txn,
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err := p.ApplyTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBlockHeader applies nothing and has no effect on this plugin.
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+	return nil
+}
+
+// ApplyTransaction applies the attestation of a transaction to the proof-of-reserve bucket.
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("proof-of-reserve bucket does not exist")
+	}
+	txType, ok := p.transactionType(txn.Version)
+	if !ok || txn.BlockHeight < p.activationHeight || !portypes.IsAttestationArbitraryData(txn.ArbitraryData) {
+		return nil // nothing to do
+	}
+	attestation, err := portypes.UnmarshalAttestationArbitraryData(txn.ArbitraryData)
+	if err != nil {
+		// attestations are validated as of the activation height,
+		// arbitrary data which cannot be decoded is simply not an attestation
+		return nil
+	}
+	attestationsBucket, transactionsBucket, err := getBuckets(bucket)
+	if err != nil {
+		return err
+	}
+	bRecord, err := rivbin.Marshal(AttestationRecord{
+		TransactionID:   txn.ID(),
+		TransactionType: txType,
+		BlockHeight:     txn.BlockHeight,
+		BlockTime:       txn.BlockTime,
+		Attestation:     attestation,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal attestation record: %v", err)
+	}
+	key := attestationKey(txn.BlockHeight, txn.SequenceID)
+	err = attestationsBucket.Put(key, bRecord)
+	if err != nil {
+		return fmt.Errorf("failed to store attestation of transaction %s: %v", txn.ID().String(), err)
+	}
+	bTxnID, err := rivbin.Marshal(txn.ID())
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal transaction ID: %v", err)
+	}
+	err = transactionsBucket.Put(bTxnID, key)
+	if err != nil {
+		return fmt.Errorf("failed to link transaction %s to its attestation: %v", txn.ID().String(), err)
+	}
+	return nil
+}
+
+// RevertBlock reverts the attestations of a block from the proof-of-reserve bucket.
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("proof-of-reserve bucket does not exist")
+	}
+	// revert all transactions in reverse order
+	for idx := len(block.Transactions) - 1; idx >= 0; idx-- {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            block.Transactions[idx],
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err := p.RevertTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBlockHeader reverts nothing and has no effect on this plugin.
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+	return nil
+}
+
+// RevertTransaction reverts the attestation of a transaction from the proof-of-reserve bucket.
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("proof-of-reserve bucket does not exist")
+	}
+	if _, ok := p.transactionType(txn.Version); !ok || txn.BlockHeight < p.activationHeight || !portypes.IsAttestationArbitraryData(txn.ArbitraryData) {
+		return nil // nothing to do
+	}
+	attestationsBucket, transactionsBucket, err := getBuckets(bucket)
+	if err != nil {
+		return err
+	}
+	err = attestationsBucket.Delete(attestationKey(txn.BlockHeight, txn.SequenceID))
+	if err != nil {
+		return fmt.Errorf("failed to delete attestation of transaction %s: %v", txn.ID().String(), err)
+	}
+	bTxnID, err := rivbin.Marshal(txn.ID())
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal transaction ID: %v", err)
+	}
+	err = transactionsBucket.Delete(bTxnID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink transaction %s from its attestation: %v", txn.ID().String(), err)
+	}
+	return nil
+}
+
+// GetAttestation returns the attestation attached to the transaction with the given ID,
+// returning ErrAttestationNotFound if the transaction is not known or carries no attestation.
+func (p *Plugin) GetAttestation(id types.TransactionID) (AttestationRecord, error) {
+	var record AttestationRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		attestationsBucket, transactionsBucket := bucket.Bucket(bucketAttestations), bucket.Bucket(bucketTransactions)
+		if attestationsBucket == nil || transactionsBucket == nil {
+			return errors.New("corrupt proof-of-reserve plugin: did not find any attestations")
+		}
+		bTxnID, err := rivbin.Marshal(id)
+		if err != nil {
+			return fmt.Errorf("failed to rivbin marshal transaction ID: %v", err)
+		}
+		key := transactionsBucket.Get(bTxnID)
+		if len(key) == 0 {
+			return ErrAttestationNotFound
+		}
+		b := attestationsBucket.Get(key)
+		if len(b) == 0 {
+			return fmt.Errorf("corrupt proof-of-reserve plugin: did not find attestation of transaction %s", id.String())
+		}
+		return rivbin.Unmarshal(b, &record)
+	})
+	return record, err
+}
+
+// GetAttestations returns all attestations found on the chain which match the given filter,
+// in the order they were applied.
+func (p *Plugin) GetAttestations(filter AttestationFilter) ([]AttestationRecord, error) {
+	var records []AttestationRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		attestationsBucket := bucket.Bucket(bucketAttestations)
+		if attestationsBucket == nil {
+			return errors.New("corrupt proof-of-reserve plugin: did not find any attestations")
+		}
+		return attestationsBucket.ForEach(func(_, v []byte) error {
+			var record AttestationRecord
+			err := rivbin.Unmarshal(v, &record)
+			if err != nil {
+				return fmt.Errorf("failed to rivbin unmarshal attestation record: %v", err)
+			}
+			if filter.Match(record) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
+
+// Match returns true if the given record matches the filter.
+func (filter AttestationFilter) Match(record AttestationRecord) bool {
+	if filter.VaultID != "" && filter.VaultID != record.Attestation.VaultID {
+		return false
+	}
+	if filter.TransactionType != "" && filter.TransactionType != record.TransactionType {
+		return false
+	}
+	return true
+}
+
+// TransactionValidatorVersionFunctionMapping returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidatorVersionFunctionMapping() map[types.TransactionVersion][]modules.PluginTransactionValidationFunction {
+	return map[types.TransactionVersion][]modules.PluginTransactionValidationFunction{
+		p.coinCreationTransactionVersion: {
+			p.validateAttestation,
+		},
+		p.coinDestructionTransactionVersion: {
+			p.validateAttestation,
+		},
+	}
+}
+
+// TransactionValidators returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFunction {
+	return nil
+}
+
+// validateAttestation validates the shape of the attestation attached to a coin creation or coin destruction transaction,
+// arbitrary data that does not carry an attestation, or which is part of a block prior to the activation height,
+// is not validated by this plugin.
+func (p *Plugin) validateAttestation(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+	if ctx.BlockHeight < p.activationHeight || !portypes.IsAttestationArbitraryData(tx.ArbitraryData) {
+		return nil // nothing to do
+	}
+	attestation, err := portypes.UnmarshalAttestationArbitraryData(tx.ArbitraryData)
+	if err == nil {
+		err = attestation.Validate()
+	}
+	if err != nil {
+		return gctypes.NewCodedError(gctypes.ErrorCodeInvalidReserveAttestation, err, nil)
+	}
+	return nil
+}
+
+// Close releases any resources held by the plugin like the PluginViewStorage
+func (p *Plugin) Close() error {
+	if p.storage == nil {
+		return nil
+	}
+	return p.storage.Close()
+}
+
+func (p *Plugin) transactionType(version types.TransactionVersion) (string, bool) {
+	switch version {
+	case p.coinCreationTransactionVersion:
+		return TransactionTypeCoinCreation, true
+	case p.coinDestructionTransactionVersion:
+		return TransactionTypeCoinDestruction, true
+	default:
+		return "", false
+	}
+}
+
+func getBuckets(bucket *persist.LazyBoltBucket) (*bolt.Bucket, *bolt.Bucket, error) {
+	attestationsBucket, err := bucket.Bucket(bucketAttestations)
+	if err != nil {
+		return nil, nil, fmt.Errorf("corrupt proof-of-reserve plugin: did not find any attestations: %v", err)
+	}
+	transactionsBucket, err := bucket.Bucket(bucketTransactions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("corrupt proof-of-reserve plugin: did not find any transactions: %v", err)
+	}
+	return attestationsBucket, transactionsBucket, nil
+}
+
+func attestationKey(height types.BlockHeight, sequenceID uint16) []byte {
+	key := make([]byte, 10)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	binary.BigEndian.PutUint16(key[8:], sequenceID)
+	return key
+}
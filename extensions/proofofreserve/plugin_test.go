@@ -0,0 +1,74 @@
+package proofofreserve
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	portypes "github.com/nbh-digital/goldchain/extensions/proofofreserve/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+func TestValidateAttestation(t *testing.T) {
+	p := NewPlugin(gctypes.TransactionVersionCoinCreation, gctypes.TransactionVersionCoinDestruction, 10)
+	validators := p.TransactionValidatorVersionFunctionMapping()
+	for _, version := range []types.TransactionVersion{gctypes.TransactionVersionCoinCreation, gctypes.TransactionVersionCoinDestruction} {
+		if len(validators[version]) != 1 {
+			t.Fatalf("expected one validator for transaction version %d", version)
+		}
+	}
+
+	valid, err := portypes.Attestation{
+		VaultID:              "zurich-01",
+		BarSerials:           []string{"AB-0001"},
+		Weight:               12500000,
+		AssayerSignatureHash: crypto.Hash{1},
+	}.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := portypes.Attestation{
+		VaultID:    "zurich-01",
+		BarSerials: []string{"AB-0001", "AB-0001"},
+		Weight:     12500000,
+	}.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		ArbitraryData []byte
+		Valid         bool
+	}{
+		{nil, true},
+		{[]byte("monthly gold intake"), true},
+		{valid, true},
+		{invalid, false},
+		{valid[:len(valid)-1], false},
+	}
+	for idx, testCase := range testCases {
+		txn := modules.ConsensusTransaction{
+			Transaction: types.Transaction{
+				Version:       gctypes.TransactionVersionCoinCreation,
+				ArbitraryData: testCase.ArbitraryData,
+			},
+		}
+		// arbitrary data prior to the activation height is never interpreted as an attestation
+		err := p.validateAttestation(txn, types.TransactionValidationContext{ValidationContext: types.ValidationContext{BlockHeight: 9}}, nil)
+		if err != nil {
+			t.Error(idx+1, "unexpected error prior to activation:", err)
+		}
+		err = p.validateAttestation(txn, types.TransactionValidationContext{ValidationContext: types.ValidationContext{BlockHeight: 10}}, nil)
+		if testCase.Valid {
+			if err != nil {
+				t.Error(idx+1, "unexpected error:", err)
+			}
+			continue
+		}
+		cErr, ok := gctypes.AsCodedError(err)
+		if !ok || cErr.Code != gctypes.ErrorCodeInvalidReserveAttestation {
+			t.Error(idx+1, "unexpected error:", err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+)
+
+const (
+	// AttestationVersion is the version of the (binary) attestation format,
+	// encoded right after the attestation prefix in the arbitrary data of a transaction.
+	AttestationVersion uint8 = 1
+
+	// MaxVaultIDLength is the maximum length of the ID of a vault.
+	MaxVaultIDLength = 64
+	// MaxBarSerials is the maximum amount of bar serials a single attestation can list.
+	MaxBarSerials = 64
+	// MaxBarSerialLength is the maximum length of a single bar serial.
+	MaxBarSerialLength = 32
+
+	// ArbitraryDataSizeLimit is the maximum size of the arbitrary data of a coin creation or coin destruction
+	// transaction, which carries an attestation. It replaces the (much lower) arbitrary data size limit of the chain,
+	// and is well above the size of the largest attestation which has a valid shape.
+	ArbitraryDataSizeLimit = 4096
+)
+
+// AttestationPrefix is the prefix of the arbitrary data of a transaction,
+// identifying that the arbitrary data carries a (binary encoded) proof-of-reserve attestation.
+// Arbitrary data which does not start with this prefix is not considered an attestation.
+var AttestationPrefix = []byte("GFTPOR")
+
+// Errors returned for attestations which do not have a valid shape.
+var (
+	ErrNoVaultID              = errors.New("attestation has no vault ID")
+	ErrVaultIDTooLong         = fmt.Errorf("attestation vault ID is longer than %d characters", MaxVaultIDLength)
+	ErrNoBarSerials           = errors.New("attestation lists no bar serials")
+	ErrTooManyBarSerials      = fmt.Errorf("attestation lists more than %d bar serials", MaxBarSerials)
+	ErrInvalidBarSerial       = errors.New("attestation lists an invalid bar serial")
+	ErrDuplicateBarSerial     = errors.New("attestation lists a duplicate bar serial")
+	ErrNoWeight               = errors.New("attestation has no weight")
+	ErrNoAssayerSignatureHash = errors.New("attestation has no assayer signature hash")
+	ErrUnknownVersion         = errors.New("unknown attestation version")
+)
+
+// Attestation is a proof-of-reserve attestation, attached to a coin creation or coin destruction transaction,
+// describing the vaulted gold that backs the created coins, or the gold released by the destruction of coins.
+type Attestation struct {
+	// VaultID identifies the vault in which the gold is stored
+	VaultID string `json:"vaultid"`
+	// BarSerials lists the serial numbers of the gold bars
+	BarSerials []string `json:"barserials"`
+	// Weight is the total weight of fine gold of all bars
+	Weight Weight `json:"weight"`
+	// AssayerSignatureHash is the hash of the signature of the assayer on the assay report of the bars,
+	// such that the off-chain report can be matched to the attestation
+	AssayerSignatureHash crypto.Hash `json:"assayersignaturehash"`
+}
+
+// Validate ensures the attestation has a valid shape.
+func (a Attestation) Validate() error {
+	if a.VaultID == "" {
+		return ErrNoVaultID
+	}
+	if len(a.VaultID) > MaxVaultIDLength {
+		return ErrVaultIDTooLong
+	}
+	if len(a.BarSerials) == 0 {
+		return ErrNoBarSerials
+	}
+	if len(a.BarSerials) > MaxBarSerials {
+		return ErrTooManyBarSerials
+	}
+	serials := make(map[string]struct{}, len(a.BarSerials))
+	for _, serial := range a.BarSerials {
+		if serial == "" || len(serial) > MaxBarSerialLength || strings.TrimSpace(serial) != serial {
+			return fmt.Errorf("%v: %q", ErrInvalidBarSerial, serial)
+		}
+		if _, ok := serials[serial]; ok {
+			return fmt.Errorf("%v: %q", ErrDuplicateBarSerial, serial)
+		}
+		serials[serial] = struct{}{}
+	}
+	if a.Weight == 0 {
+		return ErrNoWeight
+	}
+	if a.AssayerSignatureHash == (crypto.Hash{}) {
+		return ErrNoAssayerSignatureHash
+	}
+	return nil
+}
+
+// MarshalArbitraryData encodes the attestation as the arbitrary data of a transaction.
+func (a Attestation) MarshalArbitraryData() ([]byte, error) {
+	b, err := rivbin.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rivbin marshal attestation: %v", err)
+	}
+	data := make([]byte, 0, len(AttestationPrefix)+1+len(b))
+	data = append(data, AttestationPrefix...)
+	data = append(data, AttestationVersion)
+	return append(data, b...), nil
+}
+
+// IsAttestationArbitraryData returns true if the given arbitrary data carries an attestation,
+// which does not mean the attestation can be decoded or has a valid shape.
+func IsAttestationArbitraryData(data []byte) bool {
+	return bytes.HasPrefix(data, AttestationPrefix)
+}
+
+// UnmarshalAttestationArbitraryData decodes the attestation carried by the given arbitrary data.
+func UnmarshalAttestationArbitraryData(data []byte) (Attestation, error) {
+	if !IsAttestationArbitraryData(data) {
+		return Attestation{}, errors.New("arbitrary data does not carry an attestation")
+	}
+	data = data[len(AttestationPrefix):]
+	if len(data) == 0 || data[0] != AttestationVersion {
+		return Attestation{}, ErrUnknownVersion
+	}
+	r := bytes.NewReader(data[1:])
+	var a Attestation
+	err := rivbin.NewDecoder(r).Decode(&a)
+	if err != nil {
+		return Attestation{}, fmt.Errorf("failed to rivbin unmarshal attestation: %v", err)
+	}
+	if r.Len() != 0 {
+		return Attestation{}, fmt.Errorf("attestation is followed by %d unexpected bytes", r.Len())
+	}
+	return a, nil
+}
+
+// Weight is a weight of fine gold, expressed in milligrams.
+type Weight uint64
+
+// String returns the weight in grams, using 3 decimals.
+func (w Weight) String() string {
+	return fmt.Sprintf("%d.%03d", w/1000, w%1000)
+}
+
+// LoadString loads a weight expressed in grams, with at most 3 decimals.
+func (w *Weight) LoadString(str string) error {
+	r, ok := new(big.Rat).SetString(str)
+	if !ok || r.Sign() < 0 {
+		return fmt.Errorf("invalid weight %q", str)
+	}
+	r.Mul(r, big.NewRat(1000, 1))
+	if !r.IsInt() {
+		return fmt.Errorf("invalid weight %q: at most 3 decimals are supported", str)
+	}
+	if !r.Num().IsUint64() {
+		return fmt.Errorf("invalid weight %q: overflow", str)
+	}
+	*w = Weight(r.Num().Uint64())
+	return nil
+}
@@ -0,0 +1,136 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+)
+
+func TestAttestationArbitraryDataRoundTrip(t *testing.T) {
+	attestation := Attestation{
+		VaultID:              "zurich-01",
+		BarSerials:           []string{"AB-0001", "AB-0002"},
+		Weight:               25000000,
+		AssayerSignatureHash: crypto.Hash{1, 2, 3},
+	}
+	data, err := attestation.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAttestationArbitraryData(data) {
+		t.Fatal("expected arbitrary data to carry an attestation")
+	}
+	decoded, err := UnmarshalAttestationArbitraryData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(attestation, decoded) {
+		t.Fatalf("%v != %v", attestation, decoded)
+	}
+
+	if IsAttestationArbitraryData([]byte("monthly gold intake")) {
+		t.Error("expected description not to carry an attestation")
+	}
+	for idx, invalid := range [][]byte{
+		append([]byte(nil), data[:len(data)-1]...),
+		append(append([]byte(nil), data...), 0),
+		append(append([]byte(nil), AttestationPrefix...), AttestationVersion+1),
+		append([]byte(nil), AttestationPrefix...),
+	} {
+		if _, err = UnmarshalAttestationArbitraryData(invalid); err == nil {
+			t.Error(idx+1, "expected decoding to fail")
+		}
+	}
+}
+
+func TestAttestationValidate(t *testing.T) {
+	valid := Attestation{
+		VaultID:              "zurich-01",
+		BarSerials:           []string{"AB-0001"},
+		Weight:               1,
+		AssayerSignatureHash: crypto.Hash{1},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	maxSerials := make([]string, MaxBarSerials)
+	for idx := range maxSerials {
+		maxSerials[idx] = fmt.Sprintf("%0*d", MaxBarSerialLength, idx)
+	}
+	largest := Attestation{
+		VaultID:              strings.Repeat("v", MaxVaultIDLength),
+		BarSerials:           maxSerials,
+		Weight:               Weight(^uint64(0)),
+		AssayerSignatureHash: crypto.Hash{1},
+	}
+	if err := largest.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := largest.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > ArbitraryDataSizeLimit {
+		t.Fatalf("largest valid attestation (%d bytes) exceeds the size limit of %d bytes", len(data), ArbitraryDataSizeLimit)
+	}
+
+	testCases := []struct {
+		Modify func(*Attestation)
+		Err    error
+	}{
+		{func(a *Attestation) { a.VaultID = "" }, ErrNoVaultID},
+		{func(a *Attestation) { a.VaultID = strings.Repeat("v", MaxVaultIDLength+1) }, ErrVaultIDTooLong},
+		{func(a *Attestation) { a.BarSerials = nil }, ErrNoBarSerials},
+		{func(a *Attestation) { a.BarSerials = append(maxSerials, "AB-0001") }, ErrTooManyBarSerials},
+		{func(a *Attestation) { a.BarSerials = []string{""} }, ErrInvalidBarSerial},
+		{func(a *Attestation) { a.BarSerials = []string{" AB-0001"} }, ErrInvalidBarSerial},
+		{func(a *Attestation) { a.BarSerials = []string{strings.Repeat("1", MaxBarSerialLength+1)} }, ErrInvalidBarSerial},
+		{func(a *Attestation) { a.BarSerials = []string{"AB-0001", "AB-0001"} }, ErrDuplicateBarSerial},
+		{func(a *Attestation) { a.Weight = 0 }, ErrNoWeight},
+		{func(a *Attestation) { a.AssayerSignatureHash = crypto.Hash{} }, ErrNoAssayerSignatureHash},
+	}
+	for idx, testCase := range testCases {
+		attestation := valid
+		testCase.Modify(&attestation)
+		err := attestation.Validate()
+		if err == nil || !strings.HasPrefix(err.Error(), testCase.Err.Error()) {
+			t.Errorf("%d: unexpected error: %v (expected: %v)", idx+1, err, testCase.Err)
+		}
+	}
+}
+
+func TestWeightLoadString(t *testing.T) {
+	testCases := []struct {
+		Input  string
+		Weight Weight
+		String string
+	}{
+		{"1", 1000, "1.000"},
+		{"12.5", 12500, "12.500"},
+		{"0.001", 1, "0.001"},
+		{"12500", 12500000, "12500.000"},
+	}
+	for idx, testCase := range testCases {
+		var w Weight
+		err := w.LoadString(testCase.Input)
+		if err != nil {
+			t.Error(idx+1, err)
+			continue
+		}
+		if w != testCase.Weight {
+			t.Error(idx+1, w, "!=", testCase.Weight)
+		}
+		if w.String() != testCase.String {
+			t.Error(idx+1, w.String(), "!=", testCase.String)
+		}
+	}
+	for idx, invalid := range []string{"", "foo", "-1", "0.0001", "18446744073709552"} {
+		var w Weight
+		if err := w.LoadString(invalid); err == nil {
+			t.Error(idx+1, "expected weight", invalid, "to be invalid")
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nbh-digital/goldchain/extensions/redemption"
+	"github.com/threefoldtech/rivine/modules"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/types"
+)
+
+type (
+	// RedemptionsGet is the response of the redemptions Get explorer endpoint
+	RedemptionsGet struct {
+		Redemptions []redemption.RedemptionRecord `json:"redemptions"`
+	}
+
+	// RedemptionGet is the response of the redemption Get explorer endpoint,
+	// returning the redemption of a single reference
+	RedemptionGet struct {
+		Redemption redemption.RedemptionRecord `json:"redemption"`
+	}
+)
+
+// RegisterExplorerRedemptionHTTPHandlers registers the default explorer HTTP handlers specific to the redemption package.
+// The transaction pool is optional, pending redemptions are only listed if it is defined.
+func RegisterExplorerRedemptionHTTPHandlers(router rapi.Router, plugin *redemption.Plugin, cs modules.ConsensusSet, tpool modules.TransactionPool) {
+	router.GET("/explorer/redemptions", NewRedemptionsGetHandler(plugin, cs, tpool))
+	router.GET("/explorer/redemptions/:reference", NewRedemptionGetHandler(plugin, cs, tpool))
+}
+
+// NewRedemptionsGetHandler creates a handler to handle the API calls to
+// /explorer/redemptions?status=&vault=&customeridhash=.
+func NewRedemptionsGetHandler(plugin *redemption.Plugin, cs modules.ConsensusSet, tpool modules.TransactionPool) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		q := req.URL.Query()
+		filter := redemption.RedemptionFilter{
+			Status:        q.Get("status"),
+			DeliveryVault: q.Get("vault"),
+		}
+		switch filter.Status {
+		case "", redemption.StatusPending, redemption.StatusConfirmed:
+		default:
+			rapi.WriteError(w, rapi.Error{Message: "invalid status query param '" + filter.Status + "'"}, http.StatusBadRequest)
+			return
+		}
+		if str := q.Get("customeridhash"); str != "" {
+			err := filter.CustomerIDHash.LoadString(str)
+			if err != nil {
+				rapi.WriteError(w, rapi.Error{Message: "invalid customeridhash query param: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+		records, err := plugin.GetRedemptions(filter)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		for _, record := range pendingRedemptions(plugin, cs, tpool) {
+			if filter.Match(record) {
+				records = append(records, record)
+			}
+		}
+		if records == nil {
+			records = []redemption.RedemptionRecord{}
+		}
+		rapi.WriteJSON(w, RedemptionsGet{Redemptions: records})
+	}
+}
+
+// NewRedemptionGetHandler creates a handler to handle the API calls to /explorer/redemptions/:reference.
+func NewRedemptionGetHandler(plugin *redemption.Plugin, cs modules.ConsensusSet, tpool modules.TransactionPool) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		reference := ps.ByName("reference")
+		record, err := plugin.GetRedemption(reference)
+		if err == nil {
+			rapi.WriteJSON(w, RedemptionGet{Redemption: record})
+			return
+		}
+		if err != redemption.ErrRedemptionNotFound {
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		for _, record := range pendingRedemptions(plugin, cs, tpool) {
+			if record.Redemption.Reference == reference {
+				rapi.WriteJSON(w, RedemptionGet{Redemption: record})
+				return
+			}
+		}
+		rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusNotFound)
+	}
+}
+
+// pendingRedemptions returns the redemptions found in the transaction pool,
+// which are not yet confirmed. The parent outputs of the pool transactions are looked up
+// in the consensus set, or within the pool itself for unconfirmed parent outputs.
+func pendingRedemptions(plugin *redemption.Plugin, cs modules.ConsensusSet, tpool modules.TransactionPool) []redemption.RedemptionRecord {
+	if tpool == nil {
+		return nil
+	}
+	txns := tpool.TransactionList()
+	var poolOutputs map[types.CoinOutputID]types.CoinOutput
+	parent := func(id types.CoinOutputID) (types.CoinOutput, error) {
+		co, err := cs.GetCoinOutput(id)
+		if err == nil {
+			return co, nil
+		}
+		if poolOutputs == nil {
+			poolOutputs = make(map[types.CoinOutputID]types.CoinOutput)
+			for _, txn := range txns {
+				for idx, co := range txn.CoinOutputs {
+					poolOutputs[txn.CoinOutputID(uint64(idx))] = co
+				}
+			}
+		}
+		if co, ok := poolOutputs[id]; ok {
+			return co, nil
+		}
+		return types.CoinOutput{}, err
+	}
+	var records []redemption.RedemptionRecord
+	for _, record := range plugin.PendingRedemptions(txns, parent) {
+		// a pending redemption of an already redeemed reference will never be confirmed
+		if _, err := plugin.GetRedemption(record.Redemption.Reference); err == redemption.ErrRedemptionNotFound {
+			records = append(records, record)
+		}
+	}
+	return records
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/pkg/client"
+	rivinecli "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+
+	"github.com/nbh-digital/goldchain/extensions/redemption"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateExplorerSubCmds adds the explorer cli subcommands for the redemption plugin
+func CreateExplorerSubCmds(ccli *rivinecli.CommandLineClient) error {
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(ccli)
+	if err != nil {
+		return err
+	}
+
+	explorerSubCmds := &explorerSubCmds{
+		cli:      ccli,
+		rdClient: NewPluginExplorerClient(bc),
+	}
+
+	// define commands
+	getRedemptionsCmd := &cobra.Command{
+		Use:   "redemptions [reference]",
+		Short: "Get the pending and confirmed redemptions of burned coins",
+		Long: `Get the redemptions attached to coin destruction transactions,
+listing the reference, customer ID hash and delivery vault of each redemption,
+as well as the amount of coins burned and the status of the burn transaction.
+
+All redemptions are listed, unless a reference is given,
+in which case only the redemption of that reference is returned.
+The listed redemptions can be filtered by status, delivery vault and customer.
+`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  explorerSubCmds.getRedemptions,
+	}
+
+	// add commands as explorer sub commands
+	ccli.ExploreCmd.AddCommand(getRedemptionsCmd)
+
+	// register flags
+	getRedemptionsCmd.Flags().StringVar(
+		&explorerSubCmds.getRedemptionsCfg.Status, "status", "",
+		fmt.Sprintf("only list the redemptions of the given status (%s or %s)",
+			redemption.StatusPending, redemption.StatusConfirmed))
+	getRedemptionsCmd.Flags().StringVar(
+		&explorerSubCmds.getRedemptionsCfg.DeliveryVault, "vault", "",
+		"only list the redemptions to be delivered from the given vault")
+	getRedemptionsCmd.Flags().StringVar(
+		&explorerSubCmds.getRedemptionsCfg.CustomerIDHash, "customer-id-hash", "",
+		"only list the redemptions of the customer with the given ID hash")
+	getRedemptionsCmd.Flags().Var(
+		cli.NewEncodingTypeFlag(0, &explorerSubCmds.getRedemptionsCfg.EncodingType, 0), "encoding",
+		cli.EncodingTypeFlagDescription(0))
+
+	return nil
+}
+
+type explorerSubCmds struct {
+	cli               *rivinecli.CommandLineClient
+	rdClient          *PluginClient
+	getRedemptionsCfg struct {
+		Status         string
+		DeliveryVault  string
+		CustomerIDHash string
+		EncodingType   cli.EncodingType
+	}
+}
+
+func (explorerSubCmds *explorerSubCmds) getRedemptions(cmd *cobra.Command, args []string) {
+	var (
+		result interface{}
+		err    error
+	)
+	if len(args) == 1 {
+		result, err = explorerSubCmds.rdClient.GetRedemption(args[0])
+	} else {
+		filter := redemption.RedemptionFilter{
+			Status:        explorerSubCmds.getRedemptionsCfg.Status,
+			DeliveryVault: explorerSubCmds.getRedemptionsCfg.DeliveryVault,
+		}
+		if explorerSubCmds.getRedemptionsCfg.CustomerIDHash != "" {
+			err = filter.CustomerIDHash.LoadString(explorerSubCmds.getRedemptionsCfg.CustomerIDHash)
+			if err != nil {
+				cmd.UsageFunc()(cmd)
+				cli.DieWithError("error while string-decoding customer ID hash", err)
+				return
+			}
+		}
+		result, err = explorerSubCmds.rdClient.GetRedemptions(filter)
+	}
+	if err != nil {
+		cli.DieWithError("error while getting redemptions from explorer", err)
+		return
+	}
+
+	// encode depending on the encoding flag
+	var encode func(interface{}) error
+	switch explorerSubCmds.getRedemptionsCfg.EncodingType {
+	case cli.EncodingTypeHuman:
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		encode = e.Encode
+	case cli.EncodingTypeJSON:
+		encode = json.NewEncoder(os.Stdout).Encode
+	case cli.EncodingTypeHex:
+		encode = func(v interface{}) error {
+			b, err := rivbin.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Println(hex.EncodeToString(b))
+			return nil
+		}
+	}
+	err = encode(result)
+	if err != nil {
+		cli.DieWithError("failed to encode redemptions", err)
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/nbh-digital/goldchain/extensions/redemption"
+	"github.com/nbh-digital/goldchain/extensions/redemption/api"
+	"github.com/threefoldtech/rivine/crypto"
+	client "github.com/threefoldtech/rivine/pkg/client"
+)
+
+// PluginClient is used to be able to get the redemptions
+// attached to coin destruction transactions.
+type PluginClient struct {
+	client       client.BaseClient
+	rootEndpoint string
+}
+
+// NewPluginExplorerClient creates a new PluginClient,
+// that can be used for easy interaction with the Redemption Extension API exposed via the Explorer endpoints
+func NewPluginExplorerClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client:       cli,
+		rootEndpoint: "/explorer",
+	}
+}
+
+// GetRedemptions returns all pending and confirmed redemptions which match the given filter.
+func (cli *PluginClient) GetRedemptions(filter redemption.RedemptionFilter) ([]redemption.RedemptionRecord, error) {
+	query := url.Values{}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.DeliveryVault != "" {
+		query.Set("vault", filter.DeliveryVault)
+	}
+	if filter.CustomerIDHash != (crypto.Hash{}) {
+		query.Set("customeridhash", filter.CustomerIDHash.String())
+	}
+	endpoint := cli.rootEndpoint + "/redemptions"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var result api.RedemptionsGet
+	err := cli.client.HTTP().GetWithResponse(endpoint, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemptions from daemon: %v", err)
+	}
+	return result.Redemptions, nil
+}
+
+// GetRedemption returns the pending or confirmed redemption of the given reference.
+func (cli *PluginClient) GetRedemption(reference string) (redemption.RedemptionRecord, error) {
+	var result api.RedemptionGet
+	err := cli.client.HTTP().GetWithResponse(
+		fmt.Sprintf("%s/redemptions/%s", cli.rootEndpoint, url.PathEscape(reference)),
+		&result)
+	if err != nil {
+		return redemption.RedemptionRecord{}, fmt.Errorf(
+			"failed to get redemption %q from daemon: %v", reference, err)
+	}
+	return result.Redemption, nil
+}
@@ -0,0 +1,446 @@
+package redemption
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	rdtypes "github.com/nbh-digital/goldchain/extensions/redemption/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+const (
+	pluginDBVersion = "1.0.0.0"
+	pluginDBHeader  = "redemptionPlugin"
+)
+
+var (
+	// redemptions, stored by reference
+	bucketRedemptions = []byte("redemptions")
+)
+
+// ErrRedemptionNotFound is returned when no redemption is known for a reference.
+var ErrRedemptionNotFound = errors.New("no redemption found for reference")
+
+// Statuses of a redemption.
+const (
+	// StatusPending is the status of a redemption of which the coin destruction transaction is not yet confirmed
+	StatusPending = "pending"
+	// StatusConfirmed is the status of a redemption of which the coin destruction transaction is confirmed
+	StatusConfirmed = "confirmed"
+)
+
+type (
+	// Plugin is a struct that defines the redemption plugin,
+	// validating and indexing the redemptions attached to coin destruction transactions.
+	Plugin struct {
+		coinDestructionTransactionVersion types.TransactionVersion
+		activationHeight                  types.BlockHeight
+
+		storage            modules.PluginViewStorage
+		unregisterCallback modules.PluginUnregisterCallback
+	}
+
+	// RedemptionRecord is a redemption, as found on the chain or in the transaction pool.
+	RedemptionRecord struct {
+		Redemption    rdtypes.Redemption  `json:"redemption"`
+		TransactionID types.TransactionID `json:"transactionid"`
+		Status        string              `json:"status"`
+		// BlockHeight and BlockTime are only defined for confirmed redemptions
+		BlockHeight types.BlockHeight `json:"blockheight,omitempty"`
+		BlockTime   types.Timestamp   `json:"blocktime,omitempty"`
+		// Amount is the amount of coins burned, custody fee and miner fees excluded
+		Amount     types.Currency `json:"amount"`
+		CustodyFee types.Currency `json:"custodyfee"`
+	}
+
+	// RedemptionFilter can be used to only list the redemptions that match it,
+	// empty fields match all redemptions.
+	RedemptionFilter struct {
+		Status         string
+		DeliveryVault  string
+		CustomerIDHash crypto.Hash
+	}
+)
+
+// NewPlugin creates a new redemption Plugin,
+// handling the coin destruction transactions of the given version.
+// Redemptions are only validated and indexed as of the given activation height,
+// the arbitrary data of transactions in prior blocks is never interpreted as a redemption.
+func NewPlugin(coinDestructionTransactionVersion types.TransactionVersion, activationHeight types.BlockHeight) *Plugin {
+	return &Plugin{
+		coinDestructionTransactionVersion: coinDestructionTransactionVersion,
+		activationHeight:                  activationHeight,
+	}
+}
+
+// InitPlugin initializes the Bucket for the first time
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+	p.storage = storage
+	p.unregisterCallback = unregisterCallback
+	if metadata == nil {
+		if bucket.Bucket(bucketRedemptions) == nil {
+			_, err := bucket.CreateBucket(bucketRedemptions)
+			if err != nil {
+				return persist.Metadata{}, fmt.Errorf("failed to create %s bucket for redemption plugin: %v", string(bucketRedemptions), err)
+			}
+		}
+
+		metadata = &persist.Metadata{
+			Version: pluginDBVersion,
+			Header:  pluginDBHeader,
+		}
+	} else if metadata.Version != pluginDBVersion {
+		return persist.Metadata{}, errors.New("There is only 1 version of this plugin, version mismatch")
+	}
+	return *metadata, nil
+}
+
+// ApplyBlock applies the redemptions of a block to the redemption bucket.
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("redemption bucket does not exist")
+	}
+	for idx, txn := range block.Transactions {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            txn,
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err := p.ApplyTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBlockHeader applies nothing and has no effect on this plugin.
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+	return nil
+}
+
+// ApplyTransaction applies the redemption of a transaction to the redemption bucket.
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("redemption bucket does not exist")
+	}
+	if txn.Version != p.coinDestructionTransactionVersion || txn.BlockHeight < p.activationHeight ||
+		!rdtypes.IsRedemptionArbitraryData(txn.ArbitraryData) {
+		return nil // nothing to do
+	}
+	redemption, err := rdtypes.UnmarshalRedemptionArbitraryData(txn.ArbitraryData)
+	if err != nil {
+		// redemptions are validated as of the activation height,
+		// arbitrary data which cannot be decoded is simply not a redemption
+		return nil
+	}
+	redemptionsBucket, err := getRedemptionsBucket(bucket)
+	if err != nil {
+		return err
+	}
+	if len(redemptionsBucket.Get([]byte(redemption.Reference))) != 0 {
+		// a reference is only redeemed once, which can only be bypassed within a single block,
+		// in which case the first redemption is kept
+		return nil
+	}
+	amount, custodyFee, err := BurnedAmounts(txn.Transaction, func(id types.CoinOutputID) (types.CoinOutput, error) {
+		co, ok := txn.SpentCoinOutputs[id]
+		if !ok {
+			return types.CoinOutput{}, fmt.Errorf("spent coin output %s not found", id.String())
+		}
+		return co, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compute the burned amount of transaction %s: %v", txn.ID().String(), err)
+	}
+	bRecord, err := rivbin.Marshal(RedemptionRecord{
+		Redemption:    redemption,
+		TransactionID: txn.ID(),
+		Status:        StatusConfirmed,
+		BlockHeight:   txn.BlockHeight,
+		BlockTime:     txn.BlockTime,
+		Amount:        amount,
+		CustodyFee:    custodyFee,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rivbin marshal redemption record: %v", err)
+	}
+	err = redemptionsBucket.Put([]byte(redemption.Reference), bRecord)
+	if err != nil {
+		return fmt.Errorf("failed to store redemption of transaction %s: %v", txn.ID().String(), err)
+	}
+	return nil
+}
+
+// RevertBlock reverts the redemptions of a block from the redemption bucket.
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("redemption bucket does not exist")
+	}
+	// revert all transactions in reverse order
+	for idx := len(block.Transactions) - 1; idx >= 0; idx-- {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            block.Transactions[idx],
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err := p.RevertTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBlockHeader reverts nothing and has no effect on this plugin.
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+	return nil
+}
+
+// RevertTransaction reverts the redemption of a transaction from the redemption bucket.
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+	if bucket == nil {
+		return errors.New("redemption bucket does not exist")
+	}
+	if txn.Version != p.coinDestructionTransactionVersion || txn.BlockHeight < p.activationHeight ||
+		!rdtypes.IsRedemptionArbitraryData(txn.ArbitraryData) {
+		return nil // nothing to do
+	}
+	redemption, err := rdtypes.UnmarshalRedemptionArbitraryData(txn.ArbitraryData)
+	if err != nil {
+		// redemptions are validated as of the activation height,
+		// arbitrary data which cannot be decoded is simply not a redemption
+		return nil
+	}
+	redemptionsBucket, err := getRedemptionsBucket(bucket)
+	if err != nil {
+		return err
+	}
+	b := redemptionsBucket.Get([]byte(redemption.Reference))
+	if len(b) == 0 {
+		return nil
+	}
+	var record RedemptionRecord
+	err = rivbin.Unmarshal(b, &record)
+	if err != nil {
+		return fmt.Errorf("failed to rivbin unmarshal redemption record: %v", err)
+	}
+	if record.TransactionID != txn.ID() {
+		return nil // the redemption is stored for the first transaction that redeemed the reference
+	}
+	err = redemptionsBucket.Delete([]byte(redemption.Reference))
+	if err != nil {
+		return fmt.Errorf("failed to delete redemption of transaction %s: %v", txn.ID().String(), err)
+	}
+	return nil
+}
+
+// GetRedemption returns the confirmed redemption of the given reference,
+// returning ErrRedemptionNotFound if the reference is not (yet) redeemed.
+func (p *Plugin) GetRedemption(reference string) (RedemptionRecord, error) {
+	var record RedemptionRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		redemptionsBucket := bucket.Bucket(bucketRedemptions)
+		if redemptionsBucket == nil {
+			return errors.New("corrupt redemption plugin: did not find any redemptions")
+		}
+		b := redemptionsBucket.Get([]byte(reference))
+		if len(b) == 0 {
+			return ErrRedemptionNotFound
+		}
+		return rivbin.Unmarshal(b, &record)
+	})
+	return record, err
+}
+
+// GetRedemptions returns all confirmed redemptions which match the given filter, ordered by reference.
+func (p *Plugin) GetRedemptions(filter RedemptionFilter) ([]RedemptionRecord, error) {
+	var records []RedemptionRecord
+	err := p.storage.View(func(bucket *bolt.Bucket) error {
+		redemptionsBucket := bucket.Bucket(bucketRedemptions)
+		if redemptionsBucket == nil {
+			return errors.New("corrupt redemption plugin: did not find any redemptions")
+		}
+		return redemptionsBucket.ForEach(func(_, v []byte) error {
+			var record RedemptionRecord
+			err := rivbin.Unmarshal(v, &record)
+			if err != nil {
+				return fmt.Errorf("failed to rivbin unmarshal redemption record: %v", err)
+			}
+			if filter.Match(record) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
+
+// PendingRedemptions returns the redemptions of the given unconfirmed transactions, ignoring invalid redemptions.
+// The parent outputs of the coin inputs of these transactions are looked up using the given function,
+// in order to compute the amount of coins burned.
+func (p *Plugin) PendingRedemptions(txns []types.Transaction, parent func(types.CoinOutputID) (types.CoinOutput, error)) []RedemptionRecord {
+	var records []RedemptionRecord
+	for _, txn := range txns {
+		if txn.Version != p.coinDestructionTransactionVersion {
+			continue
+		}
+		redemption, ok, err := transactionRedemption(txn)
+		if err != nil || !ok {
+			continue
+		}
+		record := RedemptionRecord{
+			Redemption:    redemption,
+			TransactionID: txn.ID(),
+			Status:        StatusPending,
+		}
+		// the amounts remain undefined in case a parent output cannot be found
+		record.Amount, record.CustodyFee, _ = BurnedAmounts(txn, parent)
+		records = append(records, record)
+	}
+	return records
+}
+
+// Match returns true if the given record matches the filter.
+func (filter RedemptionFilter) Match(record RedemptionRecord) bool {
+	if filter.Status != "" && filter.Status != record.Status {
+		return false
+	}
+	if filter.DeliveryVault != "" && filter.DeliveryVault != record.Redemption.DeliveryVault {
+		return false
+	}
+	if filter.CustomerIDHash != (crypto.Hash{}) && filter.CustomerIDHash != record.Redemption.CustomerIDHash {
+		return false
+	}
+	return true
+}
+
+// BurnedAmounts returns the amount of coins burned by the given coin destruction transaction,
+// being the value of its coin inputs minus the value of its coin outputs and miner fees, as well as
+// the custody fee it pays for the burned coins. The parent outputs of the coin inputs are looked up using the given function.
+func BurnedAmounts(txn types.Transaction, parent func(types.CoinOutputID) (types.CoinOutput, error)) (amount, custodyFee types.Currency, err error) {
+	var inputs, outputs types.Currency
+	for _, ci := range txn.CoinInputs {
+		co, err := parent(ci.ParentID)
+		if err != nil {
+			return types.Currency{}, types.Currency{}, err
+		}
+		inputs = inputs.Add(co.Value)
+	}
+	for _, co := range txn.CoinOutputs {
+		outputs = outputs.Add(co.Value)
+		if _, ok := co.Condition.Condition.(*cftypes.CustodyFeeCondition); ok {
+			custodyFee = custodyFee.Add(co.Value)
+		}
+	}
+	for _, fee := range txn.MinerFees {
+		outputs = outputs.Add(fee)
+	}
+	if inputs.Cmp(outputs) < 0 {
+		return types.Currency{}, types.Currency{}, errors.New("coin outputs and miner fees exceed the coin inputs")
+	}
+	return inputs.Sub(outputs), custodyFee, nil
+}
+
+// TransactionValidatorVersionFunctionMapping returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidatorVersionFunctionMapping() map[types.TransactionVersion][]modules.PluginTransactionValidationFunction {
+	return map[types.TransactionVersion][]modules.PluginTransactionValidationFunction{
+		p.coinDestructionTransactionVersion: {
+			p.validateRedemption,
+		},
+	}
+}
+
+// TransactionValidators returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFunction {
+	return nil
+}
+
+// validateRedemption validates the shape of the redemption attached to a coin destruction transaction,
+// and ensures its reference is not yet redeemed by another transaction.
+// Arbitrary data that does not carry a redemption, or which is part of a block prior to the activation height,
+// is not validated by this plugin.
+func (p *Plugin) validateRedemption(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+	if ctx.BlockHeight < p.activationHeight {
+		return nil // nothing to do
+	}
+	redemption, ok, err := transactionRedemption(tx.Transaction)
+	if err != nil {
+		return gctypes.NewCodedError(gctypes.ErrorCodeInvalidRedemption, err, nil)
+	}
+	if !ok {
+		return nil // nothing to do
+	}
+	if bucket == nil {
+		return errors.New("redemption bucket does not exist")
+	}
+	redemptionsBucket, err := getRedemptionsBucket(bucket)
+	if err != nil {
+		return err
+	}
+	b := redemptionsBucket.Get([]byte(redemption.Reference))
+	if len(b) == 0 {
+		return nil
+	}
+	var record RedemptionRecord
+	err = rivbin.Unmarshal(b, &record)
+	if err != nil {
+		return fmt.Errorf("failed to rivbin unmarshal redemption record: %v", err)
+	}
+	if record.TransactionID == tx.ID() {
+		return nil
+	}
+	return gctypes.NewCodedError(gctypes.ErrorCodeDuplicateRedemption,
+		fmt.Errorf("redemption %q is already redeemed by transaction %s", redemption.Reference, record.TransactionID.String()),
+		map[string]string{
+			"reference":     redemption.Reference,
+			"transactionid": record.TransactionID.String(),
+		})
+}
+
+// Close releases any resources held by the plugin like the PluginViewStorage
+func (p *Plugin) Close() error {
+	if p.storage == nil {
+		return nil
+	}
+	return p.storage.Close()
+}
+
+// transactionRedemption returns the redemption carried by the arbitrary data of the given transaction,
+// validating its shape. False is returned if the arbitrary data does not carry a redemption.
+func transactionRedemption(txn types.Transaction) (rdtypes.Redemption, bool, error) {
+	if !rdtypes.IsRedemptionArbitraryData(txn.ArbitraryData) {
+		return rdtypes.Redemption{}, false, nil
+	}
+	redemption, err := rdtypes.UnmarshalRedemptionArbitraryData(txn.ArbitraryData)
+	if err == nil {
+		err = redemption.Validate()
+	}
+	if err != nil {
+		return rdtypes.Redemption{}, false, err
+	}
+	return redemption, true, nil
+}
+
+func getRedemptionsBucket(bucket *persist.LazyBoltBucket) (*bolt.Bucket, error) {
+	redemptionsBucket, err := bucket.Bucket(bucketRedemptions)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt redemption plugin: did not find any redemptions: %v", err)
+	}
+	return redemptionsBucket, nil
+}
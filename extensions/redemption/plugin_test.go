@@ -0,0 +1,151 @@
+package redemption
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	rdtypes "github.com/nbh-digital/goldchain/extensions/redemption/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+func TestValidateRedemption(t *testing.T) {
+	p := NewPlugin(gctypes.TransactionVersionCoinDestruction, 10)
+	validators := p.TransactionValidatorVersionFunctionMapping()
+	if len(validators[gctypes.TransactionVersionCoinDestruction]) != 1 {
+		t.Fatalf("expected one validator for transaction version %d", gctypes.TransactionVersionCoinDestruction)
+	}
+
+	valid, err := rdtypes.Redemption{
+		Reference:      "RDM-2026-0001",
+		CustomerIDHash: crypto.Hash{42},
+		DeliveryVault:  "zurich-01",
+	}.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := rdtypes.Redemption{
+		Reference:     "RDM-2026-0002",
+		DeliveryVault: "zurich-01",
+	}.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		ArbitraryData []byte
+		Redemption    bool
+		Valid         bool
+	}{
+		{nil, false, true},
+		{[]byte("burn for redemption"), false, true},
+		{valid, true, true},
+		{invalid, false, false},
+		{valid[:len(valid)-1], false, false},
+	}
+	for idx, testCase := range testCases {
+		txn := types.Transaction{
+			Version:       gctypes.TransactionVersionCoinDestruction,
+			ArbitraryData: testCase.ArbitraryData,
+		}
+		_, ok, err := transactionRedemption(txn)
+		if testCase.Valid {
+			if err != nil {
+				t.Error(idx+1, "unexpected error:", err)
+			}
+		} else if err == nil {
+			t.Error(idx+1, "expected error, but none received")
+		}
+		if ok != testCase.Redemption {
+			t.Error(idx+1, "unexpected redemption flag:", ok)
+		}
+		if testCase.Valid {
+			continue
+		}
+		// arbitrary data prior to the activation height is never interpreted as a redemption
+		ctx := types.TransactionValidationContext{ValidationContext: types.ValidationContext{BlockHeight: 9}}
+		err = p.validateRedemption(modules.ConsensusTransaction{Transaction: txn}, ctx, nil)
+		if err != nil {
+			t.Error(idx+1, "unexpected error prior to activation:", err)
+		}
+		// invalid redemptions are refused by the validator, prior to looking up the reference
+		ctx.BlockHeight = 10
+		err = p.validateRedemption(modules.ConsensusTransaction{Transaction: txn}, ctx, nil)
+		if cErr, ok := gctypes.AsCodedError(err); !ok || cErr.Code != gctypes.ErrorCodeInvalidRedemption {
+			t.Error(idx+1, "unexpected error:", err)
+		}
+	}
+}
+
+func TestBurnedAmounts(t *testing.T) {
+	parents := map[types.CoinOutputID]types.CoinOutput{
+		{1}: {Value: types.NewCurrency64(700)},
+		{2}: {Value: types.NewCurrency64(300)},
+	}
+	parent := func(id types.CoinOutputID) (types.CoinOutput, error) {
+		co, ok := parents[id]
+		if !ok {
+			return types.CoinOutput{}, errors.New("unknown coin output")
+		}
+		return co, nil
+	}
+	var uh types.UnlockHash
+	uh.Type = types.UnlockTypePubKey
+	txn := types.Transaction{
+		Version:    gctypes.TransactionVersionCoinDestruction,
+		CoinInputs: []types.CoinInput{{ParentID: types.CoinOutputID{1}}, {ParentID: types.CoinOutputID{2}}},
+		CoinOutputs: []types.CoinOutput{
+			{Value: types.NewCurrency64(5), Condition: types.NewCondition(&cftypes.CustodyFeeCondition{})},
+			{Value: types.NewCurrency64(95), Condition: types.NewCondition(types.NewUnlockHashCondition(uh))},
+		},
+		MinerFees: []types.Currency{types.NewCurrency64(10)},
+	}
+	amount, custodyFee, err := BurnedAmounts(txn, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !amount.Equals64(890) {
+		t.Error("unexpected burned amount:", amount)
+	}
+	if !custodyFee.Equals64(5) {
+		t.Error("unexpected custody fee:", custodyFee)
+	}
+
+	txn.CoinInputs = append(txn.CoinInputs, types.CoinInput{ParentID: types.CoinOutputID{3}})
+	if _, _, err = BurnedAmounts(txn, parent); err == nil {
+		t.Error("expected error for unknown parent coin output")
+	}
+	txn.CoinInputs = nil
+	if _, _, err = BurnedAmounts(txn, parent); err == nil {
+		t.Error("expected error for coin outputs exceeding the coin inputs")
+	}
+}
+
+func TestRedemptionFilter(t *testing.T) {
+	record := RedemptionRecord{
+		Redemption: rdtypes.Redemption{
+			Reference:      "RDM-2026-0001",
+			CustomerIDHash: crypto.Hash{1},
+			DeliveryVault:  "zurich-01",
+		},
+		Status: StatusConfirmed,
+	}
+	testCases := []struct {
+		Filter RedemptionFilter
+		Match  bool
+	}{
+		{RedemptionFilter{}, true},
+		{RedemptionFilter{Status: StatusConfirmed, DeliveryVault: "zurich-01", CustomerIDHash: crypto.Hash{1}}, true},
+		{RedemptionFilter{Status: StatusPending}, false},
+		{RedemptionFilter{DeliveryVault: "london-01"}, false},
+		{RedemptionFilter{CustomerIDHash: crypto.Hash{2}}, false},
+	}
+	for idx, testCase := range testCases {
+		if match := testCase.Filter.Match(record); match != testCase.Match {
+			t.Error(idx+1, "unexpected match:", match)
+		}
+	}
+}
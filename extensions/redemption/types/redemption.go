@@ -0,0 +1,132 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// RedemptionVersion is the version of the (binary) redemption format,
+	// encoded right after the redemption prefix in the arbitrary data of a transaction.
+	RedemptionVersion uint8 = 1
+
+	// MaxReferenceLength is the maximum length of the reference of a redemption request.
+	MaxReferenceLength = 64
+	// MaxDeliveryVaultLength is the maximum length of the ID of a delivery vault.
+	MaxDeliveryVaultLength = 64
+
+	// MinIssuerKeyLength and MaxIssuerKeyLength define the length range of the secret key
+	// of an issuer, used to hash the IDs of its customers.
+	MinIssuerKeyLength = 16
+	MaxIssuerKeyLength = blake2b.Size
+
+	// ArbitraryDataSizeLimit is the maximum size of the arbitrary data of a coin destruction transaction
+	// which carries a redemption, replacing the arbitrary data size limit of the chain.
+	ArbitraryDataSizeLimit = 256
+)
+
+// RedemptionPrefix is the prefix of the arbitrary data of a transaction,
+// identifying that the arbitrary data carries a (binary encoded) redemption.
+var RedemptionPrefix = []byte("GFTRDM")
+
+// Errors returned for redemptions which do not have a valid shape.
+var (
+	ErrInvalidReference     = fmt.Errorf("redemption reference has to be 1 to %d characters, without surrounding whitespace", MaxReferenceLength)
+	ErrNoCustomerIDHash     = errors.New("redemption has no customer ID hash")
+	ErrInvalidDeliveryVault = fmt.Errorf("redemption delivery vault has to be 1 to %d characters, without surrounding whitespace", MaxDeliveryVaultLength)
+	ErrUnknownVersion       = errors.New("unknown redemption version")
+	ErrInvalidIssuerKey     = fmt.Errorf("issuer key has to be %d to %d bytes", MinIssuerKeyLength, MaxIssuerKeyLength)
+)
+
+// Redemption is the redemption request of physical gold, attached to the coin destruction transaction
+// which burns the coins being redeemed, such that the burn can be matched to the request of the customer.
+type Redemption struct {
+	// Reference is the reference of the redemption request, unique for each request
+	Reference string `json:"reference"`
+	// CustomerIDHash is the keyed hash of the ID of the customer who requested the redemption,
+	// such that the customer can be matched by the issuer without disclosing their ID on the chain
+	CustomerIDHash crypto.Hash `json:"customeridhash"`
+	// DeliveryVault identifies the vault from which the gold is to be delivered
+	DeliveryVault string `json:"deliveryvault"`
+}
+
+// HashCustomerID returns the hash of the given customer ID, as used by a redemption,
+// keyed using the secret key of the issuer. Customer IDs are often short or predictable,
+// which is why an unkeyed hash could be reversed by anyone by simply hashing all candidate IDs.
+// Only the issuer can compute and match the hashes of its customers.
+func HashCustomerID(issuerKey []byte, id string) (crypto.Hash, error) {
+	if len(issuerKey) < MinIssuerKeyLength || len(issuerKey) > MaxIssuerKeyLength {
+		return crypto.Hash{}, ErrInvalidIssuerKey
+	}
+	mac, err := blake2b.New256(issuerKey)
+	if err != nil {
+		return crypto.Hash{}, err
+	}
+	mac.Write([]byte(id))
+	var h crypto.Hash
+	copy(h[:], mac.Sum(nil))
+	return h, nil
+}
+
+// Validate ensures the redemption has a valid shape.
+func (r Redemption) Validate() error {
+	if !validIdentifier(r.Reference, MaxReferenceLength) {
+		return ErrInvalidReference
+	}
+	if r.CustomerIDHash == (crypto.Hash{}) {
+		return ErrNoCustomerIDHash
+	}
+	if !validIdentifier(r.DeliveryVault, MaxDeliveryVaultLength) {
+		return ErrInvalidDeliveryVault
+	}
+	return nil
+}
+
+func validIdentifier(str string, maxLength int) bool {
+	return str != "" && len(str) <= maxLength && strings.TrimSpace(str) == str
+}
+
+// MarshalArbitraryData encodes the redemption as the arbitrary data of a transaction.
+func (r Redemption) MarshalArbitraryData() ([]byte, error) {
+	b, err := rivbin.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rivbin marshal redemption: %v", err)
+	}
+	data := make([]byte, 0, len(RedemptionPrefix)+1+len(b))
+	data = append(data, RedemptionPrefix...)
+	data = append(data, RedemptionVersion)
+	return append(data, b...), nil
+}
+
+// IsRedemptionArbitraryData returns true if the given arbitrary data carries a redemption,
+// which does not mean the redemption can be decoded or has a valid shape.
+func IsRedemptionArbitraryData(data []byte) bool {
+	return bytes.HasPrefix(data, RedemptionPrefix)
+}
+
+// UnmarshalRedemptionArbitraryData decodes the redemption carried by the given arbitrary data.
+func UnmarshalRedemptionArbitraryData(data []byte) (Redemption, error) {
+	if !IsRedemptionArbitraryData(data) {
+		return Redemption{}, errors.New("arbitrary data does not carry a redemption")
+	}
+	data = data[len(RedemptionPrefix):]
+	if len(data) == 0 || data[0] != RedemptionVersion {
+		return Redemption{}, ErrUnknownVersion
+	}
+	reader := bytes.NewReader(data[1:])
+	var r Redemption
+	err := rivbin.NewDecoder(reader).Decode(&r)
+	if err != nil {
+		return Redemption{}, fmt.Errorf("failed to rivbin unmarshal redemption: %v", err)
+	}
+	if reader.Len() != 0 {
+		return Redemption{}, fmt.Errorf("redemption is followed by %d unexpected bytes", reader.Len())
+	}
+	return r, nil
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+)
+
+func TestHashCustomerID(t *testing.T) {
+	key := []byte("issuer-secret-key-0001")
+	h, err := HashCustomerID(key, "customer-1337")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == crypto.HashBytes([]byte("customer-1337")) {
+		t.Error("expected the customer ID hash to be keyed")
+	}
+	if h2, _ := HashCustomerID(key, "customer-1337"); h2 != h {
+		t.Error("expected the customer ID hash to be deterministic")
+	}
+	if h2, _ := HashCustomerID([]byte("issuer-secret-key-0002"), "customer-1337"); h2 == h {
+		t.Error("expected the customer ID hash to depend on the issuer key")
+	}
+	for _, invalid := range [][]byte{nil, make([]byte, MinIssuerKeyLength-1), make([]byte, MaxIssuerKeyLength+1)} {
+		if _, err = HashCustomerID(invalid, "customer-1337"); err != ErrInvalidIssuerKey {
+			t.Errorf("expected %v for a key of %d bytes, received %v", ErrInvalidIssuerKey, len(invalid), err)
+		}
+	}
+}
+
+func TestRedemptionArbitraryDataRoundTrip(t *testing.T) {
+	redemption := Redemption{
+		Reference:      "RDM-2026-0042",
+		CustomerIDHash: crypto.Hash{13, 37},
+		DeliveryVault:  "zurich-01",
+	}
+	data, err := redemption.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsRedemptionArbitraryData(data) {
+		t.Fatal("expected arbitrary data to carry a redemption")
+	}
+	decoded, err := UnmarshalRedemptionArbitraryData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != redemption {
+		t.Fatalf("%v != %v", redemption, decoded)
+	}
+
+	if IsRedemptionArbitraryData([]byte("redemption of 1kg")) {
+		t.Error("expected description not to carry a redemption")
+	}
+	for idx, invalid := range [][]byte{
+		append([]byte(nil), data[:len(data)-1]...),
+		append(append([]byte(nil), data...), 0),
+		append(append([]byte(nil), RedemptionPrefix...), RedemptionVersion+1),
+		append([]byte(nil), RedemptionPrefix...),
+	} {
+		if _, err = UnmarshalRedemptionArbitraryData(invalid); err == nil {
+			t.Error(idx+1, "expected decoding to fail")
+		}
+	}
+}
+
+func TestRedemptionValidate(t *testing.T) {
+	largest := Redemption{
+		Reference:      strings.Repeat("r", MaxReferenceLength),
+		CustomerIDHash: crypto.Hash{1},
+		DeliveryVault:  strings.Repeat("v", MaxDeliveryVaultLength),
+	}
+	if err := largest.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := largest.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > ArbitraryDataSizeLimit {
+		t.Fatalf("largest valid redemption (%d bytes) exceeds the size limit of %d bytes", len(data), ArbitraryDataSizeLimit)
+	}
+
+	testCases := []struct {
+		modify func(r *Redemption)
+		err    error
+	}{
+		{func(r *Redemption) { r.Reference = "" }, ErrInvalidReference},
+		{func(r *Redemption) { r.Reference += "r" }, ErrInvalidReference},
+		{func(r *Redemption) { r.Reference = " RDM-1" }, ErrInvalidReference},
+		{func(r *Redemption) { r.CustomerIDHash = crypto.Hash{} }, ErrNoCustomerIDHash},
+		{func(r *Redemption) { r.DeliveryVault = "" }, ErrInvalidDeliveryVault},
+		{func(r *Redemption) { r.DeliveryVault += "v" }, ErrInvalidDeliveryVault},
+	}
+	for idx, testCase := range testCases {
+		r := largest
+		testCase.modify(&r)
+		if err := r.Validate(); err != testCase.err {
+			t.Errorf("test case #%d: expected error %v, got %v", idx+1, testCase.err, err)
+		}
+	}
+}
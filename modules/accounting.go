@@ -0,0 +1,323 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+)
+
+// WalletMovement is a single movement of coins from or to the wallet, as part of a confirmed transaction.
+// Movements are used to export the history of the wallet for accounting purposes.
+type WalletMovement struct {
+	Timestamp     types.Timestamp      `json:"timestamp"`
+	TransactionID types.TransactionID  `json:"transactionid"`
+	Direction     TransactionDirection `json:"direction"`
+	// Counterparty is the address the coins are received from or sent to,
+	// undefined for miner payouts, minted coins and fees
+	Counterparty *types.UnlockHash `json:"counterparty,omitempty"`
+	// GrossAmount is the amount of coins received or sent, fees excluded
+	GrossAmount types.Currency `json:"grossamount"`
+	// InputID is only defined for the custody fee paid to spend a coin input of the wallet
+	InputID    *types.CoinOutputID `json:"inputid,omitempty"`
+	CustodyFee types.Currency      `json:"custodyfee"`
+	MinerFee   types.Currency      `json:"minerfee"`
+	// Balance is the confirmed coin balance of the wallet after the movement,
+	// custody fees being accounted for at the moment they are paid
+	Balance types.Currency `json:"balance"`
+}
+
+// WalletMovements splits the given confirmed transactions, in chronological order,
+// into the coin movements of the wallet, starting from the given balance.
+// The custody fee information of the processed transactions is used
+// to report the custody fee paid for each coin input spent by the wallet.
+//
+// Incoming transactions result in a movement per coin output received by the wallet.
+// Outgoing transactions result in a movement per coin output sent to another address,
+// a movement per custody fee paid, a movement for the miner fees and
+// a movement for any coins spent otherwise (e.g. destroyed).
+func WalletMovements(pts []ProcessedTransaction, balance types.Currency) []WalletMovement {
+	var movements []WalletMovement
+	for _, pt := range pts {
+		var (
+			outgoing              bool
+			received, spent       types.Currency
+			sender                *types.UnlockHash
+			custodyFees, external types.Currency
+			txnMovements          []WalletMovement
+		)
+		newMovement := func(direction TransactionDirection) WalletMovement {
+			return WalletMovement{
+				Timestamp:     pt.ConfirmationTimestamp,
+				TransactionID: pt.TransactionID,
+				Direction:     direction,
+			}
+		}
+		for idx := range pt.Inputs {
+			input := &pt.Inputs[idx]
+			if input.FundType != types.SpecifierCoinInput {
+				continue
+			}
+			if !input.WalletAddress {
+				if sender == nil {
+					sender = &input.RelatedAddress
+				}
+				continue
+			}
+			outgoing = true
+			spent = spent.Add(input.Value)
+			if input.CoinInfo != nil && !input.CoinInfo.CustodyFee.IsZero() {
+				custodyFees = custodyFees.Add(input.CoinInfo.CustodyFee)
+				movement := newMovement(TransactionDirectionOutgoing)
+				inputID := types.CoinOutputID(input.ParentOutputID)
+				movement.InputID = &inputID
+				movement.CustodyFee = input.CoinInfo.CustodyFee
+				txnMovements = append(txnMovements, movement)
+			}
+		}
+		for idx := range pt.Outputs {
+			output := &pt.Outputs[idx]
+			if output.FundType != types.SpecifierCoinOutput && output.FundType != types.SpecifierMinerPayout {
+				continue
+			}
+			if output.WalletAddress {
+				received = received.Add(output.Value)
+				if !outgoing {
+					movement := newMovement(TransactionDirectionIncoming)
+					movement.Counterparty = sender
+					movement.GrossAmount = output.Value
+					txnMovements = append(txnMovements, movement)
+				}
+				continue
+			}
+			if !outgoing || (output.CoinInfo != nil && output.CoinInfo.IsCustodyFee) {
+				continue
+			}
+			external = external.Add(output.Value)
+			movement := newMovement(TransactionDirectionOutgoing)
+			movement.Counterparty = &output.RelatedAddress
+			movement.GrossAmount = output.Value
+			txnMovements = append(txnMovements, movement)
+		}
+		if outgoing {
+			var minerFees types.Currency
+			for _, fee := range pt.Transaction.MinerFees {
+				minerFees = minerFees.Add(fee)
+			}
+			if !minerFees.IsZero() {
+				movement := newMovement(TransactionDirectionOutgoing)
+				movement.MinerFee = minerFees
+				txnMovements = append(txnMovements, movement)
+			}
+			// coins spent by the wallet, which are neither received by another address nor paid as fees
+			accounted := received.Add(external).Add(custodyFees).Add(minerFees)
+			if spent.Cmp(accounted) > 0 {
+				movement := newMovement(TransactionDirectionOutgoing)
+				movement.GrossAmount = spent.Sub(accounted)
+				txnMovements = append(txnMovements, movement)
+			}
+		}
+
+		// compute the running balance, ensuring the balance after the last movement
+		// of a transaction is exact, even if not all coins spent are accounted for by the movements
+		balanceAfter := subCurrencySaturated(balance.Add(received), spent)
+		for idx := range txnMovements {
+			movement := &txnMovements[idx]
+			if movement.Direction == TransactionDirectionIncoming {
+				balance = balance.Add(movement.GrossAmount)
+			} else {
+				balance = subCurrencySaturated(balance, movement.GrossAmount.Add(movement.CustodyFee).Add(movement.MinerFee))
+			}
+			movement.Balance = balance
+		}
+		if len(txnMovements) > 0 {
+			txnMovements[len(txnMovements)-1].Balance = balanceAfter
+		}
+		balance = balanceAfter
+		movements = append(movements, txnMovements...)
+	}
+	return movements
+}
+
+// subCurrencySaturated returns a - b, or zero if b is greater than a.
+func subCurrencySaturated(a, b types.Currency) types.Currency {
+	if a.Cmp(b) <= 0 {
+		return types.ZeroCurrency
+	}
+	return a.Sub(b)
+}
+
+// StatementPeriod defines the length of the periods of a wallet statement.
+type StatementPeriod string
+
+// The supported wallet statement periods, periods start at midnight UTC.
+const (
+	StatementPeriodDay   StatementPeriod = "day"
+	StatementPeriodMonth StatementPeriod = "month"
+)
+
+// MaxStatementPeriods is the maximum amount of periods a single wallet statement can contain.
+const MaxStatementPeriods = 1000
+
+// ErrStatementTooManyPeriods is returned for a wallet statement
+// which would contain more than MaxStatementPeriods periods.
+var ErrStatementTooManyPeriods = fmt.Errorf("statement cannot contain more than %d periods, narrow its time range or use a longer period", MaxStatementPeriods)
+
+// WalletStatementPeriod summarizes the coin movements of the wallet within a single period [Start, End).
+type WalletStatementPeriod struct {
+	Start types.Timestamp `json:"start"`
+	End   types.Timestamp `json:"end"`
+
+	OpeningBalance types.Currency `json:"openingbalance"`
+	// Incoming and Outgoing are the gross amounts received and sent, fees excluded
+	Incoming        types.Currency `json:"incoming"`
+	Outgoing        types.Currency `json:"outgoing"`
+	CustodyFeesPaid types.Currency `json:"custodyfeespaid"`
+	MinerFeesPaid   types.Currency `json:"minerfeespaid"`
+	// CustodyFeesAccrued are the custody fees accrued by the coin outputs,
+	// which are still unspent at the end of the period, and thus not yet paid
+	CustodyFeesAccrued types.Currency `json:"custodyfeesaccrued"`
+	ClosingBalance     types.Currency `json:"closingbalance"`
+}
+
+// WalletStatement summarizes the given confirmed transactions, in chronological order,
+// as a statement per period, for all periods overlapping with [start, end].
+// An undefined start or end defaults to the time of the first transaction and the current time.
+// Custody fees accrued but unpaid are evaluated at the end of each period,
+// or at the end of the statement, or the given current time, should the period end later.
+// ErrStatementTooManyPeriods is returned if more than MaxStatementPeriods periods would be covered.
+func WalletStatement(pts []ProcessedTransaction, period StatementPeriod, start, end, now types.Timestamp) ([]WalletStatementPeriod, error) {
+	if period != StatementPeriodDay && period != StatementPeriodMonth {
+		return nil, fmt.Errorf("unknown statement period: %q", period)
+	}
+	if start == 0 {
+		// start from the period of the first transaction by default
+		start = now
+		if len(pts) > 0 {
+			start = pts[0].ConfirmationTimestamp
+		}
+	}
+	if end == 0 {
+		end = now
+	}
+	if start > end {
+		return nil, errors.New("statement start cannot be after its end")
+	}
+	periods := 0
+	for periodStart := statementPeriodStart(start, period); periodStart <= end; periodStart = statementPeriodNext(periodStart, period) {
+		periods++
+		if periods > MaxStatementPeriods {
+			return nil, ErrStatementTooManyPeriods
+		}
+	}
+
+	// collect the lifetime of all coin outputs of the wallet,
+	// such that the custody fees they accrued can be computed at any time
+	type coinOutputLifetime struct {
+		Value          types.Currency
+		Created, Spent types.Timestamp
+	}
+	var outputs []*coinOutputLifetime
+	outputMap := make(map[types.OutputID]*coinOutputLifetime)
+	for _, pt := range pts {
+		for _, input := range pt.Inputs {
+			if output, ok := outputMap[input.ParentOutputID]; ok && input.WalletAddress {
+				output.Spent = pt.ConfirmationTimestamp
+			}
+		}
+		for _, output := range pt.Outputs {
+			if !output.WalletAddress || (output.FundType != types.SpecifierCoinOutput && output.FundType != types.SpecifierMinerPayout) {
+				continue
+			}
+			lifetime := &coinOutputLifetime{Value: output.Value, Created: pt.ConfirmationTimestamp}
+			outputs = append(outputs, lifetime)
+			outputMap[output.OutputID] = lifetime
+		}
+	}
+	// the periods are evaluated in chronological order, such that the outputs,
+	// ordered by creation, only have to be activated and dropped once
+	var (
+		active []*coinOutputLifetime
+		next   int
+	)
+	accruedAt := func(ts types.Timestamp) (fees types.Currency) {
+		for ; next < len(outputs) && outputs[next].Created < ts; next++ {
+			active = append(active, outputs[next])
+		}
+		unspent := active[:0]
+		for _, output := range active {
+			if output.Spent != 0 && output.Spent < ts {
+				continue // spent prior to this and all following periods
+			}
+			unspent = append(unspent, output)
+			_, fee := custodyfees.AmountCustodyFeePairAfterXSeconds(output.Value, ts-output.Created)
+			fees = fees.Add(fee)
+		}
+		active = unspent
+		return
+	}
+
+	var (
+		statement = make([]WalletStatementPeriod, 0, periods)
+		balance   types.Currency
+		movements = WalletMovements(pts, types.ZeroCurrency)
+	)
+	for periodStart := statementPeriodStart(start, period); periodStart <= end; {
+		periodEnd := statementPeriodNext(periodStart, period)
+		sp := WalletStatementPeriod{
+			Start:          periodStart,
+			End:            periodEnd,
+			OpeningBalance: balance,
+		}
+		for len(movements) > 0 && movements[0].Timestamp < periodEnd {
+			movement := movements[0]
+			movements = movements[1:]
+			if movement.Timestamp >= periodStart {
+				if movement.Direction == TransactionDirectionIncoming {
+					sp.Incoming = sp.Incoming.Add(movement.GrossAmount)
+				} else {
+					sp.Outgoing = sp.Outgoing.Add(movement.GrossAmount)
+				}
+				sp.CustodyFeesPaid = sp.CustodyFeesPaid.Add(movement.CustodyFee)
+				sp.MinerFeesPaid = sp.MinerFeesPaid.Add(movement.MinerFee)
+			} else {
+				// movement prior to the first period, only affecting the opening balance
+				sp.OpeningBalance = movement.Balance
+			}
+			balance = movement.Balance
+		}
+		sp.ClosingBalance = balance
+		accruedTime := periodEnd
+		if end < accruedTime {
+			accruedTime = end
+		}
+		if now < accruedTime {
+			accruedTime = now
+		}
+		sp.CustodyFeesAccrued = accruedAt(accruedTime)
+		statement = append(statement, sp)
+		periodStart = periodEnd
+	}
+	return statement, nil
+}
+
+// statementPeriodStart returns the start of the period which contains the given timestamp.
+func statementPeriodStart(ts types.Timestamp, period StatementPeriod) types.Timestamp {
+	t := time.Unix(int64(ts), 0).UTC()
+	if period == StatementPeriodMonth {
+		return types.Timestamp(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Unix())
+	}
+	return types.Timestamp(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix())
+}
+
+// statementPeriodNext returns the start of the period following the period starting at the given timestamp.
+func statementPeriodNext(ts types.Timestamp, period StatementPeriod) types.Timestamp {
+	t := time.Unix(int64(ts), 0).UTC()
+	if period == StatementPeriodMonth {
+		return types.Timestamp(t.AddDate(0, 1, 0).Unix())
+	}
+	return types.Timestamp(t.AddDate(0, 0, 1).Unix())
+}
@@ -0,0 +1,230 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+)
+
+// TestWalletMovements checks that confirmed transactions are split into
+// the coin movements of the wallet, including fees and the running balance.
+func TestWalletMovements(t *testing.T) {
+	var (
+		wallet, sender, receiver types.UnlockHash
+		ids                      [3]types.TransactionID
+	)
+	wallet.Type, sender.Type, receiver.Type = types.UnlockTypePubKey, types.UnlockTypePubKey, types.UnlockTypePubKey
+	wallet.Hash[0], sender.Hash[0], receiver.Hash[0] = 1, 2, 3
+	for i := range ids {
+		ids[i][0] = byte(i + 1)
+	}
+	pts := []ProcessedTransaction{
+		// receive 100 coins
+		{
+			TransactionID:         ids[0],
+			ConfirmationTimestamp: 1,
+			Inputs: []ProcessedInput{
+				{FundType: types.SpecifierCoinInput, RelatedAddress: sender, Value: types.NewCurrency64(150)},
+			},
+			Outputs: []ProcessedOutput{
+				{FundType: types.SpecifierCoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(100)},
+				{FundType: types.SpecifierCoinOutput, RelatedAddress: sender, Value: types.NewCurrency64(49)},
+			},
+		},
+		// send 60 coins, paying a custody fee of 5 coins and a miner fee of 1 coin
+		{
+			Transaction:           types.Transaction{MinerFees: []types.Currency{types.NewCurrency64(1)}},
+			TransactionID:         ids[1],
+			ConfirmationTimestamp: 2,
+			Inputs: []ProcessedInput{
+				{
+					FundType: types.SpecifierCoinInput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(100),
+					CoinInfo: &custodyfees.CoinOutputInfo{CustodyFee: types.NewCurrency64(5)},
+				},
+			},
+			Outputs: []ProcessedOutput{
+				{FundType: types.SpecifierCoinOutput, RelatedAddress: receiver, Value: types.NewCurrency64(60)},
+				{
+					FundType: types.SpecifierCoinOutput, Value: types.NewCurrency64(5),
+					CoinInfo: &custodyfees.CoinOutputInfo{IsCustodyFee: true},
+				},
+				{FundType: types.SpecifierCoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(34)},
+			},
+		},
+		// destroy 30 coins, paying a miner fee of 1 coin
+		{
+			Transaction:           types.Transaction{MinerFees: []types.Currency{types.NewCurrency64(1)}},
+			TransactionID:         ids[2],
+			ConfirmationTimestamp: 3,
+			Inputs: []ProcessedInput{
+				{FundType: types.SpecifierCoinInput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(34)},
+			},
+			Outputs: []ProcessedOutput{
+				{FundType: types.SpecifierCoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(3)},
+			},
+		},
+	}
+
+	type expectedMovement struct {
+		TransactionID types.TransactionID
+		Direction     TransactionDirection
+		Counterparty  *types.UnlockHash
+		GrossAmount   uint64
+		CustodyFee    uint64
+		MinerFee      uint64
+		Balance       uint64
+	}
+	expected := []expectedMovement{
+		{ids[0], TransactionDirectionIncoming, &sender, 100, 0, 0, 110},
+		{ids[1], TransactionDirectionOutgoing, nil, 0, 5, 0, 105},
+		{ids[1], TransactionDirectionOutgoing, &receiver, 60, 0, 0, 45},
+		{ids[1], TransactionDirectionOutgoing, nil, 0, 0, 1, 44},
+		{ids[2], TransactionDirectionOutgoing, nil, 0, 0, 1, 43},
+		{ids[2], TransactionDirectionOutgoing, nil, 30, 0, 0, 13},
+	}
+	movements := WalletMovements(pts, types.NewCurrency64(10))
+	if len(movements) != len(expected) {
+		t.Fatalf("expected %d movements, received %d: %+v", len(expected), len(movements), movements)
+	}
+	for idx, movement := range movements {
+		e := expected[idx]
+		if movement.TransactionID != e.TransactionID || movement.Direction != e.Direction ||
+			!movement.GrossAmount.Equals64(e.GrossAmount) || !movement.CustodyFee.Equals64(e.CustodyFee) ||
+			!movement.MinerFee.Equals64(e.MinerFee) || !movement.Balance.Equals64(e.Balance) {
+			t.Errorf("movement #%d: expected %+v, received %+v", idx, e, movement)
+		}
+		if (movement.Counterparty == nil) != (e.Counterparty == nil) ||
+			(e.Counterparty != nil && *movement.Counterparty != *e.Counterparty) {
+			t.Errorf("movement #%d: unexpected counterparty %v", idx, movement.Counterparty)
+		}
+		if (movement.InputID != nil) != !movement.CustodyFee.IsZero() {
+			t.Errorf("movement #%d: input ID should only be defined for custody fees", idx)
+		}
+	}
+}
+
+// TestWalletStatement checks that the monthly statement of a wallet summarizes its movements,
+// and computes the custody fees accrued but unpaid at the end of each period.
+func TestWalletStatement(t *testing.T) {
+	var wallet, receiver types.UnlockHash
+	wallet.Type, receiver.Type = types.UnlockTypePubKey, types.UnlockTypePubKey
+	wallet.Hash[0], receiver.Hash[0] = 1, 2
+	ts := func(year int, month time.Month, day int) types.Timestamp {
+		return types.Timestamp(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix())
+	}
+	var (
+		received, sent = ts(2020, time.January, 15), ts(2020, time.February, 10)
+		now            = ts(2020, time.March, 15)
+		value          = types.NewCurrency64(1e12)
+		_, fee         = custodyfees.AmountCustodyFeePairAfterXSeconds(value, sent-received)
+		minerFee       = types.NewCurrency64(1e9)
+		change         = value.Sub(types.NewCurrency64(9e11)).Sub(fee).Sub(minerFee)
+		changeID       = types.OutputID{2}
+	)
+	pts := []ProcessedTransaction{
+		{
+			TransactionID:         types.TransactionID{1},
+			ConfirmationTimestamp: received,
+			Outputs: []ProcessedOutput{
+				{FundType: types.SpecifierCoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: value, OutputID: types.OutputID{1}},
+			},
+		},
+		{
+			Transaction:           types.Transaction{MinerFees: []types.Currency{minerFee}},
+			TransactionID:         types.TransactionID{2},
+			ConfirmationTimestamp: sent,
+			Inputs: []ProcessedInput{
+				{
+					FundType: types.SpecifierCoinInput, WalletAddress: true, RelatedAddress: wallet, Value: value, ParentOutputID: types.OutputID{1},
+					CoinInfo: &custodyfees.CoinOutputInfo{CustodyFee: fee},
+				},
+			},
+			Outputs: []ProcessedOutput{
+				{FundType: types.SpecifierCoinOutput, RelatedAddress: receiver, Value: types.NewCurrency64(9e11)},
+				{FundType: types.SpecifierCoinOutput, Value: fee, CoinInfo: &custodyfees.CoinOutputInfo{IsCustodyFee: true}},
+				{FundType: types.SpecifierCoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: change, OutputID: changeID},
+			},
+		},
+	}
+	accrued := func(value types.Currency, from, to types.Timestamp) types.Currency {
+		_, fee := custodyfees.AmountCustodyFeePairAfterXSeconds(value, to-from)
+		return fee
+	}
+	expected := []WalletStatementPeriod{
+		{
+			Start: ts(2020, time.January, 1), End: ts(2020, time.February, 1),
+			Incoming:           value,
+			CustodyFeesAccrued: accrued(value, received, ts(2020, time.February, 1)),
+			ClosingBalance:     value,
+		},
+		{
+			Start: ts(2020, time.February, 1), End: ts(2020, time.March, 1),
+			OpeningBalance:     value,
+			Outgoing:           types.NewCurrency64(9e11),
+			CustodyFeesPaid:    fee,
+			MinerFeesPaid:      minerFee,
+			CustodyFeesAccrued: accrued(change, sent, ts(2020, time.March, 1)),
+			ClosingBalance:     change,
+		},
+		{
+			Start: ts(2020, time.March, 1), End: ts(2020, time.April, 1),
+			OpeningBalance:     change,
+			CustodyFeesAccrued: accrued(change, sent, now),
+			ClosingBalance:     change,
+		},
+	}
+	if fee.IsZero() || expected[0].CustodyFeesAccrued.IsZero() {
+		t.Fatal("expected non-zero custody fees")
+	}
+
+	statement, err := WalletStatement(pts, StatementPeriodMonth, 0, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statement) != len(expected) {
+		t.Fatalf("expected %d periods, received %d: %+v", len(expected), len(statement), statement)
+	}
+	for idx, period := range statement {
+		e := expected[idx]
+		if period.Start != e.Start || period.End != e.End ||
+			!period.OpeningBalance.Equals(e.OpeningBalance) || !period.ClosingBalance.Equals(e.ClosingBalance) ||
+			!period.Incoming.Equals(e.Incoming) || !period.Outgoing.Equals(e.Outgoing) ||
+			!period.CustodyFeesPaid.Equals(e.CustodyFeesPaid) || !period.MinerFeesPaid.Equals(e.MinerFeesPaid) ||
+			!period.CustodyFeesAccrued.Equals(e.CustodyFeesAccrued) {
+			t.Errorf("period #%d: expected %+v, received %+v", idx, e, period)
+		}
+	}
+
+	// a statement starting after the first transaction, still takes it into account for its opening balance
+	statement, err = WalletStatement(pts, StatementPeriodDay, ts(2020, time.March, 2), ts(2020, time.March, 3), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statement) != 2 || !statement[0].OpeningBalance.Equals(change) || !statement[1].ClosingBalance.Equals(change) {
+		t.Errorf("unexpected daily statement: %+v", statement)
+	}
+
+	// a statement ending during a period, evaluates the custody fees accrued at its end
+	end := ts(2020, time.February, 20)
+	statement, err = WalletStatement(pts, StatementPeriodMonth, 0, end, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statement) != 2 || !statement[1].CustodyFeesAccrued.Equals(accrued(change, sent, end)) {
+		t.Errorf("unexpected statement ending during a period: %+v", statement)
+	}
+
+	_, err = WalletStatement(pts, "year", 0, 0, now)
+	if err == nil {
+		t.Error("expected unknown period to be rejected")
+	}
+
+	// a daily statement over a decade covers too many periods
+	_, err = WalletStatement(pts, StatementPeriodDay, ts(2010, time.January, 1), ts(2020, time.January, 1), now)
+	if err != ErrStatementTooManyPeriods {
+		t.Errorf("expected %v, received %v", ErrStatementTooManyPeriods, err)
+	}
+}
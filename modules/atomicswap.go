@@ -0,0 +1,130 @@
+package modules
+
+import (
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+type (
+	// AtomicSwapContract describes an atomic swap contract, locked in a coin output,
+	// together with the custody fee to be paid when redeeming or refunding it.
+	AtomicSwapContract struct {
+		OutputID        types.CoinOutputID        `json:"outputid"`
+		ContractAddress types.UnlockHash          `json:"contractaddress"`
+		Contract        types.AtomicSwapCondition `json:"contract"`
+		// MultiSigReceiver is only defined for a contract redeemed by the co-signers of a multisig receiver,
+		// in which case the receiver of the contract is the address of this multisig condition
+		MultiSigReceiver *types.MultiSignatureCondition `json:"multisigreceiver,omitempty"`
+		// Value is the value the contract was created with,
+		// SpendableValue is the value that remains after paying
+		// the custody fee computed at the fee computation time
+		Value              types.Currency  `json:"value"`
+		FeeComputationTime types.Timestamp `json:"feecomputationtime"`
+		CustodyFee         types.Currency  `json:"custodyfee"`
+		SpendableValue     types.Currency  `json:"spendablevalue"`
+		// WorstCaseCustodyFee is the custody fee to be paid when the contract is spent at its time lock,
+		// GuaranteedValue is the value the receiver receives at least when redeeming the contract until then,
+		// the miner fee of the redeem transaction already subtracted from it
+		WorstCaseCustodyFee types.Currency `json:"worstcasecustodyfee"`
+		GuaranteedValue     types.Currency `json:"guaranteedvalue"`
+		// Confirmed is false for a contract which is still in the transaction pool
+		Confirmed bool `json:"confirmed"`
+		// Refundable is true once the time lock of the contract has been reached,
+		// from then on the contract can be refunded by the sender, as long as it hasn't been redeemed yet
+		Refundable bool `json:"refundable"`
+	}
+
+	// AtomicSwapReceiver is a receiver of a (multi-output) atomic swap contract,
+	// together with the amount of coins locked for it.
+	AtomicSwapReceiver struct {
+		Address types.UnlockHash `json:"address"`
+		Amount  types.Currency   `json:"amount"`
+		// MultiSigCondition has to be defined for a multisig receiver, as its co-signers redeem the contract,
+		// the address is optional in that case, as it is the address of this condition
+		MultiSigCondition *types.MultiSignatureCondition `json:"multisigcondition,omitempty"`
+	}
+
+	// AtomicSwapContractState is the state of an atomic swap contract tracked by the wallet.
+	AtomicSwapContractState string
+
+	// TrackedAtomicSwapContract is a confirmed atomic swap contract, of which the wallet owns
+	// the sender and/or receiver address, as tracked by the wallet in order to redeem or refund it automatically.
+	TrackedAtomicSwapContract struct {
+		OutputID        types.CoinOutputID        `json:"outputid"`
+		ContractAddress types.UnlockHash          `json:"contractaddress"`
+		Contract        types.AtomicSwapCondition `json:"contract"`
+		// MultiSigReceiver is only defined for a contract redeemed by the co-signers of a multisig receiver
+		MultiSigReceiver   *types.MultiSignatureCondition `json:"multisigreceiver,omitempty"`
+		Value              types.Currency                 `json:"value"`
+		ConfirmationHeight types.BlockHeight              `json:"confirmationheight"`
+		// IsSender is true if the wallet can refund the contract,
+		// IsReceiver is true if the wallet can redeem the contract,
+		// or co-sign its redemption in case of a multisig receiver
+		IsSender   bool                    `json:"issender"`
+		IsReceiver bool                    `json:"isreceiver"`
+		State      AtomicSwapContractState `json:"state"`
+		// SpendHeight and SpendTransactionID identify the transaction
+		// which redeemed or refunded the contract, and are only defined once it is spent
+		SpendHeight        types.BlockHeight    `json:"spendheight,omitempty"`
+		SpendTransactionID *types.TransactionID `json:"spendtransactionid,omitempty"`
+		// Secret is only defined once the secret matching the hashed secret
+		// of the contract has been revealed on chain, by any transaction
+		Secret *types.AtomicSwapSecret `json:"secret,omitempty"`
+		// Refundable is true for an open contract of which the time lock has been reached,
+		// computed at the time of the current block
+		Refundable bool `json:"refundable"`
+	}
+)
+
+const (
+	// AtomicSwapContractStateOpen is the state of a tracked contract which isn't spent yet.
+	AtomicSwapContractStateOpen AtomicSwapContractState = "open"
+	// AtomicSwapContractStateRedeemed is the state of a tracked contract redeemed by its receiver.
+	AtomicSwapContractStateRedeemed AtomicSwapContractState = "redeemed"
+	// AtomicSwapContractStateRefunded is the state of a tracked contract refunded by its sender.
+	AtomicSwapContractStateRefunded AtomicSwapContractState = "refunded"
+)
+
+// AtomicSwapContractCondition returns the atomic swap contract defined by the given condition,
+// which is either a regular or a multisig atomic swap condition, in which case the multisig receiver is returned as well.
+// False is returned if the condition does not define an atomic swap contract.
+func AtomicSwapContractCondition(condition types.UnlockConditionProxy) (types.AtomicSwapCondition, *types.MultiSignatureCondition, bool) {
+	switch c := condition.Condition.(type) {
+	case *types.AtomicSwapCondition:
+		return *c, nil, true
+	case *gctypes.MultiSignatureAtomicSwapCondition:
+		receiver := c.ReceiverCondition()
+		return c.AtomicSwapCondition(), &receiver, true
+	default:
+		return types.AtomicSwapCondition{}, nil, false
+	}
+}
+
+// AtomicSwapContractGuarantees computes the custody fee to be paid for an atomic swap contract,
+// created with the given value at the given time, when it is spent at its time lock,
+// as well as the value its receiver receives at least when redeeming it until then, using the given miner fee.
+func AtomicSwapContractGuarantees(value types.Currency, creationTime types.Timestamp, condition types.AtomicSwapCondition, minerFee types.Currency) (guaranteedValue, worstCaseCustodyFee types.Currency) {
+	spendableValue, worstCaseCustodyFee := custodyfees.AmountCustodyFeePairAfterXSeconds(value, atomicSwapContractDuration(creationTime, condition.TimeLock))
+	if spendableValue.Cmp(minerFee) > 0 {
+		guaranteedValue = spendableValue.Sub(minerFee)
+	}
+	return guaranteedValue, worstCaseCustodyFee
+}
+
+// AtomicSwapEscrowValue computes the value an atomic swap contract, created at the given time, has to lock,
+// such that its receiver receives at least the given amount when redeeming it until its time lock,
+// using the given miner fee. The difference with the given amount is the custody fee buffer
+// pre-funded by the sender of the contract, together with the miner fee.
+func AtomicSwapEscrowValue(amount types.Currency, creationTime, timeLock types.Timestamp, minerFee types.Currency) types.Currency {
+	return custodyfees.AmountRequiredForSpendableAmountAfterXSeconds(amount.Add(minerFee), atomicSwapContractDuration(creationTime, timeLock))
+}
+
+// atomicSwapContractDuration returns the amount of seconds between the creation of a contract and its time lock.
+func atomicSwapContractDuration(creationTime, timeLock types.Timestamp) types.Timestamp {
+	if timeLock <= creationTime {
+		return 0
+	}
+	return timeLock - creationTime
+}
@@ -0,0 +1,43 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestAtomicSwapEscrowValue checks that the receiver of a contract, funded with the escrow value,
+// is guaranteed to receive the agreed amount, as long as the contract is redeemed before its time lock.
+func TestAtomicSwapEscrowValue(t *testing.T) {
+	minerFee := types.NewCurrency64(100000000)
+	testCases := []struct {
+		Amount       types.Currency
+		CreationTime types.Timestamp
+		TimeLock     types.Timestamp
+	}{
+		{types.NewCurrency64(1), 1000, 1000},
+		{types.NewCurrency64(1000000000), 1000, 1000 + 24*60*60},
+		{types.NewCurrency64(123456789000000), 1000, 1000 + 48*60*60},
+		{types.NewCurrency64(123456789000000), 1000 + 48*60*60, 1000},
+	}
+	for idx, testCase := range testCases {
+		condition := types.AtomicSwapCondition{TimeLock: testCase.TimeLock}
+		value := AtomicSwapEscrowValue(testCase.Amount, testCase.CreationTime, testCase.TimeLock, minerFee)
+		guaranteedValue, worstCaseCustodyFee := AtomicSwapContractGuarantees(value, testCase.CreationTime, condition, minerFee)
+		if guaranteedValue.Cmp(testCase.Amount) < 0 {
+			t.Errorf("test case #%d: guaranteed value %s is less than the agreed amount %s", idx+1, guaranteedValue.String(), testCase.Amount.String())
+		}
+		if expected := value.Sub(minerFee).Sub(worstCaseCustodyFee); !guaranteedValue.Equals(expected) {
+			t.Errorf("test case #%d: unexpected guaranteed value %s, expected %s", idx+1, guaranteedValue.String(), expected.String())
+		}
+		if testCase.TimeLock <= testCase.CreationTime && !worstCaseCustodyFee.IsZero() {
+			t.Errorf("test case #%d: unexpected custody fee %s for an expired contract", idx+1, worstCaseCustodyFee.String())
+		}
+	}
+
+	// a contract not covering the miner fee guarantees nothing
+	guaranteedValue, _ := AtomicSwapContractGuarantees(minerFee, 1000, types.AtomicSwapCondition{TimeLock: 2000}, minerFee)
+	if !guaranteedValue.IsZero() {
+		t.Error("unexpected guaranteed value for a contract not covering the miner fee:", guaranteedValue.String())
+	}
+}
@@ -0,0 +1,114 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// AuthAddressBundleVersion is the version of the auth address bundle format,
+// as created by this version of the client.
+const AuthAddressBundleVersion uint8 = 1
+
+var (
+	// ErrNoAuthAddressBundles is returned when combining an empty list of auth address bundles.
+	ErrNoAuthAddressBundles = errors.New("no auth address bundles given")
+	// ErrUnknownAuthAddressBundleVersion is returned for an auth address bundle of a version not supported by the client.
+	ErrUnknownAuthAddressBundleVersion = errors.New("unknown auth address bundle version")
+	// ErrAuthAddressBundleMismatch is returned when combining auth address bundles which do not define the same transactions.
+	ErrAuthAddressBundleMismatch = errors.New("auth address bundles do not define the same transactions")
+)
+
+// AuthAddressBundle is a signing bundle for the auth address update transactions that (de)authorize
+// a list of addresses (e.g. all customers onboarded in a month) in a single operation.
+// Each transaction is wrapped in a PST, such that the bundle as a whole
+// can be signed by the co-signers of the auth condition.
+type AuthAddressBundle struct {
+	Version      uint8                        `json:"version"`
+	Transactions []PartiallySignedTransaction `json:"transactions"`
+}
+
+// SplitAuthAddresses splits the given addresses, in order, over as few transactions as possible,
+// each transaction fitting within the transaction size limit. The returned list defines the addresses of each transaction.
+//
+// The given function creates the transaction for a set of addresses, and is used to compute its size.
+// As the transactions are not yet signed, it should define the largest fulfillment the transaction can expect.
+func SplitAuthAddresses(addresses []types.UnlockHash, newTransaction func([]types.UnlockHash) types.Transaction, transactionSizeLimit int) ([][]types.UnlockHash, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no addresses given")
+	}
+	transactionSize := func(uhs []types.UnlockHash) (int, error) {
+		b, err := siabin.Marshal(newTransaction(uhs))
+		if err != nil {
+			return 0, fmt.Errorf("failed to (siabin) marshal transaction: %v", err)
+		}
+		return len(b), nil
+	}
+
+	var (
+		transactions [][]types.UnlockHash
+		txnAddresses []types.UnlockHash
+	)
+	for idx, uh := range addresses {
+		size, err := transactionSize(append(txnAddresses[:len(txnAddresses):len(txnAddresses)], uh))
+		if err != nil {
+			return nil, err
+		}
+		if size <= transactionSizeLimit {
+			txnAddresses = append(txnAddresses, uh)
+			continue
+		}
+		if len(txnAddresses) == 0 {
+			return nil, fmt.Errorf("address #%d does not fit in a transaction of %d bytes", idx+1, transactionSizeLimit)
+		}
+		transactions = append(transactions, txnAddresses)
+		txnAddresses = []types.UnlockHash{uh}
+	}
+	return append(transactions, txnAddresses), nil
+}
+
+// CombineAuthAddressBundles combines the signatures of the given auth address bundles,
+// each signed by one or multiple co-signers, into a single bundle.
+// All bundles have to be created for the same (unsigned) transactions.
+func CombineAuthAddressBundles(bundles ...AuthAddressBundle) (AuthAddressBundle, error) {
+	if len(bundles) == 0 {
+		return AuthAddressBundle{}, ErrNoAuthAddressBundles
+	}
+	for _, bundle := range bundles {
+		if bundle.Version != AuthAddressBundleVersion {
+			return AuthAddressBundle{}, ErrUnknownAuthAddressBundleVersion
+		}
+		if len(bundle.Transactions) != len(bundles[0].Transactions) {
+			return AuthAddressBundle{}, ErrAuthAddressBundleMismatch
+		}
+	}
+
+	combined := AuthAddressBundle{
+		Version:      AuthAddressBundleVersion,
+		Transactions: make([]PartiallySignedTransaction, len(bundles[0].Transactions)),
+	}
+	psts := make([]PartiallySignedTransaction, len(bundles))
+	for txnIdx := range bundles[0].Transactions {
+		for idx, bundle := range bundles {
+			psts[idx] = bundle.Transactions[txnIdx]
+		}
+		pst, err := CombinePartiallySignedTransactions(psts...)
+		if err != nil {
+			return AuthAddressBundle{}, fmt.Errorf("failed to combine transaction #%d: %v", txnIdx+1, err)
+		}
+		combined.Transactions[txnIdx] = pst
+	}
+	return combined, nil
+}
+
+// Complete returns true if all transactions of the bundle have the required signatures.
+func (bundle AuthAddressBundle) Complete() bool {
+	for _, pst := range bundle.Transactions {
+		if !pst.Complete() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestSplitAuthAddresses checks that addresses are split, in order, over transactions
+// which fit within the transaction size limit, each filled as much as possible.
+func TestSplitAuthAddresses(t *testing.T) {
+	keys := []pstTestKey{newPSTTestKey(t), newPSTTestKey(t), newPSTTestKey(t)}
+	fulfillment := LargestFulfillment(types.NewCondition(types.NewMultiSignatureCondition(
+		types.UnlockHashSlice{keys[0].uh, keys[1].uh, keys[2].uh}, 2)))
+	newTransaction := func(uhs []types.UnlockHash) types.Transaction {
+		outputs := make([]types.CoinOutput, 0, len(uhs))
+		for _, uh := range uhs {
+			outputs = append(outputs, types.CoinOutput{Condition: types.NewCondition(types.NewUnlockHashCondition(uh))})
+		}
+		return types.Transaction{
+			Version:     types.TransactionVersionOne,
+			CoinInputs:  []types.CoinInput{{Fulfillment: fulfillment}},
+			CoinOutputs: outputs,
+		}
+	}
+	transactionSize := func(uhs []types.UnlockHash) int {
+		b, err := siabin.Marshal(newTransaction(uhs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(b)
+	}
+
+	addresses := make([]types.UnlockHash, 0, 500)
+	for i := 0; i < cap(addresses); i++ {
+		var uh types.UnlockHash
+		uh.Type = types.UnlockTypePubKey
+		uh.Hash[0], uh.Hash[1] = byte(i), byte(i>>8)
+		addresses = append(addresses, uh)
+	}
+	const transactionSizeLimit = 2000
+	transactions, err := SplitAuthAddresses(addresses, newTransaction, transactionSizeLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) < 2 {
+		t.Fatalf("expected multiple transactions, found %d", len(transactions))
+	}
+	offset := 0
+	for txnIdx, uhs := range transactions {
+		if size := transactionSize(uhs); size > transactionSizeLimit {
+			t.Fatalf("transaction #%d has a size of %d bytes", txnIdx+1, size)
+		}
+		for _, uh := range uhs {
+			if uh != addresses[offset] {
+				t.Fatalf("transaction #%d defines address %v, expected %v", txnIdx+1, uh, addresses[offset])
+			}
+			offset++
+		}
+		if offset < len(addresses) && transactionSize(append(uhs[:len(uhs):len(uhs)], addresses[offset])) <= transactionSizeLimit {
+			t.Fatalf("transaction #%d could fit another address", txnIdx+1)
+		}
+	}
+	if offset != len(addresses) {
+		t.Fatalf("expected %d addresses to be split, found %d", len(addresses), offset)
+	}
+
+	// an address which does not fit in a transaction cannot be split
+	if _, err = SplitAuthAddresses(addresses, newTransaction, transactionSize(nil)); err == nil {
+		t.Fatal("expected addresses not to be split within a too small transaction size limit")
+	}
+	if _, err = SplitAuthAddresses(nil, newTransaction, transactionSizeLimit); err == nil {
+		t.Fatal("expected no addresses not to be split")
+	}
+}
+
+// TestCombineAuthAddressBundles checks that the signatures of bundles signed by different co-signers
+// are combined per transaction, and that bundles of different transactions are not combined.
+func TestCombineAuthAddressBundles(t *testing.T) {
+	keys := []pstTestKey{newPSTTestKey(t), newPSTTestKey(t), newPSTTestKey(t)}
+	condition := types.NewCondition(types.NewMultiSignatureCondition(
+		types.UnlockHashSlice{keys[0].uh, keys[1].uh, keys[2].uh}, 2))
+	newPST := func(parent byte) PartiallySignedTransaction {
+		var parentID types.CoinOutputID
+		parentID[0] = parent
+		pst := PartiallySignedTransaction{
+			Version: PSTVersion,
+			Transaction: types.Transaction{
+				Version:     types.TransactionVersionOne,
+				CoinInputs:  []types.CoinInput{{ParentID: parentID}},
+				CoinOutputs: []types.CoinOutput{{Value: types.NewCurrency64(99), Condition: condition}},
+				MinerFees:   []types.Currency{types.NewCurrency64(1)},
+			},
+			CoinInputs: []PSTCoinInput{
+				{ParentID: parentID, Parent: types.CoinOutput{Value: types.NewCurrency64(100), Condition: condition}},
+			},
+		}
+		if err := pst.UpdateSignatureStatus(); err != nil {
+			t.Fatal(err)
+		}
+		return pst
+	}
+	bundle := AuthAddressBundle{
+		Version:      AuthAddressBundleVersion,
+		Transactions: []PartiallySignedTransaction{newPST(1), newPST(2), newPST(3)},
+	}
+	signBundle := func(key pstTestKey) AuthAddressBundle {
+		signed := AuthAddressBundle{Version: bundle.Version}
+		for _, pst := range bundle.Transactions {
+			signed.Transactions = append(signed.Transactions, signPSTMultisigInput(t, pst, key))
+		}
+		return signed
+	}
+
+	first, second := signBundle(keys[0]), signBundle(keys[1])
+	if first.Complete() || second.Complete() {
+		t.Fatal("expected bundle signed by a single co-signer to be incomplete")
+	}
+	combined, err := CombineAuthAddressBundles(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !combined.Complete() {
+		t.Fatal("expected combined bundle to be complete")
+	}
+	if len(combined.Transactions) != 3 {
+		t.Fatalf("unexpected transactions of combined bundle: %+v", combined.Transactions)
+	}
+
+	// bundles of different transactions cannot be combined
+	other := signBundle(keys[2])
+	other.Transactions = other.Transactions[:2]
+	if _, err = CombineAuthAddressBundles(first, other); err != ErrAuthAddressBundleMismatch {
+		t.Fatal("unexpected error for bundles of different transactions:", err)
+	}
+	other = signBundle(keys[2])
+	other.Transactions[2] = newPST(4)
+	if _, err = CombineAuthAddressBundles(first, other); err == nil {
+		t.Fatal("expected bundles of different transactions not to be combined")
+	}
+	other.Version = 0
+	if _, err = CombineAuthAddressBundles(first, other); err != ErrUnknownAuthAddressBundleVersion {
+		t.Fatal("unexpected error for bundle of unknown version:", err)
+	}
+	if _, err = CombineAuthAddressBundles(); err != ErrNoAuthAddressBundles {
+		t.Fatal("unexpected error for no bundles:", err)
+	}
+}
@@ -0,0 +1,205 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// CoinCreationBundleVersion is the version of the coin creation bundle format,
+// as created by this version of the client.
+const CoinCreationBundleVersion uint8 = 1
+
+var (
+	// ErrNoCoinCreationBundles is returned when combining an empty list of coin creation bundles.
+	ErrNoCoinCreationBundles = errors.New("no coin creation bundles given")
+	// ErrUnknownCoinCreationBundleVersion is returned for a coin creation bundle of a version not supported by the client.
+	ErrUnknownCoinCreationBundleVersion = errors.New("unknown coin creation bundle version")
+	// ErrCoinCreationBundleMismatch is returned when combining coin creation bundles which do not define the same batches.
+	ErrCoinCreationBundleMismatch = errors.New("coin creation bundles do not define the same batches of transactions")
+)
+
+type (
+	// CoinCreationBundle is a signing bundle for the coin creation transactions that issue
+	// a list of coin outputs (e.g. the monthly gold intake) in a single operation.
+	// The transactions are grouped in batches, each batch fitting in a single block,
+	// and each transaction is wrapped in a PST, such that the bundle as a whole
+	// can be signed by the co-signers of the mint condition.
+	CoinCreationBundle struct {
+		Version uint8               `json:"version"`
+		Batches []CoinCreationBatch `json:"batches"`
+	}
+
+	// CoinCreationBatch is a batch of coin creation transactions,
+	// which fit together in a single block.
+	CoinCreationBatch struct {
+		Transactions []PartiallySignedTransaction `json:"transactions"`
+	}
+
+	// CoinCreationBatchLimits defines the size limits used to split coin outputs
+	// into coin creation transactions and batches.
+	CoinCreationBatchLimits struct {
+		// TransactionSizeLimit is the maximum (siabin encoded) size of a single transaction
+		TransactionSizeLimit int
+		// BatchSizeLimit is the maximum (siabin encoded) size of all transactions of a single batch
+		BatchSizeLimit int
+	}
+)
+
+// SplitCoinCreationOutputs splits the given coin outputs, in order, over as few transactions as possible,
+// each transaction fitting within the transaction size limit, and groups these transactions into batches,
+// each batch fitting within the batch size limit. The returned batches list the coin outputs of each transaction.
+//
+// The given function creates the transaction for a set of coin outputs, and is used to compute its size.
+// As the transactions are not yet signed, it should define the largest fulfillment the transaction can expect.
+func SplitCoinCreationOutputs(outputs []types.CoinOutput, newTransaction func([]types.CoinOutput) types.Transaction, limits CoinCreationBatchLimits) ([][][]types.CoinOutput, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("no coin outputs given")
+	}
+	if limits.TransactionSizeLimit > limits.BatchSizeLimit {
+		limits.TransactionSizeLimit = limits.BatchSizeLimit
+	}
+	transactionSize := func(cos []types.CoinOutput) (int, error) {
+		b, err := siabin.Marshal(newTransaction(cos))
+		if err != nil {
+			return 0, fmt.Errorf("failed to (siabin) marshal transaction: %v", err)
+		}
+		return len(b), nil
+	}
+
+	var (
+		batches   [][][]types.CoinOutput
+		batch     [][]types.CoinOutput
+		batchSize int
+	)
+	addTransaction := func(cos []types.CoinOutput, size int) {
+		if batchSize+size > limits.BatchSizeLimit {
+			batches = append(batches, batch)
+			batch, batchSize = nil, 0
+		}
+		batch = append(batch, cos)
+		batchSize += size
+	}
+
+	var (
+		txnOutputs []types.CoinOutput
+		txnSize    int
+	)
+	for idx, co := range outputs {
+		size, err := transactionSize(append(txnOutputs[:len(txnOutputs):len(txnOutputs)], co))
+		if err != nil {
+			return nil, err
+		}
+		if size <= limits.TransactionSizeLimit {
+			txnOutputs = append(txnOutputs, co)
+			txnSize = size
+			continue
+		}
+		if len(txnOutputs) == 0 {
+			return nil, fmt.Errorf("coin output #%d does not fit in a transaction of %d bytes", idx+1, limits.TransactionSizeLimit)
+		}
+		addTransaction(txnOutputs, txnSize)
+		txnOutputs = nil
+		size, err = transactionSize([]types.CoinOutput{co})
+		if err != nil {
+			return nil, err
+		}
+		if size > limits.TransactionSizeLimit {
+			return nil, fmt.Errorf("coin output #%d does not fit in a transaction of %d bytes", idx+1, limits.TransactionSizeLimit)
+		}
+		txnOutputs = []types.CoinOutput{co}
+		txnSize = size
+	}
+	addTransaction(txnOutputs, txnSize)
+	return append(batches, batch), nil
+}
+
+// LargestFulfillment returns the largest fulfillment that can be used to fulfill the given condition,
+// using placeholder keys and signatures. It is used to compute the size of a transaction prior to it being signed.
+// The nil fulfillment is returned for conditions not fulfilled by signatures.
+func LargestFulfillment(condition types.UnlockConditionProxy) types.UnlockFulfillmentProxy {
+	return types.NewFulfillment(largestFulfillment(condition.Condition))
+}
+
+func largestFulfillment(condition types.MarshalableUnlockCondition) types.MarshalableUnlockFulfillment {
+	pair := types.PublicKeySignaturePair{
+		PublicKey: types.Ed25519PublicKey(crypto.PublicKey{}),
+		Signature: make(types.ByteSlice, crypto.SignatureSize),
+	}
+	switch c := condition.(type) {
+	case nil, *types.NilCondition, *types.UnlockHashCondition:
+		return &types.SingleSignatureFulfillment{
+			PublicKey: pair.PublicKey,
+			Signature: pair.Signature,
+		}
+	case *types.TimeLockCondition:
+		return largestFulfillment(c.Condition)
+	case *types.MultiSignatureCondition:
+		// all co-signers can sign, even though only the minimum amount of signatures is required
+		fulfillment := &types.MultiSignatureFulfillment{}
+		for range c.UnlockHashes {
+			fulfillment.Pairs = append(fulfillment.Pairs, pair)
+		}
+		return fulfillment
+	default:
+		return &types.NilFulfillment{}
+	}
+}
+
+// CombineCoinCreationBundles combines the signatures of the given coin creation bundles,
+// each signed by one or multiple co-signers, into a single bundle.
+// All bundles have to be created for the same batches of (unsigned) transactions.
+func CombineCoinCreationBundles(bundles ...CoinCreationBundle) (CoinCreationBundle, error) {
+	if len(bundles) == 0 {
+		return CoinCreationBundle{}, ErrNoCoinCreationBundles
+	}
+	for _, bundle := range bundles {
+		if bundle.Version != CoinCreationBundleVersion {
+			return CoinCreationBundle{}, ErrUnknownCoinCreationBundleVersion
+		}
+		if len(bundle.Batches) != len(bundles[0].Batches) {
+			return CoinCreationBundle{}, ErrCoinCreationBundleMismatch
+		}
+		for idx, batch := range bundle.Batches {
+			if len(batch.Transactions) != len(bundles[0].Batches[idx].Transactions) {
+				return CoinCreationBundle{}, ErrCoinCreationBundleMismatch
+			}
+		}
+	}
+
+	combined := CoinCreationBundle{
+		Version: CoinCreationBundleVersion,
+		Batches: make([]CoinCreationBatch, len(bundles[0].Batches)),
+	}
+	psts := make([]PartiallySignedTransaction, len(bundles))
+	for batchIdx, batch := range bundles[0].Batches {
+		combined.Batches[batchIdx].Transactions = make([]PartiallySignedTransaction, len(batch.Transactions))
+		for txnIdx := range batch.Transactions {
+			for idx, bundle := range bundles {
+				psts[idx] = bundle.Batches[batchIdx].Transactions[txnIdx]
+			}
+			pst, err := CombinePartiallySignedTransactions(psts...)
+			if err != nil {
+				return CoinCreationBundle{}, fmt.Errorf(
+					"failed to combine transaction #%d of batch #%d: %v", txnIdx+1, batchIdx+1, err)
+			}
+			combined.Batches[batchIdx].Transactions[txnIdx] = pst
+		}
+	}
+	return combined, nil
+}
+
+// Complete returns true if all transactions of the bundle have the required signatures.
+func (bundle CoinCreationBundle) Complete() bool {
+	for _, batch := range bundle.Batches {
+		for _, pst := range batch.Transactions {
+			if !pst.Complete() {
+				return false
+			}
+		}
+	}
+	return true
+}
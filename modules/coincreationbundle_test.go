@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestSplitCoinCreationOutputs checks that coin outputs are split, in order, over transactions
+// which fit within the transaction size limit, each filled as much as possible,
+// and that these transactions are grouped into batches which fit within the batch size limit.
+func TestSplitCoinCreationOutputs(t *testing.T) {
+	keys := []pstTestKey{newPSTTestKey(t), newPSTTestKey(t), newPSTTestKey(t)}
+	fulfillment := LargestFulfillment(types.NewCondition(types.NewMultiSignatureCondition(
+		types.UnlockHashSlice{keys[0].uh, keys[1].uh, keys[2].uh}, 2)))
+	newTransaction := func(cos []types.CoinOutput) types.Transaction {
+		return types.Transaction{
+			Version:     types.TransactionVersionOne,
+			CoinInputs:  []types.CoinInput{{Fulfillment: fulfillment}},
+			CoinOutputs: cos,
+			MinerFees:   []types.Currency{types.NewCurrency64(1)},
+		}
+	}
+	transactionSize := func(cos []types.CoinOutput) int {
+		b, err := siabin.Marshal(newTransaction(cos))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(b)
+	}
+
+	outputs := make([]types.CoinOutput, 0, 500)
+	for i := 0; i < cap(outputs); i++ {
+		var uh types.UnlockHash
+		uh.Type = types.UnlockTypePubKey
+		uh.Hash[0], uh.Hash[1] = byte(i), byte(i>>8)
+		outputs = append(outputs, types.CoinOutput{
+			Value:     types.NewCurrency64(uint64(i + 1)),
+			Condition: types.NewCondition(types.NewUnlockHashCondition(uh)),
+		})
+	}
+	limits := CoinCreationBatchLimits{
+		TransactionSizeLimit: 2000,
+		BatchSizeLimit:       5000,
+	}
+	batches, err := SplitCoinCreationOutputs(outputs, newTransaction, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		txnCount int
+		offset   int
+	)
+	for batchIdx, batch := range batches {
+		if len(batch) == 0 {
+			t.Fatalf("batch #%d is empty", batchIdx+1)
+		}
+		batchSize := 0
+		for _, cos := range batch {
+			txnCount++
+			size := transactionSize(cos)
+			if size > limits.TransactionSizeLimit {
+				t.Fatalf("transaction #%d has a size of %d bytes", txnCount, size)
+			}
+			batchSize += size
+			for _, co := range cos {
+				if !co.Value.Equals(outputs[offset].Value) {
+					t.Fatalf("transaction #%d defines coin output %v, expected %v", txnCount, co.Value, outputs[offset].Value)
+				}
+				offset++
+			}
+			if offset < len(outputs) && transactionSize(append(cos[:len(cos):len(cos)], outputs[offset])) <= limits.TransactionSizeLimit {
+				t.Fatalf("transaction #%d could fit another coin output", txnCount)
+			}
+		}
+		if batchSize > limits.BatchSizeLimit {
+			t.Fatalf("batch #%d has a size of %d bytes", batchIdx+1, batchSize)
+		}
+	}
+	if offset != len(outputs) {
+		t.Fatalf("expected %d coin outputs to be split, found %d", len(outputs), offset)
+	}
+	if len(batches) < 2 || txnCount <= len(batches) {
+		t.Fatalf("expected multiple batches of multiple transactions, found %d transactions in %d batches", txnCount, len(batches))
+	}
+
+	// a coin output which does not fit in a transaction cannot be split
+	_, err = SplitCoinCreationOutputs(outputs, newTransaction, CoinCreationBatchLimits{
+		TransactionSizeLimit: transactionSize(nil),
+		BatchSizeLimit:       limits.BatchSizeLimit,
+	})
+	if err == nil {
+		t.Fatal("expected coin outputs not to be split within a too small transaction size limit")
+	}
+	if _, err = SplitCoinCreationOutputs(nil, newTransaction, limits); err == nil {
+		t.Fatal("expected no coin outputs not to be split")
+	}
+}
+
+// TestCombineCoinCreationBundles checks that the signatures of bundles signed by different co-signers
+// are combined per transaction, and that bundles of different batches are not combined.
+func TestCombineCoinCreationBundles(t *testing.T) {
+	keys := []pstTestKey{newPSTTestKey(t), newPSTTestKey(t), newPSTTestKey(t)}
+	condition := types.NewCondition(types.NewMultiSignatureCondition(
+		types.UnlockHashSlice{keys[0].uh, keys[1].uh, keys[2].uh}, 2))
+	newPST := func(parent byte) PartiallySignedTransaction {
+		var parentID types.CoinOutputID
+		parentID[0] = parent
+		pst := PartiallySignedTransaction{
+			Version: PSTVersion,
+			Transaction: types.Transaction{
+				Version:     types.TransactionVersionOne,
+				CoinInputs:  []types.CoinInput{{ParentID: parentID}},
+				CoinOutputs: []types.CoinOutput{{Value: types.NewCurrency64(99), Condition: condition}},
+				MinerFees:   []types.Currency{types.NewCurrency64(1)},
+			},
+			CoinInputs: []PSTCoinInput{
+				{ParentID: parentID, Parent: types.CoinOutput{Value: types.NewCurrency64(100), Condition: condition}},
+			},
+		}
+		if err := pst.UpdateSignatureStatus(); err != nil {
+			t.Fatal(err)
+		}
+		return pst
+	}
+	bundle := CoinCreationBundle{
+		Version: CoinCreationBundleVersion,
+		Batches: []CoinCreationBatch{
+			{Transactions: []PartiallySignedTransaction{newPST(1), newPST(2)}},
+			{Transactions: []PartiallySignedTransaction{newPST(3)}},
+		},
+	}
+	signBundle := func(key pstTestKey) CoinCreationBundle {
+		signed := CoinCreationBundle{Version: bundle.Version}
+		for _, batch := range bundle.Batches {
+			var signedBatch CoinCreationBatch
+			for _, pst := range batch.Transactions {
+				signedBatch.Transactions = append(signedBatch.Transactions, signPSTMultisigInput(t, pst, key))
+			}
+			signed.Batches = append(signed.Batches, signedBatch)
+		}
+		return signed
+	}
+
+	first, second := signBundle(keys[0]), signBundle(keys[1])
+	if first.Complete() || second.Complete() {
+		t.Fatal("expected bundle signed by a single co-signer to be incomplete")
+	}
+	combined, err := CombineCoinCreationBundles(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !combined.Complete() {
+		t.Fatal("expected combined bundle to be complete")
+	}
+	if len(combined.Batches) != 2 || len(combined.Batches[0].Transactions) != 2 || len(combined.Batches[1].Transactions) != 1 {
+		t.Fatalf("unexpected batches of combined bundle: %+v", combined.Batches)
+	}
+
+	// bundles of different batches cannot be combined
+	other := signBundle(keys[2])
+	other.Batches = other.Batches[:1]
+	if _, err = CombineCoinCreationBundles(first, other); err != ErrCoinCreationBundleMismatch {
+		t.Fatal("unexpected error for bundles of different batches:", err)
+	}
+	other = signBundle(keys[2])
+	other.Batches[1].Transactions[0] = newPST(4)
+	if _, err = CombineCoinCreationBundles(first, other); err == nil {
+		t.Fatal("expected bundles of different transactions not to be combined")
+	}
+	other.Version = 0
+	if _, err = CombineCoinCreationBundles(first, other); err != ErrUnknownCoinCreationBundleVersion {
+		t.Fatal("unexpected error for bundle of unknown version:", err)
+	}
+	if _, err = CombineCoinCreationBundles(); err != ErrNoCoinCreationBundles {
+		t.Fatal("unexpected error for no bundles:", err)
+	}
+}
@@ -1,26 +1,49 @@
 package consensus
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/modules/consensus"
 	"github.com/threefoldtech/rivine/types"
 
 	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	portypes "github.com/nbh-digital/goldchain/extensions/proofofreserve/types"
+	rdtypes "github.com/nbh-digital/goldchain/extensions/redemption/types"
+	"github.com/nbh-digital/goldchain/pkg/config"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
-func GetTestnetTransactionValidators() []modules.TransactionValidationFunction {
-	return getTransactionValidators()
+// NamedTransactionValidator is a transaction validation function, identified by a stable name,
+// such that the rule it validates can be reported, as done by the transaction simulation API.
+type NamedTransactionValidator struct {
+	Name string
+	Fn   modules.TransactionValidationFunction
 }
 
-func GetTestnetTransactionVersionMappedValidators() map[types.TransactionVersion][]modules.TransactionValidationFunction {
+// TransactionValidationFunctions returns the validation functions of the given named validators, in order.
+func TransactionValidationFunctions(validators []NamedTransactionValidator) []modules.TransactionValidationFunction {
+	fns := make([]modules.TransactionValidationFunction, 0, len(validators))
+	for _, validator := range validators {
+		fns = append(fns, validator.Fn)
+	}
+	return fns
+}
+
+func GetTestnetTransactionValidators() []NamedTransactionValidator {
+	return getTransactionValidators(config.GetTestnetFeatureActivationHeights())
+}
+
+func GetTestnetTransactionVersionMappedValidators() map[types.TransactionVersion][]NamedTransactionValidator {
 	return getTransactionVersionMappedValidators()
 }
 
-func GetDevnetTransactionValidators() []modules.TransactionValidationFunction {
-	return getTransactionValidators()
+func GetDevnetTransactionValidators() []NamedTransactionValidator {
+	return getTransactionValidators(config.GetDevnetFeatureActivationHeights())
 }
 
-func GetDevnetTransactionVersionMappedValidators() map[types.TransactionVersion][]modules.TransactionValidationFunction {
+func GetDevnetTransactionVersionMappedValidators() map[types.TransactionVersion][]NamedTransactionValidator {
 	return getTransactionVersionMappedValidators()
 }
 
@@ -29,43 +52,108 @@ func GetDevnetTransactionVersionMappedValidators() map[types.TransactionVersion]
 // the exception is that Custody Fees are allowed to have a value equal to zero.
 func ValidateCoinOutputsAreValid(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext) error {
 	var err error
-	for _, co := range tx.CoinOutputs {
+	for idx, co := range tx.CoinOutputs {
 		if co.Value.IsZero() && co.Condition.ConditionType() != cftypes.ConditionTypeCustodyFee {
-			return types.ErrZeroOutput
+			return gctypes.NewCodedError(gctypes.ErrorCodeZeroCoinOutput, types.ErrZeroOutput, map[string]string{
+				"index": strconv.Itoa(idx),
+			})
 		}
 		err = co.Condition.IsStandardCondition(ctx.ValidationContext)
 		if err != nil {
-			return err
+			return gctypes.NewCodedError(gctypes.ErrorCodeNonStandardCondition, err, map[string]string{
+				"index":         strconv.Itoa(idx),
+				"conditiontype": strconv.Itoa(int(co.Condition.ConditionType())),
+			})
 		}
 	}
 	return nil
 }
 
-func getTransactionVersionMappedValidators() map[types.TransactionVersion][]modules.TransactionValidationFunction {
-	return map[types.TransactionVersion][]modules.TransactionValidationFunction{
+// NewTransactionArbitraryDataValidator creates a validator function that checks if a transaction's arbitrary data is valid,
+// the exception is that, as of the given activation height, coin creation and coin destruction transactions which carry
+// a proof-of-reserve attestation are allowed to have arbitrary data up to the (higher) size limit of attestations,
+// and coin destruction transactions which carry a redemption up to the size limit of redemptions.
+// The shape of the attestation and redemption itself is validated by the proof-of-reserve and redemption plugin.
+func NewTransactionArbitraryDataValidator(activationHeight types.BlockHeight) modules.TransactionValidationFunction {
+	return func(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext) error {
+		if ctx.BlockHeight >= activationHeight &&
+			(tx.Version == gctypes.TransactionVersionCoinCreation || tx.Version == gctypes.TransactionVersionCoinDestruction) &&
+			portypes.IsAttestationArbitraryData(tx.ArbitraryData) {
+			return types.ArbitraryDataFits(tx.ArbitraryData, portypes.ArbitraryDataSizeLimit)
+		}
+		if ctx.BlockHeight >= activationHeight &&
+			tx.Version == gctypes.TransactionVersionCoinDestruction && rdtypes.IsRedemptionArbitraryData(tx.ArbitraryData) {
+			return types.ArbitraryDataFits(tx.ArbitraryData, rdtypes.ArbitraryDataSizeLimit)
+		}
+		return consensus.ValidateTransactionArbitraryData(tx, ctx)
+	}
+}
+
+// NewMultiSignatureAtomicSwapValidator creates a validator function that checks, until the given activation height,
+// that a transaction does not create or spend multisig atomic swap contracts,
+// as these contracts are not known to the nodes running a version prior to that height.
+func NewMultiSignatureAtomicSwapValidator(activationHeight types.BlockHeight) modules.TransactionValidationFunction {
+	return func(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext) error {
+		if ctx.BlockHeight >= activationHeight {
+			return nil
+		}
+		for idx, co := range tx.CoinOutputs {
+			if co.Condition.ConditionType() == gctypes.ConditionTypeMultiSignatureAtomicSwap {
+				return gctypes.NewCodedError(gctypes.ErrorCodeNonStandardCondition,
+					errors.New("multisig atomic swap contracts are not yet activated"), map[string]string{
+						"index":         strconv.Itoa(idx),
+						"conditiontype": strconv.Itoa(int(co.Condition.ConditionType())),
+					})
+			}
+		}
+		for idx, bso := range tx.BlockStakeOutputs {
+			if bso.Condition.ConditionType() == gctypes.ConditionTypeMultiSignatureAtomicSwap {
+				return gctypes.NewCodedError(gctypes.ErrorCodeNonStandardCondition,
+					errors.New("multisig atomic swap contracts are not yet activated"), map[string]string{
+						"index":         strconv.Itoa(idx),
+						"conditiontype": strconv.Itoa(int(bso.Condition.ConditionType())),
+					})
+			}
+		}
+		for idx, ci := range tx.CoinInputs {
+			if ci.Fulfillment.FulfillmentType() == gctypes.FulfillmentTypeMultiSignatureAtomicSwap {
+				return gctypes.NewCodedError(gctypes.ErrorCodeNonStandardFulfillment,
+					errors.New("multisig atomic swap fulfillments are not yet activated"), map[string]string{
+						"index":           strconv.Itoa(idx),
+						"fulfillmenttype": strconv.Itoa(int(ci.Fulfillment.FulfillmentType())),
+					})
+			}
+		}
+		return nil
+	}
+}
+
+func getTransactionVersionMappedValidators() map[types.TransactionVersion][]NamedTransactionValidator {
+	return map[types.TransactionVersion][]NamedTransactionValidator{
 		types.TransactionVersionZero: {
-			consensus.ValidateInvalidByDefault,
+			{Name: "transaction version is accepted", Fn: consensus.ValidateInvalidByDefault},
 		},
 		types.TransactionVersionOne: {
-			consensus.ValidateCoinOutputsAreBalanced,
-			consensus.ValidateBlockStakeOutputsAreBalanced,
-			consensus.ValidateMinerFeeIsPresent,
+			{Name: "coin outputs are balanced", Fn: consensus.ValidateCoinOutputsAreBalanced},
+			{Name: "block stake outputs are balanced", Fn: consensus.ValidateBlockStakeOutputsAreBalanced},
+			{Name: "miner fee is present", Fn: consensus.ValidateMinerFeeIsPresent},
 		},
 	}
 }
 
-func getTransactionValidators() []modules.TransactionValidationFunction {
-	return []modules.TransactionValidationFunction{
-		consensus.ValidateTransactionFitsInABlock,
-		consensus.ValidateTransactionArbitraryData,
-		consensus.ValidateCoinInputsAreValid,
-		ValidateCoinOutputsAreValid,
-		consensus.ValidateBlockStakeInputsAreValid,
-		consensus.ValidateBlockStakeOutputsAreValid,
-		consensus.ValidateMinerFeesAreValid,
-		consensus.ValidateDoubleCoinSpends,
-		consensus.ValidateDoubleBlockStakeSpends,
-		consensus.ValidateCoinInputsAreFulfilled,
-		consensus.ValidateBlockStakeInputsAreFulfilled,
+func getTransactionValidators(activationHeights config.FeatureActivationHeights) []NamedTransactionValidator {
+	return []NamedTransactionValidator{
+		{Name: "transaction fits in a block", Fn: consensus.ValidateTransactionFitsInABlock},
+		{Name: "arbitrary data is valid", Fn: NewTransactionArbitraryDataValidator(activationHeights.ExtendedArbitraryData)},
+		{Name: "multisig atomic swaps are activated", Fn: NewMultiSignatureAtomicSwapValidator(activationHeights.MultiSignatureAtomicSwap)},
+		{Name: "coin inputs are valid", Fn: consensus.ValidateCoinInputsAreValid},
+		{Name: "coin outputs are valid", Fn: ValidateCoinOutputsAreValid},
+		{Name: "block stake inputs are valid", Fn: consensus.ValidateBlockStakeInputsAreValid},
+		{Name: "block stake outputs are valid", Fn: consensus.ValidateBlockStakeOutputsAreValid},
+		{Name: "miner fees are valid", Fn: consensus.ValidateMinerFeesAreValid},
+		{Name: "coin inputs are not double spent", Fn: consensus.ValidateDoubleCoinSpends},
+		{Name: "block stake inputs are not double spent", Fn: consensus.ValidateDoubleBlockStakeSpends},
+		{Name: "coin inputs are fulfilled", Fn: consensus.ValidateCoinInputsAreFulfilled},
+		{Name: "block stake inputs are fulfilled", Fn: consensus.ValidateBlockStakeInputsAreFulfilled},
 	}
 }
@@ -2,15 +2,20 @@ package consensus
 
 import (
 	"encoding/hex"
+	"errors"
 	"sync"
 	"testing"
 
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/pkg/client"
 	"github.com/threefoldtech/rivine/types"
 
 	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	portypes "github.com/nbh-digital/goldchain/extensions/proofofreserve/types"
+	rdtypes "github.com/nbh-digital/goldchain/extensions/redemption/types"
 	"github.com/nbh-digital/goldchain/pkg/config"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
 func TestValidateCoinOutputsAreValid_ValidTxs(t *testing.T) {
@@ -132,7 +137,164 @@ func TestValidateCoinOutputsAreValid_InvalidTxs(t *testing.T) {
 		err := ValidateCoinOutputsAreValid(tx, types.TransactionValidationContext{})
 		if err == nil {
 			t.Error(idx+1, "expected an error but none was received")
+			continue
 		}
+		cErr, ok := gctypes.AsCodedError(err)
+		if !ok || cErr.Code != gctypes.ErrorCodeZeroCoinOutput || cErr.Details["index"] == "" {
+			t.Error(idx+1, "expected a coded zero coin output error, received:", err)
+		}
+		if !errors.Is(err, types.ErrZeroOutput) {
+			t.Error(idx+1, "expected the coded error to wrap the zero output error, received:", err)
+		}
+	}
+}
+
+func TestValidateTransactionArbitraryData(t *testing.T) {
+	attestation, err := portypes.Attestation{
+		VaultID:              "vault-1",
+		BarSerials:           []string{"AB-0001", "AB-0002", "AB-0003", "AB-0004", "AB-0005"},
+		Weight:               62500000,
+		AssayerSignatureHash: crypto.Hash{1},
+	}.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attestation) <= 83 {
+		t.Fatalf("expected attestation (%d bytes) to exceed the arbitrary data size limit of the chain", len(attestation))
+	}
+	redemption, err := rdtypes.Redemption{
+		Reference:      "RDM-2026-0001-CUSTOMER-ACCOUNT-00042",
+		CustomerIDHash: crypto.Hash{42},
+		DeliveryVault:  "vault-zurich-freeport-01",
+	}.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(redemption) <= 83 {
+		t.Fatalf("expected redemption (%d bytes) to exceed the arbitrary data size limit of the chain", len(redemption))
+	}
+	ctx := types.TransactionValidationContext{ArbitraryDataSizeLimit: 83}
+	tooLarge := make([]byte, portypes.ArbitraryDataSizeLimit+1)
+	copy(tooLarge, portypes.AttestationPrefix)
+	tooLargeRedemption := make([]byte, rdtypes.ArbitraryDataSizeLimit+1)
+	copy(tooLargeRedemption, rdtypes.RedemptionPrefix)
+	testCases := []struct {
+		Version       types.TransactionVersion
+		ArbitraryData []byte
+		Valid         bool
+	}{
+		{types.TransactionVersionOne, make([]byte, 83), true},
+		{types.TransactionVersionOne, make([]byte, 84), false},
+		{types.TransactionVersionOne, attestation, false},
+		{gctypes.TransactionVersionCoinCreation, make([]byte, 84), false},
+		{gctypes.TransactionVersionCoinCreation, attestation, true},
+		{gctypes.TransactionVersionCoinDestruction, attestation, true},
+		{gctypes.TransactionVersionCoinCreation, tooLarge, false},
+		{gctypes.TransactionVersionCoinDestruction, redemption, true},
+		{gctypes.TransactionVersionCoinCreation, redemption, false},
+		{types.TransactionVersionOne, redemption, false},
+		{gctypes.TransactionVersionCoinDestruction, tooLargeRedemption, false},
+	}
+	validate := NewTransactionArbitraryDataValidator(10)
+	for idx, testCase := range testCases {
+		txn := modules.ConsensusTransaction{
+			Transaction: types.Transaction{
+				Version:       testCase.Version,
+				ArbitraryData: testCase.ArbitraryData,
+			},
+		}
+		ctx.BlockHeight = 10
+		err := validate(txn, ctx)
+		if testCase.Valid && err != nil {
+			t.Error(idx+1, "unexpected error:", err)
+		} else if !testCase.Valid && err == nil {
+			t.Error(idx+1, "expected an error, but none was returned")
+		}
+		// prior to the activation height, the arbitrary data size limit of the chain applies to all transactions
+		ctx.BlockHeight = 9
+		err = validate(txn, ctx)
+		if fits := len(testCase.ArbitraryData) <= 83; fits && err != nil {
+			t.Error(idx+1, "unexpected error prior to activation:", err)
+		} else if !fits && err == nil {
+			t.Error(idx+1, "expected an error prior to activation, but none was returned")
+		}
+	}
+}
+
+// TestTransactionValidatorNames checks that all transaction validators are named uniquely,
+// such that the rules they validate can be identified by their name.
+func TestTransactionValidatorNames(t *testing.T) {
+	validators := GetTestnetTransactionValidators()
+	for _, mappedValidators := range GetTestnetTransactionVersionMappedValidators() {
+		validators = append(validators, mappedValidators...)
+	}
+	names := make(map[string]struct{}, len(validators))
+	for idx, validator := range validators {
+		if validator.Name == "" || validator.Fn == nil {
+			t.Errorf("validator #%d is not named or has no function: %+v", idx+1, validator)
+		}
+		if _, exists := names[validator.Name]; exists {
+			t.Errorf("validator name %q is not unique", validator.Name)
+		}
+		names[validator.Name] = struct{}{}
+	}
+	if fns := TransactionValidationFunctions(validators); len(fns) != len(validators) {
+		t.Errorf("expected %d validation functions, received %d", len(validators), len(fns))
+	}
+}
+
+func TestMultiSignatureAtomicSwapValidator(t *testing.T) {
+	condition := gctypes.NewMultiSignatureAtomicSwapCondition(
+		nuh("0165c4d7cf3c52cab81fd7e82cd9e39d7fb8a1c7ab7515ac904299495244d0822c15841672f205"),
+		*nmsc(1,
+			"01fc8714235d549f890f35e52d745b9eeeee34926f96c4b9ef1689832f338d9349b453898f7e51",
+			"0165c4d7cf3c52cab81fd7e82cd9e39d7fb8a1c7ab7515ac904299495244d0822c15841672f205"),
+		types.AtomicSwapHashedSecret{1}, 42)
+	txs := []struct {
+		tx   modules.ConsensusTransaction
+		code gctypes.ErrorCode
+	}{
+		{nct(nco("1", nnc()), nco("1", condition)), gctypes.ErrorCodeNonStandardCondition},
+		{
+			modules.ConsensusTransaction{
+				Transaction: types.Transaction{
+					BlockStakeOutputs: []types.BlockStakeOutput{
+						{Value: gft("1"), Condition: types.NewCondition(nnc())},
+						{Value: gft("1"), Condition: types.NewCondition(condition)},
+					},
+				},
+			},
+			gctypes.ErrorCodeNonStandardCondition,
+		},
+		{
+			modules.ConsensusTransaction{
+				Transaction: types.Transaction{
+					CoinInputs: []types.CoinInput{
+						{Fulfillment: types.NewFulfillment(&types.NilFulfillment{})},
+						{Fulfillment: types.NewFulfillment(&gctypes.MultiSignatureAtomicSwapFulfillment{})},
+					},
+				},
+			},
+			gctypes.ErrorCodeNonStandardFulfillment,
+		},
+	}
+	validate := NewMultiSignatureAtomicSwapValidator(10)
+	for idx, testCase := range txs {
+		tx := testCase.tx
+		err := validate(tx, types.TransactionValidationContext{ValidationContext: types.ValidationContext{BlockHeight: 9}})
+		if err == nil {
+			t.Error(idx+1, "expected an error prior to activation, but none was returned")
+		} else if cErr, ok := gctypes.AsCodedError(err); !ok || cErr.Code != testCase.code || cErr.Details["index"] != "1" {
+			t.Error(idx+1, "unexpected error prior to activation:", cErr.Code, cErr.Details, err)
+		}
+		err = validate(tx, types.TransactionValidationContext{ValidationContext: types.ValidationContext{BlockHeight: 10}})
+		if err != nil {
+			t.Error(idx+1, "unexpected error:", err)
+		}
+	}
+	err := validate(nct(nco("1", nnc())), types.TransactionValidationContext{})
+	if err != nil {
+		t.Error("unexpected error for regular transaction prior to activation:", err)
 	}
 }
 
@@ -0,0 +1,460 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/types"
+
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// PSTVersion is the version of the partially signed transaction (PST) format,
+// as created by this version of the wallet.
+const PSTVersion uint8 = 1
+
+var (
+	// ErrNoPartiallySignedTransactions is returned when combining an empty list of PSTs.
+	ErrNoPartiallySignedTransactions = errors.New("no partially signed transactions given")
+	// ErrUnknownPSTVersion is returned for a PST of a version not supported by the wallet.
+	ErrUnknownPSTVersion = errors.New("unknown partially signed transaction version")
+	// ErrPSTInputMismatch is returned when the inputs described by a PST do not match its transaction.
+	ErrPSTInputMismatch = errors.New("partially signed transaction inputs do not match its transaction")
+)
+
+type (
+	// PartiallySignedTransaction (PST) is a self-describing container for a transaction,
+	// which has to be signed by multiple parties (e.g. the co-signers of a multisig wallet) before it can be published.
+	// Next to the transaction itself it defines the parent outputs of all inputs, the inputs used
+	// to compute the custody fee and, per input, which signatures are already provided and which are still missing,
+	// such that a co-signer can review the transaction without having to look up its parent outputs.
+	PartiallySignedTransaction struct {
+		Version          uint8                `json:"version"`
+		Transaction      types.Transaction    `json:"transaction"`
+		CoinInputs       []PSTCoinInput       `json:"coininputs,omitempty"`
+		BlockStakeInputs []PSTBlockStakeInput `json:"blockstakeinputs,omitempty"`
+		// Extension is only defined for transactions which have to fulfill
+		// a condition as part of their extension data (e.g. the mint condition for a coin creation)
+		Extension *PSTExtension `json:"extension,omitempty"`
+		// CustodyFee is only defined for transactions which spend coin inputs
+		CustodyFee *PSTCustodyFee `json:"custodyfee,omitempty"`
+	}
+
+	// PSTCoinInput describes the parent output of a coin input of a PST,
+	// as well as the custody fee to be paid for it.
+	PSTCoinInput struct {
+		ParentID types.CoinOutputID `json:"parentid"`
+		Parent   types.CoinOutput   `json:"parent"`
+		// CreationTime and CreationValue are the inputs used, together
+		// with the computation time of the PST, to compute the custody fee
+		CreationTime   types.Timestamp    `json:"creationtime"`
+		CreationValue  types.Currency     `json:"creationvalue"`
+		CustodyFee     types.Currency     `json:"custodyfee"`
+		SpendableValue types.Currency     `json:"spendablevalue"`
+		Signatures     PSTSignatureStatus `json:"signatures"`
+	}
+
+	// PSTBlockStakeInput describes the parent output of a block stake input of a PST.
+	PSTBlockStakeInput struct {
+		ParentID   types.BlockStakeOutputID `json:"parentid"`
+		Parent     types.BlockStakeOutput   `json:"parent"`
+		Signatures PSTSignatureStatus       `json:"signatures"`
+	}
+
+	// PSTExtension describes the condition to be fulfilled as part of the extension data of a PST.
+	PSTExtension struct {
+		Condition  types.UnlockConditionProxy `json:"condition"`
+		Signatures PSTSignatureStatus         `json:"signatures"`
+	}
+
+	// PSTCustodyFee describes the custody fee expected to be paid by a PST,
+	// computed at the computation time defined by its custody fee output, and the custody fee it actually pays.
+	PSTCustodyFee struct {
+		ComputationTime types.Timestamp `json:"computationtime"`
+		Expected        types.Currency  `json:"expected"`
+		Paid            types.Currency  `json:"paid"`
+	}
+
+	// PSTSignatureStatus defines the signatures provided for a single fulfillment of a PST.
+	// Signatures are only checked for presence, their validity is checked when the transaction is finalized.
+	PSTSignatureStatus struct {
+		// Required is the amount of signatures required to fulfill the condition
+		Required uint64 `json:"required"`
+		// Signed are the addresses that provided a signature,
+		// Missing the addresses that can still provide a signature
+		Signed  []types.UnlockHash `json:"signed,omitempty"`
+		Missing []types.UnlockHash `json:"missing,omitempty"`
+		// Complete indicates the required signatures are provided
+		Complete bool `json:"complete"`
+	}
+)
+
+// NewPSTSignatureStatus returns the signature status of the given fulfillment, used to fulfill the given condition.
+// Conditions other than the nil, unlock hash, multisig, multisig atomic swap and time lock conditions
+// are not supported by the PST format,
+// and are considered complete as soon as a fulfillment is defined.
+func NewPSTSignatureStatus(condition types.UnlockConditionProxy, fulfillment types.UnlockFulfillmentProxy) PSTSignatureStatus {
+	return newPSTSignatureStatus(condition.Condition, fulfillment.Fulfillment)
+}
+
+func newPSTSignatureStatus(condition types.MarshalableUnlockCondition, fulfillment types.MarshalableUnlockFulfillment) PSTSignatureStatus {
+	status := PSTSignatureStatus{Required: 1}
+	switch c := condition.(type) {
+	case nil, *types.NilCondition:
+		// a nil condition can be fulfilled by any single signature
+		if uh, ok := singleSignatureSigner(fulfillment); ok {
+			status.Signed = []types.UnlockHash{uh}
+		}
+
+	case *types.UnlockHashCondition:
+		if uh, ok := singleSignatureSigner(fulfillment); ok && uh.Cmp(c.TargetUnlockHash) == 0 {
+			status.Signed = []types.UnlockHash{uh}
+		} else {
+			status.Missing = []types.UnlockHash{c.TargetUnlockHash}
+		}
+
+	case *types.TimeLockCondition:
+		return newPSTSignatureStatus(c.Condition, fulfillment)
+
+	case *types.MultiSignatureCondition:
+		status.Required = c.MinimumSignatureCount
+		var pairs []types.PublicKeySignaturePair
+		if msf, ok := fulfillment.(*types.MultiSignatureFulfillment); ok {
+			pairs = msf.Pairs
+		}
+		status.Signed, status.Missing = pairSigners(c.UnlockHashes, pairs)
+
+	case *gctypes.MultiSignatureAtomicSwapCondition:
+		// the contract is redeemed by its receivers when the secret is revealed, and refunded by its sender otherwise
+		signers := []types.UnlockHash{c.Sender}
+		var pairs []types.PublicKeySignaturePair
+		if msf, ok := fulfillment.(*gctypes.MultiSignatureAtomicSwapFulfillment); ok {
+			if msf.Secret != (types.AtomicSwapSecret{}) {
+				signers = c.Receivers
+				status.Required = c.MinimumSignatureCount
+			}
+			pairs = msf.Pairs
+		}
+		status.Signed, status.Missing = pairSigners(signers, pairs)
+
+	default:
+		status.Complete = fulfillment != nil && fulfillment.FulfillmentType() != types.FulfillmentTypeNil
+		return status
+	}
+	status.Complete = uint64(len(status.Signed)) >= status.Required
+	return status
+}
+
+// pairSigners splits the given signers into those that signed one of the given pairs, and those that did not.
+func pairSigners(signers []types.UnlockHash, pairs []types.PublicKeySignaturePair) (signed, missing []types.UnlockHash) {
+	signatures := make(map[types.UnlockHash]struct{})
+	for _, pair := range pairs {
+		if len(pair.Signature) == 0 {
+			continue
+		}
+		if uh, err := types.NewPubKeyUnlockHash(pair.PublicKey); err == nil {
+			signatures[uh] = struct{}{}
+		}
+	}
+	for _, uh := range signers {
+		if _, ok := signatures[uh]; ok {
+			signed = append(signed, uh)
+		} else {
+			missing = append(missing, uh)
+		}
+	}
+	return signed, missing
+}
+
+// singleSignatureSigner returns the address of the signer of the given fulfillment,
+// only if it is a signed single signature fulfillment.
+func singleSignatureSigner(fulfillment types.MarshalableUnlockFulfillment) (types.UnlockHash, bool) {
+	ssf, ok := fulfillment.(*types.SingleSignatureFulfillment)
+	if !ok || len(ssf.Signature) == 0 {
+		return types.UnlockHash{}, false
+	}
+	uh, err := types.NewPubKeyUnlockHash(ssf.PublicKey)
+	if err != nil {
+		return types.UnlockHash{}, false
+	}
+	return uh, true
+}
+
+// UpdateSignatureStatus updates the signature status of all inputs (and extension) of the PST,
+// using the fulfillments currently defined in its transaction.
+func (pst *PartiallySignedTransaction) UpdateSignatureStatus() error {
+	if pst.Version != PSTVersion {
+		return ErrUnknownPSTVersion
+	}
+	if len(pst.CoinInputs) != len(pst.Transaction.CoinInputs) || len(pst.BlockStakeInputs) != len(pst.Transaction.BlockStakeInputs) {
+		return ErrPSTInputMismatch
+	}
+	for idx, ci := range pst.Transaction.CoinInputs {
+		if ci.ParentID != pst.CoinInputs[idx].ParentID {
+			return ErrPSTInputMismatch
+		}
+		pst.CoinInputs[idx].Signatures = NewPSTSignatureStatus(pst.CoinInputs[idx].Parent.Condition, ci.Fulfillment)
+	}
+	for idx, bsi := range pst.Transaction.BlockStakeInputs {
+		if bsi.ParentID != pst.BlockStakeInputs[idx].ParentID {
+			return ErrPSTInputMismatch
+		}
+		pst.BlockStakeInputs[idx].Signatures = NewPSTSignatureStatus(pst.BlockStakeInputs[idx].Parent.Condition, bsi.Fulfillment)
+	}
+	fulfillment, condition, ok, err := extensionFulfillment(&pst.Transaction)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		pst.Extension = nil
+		return nil
+	}
+	pst.Extension = &PSTExtension{
+		Condition:  condition,
+		Signatures: NewPSTSignatureStatus(condition, *fulfillment),
+	}
+	return nil
+}
+
+// Complete returns true if all inputs (and extension) of the PST have the required signatures.
+func (pst PartiallySignedTransaction) Complete() bool {
+	for _, ci := range pst.CoinInputs {
+		if !ci.Signatures.Complete {
+			return false
+		}
+	}
+	for _, bsi := range pst.BlockStakeInputs {
+		if !bsi.Signatures.Complete {
+			return false
+		}
+	}
+	return pst.Extension == nil || pst.Extension.Signatures.Complete
+}
+
+// SignatureCount returns the amount of signatures provided for the inputs (and extension) of the PST.
+func (pst PartiallySignedTransaction) SignatureCount() (count int) {
+	for _, ci := range pst.CoinInputs {
+		count += len(ci.Signatures.Signed)
+	}
+	for _, bsi := range pst.BlockStakeInputs {
+		count += len(bsi.Signatures.Signed)
+	}
+	if pst.Extension != nil {
+		count += len(pst.Extension.Signatures.Signed)
+	}
+	return
+}
+
+// MissingSigners returns the unique addresses that can still sign one of the
+// incomplete inputs (or extension) of the PST, in the order they are first defined.
+func (pst PartiallySignedTransaction) MissingSigners() []types.UnlockHash {
+	var (
+		signers []types.UnlockHash
+		known   = make(map[types.UnlockHash]struct{})
+	)
+	addStatus := func(status PSTSignatureStatus) {
+		if status.Complete {
+			return
+		}
+		for _, uh := range status.Missing {
+			if _, ok := known[uh]; !ok {
+				known[uh] = struct{}{}
+				signers = append(signers, uh)
+			}
+		}
+	}
+	for _, ci := range pst.CoinInputs {
+		addStatus(ci.Signatures)
+	}
+	for _, bsi := range pst.BlockStakeInputs {
+		addStatus(bsi.Signatures)
+	}
+	if pst.Extension != nil {
+		addStatus(pst.Extension.Signatures)
+	}
+	return signers
+}
+
+// CombinePartiallySignedTransactions combines the signatures of the given PSTs,
+// each signed by one or multiple co-signers, into a single PST.
+// All PSTs have to be created for the same (unsigned) transaction. The signatures of multisig fulfillments are merged,
+// a signature of a public key being kept only once. For all other fulfillments the first signed one is kept.
+func CombinePartiallySignedTransactions(psts ...PartiallySignedTransaction) (PartiallySignedTransaction, error) {
+	if len(psts) == 0 {
+		return PartiallySignedTransaction{}, ErrNoPartiallySignedTransactions
+	}
+	combined, err := copyPartiallySignedTransaction(psts[0])
+	if err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+	// the signature hash is used to compare the transactions, as the transaction ID covers the fulfillments as well
+	sigHash, err := combined.Transaction.SignatureHash()
+	if err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+	for idx := range psts {
+		if psts[idx].Version != PSTVersion {
+			return PartiallySignedTransaction{}, ErrUnknownPSTVersion
+		}
+		otherSigHash, err := psts[idx].Transaction.SignatureHash()
+		if err != nil {
+			return PartiallySignedTransaction{}, err
+		}
+		if otherSigHash != sigHash {
+			return PartiallySignedTransaction{}, fmt.Errorf(
+				"partially signed transaction #%d is created for another transaction than partially signed transaction #1", idx+1)
+		}
+	}
+
+	for idx := range combined.Transaction.CoinInputs {
+		fulfillment := &combined.Transaction.CoinInputs[idx].Fulfillment
+		*fulfillment = types.UnlockFulfillmentProxy{}
+		for _, pst := range psts {
+			err = mergeFulfillment(fulfillment, pst.Transaction.CoinInputs[idx].Fulfillment)
+			if err != nil {
+				return PartiallySignedTransaction{}, fmt.Errorf("failed to combine coin input #%d: %v", idx+1, err)
+			}
+		}
+	}
+	for idx := range combined.Transaction.BlockStakeInputs {
+		fulfillment := &combined.Transaction.BlockStakeInputs[idx].Fulfillment
+		*fulfillment = types.UnlockFulfillmentProxy{}
+		for _, pst := range psts {
+			err = mergeFulfillment(fulfillment, pst.Transaction.BlockStakeInputs[idx].Fulfillment)
+			if err != nil {
+				return PartiallySignedTransaction{}, fmt.Errorf("failed to combine block stake input #%d: %v", idx+1, err)
+			}
+		}
+	}
+	fulfillment, _, ok, err := extensionFulfillment(&combined.Transaction)
+	if err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+	if ok {
+		*fulfillment = types.UnlockFulfillmentProxy{}
+		for _, pst := range psts {
+			txn := pst.Transaction
+			other, _, _, err := extensionFulfillment(&txn)
+			if err != nil {
+				return PartiallySignedTransaction{}, err
+			}
+			if other == nil {
+				continue
+			}
+			err = mergeFulfillment(fulfillment, *other)
+			if err != nil {
+				return PartiallySignedTransaction{}, fmt.Errorf("failed to combine extension fulfillment: %v", err)
+			}
+		}
+	}
+
+	err = combined.UpdateSignatureStatus()
+	if err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+	return combined, nil
+}
+
+// mergeFulfillment merges the signatures of the src fulfillment into the dst fulfillment.
+func mergeFulfillment(dst *types.UnlockFulfillmentProxy, src types.UnlockFulfillmentProxy) error {
+	switch sf := src.Fulfillment.(type) {
+	case nil, *types.NilFulfillment:
+		return nil
+
+	case *types.MultiSignatureFulfillment:
+		df, ok := dst.Fulfillment.(*types.MultiSignatureFulfillment)
+		if !ok {
+			if dst.FulfillmentType() != types.FulfillmentTypeNil {
+				return fmt.Errorf("cannot combine multisig fulfillment with fulfillment of type %d", dst.FulfillmentType())
+			}
+			df = &types.MultiSignatureFulfillment{}
+			dst.Fulfillment = df
+		}
+		for _, pair := range sf.Pairs {
+			if len(pair.Signature) == 0 || containsSignaturePair(df.Pairs, pair.PublicKey) {
+				continue
+			}
+			df.Pairs = append(df.Pairs, pair)
+		}
+		return nil
+
+	case *gctypes.MultiSignatureAtomicSwapFulfillment:
+		df, ok := dst.Fulfillment.(*gctypes.MultiSignatureAtomicSwapFulfillment)
+		if !ok {
+			if dst.FulfillmentType() != types.FulfillmentTypeNil {
+				return fmt.Errorf("cannot combine multisig atomic swap fulfillment with fulfillment of type %d", dst.FulfillmentType())
+			}
+			df = &gctypes.MultiSignatureAtomicSwapFulfillment{Secret: sf.Secret}
+			dst.Fulfillment = df
+		}
+		if df.Secret != sf.Secret {
+			return errors.New("cannot combine multisig atomic swap fulfillments revealing a different secret")
+		}
+		for _, pair := range sf.Pairs {
+			if len(pair.Signature) == 0 || containsSignaturePair(df.Pairs, pair.PublicKey) {
+				continue
+			}
+			df.Pairs = append(df.Pairs, pair)
+		}
+		return nil
+
+	case *types.SingleSignatureFulfillment:
+		switch df := dst.Fulfillment.(type) {
+		case nil, *types.NilFulfillment:
+		case *types.SingleSignatureFulfillment:
+			if len(df.Signature) > 0 || len(sf.Signature) == 0 {
+				return nil
+			}
+		default:
+			return fmt.Errorf("cannot combine single signature fulfillment with fulfillment of type %d", dst.FulfillmentType())
+		}
+		dst.Fulfillment = &types.SingleSignatureFulfillment{
+			PublicKey: sf.PublicKey,
+			Signature: sf.Signature,
+		}
+		return nil
+
+	default:
+		if dst.FulfillmentType() == types.FulfillmentTypeNil {
+			dst.Fulfillment = src.Fulfillment
+		}
+		return nil
+	}
+}
+
+// containsSignaturePair returns true if a signature of the given public key is part of the given pairs.
+func containsSignaturePair(pairs []types.PublicKeySignaturePair, pk types.PublicKey) bool {
+	for _, pair := range pairs {
+		if pair.PublicKey.Algorithm == pk.Algorithm && bytes.Equal(pair.PublicKey.Key, pk.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionFulfillment returns a pointer to the fulfillment defined within the extension data of the given transaction,
+// as well as the condition it has to fulfill. False is returned if the extension data of the transaction defines no fulfillment.
+func extensionFulfillment(txn *types.Transaction) (fulfillment *types.UnlockFulfillmentProxy, condition types.UnlockConditionProxy, ok bool, err error) {
+	err = txn.SignExtension(func(ff *types.UnlockFulfillmentProxy, cond types.UnlockConditionProxy, _ ...interface{}) error {
+		fulfillment, condition, ok = ff, cond, ff != nil
+		return nil
+	})
+	return
+}
+
+// copyPartiallySignedTransaction creates a deep copy of the given PST,
+// such that its fulfillments can be modified without affecting the original PST.
+func copyPartiallySignedTransaction(pst PartiallySignedTransaction) (PartiallySignedTransaction, error) {
+	b, err := json.Marshal(pst)
+	if err != nil {
+		return PartiallySignedTransaction{}, fmt.Errorf("failed to copy partially signed transaction: %v", err)
+	}
+	var cpst PartiallySignedTransaction
+	err = json.Unmarshal(b, &cpst)
+	if err != nil {
+		return PartiallySignedTransaction{}, fmt.Errorf("failed to copy partially signed transaction: %v", err)
+	}
+	return cpst, nil
+}
@@ -0,0 +1,253 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/types"
+
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+func init() {
+	gctypes.RegisterMultiSignatureAtomicSwapTypes()
+}
+
+type pstTestKey struct {
+	pk types.PublicKey
+	sk crypto.SecretKey
+	uh types.UnlockHash
+}
+
+func newPSTTestKey(t *testing.T) pstTestKey {
+	sk, pk := crypto.GenerateKeyPair()
+	key := pstTestKey{pk: types.Ed25519PublicKey(pk), sk: sk}
+	var err error
+	key.uh, err = types.NewPubKeyUnlockHash(key.pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signPSTMultisigInput signs the first coin input of the given PST, using a multisig fulfillment.
+func signPSTMultisigInput(t *testing.T, pst PartiallySignedTransaction, key pstTestKey) PartiallySignedTransaction {
+	pst, err := copyPartiallySignedTransaction(pst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fulfillment := &pst.Transaction.CoinInputs[0].Fulfillment
+	if fulfillment.FulfillmentType() == types.FulfillmentTypeNil {
+		fulfillment.Fulfillment = &types.MultiSignatureFulfillment{}
+	}
+	err = fulfillment.Sign(types.FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  pst.Transaction,
+		Key: types.KeyPair{
+			PublicKey:  key.pk,
+			PrivateKey: types.ByteSlice(key.sk[:]),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pst.UpdateSignatureStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pst
+}
+
+// TestCombinePartiallySignedTransactions checks that the signatures of co-signers,
+// each signing their own copy of a PST spending a 2-of-3 multisig output, are combined
+// into a single valid fulfillment, and that the missing signatures are reported until then.
+func TestCombinePartiallySignedTransactions(t *testing.T) {
+	keys := []pstTestKey{newPSTTestKey(t), newPSTTestKey(t), newPSTTestKey(t)}
+	condition := types.NewCondition(types.NewMultiSignatureCondition(
+		types.UnlockHashSlice{keys[0].uh, keys[1].uh, keys[2].uh}, 2))
+	var parentID types.CoinOutputID
+	parentID[0] = 1
+	pst := PartiallySignedTransaction{
+		Version: PSTVersion,
+		Transaction: types.Transaction{
+			Version: types.TransactionVersionOne,
+			CoinInputs: []types.CoinInput{
+				{ParentID: parentID},
+			},
+			CoinOutputs: []types.CoinOutput{
+				{Value: types.NewCurrency64(99), Condition: types.NewCondition(types.NewUnlockHashCondition(keys[0].uh))},
+			},
+			MinerFees: []types.Currency{types.NewCurrency64(1)},
+		},
+		CoinInputs: []PSTCoinInput{
+			{ParentID: parentID, Parent: types.CoinOutput{Value: types.NewCurrency64(100), Condition: condition}},
+		},
+	}
+	err := pst.UpdateSignatureStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pst.Complete() || pst.SignatureCount() != 0 || len(pst.MissingSigners()) != 3 {
+		t.Fatalf("unexpected status of unsigned PST: %+v", pst.CoinInputs[0].Signatures)
+	}
+
+	// the first co-signer signs twice, as the wallet does when signing an already signed PST
+	first := signPSTMultisigInput(t, signPSTMultisigInput(t, pst, keys[0]), keys[0])
+	status := first.CoinInputs[0].Signatures
+	if first.Complete() || status.Required != 2 || len(status.Signed) != 1 || status.Signed[0] != keys[0].uh || len(status.Missing) != 2 {
+		t.Fatalf("unexpected status of PST signed by first co-signer: %+v", status)
+	}
+	second := signPSTMultisigInput(t, pst, keys[2])
+
+	combined, err := CombinePartiallySignedTransactions(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !combined.Complete() || combined.SignatureCount() != 2 || len(combined.MissingSigners()) != 0 {
+		t.Fatalf("unexpected status of combined PST: %+v", combined.CoinInputs[0].Signatures)
+	}
+	if pairs := combined.Transaction.CoinInputs[0].Fulfillment.Fulfillment.(*types.MultiSignatureFulfillment).Pairs; len(pairs) != 2 {
+		t.Fatalf("expected 2 signature pairs, found %d", len(pairs))
+	}
+	err = condition.Fulfill(combined.Transaction.CoinInputs[0].Fulfillment, types.FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  combined.Transaction,
+	})
+	if err != nil {
+		t.Fatal("combined fulfillment is invalid:", err)
+	}
+	// combining does not modify the given PSTs
+	if first.SignatureCount() != 1 || second.SignatureCount() != 1 {
+		t.Fatal("combining modified the given PSTs")
+	}
+
+	// PSTs of different transactions cannot be combined
+	other := pst
+	other.Transaction.MinerFees = []types.Currency{types.NewCurrency64(2)}
+	if _, err = CombinePartiallySignedTransactions(first, other); err == nil {
+		t.Fatal("expected PSTs of different transactions not to be combined")
+	}
+	if _, err = CombinePartiallySignedTransactions(); err != ErrNoPartiallySignedTransactions {
+		t.Fatal("unexpected error for no PSTs:", err)
+	}
+}
+
+// TestNewPSTSignatureStatus checks the signature status of single signature and time locked fulfillments.
+func TestNewPSTSignatureStatus(t *testing.T) {
+	key, otherKey := newPSTTestKey(t), newPSTTestKey(t)
+	condition := types.NewCondition(types.NewTimeLockCondition(42, types.NewUnlockHashCondition(key.uh)))
+
+	status := NewPSTSignatureStatus(condition, types.NewFulfillment(types.NewSingleSignatureFulfillment(key.pk)))
+	if status.Complete || len(status.Signed) != 0 || len(status.Missing) != 1 || status.Missing[0] != key.uh {
+		t.Fatalf("unexpected status of unsigned fulfillment: %+v", status)
+	}
+	status = NewPSTSignatureStatus(condition, types.NewFulfillment(&types.SingleSignatureFulfillment{
+		PublicKey: otherKey.pk, Signature: types.ByteSlice{1},
+	}))
+	if status.Complete {
+		t.Fatalf("unexpected status of fulfillment signed by another key: %+v", status)
+	}
+	status = NewPSTSignatureStatus(condition, types.NewFulfillment(&types.SingleSignatureFulfillment{
+		PublicKey: key.pk, Signature: types.ByteSlice{1},
+	}))
+	if !status.Complete || len(status.Signed) != 1 || status.Signed[0] != key.uh || len(status.Missing) != 0 {
+		t.Fatalf("unexpected status of signed fulfillment: %+v", status)
+	}
+}
+
+// TestMultiSigAtomicSwapPST checks that the co-signers of the multisig receiver of an atomic swap contract
+// each sign their own copy of a PST redeeming it, and that the combined fulfillment redeems the contract,
+// while a refund only requires the signature of the sender.
+func TestMultiSigAtomicSwapPST(t *testing.T) {
+	sender := newPSTTestKey(t)
+	keys := []pstTestKey{newPSTTestKey(t), newPSTTestKey(t), newPSTTestKey(t)}
+	secret := types.AtomicSwapSecret{1}
+	contract := gctypes.NewMultiSignatureAtomicSwapCondition(sender.uh,
+		*types.NewMultiSignatureCondition(types.UnlockHashSlice{keys[0].uh, keys[1].uh, keys[2].uh}, 2),
+		types.NewAtomicSwapHashedSecret(secret), 42)
+	condition := types.NewCondition(contract)
+	var parentID types.CoinOutputID
+	parentID[0] = 1
+	newPST := func(secret types.AtomicSwapSecret) PartiallySignedTransaction {
+		pst := PartiallySignedTransaction{
+			Version: PSTVersion,
+			Transaction: types.Transaction{
+				Version: types.TransactionVersionOne,
+				CoinInputs: []types.CoinInput{
+					{
+						ParentID:    parentID,
+						Fulfillment: types.NewFulfillment(&gctypes.MultiSignatureAtomicSwapFulfillment{Secret: secret}),
+					},
+				},
+				CoinOutputs: []types.CoinOutput{
+					{Value: types.NewCurrency64(99), Condition: types.NewCondition(types.NewUnlockHashCondition(keys[0].uh))},
+				},
+				MinerFees: []types.Currency{types.NewCurrency64(1)},
+			},
+			CoinInputs: []PSTCoinInput{
+				{ParentID: parentID, Parent: types.CoinOutput{Value: types.NewCurrency64(100), Condition: condition}},
+			},
+		}
+		err := pst.UpdateSignatureStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pst
+	}
+	fulfill := func(pst PartiallySignedTransaction, blockTime types.Timestamp) error {
+		return condition.Fulfill(pst.Transaction.CoinInputs[0].Fulfillment, types.FulfillContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			BlockTime:    blockTime,
+			Transaction:  pst.Transaction,
+		})
+	}
+
+	pst := newPST(secret)
+	if status := pst.CoinInputs[0].Signatures; pst.Complete() || status.Required != 2 || len(status.Missing) != 3 {
+		t.Fatalf("unexpected status of unsigned redeem PST: %+v", status)
+	}
+	first := signPSTMultisigInput(t, pst, keys[0])
+	if first.Complete() || first.SignatureCount() != 1 {
+		t.Fatalf("unexpected status of redeem PST signed by first co-signer: %+v", first.CoinInputs[0].Signatures)
+	}
+	if err := fulfill(first, 0); err != types.ErrInsufficientSignatures {
+		t.Fatal("expected contract not to be redeemed using a single signature, received:", err)
+	}
+	if err := fulfill(signPSTMultisigInput(t, first, keys[0]), 0); err != types.ErrUnauthorizedPubKey {
+		t.Fatal("expected contract not to be redeemed using the same signature twice, received:", err)
+	}
+	combined, err := CombinePartiallySignedTransactions(first, signPSTMultisigInput(t, pst, keys[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !combined.Complete() || combined.SignatureCount() != 2 {
+		t.Fatalf("unexpected status of combined redeem PST: %+v", combined.CoinInputs[0].Signatures)
+	}
+	if err = fulfill(combined, 0); err != nil {
+		t.Fatal("combined fulfillment does not redeem the contract:", err)
+	}
+
+	// co-signers revealing different secrets cannot be combined
+	if _, err = CombinePartiallySignedTransactions(first, newPST(types.AtomicSwapSecret{2})); err == nil {
+		t.Fatal("expected fulfillments revealing different secrets not to be combined")
+	}
+
+	// a refund only requires the signature of the sender, once the contract has expired
+	refund := newPST(types.AtomicSwapSecret{})
+	if status := refund.CoinInputs[0].Signatures; status.Required != 1 || len(status.Missing) != 1 || status.Missing[0] != sender.uh {
+		t.Fatalf("unexpected status of unsigned refund PST: %+v", status)
+	}
+	if err = fulfill(signPSTMultisigInput(t, refund, keys[0]), 43); err != types.ErrUnauthorizedPubKey {
+		t.Fatal("expected contract not to be refunded by a receiver, received:", err)
+	}
+	refund = signPSTMultisigInput(t, refund, sender)
+	if !refund.Complete() {
+		t.Fatalf("unexpected status of signed refund PST: %+v", refund.CoinInputs[0].Signatures)
+	}
+	if err = fulfill(refund, 42); err != types.ErrPrematureRefund {
+		t.Fatal("expected contract not to be refunded before its time lock, received:", err)
+	}
+	if err = fulfill(refund, 43); err != nil {
+		t.Fatal("signed fulfillment does not refund the contract:", err)
+	}
+}
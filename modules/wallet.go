@@ -1,10 +1,24 @@
 package modules
 
 import (
+	"time"
+
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/types"
 
 	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	"github.com/nbh-digital/goldchain/pkg/hdkeys"
+)
+
+// The directions and categories which can be used to filter the transactions of a wallet.
+const (
+	TransactionDirectionIncoming TransactionDirection = "incoming"
+	TransactionDirectionOutgoing TransactionDirection = "outgoing"
+
+	TransactionCategoryRegular TransactionCategory = "regular"
+	TransactionCategoryMinting TransactionCategory = "minting"
+	TransactionCategoryAuth    TransactionCategory = "auth"
 )
 
 type (
@@ -24,13 +38,270 @@ type (
 		// for all unlocked and locked confirmed coin outputs, in case you would spent them all.
 		ConfirmedCustodyFeesToBePaid() (custodyfees types.Currency, err error)
 
-		// Transactions returns all of the transactions that were confirmed at
-		// heights [startHeight, endHeight]. Unconfirmed transactions are not
-		// included.
-		TransactionsWithCustodyInfo(startHeight types.BlockHeight, endHeight types.BlockHeight) ([]ProcessedTransaction, error)
+		// TransactionsWithCustodyInfo returns the transactions, confirmed at heights [filter.StartHeight, filter.EndHeight],
+		// which match the given filter. Unconfirmed transactions are not included.
+		// The transactions of the watch-only addresses are listed separately, using the WatchOnly filter property.
+		// If more transactions match the filter than the defined limit, the cursor
+		// to be used to fetch the next page of transactions is returned as well.
+		TransactionsWithCustodyInfo(filter TransactionFilter) ([]ProcessedTransaction, string, error)
 
 		// MultiSigWalletsWithCustodyFeeDebt is the same as regular MultiSigWalletsCall but with custody fee debt included.
 		MultiSigWalletsWithCustodyFeeDebt() ([]MultiSigWallet, error)
+		// FundMultiSigCoinTransaction creates a transaction sending the given coin outputs,
+		// funded by the given multisig wallet and signed by this wallet,
+		// returned as a partially signed transaction to be signed by the other co-signers.
+		FundMultiSigCoinTransaction(address types.UnlockHash, coinOutputs []types.CoinOutput, data []byte) (PartiallySignedTransaction, error)
+
+		// WatchOnlyAddresses returns all addresses tracked by this wallet,
+		// for which the wallet does not own the keys required to spend from them.
+		WatchOnlyAddresses() ([]WatchOnlyAddress, error)
+
+		// AddWatchOnlyAddresses adds the given addresses as watch-only addresses to this wallet,
+		// rescanning the consensus set such that the history of these addresses is known as well.
+		AddWatchOnlyAddresses(addresses []WatchOnlyAddress) error
+
+		// RemoveWatchOnlyAddresses removes the given addresses from the watch-only addresses of this wallet.
+		RemoveWatchOnlyAddresses(addresses []types.UnlockHash) error
+
+		// ConfirmedBalances returns the confirmed balances and custody fee debt of this wallet,
+		// with those of its watch-only addresses in a separate section. The balances of the
+		// addresses owned by the wallet are only defined while the wallet is unlocked.
+		ConfirmedBalances() (WalletBalances, error)
+
+		// CreateWatchOnlyCoinTransaction creates an unsigned transaction, sending the given coin outputs,
+		// funded by the unspent coin outputs of the watch-only addresses of this wallet.
+		// The returned transaction is to be signed offline, by the owner(s) of the watch-only addresses.
+		CreateWatchOnlyCoinTransaction(coinOutputs []types.CoinOutput, data []byte, refundAddress *types.UnlockHash) (types.Transaction, error)
+
+		// CreateCoinTransaction creates and signs a transaction, sending the given coin outputs,
+		// funded by the unspent coin outputs of this wallet, without giving it to the transaction pool.
+		// It allows a transaction to be inspected or simulated, prior to actually sending the coins.
+		CreateCoinTransaction(coinOutputs []types.CoinOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error)
+
+		// BurnCoins creates and signs a coin destruction transaction, burning the given amount of coins,
+		// funded by the unspent coin outputs of this wallet, with the custody fee and miner fee paid on top.
+		// The transaction is given to the transaction pool, keeping its coin inputs reserved until then.
+		BurnCoins(amount types.Currency, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error)
+
+		// CreatePartiallySignedTransaction wraps the given transaction into a partially signed transaction (PST),
+		// describing the parent outputs, custody fee and missing signatures of its inputs.
+		CreatePartiallySignedTransaction(txn types.Transaction) (PartiallySignedTransaction, error)
+
+		// SignPartiallySignedTransaction adds all signatures to the given PST
+		// that can be provided using the keys of this wallet.
+		SignPartiallySignedTransaction(pst PartiallySignedTransaction) (PartiallySignedTransaction, error)
+
+		// FinalizePartiallySignedTransaction gives the transaction of the given PST to the transaction pool,
+		// given it has all required signatures.
+		FinalizePartiallySignedTransaction(pst PartiallySignedTransaction) (types.Transaction, error)
+
+		// CreateAtomicSwapContract creates and funds an atomic swap contract, locking the given amount of coins,
+		// redeemable by the receiver using the secret of the given hashed secret,
+		// or refundable by the sender, an address of this wallet, once the given duration has passed.
+		// When escrowing the custody fee, the sender pre-funds the custody fee and miner fee on top of the given amount,
+		// such that the receiver receives at least the given amount when redeeming the contract before its time lock.
+		CreateAtomicSwapContract(receiver types.UnlockHash, amount types.Currency, hashedSecret types.AtomicSwapHashedSecret, duration time.Duration, sender *types.UnlockHash, escrowCustodyFee bool) (AtomicSwapContract, types.Transaction, error)
+
+		// CreateAtomicSwapContracts creates and funds a multi-output atomic swap contract in a single transaction,
+		// locking an atomic swap contract for each of the given receivers, all sharing the same sender,
+		// hashed secret and time lock, such that the secret revealed to redeem one of them redeems all of them.
+		CreateAtomicSwapContracts(receivers []AtomicSwapReceiver, hashedSecret types.AtomicSwapHashedSecret, duration time.Duration, sender *types.UnlockHash, escrowCustodyFee bool) ([]AtomicSwapContract, types.Transaction, error)
+
+		// AuditAtomicSwapContract returns the (confirmed or unconfirmed) atomic swap contract
+		// locked in the unspent coin output with the given ID.
+		AuditAtomicSwapContract(outputID types.CoinOutputID) (AtomicSwapContract, error)
+
+		// RedeemAtomicSwapContract redeems the atomic swap contract locked in the given coin output,
+		// using the given secret and the key of the receiver owned by this wallet.
+		RedeemAtomicSwapContract(outputID types.CoinOutputID, secret types.AtomicSwapSecret) (types.Transaction, error)
+
+		// RedeemAtomicSwapContracts redeems the atomic swap contracts locked in the given coin outputs
+		// in a single transaction, using the given secret and the keys of the receivers owned by this wallet.
+		RedeemAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) (types.Transaction, error)
+
+		// RedeemMultiSigAtomicSwapContracts creates the transaction redeeming the atomic swap contracts locked in the given coin outputs,
+		// of which the receivers are multisig addresses, signed using the keys of the co-signers owned by this wallet.
+		// It is returned as a PST, to be signed by the other co-signers and finalized once complete.
+		RedeemMultiSigAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) (PartiallySignedTransaction, error)
+
+		// RefundAtomicSwapContract refunds the atomic swap contract locked in the given coin output,
+		// using the key of the sender owned by this wallet, once the time lock of the contract has been reached.
+		RefundAtomicSwapContract(outputID types.CoinOutputID) (types.Transaction, error)
+
+		// RefundAtomicSwapContracts refunds the atomic swap contracts locked in the given coin outputs
+		// in a single transaction, using the keys of the senders owned by this wallet,
+		// once the time locks of the contracts have been reached.
+		RefundAtomicSwapContracts(outputIDs []types.CoinOutputID) (types.Transaction, error)
+
+		// AtomicSwapContracts returns all confirmed atomic swap contracts of which the wallet
+		// owns the sender and/or receiver address, open or spent, in the order they were confirmed.
+		// Open contracts are redeemed by the wallet as soon as their secret is revealed on chain,
+		// and refunded as soon as their time lock has been reached.
+		AtomicSwapContracts() ([]TrackedAtomicSwapContract, error)
+
+		// AddressLabels returns the labels, notes and tags of all addresses labeled by this wallet,
+		// sorted in byte-order of the addresses.
+		AddressLabels() ([]AddressLabel, error)
+
+		// SetAddressLabels labels the given addresses, which can be owned by the wallet or not,
+		// replacing the label, notes and tags previously defined for those addresses.
+		SetAddressLabels(labels []AddressLabel) error
+
+		// RemoveAddressLabels removes the label, notes and tags of the given addresses.
+		RemoveAddressLabels(addresses []types.UnlockHash) error
+
+		// EnableHDDerivation migrates the primary seed to hierarchical deterministic (HD) key derivation,
+		// such that all new addresses are derived per account, using separate deposit and change chains.
+		// Addresses generated prior to this migration remain tracked and spendable.
+		EnableHDDerivation() error
+
+		// HDAccounts returns the derivation progress of all HD accounts of the primary seed.
+		HDAccounts() ([]HDAccount, error)
+
+		// NextHDAddresses reserves the next deposit addresses of the given HD account of the primary seed.
+		NextHDAddresses(account uint32, count uint64) ([]HDAddress, error)
+
+		// AddressGapLimit returns the number of consecutive unused addresses
+		// that the wallet tracks for each seed, past the last used address of that seed.
+		AddressGapLimit() uint64
+
+		// SetAddressGapLimit configures the number of consecutive unused addresses
+		// that the wallet tracks for each seed, past the last used address of that seed.
+		SetAddressGapLimit(limit uint64) error
+
+		// RescanSeeds rescans the consensus set, extending the keys of each seed until
+		// the address gap limit is respected, and reports the keys and funds discovered for each seed.
+		RescanSeeds() ([]SeedScanReport, error)
+
+		// SeedScanReports reports the keys and funds currently known for each seed of the wallet.
+		SeedScanReports() ([]SeedScanReport, error)
+
+		// ChangePassword re-encrypts the wallet using the new master key,
+		// given the current master key of the wallet is correct.
+		ChangePassword(masterKey, newMasterKey crypto.TwofishKey) error
+	}
+
+	// SeedScanReport contains the keys and funds discovered for a single seed of the wallet.
+	SeedScanReport struct {
+		// SeedIndex is the index of the seed, the primary seed has index 0
+		SeedIndex int  `json:"seedindex"`
+		Primary   bool `json:"primary"`
+		// AddressesLoaded is the amount of addresses tracked for the seed,
+		// AddressesUsed is the amount of those addresses which received funds
+		AddressesLoaded uint64 `json:"addressesloaded"`
+		AddressesUsed   uint64 `json:"addressesused"`
+
+		ConfirmedCoinBalance             types.Currency `json:"confirmedcoinbalance"`
+		ConfirmedLockedCoinBalance       types.Currency `json:"confirmedlockedcoinbalance"`
+		ConfirmedCustodyFeeDebt          types.Currency `json:"confirmedcustodyfeedebt"`
+		ConfirmedBlockStakeBalance       types.Currency `json:"confirmedblockstakebalance"`
+		ConfirmedLockedBlockStakeBalance types.Currency `json:"confirmedlockedblockstakebalance"`
+	}
+
+	// HDAccount contains the derivation progress of a single HD account.
+	HDAccount struct {
+		Index uint32 `json:"index"`
+		Path  string `json:"path"`
+		// ExtendedPublicKey allows the deposit (chain 0) and change (chain 1) addresses
+		// of this account to be derived without access to the seed
+		ExtendedPublicKey hdkeys.ExtendedPublicKey `json:"extendedpublickey"`
+		// ExternalAddresses and InternalAddresses are the amount of
+		// deposit and change addresses handed out by the wallet
+		ExternalAddresses uint64 `json:"externaladdresses"`
+		InternalAddresses uint64 `json:"internaladdresses"`
+		// ExternalUsed and InternalUsed are the index of the
+		// last used deposit and change address + 1, as seen on chain
+		ExternalUsed uint64 `json:"externalused"`
+		InternalUsed uint64 `json:"internalused"`
+	}
+
+	// HDAddress is an address derived using HD derivation.
+	HDAddress struct {
+		Address   types.UnlockHash `json:"address"`
+		PublicKey types.PublicKey  `json:"publickey"`
+		Path      string           `json:"path"`
+	}
+
+	// WatchOnlyAddress is an address tracked by the wallet,
+	// without the wallet owning the private key required to spend from it.
+	WatchOnlyAddress struct {
+		Address types.UnlockHash `json:"address"`
+		// PublicKey is only defined if the address was imported using its public key
+		PublicKey *types.PublicKey `json:"publickey,omitempty"`
+	}
+
+	// AddressLabel is the metadata the wallet keeps for an address,
+	// either owned by the wallet or used as a counterparty.
+	AddressLabel struct {
+		Address types.UnlockHash `json:"address"`
+		Label   string           `json:"label"`
+		Notes   string           `json:"notes,omitempty"`
+		Tags    []string         `json:"tags,omitempty"`
+		// Owned indicates the address is owned by this wallet,
+		// it is defined by the wallet and ignored when labeling an address
+		Owned bool `json:"owned,omitempty"`
+	}
+
+	// WalletBalances contains the confirmed balances and custody fee debt of a wallet,
+	// with those of its watch-only addresses in a separate section.
+	WalletBalances struct {
+		// Owned is only defined while the wallet is unlocked
+		Owned *Balance `json:"owned,omitempty"`
+		// WatchOnly is the total of all watch-only addresses,
+		// with WatchOnlyAddresses containing the balance of each of them
+		WatchOnly          Balance                      `json:"watchonly"`
+		WatchOnlyAddresses map[types.UnlockHash]Balance `json:"watchonlyaddresses"`
+	}
+
+	// Balance contains the confirmed balances and custody fee debt,
+	// of one or multiple addresses.
+	Balance struct {
+		ConfirmedCoinBalance       types.Currency `json:"confirmedcoinbalance"`
+		ConfirmedLockedCoinBalance types.Currency `json:"confirmedlockedcoinbalance"`
+		ConfirmedCustodyFeeDebt    types.Currency `json:"confirmedcustodyfeedebt"`
+
+		ConfirmedBlockStakeBalance       types.Currency `json:"confirmedblockstakebalance"`
+		ConfirmedLockedBlockStakeBalance types.Currency `json:"confirmedlockedblockstakebalance"`
+	}
+
+	// TransactionDirection defines the direction of a transaction, as seen by the wallet.
+	TransactionDirection string
+
+	// TransactionCategory defines the category of a transaction, based on its version.
+	TransactionCategory string
+
+	// TransactionFilter is used to filter and paginate the confirmed transactions of the wallet.
+	// All filter properties which are not defined (zero) are ignored.
+	TransactionFilter struct {
+		StartHeight types.BlockHeight
+		EndHeight   types.BlockHeight
+
+		// Address only accepts transactions which have an input or output related to it
+		Address *types.UnlockHash
+		// Direction only accepts incoming or outgoing transactions,
+		// a transaction is outgoing when it spends at least one wallet input
+		Direction TransactionDirection
+		// Categories only accepts transactions of one of the given categories
+		Categories []TransactionCategory
+
+		// StartTime and EndTime only accept transactions confirmed within [StartTime, EndTime]
+		StartTime types.Timestamp
+		EndTime   types.Timestamp
+
+		// MinAmount and MaxAmount only accept transactions, for which the net
+		// amount of coins received or sent by the wallet is within [MinAmount, MaxAmount]
+		MinAmount types.Currency
+		MaxAmount types.Currency
+
+		// Cursor continues the listing right after the last transaction of a previous page
+		Cursor string
+		// Limit is the maximum amount of transactions returned
+		Limit uint64
+
+		// WatchOnly lists the transactions of the watch-only addresses,
+		// instead of those of the addresses owned by the wallet,
+		// in which case the wallet does not have to be unlocked
+		WatchOnly bool
 	}
 
 	WalletCoinOutput struct {
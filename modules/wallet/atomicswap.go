@@ -0,0 +1,502 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+var (
+	errAtomicSwapContractNotFound  = errors.New("no atomic swap contract found for the given output ID")
+	errNotAnAtomicSwapContract     = errors.New("the given output does not lock an atomic swap contract")
+	errAtomicSwapValueTooLow       = errors.New("an atomic swap contract has to have a coin value higher than the minimum transaction fee, or greater than 0 when escrowing its custody fee")
+	errAtomicSwapNilDuration       = errors.New("the duration of an atomic swap contract has to be greater than 0")
+	errAtomicSwapNilHashedSecret   = errors.New("the hashed secret of an atomic swap contract cannot be nil")
+	errAtomicSwapInvalidAddress    = errors.New("the sender and receiver of an atomic swap contract have to be public key addresses")
+	errAtomicSwapMultiSigReceiver  = errors.New("the multisig condition of a multisig receiver has to be given, matching its address, as its co-signers redeem the atomic swap contract")
+	errAtomicSwapMultiSigRedeem    = errors.New("the receiver of the atomic swap contract is a multisig address, its redeem transaction has to be co-signed as a partially signed transaction")
+	errAtomicSwapNoReceivers       = errors.New("an atomic swap contract requires at least one receiver")
+	errAtomicSwapDuplicateReceiver = errors.New("the receivers of a multi-output atomic swap contract have to be unique")
+	errAtomicSwapNoContracts       = errors.New("at least one atomic swap contract has to be given")
+	errAtomicSwapDuplicateContract = errors.New("an atomic swap contract cannot be spent more than once in the same transaction")
+	errAtomicSwapInvalidSecret     = errors.New("the given secret does not match the hashed secret of the atomic swap contract")
+	errAtomicSwapNotRefundable     = errors.New("the time lock of the atomic swap contract has not been reached yet")
+	errAtomicSwapKeyNotOwned       = errors.New("the wallet does not own the key required to spend the atomic swap contract")
+	errAtomicSwapSpendableValueLow = errors.New("the spendable value of the atomic swap contract does not cover the minimum transaction fee")
+)
+
+// CreateAtomicSwapContract creates an atomic swap contract, locking the given amount of coins,
+// which can be redeemed by the receiver using the secret matching the given hashed secret,
+// or refunded by the sender once the given duration has passed. The sender has to be an address of this wallet,
+// a new address is generated for it in case none is given.
+// The contract is funded by this wallet, and the transaction creating it is given to the transaction pool.
+//
+// When escrowing the custody fee, the contract locks the given amount together with a buffer
+// covering the miner fee and the custody fee to be paid when the contract is spent at its time lock,
+// such that the receiver receives at least the given amount when redeeming it in time.
+func (w *Wallet) CreateAtomicSwapContract(receiver types.UnlockHash, amount types.Currency, hashedSecret types.AtomicSwapHashedSecret, duration time.Duration, sender *types.UnlockHash, escrowCustodyFee bool) (gcmodules.AtomicSwapContract, types.Transaction, error) {
+	contracts, txn, err := w.CreateAtomicSwapContracts([]gcmodules.AtomicSwapReceiver{
+		{Address: receiver, Amount: amount},
+	}, hashedSecret, duration, sender, escrowCustodyFee)
+	if err != nil {
+		return gcmodules.AtomicSwapContract{}, types.Transaction{}, err
+	}
+	return contracts[0], txn, nil
+}
+
+// CreateAtomicSwapContracts creates a multi-output atomic swap contract, locking an atomic swap contract
+// for each of the given receivers, all sharing the same sender, hashed secret and time lock,
+// such that revealing the secret to redeem any of them allows all of them to be redeemed.
+// A receiver can be a multisig address, in which case its contract is redeemed
+// by the co-signers of that address, the redeemed coins being sent to it.
+// All contracts are funded by this wallet in a single transaction, which is given to the transaction pool,
+// and are returned in the order of the given receivers.
+func (w *Wallet) CreateAtomicSwapContracts(receivers []gcmodules.AtomicSwapReceiver, hashedSecret types.AtomicSwapHashedSecret, duration time.Duration, sender *types.UnlockHash, escrowCustodyFee bool) ([]gcmodules.AtomicSwapContract, types.Transaction, error) {
+	if len(receivers) == 0 {
+		return nil, types.Transaction{}, types.NewClientError(errAtomicSwapNoReceivers, types.ClientErrorBadRequest)
+	}
+	receivers = append([]gcmodules.AtomicSwapReceiver(nil), receivers...)
+	unique := make(map[types.UnlockHash]struct{}, len(receivers))
+	for idx := range receivers {
+		receiver := &receivers[idx]
+		if receiver.Amount.IsZero() || (!escrowCustodyFee && receiver.Amount.Cmp(w.chainCts.MinimumTransactionFee) <= 0) {
+			return nil, types.Transaction{}, types.NewClientError(errAtomicSwapValueTooLow, types.ClientErrorBadRequest)
+		}
+		if receiver.MultiSigCondition != nil {
+			if err := receiver.MultiSigCondition.IsStandardCondition(types.ValidationContext{}); err != nil {
+				return nil, types.Transaction{}, types.NewClientError(
+					fmt.Errorf("invalid multisig condition of receiver #%d: %v", idx+1, err), types.ClientErrorBadRequest)
+			}
+			uh := receiver.MultiSigCondition.UnlockHash()
+			if receiver.Address == (types.UnlockHash{}) {
+				receiver.Address = uh
+			} else if receiver.Address.Cmp(uh) != 0 {
+				return nil, types.Transaction{}, types.NewClientError(errAtomicSwapMultiSigReceiver, types.ClientErrorBadRequest)
+			}
+		}
+		switch receiver.Address.Type {
+		case types.UnlockTypePubKey:
+		case types.UnlockTypeMultiSig:
+			if receiver.MultiSigCondition == nil {
+				return nil, types.Transaction{}, types.NewClientError(errAtomicSwapMultiSigReceiver, types.ClientErrorBadRequest)
+			}
+		default:
+			return nil, types.Transaction{}, types.NewClientError(errAtomicSwapInvalidAddress, types.ClientErrorBadRequest)
+		}
+		// each contract is identified by its condition, which only differs in its receiver
+		if _, ok := unique[receiver.Address]; ok {
+			return nil, types.Transaction{}, types.NewClientError(errAtomicSwapDuplicateReceiver, types.ClientErrorBadRequest)
+		}
+		unique[receiver.Address] = struct{}{}
+	}
+	if duration <= 0 {
+		return nil, types.Transaction{}, types.NewClientError(errAtomicSwapNilDuration, types.ClientErrorBadRequest)
+	}
+	if hashedSecret == (types.AtomicSwapHashedSecret{}) {
+		return nil, types.Transaction{}, types.NewClientError(errAtomicSwapNilHashedSecret, types.ClientErrorBadRequest)
+	}
+
+	var (
+		senderUH types.UnlockHash
+		err      error
+	)
+	if sender != nil {
+		senderUH = *sender
+	} else {
+		senderUH, err = w.NextAddress()
+		if err != nil {
+			return nil, types.Transaction{}, err
+		}
+	}
+	if senderUH.Type != types.UnlockTypePubKey {
+		return nil, types.Transaction{}, types.NewClientError(errAtomicSwapInvalidAddress, types.ClientErrorBadRequest)
+	}
+	if sender != nil {
+		// ensure the contracts can be refunded by this wallet
+		w.mu.RLock()
+		unlocked := w.unlocked
+		_, owned := w.keys[senderUH]
+		w.mu.RUnlock()
+		if !unlocked {
+			return nil, types.Transaction{}, modules.ErrLockedWallet
+		}
+		if !owned {
+			return nil, types.Transaction{}, types.NewClientError(errAtomicSwapKeyNotOwned, types.ClientErrorBadRequest)
+		}
+	}
+
+	// the contracts are created at the earliest at the time of the current block
+	creationTime := w.getFulfillableContextForLatestBlock().BlockTime
+	timeLock := types.OffsetTimestamp(duration)
+	contracts := make([]gcmodules.AtomicSwapContract, 0, len(receivers))
+	coinOutputs := make([]types.CoinOutput, 0, len(receivers))
+	for _, receiver := range receivers {
+		var (
+			condition        types.MarshalableUnlockCondition
+			multiSigReceiver *types.MultiSignatureCondition
+		)
+		if receiver.MultiSigCondition != nil {
+			condition = gctypes.NewMultiSignatureAtomicSwapCondition(senderUH, *receiver.MultiSigCondition, hashedSecret, timeLock)
+			multiSigReceiver = receiver.MultiSigCondition
+		} else {
+			condition = &types.AtomicSwapCondition{
+				Sender:       senderUH,
+				Receiver:     receiver.Address,
+				HashedSecret: hashedSecret,
+				TimeLock:     timeLock,
+			}
+		}
+		contractCondition, _, _ := gcmodules.AtomicSwapContractCondition(types.NewCondition(condition))
+		value := receiver.Amount
+		if escrowCustodyFee {
+			value = gcmodules.AtomicSwapEscrowValue(receiver.Amount, creationTime, timeLock, w.chainCts.MinimumTransactionFee)
+		}
+		contract := gcmodules.AtomicSwapContract{
+			ContractAddress:    condition.UnlockHash(),
+			Contract:           contractCondition,
+			MultiSigReceiver:   multiSigReceiver,
+			Value:              value,
+			FeeComputationTime: creationTime,
+			SpendableValue:     value,
+		}
+		contract.GuaranteedValue, contract.WorstCaseCustodyFee = gcmodules.AtomicSwapContractGuarantees(
+			value, creationTime, contractCondition, w.chainCts.MinimumTransactionFee)
+		contracts = append(contracts, contract)
+		coinOutputs = append(coinOutputs, types.CoinOutput{
+			Value:     value,
+			Condition: types.NewCondition(condition),
+		})
+	}
+	txn, err := w.SendOutputs(coinOutputs, nil, nil, nil, true)
+	if err != nil {
+		return nil, types.Transaction{}, err
+	}
+
+	for idx := range contracts {
+		found := false
+		for coIdx, co := range txn.CoinOutputs {
+			if co.Condition.UnlockHash().Cmp(contracts[idx].ContractAddress) == 0 {
+				contracts[idx].OutputID = txn.CoinOutputID(uint64(coIdx))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, types.Transaction{}, fmt.Errorf(
+				"atomic swap contract %s not found in the coin outputs of transaction %s",
+				contracts[idx].ContractAddress.String(), txn.ID().String())
+		}
+	}
+	return contracts, txn, nil
+}
+
+// AuditAtomicSwapContract returns the atomic swap contract locked in the unspent coin output with the given ID,
+// which can be confirmed or still be part of the transaction pool.
+// The custody fee of the contract is computed at the time of the current block.
+func (w *Wallet) AuditAtomicSwapContract(outputID types.CoinOutputID) (gcmodules.AtomicSwapContract, error) {
+	if err := w.tg.Add(); err != nil {
+		return gcmodules.AtomicSwapContract{}, err
+	}
+	defer w.tg.Done()
+
+	ctx := w.getFulfillableContextForLatestBlock()
+	// any error is treated as the output not being unspent (anymore)
+	co, err := w.cs.GetCoinOutput(outputID)
+	if err == nil {
+		contract, err := newAtomicSwapContract(outputID, co, ctx)
+		if err != nil {
+			return gcmodules.AtomicSwapContract{}, err
+		}
+		contract.Confirmed = true
+		info, err := w.cfplugin.GetCoinOutputInfo(outputID, ctx.BlockTime)
+		if err != nil {
+			return gcmodules.AtomicSwapContract{}, fmt.Errorf("failed to get custody fee info for coin output %s: %v", outputID.String(), err)
+		}
+		contract.CustodyFee = info.CustodyFee
+		contract.SpendableValue = info.SpendableValue
+		contract.GuaranteedValue, contract.WorstCaseCustodyFee = gcmodules.AtomicSwapContractGuarantees(
+			contract.Value, info.CreationTime, contract.Contract, w.chainCts.MinimumTransactionFee)
+		return contract, nil
+	}
+
+	// the contract might not be confirmed yet,
+	// in which case no custody fee has to be paid for it yet,
+	// and it is created at the earliest at the time of the current block
+	for _, txn := range w.tpool.TransactionList() {
+		for idx, co := range txn.CoinOutputs {
+			if txn.CoinOutputID(uint64(idx)) != outputID {
+				continue
+			}
+			contract, err := newAtomicSwapContract(outputID, co, ctx)
+			if err != nil {
+				return gcmodules.AtomicSwapContract{}, err
+			}
+			contract.SpendableValue = co.Value
+			contract.GuaranteedValue, contract.WorstCaseCustodyFee = gcmodules.AtomicSwapContractGuarantees(
+				co.Value, ctx.BlockTime, contract.Contract, w.chainCts.MinimumTransactionFee)
+			return contract, nil
+		}
+	}
+	return gcmodules.AtomicSwapContract{}, types.NewClientError(errAtomicSwapContractNotFound, types.ClientErrorNotFound)
+}
+
+// newAtomicSwapContract creates the description of the atomic swap contract locked in the given coin output,
+// without any custody fee information.
+func newAtomicSwapContract(outputID types.CoinOutputID, co types.CoinOutput, ctx types.FulfillableContext) (gcmodules.AtomicSwapContract, error) {
+	condition, multiSigReceiver, ok := gcmodules.AtomicSwapContractCondition(co.Condition)
+	if !ok {
+		return gcmodules.AtomicSwapContract{}, types.NewClientError(errNotAnAtomicSwapContract, types.ClientErrorBadRequest)
+	}
+	return gcmodules.AtomicSwapContract{
+		OutputID:           outputID,
+		ContractAddress:    co.Condition.UnlockHash(),
+		Contract:           condition,
+		MultiSigReceiver:   multiSigReceiver,
+		Value:              co.Value,
+		FeeComputationTime: ctx.BlockTime,
+		Refundable:         ctx.BlockTime > condition.TimeLock,
+	}, nil
+}
+
+// RedeemAtomicSwapContract redeems the atomic swap contract locked in the unspent coin output with the given ID,
+// using the given secret. The receiver of the contract has to be an address of this wallet.
+// The transaction redeeming the contract is given to the transaction pool.
+func (w *Wallet) RedeemAtomicSwapContract(outputID types.CoinOutputID, secret types.AtomicSwapSecret) (types.Transaction, error) {
+	return w.RedeemAtomicSwapContracts([]types.CoinOutputID{outputID}, secret)
+}
+
+// RedeemAtomicSwapContracts redeems the atomic swap contracts locked in the unspent coin outputs with the given IDs,
+// all in the same transaction, using the given secret. The receivers of the contracts have to be addresses of this wallet,
+// a multisig receiver only in case this wallet can provide all signatures it requires.
+// The transaction redeeming the contracts is given to the transaction pool.
+func (w *Wallet) RedeemAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) (types.Transaction, error) {
+	if secret == (types.AtomicSwapSecret{}) {
+		return types.Transaction{}, types.NewClientError(errAtomicSwapInvalidSecret, types.ClientErrorBadRequest)
+	}
+	return w.spendAtomicSwapContracts(outputIDs, secret)
+}
+
+// RedeemMultiSigAtomicSwapContracts creates the transaction redeeming the atomic swap contracts locked in the
+// unspent coin outputs with the given IDs, using the given secret, signed using the keys owned by this wallet.
+// The receivers of the contracts are expected to be multisig addresses this wallet is a co-signer of,
+// the transaction is returned as a PST, to be signed by the other co-signers and finalized once complete.
+func (w *Wallet) RedeemMultiSigAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) (gcmodules.PartiallySignedTransaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	defer w.tg.Done()
+
+	if secret == (types.AtomicSwapSecret{}) {
+		return gcmodules.PartiallySignedTransaction{}, types.NewClientError(errAtomicSwapInvalidSecret, types.ClientErrorBadRequest)
+	}
+	txn, _, err := w.createAtomicSwapSpendTransaction(outputIDs, secret)
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	return w.CreatePartiallySignedTransaction(txn)
+}
+
+// RefundAtomicSwapContract refunds the atomic swap contract locked in the unspent coin output with the given ID,
+// given its time lock has been reached. The sender of the contract has to be an address of this wallet.
+// The transaction refunding the contract is given to the transaction pool.
+func (w *Wallet) RefundAtomicSwapContract(outputID types.CoinOutputID) (types.Transaction, error) {
+	return w.RefundAtomicSwapContracts([]types.CoinOutputID{outputID})
+}
+
+// RefundAtomicSwapContracts refunds the atomic swap contracts locked in the unspent coin outputs with the given IDs,
+// all in the same transaction, given their time locks have been reached.
+// The senders of the contracts have to be addresses of this wallet.
+// The transaction refunding the contracts is given to the transaction pool.
+func (w *Wallet) RefundAtomicSwapContracts(outputIDs []types.CoinOutputID) (types.Transaction, error) {
+	return w.spendAtomicSwapContracts(outputIDs, types.AtomicSwapSecret{})
+}
+
+// spendAtomicSwapContracts redeems the atomic swap contracts in case a secret is given, and refunds them otherwise.
+// The transaction is given to the transaction pool, and can therefore only redeem contracts of a multisig receiver
+// in case this wallet provided all signatures they require.
+func (w *Wallet) spendAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+
+	txn, complete, err := w.createAtomicSwapSpendTransaction(outputIDs, secret)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	if !complete {
+		return types.Transaction{}, types.NewClientError(errAtomicSwapMultiSigRedeem, types.ClientErrorBadRequest)
+	}
+	err = w.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	return txn, nil
+}
+
+// atomicSwapContractInput is an atomic swap contract spent by the wallet,
+// together with the signers which can fulfill it and the keys this wallet owns of them.
+type atomicSwapContractInput struct {
+	outputID  types.CoinOutputID
+	condition types.MarshalableUnlockCondition
+	// required is the amount of signatures required to fulfill the contract
+	required uint64
+	keys     []spendableKey
+}
+
+// createAtomicSwapSpendTransaction creates the transaction redeeming the atomic swap contracts in case a secret is given,
+// and refunding them otherwise, signed using the keys of this wallet. False is returned as well in case the wallet
+// could not provide all signatures required by the contracts of a multisig receiver.
+// The spendable value of the contracts is sent to the addresses used to spend them,
+// as the signing keys never leave the wallet, the miner fee being paid using the value of the first contract.
+// A single custody fee output is created, paying the custody fee of all contracts.
+func (w *Wallet) createAtomicSwapSpendTransaction(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) (types.Transaction, bool, error) {
+	if len(outputIDs) == 0 {
+		return types.Transaction{}, false, types.NewClientError(errAtomicSwapNoContracts, types.ClientErrorBadRequest)
+	}
+	unique := make(map[types.CoinOutputID]struct{}, len(outputIDs))
+	for _, outputID := range outputIDs {
+		if _, ok := unique[outputID]; ok {
+			return types.Transaction{}, false, types.NewClientError(errAtomicSwapDuplicateContract, types.ClientErrorBadRequest)
+		}
+		unique[outputID] = struct{}{}
+	}
+
+	ctx := w.getFulfillableContextForLatestBlock()
+	var (
+		inputs       = make([]atomicSwapContractInput, 0, len(outputIDs))
+		spenders     = make([][]types.UnlockHash, 0, len(outputIDs))
+		coinOutputs  []types.CoinOutput
+		outputIndex  = make(map[types.UnlockHash]int)
+		custodyFee   types.Currency
+		minerFee     = w.chainCts.MinimumTransactionFee
+		hashedSecret = types.NewAtomicSwapHashedSecret(secret)
+	)
+	for _, outputID := range outputIDs {
+		co, err := w.cs.GetCoinOutput(outputID)
+		if err != nil {
+			return types.Transaction{}, false, types.NewClientError(errAtomicSwapContractNotFound, types.ClientErrorNotFound)
+		}
+		condition, multiSigReceiver, ok := gcmodules.AtomicSwapContractCondition(co.Condition)
+		if !ok {
+			return types.Transaction{}, false, types.NewClientError(errNotAnAtomicSwapContract, types.ClientErrorBadRequest)
+		}
+		input := atomicSwapContractInput{
+			outputID:  outputID,
+			condition: co.Condition.Condition,
+			required:  1,
+		}
+		// the coins are sent to the address used to spend the contract,
+		// the address of the multisig receiver in case the contract is redeemed by its co-signers
+		var destination types.MarshalableUnlockCondition
+		if secret != (types.AtomicSwapSecret{}) {
+			if hashedSecret != condition.HashedSecret {
+				return types.Transaction{}, false, types.NewClientError(errAtomicSwapInvalidSecret, types.ClientErrorBadRequest)
+			}
+			if multiSigReceiver != nil {
+				spenders = append(spenders, multiSigReceiver.UnlockHashes)
+				input.required = multiSigReceiver.MinimumSignatureCount
+				destination = multiSigReceiver
+			} else {
+				spenders = append(spenders, []types.UnlockHash{condition.Receiver})
+				destination = types.NewUnlockHashCondition(condition.Receiver)
+			}
+		} else {
+			if ctx.BlockTime <= condition.TimeLock {
+				return types.Transaction{}, false, types.NewClientError(errAtomicSwapNotRefundable, types.ClientErrorBadRequest)
+			}
+			spenders = append(spenders, []types.UnlockHash{condition.Sender})
+			destination = types.NewUnlockHashCondition(condition.Sender)
+		}
+		inputs = append(inputs, input)
+
+		info, err := w.cfplugin.GetCoinOutputInfo(outputID, ctx.BlockTime)
+		if err != nil {
+			return types.Transaction{}, false, fmt.Errorf("failed to get custody fee info for coin output %s: %v", outputID.String(), err)
+		}
+		custodyFee = custodyFee.Add(info.CustodyFee)
+		// the spendable value of contracts spent by the same address is sent back to it in a single output
+		uh := destination.UnlockHash()
+		idx, ok := outputIndex[uh]
+		if !ok {
+			idx = len(coinOutputs)
+			outputIndex[uh] = idx
+			coinOutputs = append(coinOutputs, types.CoinOutput{
+				Condition: types.NewCondition(destination),
+			})
+		}
+		coinOutputs[idx].Value = coinOutputs[idx].Value.Add(info.SpendableValue)
+	}
+	if coinOutputs[0].Value.Cmp(minerFee) <= 0 {
+		return types.Transaction{}, false, types.NewClientError(errAtomicSwapSpendableValueLow, types.ClientErrorBadRequest)
+	}
+	coinOutputs[0].Value = coinOutputs[0].Value.Sub(minerFee)
+
+	w.mu.RLock()
+	unlocked := w.unlocked
+	owned := true
+	for idx := range inputs {
+		for _, spender := range spenders[idx] {
+			if key, ok := w.keys[spender]; ok {
+				inputs[idx].keys = append(inputs[idx].keys, key)
+			}
+		}
+		owned = owned && len(inputs[idx].keys) > 0
+	}
+	w.mu.RUnlock()
+	if !unlocked {
+		return types.Transaction{}, false, modules.ErrLockedWallet
+	}
+	if !owned {
+		return types.Transaction{}, false, types.NewClientError(errAtomicSwapKeyNotOwned, types.ClientErrorBadRequest)
+	}
+
+	txn := types.Transaction{
+		Version: w.chainCts.DefaultTransactionVersion,
+		CoinOutputs: append(coinOutputs, types.CoinOutput{
+			Value: custodyFee,
+			Condition: types.NewCondition(&cftypes.CustodyFeeCondition{
+				ComputationTime: ctx.BlockTime,
+			}),
+		}),
+		MinerFees: []types.Currency{minerFee},
+	}
+	for _, input := range inputs {
+		var fulfillment types.MarshalableUnlockFulfillment
+		if _, ok := input.condition.(*gctypes.MultiSignatureAtomicSwapCondition); ok {
+			fulfillment = &gctypes.MultiSignatureAtomicSwapFulfillment{Secret: secret}
+		} else {
+			fulfillment = &types.AtomicSwapFulfillment{
+				PublicKey: types.Ed25519PublicKey(input.keys[0].PublicKey),
+				Secret:    secret,
+			}
+		}
+		txn.CoinInputs = append(txn.CoinInputs, types.CoinInput{
+			ParentID:    input.outputID,
+			Fulfillment: types.NewFulfillment(fulfillment),
+		})
+	}
+	complete := true
+	for idx, input := range inputs {
+		// a multisig receiver signs using all keys this wallet owns of it, up to the required amount of signatures
+		keys := input.keys
+		if uint64(len(keys)) > input.required {
+			keys = keys[:input.required]
+		}
+		for _, key := range keys {
+			err := key.sign(txn.CoinInputs[idx].Fulfillment.Fulfillment, txn, []interface{}{uint64(idx)})
+			if err != nil {
+				return types.Transaction{}, false, fmt.Errorf("failed to sign atomic swap contract input #%d: %v", idx, err)
+			}
+		}
+		complete = complete && uint64(len(keys)) >= input.required
+	}
+	return txn, complete, nil
+}
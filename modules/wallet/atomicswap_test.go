@@ -0,0 +1,317 @@
+package wallet
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+func init() {
+	gctypes.RegisterMultiSignatureAtomicSwapTypes()
+}
+
+// TestCreateAtomicSwapContractValidation checks that invalid atomic swap contracts are refused,
+// prior to funding them, and that unknown contracts cannot be audited or spent.
+func TestCreateAtomicSwapContractValidation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	receiver := types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{1})
+	notOwned := types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{2})
+	amount := wt.wallet.chainCts.MinimumTransactionFee.Mul64(10)
+	hashedSecret := types.AtomicSwapHashedSecret{1}
+
+	testCases := []struct {
+		Receiver     types.UnlockHash
+		Amount       types.Currency
+		HashedSecret types.AtomicSwapHashedSecret
+		Duration     time.Duration
+		Sender       *types.UnlockHash
+		Escrow       bool
+		ExpectedErr  error
+	}{
+		{receiver, wt.wallet.chainCts.MinimumTransactionFee, hashedSecret, time.Hour, nil, false, errAtomicSwapValueTooLow},
+		{receiver, types.ZeroCurrency, hashedSecret, time.Hour, nil, true, errAtomicSwapValueTooLow},
+		{receiver, amount, hashedSecret, 0, nil, false, errAtomicSwapNilDuration},
+		{receiver, amount, types.AtomicSwapHashedSecret{}, time.Hour, nil, false, errAtomicSwapNilHashedSecret},
+		{types.NewUnlockHash(types.UnlockTypeMultiSig, crypto.Hash{1}), amount, hashedSecret, time.Hour, nil, false, errAtomicSwapMultiSigReceiver},
+		{types.NewUnlockHash(types.UnlockTypeNil, crypto.Hash{}), amount, hashedSecret, time.Hour, nil, false, errAtomicSwapInvalidAddress},
+		{receiver, amount, hashedSecret, time.Hour, &notOwned, true, errAtomicSwapKeyNotOwned},
+	}
+	for idx, testCase := range testCases {
+		_, _, err = wt.wallet.CreateAtomicSwapContract(testCase.Receiver, testCase.Amount, testCase.HashedSecret, testCase.Duration, testCase.Sender, testCase.Escrow)
+		cErr, ok := err.(types.ClientError)
+		if !ok || cErr.Err != testCase.ExpectedErr {
+			t.Errorf("test case #%d: expected error %v, received: %v", idx, testCase.ExpectedErr, err)
+		}
+	}
+
+	multiSigReceiver := types.NewMultiSignatureCondition(types.UnlockHashSlice{receiver, notOwned}, 2)
+	for idx, receivers := range [][]gcmodules.AtomicSwapReceiver{
+		nil,
+		{{Address: receiver, Amount: amount}, {Address: receiver, Amount: amount}},
+		{{Address: receiver, Amount: amount, MultiSigCondition: types.NewMultiSignatureCondition(types.UnlockHashSlice{receiver}, 2)}},
+		{{Address: multiSigReceiver.UnlockHash(), Amount: amount}, {MultiSigCondition: multiSigReceiver, Amount: amount}},
+	} {
+		_, _, err = wt.wallet.CreateAtomicSwapContracts(receivers, hashedSecret, time.Hour, nil, false)
+		if _, ok := err.(types.ClientError); !ok {
+			t.Errorf("receivers #%d: expected multi-output contract to be refused, received: %v", idx, err)
+		}
+	}
+
+	_, _, err = wt.wallet.CreateAtomicSwapContracts([]gcmodules.AtomicSwapReceiver{
+		{Address: receiver, Amount: amount, MultiSigCondition: multiSigReceiver},
+	}, hashedSecret, time.Hour, nil, false)
+	if cErr, ok := err.(types.ClientError); !ok || cErr.Err != errAtomicSwapMultiSigReceiver {
+		t.Error("expected multisig condition not matching the receiver's address to be refused, received:", err)
+	}
+
+	var outputID types.CoinOutputID
+	outputID[0] = 1
+	_, err = wt.wallet.RefundAtomicSwapContracts(nil)
+	if cErr, ok := err.(types.ClientError); !ok || cErr.Err != errAtomicSwapNoContracts {
+		t.Error("expected no contracts to be refused, received:", err)
+	}
+	_, err = wt.wallet.RefundAtomicSwapContracts([]types.CoinOutputID{outputID, outputID})
+	if cErr, ok := err.(types.ClientError); !ok || cErr.Err != errAtomicSwapDuplicateContract {
+		t.Error("expected duplicate contracts to be refused, received:", err)
+	}
+	_, err = wt.wallet.AuditAtomicSwapContract(outputID)
+	if cErr, ok := err.(types.ClientError); !ok || cErr.Err != errAtomicSwapContractNotFound || cErr.Kind != types.ClientErrorNotFound {
+		t.Error("expected unknown contract not to be found, received:", err)
+	}
+	_, err = wt.wallet.RefundAtomicSwapContract(outputID)
+	if cErr, ok := err.(types.ClientError); !ok || cErr.Err != errAtomicSwapContractNotFound {
+		t.Error("expected unknown contract not to be refundable, received:", err)
+	}
+	_, err = wt.wallet.RedeemAtomicSwapContract(outputID, types.AtomicSwapSecret{})
+	if cErr, ok := err.(types.ClientError); !ok || cErr.Err != errAtomicSwapInvalidSecret {
+		t.Error("expected nil secret to be refused, received:", err)
+	}
+}
+
+// TestAtomicSwapContractTracking checks that the atomic swap contracts of the wallet are tracked,
+// that secrets revealed on chain are stored for all contracts they unlock,
+// and that reverted blocks are reverted in the tracked state as well.
+func TestAtomicSwapContractTracking(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cs := newConsensusSetStub()
+	wt, err := createWalletTesterWithStubCS(t.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	owned, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{1})
+	secret := types.AtomicSwapSecret{1}
+	hashedSecret := types.NewAtomicSwapHashedSecret(secret)
+	chainCts := types.TestnetChainConstants()
+
+	// the wallet refunds the first contract, and redeems the second
+	createTxn := types.Transaction{
+		Version: chainCts.DefaultTransactionVersion,
+		CoinOutputs: []types.CoinOutput{
+			{
+				Value: types.NewCurrency64(100),
+				Condition: types.NewCondition(&types.AtomicSwapCondition{
+					Sender: owned, Receiver: other, HashedSecret: hashedSecret, TimeLock: 1,
+				}),
+			},
+			{
+				Value: types.NewCurrency64(200),
+				Condition: types.NewCondition(&types.AtomicSwapCondition{
+					Sender: other, Receiver: owned, HashedSecret: hashedSecret, TimeLock: types.OffsetTimestamp(time.Hour),
+				}),
+			},
+			{
+				Value: types.NewCurrency64(300),
+				Condition: types.NewCondition(gctypes.NewMultiSignatureAtomicSwapCondition(other,
+					*types.NewMultiSignatureCondition(types.UnlockHashSlice{other, owned}, 2),
+					hashedSecret, types.OffsetTimestamp(time.Hour))),
+			},
+		},
+	}
+	refundID, redeemID, multiSigID := createTxn.CoinOutputID(0), createTxn.CoinOutputID(1), createTxn.CoinOutputID(2)
+	err = cs.AcceptBlock(types.Block{
+		ParentID:     cs.CurrentBlock().ID(),
+		Timestamp:    types.CurrentTimestamp(),
+		Transactions: []types.Transaction{createTxn},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createBlock := cs.CurrentBlock()
+
+	getContracts := func() map[types.CoinOutputID]gcmodules.TrackedAtomicSwapContract {
+		t.Helper()
+		contracts, err := wt.wallet.AtomicSwapContracts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[types.CoinOutputID]gcmodules.TrackedAtomicSwapContract, len(contracts))
+		for _, contract := range contracts {
+			m[contract.OutputID] = contract
+		}
+		return m
+	}
+	contracts := getContracts()
+	if len(contracts) != 3 {
+		t.Fatalf("expected 3 tracked contracts, found %d", len(contracts))
+	}
+	refund, redeem := contracts[refundID], contracts[redeemID]
+	if !refund.IsSender || refund.IsReceiver || !refund.Refundable || refund.State != gcmodules.AtomicSwapContractStateOpen {
+		t.Errorf("unexpected refundable contract: %+v", refund)
+	}
+	if redeem.IsSender || !redeem.IsReceiver || redeem.Refundable || redeem.Secret != nil {
+		t.Errorf("unexpected redeemable contract: %+v", redeem)
+	}
+	blockTime := cs.CurrentBlock().Timestamp
+	if action := nextAtomicSwapContractAction(refund, blockTime); action != atomicSwapContractActionRefund {
+		t.Errorf("expected expired contract to be refunded, action: %d", action)
+	}
+	if action := nextAtomicSwapContractAction(redeem, blockTime); action != atomicSwapContractActionNone {
+		t.Errorf("expected contract with unknown secret not to be spent, action: %d", action)
+	}
+
+	// the counterparty redeems the first contract, revealing the secret of the second
+	redeemTxn := types.Transaction{
+		Version: chainCts.DefaultTransactionVersion,
+		CoinInputs: []types.CoinInput{
+			{
+				ParentID: refundID,
+				Fulfillment: types.NewFulfillment(&types.AtomicSwapFulfillment{
+					PublicKey: types.Ed25519PublicKey(crypto.PublicKey{1}),
+					Signature: types.ByteSlice{1},
+					Secret:    secret,
+				}),
+			},
+		},
+	}
+	err = cs.AcceptBlock(types.Block{
+		ParentID:     cs.CurrentBlock().ID(),
+		Timestamp:    types.CurrentTimestamp(),
+		Transactions: []types.Transaction{redeemTxn},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contracts = getContracts()
+	refund, redeem = contracts[refundID], contracts[redeemID]
+	if refund.State != gcmodules.AtomicSwapContractStateRedeemed || refund.Refundable ||
+		refund.SpendTransactionID == nil || *refund.SpendTransactionID != redeemTxn.ID() {
+		t.Errorf("unexpected redeemed contract: %+v", refund)
+	}
+	if redeem.Secret == nil || *redeem.Secret != secret {
+		t.Fatalf("secret of redeemable contract is not known: %+v", redeem)
+	}
+	if action := nextAtomicSwapContractAction(redeem, blockTime); action != atomicSwapContractActionRedeem {
+		t.Errorf("expected contract with known secret to be redeemed, action: %d", action)
+	}
+	// the contract of a multisig receiver is co-signed by the wallet, but never redeemed by the watcher
+	multiSig := contracts[multiSigID]
+	if multiSig.IsSender || !multiSig.IsReceiver || multiSig.MultiSigReceiver == nil ||
+		multiSig.ContractAddress != createTxn.CoinOutputs[2].Condition.UnlockHash() || multiSig.Secret == nil {
+		t.Errorf("unexpected multisig contract: %+v", multiSig)
+	}
+	if action := nextAtomicSwapContractAction(multiSig, blockTime); action != atomicSwapContractActionNone {
+		t.Errorf("expected multisig contract not to be redeemed by the watcher, action: %d", action)
+	}
+
+	// reverting the redeem reopens the contract, while the secret remains known
+	wt.wallet.ProcessConsensusChange(modules.ConsensusChange{RevertedBlocks: []types.Block{cs.CurrentBlock()}})
+	contracts = getContracts()
+	refund = contracts[refundID]
+	if refund.State != gcmodules.AtomicSwapContractStateOpen || refund.SpendTransactionID != nil || refund.Secret == nil {
+		t.Errorf("unexpected reopened contract: %+v", refund)
+	}
+	// reverting the creation stops tracking the contracts
+	wt.wallet.ProcessConsensusChange(modules.ConsensusChange{RevertedBlocks: []types.Block{createBlock}})
+	if contracts = getContracts(); len(contracts) != 0 {
+		t.Errorf("expected reverted contracts not to be tracked, found %d", len(contracts))
+	}
+}
+
+// TestMultiSigAtomicSwapSign checks that the co-signers of a multisig receiver
+// sign the fulfillment redeeming an atomic swap contract using regular (non-HD) keys.
+func TestMultiSigAtomicSwapSign(t *testing.T) {
+	var keys []spendableKey
+	var uhs types.UnlockHashSlice
+	for i := 0; i < 3; i++ {
+		sk, pk := crypto.GenerateKeyPair()
+		key := spendableKey{PublicKey: pk, SecretKey: sk}
+		uh, err := key.UnlockHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, uhs = append(keys, key), append(uhs, uh)
+	}
+	secret := types.AtomicSwapSecret{1}
+	condition := gctypes.NewMultiSignatureAtomicSwapCondition(uhs[0],
+		*types.NewMultiSignatureCondition(uhs[1:], 2), types.NewAtomicSwapHashedSecret(secret), 1)
+	txn := types.Transaction{
+		Version:     types.TransactionVersionOne,
+		CoinOutputs: []types.CoinOutput{{Value: types.NewCurrency64(1)}},
+	}
+	ctx := types.FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockTime:    types.CurrentTimestamp(),
+		Transaction:  txn,
+	}
+
+	fulfillment := &gctypes.MultiSignatureAtomicSwapFulfillment{Secret: secret}
+	for _, key := range keys[1:] {
+		if err := key.sign(fulfillment, txn, ctx.ExtraObjects); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := condition.Fulfill(fulfillment, ctx); err != nil {
+		t.Error("invalid redeem fulfillment:", err)
+	}
+	fulfillment = &gctypes.MultiSignatureAtomicSwapFulfillment{}
+	if err := keys[0].sign(fulfillment, txn, ctx.ExtraObjects); err != nil {
+		t.Fatal(err)
+	}
+	if err := condition.Fulfill(fulfillment, ctx); err != nil {
+		t.Error("invalid refund fulfillment:", err)
+	}
+}
+
+// TestAtomicSwapWatcherNotify checks that consensus changes notify the single atomic swap watcher,
+// instead of starting a goroutine for each change, as happens while syncing.
+func TestAtomicSwapWatcherNotify(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTesterWithStubCS(t.Name(), newConsensusSetStub())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		wt.wallet.ProcessConsensusChange(modules.ConsensusChange{Synced: true})
+	}
+	if n := runtime.NumGoroutine(); n > goroutines+5 {
+		t.Errorf("expected no goroutine per consensus change, goroutines went from %d to %d", goroutines, n)
+	}
+}
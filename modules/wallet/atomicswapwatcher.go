@@ -0,0 +1,306 @@
+package wallet
+
+import (
+	"sort"
+
+	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// atomicSwapSecretGetter is implemented by the (legacy) and multisig atomic swap fulfillments,
+// and is used to extract the secret from a fulfillment which redeemed a contract.
+type atomicSwapSecretGetter interface {
+	AtomicSwapSecret() types.AtomicSwapSecret
+}
+
+// atomicSwapContractAction defines how the atomic swap watcher spends an open tracked contract.
+type atomicSwapContractAction uint8
+
+const (
+	atomicSwapContractActionNone atomicSwapContractAction = iota
+	atomicSwapContractActionRedeem
+	atomicSwapContractActionRefund
+)
+
+// AtomicSwapContracts returns all confirmed atomic swap contracts of which the wallet
+// owns the sender and/or receiver address, in the order they were confirmed.
+func (w *Wallet) AtomicSwapContracts() ([]gcmodules.TrackedAtomicSwapContract, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	var contracts []gcmodules.TrackedAtomicSwapContract
+	err := w.db.View(func(tx *bolt.Tx) (err error) {
+		contracts, err = dbAtomicSwapContracts(tx, nil)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx := w.getFulfillableContextForLatestBlock()
+	for idx := range contracts {
+		contracts[idx].Refundable = contracts[idx].State == gcmodules.AtomicSwapContractStateOpen &&
+			ctx.BlockTime > contracts[idx].Contract.TimeLock
+	}
+	sort.SliceStable(contracts, func(i, j int) bool {
+		if contracts[i].ConfirmationHeight != contracts[j].ConfirmationHeight {
+			return contracts[i].ConfirmationHeight < contracts[j].ConfirmationHeight
+		}
+		return contracts[i].OutputID.String() < contracts[j].OutputID.String()
+	})
+	return contracts, nil
+}
+
+// updateAtomicSwapContracts updates the tracked atomic swap contracts using a confirmed transaction.
+// The contracts spent by the transaction are marked as redeemed or refunded,
+// the contracts created by it are tracked if the wallet owns their sender and/or receiver address,
+// and the secrets revealed by it are stored for all tracked contracts they unlock.
+func (w *Wallet) updateAtomicSwapContracts(tx *bolt.Tx, txn types.Transaction, height types.BlockHeight) error {
+	var secrets []types.AtomicSwapSecret
+	for _, ci := range txn.CoinInputs {
+		switch ci.Fulfillment.FulfillmentType() {
+		case types.FulfillmentTypeAtomicSwap, gctypes.FulfillmentTypeMultiSignatureAtomicSwap:
+		default:
+			continue
+		}
+		getter, ok := ci.Fulfillment.Fulfillment.(atomicSwapSecretGetter)
+		if !ok {
+			continue
+		}
+		secret := getter.AtomicSwapSecret()
+		if secret != (types.AtomicSwapSecret{}) {
+			secrets = append(secrets, secret)
+		}
+		contract, exists, err := dbGetAtomicSwapContract(tx, ci.ParentID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		contract.State = gcmodules.AtomicSwapContractStateRefunded
+		if secret != (types.AtomicSwapSecret{}) {
+			contract.State = gcmodules.AtomicSwapContractStateRedeemed
+		}
+		txnID := txn.ID()
+		contract.SpendHeight = height
+		contract.SpendTransactionID = &txnID
+		err = dbPutAtomicSwapContract(tx, contract)
+		if err != nil {
+			return err
+		}
+	}
+
+	for idx, co := range txn.CoinOutputs {
+		condition, multiSigReceiver, ok := gcmodules.AtomicSwapContractCondition(co.Condition)
+		if !ok {
+			continue
+		}
+		_, isSender := w.keys[condition.Sender]
+		_, isReceiver := w.keys[condition.Receiver]
+		if multiSigReceiver != nil {
+			// the wallet can co-sign the redemption of the contract with any key of the multisig receiver
+			for _, uh := range multiSigReceiver.UnlockHashes {
+				if _, isReceiver = w.keys[uh]; isReceiver {
+					break
+				}
+			}
+		}
+		if !isSender && !isReceiver {
+			continue
+		}
+		contract := gcmodules.TrackedAtomicSwapContract{
+			OutputID:           txn.CoinOutputID(uint64(idx)),
+			ContractAddress:    co.Condition.UnlockHash(),
+			Contract:           condition,
+			MultiSigReceiver:   multiSigReceiver,
+			Value:              co.Value,
+			ConfirmationHeight: height,
+			IsSender:           isSender,
+			IsReceiver:         isReceiver,
+			State:              gcmodules.AtomicSwapContractStateOpen,
+		}
+		// the secret might already have been revealed for another tracked contract
+		known, err := dbAtomicSwapContracts(tx, func(other gcmodules.TrackedAtomicSwapContract) bool {
+			return other.Secret != nil && other.Contract.HashedSecret == condition.HashedSecret
+		})
+		if err != nil {
+			return err
+		}
+		if len(known) > 0 {
+			contract.Secret = known[0].Secret
+		}
+		err = dbPutAtomicSwapContract(tx, contract)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, secret := range secrets {
+		secret := secret
+		hashedSecret := types.NewAtomicSwapHashedSecret(secret)
+		unlocked, err := dbAtomicSwapContracts(tx, func(contract gcmodules.TrackedAtomicSwapContract) bool {
+			return contract.Secret == nil && contract.Contract.HashedSecret == hashedSecret
+		})
+		if err != nil {
+			return err
+		}
+		for _, contract := range unlocked {
+			contract.Secret = &secret
+			err = dbPutAtomicSwapContract(tx, contract)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// revertAtomicSwapContracts stops tracking the atomic swap contracts confirmed at the given height,
+// and reopens the tracked contracts spent at that height. Secrets, once revealed, remain known.
+func revertAtomicSwapContracts(tx *bolt.Tx, height types.BlockHeight) error {
+	bucket := tx.Bucket(bucketAtomicSwapContracts)
+	contracts, err := dbAtomicSwapContracts(tx, func(contract gcmodules.TrackedAtomicSwapContract) bool {
+		return contract.ConfirmationHeight == height ||
+			(contract.State != gcmodules.AtomicSwapContractStateOpen && contract.SpendHeight == height)
+	})
+	if err != nil {
+		return err
+	}
+	for _, contract := range contracts {
+		if contract.ConfirmationHeight == height {
+			err = bucket.Delete(contract.OutputID[:])
+		} else {
+			contract.State = gcmodules.AtomicSwapContractStateOpen
+			contract.SpendHeight = 0
+			contract.SpendTransactionID = nil
+			err = dbPutAtomicSwapContract(tx, contract)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextAtomicSwapContractAction returns how the atomic swap watcher spends the given tracked contract
+// at the given block time. A contract is redeemed as soon as its secret is known,
+// and refunded once its time lock has been reached, redeeming it taking precedence
+// in case the wallet owns both the sender and receiver address.
+// A contract of a multisig receiver is never redeemed by the watcher,
+// as its redemption has to be co-signed as a partially signed transaction.
+func nextAtomicSwapContractAction(contract gcmodules.TrackedAtomicSwapContract, blockTime types.Timestamp) atomicSwapContractAction {
+	if contract.State != gcmodules.AtomicSwapContractStateOpen {
+		return atomicSwapContractActionNone
+	}
+	if contract.IsReceiver && contract.Secret != nil && contract.MultiSigReceiver == nil {
+		return atomicSwapContractActionRedeem
+	}
+	if contract.IsSender && blockTime > contract.Contract.TimeLock {
+		return atomicSwapContractActionRefund
+	}
+	return atomicSwapContractActionNone
+}
+
+// notifyAtomicSwapWatcher notifies the atomic swap watcher that the tracked atomic swap contracts
+// might have become redeemable or refundable, without blocking. A notification sent while
+// another one is still pending is dropped, as the watcher loads the latest state of the contracts anyway.
+func (w *Wallet) notifyAtomicSwapWatcher() {
+	select {
+	case w.atomicSwapWatch <- struct{}{}:
+	default:
+	}
+}
+
+// threadedAtomicSwapWatcher watches the tracked atomic swap contracts each time it is notified,
+// until the wallet is closed. It is the only goroutine spending the contracts automatically,
+// such that the same contract is never spent concurrently.
+func (w *Wallet) threadedAtomicSwapWatcher() {
+	for {
+		select {
+		case <-w.tg.StopChan():
+			return
+		case <-w.atomicSwapWatch:
+			w.watchAtomicSwapContracts()
+		}
+	}
+}
+
+// watchAtomicSwapContracts redeems and refunds the open tracked atomic swap contracts
+// which can be spent by the wallet, skipping the contracts already spent by an unconfirmed transaction.
+// Nothing is done while the wallet is locked or the consensus set is not synced.
+func (w *Wallet) watchAtomicSwapContracts() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+	if !w.cs.Synced() {
+		return
+	}
+
+	w.mu.RLock()
+	if !w.unlocked {
+		w.mu.RUnlock()
+		return
+	}
+	var contracts []gcmodules.TrackedAtomicSwapContract
+	err := w.db.View(func(tx *bolt.Tx) (err error) {
+		contracts, err = dbAtomicSwapContracts(tx, func(contract gcmodules.TrackedAtomicSwapContract) bool {
+			return contract.State == gcmodules.AtomicSwapContractStateOpen
+		})
+		return
+	})
+	w.mu.RUnlock()
+	if err != nil {
+		w.log.Println("WARN: failed to load the tracked atomic swap contracts:", err)
+		return
+	}
+	if len(contracts) == 0 {
+		return
+	}
+
+	pending := make(map[types.CoinOutputID]struct{})
+	for _, txn := range w.tpool.TransactionList() {
+		for _, ci := range txn.CoinInputs {
+			pending[ci.ParentID] = struct{}{}
+		}
+	}
+	blockTime := w.getFulfillableContextForLatestBlock().BlockTime
+	for _, contract := range contracts {
+		if _, ok := pending[contract.OutputID]; ok {
+			continue
+		}
+		var (
+			txn types.Transaction
+			err error
+		)
+		switch nextAtomicSwapContractAction(contract, blockTime) {
+		case atomicSwapContractActionRedeem:
+			txn, err = w.RedeemAtomicSwapContract(contract.OutputID, *contract.Secret)
+			if err == nil {
+				w.log.Printf("INFO: redeemed atomic swap contract %s in transaction %s", contract.OutputID.String(), txn.ID().String())
+			}
+		case atomicSwapContractActionRefund:
+			txn, err = w.RefundAtomicSwapContract(contract.OutputID)
+			if err == nil {
+				w.log.Printf("INFO: refunded atomic swap contract %s in transaction %s", contract.OutputID.String(), txn.ID().String())
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			w.log.Printf("WARN: failed to spend atomic swap contract %s: %v", contract.OutputID.String(), err)
+		}
+	}
+}
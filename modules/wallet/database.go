@@ -0,0 +1,517 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+const (
+	dbFile = modules.WalletDir + ".db"
+)
+
+var (
+	dbMetadata = persist.Metadata{
+		Header:  "Wallet Database",
+		Version: "1.0.0",
+	}
+)
+
+var (
+	bucketInternal = []byte("Internal")
+	// keys for bucketInternal
+	internalBlockHeight  = []byte("BlockHeight")
+	internalRecentChange = []byte("RecentChange")
+	// internalIncompleteHistory is set when a consensus change is processed
+	// while the keys of the wallet's seeds are not loaded (yet)
+	internalIncompleteHistory = []byte("IncompleteHistory")
+
+	// bucketProcessedTransactions maps a history key (see dbHistoryKey)
+	// to a confirmed transaction relevant to the wallet.
+	bucketProcessedTransactions = []byte("ProcessedTransactions")
+	// bucketProcessedTransactionIDs maps a transaction ID to its history key.
+	bucketProcessedTransactionIDs = []byte("ProcessedTransactionIDs")
+	// bucketAddressTransactions indexes the history keys by related address,
+	// using the address followed by the history key as key, and no value.
+	bucketAddressTransactions = []byte("AddressTransactions")
+	// bucketWatchOnlyProcessedTransactions maps a history key
+	// to a confirmed transaction relevant to the watch-only addresses of the wallet.
+	bucketWatchOnlyProcessedTransactions = []byte("WatchOnlyProcessedTransactions")
+	// bucketHistoricOutputs maps an output ID to its address and value,
+	// such that the inputs of processed transactions can be resolved.
+	bucketHistoricOutputs = []byte("HistoricOutputs")
+
+	// The confirmed set of the wallet is kept in memory,
+	// and mirrored in the following buckets, such that it does not have to be rebuilt.
+	bucketCoinOutputs                = []byte("CoinOutputs")
+	bucketBlockStakeOutputs          = []byte("BlockStakeOutputs")
+	bucketUnspentBlockStakeOutputs   = []byte("UnspentBlockStakeOutputs")
+	bucketMultiSigCoinOutputs        = []byte("MultiSigCoinOutputs")
+	bucketMultiSigBlockStakeOutputs  = []byte("MultiSigBlockStakeOutputs")
+	bucketWatchOnlyCoinOutputs       = []byte("WatchOnlyCoinOutputs")
+	bucketWatchOnlyBlockStakeOutputs = []byte("WatchOnlyBlockStakeOutputs")
+	bucketUsedAddresses              = []byte("UsedAddresses")
+
+	// bucketAtomicSwapContracts maps the output ID of a confirmed atomic swap contract,
+	// of which the wallet owns the sender and/or receiver address, to its tracked state.
+	bucketAtomicSwapContracts = []byte("AtomicSwapContracts")
+
+	dbBuckets = [][]byte{
+		bucketInternal,
+		bucketProcessedTransactions,
+		bucketProcessedTransactionIDs,
+		bucketAddressTransactions,
+		bucketWatchOnlyProcessedTransactions,
+		bucketHistoricOutputs,
+		bucketCoinOutputs,
+		bucketBlockStakeOutputs,
+		bucketUnspentBlockStakeOutputs,
+		bucketMultiSigCoinOutputs,
+		bucketMultiSigBlockStakeOutputs,
+		bucketWatchOnlyCoinOutputs,
+		bucketWatchOnlyBlockStakeOutputs,
+		bucketUsedAddresses,
+		bucketAtomicSwapContracts,
+	}
+)
+
+// initDatabase opens the wallet database, creating all buckets if required,
+// and loads the confirmed set stored in it into memory.
+func (w *Wallet) initDatabase() error {
+	db, err := persist.OpenDatabase(dbMetadata, filepath.Join(w.persistDir, dbFile))
+	if err != nil {
+		return err
+	}
+	w.db = db
+	return w.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range dbBuckets {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return w.dbLoadConfirmedSet(tx)
+	})
+}
+
+// dbReset deletes the transaction history and confirmed set from the database,
+// such that the consensus set can be rescanned from the very first block.
+func dbReset(tx *bolt.Tx) error {
+	for _, bucket := range dbBuckets {
+		err := tx.DeleteBucket(bucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket(bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbSetInternal sets the specified key of bucketInternal to the encoded value.
+func dbSetInternal(tx *bolt.Tx, key []byte, val interface{}) error {
+	return dbPut(tx.Bucket(bucketInternal), key, val)
+}
+
+// dbGetInternal decodes the specified key of bucketInternal into the supplied pointer,
+// leaving it untouched if the key is not yet set.
+func dbGetInternal(tx *bolt.Tx, key []byte, val interface{}) error {
+	return dbGet(tx.Bucket(bucketInternal), key, val)
+}
+
+// dbPut encodes the given value and stores it in the bucket under the given key.
+func dbPut(bucket *bolt.Bucket, key []byte, val interface{}) error {
+	valBytes, err := rivbin.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("failed to (rivbin) marshal value: %v", err)
+	}
+	return bucket.Put(key, valBytes)
+}
+
+// dbGet decodes the value stored in the bucket under the given key into the supplied pointer,
+// leaving it untouched if the key does not exist.
+func dbGet(bucket *bolt.Bucket, key []byte, val interface{}) error {
+	valBytes := bucket.Get(key)
+	if valBytes == nil {
+		return nil
+	}
+	return rivbin.Unmarshal(valBytes, val)
+}
+
+// dbAddressKey returns the key used to index the given address.
+func dbAddressKey(uh types.UnlockHash) []byte {
+	return append([]byte{byte(uh.Type)}, uh.Hash[:]...)
+}
+
+// dbHistoryKey returns the key of a processed transaction, such that processed transactions
+// are sorted chronologically. The position is 0 for the miner payouts of a block,
+// and the transaction index + 1 for the transactions of a block.
+func dbHistoryKey(height types.BlockHeight, position uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(height))
+	binary.BigEndian.PutUint64(key[8:], position)
+	return key
+}
+
+// dbHistoryKeyHeight returns the confirmation height encoded in the given history key.
+func dbHistoryKeyHeight(key []byte) types.BlockHeight {
+	return types.BlockHeight(binary.BigEndian.Uint64(key[:8]))
+}
+
+// dbHistoryKeyPosition returns the position within its block encoded in the given history key.
+func dbHistoryKeyPosition(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[8:])
+}
+
+// dbAddProcessedTransaction stores a processed transaction relevant to the wallet,
+// indexing it by its ID and by all addresses related to it.
+func dbAddProcessedTransaction(tx *bolt.Tx, key []byte, pt gcmodules.WalletProcessedTransaction) error {
+	err := dbPut(tx.Bucket(bucketProcessedTransactions), key, pt)
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(bucketProcessedTransactionIDs).Put(pt.TransactionID[:], key)
+	if err != nil {
+		return err
+	}
+	addressBucket := tx.Bucket(bucketAddressTransactions)
+	for _, uh := range processedTransactionAddresses(pt) {
+		err = addressBucket.Put(append(dbAddressKey(uh), key...), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbRevertProcessedTransactions deletes all processed transactions
+// that were confirmed at the given height, including those of the watch-only addresses.
+func dbRevertProcessedTransactions(tx *bolt.Tx, height types.BlockHeight) error {
+	prefix := dbHistoryKey(height, 0)[:8]
+	idBucket := tx.Bucket(bucketProcessedTransactionIDs)
+	addressBucket := tx.Bucket(bucketAddressTransactions)
+	err := dbDeletePrefix(tx.Bucket(bucketProcessedTransactions), prefix, func(key, value []byte) error {
+		var pt gcmodules.WalletProcessedTransaction
+		err := rivbin.Unmarshal(value, &pt)
+		if err != nil {
+			return err
+		}
+		err = idBucket.Delete(pt.TransactionID[:])
+		if err != nil {
+			return err
+		}
+		for _, uh := range processedTransactionAddresses(pt) {
+			err = addressBucket.Delete(append(dbAddressKey(uh), key...))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return dbDeletePrefix(tx.Bucket(bucketWatchOnlyProcessedTransactions), prefix, nil)
+}
+
+// dbDeletePrefix deletes all keys of the bucket that start with the given prefix,
+// calling the optional callback for each key prior to deleting it.
+func dbDeletePrefix(bucket *bolt.Bucket, prefix []byte, f func(key, value []byte) error) error {
+	var keys [][]byte
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if f != nil {
+			err := f(k, v)
+			if err != nil {
+				return err
+			}
+		}
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		err := bucket.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processedTransactionAddresses returns the unique addresses related
+// to the inputs and outputs of the given processed transaction.
+func processedTransactionAddresses(pt gcmodules.WalletProcessedTransaction) []types.UnlockHash {
+	seen := make(map[types.UnlockHash]struct{}, len(pt.Inputs)+len(pt.Outputs))
+	var addresses []types.UnlockHash
+	for _, input := range pt.Inputs {
+		if _, ok := seen[input.RelatedAddress]; !ok {
+			seen[input.RelatedAddress] = struct{}{}
+			addresses = append(addresses, input.RelatedAddress)
+		}
+	}
+	for _, output := range pt.Outputs {
+		if _, ok := seen[output.RelatedAddress]; !ok {
+			seen[output.RelatedAddress] = struct{}{}
+			addresses = append(addresses, output.RelatedAddress)
+		}
+	}
+	return addresses
+}
+
+// dbGetProcessedTransaction returns the processed transaction with the given ID.
+// False is returned if no such transaction is relevant to the wallet.
+func dbGetProcessedTransaction(tx *bolt.Tx, txid types.TransactionID) (pt gcmodules.WalletProcessedTransaction, exists bool, err error) {
+	key := tx.Bucket(bucketProcessedTransactionIDs).Get(txid[:])
+	if key == nil {
+		return
+	}
+	err = rivbin.Unmarshal(tx.Bucket(bucketProcessedTransactions).Get(key), &pt)
+	exists = err == nil
+	return
+}
+
+// dbForEachProcessedTransaction calls the given function, in chronological order,
+// for all processed transactions stored in the given bucket, starting from the given history key
+// and confirmed at or before the given end height.
+// Iteration stops as soon as the function returns false or an error.
+func dbForEachProcessedTransaction(bucket *bolt.Bucket, startKey []byte, endHeight types.BlockHeight, f func(key []byte, pt gcmodules.WalletProcessedTransaction) (bool, error)) error {
+	c := bucket.Cursor()
+	for k, v := c.Seek(startKey); k != nil && dbHistoryKeyHeight(k) <= endHeight; k, v = c.Next() {
+		var pt gcmodules.WalletProcessedTransaction
+		err := rivbin.Unmarshal(v, &pt)
+		if err != nil {
+			return err
+		}
+		cont, err := f(k, pt)
+		if err != nil || !cont {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbForEachAddressTransaction calls the given function, in chronological order,
+// for all processed transactions related to the given address, starting from the given history key.
+// Iteration starts from the first transaction related to the address if no history key is given.
+// Iteration stops as soon as the function returns false or an error.
+func dbForEachAddressTransaction(tx *bolt.Tx, uh types.UnlockHash, startKey []byte, f func(key []byte, pt gcmodules.WalletProcessedTransaction) (bool, error)) error {
+	prefix := dbAddressKey(uh)
+	ptBucket := tx.Bucket(bucketProcessedTransactions)
+	c := tx.Bucket(bucketAddressTransactions).Cursor()
+	for k, _ := c.Seek(append(prefix, startKey...)); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		key := k[len(prefix):]
+		var pt gcmodules.WalletProcessedTransaction
+		err := rivbin.Unmarshal(ptBucket.Get(key), &pt)
+		if err != nil {
+			return err
+		}
+		cont, err := f(key, pt)
+		if err != nil || !cont {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbGetHistoricOutput returns the address and value of the output with the given ID.
+// A nil historic output is returned if the output is unknown to the wallet.
+func dbGetHistoricOutput(tx *bolt.Tx, id types.OutputID) (ho historicOutput, err error) {
+	err = dbGet(tx.Bucket(bucketHistoricOutputs), id[:], &ho)
+	return
+}
+
+// dbAddHistoricOutput stores the address and value of the output with the given ID.
+func dbAddHistoricOutput(tx *bolt.Tx, id types.OutputID, ho historicOutput) error {
+	return dbPut(tx.Bucket(bucketHistoricOutputs), id[:], ho)
+}
+
+// dbGetAtomicSwapContract returns the tracked atomic swap contract locked in the output with the given ID.
+// False is returned if the wallet does not track such a contract.
+func dbGetAtomicSwapContract(tx *bolt.Tx, id types.CoinOutputID) (contract gcmodules.TrackedAtomicSwapContract, exists bool, err error) {
+	valBytes := tx.Bucket(bucketAtomicSwapContracts).Get(id[:])
+	if valBytes == nil {
+		return
+	}
+	err = rivbin.Unmarshal(valBytes, &contract)
+	exists = err == nil
+	return
+}
+
+// dbPutAtomicSwapContract stores the given tracked atomic swap contract.
+func dbPutAtomicSwapContract(tx *bolt.Tx, contract gcmodules.TrackedAtomicSwapContract) error {
+	return dbPut(tx.Bucket(bucketAtomicSwapContracts), contract.OutputID[:], contract)
+}
+
+// dbAtomicSwapContracts returns all tracked atomic swap contracts for which the given filter returns true,
+// or all of them in case no filter is given.
+func dbAtomicSwapContracts(tx *bolt.Tx, filter func(gcmodules.TrackedAtomicSwapContract) bool) (contracts []gcmodules.TrackedAtomicSwapContract, err error) {
+	err = tx.Bucket(bucketAtomicSwapContracts).ForEach(func(_, v []byte) error {
+		var contract gcmodules.TrackedAtomicSwapContract
+		err := rivbin.Unmarshal(v, &contract)
+		if err != nil {
+			return err
+		}
+		if filter == nil || filter(contract) {
+			contracts = append(contracts, contract)
+		}
+		return nil
+	})
+	return
+}
+
+// dbUpdateConfirmedSet mirrors the changes, made by the given consensus change
+// to the confirmed set of the wallet in memory, to the database.
+func (w *Wallet) dbUpdateConfirmedSet(tx *bolt.Tx, cc modules.ConsensusChange) error {
+	usedAddresses := tx.Bucket(bucketUsedAddresses)
+	for _, diff := range cc.CoinOutputDiffs {
+		for bucket, outputs := range map[string]map[types.CoinOutputID]types.CoinOutput{
+			string(bucketCoinOutputs):          w.coinOutputs,
+			string(bucketMultiSigCoinOutputs):  w.multiSigCoinOutputs,
+			string(bucketWatchOnlyCoinOutputs): w.watchOnlyCoinOutputs,
+		} {
+			co, exists := outputs[diff.ID]
+			err := dbUpdateOutput(tx.Bucket([]byte(bucket)), diff.ID[:], co, exists)
+			if err != nil {
+				return err
+			}
+		}
+		if key, exists := w.keys[diff.CoinOutput.Condition.UnlockHash()]; exists && diff.Direction == modules.DiffApply {
+			err := usedAddresses.Put(key.PublicKey[:], nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, diff := range cc.BlockStakeOutputDiffs {
+		for bucket, outputs := range map[string]map[types.BlockStakeOutputID]types.BlockStakeOutput{
+			string(bucketBlockStakeOutputs):          w.blockstakeOutputs,
+			string(bucketMultiSigBlockStakeOutputs):  w.multiSigBlockStakeOutputs,
+			string(bucketWatchOnlyBlockStakeOutputs): w.watchOnlyBlockStakeOutputs,
+		} {
+			bso, exists := outputs[diff.ID]
+			err := dbUpdateOutput(tx.Bucket([]byte(bucket)), diff.ID[:], bso, exists)
+			if err != nil {
+				return err
+			}
+		}
+		ubso, exists := w.unspentblockstakeoutputs[diff.ID]
+		err := dbUpdateOutput(tx.Bucket(bucketUnspentBlockStakeOutputs), diff.ID[:], ubso, exists)
+		if err != nil {
+			return err
+		}
+		if key, exists := w.keys[diff.BlockStakeOutput.Condition.UnlockHash()]; exists && diff.Direction == modules.DiffApply {
+			err := usedAddresses.Put(key.PublicKey[:], nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dbUpdateOutput stores the given output if it exists, and deletes it otherwise.
+func dbUpdateOutput(bucket *bolt.Bucket, key []byte, output interface{}, exists bool) error {
+	if !exists {
+		return bucket.Delete(key)
+	}
+	return dbPut(bucket, key, output)
+}
+
+// dbLoadConfirmedSet loads the confirmed set of the wallet,
+// and the height of the last processed block, from the database into memory.
+func (w *Wallet) dbLoadConfirmedSet(tx *bolt.Tx) error {
+	err := dbGetInternal(tx, internalBlockHeight, &w.consensusSetHeight)
+	if err != nil {
+		return err
+	}
+	for bucket, outputs := range map[string]map[types.CoinOutputID]types.CoinOutput{
+		string(bucketCoinOutputs):          w.coinOutputs,
+		string(bucketMultiSigCoinOutputs):  w.multiSigCoinOutputs,
+		string(bucketWatchOnlyCoinOutputs): w.watchOnlyCoinOutputs,
+	} {
+		err = tx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+			var id types.CoinOutputID
+			copy(id[:], k)
+			var co types.CoinOutput
+			err := rivbin.Unmarshal(v, &co)
+			if err != nil {
+				return err
+			}
+			outputs[id] = co
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for bucket, outputs := range map[string]map[types.BlockStakeOutputID]types.BlockStakeOutput{
+		string(bucketBlockStakeOutputs):          w.blockstakeOutputs,
+		string(bucketMultiSigBlockStakeOutputs):  w.multiSigBlockStakeOutputs,
+		string(bucketWatchOnlyBlockStakeOutputs): w.watchOnlyBlockStakeOutputs,
+	} {
+		err = tx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+			var id types.BlockStakeOutputID
+			copy(id[:], k)
+			var bso types.BlockStakeOutput
+			err := rivbin.Unmarshal(v, &bso)
+			if err != nil {
+				return err
+			}
+			outputs[id] = bso
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Bucket(bucketUnspentBlockStakeOutputs).ForEach(func(k, v []byte) error {
+		var id types.BlockStakeOutputID
+		copy(id[:], k)
+		var ubso types.UnspentBlockStakeOutput
+		err := rivbin.Unmarshal(v, &ubso)
+		if err != nil {
+			return err
+		}
+		w.unspentblockstakeoutputs[id] = ubso
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketUsedAddresses).ForEach(func(k, _ []byte) error {
+		var pk crypto.PublicKey
+		copy(pk[:], k)
+		w.usedAddresses[pk] = struct{}{}
+		return nil
+	})
+}
+
+// resetDatabase resets the confirmed set and transaction history of the wallet,
+// both in memory and in the database, such that the consensus set can be rescanned
+// from the very first block.
+func (w *Wallet) resetDatabase() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.consensusSetHeight = 0
+	w.coinOutputs = make(map[types.CoinOutputID]types.CoinOutput)
+	w.blockstakeOutputs = make(map[types.BlockStakeOutputID]types.BlockStakeOutput)
+	w.unspentblockstakeoutputs = make(map[types.BlockStakeOutputID]types.UnspentBlockStakeOutput)
+	w.multiSigCoinOutputs = make(map[types.CoinOutputID]types.CoinOutput)
+	w.multiSigBlockStakeOutputs = make(map[types.BlockStakeOutputID]types.BlockStakeOutput)
+	w.watchOnlyCoinOutputs = make(map[types.CoinOutputID]types.CoinOutput)
+	w.watchOnlyBlockStakeOutputs = make(map[types.BlockStakeOutputID]types.BlockStakeOutput)
+	w.usedAddresses = make(map[crypto.PublicKey]struct{})
+	return w.db.Update(dbReset)
+}
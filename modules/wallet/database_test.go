@@ -0,0 +1,128 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+)
+
+// TestPersistentHistory checks that the transaction history and confirmed set
+// of the wallet are persisted, and that a reopened wallet only processes
+// the blocks it has not seen yet.
+func TestPersistentHistory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cs := newConsensusSetStub()
+	wt, err := createWalletTesterWithStubCS(t.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uh, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(1); i <= 2; i++ {
+		err = cs.addTransactionAsBlock(uh, types.NewCurrency64(i*100))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkHistory := func(expected int) {
+		t.Helper()
+		pts, err := wt.wallet.Transactions(0, cs.Height()+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pts) != expected {
+			t.Fatalf("expected %d transactions, received %d", expected, len(pts))
+		}
+		apts, err := wt.wallet.AddressTransactions(uh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(apts) != expected {
+			t.Fatalf("expected %d address transactions, received %d", expected, len(apts))
+		}
+		for idx, pt := range pts {
+			if apts[idx].TransactionID != pt.TransactionID {
+				t.Errorf("address transaction #%d does not match: %v != %v", idx, apts[idx].TransactionID, pt.TransactionID)
+			}
+			tpt, ok, err := wt.wallet.Transaction(pt.TransactionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || tpt.TransactionID != pt.TransactionID || tpt.Transaction.ID() != pt.TransactionID {
+				t.Errorf("transaction %v could not be retrieved by its ID", pt.TransactionID)
+			}
+		}
+		wt.wallet.mu.RLock()
+		coinOutputs := len(wt.wallet.coinOutputs)
+		wt.wallet.mu.RUnlock()
+		if coinOutputs != expected {
+			t.Fatalf("expected %d coin outputs, received %d", expected, coinOutputs)
+		}
+	}
+	checkHistory(2)
+
+	// reopen the wallet, the history and confirmed set are loaded from the database
+	err = wt.wallet.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcInfo := types.DefaultBlockchainInfo()
+	chainCts := types.TestnetChainConstants()
+	plugin := custodyfees.NewPlugin(types.Timestamp(chainCts.BlockFrequency*5), 5)
+	wt.wallet, err = New(cs, wt.tpool, plugin, filepath.Join(wt.persistDir, modules.WalletDir), bcInfo, chainCts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	height, coinOutputs := wt.wallet.consensusSetHeight, len(wt.wallet.coinOutputs)
+	wt.wallet.mu.RUnlock()
+	if height != cs.Height()+1 || coinOutputs != 2 {
+		t.Fatalf("confirmed set was not loaded: height %d, %d coin outputs", height, coinOutputs)
+	}
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the wallet resumed from the last block it processed, rather than rescanning all blocks
+	checkHistory(2)
+
+	err = cs.addTransactionAsBlock(uh, types.NewCurrency64(300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHistory(3)
+
+	// revert the last block
+	block := cs.CurrentBlock()
+	cc := modules.ConsensusChange{RevertedBlocks: []types.Block{block}}
+	wt.wallet.mu.RLock()
+	for id, co := range wt.wallet.coinOutputs {
+		if co.Value.Equals64(300) {
+			cc.CoinOutputDiffs = append(cc.CoinOutputDiffs, modules.CoinOutputDiff{
+				Direction:  modules.DiffRevert,
+				ID:         id,
+				CoinOutput: co,
+			})
+		}
+	}
+	wt.wallet.mu.RUnlock()
+	wt.wallet.ProcessConsensusChange(cc)
+	checkHistory(2)
+	_, ok, err := wt.wallet.Transaction(block.Transactions[0].ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("reverted transaction is still part of the wallet history")
+	}
+}
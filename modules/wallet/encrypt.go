@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"errors"
 
+	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/types"
@@ -28,27 +29,32 @@ func uidEncryptionKey(masterKey crypto.TwofishKey, uid UniqueID) (crypto.Twofish
 	return crypto.TwofishKey(h), nil
 }
 
-// checkMasterKey verifies that the master key is correct.
-func (w *Wallet) checkMasterKey(masterKey crypto.TwofishKey) error {
+// checkMasterKey verifies that the master key is correct,
+// returning the encryption key derived from it.
+func (w *Wallet) checkMasterKey(masterKey crypto.TwofishKey) (crypto.TwofishKey, error) {
 	// ensure if crypto key is given
 	if masterKey == (crypto.TwofishKey{}) {
-		return modules.ErrBadEncryptionKey
+		return crypto.TwofishKey{}, modules.ErrBadEncryptionKey
 	}
 
-	uk, err := uidEncryptionKey(masterKey, w.persist.UID)
+	key, err := w.encryptionKey(masterKey)
 	if err != nil {
-		return err
+		return crypto.TwofishKey{}, err
+	}
+	uk, err := uidEncryptionKey(key, w.persist.UID)
+	if err != nil {
+		return crypto.TwofishKey{}, err
 	}
 	verification, err := uk.DecryptBytes(w.persist.EncryptionVerification)
 	if err != nil {
 		// Most of the time, the failure is an authentication failure.
-		return modules.ErrBadEncryptionKey
+		return crypto.TwofishKey{}, modules.ErrBadEncryptionKey
 	}
 	expected := make([]byte, encryptionVerificationLen)
 	if !bytes.Equal(expected, verification) {
-		return modules.ErrBadEncryptionKey
+		return crypto.TwofishKey{}, modules.ErrBadEncryptionKey
 	}
-	return nil
+	return key, nil
 }
 
 // initEncryption checks that the provided encryption key is the valid
@@ -85,21 +91,31 @@ func (w *Wallet) initEncryption(masterKey crypto.TwofishKey, seed modules.Seed)
 		preloadDepth = modules.WalletSeedPreloadDepth
 	}
 
-	// If the input key is blank, use the seed to create the master key.
-	// Otherwise, use the input key.
-	err := w.createEncryptedSeed(masterKey, seed, preloadDepth)
+	// Derive the encryption key from the master key,
+	// using a memory-hard key derivation function.
+	kdf, err := newKDFParameters()
+	if err != nil {
+		return modules.Seed{}, err
+	}
+	key, err := kdf.deriveKey(masterKey)
+	if err != nil {
+		return modules.Seed{}, err
+	}
+
+	err = w.createEncryptedSeed(key, seed, preloadDepth)
 	if err != nil {
 		return modules.Seed{}, err
 	}
 
-	// Establish the encryption verification using the masterKey. After this
+	// Establish the encryption verification using the encryption key. After this
 	// point, the wallet is encrypted.
-	uk, err := uidEncryptionKey(masterKey, w.persist.UID)
+	uk, err := uidEncryptionKey(key, w.persist.UID)
 	if err != nil {
 		return modules.Seed{}, err
 	}
 	encryptionBase := make([]byte, encryptionVerificationLen)
 	w.persist.EncryptionVerification = uk.EncryptBytes(encryptionBase)
+	w.persist.KDF = kdf
 	err = w.saveSettings()
 	if err != nil {
 		return modules.Seed{}, err
@@ -128,26 +144,46 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 		}
 
 		// Initialize the encryption of the wallet.
-		err := w.checkMasterKey(masterKey)
+		key, err := w.checkMasterKey(masterKey)
 		if err != nil {
 			return err
 		}
 
 		// Load the wallet seed that is used to generate new addresses.
-		err = w.initEncryptedPrimarySeed(masterKey)
+		err = w.initEncryptedPrimarySeed(key)
 		if err != nil {
 			return err
 		}
 
 		// Load all wallet seeds that are not used to generate new addresses.
-		return w.initEncryptedAuxiliarySeeds(masterKey)
+		err = w.initEncryptedAuxiliarySeeds(key)
+		if err != nil {
+			return err
+		}
+
+		// Load the keys used past the pregenerated windows of the seeds.
+		w.loadUsedKeys()
+
+		// Upgrade wallets that still use the legacy encryption format.
+		// A failed upgrade does not prevent the wallet from being unlocked,
+		// as it will simply be tried again on the next unlock.
+		if w.persist.KDF == nil {
+			w.log.Println("INFO: Upgrading wallet encryption to use a memory-hard key derivation function.")
+			err = w.upgradeEncryption(masterKey)
+			if err != nil {
+				w.log.Println("WARN: failed to upgrade wallet encryption:", err)
+			}
+		}
+		return nil
 	}()
 	if err != nil {
 		return err
 	}
 
 	// Subscribe to the consensus set if this is the first unlock for the
-	// wallet object.
+	// wallet object. The wallet might already be subscribed in order to track
+	// its watch-only addresses, in which case it rescans the consensus set
+	// if it processed consensus changes without knowing its keys.
 	if !subscribed {
 		err = w.subscribeWallet()
 		if err != nil {
@@ -156,11 +192,19 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 		w.mu.Lock()
 		w.subscribed = true
 		w.mu.Unlock()
+	} else {
+		err = w.managedRescanIfIncomplete()
+		if err != nil {
+			return err
+		}
 	}
 
 	w.mu.Lock()
 	w.unlocked = true
 	w.mu.Unlock()
+
+	// spend the tracked atomic swap contracts which became redeemable or refundable while the wallet was locked
+	w.notifyAtomicSwapWatcher()
 	return nil
 }
 
@@ -176,6 +220,7 @@ func (w *Wallet) wipeSecrets() {
 	}
 	crypto.SecureWipe(w.primarySeed[:])
 	w.seeds = w.seeds[:0]
+	w.wipeHDSeeds()
 }
 
 // Encrypted returns whether or not the wallet has been encrypted.
@@ -204,7 +249,57 @@ func (w *Wallet) Encrypt(masterKey crypto.TwofishKey, primarySeed modules.Seed)
 	defer w.tg.Done()
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	return w.initEncryption(masterKey, primarySeed)
+	seed, err := w.initEncryption(masterKey, primarySeed)
+	if err != nil || !w.subscribed || primarySeed == (modules.Seed{}) {
+		return seed, err
+	}
+	// The wallet is already tracking its watch-only addresses,
+	// rescan once the keys of the recovered seed are loaded on the first unlock.
+	err = w.db.Update(func(tx *bolt.Tx) error {
+		return dbSetInternal(tx, internalIncompleteHistory, true)
+	})
+	return seed, err
+}
+
+// ChangePassword re-encrypts the wallet using the new master key,
+// given the current master key of the wallet is correct.
+// The new encryption key is derived from the new master key using
+// newly generated key derivation parameters, upgrading wallets that
+// use the legacy encryption format as well. The wallet remains locked or unlocked,
+// as it was prior to the password change.
+func (w *Wallet) ChangePassword(masterKey, newMasterKey crypto.TwofishKey) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.persist.EncryptionVerification) == 0 {
+		return errUnencryptedWallet
+	}
+	if newMasterKey == (crypto.TwofishKey{}) {
+		return modules.ErrBadEncryptionKey
+	}
+	key, err := w.checkMasterKey(masterKey)
+	if err != nil {
+		return err
+	}
+	kdf, err := newKDFParameters()
+	if err != nil {
+		return err
+	}
+	newKey, err := kdf.deriveKey(newMasterKey)
+	if err != nil {
+		return err
+	}
+	wp, err := reencryptPersist(w.persist, key, newKey)
+	if err != nil {
+		return err
+	}
+	wp.KDF = kdf
+	w.log.Println("INFO: Changing the wallet password.")
+	return w.saveReencryptedSettings(wp, key, newKey)
 }
 
 // Unlocked indicates whether the wallet is locked or unlocked.
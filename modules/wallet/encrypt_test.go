@@ -2,15 +2,24 @@ package wallet
 
 import (
 	"crypto/rand"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/nbh-digital/goldchain/extensions/custodyfees"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
 	"github.com/threefoldtech/rivine/types"
 )
 
+func init() {
+	// use the cheap testing key derivation parameters,
+	// also when the tests are not built using the testing build tag
+	defaultKDFTime, defaultKDFMemory, defaultKDFThreads = 1, 64, 1
+}
+
 // postEncryptionTesting runs a series of checks on the wallet after it has
 // been encrypted, to make sure that locking, unlocking, and spending after
 // unlocking are all happening in the correct order and returning the correct
@@ -110,6 +119,12 @@ func TestIntegrationPreEncryption(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Close the wallet, as its database can only be opened by a single wallet at once.
+	err = wt.wallet.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	plugin := custodyfees.NewPlugin(1000, 5)
 	// Create a second wallet using the same directory - make sure that if any
 	// files have been created, the wallet is still being treated as new.
@@ -119,6 +134,7 @@ func TestIntegrationPreEncryption(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	wt.wallet = w1
 	if w1.Encrypted() {
 		t.Error("wallet is reporting that it has been encrypted when no such action has occurred")
 	}
@@ -252,3 +268,385 @@ func TestLock(t *testing.T) {
 	// 	t.Error("balance should increase after a block was mined")
 	// }
 }
+
+// TestEncryptionUpgrade checks that a wallet using the legacy encryption format
+// is upgraded to the KDF-based encryption format on its first unlock.
+func TestEncryptionUpgrade(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// new wallets use the KDF-based encryption format
+	wt.wallet.mu.Lock()
+	kdf := wt.wallet.persist.KDF
+	if kdf == nil || kdf.Algorithm != kdfArgon2id || len(kdf.Salt) != kdfSaltLen {
+		wt.wallet.mu.Unlock()
+		t.Fatal("unexpected KDF parameters for a new wallet:", kdf)
+	}
+	key, err := kdf.deriveKey(wt.walletMasterKey)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	// downgrade the wallet to the legacy encryption format
+	legacy, err := reencryptPersist(wt.wallet.persist, key, wt.walletMasterKey)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	legacy.KDF = nil
+	wt.wallet.persist = legacy
+	err = wt.wallet.saveSettingsSync()
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wrongKey crypto.TwofishKey
+	_, err = rand.Read(wrongKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wt.wallet.Unlock(wrongKey); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, received:", err)
+	}
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the wallet is upgraded, and the master key can no longer be used directly
+	wt.wallet.mu.RLock()
+	kdf = wt.wallet.persist.KDF
+	primarySeedFile := wt.wallet.persist.PrimarySeedFile
+	primarySeed := wt.wallet.primarySeed
+	wt.wallet.mu.RUnlock()
+	if kdf == nil {
+		t.Fatal("wallet encryption was not upgraded on unlock")
+	}
+	if _, err = decryptSeedFile(wt.walletMasterKey, primarySeedFile); err == nil {
+		t.Error("upgraded primary seed file can still be decrypted using the master key")
+	}
+	key, err = kdf.deriveKey(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, err := decryptSeedFile(key, primarySeedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed != primarySeed {
+		t.Error("upgraded primary seed file does not contain the primary seed")
+	}
+
+	// the upgraded wallet can be unlocked again
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestChangePassword checks that the wallet can only be unlocked
+// using the new master key, after its password was changed.
+func TestChangePassword(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	var newMasterKey crypto.TwofishKey
+	_, err = rand.Read(newMasterKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wt.wallet.ChangePassword(newMasterKey, newMasterKey); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, received:", err)
+	}
+	if err = wt.wallet.ChangePassword(wt.walletMasterKey, crypto.TwofishKey{}); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, received:", err)
+	}
+	err = wt.wallet.ChangePassword(wt.walletMasterKey, newMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wt.wallet.Unlocked() {
+		t.Fatal("wallet got locked by changing its password")
+	}
+	if _, err = os.Stat(filepath.Join(wt.wallet.persistDir, settingsRollbackFile)); !os.IsNotExist(err) {
+		t.Error("rollback copy of the wallet settings was not removed:", err)
+	}
+	wt.wallet.mu.RLock()
+	newKey, err := wt.wallet.persist.KDF.deriveKey(newMasterKey)
+	wt.wallet.mu.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSeedBackups(t, "", wt.wallet, newKey)
+
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wt.wallet.Unlock(wt.walletMasterKey); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, received:", err)
+	}
+	err = wt.wallet.Unlock(newMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.walletMasterKey = newMasterKey
+}
+
+// checkSeedBackups checks that all seed backup files of the wallet can be decrypted
+// using the given encryption key, and that no re-encrypted copies are left behind.
+func checkSeedBackups(t *testing.T, name string, w *Wallet, key crypto.TwofishKey) {
+	filenames, err := w.seedBackupFiles("")
+	if err != nil {
+		t.Fatal(name, err)
+	}
+	if len(filenames) == 0 {
+		t.Error(name, "no seed backup files found")
+	}
+	for _, filename := range filenames {
+		var sf SeedFile
+		err = persist.LoadJSON(seedMetadata, &sf, filename)
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if _, err = decryptSeedFile(key, sf); err != nil {
+			t.Error(name, "failed to decrypt seed backup file", filename, ":", err)
+		}
+	}
+	for _, suffix := range []string{seedBackupReencryptedSuffix, seedBackupReencryptedSuffix + settingsTempSuffix} {
+		filenames, err = w.seedBackupFiles(suffix)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if len(filenames) != 0 {
+			t.Error(name, "re-encrypted seed backup files were left behind:", filenames)
+		}
+	}
+}
+
+// TestChangePasswordCrashSafety checks that the wallet can be unlocked using
+// either the old or the new master key, should the wallet be interrupted
+// at any step while changing its password.
+func TestChangePasswordCrashSafety(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	oldMasterKey := wt.walletMasterKey
+	var newMasterKey crypto.TwofishKey
+	_, err = rand.Read(newMasterKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// compute the re-encrypted settings, without writing them
+	wt.wallet.mu.Lock()
+	oldPersist := wt.wallet.persist
+	key, err := wt.wallet.checkMasterKey(oldMasterKey)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	kdf, err := newKDFParameters()
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	newKey, err := kdf.deriveKey(newMasterKey)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	newPersist, err := reencryptPersist(oldPersist, key, newKey)
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPersist.KDF = kdf
+
+	// compute the re-encrypted seed backup files as well
+	backupFilenames, err := wt.wallet.seedBackupFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldBackups := make([]SeedFile, len(backupFilenames))
+	newBackups := make([]SeedFile, len(backupFilenames))
+	for idx, filename := range backupFilenames {
+		err = persist.LoadJSON(seedMetadata, &oldBackups[idx], filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newBackups[idx], err = reencryptSeedFile(oldBackups[idx], key, newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveReencryptedBackups := func() error {
+		for idx, filename := range backupFilenames {
+			err := persist.SaveJSON(seedMetadata, newBackups[idx], filename+seedBackupReencryptedSuffix)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	persistDir := wt.wallet.persistDir
+	settingsFilename := filepath.Join(persistDir, settingsFile)
+	rollbackFilename := filepath.Join(persistDir, settingsRollbackFile)
+	err = wt.wallet.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each test case defines the state of the wallet directory,
+	// as left behind when interrupted at a given step of the password change
+	testCases := []struct {
+		Name        string
+		Setup       func() error
+		ExpectedKey crypto.TwofishKey
+	}{
+		{"incomplete rollback copy", func() error {
+			return ioutil.WriteFile(rollbackFilename, []byte("\"Wallet Settings\"\n"), 0600)
+		}, oldMasterKey},
+		{"rollback copy synced", func() error {
+			return persist.SaveJSON(settingsMetadata, oldPersist, rollbackFilename)
+		}, oldMasterKey},
+		{"seed backup files partially re-encrypted", func() error {
+			err := persist.SaveJSON(settingsMetadata, oldPersist, rollbackFilename)
+			if err != nil {
+				return err
+			}
+			err = saveReencryptedBackups()
+			if err != nil {
+				return err
+			}
+			return os.Truncate(backupFilenames[0]+seedBackupReencryptedSuffix, 16)
+		}, oldMasterKey},
+		{"new settings partially written", func() error {
+			err := persist.SaveJSON(settingsMetadata, oldPersist, rollbackFilename)
+			if err != nil {
+				return err
+			}
+			err = saveReencryptedBackups()
+			if err != nil {
+				return err
+			}
+			err = persist.SaveJSON(settingsMetadata, newPersist, settingsFilename)
+			if err != nil {
+				return err
+			}
+			return os.Truncate(settingsFilename, 64)
+		}, oldMasterKey},
+		{"new settings synced", func() error {
+			err := persist.SaveJSON(settingsMetadata, oldPersist, rollbackFilename)
+			if err != nil {
+				return err
+			}
+			err = saveReencryptedBackups()
+			if err != nil {
+				return err
+			}
+			return persist.SaveJSON(settingsMetadata, newPersist, settingsFilename)
+		}, oldMasterKey},
+		{"rollback copy removed", func() error {
+			err := saveReencryptedBackups()
+			if err != nil {
+				return err
+			}
+			return persist.SaveJSON(settingsMetadata, newPersist, settingsFilename)
+		}, newMasterKey},
+		{"seed backup files partially replaced", func() error {
+			err := saveReencryptedBackups()
+			if err != nil {
+				return err
+			}
+			err = os.Rename(backupFilenames[0]+seedBackupReencryptedSuffix, backupFilenames[0])
+			if err != nil {
+				return err
+			}
+			return persist.SaveJSON(settingsMetadata, newPersist, settingsFilename)
+		}, newMasterKey},
+	}
+	bcInfo := types.DefaultBlockchainInfo()
+	chainCts := types.TestnetChainConstants()
+	plugin := custodyfees.NewPlugin(types.Timestamp(chainCts.BlockFrequency*5), 5)
+	for _, testCase := range testCases {
+		// start each test case from the original settings and seed backup files
+		err = persist.SaveJSON(settingsMetadata, oldPersist, settingsFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx, filename := range backupFilenames {
+			err = persist.SaveJSON(seedMetadata, oldBackups[idx], filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = testCase.Setup()
+		if err != nil {
+			t.Fatal(testCase.Name, err)
+		}
+
+		w, err := New(wt.cs, wt.tpool, plugin, persistDir, bcInfo, chainCts, false)
+		if err != nil {
+			t.Fatal(testCase.Name, err)
+		}
+		wt.wallet = w
+		if _, err = os.Stat(rollbackFilename); !os.IsNotExist(err) {
+			t.Error(testCase.Name, "rollback copy of the wallet settings was not removed:", err)
+		}
+		otherKey := oldMasterKey
+		if testCase.ExpectedKey == oldMasterKey {
+			otherKey = newMasterKey
+		}
+		if err = w.Unlock(otherKey); err != modules.ErrBadEncryptionKey {
+			t.Error(testCase.Name, "expected ErrBadEncryptionKey, received:", err)
+		}
+		if err = w.Unlock(testCase.ExpectedKey); err != nil {
+			t.Error(testCase.Name, err)
+		}
+		expectedKey := key
+		if testCase.ExpectedKey == newMasterKey {
+			expectedKey = newKey
+		}
+		checkSeedBackups(t, testCase.Name, w, expectedKey)
+		err = w.Close()
+		if err != nil {
+			t.Fatal(testCase.Name, err)
+		}
+	}
+
+	// reopen the wallet, such that it can be closed by the wallet tester
+	wt.wallet, err = New(wt.cs, wt.tpool, plugin, persistDir, bcInfo, chainCts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
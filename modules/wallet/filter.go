@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+var (
+	errInvalidTransactionCursor    = errors.New("invalid transaction cursor")
+	errUnknownTransactionDirection = errors.New("unknown transaction direction")
+	errUnknownTransactionCategory  = errors.New("unknown transaction category")
+	errInvalidTimeRange            = errors.New("start time cannot be after the end time")
+	errInvalidAmountRange          = errors.New("minimum amount cannot be greater than the maximum amount")
+)
+
+// validateTransactionFilter ensures the given filter only defines known directions and categories,
+// and that its time and amount ranges are valid.
+func validateTransactionFilter(filter gcmodules.TransactionFilter) error {
+	switch filter.Direction {
+	case "", gcmodules.TransactionDirectionIncoming, gcmodules.TransactionDirectionOutgoing:
+	default:
+		return fmt.Errorf("%v: %q", errUnknownTransactionDirection, filter.Direction)
+	}
+	for _, category := range filter.Categories {
+		switch category {
+		case gcmodules.TransactionCategoryRegular, gcmodules.TransactionCategoryMinting, gcmodules.TransactionCategoryAuth:
+		default:
+			return fmt.Errorf("%v: %q", errUnknownTransactionCategory, category)
+		}
+	}
+	if filter.EndTime != 0 && filter.StartTime > filter.EndTime {
+		return errInvalidTimeRange
+	}
+	if !filter.MaxAmount.IsZero() && filter.MinAmount.Cmp(filter.MaxAmount) > 0 {
+		return errInvalidAmountRange
+	}
+	return nil
+}
+
+// transactionMatchesFilter returns true if the given processed transaction
+// is accepted by all properties defined by the given filter.
+// The confirmation height and pagination properties of the filter are not checked.
+func transactionMatchesFilter(filter gcmodules.TransactionFilter, pt gcmodules.WalletProcessedTransaction) bool {
+	if filter.Address != nil && !transactionRelatedToAddress(pt, *filter.Address) {
+		return false
+	}
+	if filter.Direction != "" && transactionDirection(pt) != filter.Direction {
+		return false
+	}
+	if len(filter.Categories) > 0 {
+		category := transactionCategory(pt)
+		var accepted bool
+		for _, c := range filter.Categories {
+			if c == category {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return false
+		}
+	}
+	if pt.ConfirmationTimestamp < filter.StartTime || (filter.EndTime != 0 && pt.ConfirmationTimestamp > filter.EndTime) {
+		return false
+	}
+	if !filter.MinAmount.IsZero() || !filter.MaxAmount.IsZero() {
+		amount := transactionNetCoinAmount(pt)
+		if amount.Cmp(filter.MinAmount) < 0 || (!filter.MaxAmount.IsZero() && amount.Cmp(filter.MaxAmount) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionRelatedToAddress returns true if any input or output
+// of the given processed transaction is related to the given address.
+func transactionRelatedToAddress(pt gcmodules.WalletProcessedTransaction, uh types.UnlockHash) bool {
+	for _, input := range pt.Inputs {
+		if input.RelatedAddress == uh {
+			return true
+		}
+	}
+	for _, output := range pt.Outputs {
+		if output.RelatedAddress == uh {
+			return true
+		}
+	}
+	return false
+}
+
+// transactionDirection returns the direction of the given processed transaction,
+// which is outgoing if it spends at least one input owned by the wallet, and incoming otherwise.
+func transactionDirection(pt gcmodules.WalletProcessedTransaction) gcmodules.TransactionDirection {
+	for _, input := range pt.Inputs {
+		if input.WalletAddress {
+			return gcmodules.TransactionDirectionOutgoing
+		}
+	}
+	return gcmodules.TransactionDirectionIncoming
+}
+
+// transactionCategory returns the category of the given processed transaction, based on its version.
+// Miner payouts are considered regular transactions.
+func transactionCategory(pt gcmodules.WalletProcessedTransaction) gcmodules.TransactionCategory {
+	switch pt.Transaction.Version {
+	case gctypes.TransactionVersionMinterDefinition, gctypes.TransactionVersionCoinCreation, gctypes.TransactionVersionCoinDestruction:
+		return gcmodules.TransactionCategoryMinting
+	case gctypes.TransactionVersionAuthAddressUpdate, gctypes.TransactionVersionAuthConditionUpdate:
+		return gcmodules.TransactionCategoryAuth
+	default:
+		return gcmodules.TransactionCategoryRegular
+	}
+}
+
+// transactionNetCoinAmount returns the net amount of coins received or sent by the wallet
+// in the given processed transaction, as the absolute difference between
+// the coin outputs received by the wallet and the coin inputs spent by the wallet.
+func transactionNetCoinAmount(pt gcmodules.WalletProcessedTransaction) types.Currency {
+	var received, sent types.Currency
+	for _, input := range pt.Inputs {
+		if input.WalletAddress && input.FundType == types.SpecifierCoinInput {
+			sent = sent.Add(input.Value)
+		}
+	}
+	for _, output := range pt.Outputs {
+		if output.WalletAddress && (output.FundType == types.SpecifierCoinOutput || output.FundType == types.SpecifierMinerPayout) {
+			received = received.Add(output.Value)
+		}
+	}
+	if received.Cmp(sent) >= 0 {
+		return received.Sub(sent)
+	}
+	return sent.Sub(received)
+}
+
+// encodeTransactionCursor encodes the given history key as a transaction cursor.
+func encodeTransactionCursor(key []byte) string {
+	return hex.EncodeToString(key)
+}
+
+// decodeTransactionCursor decodes the history key from the given transaction cursor.
+func decodeTransactionCursor(cursor string) ([]byte, error) {
+	key, err := hex.DecodeString(cursor)
+	if err != nil || len(key) != len(dbHistoryKey(0, 0)) {
+		return nil, errInvalidTransactionCursor
+	}
+	return key, nil
+}
@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+)
+
+// TestFilteredTransactions checks that the transaction history of the wallet
+// can be filtered and paginated.
+func TestFilteredTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cs := newConsensusSetStub()
+	wt, err := createWalletTesterWithStubCS(t.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uhs := make([]types.UnlockHash, 2)
+	for i := range uhs {
+		uhs[i], err = wt.wallet.NextAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := uint64(1); i <= 5; i++ {
+		err = cs.addTransactionAsBlock(uhs[i%2], types.NewCurrency64(i*100))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listTransactions := func(filter gcmodules.TransactionFilter) ([]gcmodules.WalletProcessedTransaction, string, error) {
+		filter.EndHeight = cs.Height() + 1
+		wt.wallet.mu.Lock()
+		defer wt.wallet.mu.Unlock()
+		return wt.wallet.processedTransactions(bucketProcessedTransactions, filter)
+	}
+	values := func(pts []gcmodules.WalletProcessedTransaction) (values []uint64) {
+		for _, pt := range pts {
+			values = append(values, transactionNetCoinAmount(pt).Big().Uint64())
+		}
+		return
+	}
+
+	testCases := []struct {
+		Name   string
+		Filter gcmodules.TransactionFilter
+		Values []uint64
+	}{
+		{"all", gcmodules.TransactionFilter{}, []uint64{100, 200, 300, 400, 500}},
+		{"address", gcmodules.TransactionFilter{Address: &uhs[0]}, []uint64{200, 400}},
+		{"incoming", gcmodules.TransactionFilter{Direction: gcmodules.TransactionDirectionIncoming}, []uint64{100, 200, 300, 400, 500}},
+		{"outgoing", gcmodules.TransactionFilter{Direction: gcmodules.TransactionDirectionOutgoing}, nil},
+		{"regular", gcmodules.TransactionFilter{Categories: []gcmodules.TransactionCategory{gcmodules.TransactionCategoryRegular}}, []uint64{100, 200, 300, 400, 500}},
+		{"minting", gcmodules.TransactionFilter{Categories: []gcmodules.TransactionCategory{gcmodules.TransactionCategoryMinting}}, nil},
+		{"future", gcmodules.TransactionFilter{StartTime: types.CurrentTimestamp() + 3600}, nil},
+		{"amount", gcmodules.TransactionFilter{MinAmount: types.NewCurrency64(200), MaxAmount: types.NewCurrency64(400)}, []uint64{200, 300, 400}},
+		{"address-amount", gcmodules.TransactionFilter{Address: &uhs[1], MinAmount: types.NewCurrency64(200)}, []uint64{300, 500}},
+	}
+	for _, testCase := range testCases {
+		pts, cursor, err := listTransactions(testCase.Filter)
+		if err != nil {
+			t.Fatalf("%s: %v", testCase.Name, err)
+		}
+		if cursor != "" {
+			t.Errorf("%s: unexpected cursor %q", testCase.Name, cursor)
+		}
+		if result := values(pts); len(result) != len(testCase.Values) {
+			t.Errorf("%s: expected %v, received %v", testCase.Name, testCase.Values, result)
+		} else {
+			for i, value := range testCase.Values {
+				if result[i] != value {
+					t.Errorf("%s: expected %v, received %v", testCase.Name, testCase.Values, result)
+					break
+				}
+			}
+		}
+	}
+
+	// paginate through all transactions related to the second address
+	var (
+		result []uint64
+		filter = gcmodules.TransactionFilter{Address: &uhs[1], Limit: 2}
+	)
+	for page := 0; ; page++ {
+		if page > 2 {
+			t.Fatal("pagination did not end")
+		}
+		pts, cursor, err := listTransactions(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint64(len(pts)) > filter.Limit {
+			t.Fatalf("page %d contains %d transactions, while the limit is %d", page, len(pts), filter.Limit)
+		}
+		result = append(result, values(pts)...)
+		if cursor == "" {
+			break
+		}
+		filter.Cursor = cursor
+	}
+	if len(result) != 3 || result[0] != 100 || result[1] != 300 || result[2] != 500 {
+		t.Errorf("unexpected paginated transactions: %v", result)
+	}
+
+	// invalid filters are reported as client errors
+	for _, filter := range []gcmodules.TransactionFilter{
+		{Direction: "sideways"},
+		{Categories: []gcmodules.TransactionCategory{"unknown"}},
+		{StartTime: 2, EndTime: 1},
+		{MinAmount: types.NewCurrency64(2), MaxAmount: types.NewCurrency64(1)},
+		{Cursor: "invalid"},
+	} {
+		_, _, err := listTransactions(filter)
+		if _, ok := err.(types.ClientError); !ok {
+			t.Errorf("expected client error for filter %+v, received: %v", filter, err)
+		}
+	}
+}
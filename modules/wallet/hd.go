@@ -0,0 +1,490 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	"github.com/nbh-digital/goldchain/pkg/hdkeys"
+)
+
+// The wallet supports hierarchical deterministic (HD) key derivation, using BIP44-style paths:
+//
+//	m / 44' / coin_type' / account' / chain / index
+//
+// where chain is 0 for the external (deposit) chain, and 1 for the internal (change) chain.
+// The hardened levels are derived as defined by SLIP-0010 for ed25519 keys,
+// the account key is then used as the root of a BIP32-Ed25519 derivation (see the hdkeys package),
+// such that the chain and index levels can be derived from the extended public key of the account.
+// This allows deposit addresses to be derived without access to the seed,
+// as an alternative to reserving them in batches using NextHDAddresses.
+//
+// Keys derived from the flat legacy derivation (see generateSpendableKey) remain tracked at all times,
+// such that existing seeds can be migrated to the HD derivation without losing access to their funds.
+const (
+	hdPurpose        uint32 = 44
+	hdHardenedOffset uint32 = 1 << 31
+
+	// hdCoinType is not registered in SLIP-0044,
+	// it is the ASCII encoding of "gold" as a big endian integer.
+	hdCoinType uint32 = 0x676f6c64
+
+	hdExternalChain uint32 = 0
+	hdInternalChain uint32 = 1
+
+	// hdMaxAddressBatch is the maximum amount of addresses that can be reserved at once.
+	hdMaxAddressBatch = 1000
+)
+
+var (
+	errHDDisabled           = errors.New("HD key derivation is not enabled for this wallet")
+	errHDAlreadyEnabled     = errors.New("HD key derivation is already enabled for this wallet")
+	errHDInvalidIndex       = errors.New("HD derivation index out of range")
+	errHDInvalidBatchSize   = fmt.Errorf("amount of addresses has to be within the range [1, %d]", hdMaxAddressBatch)
+	errHDAccountUnavailable = errors.New("HD account is not available, as the previous account has no addresses yet")
+
+	hdMasterKeyHMACKey = []byte("ed25519 seed")
+)
+
+type (
+	// hdExtendedKey is a SLIP-0010 extended ed25519 private key.
+	hdExtendedKey struct {
+		Key       [crypto.EntropySize]byte
+		ChainCode [32]byte
+	}
+
+	// hdChain identifies a single chain of keys within an HD seed.
+	hdChain struct {
+		Account uint32
+		Chain   uint32
+	}
+
+	// hdSeed tracks the HD keys that are loaded for a single seed.
+	hdSeed struct {
+		master hdExtendedKey
+		wiped  bool
+		// chainKeys caches the extended key of each loaded chain
+		chainKeys map[hdChain]*hdkeys.ExtendedPrivateKey
+		// accountKeys caches the extended public key of each loaded account,
+		// which remain available while the wallet is locked
+		accountKeys map[uint32]hdkeys.ExtendedPublicKey
+		// loaded is the amount of keys loaded for each chain
+		loaded map[hdChain]uint64
+		// used is the index of the last used key + 1, for each chain
+		used map[hdChain]uint64
+	}
+
+	// HDSeedProgress tracks the amount of addresses handed out
+	// for each account of a seed that uses HD derivation.
+	HDSeedProgress struct {
+		Accounts []HDAccountProgress
+	}
+
+	// HDAccountProgress tracks the amount of addresses handed out
+	// for the external and internal chain of an HD account.
+	HDAccountProgress struct {
+		External uint64
+		Internal uint64
+	}
+)
+
+// newHDMasterKey creates the SLIP-0010 master key for the given seed.
+func newHDMasterKey(seed []byte) hdExtendedKey {
+	mac := hmac.New(sha512.New, hdMasterKeyHMACKey)
+	mac.Write(seed)
+	return newHDExtendedKey(mac.Sum(nil))
+}
+
+func newHDExtendedKey(i []byte) (key hdExtendedKey) {
+	copy(key.Key[:], i[:32])
+	copy(key.ChainCode[:], i[32:])
+	crypto.SecureWipe(i)
+	return
+}
+
+// HardenedChild derives the hardened child key at the given index.
+func (key hdExtendedKey) HardenedChild(index uint32) (hdExtendedKey, error) {
+	if index >= hdHardenedOffset {
+		return hdExtendedKey{}, errHDInvalidIndex
+	}
+	data := make([]byte, 1+len(key.Key)+4)
+	copy(data[1:], key.Key[:])
+	binary.BigEndian.PutUint32(data[1+len(key.Key):], index+hdHardenedOffset)
+	mac := hmac.New(sha512.New, key.ChainCode[:])
+	mac.Write(data)
+	crypto.SecureWipe(data)
+	return newHDExtendedKey(mac.Sum(nil)), nil
+}
+
+// DerivePath derives the child key at the given (hardened) path.
+func (key hdExtendedKey) DerivePath(path ...uint32) (hdExtendedKey, error) {
+	var err error
+	for _, index := range path {
+		key, err = key.HardenedChild(index)
+		if err != nil {
+			return hdExtendedKey{}, err
+		}
+	}
+	return key, nil
+}
+
+// Wipe erases the secret key material of the extended key.
+func (key *hdExtendedKey) Wipe() {
+	crypto.SecureWipe(key.Key[:])
+	crypto.SecureWipe(key.ChainCode[:])
+}
+
+// String returns the BIP44-style path of the key at the given index within this chain.
+func (chain hdChain) String(index uint64) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", hdPurpose, hdCoinType, chain.Account, chain.Chain, index)
+}
+
+// hdAccountPath returns the BIP44-style path of the given account.
+func hdAccountPath(account uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'", hdPurpose, hdCoinType, account)
+}
+
+// hdAccountKey derives the extended (BIP32-Ed25519) private key of the given account.
+func (key hdExtendedKey) hdAccountKey(account uint32) (hdkeys.ExtendedPrivateKey, error) {
+	node, err := key.DerivePath(hdPurpose, hdCoinType, account)
+	if err != nil {
+		return hdkeys.ExtendedPrivateKey{}, err
+	}
+	defer node.Wipe()
+	return hdkeys.NewExtendedPrivateKey(node.Key, node.ChainCode), nil
+}
+
+// generateHDSpendableKey creates the keys and unlock conditions
+// for the given chain key at a given index.
+func generateHDSpendableKey(chainKey *hdkeys.ExtendedPrivateKey, seedIndex int, chain hdChain, index uint64) (spendableKey, error) {
+	if index >= uint64(hdHardenedOffset) {
+		return spendableKey{}, errHDInvalidIndex
+	}
+	key, err := chainKey.Child(uint32(index))
+	if err != nil {
+		return spendableKey{}, err
+	}
+	return spendableKey{
+		PublicKey: crypto.PublicKey(key.PublicKey()),
+		Index:     index,
+		SeedIndex: seedIndex,
+		HDChain:   &chain,
+		HDKey:     &key,
+	}, nil
+}
+
+// loadHDSeed loads the HD keys of the given seed into the wallet,
+// the seed index is the index the seed has (or will have) in the wallet's seed slice.
+// For the first account, as well as for all accounts in the given progress,
+// keys are loaded up to the address gap limit past the handed out and used keys.
+func (w *Wallet) loadHDSeed(seedIndex int, seed modules.Seed, progress *HDSeedProgress) error {
+	for len(w.hdSeeds) <= seedIndex {
+		w.hdSeeds = append(w.hdSeeds, &hdSeed{
+			chainKeys:   make(map[hdChain]*hdkeys.ExtendedPrivateKey),
+			accountKeys: make(map[uint32]hdkeys.ExtendedPublicKey),
+			loaded:      make(map[hdChain]uint64),
+			used:        make(map[hdChain]uint64),
+		})
+	}
+	hds := w.hdSeeds[seedIndex]
+	// keys might still be loaded from an earlier unlock,
+	// the chain keys will have to be rederived however
+	hds.master = newHDMasterKey(seed[:])
+	hds.wiped = false
+	hds.chainKeys = make(map[hdChain]*hdkeys.ExtendedPrivateKey)
+
+	accounts := uint32(1)
+	if progress != nil && uint32(len(progress.Accounts)) > accounts {
+		accounts = uint32(len(progress.Accounts))
+	}
+	for chain := range hds.loaded {
+		if chain.Account >= accounts {
+			accounts = chain.Account + 1
+		}
+	}
+	for account := uint32(0); account < accounts; account++ {
+		var accountProgress HDAccountProgress
+		if progress != nil && account < uint32(len(progress.Accounts)) {
+			accountProgress = progress.Accounts[account]
+		}
+		err := w.loadHDChain(seedIndex, hdChain{Account: account, Chain: hdExternalChain}, accountProgress.External)
+		if err != nil {
+			return err
+		}
+		err = w.loadHDChain(seedIndex, hdChain{Account: account, Chain: hdInternalChain}, accountProgress.Internal)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadHDChain ensures that the keys of the given chain are loaded
+// up to the address gap limit past the given index, as well as past the last used key of that chain.
+func (w *Wallet) loadHDChain(seedIndex int, chain hdChain, index uint64) error {
+	hds := w.hdSeeds[seedIndex]
+	if hds.wiped {
+		// keys cannot be derived while the wallet is locked,
+		// they'll be loaded once the wallet gets unlocked again
+		return nil
+	}
+	if used := hds.used[chain]; used > index {
+		index = used
+	}
+	target := index + w.addressGapLimit()
+	if loaded := hds.loaded[chain]; loaded > target {
+		target = loaded
+	}
+	start := hds.loaded[chain]
+	chainKey, ok := hds.chainKeys[chain]
+	if !ok {
+		accountKey, err := hds.master.hdAccountKey(chain.Account)
+		if err != nil {
+			return err
+		}
+		hds.accountKeys[chain.Account] = accountKey.Public()
+		key, err := accountKey.Child(chain.Chain)
+		accountKey.Wipe()
+		if err != nil {
+			return err
+		}
+		chainKey = &key
+		hds.chainKeys[chain] = chainKey
+		// (re)load all keys of this chain, as the secret keys might have been wiped
+		start = 0
+	}
+	for i := start; i < target; i++ {
+		spendableKey, err := generateHDSpendableKey(chainKey, seedIndex, chain, i)
+		if err != nil {
+			return err
+		}
+		uh, err := spendableKey.UnlockHash()
+		if err != nil {
+			return err
+		}
+		w.keys[uh] = spendableKey
+	}
+	hds.loaded[chain] = target
+	return nil
+}
+
+// markHDKeyUsed extends the chain of the given HD key,
+// such that the address gap limit is respected past this key. If this is the first used
+// key of an account, the keys of the next account are loaded as well.
+func (w *Wallet) markHDKeyUsed(key spendableKey) {
+	if key.SeedIndex >= len(w.hdSeeds) {
+		return
+	}
+	hds := w.hdSeeds[key.SeedIndex]
+	chain := *key.HDChain
+	if hds.used[chain] > key.Index {
+		return // chain is already extended past this key
+	}
+	hds.used[chain] = key.Index + 1
+	if hds.wiped {
+		// the wallet is locked, the keys past this key are loaded on the next unlock
+		_, nextAccountLoaded := hds.loaded[hdChain{Account: chain.Account + 1, Chain: hdExternalChain}]
+		if hds.loaded[chain] < hds.used[chain]+w.addressGapLimit() || !nextAccountLoaded {
+			w.keysIncomplete = true
+		}
+		return
+	}
+	err := w.loadHDChain(key.SeedIndex, chain, key.Index+1)
+	if err != nil {
+		w.log.Println("WARN: failed to extend HD chain", chain.String(key.Index), ":", err)
+		return
+	}
+	next := chain.Account + 1
+	if _, ok := hds.loaded[hdChain{Account: next, Chain: hdExternalChain}]; ok {
+		return
+	}
+	for _, c := range []uint32{hdExternalChain, hdInternalChain} {
+		err = w.loadHDChain(key.SeedIndex, hdChain{Account: next, Chain: c}, 0)
+		if err != nil {
+			w.log.Println("WARN: failed to load HD account", next, ":", err)
+			return
+		}
+	}
+}
+
+// wipeHDSeeds erases the secret keys of all HD seeds,
+// while keeping track of the keys that were loaded and used.
+func (w *Wallet) wipeHDSeeds() {
+	for _, hds := range w.hdSeeds {
+		hds.master.Wipe()
+		for chain, key := range hds.chainKeys {
+			key.Wipe()
+			delete(hds.chainKeys, chain)
+		}
+		hds.wiped = true
+	}
+}
+
+// nextHDKey fetches the next unused key from the given chain of the primary seed,
+// the caller is responsible for saving the updated progress.
+// The primary seed is always the first seed of the wallet.
+func (w *Wallet) nextHDKey(account, chain uint32) (spendableKey, error) {
+	if !w.unlocked {
+		return spendableKey{}, modules.ErrLockedWallet
+	}
+	progress := w.persist.PrimarySeedHDProgress
+	if progress == nil {
+		return spendableKey{}, errHDDisabled
+	}
+	if account > uint32(len(progress.Accounts)) {
+		return spendableKey{}, errHDAccountUnavailable
+	}
+	if account == uint32(len(progress.Accounts)) {
+		if account > 0 && progress.Accounts[account-1].External == 0 {
+			return spendableKey{}, errHDAccountUnavailable
+		}
+		progress.Accounts = append(progress.Accounts, HDAccountProgress{})
+	}
+	hdc := hdChain{Account: account, Chain: chain}
+	index := &progress.Accounts[account].External
+	if chain == hdInternalChain {
+		index = &progress.Accounts[account].Internal
+	}
+	// never hand out a key that was already used on chain,
+	// which can be the case for recovered seeds
+	if used := w.hdSeeds[0].used[hdc]; used > *index {
+		*index = used
+	}
+	err := w.loadHDChain(0, hdc, *index+1)
+	if err != nil {
+		return spendableKey{}, err
+	}
+	key, err := generateHDSpendableKey(w.hdSeeds[0].chainKeys[hdc], 0, hdc, *index)
+	if err != nil {
+		return spendableKey{}, err
+	}
+	*index++
+	return key, nil
+}
+
+// nextChangeAddress fetches the next address to be used for refunds.
+// If HD derivation is enabled, it is taken from the internal chain of the first account,
+// otherwise it is the next address of the primary seed.
+func (w *Wallet) nextChangeAddress() (types.UnlockHash, error) {
+	if w.persist.PrimarySeedHDProgress == nil {
+		return w.nextPrimarySeedAddress()
+	}
+	key, err := w.nextHDKey(0, hdInternalChain)
+	if err != nil {
+		return types.UnlockHash{}, err
+	}
+	err = w.saveSettingsSync()
+	if err != nil {
+		return types.UnlockHash{}, err
+	}
+	return key.UnlockHash()
+}
+
+// EnableHDDerivation migrates the primary seed of the wallet to HD key derivation,
+// such that all future addresses are derived using HD derivation.
+// The addresses previously generated by the primary seed remain tracked and spendable.
+// The HD keys of the primary seed are only tracked from now on, which is why the
+// consensus set is rescanned, as these keys might already have been used (e.g. for a recovered seed).
+func (w *Wallet) EnableHDDerivation() error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if !w.unlocked {
+			return modules.ErrLockedWallet
+		}
+		if w.persist.PrimarySeedHDProgress != nil {
+			return errHDAlreadyEnabled
+		}
+		progress := &HDSeedProgress{
+			Accounts: []HDAccountProgress{{}},
+		}
+		err := w.loadHDSeed(0, w.primarySeed, progress)
+		if err != nil {
+			return err
+		}
+		w.persist.PrimarySeedHDProgress = progress
+		return w.saveSettingsSync()
+	}()
+	if err != nil {
+		return err
+	}
+	return w.managedRescanIfSubscribed()
+}
+
+// HDAccounts returns the derivation progress of all HD accounts of the primary seed.
+func (w *Wallet) HDAccounts() ([]gcmodules.HDAccount, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+	progress := w.persist.PrimarySeedHDProgress
+	if progress == nil {
+		return nil, errHDDisabled
+	}
+	accounts := make([]gcmodules.HDAccount, 0, len(progress.Accounts))
+	for idx, ap := range progress.Accounts {
+		external := hdChain{Account: uint32(idx), Chain: hdExternalChain}
+		internal := hdChain{Account: uint32(idx), Chain: hdInternalChain}
+		accounts = append(accounts, gcmodules.HDAccount{
+			Index:             uint32(idx),
+			Path:              hdAccountPath(uint32(idx)),
+			ExtendedPublicKey: w.hdSeeds[0].accountKeys[uint32(idx)],
+			ExternalAddresses: ap.External,
+			InternalAddresses: ap.Internal,
+			ExternalUsed:      w.hdSeeds[0].used[external],
+			InternalUsed:      w.hdSeeds[0].used[internal],
+		})
+	}
+	return accounts, nil
+}
+
+// NextHDAddresses reserves the next deposit addresses of the given HD account of the primary seed.
+// All returned addresses are tracked by the wallet.
+// A new account can only be used if the previous account has handed out at least one address.
+func (w *Wallet) NextHDAddresses(account uint32, count uint64) ([]gcmodules.HDAddress, error) {
+	if count == 0 || count > hdMaxAddressBatch {
+		return nil, errHDInvalidBatchSize
+	}
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	addresses := make([]gcmodules.HDAddress, 0, count)
+	for i := uint64(0); i < count; i++ {
+		key, err := w.nextHDKey(account, hdExternalChain)
+		if err != nil {
+			return nil, err
+		}
+		uh, err := key.UnlockHash()
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, gcmodules.HDAddress{
+			Address:   uh,
+			PublicKey: types.Ed25519PublicKey(key.PublicKey),
+			Path:      key.HDChain.String(key.Index),
+		})
+	}
+	err := w.saveSettingsSync()
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
@@ -0,0 +1,304 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// TestHDDerivationVectors checks the HD derivation against
+// the ed25519 test vectors defined in SLIP-0010.
+func TestHDDerivationVectors(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		Path      []uint32
+		ChainCode string
+		Key       string
+		PublicKey string
+	}{
+		{
+			nil,
+			"90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb",
+			"2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			"a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed",
+		},
+		{
+			[]uint32{0},
+			"8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69",
+			"68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			"8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c",
+		},
+		{
+			[]uint32{0, 1},
+			"a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14",
+			"b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			"1932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187",
+		},
+	}
+	master := newHDMasterKey(seed)
+	for idx, testCase := range testCases {
+		key, err := master.DerivePath(testCase.Path...)
+		if err != nil {
+			t.Errorf("test case #%d: %v", idx, err)
+			continue
+		}
+		if chainCode := hex.EncodeToString(key.ChainCode[:]); chainCode != testCase.ChainCode {
+			t.Errorf("test case #%d: unexpected chain code: %s", idx, chainCode)
+		}
+		if sk := hex.EncodeToString(key.Key[:]); sk != testCase.Key {
+			t.Errorf("test case #%d: unexpected private key: %s", idx, sk)
+		}
+		_, pk := crypto.GenerateKeyPairDeterministic(key.Key)
+		if pks := hex.EncodeToString(pk[:]); pks != testCase.PublicKey {
+			t.Errorf("test case #%d: unexpected public key: %s", idx, pks)
+		}
+	}
+
+	if _, err = master.HardenedChild(hdHardenedOffset); err != errHDInvalidIndex {
+		t.Error("expected errHDInvalidIndex, received:", err)
+	}
+}
+
+// TestHDAddresses checks that addresses are derived from the HD accounts
+// once HD derivation is enabled, and that chains get extended when keys are used.
+func TestHDAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// HD keys are only tracked once HD derivation is enabled
+	wt.wallet.mu.RLock()
+	hdSeeds := len(wt.wallet.hdSeeds)
+	wt.wallet.mu.RUnlock()
+	if hdSeeds != 0 {
+		t.Fatal("HD keys are loaded while HD derivation is not enabled")
+	}
+	_, err = wt.wallet.NextHDAddresses(0, 1)
+	if err != errHDDisabled {
+		t.Fatal("expected errHDDisabled, received:", err)
+	}
+	legacyAddress, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wt.wallet.EnableHDDerivation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wt.wallet.EnableHDDerivation(); err != errHDAlreadyEnabled {
+		t.Fatal("expected errHDAlreadyEnabled, received:", err)
+	}
+	wt.wallet.mu.RLock()
+	loaded := wt.wallet.hdSeeds[0].loaded[hdChain{Account: 0, Chain: hdExternalChain}]
+	wt.wallet.mu.RUnlock()
+	if loaded != defaultAddressGapLimit {
+		t.Fatal("unexpected amount of HD keys loaded:", loaded)
+	}
+
+	// the next address is the first key of the external chain of the first account
+	uh, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	key, ok := wt.wallet.keys[uh]
+	_, legacyOk := wt.wallet.keys[legacyAddress]
+	wt.wallet.mu.RUnlock()
+	if !ok || key.HDChain == nil || key.Index != 0 || *key.HDChain != (hdChain{Account: 0, Chain: hdExternalChain}) {
+		t.Fatal("unexpected key for first HD address:", key)
+	}
+	if !legacyOk {
+		t.Fatal("legacy address is no longer tracked after migration")
+	}
+
+	// a new account can only be used once the previous one is used
+	_, err = wt.wallet.NextHDAddresses(2, 1)
+	if err != errHDAccountUnavailable {
+		t.Fatal("expected errHDAccountUnavailable, received:", err)
+	}
+	addresses, err := wt.wallet.NextHDAddresses(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 3 {
+		t.Fatal("unexpected amount of addresses:", len(addresses))
+	}
+	for idx, addr := range addresses {
+		expectedPath := hdChain{Account: 1, Chain: hdExternalChain}.String(uint64(idx))
+		if addr.Path != expectedPath {
+			t.Errorf("unexpected path for address #%d: %s != %s", idx, addr.Path, expectedPath)
+		}
+		expectedAddress, err := types.NewPubKeyUnlockHash(addr.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.Address.Cmp(expectedAddress) != 0 {
+			t.Errorf("unexpected address #%d: %s != %s", idx, addr.Address, expectedAddress)
+		}
+	}
+	accounts, err := wt.wallet.HDAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].ExternalAddresses != 1 || accounts[1].ExternalAddresses != 3 {
+		t.Fatal("unexpected HD accounts:", accounts)
+	}
+	// the addresses can be derived from the extended public key of the account
+	chainKey, err := accounts[1].ExtendedPublicKey.Child(hdExternalChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, addr := range addresses {
+		child, err := chainKey.Child(uint32(idx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(child.PublicKey[:], addr.PublicKey.Key) {
+			t.Errorf("address #%d cannot be derived from the extended public key of its account", idx)
+		}
+	}
+
+	// receiving an output on the last loaded key of a chain extends that chain,
+	// and loads the next account
+	external := hdChain{Account: 1, Chain: hdExternalChain}
+	wt.wallet.mu.Lock()
+	lastKey, err := generateHDSpendableKey(wt.wallet.hdSeeds[0].chainKeys[external], 0, external, wt.wallet.hdSeeds[0].loaded[external]-1)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	lastUH, err := lastKey.UnlockHash()
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	wt.wallet.updateConfirmedSet(modules.ConsensusChange{
+		CoinOutputDiffs: []modules.CoinOutputDiff{{
+			Direction: modules.DiffApply,
+			ID:        types.CoinOutputID{1},
+			CoinOutput: types.CoinOutput{
+				Value:     types.NewCurrency64(1),
+				Condition: types.NewCondition(types.NewUnlockHashCondition(lastUH)),
+			},
+		}},
+	})
+	loaded = wt.wallet.hdSeeds[0].loaded[external]
+	_, nextAccountLoaded := wt.wallet.hdSeeds[0].loaded[hdChain{Account: 2, Chain: hdExternalChain}]
+	wt.wallet.mu.Unlock()
+	if loaded != lastKey.Index+1+defaultAddressGapLimit {
+		t.Error("chain was not extended past the used key:", loaded)
+	}
+	if !nextAccountLoaded {
+		t.Error("next account was not loaded after the account got used")
+	}
+
+	// all HD keys are reloaded after unlocking the wallet again
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	key = wt.wallet.keys[lastUH]
+	wt.wallet.mu.RUnlock()
+	if key.HDKey == nil || *key.HDKey != *lastKey.HDKey {
+		t.Error("secret key of used HD key was not reloaded after unlocking the wallet")
+	}
+}
+
+// TestHDKeySign checks that HD keys produce valid signatures for all fulfillment types the wallet signs.
+func TestHDKeySign(t *testing.T) {
+	var seed modules.Seed
+	seed[0] = 1
+	chain := hdChain{Account: 0, Chain: hdExternalChain}
+	accountKey, err := newHDMasterKey(seed[:]).hdAccountKey(chain.Account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainKey, err := accountKey.Child(chain.Chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := generateHDSpendableKey(&chainKey, 0, chain, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uh, err := key.UnlockHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := types.Ed25519PublicKey(key.PublicKey)
+	txn := types.Transaction{
+		Version:     types.TransactionVersionOne,
+		CoinOutputs: []types.CoinOutput{{Value: types.NewCurrency64(1)}},
+	}
+	extraObjects := []interface{}{uint64(0)}
+
+	testCases := []struct {
+		Condition   types.MarshalableUnlockCondition
+		Fulfillment types.MarshalableUnlockFulfillment
+	}{
+		{types.NewUnlockHashCondition(uh), types.NewSingleSignatureFulfillment(pk)},
+		{types.NewMultiSignatureCondition(types.UnlockHashSlice{uh}, 1), &types.MultiSignatureFulfillment{}},
+		{
+			&types.AtomicSwapCondition{Sender: uh, Receiver: uh, TimeLock: 1},
+			&types.AtomicSwapFulfillment{PublicKey: pk, Secret: types.AtomicSwapSecret{1}},
+		},
+		{
+			&types.AtomicSwapCondition{Sender: uh, Receiver: uh, TimeLock: 1},
+			&types.AtomicSwapFulfillment{PublicKey: pk},
+		},
+		{
+			&gctypes.MultiSignatureAtomicSwapCondition{Sender: uh, Receivers: []types.UnlockHash{uh}, MinimumSignatureCount: 1, TimeLock: 1},
+			&gctypes.MultiSignatureAtomicSwapFulfillment{Secret: types.AtomicSwapSecret{1}},
+		},
+		{
+			&gctypes.MultiSignatureAtomicSwapCondition{Sender: uh, Receivers: []types.UnlockHash{uh}, MinimumSignatureCount: 1, TimeLock: 1},
+			&gctypes.MultiSignatureAtomicSwapFulfillment{},
+		},
+	}
+	for idx, testCase := range testCases {
+		switch asc := testCase.Condition.(type) {
+		case *types.AtomicSwapCondition:
+			asc.HashedSecret = types.NewAtomicSwapHashedSecret(types.AtomicSwapSecret{1})
+		case *gctypes.MultiSignatureAtomicSwapCondition:
+			asc.HashedSecret = types.NewAtomicSwapHashedSecret(types.AtomicSwapSecret{1})
+		}
+		err = key.sign(testCase.Fulfillment, txn, extraObjects)
+		if err != nil {
+			t.Errorf("#%d: failed to sign: %v", idx, err)
+			continue
+		}
+		err = testCase.Condition.Fulfill(testCase.Fulfillment, types.FulfillContext{
+			ExtraObjects: extraObjects,
+			BlockHeight:  2,
+			BlockTime:    types.CurrentTimestamp(),
+			Transaction:  txn,
+		})
+		if err != nil {
+			t.Errorf("#%d: invalid fulfillment: %v", idx, err)
+		}
+	}
+
+	if _, _, err = (&Wallet{unlocked: true, keys: map[types.UnlockHash]spendableKey{uh: key}}).getKey(uh); err != errHDKeyExport {
+		t.Error("expected errHDKeyExport, received:", err)
+	}
+}
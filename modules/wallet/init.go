@@ -33,7 +33,12 @@ func (w *Wallet) Init(primarySeed modules.Seed) (modules.Seed, error) {
 		return modules.Seed{}, err
 	}
 	if subscribed {
-		return seed, nil
+		// The wallet is already tracking its watch-only addresses,
+		// rescan such that the history of the recovered seed is known as well.
+		if primarySeed == (modules.Seed{}) {
+			return seed, nil
+		}
+		return seed, w.managedRescan()
 	}
 	// subscribe wallet immediate if not yet subscribed
 	err = w.subscribeWallet()
@@ -0,0 +1,211 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// kdfArgon2id identifies the Argon2id key derivation function.
+	kdfArgon2id = "argon2id"
+
+	// kdfSaltLen is the length of the random salt used for the key derivation function.
+	kdfSaltLen = 32
+)
+
+var (
+	// the default Argon2id parameters, as recommended by the argon2 package,
+	// using less memory for dev and testing builds, such that unlocking remains fast,
+	// the package tests use the testing parameters regardless of the build tags
+	defaultKDFTime = build.Select(build.Var{
+		Standard: uint32(1),
+		Dev:      uint32(1),
+		Testing:  uint32(1),
+	}).(uint32)
+	defaultKDFMemory = build.Select(build.Var{
+		Standard: uint32(64 * 1024), // 64 MiB
+		Dev:      uint32(8 * 1024),  // 8 MiB
+		Testing:  uint32(64),        // 64 KiB
+	}).(uint32)
+	defaultKDFThreads = build.Select(build.Var{
+		Standard: uint8(4),
+		Dev:      uint8(2),
+		Testing:  uint8(1),
+	}).(uint8)
+)
+
+var (
+	errUnknownKDF = errors.New("unknown wallet key derivation function")
+	errInvalidKDF = errors.New("invalid wallet key derivation parameters")
+)
+
+// KDFParameters defines the key derivation function, and its parameters,
+// used to derive the wallet encryption key from the master key.
+//
+// Wallets without KDF parameters use the legacy encryption format,
+// where the master key is used directly as the wallet encryption key.
+// Such wallets are upgraded to the KDF-based encryption format on their first unlock.
+type KDFParameters struct {
+	Algorithm string
+	Salt      []byte
+	// Time is the number of passes over the memory
+	Time uint32
+	// Memory is the size of the memory in KiB
+	Memory uint32
+	// Threads is the number of threads used to derive the key
+	Threads uint8
+}
+
+// newKDFParameters creates the parameters for a new wallet encryption key,
+// using a random salt and the default Argon2id parameters.
+func newKDFParameters() (*KDFParameters, error) {
+	params := &KDFParameters{
+		Algorithm: kdfArgon2id,
+		Salt:      make([]byte, kdfSaltLen),
+		Time:      defaultKDFTime,
+		Memory:    defaultKDFMemory,
+		Threads:   defaultKDFThreads,
+	}
+	_, err := rand.Read(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// validate ensures the parameters define a supported and sane key derivation function.
+func (params *KDFParameters) validate() error {
+	if params.Algorithm != kdfArgon2id {
+		return fmt.Errorf("%v: %q", errUnknownKDF, params.Algorithm)
+	}
+	if len(params.Salt) < kdfSaltLen/2 || params.Time == 0 || params.Threads == 0 || params.Memory < 8*uint32(params.Threads) {
+		return errInvalidKDF
+	}
+	return nil
+}
+
+// deriveKey derives the wallet encryption key from the given master key.
+func (params *KDFParameters) deriveKey(masterKey crypto.TwofishKey) (crypto.TwofishKey, error) {
+	err := params.validate()
+	if err != nil {
+		return crypto.TwofishKey{}, err
+	}
+	var key crypto.TwofishKey
+	derived := argon2.IDKey(masterKey[:], params.Salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	copy(key[:], derived)
+	crypto.SecureWipe(derived)
+	return key, nil
+}
+
+// encryptionKey returns the key used to encrypt and decrypt the wallet files,
+// derived from the given master key using the key derivation function of the wallet.
+// The master key is returned as is for wallets that use the legacy encryption format.
+func (w *Wallet) encryptionKey(masterKey crypto.TwofishKey) (crypto.TwofishKey, error) {
+	if masterKey == (crypto.TwofishKey{}) {
+		return crypto.TwofishKey{}, modules.ErrBadEncryptionKey
+	}
+	if w.persist.KDF == nil {
+		return masterKey, nil
+	}
+	return w.persist.KDF.deriveKey(masterKey)
+}
+
+// reencryptCiphertext decrypts the given ciphertext using the old encryption key,
+// and encrypts it again using the new encryption key, both specific to the given UID.
+func reencryptCiphertext(oldKey, newKey crypto.TwofishKey, uid UniqueID, ct crypto.Ciphertext) (crypto.Ciphertext, error) {
+	oldUIDKey, err := uidEncryptionKey(oldKey, uid)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := oldUIDKey.DecryptBytes(ct)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.SecureWipe(plaintext)
+	newUIDKey, err := uidEncryptionKey(newKey, uid)
+	if err != nil {
+		return nil, err
+	}
+	return newUIDKey.EncryptBytes(plaintext), nil
+}
+
+// reencryptPersist re-encrypts the encryption verification, seed files and unseeded keys
+// of the given wallet persist object, from the old to the new encryption key.
+// The given persist object is not modified, a re-encrypted copy is returned instead.
+func reencryptPersist(wp WalletPersist, oldKey, newKey crypto.TwofishKey) (WalletPersist, error) {
+	var err error
+	wp.EncryptionVerification, err = reencryptCiphertext(oldKey, newKey, wp.UID, wp.EncryptionVerification)
+	if err != nil {
+		return WalletPersist{}, err
+	}
+	wp.PrimarySeedFile, err = reencryptSeedFile(wp.PrimarySeedFile, oldKey, newKey)
+	if err != nil {
+		return WalletPersist{}, err
+	}
+	auxiliarySeedFiles := make([]SeedFile, 0, len(wp.AuxiliarySeedFiles))
+	for _, sf := range wp.AuxiliarySeedFiles {
+		sf, err = reencryptSeedFile(sf, oldKey, newKey)
+		if err != nil {
+			return WalletPersist{}, err
+		}
+		auxiliarySeedFiles = append(auxiliarySeedFiles, sf)
+	}
+	wp.AuxiliarySeedFiles = auxiliarySeedFiles
+	unseededKeys := make([]SpendableKeyFile, 0, len(wp.UnseededKeys))
+	for _, skf := range wp.UnseededKeys {
+		skf.EncryptionVerification, err = reencryptCiphertext(oldKey, newKey, skf.UID, skf.EncryptionVerification)
+		if err != nil {
+			return WalletPersist{}, err
+		}
+		skf.SpendableKey, err = reencryptCiphertext(oldKey, newKey, skf.UID, skf.SpendableKey)
+		if err != nil {
+			return WalletPersist{}, err
+		}
+		unseededKeys = append(unseededKeys, skf)
+	}
+	wp.UnseededKeys = unseededKeys
+	return wp, nil
+}
+
+// reencryptSeedFile re-encrypts a seed file from the old to the new encryption key.
+func reencryptSeedFile(sf SeedFile, oldKey, newKey crypto.TwofishKey) (SeedFile, error) {
+	var err error
+	sf.EncryptionVerification, err = reencryptCiphertext(oldKey, newKey, sf.UID, sf.EncryptionVerification)
+	if err != nil {
+		return SeedFile{}, err
+	}
+	sf.Seed, err = reencryptCiphertext(oldKey, newKey, sf.UID, sf.Seed)
+	if err != nil {
+		return SeedFile{}, err
+	}
+	return sf, nil
+}
+
+// upgradeEncryption upgrades a wallet that uses the legacy encryption format,
+// to the KDF-based encryption format, re-encrypting all wallet secrets,
+// including the seed backup files, using an encryption key derived from the master key.
+func (w *Wallet) upgradeEncryption(masterKey crypto.TwofishKey) error {
+	if w.persist.KDF != nil {
+		return nil // already upgraded
+	}
+	params, err := newKDFParameters()
+	if err != nil {
+		return err
+	}
+	newKey, err := params.deriveKey(masterKey)
+	if err != nil {
+		return err
+	}
+	upgraded, err := reencryptPersist(w.persist, masterKey, newKey)
+	if err != nil {
+		return err
+	}
+	upgraded.KDF = params
+	return w.saveReencryptedSettings(upgraded, masterKey, newKey)
+}
@@ -0,0 +1,158 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+)
+
+// the size limits of the metadata of a labeled address
+const (
+	maxAddressLabelLength = 64
+	maxAddressNotesLength = 1024
+	maxAddressTagLength   = 32
+	maxAddressTags        = 16
+)
+
+var (
+	errNilLabelAddress      = errors.New("nil address cannot be labeled")
+	errEmptyAddressLabel    = errors.New("address label, notes and tags cannot all be empty")
+	errAddressLabelTooLarge = fmt.Errorf("address label cannot be longer than %d characters", maxAddressLabelLength)
+	errAddressNotesTooLarge = fmt.Errorf("address notes cannot be longer than %d characters", maxAddressNotesLength)
+	errTooManyAddressTags   = fmt.Errorf("an address cannot have more than %d tags", maxAddressTags)
+	errInvalidAddressTag    = fmt.Errorf("address tags have to be non-empty, without commas and no longer than %d characters", maxAddressTagLength)
+	errUnknownLabelAddress  = errors.New("given address is not labeled")
+)
+
+// AddressLabels returns the labels, notes and tags of all addresses labeled by this wallet,
+// sorted in byte-order of the addresses.
+func (w *Wallet) AddressLabels() ([]gcmodules.AddressLabel, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	labels := make([]gcmodules.AddressLabel, 0, len(w.addressLabels))
+	for _, label := range w.addressLabels {
+		_, label.Owned = w.keys[label.Address]
+		label.Tags = append([]string(nil), label.Tags...)
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Address.Cmp(labels[j].Address) < 0
+	})
+	return labels, nil
+}
+
+// SetAddressLabels labels the given addresses, which can be owned by the wallet or not,
+// replacing the label, notes and tags previously defined for those addresses.
+func (w *Wallet) SetAddressLabels(labels []gcmodules.AddressLabel) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+
+	// validate all labels prior to storing any of them
+	for idx := range labels {
+		err := validateAddressLabel(&labels[idx])
+		if err != nil {
+			return types.NewClientError(fmt.Errorf("invalid label #%d: %v", idx+1, err), types.ClientErrorBadRequest)
+		}
+	}
+	for _, label := range labels {
+		w.addressLabels[label.Address] = label
+	}
+	return w.saveAddressLabels()
+}
+
+// validateAddressLabel validates the given address label,
+// normalizing its label, notes and tags.
+func validateAddressLabel(label *gcmodules.AddressLabel) error {
+	if label.Address.Type == types.UnlockTypeNil {
+		return errNilLabelAddress
+	}
+	label.Owned = false
+	label.Label = strings.TrimSpace(label.Label)
+	if len(label.Label) > maxAddressLabelLength {
+		return errAddressLabelTooLarge
+	}
+	label.Notes = strings.TrimSpace(label.Notes)
+	if len(label.Notes) > maxAddressNotesLength {
+		return errAddressNotesTooLarge
+	}
+	if len(label.Tags) > maxAddressTags {
+		return errTooManyAddressTags
+	}
+	tags := make([]string, 0, len(label.Tags))
+	known := make(map[string]struct{}, len(label.Tags))
+	for _, tag := range label.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || len(tag) > maxAddressTagLength || strings.Contains(tag, ",") {
+			return errInvalidAddressTag
+		}
+		if _, exists := known[tag]; exists {
+			continue
+		}
+		known[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	label.Tags = tags
+	if len(label.Tags) == 0 {
+		label.Tags = nil
+		if label.Label == "" && label.Notes == "" {
+			return errEmptyAddressLabel
+		}
+	}
+	return nil
+}
+
+// RemoveAddressLabels removes the label, notes and tags of the given addresses.
+func (w *Wallet) RemoveAddressLabels(addresses []types.UnlockHash) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+
+	for _, uh := range addresses {
+		if _, exists := w.addressLabels[uh]; !exists {
+			return types.NewClientError(fmt.Errorf("%v: %s", errUnknownLabelAddress, uh), types.ClientErrorNotFound)
+		}
+	}
+	for _, uh := range addresses {
+		delete(w.addressLabels, uh)
+	}
+	return w.saveAddressLabels()
+}
+
+// saveAddressLabels stores the in-memory address labels
+// as part of the wallet's settings file, such that they are included in its backups as well.
+func (w *Wallet) saveAddressLabels() error {
+	w.persist.AddressLabels = make([]gcmodules.AddressLabel, 0, len(w.addressLabels))
+	for _, label := range w.addressLabels {
+		w.persist.AddressLabels = append(w.persist.AddressLabels, label)
+	}
+	sort.Slice(w.persist.AddressLabels, func(i, j int) bool {
+		return w.persist.AddressLabels[i].Address.Cmp(w.persist.AddressLabels[j].Address) < 0
+	})
+	return w.saveSettingsSync()
+}
@@ -0,0 +1,140 @@
+package wallet
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+)
+
+// TestAddressLabels checks that owned and counterparty addresses can be labeled,
+// and that those labels are persisted and included in the wallet backup.
+func TestAddressLabels(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	ownedUH, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counterpartyUH := types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{1})
+
+	// invalid labels are refused as a whole
+	for idx, label := range []gcmodules.AddressLabel{
+		{Label: "nil"},
+		{Address: counterpartyUH},
+		{Address: counterpartyUH, Label: strings.Repeat("a", maxAddressLabelLength+1)},
+		{Address: counterpartyUH, Tags: []string{"a,b"}},
+		{Address: counterpartyUH, Tags: []string{" "}},
+	} {
+		err = wt.wallet.SetAddressLabels([]gcmodules.AddressLabel{{Address: ownedUH, Label: "savings"}, label})
+		if _, ok := err.(types.ClientError); !ok {
+			t.Errorf("label #%d: expected client error, received: %v", idx, err)
+		}
+	}
+	if len(wt.wallet.addressLabels) != 0 {
+		t.Fatal("expected no labels to be stored, received:", wt.wallet.addressLabels)
+	}
+
+	err = wt.wallet.SetAddressLabels([]gcmodules.AddressLabel{
+		{Address: ownedUH, Label: " savings "},
+		{Address: counterpartyUH, Label: "alice", Notes: "supplier", Tags: []string{"business", "business", "eu"}, Owned: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLabels := func(labels []gcmodules.AddressLabel) {
+		if len(labels) != 2 {
+			t.Fatal("expected 2 labels, received:", labels)
+		}
+		for _, label := range labels {
+			switch label.Address {
+			case ownedUH:
+				if label.Label != "savings" || label.Notes != "" || len(label.Tags) != 0 {
+					t.Error("unexpected label for owned address:", label)
+				}
+			case counterpartyUH:
+				if label.Label != "alice" || label.Notes != "supplier" ||
+					len(label.Tags) != 2 || label.Tags[0] != "business" || label.Tags[1] != "eu" {
+					t.Error("unexpected label for counterparty address:", label)
+				}
+			default:
+				t.Error("unexpected labeled address:", label.Address)
+			}
+		}
+	}
+	labels, err := wt.wallet.AddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLabels(labels)
+	for _, label := range labels {
+		if label.Owned != (label.Address == ownedUH) {
+			t.Errorf("unexpected ownership of labeled address %v: %t", label.Address, label.Owned)
+		}
+	}
+
+	// labels are included in the wallet backup
+	backupPath := filepath.Join(wt.persistDir, "backup.json")
+	err = wt.wallet.CreateBackup(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backup WalletPersist
+	err = persist.LoadJSON(settingsMetadata, &backup, backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLabels(backup.AddressLabels)
+
+	// labels are persisted, such that they are loaded when reopening the wallet
+	persistDir := wt.wallet.persistDir
+	err = wt.wallet.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainCts := types.TestnetChainConstants()
+	plugin := custodyfees.NewPlugin(types.Timestamp(chainCts.BlockFrequency*5), 5)
+	wt.wallet, err = New(wt.cs, wt.tpool, plugin, persistDir, types.DefaultBlockchainInfo(), chainCts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels, err = wt.wallet.AddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLabels(labels)
+
+	// unknown addresses cannot be removed
+	err = wt.wallet.RemoveAddressLabels([]types.UnlockHash{ownedUH, types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{2})})
+	if _, ok := err.(types.ClientError); !ok {
+		t.Error("expected client error, received:", err)
+	}
+	err = wt.wallet.RemoveAddressLabels([]types.UnlockHash{ownedUH})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels, err = wt.wallet.AddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0].Address != counterpartyUH {
+		t.Error("unexpected labels after removal:", labels)
+	}
+}
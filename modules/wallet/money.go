@@ -3,6 +3,7 @@ package wallet
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 
 	"github.com/threefoldtech/rivine/build"
@@ -10,14 +11,59 @@ import (
 	"github.com/threefoldtech/rivine/types"
 
 	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
 	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
 // various errors returned by the wallet
 var (
 	ErrNilOutputs = errors.New("nil outputs cannot be send")
+
+	errUnknownMultiSigWallet = errors.New("no unspent coin outputs known for the given multisig wallet")
 )
 
+// errInsufficientCoins returns the coded error for a wallet unable to fund the required amount of coins,
+// given the value it can spend (after custody fees), the value used by its unconfirmed transactions,
+// and the custody fee that would be paid when spending all available coin outputs.
+// The returned error wraps modules.ErrIncompleteTransactions or modules.ErrLowBalance.
+func errInsufficientCoins(required, spendable, pending, custodyFee types.Currency) error {
+	details := map[string]string{
+		"required":  required.String(),
+		"spendable": spendable.String(),
+	}
+	if !pending.IsZero() {
+		details["pending"] = pending.String()
+	}
+	if spendable.Add(pending).Cmp(required) >= 0 {
+		return gctypes.NewCodedError(gctypes.ErrorCodeIncompleteTransactions, modules.ErrIncompleteTransactions, details)
+	}
+	if !custodyFee.IsZero() {
+		details["custodyfee"] = custodyFee.String()
+		if spendable.Add(pending).Add(custodyFee).Cmp(required) >= 0 {
+			return gctypes.NewCodedError(gctypes.ErrorCodeInsufficientSpendableAfterFees, modules.ErrLowBalance, details)
+		}
+	}
+	return gctypes.NewCodedError(gctypes.ErrorCodeInsufficientBalance, modules.ErrLowBalance, details)
+}
+
+// errInsufficientBlockStakes returns the coded error for a wallet unable to fund the required amount of block stakes,
+// given the value it can spend and the value used by its unconfirmed transactions.
+// The returned error wraps modules.ErrIncompleteTransactions or modules.ErrLowBalance.
+func errInsufficientBlockStakes(required, spendable, pending types.Currency) error {
+	details := map[string]string{
+		"required":  required.String(),
+		"spendable": spendable.String(),
+	}
+	if !pending.IsZero() {
+		details["pending"] = pending.String()
+	}
+	if spendable.Add(pending).Cmp(required) >= 0 {
+		return gctypes.NewCodedError(gctypes.ErrorCodeIncompleteTransactions, modules.ErrIncompleteTransactions, details)
+	}
+	return gctypes.NewCodedError(gctypes.ErrorCodeInsufficientBlockStakes, modules.ErrLowBalance, details)
+}
+
 // sortedOutputs is a struct containing a slice of siacoin outputs and their
 // corresponding ids. sortedOutputs can be sorted using the sort package.
 type sortedOutputs struct {
@@ -36,32 +82,8 @@ func (w *Wallet) ConfirmedBalance() (coinBalance types.Currency, blockstakeBalan
 		return
 	}
 
-	// prepare fulfillable context
-	ctx := w.getFulfillableContextForLatestBlock()
-
-	// get all coin and block stake stum
-	err = w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
-		var info custodyfees.CoinOutputInfo
-		for scoid, sco := range w.coinOutputs {
-			if sco.Condition.Fulfillable(ctx) {
-				info, err = view.GetCoinOutputInfo(scoid, ctx.BlockTime)
-				if err != nil {
-					return err
-				}
-				coinBalance = coinBalance.Add(info.SpendableValue)
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return
-	}
-	for _, sfo := range w.blockstakeOutputs {
-		if sfo.Condition.Fulfillable(ctx) {
-			blockstakeBalance = blockstakeBalance.Add(sfo.Value)
-		}
-	}
-	return
+	balance, _, err := w.confirmedBalance(w.coinOutputs, w.blockstakeOutputs)
+	return balance.ConfirmedCoinBalance, balance.ConfirmedBlockStakeBalance, err
 }
 
 // ConfirmedLockedBalance returns the locked balance of the wallet according to all of the
@@ -75,32 +97,8 @@ func (w *Wallet) ConfirmedLockedBalance() (coinBalance types.Currency, blockstak
 		return
 	}
 
-	// prepare fulfillable context
-	ctx := w.getFulfillableContextForLatestBlock()
-
-	// get all coin and block stake stum
-	err = w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
-		var info custodyfees.CoinOutputInfo
-		for scoid, sco := range w.coinOutputs {
-			if !sco.Condition.Fulfillable(ctx) {
-				info, err = view.GetCoinOutputInfo(scoid, ctx.BlockTime)
-				if err != nil {
-					return err
-				}
-				coinBalance = coinBalance.Add(info.SpendableValue)
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return
-	}
-	for _, sfo := range w.blockstakeOutputs {
-		if !sfo.Condition.Fulfillable(ctx) {
-			blockstakeBalance = blockstakeBalance.Add(sfo.Value)
-		}
-	}
-	return
+	balance, _, err := w.confirmedBalance(w.coinOutputs, w.blockstakeOutputs)
+	return balance.ConfirmedLockedCoinBalance, balance.ConfirmedLockedBlockStakeBalance, err
 }
 
 // ConfirmedCustodyFeesToBePaid returns the total amount of custody fees to be paid
@@ -114,21 +112,88 @@ func (w *Wallet) ConfirmedCustodyFeesToBePaid() (fees types.Currency, err error)
 		return
 	}
 
+	balance, _, err := w.confirmedBalance(w.coinOutputs, nil)
+	return balance.ConfirmedCustodyFeeDebt, err
+}
+
+// ConfirmedBalances returns the confirmed balances and custody fee debt of the wallet,
+// with those of its watch-only addresses in a separate section, in total as well as per address.
+// The watch-only addresses are tracked while the wallet is locked as well,
+// such that only the balances of the addresses owned by the wallet require it to be unlocked.
+func (w *Wallet) ConfirmedBalances() (balances gcmodules.WalletBalances, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.unlocked {
+		var owned gcmodules.Balance
+		owned, _, err = w.confirmedBalance(w.coinOutputs, w.blockstakeOutputs)
+		if err != nil {
+			return
+		}
+		balances.Owned = &owned
+	}
+	balances.WatchOnly, balances.WatchOnlyAddresses, err = w.confirmedBalance(w.watchOnlyCoinOutputs, w.watchOnlyBlockStakeOutputs)
+	if err != nil {
+		return
+	}
+	// list the watch-only addresses without any unspent output as well
+	for uh := range w.watchOnlyAddresses {
+		if _, ok := balances.WatchOnlyAddresses[uh]; !ok {
+			balances.WatchOnlyAddresses[uh] = gcmodules.Balance{}
+		}
+	}
+	return
+}
+
+// confirmedBalance computes the confirmed balances and custody fee debt of the given outputs,
+// in total as well as per address.
+func (w *Wallet) confirmedBalance(coinOutputs map[types.CoinOutputID]types.CoinOutput, blockStakeOutputs map[types.BlockStakeOutputID]types.BlockStakeOutput) (total gcmodules.Balance, addresses map[types.UnlockHash]gcmodules.Balance, err error) {
+	addresses = make(map[types.UnlockHash]gcmodules.Balance)
+
 	// prepare fulfillable context
 	ctx := w.getFulfillableContextForLatestBlock()
 
-	// get all coin and block stake stum
-	err = w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
-		var info custodyfees.CoinOutputInfo
-		for scoid := range w.coinOutputs {
-			info, err = view.GetCoinOutputInfo(scoid, ctx.BlockTime)
-			if err != nil {
-				return err
+	if len(coinOutputs) != 0 {
+		err = w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+			for id, co := range coinOutputs {
+				info, err := view.GetCoinOutputInfo(id, ctx.BlockTime)
+				if err != nil {
+					return fmt.Errorf("failed to get custodyfee info for coin output %s: %v", id.String(), err)
+				}
+				uh := co.Condition.UnlockHash()
+				balance := addresses[uh]
+				if co.Condition.Fulfillable(ctx) {
+					balance.ConfirmedCoinBalance = balance.ConfirmedCoinBalance.Add(info.SpendableValue)
+				} else {
+					balance.ConfirmedLockedCoinBalance = balance.ConfirmedLockedCoinBalance.Add(info.SpendableValue)
+				}
+				balance.ConfirmedCustodyFeeDebt = balance.ConfirmedCustodyFeeDebt.Add(info.CustodyFee)
+				addresses[uh] = balance
 			}
-			fees = fees.Add(info.CustodyFee)
+			return nil
+		})
+		if err != nil {
+			return
 		}
-		return nil
-	})
+	}
+	for _, bso := range blockStakeOutputs {
+		uh := bso.Condition.UnlockHash()
+		balance := addresses[uh]
+		if bso.Condition.Fulfillable(ctx) {
+			balance.ConfirmedBlockStakeBalance = balance.ConfirmedBlockStakeBalance.Add(bso.Value)
+		} else {
+			balance.ConfirmedLockedBlockStakeBalance = balance.ConfirmedLockedBlockStakeBalance.Add(bso.Value)
+		}
+		addresses[uh] = balance
+	}
+
+	for _, balance := range addresses {
+		total.ConfirmedCoinBalance = total.ConfirmedCoinBalance.Add(balance.ConfirmedCoinBalance)
+		total.ConfirmedLockedCoinBalance = total.ConfirmedLockedCoinBalance.Add(balance.ConfirmedLockedCoinBalance)
+		total.ConfirmedCustodyFeeDebt = total.ConfirmedCustodyFeeDebt.Add(balance.ConfirmedCustodyFeeDebt)
+		total.ConfirmedBlockStakeBalance = total.ConfirmedBlockStakeBalance.Add(balance.ConfirmedBlockStakeBalance)
+		total.ConfirmedLockedBlockStakeBalance = total.ConfirmedLockedBlockStakeBalance.Add(balance.ConfirmedLockedBlockStakeBalance)
+	}
 	return
 }
 
@@ -338,6 +403,190 @@ func (w *Wallet) MultiSigWalletsWithCustodyFeeDebt() ([]gcmodules.MultiSigWallet
 	return msws, nil
 }
 
+// FundMultiSigCoinTransaction creates a transaction sending the given coin outputs, funded by the unspent coin outputs
+// of the given multisig wallet, which has to contain at least one address owned by this wallet.
+// The minimum transaction fee is added as miner fee, as well as the required custody fee,
+// and any refund is sent back to the multisig wallet.
+//
+// The transaction is signed using the key(s) of this wallet, and returned as a partially signed transaction,
+// to be signed by the other co-signers of the multisig wallet, prior to being finalized.
+func (w *Wallet) FundMultiSigCoinTransaction(address types.UnlockHash, coinOutputs []types.CoinOutput, data []byte) (gcmodules.PartiallySignedTransaction, error) {
+	if len(coinOutputs) == 0 {
+		return gcmodules.PartiallySignedTransaction{}, ErrNilOutputs
+	}
+	if uint64(len(data)) > w.chainCts.ArbitraryDataSizeLimit {
+		return gcmodules.PartiallySignedTransaction{}, errArbitraryDataTooLarge
+	}
+
+	if err := w.tg.Add(); err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	defer w.tg.Done()
+
+	txn, spentScoids, err := func() (types.Transaction, []types.CoinOutputID, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if !w.unlocked {
+			return types.Transaction{}, nil, modules.ErrLockedWallet
+		}
+
+		outputs := make(map[types.CoinOutputID]types.CoinOutput)
+		var refundCondition types.UnlockConditionProxy
+		for id, co := range w.multiSigCoinOutputs {
+			if co.Condition.UnlockHash().Cmp(address) != 0 {
+				continue
+			}
+			if len(outputs) == 0 {
+				// refund to the multisig wallet itself, without any time lock
+				unlockhashes, minSignatureCount := getMultisigConditionProperties(co.Condition.Condition)
+				refundCondition = types.NewCondition(types.NewMultiSignatureCondition(unlockhashes, minSignatureCount))
+			}
+			outputs[id] = co
+		}
+		if len(outputs) == 0 {
+			return types.Transaction{}, nil, types.NewClientError(errUnknownMultiSigWallet, types.ClientErrorBadRequest)
+		}
+
+		minerFee := w.chainCts.MinimumTransactionFee
+		amount := minerFee
+		txn := types.Transaction{
+			Version:       w.chainCts.DefaultTransactionVersion,
+			MinerFees:     []types.Currency{minerFee},
+			ArbitraryData: data,
+		}
+		for _, co := range coinOutputs {
+			txn.CoinOutputs = append(txn.CoinOutputs, co)
+			amount = amount.Add(co.Value)
+		}
+		spentScoids, err := w.fundCoinTransactionFrom(&txn, amount, outputs, &refundCondition, func(types.CoinOutput) types.UnlockFulfillmentProxy {
+			return types.NewFulfillment(&types.MultiSignatureFulfillment{})
+		})
+		return txn, spentScoids, err
+	}()
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+
+	pst, err := w.CreatePartiallySignedTransaction(txn)
+	if err == nil {
+		pst, err = w.SignPartiallySignedTransaction(pst)
+	}
+	if err != nil {
+		// release the used coin outputs, as the transaction is not returned
+		w.mu.Lock()
+		for _, scoid := range spentScoids {
+			delete(w.spentOutputs, types.OutputID(scoid))
+		}
+		w.mu.Unlock()
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	return pst, nil
+}
+
+// fundCoinTransactionFrom funds the given transaction with the given amount (miner fees included),
+// using the given unspent coin outputs, which are to be signed by other parties than (or next to) this wallet,
+// such as the owner(s) of watch-only addresses or the co-signers of a multisig wallet.
+// The fulfillment of each coin input is created using the given callback.
+//
+// The required custody fee output is added, as well as a refund output if needed, using the given refund condition,
+// or the condition of the largest used coin output if no refund condition is given.
+// The used coin outputs are marked as spent, and their IDs are returned.
+// The caller is expected to hold the lock of the wallet.
+func (w *Wallet) fundCoinTransactionFrom(txn *types.Transaction, amount types.Currency, outputs map[types.CoinOutputID]types.CoinOutput, refundCondition *types.UnlockConditionProxy, newFulfillment func(types.CoinOutput) types.UnlockFulfillmentProxy) ([]types.CoinOutputID, error) {
+	// prepare fulfillable context
+	ctx := w.getFulfillableContextForLatestBlock()
+
+	// Collect a value-sorted set of fulfillable coin outputs.
+	var so sortedOutputs
+	for scoid, sco := range outputs {
+		if !sco.Condition.Fulfillable(ctx) {
+			continue
+		}
+		so.ids = append(so.ids, scoid)
+		so.outputs = append(so.outputs, sco)
+	}
+	sort.Sort(sort.Reverse(so))
+
+	// Prevent an underflow error.
+	allowedHeight := w.consensusSetHeight - RespendTimeout
+	if w.consensusSetHeight < RespendTimeout {
+		allowedHeight = 0
+	}
+
+	var (
+		fund, potentialFund    types.Currency
+		custodyFeeTotal        types.Currency
+		maxSpendableValue      types.Currency
+		largestOutputCondition types.UnlockConditionProxy
+		spentScoids            []types.CoinOutputID
+	)
+	err := w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+		for i := range so.ids {
+			scoid := so.ids[i]
+			sco := so.outputs[i]
+			info, err := view.GetCoinOutputInfo(scoid, ctx.BlockTime)
+			if err != nil {
+				return err
+			}
+			// Check that this output has not recently been used by the wallet.
+			if spendHeight := w.spentOutputs[types.OutputID(scoid)]; spendHeight > allowedHeight {
+				potentialFund = potentialFund.Add(info.SpendableValue)
+				continue
+			}
+
+			txn.CoinInputs = append(txn.CoinInputs, types.CoinInput{
+				ParentID:    scoid,
+				Fulfillment: newFulfillment(sco),
+			})
+			spentScoids = append(spentScoids, scoid)
+			custodyFeeTotal = custodyFeeTotal.Add(info.CustodyFee)
+			if maxSpendableValue.Cmp(info.SpendableValue) < 0 {
+				maxSpendableValue = info.SpendableValue
+				largestOutputCondition = sco.Condition
+			}
+
+			fund = fund.Add(info.SpendableValue)
+			potentialFund = potentialFund.Add(info.SpendableValue)
+			if fund.Cmp(amount) >= 0 {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fund.Cmp(amount) < 0 {
+		return nil, errInsufficientCoins(amount, fund, potentialFund.Sub(fund), custodyFeeTotal)
+	}
+
+	// Create and add the Custody Fee Coin Output
+	txn.CoinOutputs = append(txn.CoinOutputs, types.CoinOutput{
+		Value: custodyFeeTotal,
+		Condition: types.NewCondition(&cftypes.CustodyFeeCondition{
+			ComputationTime: ctx.BlockTime,
+		}),
+	})
+
+	// Create a refund output if needed.
+	if !amount.Equals(fund) {
+		if refundCondition == nil {
+			refundCondition = &largestOutputCondition
+		}
+		txn.CoinOutputs = append(txn.CoinOutputs, types.CoinOutput{
+			Value:     fund.Sub(amount),
+			Condition: *refundCondition,
+		})
+	}
+
+	// Mark all outputs that were used as spent.
+	for _, scoid := range spentScoids {
+		w.spentOutputs[types.OutputID(scoid)] = w.consensusSetHeight
+	}
+	return spentScoids, nil
+}
+
 // SendCoins creates a transaction sending 'amount' to whoever can fulfill the condition. If data is provided,
 // it is added as arbitrary data to the transaction. The transaction
 // is submitted to the transaction pool and is also returned.
@@ -364,16 +613,96 @@ func (w *Wallet) SendBlockStakes(amount types.Currency, cond types.UnlockConditi
 // SendOutputs is a tool for sending coins and block stakes from the wallet, to one or multiple addreses.
 // The transaction is automatically given to the transaction pool, and is also returned to the caller.
 func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs []types.BlockStakeOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error) {
-	if len(coinOutputs) == 0 && len(blockstakeOutputs) == 0 {
-		// at least one coin output OR one block stake output has to be send
-		return types.Transaction{}, ErrNilOutputs
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
 	}
+	defer w.tg.Done()
 
+	txnBuilder, txnSet, err := w.signOutputs(coinOutputs, blockstakeOutputs, data, refundAddress, reuseRefundAddress)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		txnBuilder.Drop()
+		return types.Transaction{}, err
+	}
+	return txnSet[0], nil
+}
+
+// CreateCoinTransaction creates and signs a transaction, sending the given coin outputs,
+// funded by the unspent coin outputs of this wallet, in the exact same way as SendOutputs does.
+// The transaction is not given to the transaction pool, and the coin outputs used
+// to fund it remain available for other transactions of the wallet.
+func (w *Wallet) CreateCoinTransaction(coinOutputs []types.CoinOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error) {
 	if err := w.tg.Add(); err != nil {
 		return types.Transaction{}, err
 	}
 	defer w.tg.Done()
 
+	txnBuilder, txnSet, err := w.signOutputs(coinOutputs, nil, data, refundAddress, reuseRefundAddress)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	// release the funded coin outputs, as the transaction is never broadcasted by this wallet
+	txnBuilder.Drop()
+	return txnSet[0], nil
+}
+
+// BurnCoins creates and signs a coin destruction transaction, burning the given amount of coins,
+// funded by the unspent coin outputs of this wallet. The custody fee of the spent coin outputs
+// and the miner fee are paid on top of the burned amount, such that exactly the given amount is burned.
+// The transaction is given to the transaction pool, and is also returned.
+func (w *Wallet) BurnCoins(amount types.Currency, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+
+	if amount.IsZero() {
+		return types.Transaction{}, errors.New("cannot burn zero coins")
+	}
+	tpoolFee := w.chainCts.MinimumTransactionFee
+	var err error
+	txnBuilder := w.StartTransactionWithVersion(gctypes.TransactionVersionCoinDestruction)
+	// Make sure to release inputs in case of an error
+	defer func() {
+		if err != nil {
+			txnBuilder.Drop()
+		}
+	}()
+	err = txnBuilder.FundCoins(amount.Add(tpoolFee), refundAddress, reuseRefundAddress)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	txnBuilder.AddMinerFee(tpoolFee)
+	if len(data) != 0 {
+		txnBuilder.SetArbitraryData(data)
+	}
+	var txnSet []types.Transaction
+	txnSet, err = txnBuilder.Sign()
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	if len(txnSet) == 0 {
+		build.Severe(fmt.Errorf("unexpected txnSet length: %d", len(txnSet)))
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	return txnSet[0], nil
+}
+
+// signOutputs funds and signs a transaction sending the given coin and block stake outputs.
+// The transaction builder is returned, such that the caller can drop it if the transaction is not used,
+// in case of an error it is already dropped.
+func (w *Wallet) signOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs []types.BlockStakeOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (modules.TransactionBuilder, []types.Transaction, error) {
+	if len(coinOutputs) == 0 && len(blockstakeOutputs) == 0 {
+		// at least one coin output OR one block stake output has to be send
+		return nil, nil, ErrNilOutputs
+	}
+
 	tpoolFee := w.chainCts.MinimumTransactionFee.Mul64(1) // TODO better fee algo
 	totalAmount := types.NewCurrency64(0).Add(tpoolFee)
 	var err error
@@ -390,7 +719,7 @@ func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs [
 	}
 	err = txnBuilder.FundCoins(totalAmount, refundAddress, reuseRefundAddress)
 	if err != nil {
-		return types.Transaction{}, err
+		return nil, nil, err
 	}
 	txnBuilder.AddMinerFee(tpoolFee)
 	totalAmount = types.NewCurrency64(0)
@@ -401,7 +730,7 @@ func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs [
 	if !totalAmount.Equals64(0) {
 		err = txnBuilder.FundBlockStakes(totalAmount, refundAddress, reuseRefundAddress)
 		if err != nil {
-			return types.Transaction{}, err
+			return nil, nil, err
 		}
 	}
 	if len(data) != 0 {
@@ -410,16 +739,12 @@ func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs [
 	var txnSet []types.Transaction
 	txnSet, err = txnBuilder.Sign()
 	if err != nil {
-		return types.Transaction{}, err
+		return nil, nil, err
 	}
 	if len(txnSet) == 0 {
 		build.Severe(fmt.Errorf("unexpected txnSet length: " + strconv.Itoa(len(txnSet))))
 	}
-	err = w.tpool.AcceptTransactionSet(txnSet)
-	if err != nil {
-		return types.Transaction{}, err
-	}
-	return txnSet[0], nil
+	return txnBuilder, txnSet, nil
 }
 
 // Len returns the number of elements in the sortedOutputs struct.
@@ -1,5 +1,45 @@
 package wallet
 
+import (
+	"errors"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// TestErrInsufficientCoins checks that the wallet reports why it cannot fund coins,
+// using a stable error code that still wraps the original rivine error.
+func TestErrInsufficientCoins(t *testing.T) {
+	c := types.NewCurrency64
+	testCases := []struct {
+		required, spendable, pending, custodyFee types.Currency
+		code                                     gctypes.ErrorCode
+		wrapped                                  error
+	}{
+		{c(10), c(4), c(6), c(0), gctypes.ErrorCodeIncompleteTransactions, modules.ErrIncompleteTransactions},
+		{c(10), c(8), c(0), c(2), gctypes.ErrorCodeInsufficientSpendableAfterFees, modules.ErrLowBalance},
+		{c(10), c(7), c(0), c(2), gctypes.ErrorCodeInsufficientBalance, modules.ErrLowBalance},
+		{c(10), c(9), c(0), c(0), gctypes.ErrorCodeInsufficientBalance, modules.ErrLowBalance},
+	}
+	for idx, testCase := range testCases {
+		err := errInsufficientCoins(testCase.required, testCase.spendable, testCase.pending, testCase.custodyFee)
+		cErr, ok := gctypes.AsCodedError(err)
+		if !ok || cErr.Code != testCase.code {
+			t.Errorf("test case #%d: expected error code %s, received: %v", idx, testCase.code, err)
+			continue
+		}
+		if !errors.Is(err, testCase.wrapped) {
+			t.Errorf("test case #%d: expected error to wrap %v, received: %v", idx, testCase.wrapped, err)
+		}
+		if cErr.Details["required"] != testCase.required.String() || cErr.Details["spendable"] != testCase.spendable.String() {
+			t.Errorf("test case #%d: unexpected error details: %v", idx, cErr.Details)
+		}
+	}
+}
+
 // TestSendCoins probes the SendCoins method of the wallet.
 // TODO: enable again with stub custody fee plugin
 /*func TestSendCoins(t *testing.T) {
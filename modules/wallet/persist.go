@@ -4,10 +4,15 @@ import (
 	"crypto/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
+	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
 )
 
 const (
@@ -15,6 +20,16 @@ const (
 	settingsFileSuffix = ".json"
 	settingsFile       = modules.WalletDir + settingsFileSuffix
 
+	// settingsRollbackFile contains a copy of the wallet settings,
+	// while the wallet settings are being replaced by their re-encrypted version.
+	settingsRollbackFile = settingsFile + ".rollback"
+	// settingsTempSuffix is the suffix used by the persist package
+	// for the temporary file it writes prior to the file itself.
+	settingsTempSuffix = "_temp"
+	// seedBackupReencryptedSuffix is the suffix of the re-encrypted copy of a seed backup file,
+	// which replaces the seed backup file once the re-encrypted wallet settings are synced to disk.
+	seedBackupReencryptedSuffix = ".reencrypted"
+
 	encryptionVerificationLen = 32
 )
 
@@ -45,6 +60,11 @@ type WalletPersist struct {
 	UID                    UniqueID
 	EncryptionVerification crypto.Ciphertext
 
+	// KDF defines the key derivation function used to derive the encryption key
+	// of the wallet from the master key. It is nil for wallets that use the legacy
+	// encryption format, in which case the master key is used as the encryption key.
+	KDF *KDFParameters `json:",omitempty"`
+
 	// The primary seed is used to generate new addresses as they are required.
 	// All addresses are tracked and spendable. Only modules.PublicKeysPerSeed
 	// keys/addresses can be created per seed, after which a new seed will need
@@ -52,6 +72,15 @@ type WalletPersist struct {
 	PrimarySeedFile     SeedFile
 	PrimarySeedProgress uint64
 
+	// PrimarySeedHDProgress is only defined if the primary seed has been migrated
+	// to HD key derivation, in which case all new addresses are derived using HD derivation.
+	// The addresses generated prior to this migration remain tracked by PrimarySeedProgress.
+	PrimarySeedHDProgress *HDSeedProgress `json:",omitempty"`
+
+	// AddressGapLimit is the number of consecutive unused addresses tracked for each seed,
+	// past the last used address of that seed. The default limit is used if it is 0.
+	AddressGapLimit uint64 `json:",omitempty"`
+
 	// AuxiliarySeedFiles is a set of seeds that the wallet can spend from, but is
 	// no longer using to generate addresses. The primary use case is loading
 	// backups in the event of lost files or coins. All auxiliary seeds are
@@ -61,6 +90,14 @@ type WalletPersist struct {
 	// UnseededKeys are list of spendable keys that were not generated by a
 	// random seed.
 	UnseededKeys []SpendableKeyFile
+
+	// WatchOnlyAddresses are addresses that are tracked by the wallet,
+	// but for which the wallet does not own the keys to spend from them.
+	WatchOnlyAddresses []gcmodules.WatchOnlyAddress
+
+	// AddressLabels are the labels, notes and tags of addresses,
+	// either owned by the wallet or used as a counterparty.
+	AddressLabels []gcmodules.AddressLabel `json:",omitempty"`
 }
 
 // loadSettings reads the wallet's settings from the wallet's settings file,
@@ -82,13 +119,199 @@ func (w *Wallet) saveSettingsSync() error {
 	return persist.SaveJSON(settingsMetadata, w.persist, filepath.Join(w.persistDir, settingsFile))
 }
 
+// saveReencryptedSettings replaces the wallet settings with their re-encrypted version.
+// A rollback copy of the current settings is written and synced to disk first,
+// and is only removed once the new settings are synced to disk as well.
+// Should the wallet be interrupted prior to the removal of the rollback copy,
+// the current settings are restored the next time the wallet is started,
+// such that the wallet can always be unlocked using either the old or the new master key.
+//
+// The seed backup files are re-encrypted as well, using the same rollback copy:
+// their re-encrypted copies are written while the rollback copy exists,
+// and only replace the seed backup files once the rollback copy is removed.
+func (w *Wallet) saveReencryptedSettings(wp WalletPersist, oldKey, newKey crypto.TwofishKey) error {
+	err := persist.SaveJSON(settingsMetadata, w.persist, filepath.Join(w.persistDir, settingsRollbackFile))
+	if err != nil {
+		// the settings themselves were not yet modified
+		return build.ComposeErrors(err, w.removeSettingsRollback())
+	}
+	err = w.saveReencryptedSeedBackups(oldKey, newKey)
+	if err == nil {
+		// the rollback copy and re-encrypted seed backup files
+		// have to be on disk prior to modifying the settings
+		err = syncDir(w.persistDir)
+	}
+	if err != nil {
+		return build.ComposeErrors(err, w.recoverSettingsRollback())
+	}
+	w.persist = wp
+	err = w.saveSettingsSync()
+	if err == nil {
+		err = w.removeSettingsRollback()
+		if err == nil {
+			// the re-encrypted settings are in use, the seed backup files
+			// are replaced the next time the wallet is started should this fail
+			err = w.replaceSeedBackups()
+			if err != nil {
+				w.log.Println("WARN: failed to replace the seed backup files by their re-encrypted copies:", err)
+			}
+			return nil
+		}
+	}
+	// restore the current settings, both in memory and on disk
+	return build.ComposeErrors(err, w.recoverSettingsRollback())
+}
+
+// recoverSettingsRollback restores the wallet settings from the rollback copy,
+// should it exist, and removes the rollback copy afterwards.
+// A rollback copy that cannot be read was not fully synced to disk,
+// meaning the wallet settings themselves were not yet modified, and is simply removed.
+// Re-encrypted seed backup files are discarded if the rollback copy exists,
+// and replace the seed backup files otherwise, as the re-encrypted settings are in use in that case.
+func (w *Wallet) recoverSettingsRollback() error {
+	var wp WalletPersist
+	err := persist.LoadJSON(settingsMetadata, &wp, filepath.Join(w.persistDir, settingsRollbackFile))
+	if os.IsNotExist(err) {
+		return build.ComposeErrors(w.removeSettingsRollback(), w.replaceSeedBackups())
+	}
+	removeErr := w.removeReencryptedSeedBackups()
+	if removeErr != nil {
+		return removeErr
+	}
+	if err != nil {
+		w.log.Println("WARN: ignoring incomplete rollback copy of the wallet settings:", err)
+		return w.removeSettingsRollback()
+	}
+	w.log.Println("INFO: restoring the wallet settings from their rollback copy")
+	w.persist = wp
+	err = w.saveSettingsSync()
+	if err != nil {
+		return err
+	}
+	return w.removeSettingsRollback()
+}
+
+// removeSettingsRollback removes the rollback copy of the wallet settings,
+// as well as the temporary file written alongside it.
+// The removal is synced to disk, such that the rollback copy cannot reappear
+// once the seed backup files are replaced by their re-encrypted copies.
+func (w *Wallet) removeSettingsRollback() error {
+	rollbackFilename := filepath.Join(w.persistDir, settingsRollbackFile)
+	for _, filename := range []string{rollbackFilename, rollbackFilename + settingsTempSuffix} {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return syncDir(w.persistDir)
+}
+
+// seedBackupFiles returns the filenames of all seed backup files of the wallet,
+// or of their re-encrypted copies if the re-encrypted suffix is given.
+func (w *Wallet) seedBackupFiles(suffix string) ([]string, error) {
+	return filepath.Glob(filepath.Join(w.persistDir,
+		w.bcInfo.Name+seedFilePartialPrefix+"*"+seedFileSuffix+suffix))
+}
+
+// saveReencryptedSeedBackups writes a copy of each seed backup file,
+// re-encrypted from the old to the new encryption key, next to the seed backup file.
+// Seed backup files which cannot be decrypted using the old encryption key are left as is.
+func (w *Wallet) saveReencryptedSeedBackups(oldKey, newKey crypto.TwofishKey) error {
+	filenames, err := w.seedBackupFiles("")
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		var sf SeedFile
+		err = persist.LoadJSON(seedMetadata, &sf, filename)
+		if err != nil {
+			w.log.Println("WARN: not re-encrypting unreadable seed backup file", filename, ":", err)
+			continue
+		}
+		seed, err := decryptSeedFile(oldKey, sf)
+		crypto.SecureWipe(seed[:])
+		if err != nil {
+			w.log.Println("WARN: not re-encrypting seed backup file", filename, ":", err)
+			continue
+		}
+		sf, err = reencryptSeedFile(sf, oldKey, newKey)
+		if err != nil {
+			return err
+		}
+		err = persist.SaveJSON(seedMetadata, sf, filename+seedBackupReencryptedSuffix)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceSeedBackups replaces the seed backup files by their re-encrypted copies, should they exist.
+func (w *Wallet) replaceSeedBackups() error {
+	filenames, err := w.seedBackupFiles(seedBackupReencryptedSuffix)
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		err = os.Rename(filename, strings.TrimSuffix(filename, seedBackupReencryptedSuffix))
+		if err != nil {
+			return err
+		}
+	}
+	err = syncDir(w.persistDir)
+	if err != nil {
+		return err
+	}
+	// remove the temporary files left behind by the persist package
+	return w.removeReencryptedSeedBackups()
+}
+
+// removeReencryptedSeedBackups removes the re-encrypted copies of the seed backup files,
+// as well as their temporary files.
+func (w *Wallet) removeReencryptedSeedBackups() error {
+	for _, suffix := range []string{seedBackupReencryptedSuffix, seedBackupReencryptedSuffix + settingsTempSuffix} {
+		filenames, err := w.seedBackupFiles(suffix)
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			err = os.Remove(filename)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncDir syncs the given directory to disk, such that the files created,
+// renamed or removed within it are persisted, as syncing a file does not persist its directory entry.
+// Directories cannot be synced on Windows, where this is a no-op.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return build.ComposeErrors(f.Sync(), f.Close())
+}
+
 // initSettings creates the settings object at startup. If a settings file
 // exists, the settings file will be loaded into memory. If the settings file
 // does not exist, a new.persist file will be created.
 func (w *Wallet) initSettings() error {
+	// Restore the previous settings if the wallet was interrupted
+	// while replacing its settings by their re-encrypted version.
+	err := w.recoverSettingsRollback()
+	if err != nil {
+		return err
+	}
+
 	// Check if the settings file exists, if not create it.
 	settingsFilename := filepath.Join(w.persistDir, settingsFile)
-	_, err := os.Stat(settingsFilename)
+	_, err = os.Stat(settingsFilename)
 	if os.IsNotExist(err) {
 		_, err = rand.Read(w.persist.UID[:])
 		if err != nil {
@@ -104,6 +327,13 @@ func (w *Wallet) initSettings() error {
 	if err != nil {
 		return err
 	}
+	// load the watch-only addresses, prior to subscribing the wallet
+	for _, woa := range w.persist.WatchOnlyAddresses {
+		w.watchOnlyAddresses[woa.Address] = woa
+	}
+	for _, label := range w.persist.AddressLabels {
+		w.addressLabels[label.Address] = label
+	}
 	// unlock by default if the file is unencrypted,
 	// load the primary and aux seeds already as well and subscribe the wallet
 	if w.persist.PrimarySeedFile.UID != (UniqueID{}) && len(w.persist.EncryptionVerification) == 0 {
@@ -118,6 +348,7 @@ func (w *Wallet) initSettings() error {
 		if err != nil {
 			return err
 		}
+		w.loadUsedKeys()
 		err = w.subscribeWallet()
 		if err != nil {
 			return err
@@ -125,6 +356,10 @@ func (w *Wallet) initSettings() error {
 		w.mu.Lock()
 		w.subscribed = true
 		w.mu.Unlock()
+	} else if len(w.watchOnlyAddresses) != 0 {
+		// track the watch-only addresses already,
+		// without waiting for the wallet to be unlocked (or even to have a seed)
+		return w.managedSubscribe()
 	}
 	return nil
 }
@@ -146,6 +381,12 @@ func (w *Wallet) initPersist(verboseLogging bool) error {
 		return err
 	}
 
+	// Open the wallet database.
+	err = w.initDatabase()
+	if err != nil {
+		return err
+	}
+
 	// Load the settings file.
 	err = w.initSettings()
 	if err != nil {
@@ -0,0 +1,167 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+var (
+	errPSTNotSignable = errors.New("none of the missing signatures of the partially signed transaction can be provided by this wallet")
+)
+
+// CreatePartiallySignedTransaction wraps the given transaction into a partially signed transaction (PST),
+// looking up the parent outputs of all its inputs and the custody fee to be paid for its coin inputs.
+// The custody fee is computed at the computation time defined by the custody fee output of the transaction,
+// or at the time of the current block in case the transaction does not define one yet.
+// Fulfillments already defined in the transaction are kept, and reported as part of the signature status of the PST.
+//
+// All parent outputs are expected to be confirmed.
+func (w *Wallet) CreatePartiallySignedTransaction(txn types.Transaction) (gcmodules.PartiallySignedTransaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	defer w.tg.Done()
+
+	// register the transaction, such that the PST owns a copy of it
+	txn, _ = w.RegisterTransaction(txn, nil).View()
+	pst := gcmodules.PartiallySignedTransaction{
+		Version:     gcmodules.PSTVersion,
+		Transaction: txn,
+	}
+
+	var custodyFee gcmodules.PSTCustodyFee
+	custodyFeeOutputs := 0
+	for _, co := range txn.CoinOutputs {
+		if cfc, ok := co.Condition.Condition.(*cftypes.CustodyFeeCondition); ok {
+			custodyFee.ComputationTime = cfc.ComputationTime
+			custodyFee.Paid = custodyFee.Paid.Add(co.Value)
+			custodyFeeOutputs++
+		}
+	}
+	if custodyFeeOutputs > 1 {
+		return gcmodules.PartiallySignedTransaction{}, types.NewClientError(
+			errors.New("only one custody fee condition per transaction is allowed"), types.ClientErrorBadRequest)
+	}
+	if custodyFeeOutputs == 0 {
+		// compute the fee as the wallet would, at the time of the current block
+		custodyFee.ComputationTime = w.getFulfillableContextForLatestBlock().BlockTime
+	}
+
+	err := w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+		for idx, ci := range txn.CoinInputs {
+			co, err := w.cs.GetCoinOutput(ci.ParentID)
+			if err != nil {
+				return types.NewClientError(fmt.Errorf(
+					"parent %s of coin input #%d is not an unspent coin output: %v", ci.ParentID.String(), idx+1, err), types.ClientErrorBadRequest)
+			}
+			info, err := view.GetCoinOutputInfo(ci.ParentID, custodyFee.ComputationTime)
+			if err != nil {
+				return fmt.Errorf("failed to get custody fee info for coin output %s: %v", ci.ParentID.String(), err)
+			}
+			pst.CoinInputs = append(pst.CoinInputs, gcmodules.PSTCoinInput{
+				ParentID:       ci.ParentID,
+				Parent:         co,
+				CreationTime:   info.CreationTime,
+				CreationValue:  info.CreationValue,
+				CustodyFee:     info.CustodyFee,
+				SpendableValue: info.SpendableValue,
+			})
+			custodyFee.Expected = custodyFee.Expected.Add(info.CustodyFee)
+		}
+		return nil
+	})
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	if len(pst.CoinInputs) > 0 {
+		pst.CustodyFee = &custodyFee
+	}
+
+	for idx, bsi := range txn.BlockStakeInputs {
+		bso, err := w.cs.GetBlockStakeOutput(bsi.ParentID)
+		if err != nil {
+			return gcmodules.PartiallySignedTransaction{}, types.NewClientError(fmt.Errorf(
+				"parent %s of block stake input #%d is not an unspent block stake output: %v", bsi.ParentID.String(), idx+1, err), types.ClientErrorBadRequest)
+		}
+		pst.BlockStakeInputs = append(pst.BlockStakeInputs, gcmodules.PSTBlockStakeInput{
+			ParentID: bsi.ParentID,
+			Parent:   bso,
+		})
+	}
+
+	err = pst.UpdateSignatureStatus()
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	return pst, nil
+}
+
+// SignPartiallySignedTransaction adds all signatures to the given PST that can be provided using the keys of this wallet.
+// Signatures already provided are kept, an error is returned if the wallet cannot add any signature.
+func (w *Wallet) SignPartiallySignedTransaction(pst gcmodules.PartiallySignedTransaction) (gcmodules.PartiallySignedTransaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	defer w.tg.Done()
+
+	err := pst.UpdateSignatureStatus()
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, types.NewClientError(err, types.ClientErrorBadRequest)
+	}
+	signedTxn, err := w.GreedySign(pst.Transaction)
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	signed := pst
+	signed.Transaction = signedTxn
+	// combine the signed PST with the original PST, as the wallet signs a multisig fulfillment
+	// without checking whether or not one of its keys already signed it
+	signed, err = gcmodules.CombinePartiallySignedTransactions(pst, signed)
+	if err != nil {
+		return gcmodules.PartiallySignedTransaction{}, err
+	}
+	if signed.SignatureCount() <= pst.SignatureCount() {
+		return gcmodules.PartiallySignedTransaction{}, types.NewClientError(errPSTNotSignable, types.ClientErrorBadRequest)
+	}
+	return signed, nil
+}
+
+// FinalizePartiallySignedTransaction gives the transaction of the given PST to the transaction pool,
+// given it has all required signatures. The published transaction is returned.
+func (w *Wallet) FinalizePartiallySignedTransaction(pst gcmodules.PartiallySignedTransaction) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+
+	err := pst.UpdateSignatureStatus()
+	if err != nil {
+		return types.Transaction{}, types.NewClientError(err, types.ClientErrorBadRequest)
+	}
+	if !pst.Complete() {
+		missing := pst.MissingSigners()
+		addresses := make([]string, 0, len(missing))
+		for _, uh := range missing {
+			addresses = append(addresses, uh.String())
+		}
+		err = errors.New("partially signed transaction is missing signatures")
+		if len(addresses) > 0 {
+			err = fmt.Errorf("%v of: %s", err, strings.Join(addresses, ", "))
+		}
+		return types.Transaction{}, gctypes.NewCodedError(gctypes.ErrorCodeMissingSignatures, err,
+			map[string]string{"missing": strings.Join(addresses, ",")})
+	}
+	err = w.tpool.AcceptTransactionSet([]types.Transaction{pst.Transaction})
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	return pst.Transaction, nil
+}
@@ -0,0 +1,328 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/modules"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+)
+
+const (
+	// defaultAddressGapLimit is the default number of consecutive unused keys
+	// that are loaded for each seed (and HD chain), past the last used key.
+	defaultAddressGapLimit = 20
+
+	// maxAddressGapLimit is the maximum address gap limit that can be configured.
+	maxAddressGapLimit = 10000
+)
+
+var (
+	errInvalidAddressGapLimit = fmt.Errorf("address gap limit has to be within the range [1, %d]", maxAddressGapLimit)
+	errWalletNotSubscribed    = errors.New("wallet is not yet subscribed to the consensus set")
+)
+
+// legacySeedKeys tracks the keys that are loaded for a single seed,
+// using the flat (legacy) derivation.
+type legacySeedKeys struct {
+	// loaded is the amount of keys loaded
+	loaded uint64
+	// used is the index of the last used key + 1
+	used uint64
+}
+
+// addressGapLimit returns the configured address gap limit,
+// or the default one if none was configured.
+func (w *Wallet) addressGapLimit() uint64 {
+	if w.persist.AddressGapLimit == 0 {
+		return defaultAddressGapLimit
+	}
+	return w.persist.AddressGapLimit
+}
+
+// loadLegacySeedKeys loads the first count keys of the given seed into the wallet,
+// using the flat (legacy) derivation. Keys that were loaded past that count
+// during a previous unlock, as the seed was used past its pregenerated window, are reloaded as well.
+// The seed index is the index the seed has (or will have) in the wallet's seed slice.
+func (w *Wallet) loadLegacySeedKeys(seedIndex int, seed modules.Seed, count uint64) error {
+	for len(w.legacySeeds) <= seedIndex {
+		w.legacySeeds = append(w.legacySeeds, &legacySeedKeys{})
+	}
+	lsk := w.legacySeeds[seedIndex]
+	if lsk.loaded > count {
+		count = lsk.loaded
+	}
+	if target := lsk.used + w.addressGapLimit(); lsk.used > 0 && target > count {
+		count = target
+	}
+	for i := uint64(0); i < count; i++ {
+		err := w.loadLegacySeedKey(seedIndex, seed, i)
+		if err != nil {
+			return err
+		}
+	}
+	lsk.loaded = count
+	return nil
+}
+
+// loadLegacySeedKey loads a single key of the given seed into the wallet,
+// using the flat (legacy) derivation.
+func (w *Wallet) loadLegacySeedKey(seedIndex int, seed modules.Seed, index uint64) error {
+	spendableKey, err := generateSpendableKey(seed, index)
+	if err != nil {
+		return err
+	}
+	spendableKey.SeedIndex = seedIndex
+	uh, err := spendableKey.UnlockHash()
+	if err != nil {
+		return err
+	}
+	w.keys[uh] = spendableKey
+	return nil
+}
+
+// markKeyUsed is called for every output that is received by a wallet key.
+// The keys of the seed (or HD chain) the key belongs to are extended,
+// such that the address gap limit is respected past this key.
+// This allows the wallet to discover funds on keys past the pregenerated window,
+// which is especially important when recovering a seed.
+//
+// Keys can only be generated while the wallet is unlocked. While the wallet is locked,
+// the last used key is still recorded, such that the keys past it get loaded on the next unlock,
+// and the wallet history is marked as incomplete, such that the wallet rescans on that unlock.
+func (w *Wallet) markKeyUsed(key spendableKey) {
+	w.usedAddresses[key.PublicKey] = struct{}{}
+	if key.HDChain != nil {
+		w.markHDKeyUsed(key)
+		return
+	}
+	if key.SeedIndex >= len(w.legacySeeds) {
+		return
+	}
+	lsk := w.legacySeeds[key.SeedIndex]
+	if lsk.used > key.Index {
+		return // seed is already extended past this key
+	}
+	lsk.used = key.Index + 1
+	target := lsk.used + w.addressGapLimit()
+	if lsk.loaded >= target {
+		return
+	}
+	if key.SeedIndex >= len(w.seeds) {
+		// the wallet is locked
+		w.keysIncomplete = true
+		return
+	}
+	for i := lsk.loaded; i < target; i++ {
+		err := w.loadLegacySeedKey(key.SeedIndex, w.seeds[key.SeedIndex], i)
+		if err != nil {
+			w.log.Println("WARN: failed to extend the keys of seed", key.SeedIndex, ":", err)
+			return
+		}
+		lsk.loaded = i + 1
+	}
+}
+
+// markUsedKeys marks all keys that receive an output in the given consensus change as used,
+// prior to processing its outputs, until no more keys are loaded. This ensures that the outputs
+// received by keys, which only get loaded because another key of the same change is used, are not missed.
+func (w *Wallet) markUsedKeys(cc modules.ConsensusChange) {
+	for {
+		loaded := len(w.keys)
+		for _, diff := range cc.CoinOutputDiffs {
+			if key, ok := w.keys[diff.CoinOutput.Condition.UnlockHash()]; ok && diff.Direction == modules.DiffApply {
+				w.markKeyUsed(key)
+			}
+		}
+		for _, diff := range cc.BlockStakeOutputDiffs {
+			if key, ok := w.keys[diff.BlockStakeOutput.Condition.UnlockHash()]; ok && diff.Direction == modules.DiffApply {
+				w.markKeyUsed(key)
+			}
+		}
+		if len(w.keys) == loaded {
+			return // no more keys were loaded
+		}
+	}
+}
+
+// loadUsedKeys extends the keys of all seeds past the keys that were used,
+// according to the used addresses restored from the wallet database,
+// such that the wallet can resume tracking the consensus set,
+// without having to rediscover the keys used past the pregenerated windows.
+func (w *Wallet) loadUsedKeys() {
+	for {
+		loaded := len(w.keys)
+		for _, key := range w.keys {
+			if _, ok := w.usedAddresses[key.PublicKey]; ok {
+				w.markKeyUsed(key)
+			}
+		}
+		if len(w.keys) == loaded {
+			return // no more keys were loaded
+		}
+	}
+}
+
+// AddressGapLimit returns the number of consecutive unused addresses
+// that the wallet tracks for each seed, past the last used address of that seed.
+func (w *Wallet) AddressGapLimit() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.addressGapLimit()
+}
+
+// SetAddressGapLimit configures the number of consecutive unused addresses
+// that the wallet tracks for each seed, past the last used address of that seed.
+// The new limit is applied for all keys used from now on, use RescanSeeds
+// to apply it to the keys used in the past.
+func (w *Wallet) SetAddressGapLimit(limit uint64) error {
+	if limit == 0 || limit > maxAddressGapLimit {
+		return errInvalidAddressGapLimit
+	}
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.persist.AddressGapLimit = limit
+	return w.saveSettingsSync()
+}
+
+// RescanSeeds rescans the consensus set, extending the keys of each seed
+// until the address gap limit is respected past the last used key,
+// and reports the keys and funds discovered for each seed.
+func (w *Wallet) RescanSeeds() ([]gcmodules.SeedScanReport, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	unlocked, subscribed := w.unlocked, w.subscribed
+	w.mu.RUnlock()
+	if !unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+	if !subscribed {
+		return nil, errWalletNotSubscribed
+	}
+	err := w.managedRescan()
+	if err != nil {
+		return nil, err
+	}
+	return w.SeedScanReports()
+}
+
+// SeedScanReports reports the keys and funds currently known for each seed of the wallet.
+func (w *Wallet) SeedScanReports() ([]gcmodules.SeedScanReport, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	reports := make([]gcmodules.SeedScanReport, len(w.seeds))
+	for idx := range reports {
+		reports[idx].SeedIndex = idx
+		reports[idx].Primary = idx == 0
+	}
+	for _, key := range w.keys {
+		if key.SeedIndex >= len(reports) {
+			continue
+		}
+		reports[key.SeedIndex].AddressesLoaded++
+		if _, ok := w.usedAddresses[key.PublicKey]; ok {
+			reports[key.SeedIndex].AddressesUsed++
+		}
+	}
+
+	ctx := w.getFulfillableContextForLatestBlock()
+	err := w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+		for id, co := range w.coinOutputs {
+			key, ok := w.keys[co.Condition.UnlockHash()]
+			if !ok || key.SeedIndex >= len(reports) {
+				continue
+			}
+			info, err := view.GetCoinOutputInfo(id, ctx.BlockTime)
+			if err != nil {
+				return err
+			}
+			report := &reports[key.SeedIndex]
+			if co.Condition.Fulfillable(ctx) {
+				report.ConfirmedCoinBalance = report.ConfirmedCoinBalance.Add(info.SpendableValue)
+			} else {
+				report.ConfirmedLockedCoinBalance = report.ConfirmedLockedCoinBalance.Add(info.SpendableValue)
+			}
+			report.ConfirmedCustodyFeeDebt = report.ConfirmedCustodyFeeDebt.Add(info.CustodyFee)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, bso := range w.blockstakeOutputs {
+		key, ok := w.keys[bso.Condition.UnlockHash()]
+		if !ok || key.SeedIndex >= len(reports) {
+			continue
+		}
+		report := &reports[key.SeedIndex]
+		if bso.Condition.Fulfillable(ctx) {
+			report.ConfirmedBlockStakeBalance = report.ConfirmedBlockStakeBalance.Add(bso.Value)
+		} else {
+			report.ConfirmedLockedBlockStakeBalance = report.ConfirmedLockedBlockStakeBalance.Add(bso.Value)
+		}
+	}
+	return reports, nil
+}
+
+// managedRescanIfSubscribed rescans the consensus set,
+// only if the wallet was already subscribed to it.
+func (w *Wallet) managedRescanIfSubscribed() error {
+	w.mu.RLock()
+	subscribed := w.subscribed
+	w.mu.RUnlock()
+	if !subscribed {
+		return nil
+	}
+	return w.managedRescan()
+}
+
+// managedRescanIfIncomplete rescans the consensus set, only if the wallet
+// processed consensus changes while the keys of its seeds were not yet loaded.
+func (w *Wallet) managedRescanIfIncomplete() error {
+	var incompleteHistory bool
+	err := w.db.View(func(tx *bolt.Tx) error {
+		return dbGetInternal(tx, internalIncompleteHistory, &incompleteHistory)
+	})
+	if err != nil || !incompleteHistory {
+		return err
+	}
+	w.log.Println("INFO: wallet history is incomplete, rescanning the consensus set")
+	return w.managedRescan()
+}
+
+// managedRescan unsubscribes the wallet from the consensus set,
+// resets the confirmed set and transaction history of the wallet,
+// and resubscribes it to the consensus set starting from the very first block.
+// This is required as the addresses tracked by the wallet have changed,
+// as the wallet otherwise has no way to learn about the history of the new addresses.
+func (w *Wallet) managedRescan() error {
+	w.cs.Unsubscribe(w)
+
+	err := w.resetDatabase()
+	if err != nil {
+		return errors.New("wallet rescan failed: " + err.Error())
+	}
+
+	err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	if err != nil {
+		return errors.New("wallet rescan failed: " + err.Error())
+	}
+	return nil
+}
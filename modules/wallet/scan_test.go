@@ -0,0 +1,192 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestAddressGapLimit checks that the keys of a seed are extended
+// when a key near the end of its loaded window is used.
+func TestAddressGapLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if limit := wt.wallet.AddressGapLimit(); limit != defaultAddressGapLimit {
+		t.Fatal("unexpected default address gap limit:", limit)
+	}
+	for _, limit := range []uint64{0, maxAddressGapLimit + 1} {
+		if err = wt.wallet.SetAddressGapLimit(limit); err != errInvalidAddressGapLimit {
+			t.Errorf("expected errInvalidAddressGapLimit for limit %d, received: %v", limit, err)
+		}
+	}
+	err = wt.wallet.SetAddressGapLimit(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// use the last preloaded key of the primary seed
+	wt.wallet.mu.Lock()
+	loaded := wt.wallet.legacySeeds[0].loaded
+	lastKey, err := generateSpendableKey(wt.wallet.primarySeed, loaded-1)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	lastUH, err := lastKey.UnlockHash()
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	wt.wallet.updateConfirmedSet(modules.ConsensusChange{
+		BlockStakeOutputDiffs: []modules.BlockStakeOutputDiff{{
+			Direction: modules.DiffApply,
+			ID:        types.BlockStakeOutputID{1},
+			BlockStakeOutput: types.BlockStakeOutput{
+				Value:     types.NewCurrency64(3),
+				Condition: types.NewCondition(types.NewUnlockHashCondition(lastUH)),
+			},
+		}},
+	})
+	extended := wt.wallet.legacySeeds[0].loaded
+	wt.wallet.mu.Unlock()
+	if extended != loaded+50 {
+		t.Fatalf("seed was not extended past the used key: %d != %d", extended, loaded+50)
+	}
+
+	// new addresses are never taken from the used keys
+	uh, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	key := wt.wallet.keys[uh]
+	wt.wallet.mu.RUnlock()
+	if key.Index < loaded {
+		t.Fatalf("next address has a used or skipped index: %d < %d", key.Index, loaded)
+	}
+
+	wt.wallet.mu.RLock()
+	_, used := wt.wallet.usedAddresses[lastKey.PublicKey]
+	wt.wallet.mu.RUnlock()
+	if !used {
+		t.Error("used key is not tracked as a used address")
+	}
+}
+
+// TestAddressGapLimitSingleChange checks that keys loaded because of an output
+// of a consensus change are checked for the other outputs of that same change.
+func TestAddressGapLimitSingleChange(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	wt.wallet.mu.Lock()
+	defer wt.wallet.mu.Unlock()
+	loaded := wt.wallet.legacySeeds[0].loaded
+	var uhs []types.UnlockHash
+	// the key past the loaded window is listed first
+	for _, index := range []uint64{loaded + defaultAddressGapLimit/2, loaded - 1} {
+		key, err := generateSpendableKey(wt.wallet.primarySeed, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uh, err := key.UnlockHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		uhs = append(uhs, uh)
+	}
+	cc := modules.ConsensusChange{}
+	for idx, uh := range uhs {
+		cc.BlockStakeOutputDiffs = append(cc.BlockStakeOutputDiffs, modules.BlockStakeOutputDiff{
+			Direction: modules.DiffApply,
+			ID:        types.BlockStakeOutputID{byte(idx + 1)},
+			BlockStakeOutput: types.BlockStakeOutput{
+				Value:     types.NewCurrency64(1),
+				Condition: types.NewCondition(types.NewUnlockHashCondition(uh)),
+			},
+		})
+	}
+	wt.wallet.updateConfirmedSet(cc)
+	for _, diff := range cc.BlockStakeOutputDiffs {
+		if _, ok := wt.wallet.blockstakeOutputs[diff.ID]; !ok {
+			t.Errorf("output %v of the consensus change was missed", diff.ID)
+		}
+	}
+	if expected := loaded + defaultAddressGapLimit/2 + 1 + defaultAddressGapLimit; wt.wallet.legacySeeds[0].loaded != expected {
+		t.Errorf("seed was not extended past the last used key: %d != %d", wt.wallet.legacySeeds[0].loaded, expected)
+	}
+}
+
+// TestAddressGapLimitLocked checks that keys used while the wallet is locked
+// are recorded, such that the keys past them are loaded once the wallet is unlocked.
+func TestAddressGapLimitLocked(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	wt.wallet.mu.Lock()
+	loaded := wt.wallet.legacySeeds[0].loaded
+	lastKey, err := generateSpendableKey(wt.wallet.primarySeed, loaded-1)
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastUH, err := lastKey.UnlockHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wt.wallet.mu.Lock()
+	wt.wallet.updateConfirmedSet(modules.ConsensusChange{
+		BlockStakeOutputDiffs: []modules.BlockStakeOutputDiff{{
+			Direction: modules.DiffApply,
+			ID:        types.BlockStakeOutputID{1},
+			BlockStakeOutput: types.BlockStakeOutput{
+				Value:     types.NewCurrency64(1),
+				Condition: types.NewCondition(types.NewUnlockHashCondition(lastUH)),
+			},
+		}},
+	})
+	used, incomplete := wt.wallet.legacySeeds[0].used, wt.wallet.keysIncomplete
+	wt.wallet.mu.Unlock()
+	if used != loaded {
+		t.Errorf("used key was not recorded while locked: %d != %d", used, loaded)
+	}
+	if !incomplete {
+		t.Error("keys used past the loaded window while locked did not mark the keys as incomplete")
+	}
+
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	extended := wt.wallet.legacySeeds[0].loaded
+	wt.wallet.mu.RUnlock()
+	if extended != loaded+defaultAddressGapLimit {
+		t.Errorf("seed was not extended past the key used while locked: %d != %d", extended, loaded+defaultAddressGapLimit)
+	}
+}
@@ -134,19 +134,13 @@ func loadPlainSeedFile(sf SeedFile) (modules.Seed, error) {
 
 // integrateSeed takes an address seed as input and from that generates
 // 'publicKeysPerSeed' addresses that the wallet is able to spend.
+// More keys are generated as the wallet discovers used keys near the end of this window.
+// HD derivation is only used for the primary seed, and only once it is enabled.
 // integrateSeed should not be called with the primary seed.
 func (w *Wallet) integrateSeed(seed modules.Seed) error {
-	for i := uint64(0); i < modules.PublicKeysPerSeed; i++ {
-		// Generate the key and check it is new to the wallet.
-		spendableKey, err := generateSpendableKey(seed, i)
-		if err != nil {
-			return err
-		}
-		uh, err := spendableKey.UnlockHash()
-		if err != nil {
-			return err
-		}
-		w.keys[uh] = spendableKey
+	err := w.loadLegacySeedKeys(len(w.seeds), seed, modules.PublicKeysPerSeed)
+	if err != nil {
+		return err
 	}
 	w.seeds = append(w.seeds, seed)
 	return nil
@@ -263,16 +257,15 @@ func (w *Wallet) initPrimarySeed(sf func(SeedFile) (modules.Seed, error)) error
 	}
 	// The wallet preloads keys to prevent confusion when using the same wallet
 	// in multiple places.
-	for i := uint64(0); i < w.persist.PrimarySeedProgress+modules.WalletSeedPreloadDepth; i++ {
-		spendableKey, err := generateSpendableKey(seed, i)
-		if err != nil {
-			return err
-		}
-		uh, err := spendableKey.UnlockHash()
+	err = w.loadLegacySeedKeys(len(w.seeds), seed, w.persist.PrimarySeedProgress+modules.WalletSeedPreloadDepth)
+	if err != nil {
+		return err
+	}
+	if w.persist.PrimarySeedHDProgress != nil {
+		err = w.loadHDSeed(len(w.seeds), seed, w.persist.PrimarySeedHDProgress)
 		if err != nil {
 			return err
 		}
-		w.keys[uh] = spendableKey
 	}
 	w.primarySeed = seed
 	w.seeds = append(w.seeds, seed)
@@ -319,10 +312,31 @@ func (w *Wallet) nextPrimarySeedAddress() (types.UnlockHash, error) {
 		return types.UnlockHash{}, modules.ErrLockedWallet
 	}
 
+	// Take the next address from the external chain of the first account,
+	// in case the primary seed has been migrated to HD derivation.
+	if w.persist.PrimarySeedHDProgress != nil {
+		spendableKey, err := w.nextHDKey(0, hdExternalChain)
+		if err != nil {
+			return types.UnlockHash{}, err
+		}
+		err = w.saveSettingsSync()
+		if err != nil {
+			return types.UnlockHash{}, err
+		}
+		return spendableKey.UnlockHash()
+	}
+
+	// Skip all keys that are already used on chain, which can be the case
+	// for a recovered seed that was used past its preloaded keys.
+	if lsk := w.legacySeeds[0]; lsk.used > w.persist.PrimarySeedProgress+modules.WalletSeedPreloadDepth {
+		w.persist.PrimarySeedProgress = lsk.used - modules.WalletSeedPreloadDepth
+	}
+
 	// Integrate the next key into the wallet, and return the unlock
 	// conditions. Because the wallet preloads keys, the progress used is
 	// 'PrimarySeedProgress+modules.WalletSeedPreloadDepth'.
-	spendableKey, err := generateSpendableKey(w.primarySeed, w.persist.PrimarySeedProgress+modules.WalletSeedPreloadDepth)
+	index := w.persist.PrimarySeedProgress + modules.WalletSeedPreloadDepth
+	spendableKey, err := generateSpendableKey(w.primarySeed, index)
 	if err != nil {
 		return types.UnlockHash{}, err
 	}
@@ -331,6 +345,9 @@ func (w *Wallet) nextPrimarySeedAddress() (types.UnlockHash, error) {
 		return types.UnlockHash{}, err
 	}
 	w.keys[uh] = spendableKey
+	if lsk := w.legacySeeds[0]; lsk.loaded <= index {
+		lsk.loaded = index + 1
+	}
 	w.persist.PrimarySeedProgress++
 	err = w.saveSettingsSync()
 	if err != nil {
@@ -374,30 +391,43 @@ func (w *Wallet) NextAddress() (types.UnlockHash, error) {
 // LoadSeed will track all of the addresses generated by the input seed,
 // reclaiming any funds that were lost due to a deleted file or lost encryption
 // key. An error will be returned if the seed has already been integrated with
-// the wallet.
+// the wallet. The consensus set is rescanned in order to discover the funds of the seed.
 func (w *Wallet) LoadSeed(masterKey crypto.TwofishKey, seed modules.Seed) error {
 	if err := w.tg.Add(); err != nil {
 		return err
 	}
 	defer w.tg.Done()
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	err := w.checkMasterKey(masterKey)
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		key, err := w.checkMasterKey(masterKey)
+		if err != nil {
+			return err
+		}
+		return w.recoverEncryptedSeed(key, seed)
+	}()
 	if err != nil {
 		return err
 	}
-	return w.recoverEncryptedSeed(masterKey, seed)
+	return w.managedRescanIfSubscribed()
 }
 
 // LoadPlainSeed will track all of the addresses generated by the input seed,
 // reclaiming any funds that were lost due to a deleted/lost file.
 // An error will be returned if the seed has already been integrated with the wallet.
+// The consensus set is rescanned in order to discover the funds of the seed.
 func (w *Wallet) LoadPlainSeed(seed modules.Seed) error {
 	if err := w.tg.Add(); err != nil {
 		return err
 	}
 	defer w.tg.Done()
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	return w.recoverPlainSeed(seed)
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.recoverPlainSeed(seed)
+	}()
+	if err != nil {
+		return err
+	}
+	return w.managedRescanIfSubscribed()
 }
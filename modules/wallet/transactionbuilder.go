@@ -13,6 +13,7 @@ import (
 
 	"github.com/nbh-digital/goldchain/extensions/custodyfees"
 	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
 var (
@@ -159,11 +160,8 @@ func (tb *transactionBuilder) FundCoins(amount types.Currency, refundAddress *ty
 		}
 		return nil
 	})
-	if potentialFund.Cmp(amount) >= 0 && fund.Cmp(amount) < 0 {
-		return modules.ErrIncompleteTransactions
-	}
 	if fund.Cmp(amount) < 0 {
-		return modules.ErrLowBalance
+		return errInsufficientCoins(amount, fund, potentialFund.Sub(fund), custodyFeeTotal)
 	}
 
 	// Create and add the Custody Fee Coin Output
@@ -198,7 +196,7 @@ func (tb *transactionBuilder) FundCoins(amount types.Currency, refundAddress *ty
 		} else {
 			// generate a new address
 			var err error
-			refundUnlockHash, err = tb.wallet.nextPrimarySeedAddress()
+			refundUnlockHash, err = tb.wallet.nextChangeAddress()
 			if err != nil {
 				return err
 			}
@@ -302,11 +300,8 @@ func (tb *transactionBuilder) FundBlockStakes(amount types.Currency, refundAddre
 			break
 		}
 	}
-	if potentialFund.Cmp(amount) >= 0 && fund.Cmp(amount) < 0 {
-		return modules.ErrIncompleteTransactions
-	}
 	if fund.Cmp(amount) < 0 {
-		return modules.ErrLowBalance
+		return errInsufficientBlockStakes(amount, fund, potentialFund.Sub(fund))
 	}
 
 	// Create a refund output if needed.
@@ -328,7 +323,7 @@ func (tb *transactionBuilder) FundBlockStakes(amount types.Currency, refundAddre
 		} else {
 			// generate a new address
 			var err error
-			refundUnlockHash, err = tb.wallet.nextPrimarySeedAddress()
+			refundUnlockHash, err = tb.wallet.nextChangeAddress()
 			if err != nil {
 				return err
 			}
@@ -490,15 +485,11 @@ func (tb *transactionBuilder) Sign() ([]types.Transaction, error) {
 
 	for _, ctx := range tb.coinInputs {
 		input := tb.transaction.CoinInputs[ctx.InputIndex]
-		_, sk, err := tb.wallet.getKey(ctx.UnlockHash)
-		if err != nil {
-			return nil, err
+		key, found := tb.wallet.keys[ctx.UnlockHash]
+		if !found {
+			return nil, errUnknownAddress
 		}
-		err = input.Fulfillment.Sign(types.FulfillmentSignContext{
-			ExtraObjects: []interface{}{uint64(ctx.InputIndex)},
-			Transaction:  tb.transaction,
-			Key:          sk,
-		})
+		err := key.sign(input.Fulfillment.Fulfillment, tb.transaction, []interface{}{uint64(ctx.InputIndex)})
 		if err != nil {
 			return nil, err
 		}
@@ -506,15 +497,11 @@ func (tb *transactionBuilder) Sign() ([]types.Transaction, error) {
 	}
 	for _, ctx := range tb.blockstakeInputs {
 		input := tb.transaction.BlockStakeInputs[ctx.InputIndex]
-		_, sk, err := tb.wallet.getKey(ctx.UnlockHash)
-		if err != nil {
-			return nil, err
+		key, found := tb.wallet.keys[ctx.UnlockHash]
+		if !found {
+			return nil, errUnknownAddress
 		}
-		err = input.Fulfillment.Sign(types.FulfillmentSignContext{
-			ExtraObjects: []interface{}{uint64(ctx.InputIndex)},
-			Transaction:  tb.transaction,
-			Key:          sk,
-		})
+		err := key.sign(input.Fulfillment.Fulfillment, tb.transaction, []interface{}{uint64(ctx.InputIndex)})
 		if err != nil {
 			return nil, err
 		}
@@ -608,11 +595,7 @@ func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillme
 	case types.UnlockTypePubKey:
 		if key, exists := tb.wallet.keys[uh]; exists {
 			fulfillment.Fulfillment = types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(key.PublicKey))
-			err := fulfillment.Fulfillment.Sign(types.FulfillmentSignContext{
-				ExtraObjects: extraObjects,
-				Transaction:  tb.transaction,
-				Key:          key.SecretKey,
-			})
+			err := key.sign(fulfillment.Fulfillment, tb.transaction, extraObjects)
 			if err != nil {
 				return err
 			}
@@ -629,14 +612,34 @@ func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillme
 		}
 		for _, uh := range uhs {
 			if key, exists := tb.wallet.keys[uh]; exists {
-				err := fulfillment.Sign(types.FulfillmentSignContext{
-					ExtraObjects: extraObjects,
-					Transaction:  tb.transaction,
-					Key: types.KeyPair{
-						PublicKey:  types.Ed25519PublicKey(key.PublicKey),
-						PrivateKey: types.ByteSlice(key.SecretKey[:]),
-					},
-				})
+				err := key.sign(fulfillment.Fulfillment, tb.transaction, extraObjects)
+				if err != nil {
+					return err
+				}
+				tb.signed = true
+			}
+		}
+
+	case gctypes.UnlockTypeMultiSignatureAtomicSwap:
+		condition, ok := cond.(*gctypes.MultiSignatureAtomicSwapCondition)
+		if !ok {
+			return fmt.Errorf("unexpected condition type %T for multisig atomic swap condition", cond)
+		}
+		if fulfillment.FulfillmentType() == types.FulfillmentTypeNil {
+			// without a secret the contract can only be refunded by its sender
+			fulfillment.Fulfillment = &gctypes.MultiSignatureAtomicSwapFulfillment{}
+		}
+		f, ok := fulfillment.Fulfillment.(*gctypes.MultiSignatureAtomicSwapFulfillment)
+		if !ok {
+			return fmt.Errorf("unexpected fulfillment type %T for multisig atomic swap condition", fulfillment.Fulfillment)
+		}
+		signers := []types.UnlockHash{condition.Sender}
+		if f.Secret != (types.AtomicSwapSecret{}) {
+			signers = condition.Receivers
+		}
+		for _, uh := range signers {
+			if key, exists := tb.wallet.keys[uh]; exists {
+				err := key.sign(f, tb.transaction, extraObjects)
 				if err != nil {
 					return err
 				}
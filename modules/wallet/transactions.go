@@ -1,8 +1,10 @@
 package wallet
 
 import (
+	"bytes"
 	"errors"
 
+	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/types"
 
@@ -26,24 +28,12 @@ func (w *Wallet) AddressTransactions(uh types.UnlockHash) (pts []modules.Process
 		return
 	}
 
-	for _, pt := range w.processedTransactions {
-		relevant := false
-		for _, input := range pt.Inputs {
-			if input.RelatedAddress == uh {
-				relevant = true
-				break
-			}
-		}
-		for _, output := range pt.Outputs {
-			if output.RelatedAddress == uh {
-				relevant = true
-				break
-			}
-		}
-		if relevant {
+	err = w.db.View(func(tx *bolt.Tx) error {
+		return dbForEachAddressTransaction(tx, uh, nil, func(_ []byte, pt gcmodules.WalletProcessedTransaction) (bool, error) {
 			pts = append(pts, pt.AsRivineProcessedTransaction())
-		}
-	}
+			return true, nil
+		})
+	})
 	return
 }
 
@@ -89,9 +79,16 @@ func (w *Wallet) Transaction(txid types.TransactionID) (modules.ProcessedTransac
 	if !w.unlocked {
 		return modules.ProcessedTransaction{}, false, modules.ErrLockedWallet
 	}
-	pt, exists := w.processedTransactionMap[txid]
-	if !exists {
-		return modules.ProcessedTransaction{}, exists, nil
+	var (
+		pt     gcmodules.WalletProcessedTransaction
+		exists bool
+	)
+	err := w.db.View(func(tx *bolt.Tx) (err error) {
+		pt, exists, err = dbGetProcessedTransaction(tx, txid)
+		return
+	})
+	if err != nil || !exists {
+		return modules.ProcessedTransaction{}, false, err
 	}
 	return pt.AsRivineProcessedTransaction(), exists, nil
 }
@@ -107,54 +104,108 @@ func (w *Wallet) Transactions(startHeight, endHeight types.BlockHeight) (pts []m
 		return
 	}
 
-	if startHeight > w.consensusSetHeight || startHeight > endHeight {
-		return nil, errOutOfBounds
-	}
-	if len(w.processedTransactions) == 0 {
-		return nil, nil
+	wpts, _, err := w.processedTransactions(bucketProcessedTransactions, gcmodules.TransactionFilter{
+		StartHeight: startHeight,
+		EndHeight:   endHeight,
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	for _, pt := range w.processedTransactions {
-		if pt.ConfirmationHeight > endHeight {
-			break
-		}
-		if pt.ConfirmationHeight >= startHeight {
-			pts = append(pts, pt.AsRivineProcessedTransaction())
-		}
+	for _, pt := range wpts {
+		pts = append(pts, pt.AsRivineProcessedTransaction())
 	}
 	return pts, nil
 }
 
-// TransactionsWithCustodyInfo returns all transactions relevant to the wallet that were
-// confirmed in the range [startHeight, endHeight].
-func (w *Wallet) TransactionsWithCustodyInfo(startHeight, endHeight types.BlockHeight) (pts []gcmodules.ProcessedTransaction, err error) {
+// TransactionsWithCustodyInfo returns the transactions relevant to the wallet,
+// confirmed in the range [filter.StartHeight, filter.EndHeight], which match the given filter.
+// The transactions relevant to the watch-only addresses are returned instead if filter.WatchOnly is defined,
+// which does not require the wallet to be unlocked.
+// The returned cursor is only defined if more transactions match the filter than its limit.
+func (w *Wallet) TransactionsWithCustodyInfo(filter gcmodules.TransactionFilter) (pts []gcmodules.ProcessedTransaction, cursor string, err error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if !w.unlocked {
-		err = modules.ErrLockedWallet
-		return
+	bucket := bucketWatchOnlyProcessedTransactions
+	if !filter.WatchOnly {
+		if !w.unlocked {
+			err = modules.ErrLockedWallet
+			return
+		}
+		bucket = bucketProcessedTransactions
 	}
 
-	if startHeight > w.consensusSetHeight || startHeight > endHeight {
-		return nil, errOutOfBounds
+	wpts, cursor, err := w.processedTransactions(bucket, filter)
+	if err != nil {
+		return nil, "", err
 	}
-	if len(w.processedTransactions) == 0 {
-		return nil, nil
+	pts, err = w.processedTransactionsWithCustodyInfo(wpts)
+	return pts, cursor, err
+}
+
+// processedTransactions returns the processed transactions stored in the given bucket,
+// confirmed in the range [filter.StartHeight, filter.EndHeight], which match the given filter.
+// The returned cursor, pointing to the last returned transaction,
+// is only defined if more transactions match the filter than its limit.
+func (w *Wallet) processedTransactions(bucket []byte, filter gcmodules.TransactionFilter) (pts []gcmodules.WalletProcessedTransaction, cursor string, err error) {
+	if filter.StartHeight > w.consensusSetHeight || filter.StartHeight > filter.EndHeight {
+		return nil, "", errOutOfBounds
+	}
+	err = validateTransactionFilter(filter)
+	if err != nil {
+		return nil, "", types.NewClientError(err, types.ClientErrorBadRequest)
+	}
+	startKey := dbHistoryKey(filter.StartHeight, 0)
+	if filter.Cursor != "" {
+		cursorKey, err := decodeTransactionCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", types.NewClientError(err, types.ClientErrorBadRequest)
+		}
+		if bytes.Compare(cursorKey, startKey) >= 0 {
+			// continue right after the transaction the cursor points to
+			startKey = dbHistoryKey(dbHistoryKeyHeight(cursorKey), dbHistoryKeyPosition(cursorKey)+1)
+		}
 	}
 
+	var lastKey []byte
+	f := func(key []byte, pt gcmodules.WalletProcessedTransaction) (bool, error) {
+		if dbHistoryKeyHeight(key) > filter.EndHeight {
+			return false, nil
+		}
+		if !transactionMatchesFilter(filter, pt) {
+			return true, nil
+		}
+		if filter.Limit > 0 && uint64(len(pts)) == filter.Limit {
+			cursor = encodeTransactionCursor(lastKey)
+			return false, nil
+		}
+		pts = append(pts, pt)
+		lastKey = append(lastKey[:0], key...)
+		return true, nil
+	}
+	err = w.db.View(func(tx *bolt.Tx) error {
+		if filter.Address != nil && bytes.Equal(bucket, bucketProcessedTransactions) {
+			// use the address index, only available for the transactions of the wallet itself
+			return dbForEachAddressTransaction(tx, *filter.Address, startKey, f)
+		}
+		return dbForEachProcessedTransaction(tx.Bucket(bucket), startKey, filter.EndHeight, f)
+	})
+	return
+}
+
+// processedTransactionsWithCustodyInfo adds the custody fee information
+// to the coin outputs and inputs of the given processed transactions.
+func (w *Wallet) processedTransactionsWithCustodyInfo(wpts []gcmodules.WalletProcessedTransaction) (pts []gcmodules.ProcessedTransaction, err error) {
+	if len(wpts) == 0 {
+		return nil, nil
+	}
 	err = w.cfplugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
-		for _, pt := range w.processedTransactions {
-			if pt.ConfirmationHeight > endHeight {
-				break
-			}
-			if pt.ConfirmationHeight >= startHeight {
-				ept, err := pt.AsProcessedTransaction(view)
-				if err != nil {
-					return err
-				}
-				pts = append(pts, ept)
+		for _, pt := range wpts {
+			ept, err := pt.AsProcessedTransaction(view)
+			if err != nil {
+				return err
 			}
+			pts = append(pts, ept)
 		}
 		return nil
 	})
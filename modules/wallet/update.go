@@ -6,6 +6,7 @@ import (
 	"math"
 	"time"
 
+	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
 	rivinesync "github.com/threefoldtech/rivine/sync"
@@ -32,7 +33,46 @@ func (w *Wallet) subscribeWallet() error {
 			}
 		}()
 	}
-	err := w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	// Resume from the last consensus change processed by the wallet,
+	// such that only the blocks created since then have to be scanned.
+	var (
+		recentChange      modules.ConsensusChangeID
+		incompleteHistory bool
+	)
+	err := w.db.View(func(tx *bolt.Tx) error {
+		err := dbGetInternal(tx, internalRecentChange, &recentChange)
+		if err != nil {
+			return err
+		}
+		return dbGetInternal(tx, internalIncompleteHistory, &incompleteHistory)
+	})
+	if err != nil {
+		return err
+	}
+	w.mu.RLock()
+	keysLoaded := w.keysLoaded()
+	w.mu.RUnlock()
+	if incompleteHistory && keysLoaded {
+		// Consensus changes were processed while only the watch-only addresses were tracked,
+		// rescan the consensus set from the very first block such that the history of the keys is known as well.
+		w.log.Println("INFO: wallet history is incomplete, rescanning the consensus set")
+		err = w.resetDatabase()
+		if err != nil {
+			return err
+		}
+		recentChange = modules.ConsensusChangeBeginning
+	}
+	err = w.cs.ConsensusSetSubscribe(w, recentChange, w.tg.StopChan())
+	if err == modules.ErrInvalidConsensusChangeID {
+		// The consensus set no longer knows the last processed consensus change,
+		// rescan the consensus set from the very first block instead.
+		w.log.Println("WARN: unknown wallet consensus change ID, rescanning the consensus set")
+		err = w.resetDatabase()
+		if err != nil {
+			return err
+		}
+		err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	}
 	if err != nil {
 		return errors.New("wallet subscription failed: " + err.Error())
 	}
@@ -40,9 +80,29 @@ func (w *Wallet) subscribeWallet() error {
 	return nil
 }
 
+// managedSubscribe subscribes the wallet to the consensus set and transaction pool.
+func (w *Wallet) managedSubscribe() error {
+	err := w.subscribeWallet()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.subscribed = true
+	w.mu.Unlock()
+	return nil
+}
+
+// keysLoaded returns true if the keys of the wallet's seeds are loaded,
+// which is the case once the wallet is unlocked for the first time, or if it has no seed at all.
+// The wallet only tracks its watch-only addresses as long as this is not the case.
+func (w *Wallet) keysLoaded() bool {
+	return w.persist.PrimarySeedFile.UID == (UniqueID{}) || len(w.keys) != 0
+}
+
 // updateConfirmedSet uses a consensus change to update the confirmed set of
 // outputs as understood by the wallet.
 func (w *Wallet) updateConfirmedSet(cc modules.ConsensusChange) {
+	w.markUsedKeys(cc)
 	for _, diff := range cc.CoinOutputDiffs {
 		// Verify that the diff is relevant to the wallet.
 		if _, exists := w.keys[diff.CoinOutput.Condition.UnlockHash()]; exists {
@@ -60,6 +120,22 @@ func (w *Wallet) updateConfirmedSet(cc modules.ConsensusChange) {
 			}
 			continue
 		}
+		// Verify if the diff is relevant to a watch-only address of the wallet.
+		if _, exists := w.watchOnlyAddresses[diff.CoinOutput.Condition.UnlockHash()]; exists {
+			_, exists = w.watchOnlyCoinOutputs[diff.ID]
+			if diff.Direction == modules.DiffApply {
+				if exists {
+					build.Severe("adding an existing watch-only output to wallet")
+				}
+				w.watchOnlyCoinOutputs[diff.ID] = diff.CoinOutput
+			} else {
+				if !exists {
+					build.Severe("deleting nonexisting watch-only output from wallet")
+				}
+				delete(w.watchOnlyCoinOutputs, diff.ID)
+			}
+			continue
+		}
 
 		// try to get the unlock hash slice of a multisig
 		unlockhashes, _ := getMultisigConditionProperties(diff.CoinOutput.Condition.Condition)
@@ -103,6 +179,22 @@ func (w *Wallet) updateConfirmedSet(cc modules.ConsensusChange) {
 			}
 			continue
 		}
+		// Verify if the diff is relevant to a watch-only address of the wallet.
+		if _, exists := w.watchOnlyAddresses[diff.BlockStakeOutput.Condition.UnlockHash()]; exists {
+			_, exists = w.watchOnlyBlockStakeOutputs[diff.ID]
+			if diff.Direction == modules.DiffApply {
+				if exists {
+					build.Severe("adding an existing watch-only output to wallet")
+				}
+				w.watchOnlyBlockStakeOutputs[diff.ID] = diff.BlockStakeOutput
+			} else {
+				if !exists {
+					build.Severe("deleting nonexisting watch-only output from wallet")
+				}
+				delete(w.watchOnlyBlockStakeOutputs, diff.ID)
+			}
+			continue
+		}
 
 		// try to get the unlock hash slice of a multisig
 		unlockhashes, _ := getMultisigConditionProperties(diff.BlockStakeOutput.Condition.Condition)
@@ -156,38 +248,26 @@ func getMultisigConditionProperties(condition types.MarshalableUnlockCondition)
 
 // revertHistory reverts any transaction history that was destroyed by reverted
 // blocks in the consensus change.
-func (w *Wallet) revertHistory(cc modules.ConsensusChange) {
-	for _, block := range cc.RevertedBlocks {
-		// Remove any transactions that have been reverted.
-		for i := len(block.Transactions) - 1; i >= 0; i-- {
-			// If the transaction is relevant to the wallet, it will be the
-			// most recent transaction appended to w.processedTransactions.
-			// Relevance can be determined just by looking at the last element
-			// of w.processedTransactions.
-			txn := block.Transactions[i]
-			txid := txn.ID()
-			if len(w.processedTransactions) > 0 && txid == w.processedTransactions[len(w.processedTransactions)-1].TransactionID {
-				w.processedTransactions = w.processedTransactions[:len(w.processedTransactions)-1]
-				delete(w.processedTransactionMap, txid)
-			}
+func (w *Wallet) revertHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
+	for range cc.RevertedBlocks {
+		// Remove all transactions, including the miner payouts,
+		// that were confirmed as part of the reverted block.
+		err := dbRevertProcessedTransactions(tx, w.consensusSetHeight)
+		if err != nil {
+			return err
 		}
-
-		// Remove the miner payout transaction if applicable.
-		for _, mp := range block.MinerPayouts {
-			_, exists := w.keys[mp.UnlockHash]
-			if exists {
-				w.processedTransactions = w.processedTransactions[:len(w.processedTransactions)-1]
-				delete(w.processedTransactionMap, types.TransactionID(block.ID()))
-				break
-			}
+		err = revertAtomicSwapContracts(tx, w.consensusSetHeight)
+		if err != nil {
+			return err
 		}
 		w.consensusSetHeight--
 	}
+	return nil
 }
 
 // applyHistory applies any transaction history that was introduced by the
 // applied blocks.
-func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
+func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 	for _, block := range cc.AppliedBlocks {
 		w.consensusSetHeight++
 		// Apply the miner payout transaction if applicable.
@@ -212,14 +292,17 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 				Value:          mp.Value,
 				OutputID:       outputID,
 			})
-			w.historicOutputs[outputID] = historicOutput{
+			err := dbAddHistoricOutput(tx, outputID, historicOutput{
 				UnlockHash: mp.UnlockHash,
 				Value:      mp.Value,
+			})
+			if err != nil {
+				return err
 			}
 		}
-		if relevant {
-			w.processedTransactions = append(w.processedTransactions, minerPT)
-			w.processedTransactionMap[minerPT.TransactionID] = &w.processedTransactions[len(w.processedTransactions)-1]
+		err := w.addProcessedTransaction(tx, dbHistoryKey(w.consensusSetHeight, 0), minerPT, relevant)
+		if err != nil {
+			return err
 		}
 
 		blockheight, blockexists := w.cs.BlockHeightOfBlock(block)
@@ -237,7 +320,10 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 			}
 			for _, sci := range txn.CoinInputs {
 				parentOutputID := types.OutputID(sci.ParentID)
-				output := w.historicOutputs[parentOutputID]
+				output, err := dbGetHistoricOutput(tx, parentOutputID)
+				if err != nil {
+					return err
+				}
 				_, exists := w.keys[output.UnlockHash]
 				if exists {
 					relevant = true
@@ -278,14 +364,20 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 					Value:          sco.Value,
 					OutputID:       outputID,
 				})
-				w.historicOutputs[outputID] = historicOutput{
+				err := dbAddHistoricOutput(tx, outputID, historicOutput{
 					UnlockHash: uh,
 					Value:      sco.Value,
+				})
+				if err != nil {
+					return err
 				}
 			}
 			for _, sfi := range txn.BlockStakeInputs {
 				parentOutputID := types.OutputID(sfi.ParentID)
-				output := w.historicOutputs[parentOutputID]
+				output, err := dbGetHistoricOutput(tx, parentOutputID)
+				if err != nil {
+					return err
+				}
 				_, exists := w.keys[output.UnlockHash]
 				if exists {
 					relevant = true
@@ -339,19 +431,43 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 						Condition: sfo.Condition,
 					}
 				}
-				w.historicOutputs[outputID] = historicOutput{
+				err := dbAddHistoricOutput(tx, outputID, historicOutput{
 					UnlockHash: uh,
 					Value:      sfo.Value,
+				})
+				if err != nil {
+					return err
 				}
 			}
-			if relevant {
-				w.processedTransactions = append(w.processedTransactions, pt)
-				w.processedTransactionMap[pt.TransactionID] = &w.processedTransactions[len(w.processedTransactions)-1]
+			err = w.addProcessedTransaction(tx, dbHistoryKey(w.consensusSetHeight, uint64(ti)+1), pt, relevant)
+			if err != nil {
+				return err
+			}
+			err = w.updateAtomicSwapContracts(tx, txn, w.consensusSetHeight)
+			if err != nil {
+				return err
 			}
 		}
 	}
 	// Reset spent outputs map
 	w.spentOutputs = make(map[types.OutputID]types.BlockHeight)
+	return nil
+}
+
+// addProcessedTransaction stores a confirmed transaction in the transaction history of the wallet,
+// if it is relevant to the wallet, as well as in the transaction history of its watch-only addresses,
+// if it is relevant to any of them.
+func (w *Wallet) addProcessedTransaction(tx *bolt.Tx, key []byte, pt gcmodules.WalletProcessedTransaction, relevant bool) error {
+	if relevant {
+		err := dbAddProcessedTransaction(tx, key, pt)
+		if err != nil {
+			return err
+		}
+	}
+	if wopt, ok := w.watchOnlyProcessedTransaction(pt); ok {
+		return dbPut(tx.Bucket(bucketWatchOnlyProcessedTransactions), key, wopt)
+	}
+	return nil
 }
 
 // ProcessConsensusChange parses a consensus change to update the set of
@@ -366,9 +482,43 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 	defer w.tg.Done()
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.updateConfirmedSet(cc)
-	w.revertHistory(cc)
-	w.applyHistory(cc)
+	err := w.db.Update(func(tx *bolt.Tx) error {
+		w.updateConfirmedSet(cc)
+		err := w.revertHistory(tx, cc)
+		if err != nil {
+			return err
+		}
+		err = w.applyHistory(tx, cc)
+		if err != nil {
+			return err
+		}
+		err = w.dbUpdateConfirmedSet(tx, cc)
+		if err != nil {
+			return err
+		}
+		err = dbSetInternal(tx, internalBlockHeight, w.consensusSetHeight)
+		if err != nil {
+			return err
+		}
+		if !w.keysLoaded() || w.keysIncomplete {
+			// the outputs and transactions of the keys are missed,
+			// and are recovered by rescanning once the keys are loaded
+			err = dbSetInternal(tx, internalIncompleteHistory, true)
+			if err != nil {
+				return err
+			}
+			w.keysIncomplete = false
+		}
+		return dbSetInternal(tx, internalRecentChange, cc.ID)
+	})
+	if err != nil {
+		build.Critical("wallet update failed:", err)
+	}
+	// spend the tracked atomic swap contracts which became redeemable or refundable,
+	// once the consensus set is synced, such that they aren't spent based on an outdated state
+	if cc.Synced {
+		w.notifyAtomicSwapWatcher()
+	}
 }
 
 // ReceiveUpdatedUnconfirmedTransactions updates the wallet's unconfirmed
@@ -386,80 +536,98 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(txns []types.Transaction,
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	w.unconfirmedProcessedTransactions = nil
-	for _, txn := range txns {
-		// To save on code complexity, relevancy is determined while building
-		// up the wallet transaction.
-		relevant := false
-		pt := gcmodules.WalletProcessedTransaction{
-			Transaction:           txn,
-			TransactionID:         txn.ID(),
-			ConfirmationHeight:    types.BlockHeight(math.MaxUint64),
-			ConfirmationTimestamp: types.Timestamp(math.MaxUint64),
+	// The outputs created by unconfirmed transactions are only tracked
+	// for the duration of this update, as they can be spent by other unconfirmed transactions.
+	unconfirmedOutputs := make(map[types.OutputID]historicOutput)
+	getHistoricOutput := func(tx *bolt.Tx, id types.OutputID) (historicOutput, error) {
+		if output, ok := unconfirmedOutputs[id]; ok {
+			return output, nil
 		}
-		for _, sci := range txn.CoinInputs {
-			parentOutputID := types.OutputID(sci.ParentID)
-			output := w.historicOutputs[parentOutputID]
-			_, exists := w.keys[output.UnlockHash]
-			if exists {
-				relevant = true
-				// Add the outputid and height to spentoutputs map in wallet
-				w.spentOutputs[parentOutputID] = pt.ConfirmationHeight
-			} else if _, exists = w.multiSigCoinOutputs[sci.ParentID]; exists {
-				// Since we know about every multisig output that is still open and releated,
-				// any relevant multisig input must have a parent ID present in the multisig
-				// output map.
-				relevant = true
-				// set "exists" to false since the output is not owned by the wallet.
-				exists = false
+		return dbGetHistoricOutput(tx, id)
+	}
+
+	w.unconfirmedProcessedTransactions = nil
+	return w.db.View(func(tx *bolt.Tx) error {
+		for _, txn := range txns {
+			// To save on code complexity, relevancy is determined while building
+			// up the wallet transaction.
+			relevant := false
+			pt := gcmodules.WalletProcessedTransaction{
+				Transaction:           txn,
+				TransactionID:         txn.ID(),
+				ConfirmationHeight:    types.BlockHeight(math.MaxUint64),
+				ConfirmationTimestamp: types.Timestamp(math.MaxUint64),
 			}
-			pt.Inputs = append(pt.Inputs, gcmodules.WalletProcessedInput{
-				FundType:       types.SpecifierCoinInput,
-				WalletAddress:  exists,
-				RelatedAddress: output.UnlockHash,
-				Value:          output.Value,
-				ParentOutputID: parentOutputID,
-			})
-		}
-		for i, sco := range txn.CoinOutputs {
-			outputID := types.OutputID(txn.CoinOutputID(uint64(i)))
-			uh := sco.Condition.UnlockHash()
-			_, exists := w.keys[uh]
-			if exists {
-				relevant = true
-			} else if _, exists = w.multiSigCoinOutputs[types.CoinOutputID(outputID)]; exists {
-				// If the coin output is a relevant multisig output, it's ID will already
-				// be present in the multisigCoinOutputs map
-				relevant = true
-				// set "exists" to false since the output is not owned by the wallet.
-				exists = false
+			for _, sci := range txn.CoinInputs {
+				parentOutputID := types.OutputID(sci.ParentID)
+				output, err := getHistoricOutput(tx, parentOutputID)
+				if err != nil {
+					return err
+				}
+				_, exists := w.keys[output.UnlockHash]
+				if exists {
+					relevant = true
+					// Add the outputid and height to spentoutputs map in wallet
+					w.spentOutputs[parentOutputID] = pt.ConfirmationHeight
+				} else if _, exists = w.multiSigCoinOutputs[sci.ParentID]; exists {
+					// Since we know about every multisig output that is still open and releated,
+					// any relevant multisig input must have a parent ID present in the multisig
+					// output map.
+					relevant = true
+					// set "exists" to false since the output is not owned by the wallet.
+					exists = false
+				}
+				pt.Inputs = append(pt.Inputs, gcmodules.WalletProcessedInput{
+					FundType:       types.SpecifierCoinInput,
+					WalletAddress:  exists,
+					RelatedAddress: output.UnlockHash,
+					Value:          output.Value,
+					ParentOutputID: parentOutputID,
+				})
 			}
-			pt.Outputs = append(pt.Outputs, gcmodules.WalletProcessedOutput{
-				FundType:       types.SpecifierCoinOutput,
-				MaturityHeight: types.BlockHeight(math.MaxUint64),
-				WalletAddress:  exists,
-				RelatedAddress: uh,
-				Value:          sco.Value,
-				OutputID:       outputID,
-			})
-			w.historicOutputs[outputID] = historicOutput{
-				UnlockHash: uh,
-				Value:      sco.Value,
+			for i, sco := range txn.CoinOutputs {
+				outputID := types.OutputID(txn.CoinOutputID(uint64(i)))
+				uh := sco.Condition.UnlockHash()
+				_, exists := w.keys[uh]
+				if exists {
+					relevant = true
+				} else if _, exists = w.multiSigCoinOutputs[types.CoinOutputID(outputID)]; exists {
+					// If the coin output is a relevant multisig output, it's ID will already
+					// be present in the multisigCoinOutputs map
+					relevant = true
+					// set "exists" to false since the output is not owned by the wallet.
+					exists = false
+				}
+				pt.Outputs = append(pt.Outputs, gcmodules.WalletProcessedOutput{
+					FundType:       types.SpecifierCoinOutput,
+					MaturityHeight: types.BlockHeight(math.MaxUint64),
+					WalletAddress:  exists,
+					RelatedAddress: uh,
+					Value:          sco.Value,
+					OutputID:       outputID,
+				})
+				unconfirmedOutputs[outputID] = historicOutput{
+					UnlockHash: uh,
+					Value:      sco.Value,
+				}
 			}
-		}
-		for _, bsi := range txn.BlockStakeInputs {
-			parentOutputID := types.OutputID(bsi.ParentID)
-			output := w.historicOutputs[parentOutputID]
-			_, exists := w.keys[output.UnlockHash]
-			if exists {
-				relevant = true
-				// Add the outputid and height to spentoutputs map in wallet
-				w.spentOutputs[parentOutputID] = pt.ConfirmationHeight
+			for _, bsi := range txn.BlockStakeInputs {
+				parentOutputID := types.OutputID(bsi.ParentID)
+				output, err := getHistoricOutput(tx, parentOutputID)
+				if err != nil {
+					return err
+				}
+				_, exists := w.keys[output.UnlockHash]
+				if exists {
+					relevant = true
+					// Add the outputid and height to spentoutputs map in wallet
+					w.spentOutputs[parentOutputID] = pt.ConfirmationHeight
+				}
+			}
+			if relevant {
+				w.unconfirmedProcessedTransactions = append(w.unconfirmedProcessedTransactions, pt)
 			}
 		}
-		if relevant {
-			w.unconfirmedProcessedTransactions = append(w.unconfirmedProcessedTransactions, pt)
-		}
-	}
-	return nil
+		return nil
+	})
 }
@@ -18,6 +18,8 @@ import (
 
 	"github.com/nbh-digital/goldchain/extensions/custodyfees"
 	gcmodules "github.com/nbh-digital/goldchain/modules"
+	"github.com/nbh-digital/goldchain/pkg/hdkeys"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
 const (
@@ -33,6 +35,7 @@ var (
 	errNilTpool             = errors.New("wallet cannot initialize with a nil transaction pool")
 	errNilCustodyFeesPlugin = errors.New("wallet cannot initialize with a nil Custody Fees plugin")
 	errUnknownAddress       = errors.New("given wallet address is not known")
+	errHDKeyExport          = errors.New("HD derived keys cannot be exported as an ed25519 secret key")
 )
 
 // spendableKey is a set of secret keys plus the corresponding unlock
@@ -44,13 +47,86 @@ type spendableKey struct {
 	PublicKey crypto.PublicKey
 	SecretKey crypto.SecretKey
 	Index     uint64
+	// SeedIndex is the index of the seed that derived this key, in the wallet's seed slice
+	SeedIndex int
+	// HDChain is only defined for keys derived using HD derivation,
+	// in which case Index is the index of the key within that chain
+	HDChain *hdChain
+	// HDKey is only defined for keys derived using HD derivation,
+	// in which case it is used to sign instead of SecretKey
+	HDKey *hdkeys.ExtendedPrivateKey
 }
 
 func (sk spendableKey) WipeSecret() spendableKey {
 	crypto.SecureWipe(sk.SecretKey[:])
+	if sk.HDKey != nil {
+		sk.HDKey.Wipe()
+	}
 	return sk
 }
 
+// sign signs the given fulfillment of the given transaction using this key.
+// Keys derived using HD derivation are not regular ed25519 keys,
+// which is why those sign the signature hash, as defined by the fulfillment, directly.
+func (sk spendableKey) sign(fulfillment types.UnlockFulfillment, txn types.Transaction, extraObjects []interface{}) error {
+	pk := types.Ed25519PublicKey(sk.PublicKey)
+	if sk.HDKey == nil {
+		ctx := types.FulfillmentSignContext{
+			ExtraObjects: extraObjects,
+			Transaction:  txn,
+			Key:          sk.SecretKey,
+		}
+		switch fulfillment.(type) {
+		case *types.MultiSignatureFulfillment, *gctypes.MultiSignatureAtomicSwapFulfillment:
+			ctx.Key = types.KeyPair{
+				PublicKey:  pk,
+				PrivateKey: types.ByteSlice(sk.SecretKey[:]),
+			}
+		}
+		return fulfillment.Sign(ctx)
+	}
+
+	signHash := func(objects ...interface{}) (types.ByteSlice, error) {
+		sigHash, err := txn.SignatureHash(append(append([]interface{}{}, extraObjects...), objects...)...)
+		if err != nil {
+			return nil, err
+		}
+		return sk.HDKey.Sign(sigHash[:]), nil
+	}
+	var err error
+	switch f := fulfillment.(type) {
+	case *types.SingleSignatureFulfillment:
+		if len(f.Signature) != 0 {
+			return types.ErrFulfillmentDoubleSign
+		}
+		f.Signature, err = signHash()
+	case *types.AtomicSwapFulfillment:
+		if len(f.Signature) != 0 {
+			return types.ErrFulfillmentDoubleSign
+		}
+		if f.Secret != (types.AtomicSwapSecret{}) {
+			f.Signature, err = signHash(f.PublicKey, f.Secret)
+		} else {
+			f.Signature, err = signHash(f.PublicKey)
+		}
+	case *types.MultiSignatureFulfillment:
+		var signature types.ByteSlice
+		signature, err = signHash(pk)
+		if err == nil {
+			f.Pairs = append(f.Pairs, types.PublicKeySignaturePair{PublicKey: pk, Signature: signature})
+		}
+	case *gctypes.MultiSignatureAtomicSwapFulfillment:
+		var signature types.ByteSlice
+		signature, err = signHash(f.SignatureObjects(nil, pk)...)
+		if err == nil {
+			f.Pairs = append(f.Pairs, types.PublicKeySignaturePair{PublicKey: pk, Signature: signature})
+		}
+	default:
+		err = fmt.Errorf("cannot sign fulfillment of type %T using an HD key", fulfillment)
+	}
+	return err
+}
+
 func (sk spendableKey) UnlockHash() (types.UnlockHash, error) {
 	return types.NewEd25519PubKeyUnlockHash(sk.PublicKey)
 }
@@ -87,35 +163,50 @@ type Wallet struct {
 	// coinOutputs, blockstakeOutputs, and spentOutputs are kept so that they
 	// can be scanned when trying to fund transactions.
 	seeds                    []modules.Seed
+	legacySeeds              []*legacySeedKeys
+	hdSeeds                  []*hdSeed
 	keys                     map[types.UnlockHash]spendableKey
+	usedAddresses            map[crypto.PublicKey]struct{}
 	coinOutputs              map[types.CoinOutputID]types.CoinOutput
 	blockstakeOutputs        map[types.BlockStakeOutputID]types.BlockStakeOutput
 	unspentblockstakeoutputs map[types.BlockStakeOutputID]types.UnspentBlockStakeOutput
 	spentOutputs             map[types.OutputID]types.BlockHeight
 
+	// keysIncomplete is set when keys got used past the loaded keys while the wallet was locked,
+	// such that the wallet history is marked as incomplete by the consensus change that used them
+	keysIncomplete bool
+
 	// multiSigOutputs holds all the multisig addresses this wallet is part of
 	multiSigCoinOutputs       map[types.CoinOutputID]types.CoinOutput
 	multiSigBlockStakeOutputs map[types.BlockStakeOutputID]types.BlockStakeOutput
 
-	// The following fields are kept to track transaction history.
-	// processedTransactions are stored in chronological order, and have a map for
-	// constant time random access. The set of full transactions is kept as
-	// well, ordering can be determined by the processedTransactions slice.
-	//
-	// The unconfirmed transactions are kept the same way, except without the
-	// random access. It is assumed that the list of unconfirmed transactions
-	// will be small enough that this will not be a problem.
+	// watchOnlyAddresses holds all addresses tracked by this wallet
+	// for which it does not own the keys. The outputs and transactions
+	// of these addresses are tracked separately from the spendable ones.
+	watchOnlyAddresses         map[types.UnlockHash]gcmodules.WatchOnlyAddress
+	watchOnlyCoinOutputs       map[types.CoinOutputID]types.CoinOutput
+	watchOnlyBlockStakeOutputs map[types.BlockStakeOutputID]types.BlockStakeOutput
+
+	// addressLabels holds the labels, notes and tags of addresses,
+	// either owned by the wallet or used as a counterparty
+	addressLabels map[types.UnlockHash]gcmodules.AddressLabel
+
+	// The confirmed transactions relevant to the wallet, and its watch-only addresses,
+	// are stored in the wallet database, indexed by confirmation height, transaction ID and address.
+	// The addresses and values of all outputs seen by the wallet are stored as well,
+	// such that the values and addresses of transaction inputs can be determined.
+	// The confirmed set of outputs is mirrored in the database, such that the wallet
+	// only has to process the blocks created since it was last running.
 	//
-	// historicOutputs is kept so that the values and addresses of transaction inputs can be
-	// determined. historicOutputs is never cleared, but in general should be
-	// small compared to the list of transactions.
-	processedTransactions            []gcmodules.WalletProcessedTransaction
-	processedTransactionMap          map[types.TransactionID]*gcmodules.WalletProcessedTransaction
+	// The unconfirmed transactions are kept in memory only. It is assumed that
+	// the list of unconfirmed transactions will be small enough that this will not be a problem.
+	db                               *persist.BoltDatabase
 	unconfirmedProcessedTransactions []gcmodules.WalletProcessedTransaction
 
-	// TODO: Storing the whole set of historic outputs is expensive and
-	// unnecessary. There's a better way to do it.
-	historicOutputs map[types.OutputID]historicOutput
+	// atomicSwapWatch notifies the atomic swap watcher that the tracked atomic swap contracts
+	// might have become redeemable or refundable, pending notifications being coalesced,
+	// such that the contracts are only ever spent by a single goroutine.
+	atomicSwapWatch chan struct{}
 
 	persistDir string
 	log        *persist.Logger
@@ -155,6 +246,7 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, plugin *custody
 		tpool:                     tpool,
 		cfplugin:                  plugin,
 		keys:                      make(map[types.UnlockHash]spendableKey),
+		usedAddresses:             make(map[crypto.PublicKey]struct{}),
 		coinOutputs:               make(map[types.CoinOutputID]types.CoinOutput),
 		blockstakeOutputs:         make(map[types.BlockStakeOutputID]types.BlockStakeOutput),
 		spentOutputs:              make(map[types.OutputID]types.BlockHeight),
@@ -162,9 +254,12 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, plugin *custody
 		multiSigCoinOutputs:       make(map[types.CoinOutputID]types.CoinOutput),
 		multiSigBlockStakeOutputs: make(map[types.BlockStakeOutputID]types.BlockStakeOutput),
 
-		processedTransactionMap: make(map[types.TransactionID]*gcmodules.WalletProcessedTransaction),
+		watchOnlyAddresses:         make(map[types.UnlockHash]gcmodules.WatchOnlyAddress),
+		watchOnlyCoinOutputs:       make(map[types.CoinOutputID]types.CoinOutput),
+		watchOnlyBlockStakeOutputs: make(map[types.BlockStakeOutputID]types.BlockStakeOutput),
+		addressLabels:              make(map[types.UnlockHash]gcmodules.AddressLabel),
 
-		historicOutputs: make(map[types.OutputID]historicOutput),
+		atomicSwapWatch: make(chan struct{}, 1),
 
 		persistDir: persistDir,
 
@@ -175,6 +270,7 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, plugin *custody
 	if err != nil {
 		return nil, err
 	}
+	go w.threadedAtomicSwapWatcher()
 	return w, nil
 }
 
@@ -206,6 +302,9 @@ func (w *Wallet) Close() error {
 	w.cs.Unsubscribe(w)
 	w.tpool.Unsubscribe(w)
 
+	if err := w.db.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("db.Close failed: %v", err))
+	}
 	if err := w.log.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("log.Close failed: %v", err))
 	}
@@ -279,6 +378,9 @@ func (w *Wallet) getKey(address types.UnlockHash) (types.PublicKey, types.ByteSl
 	if !found {
 		return types.PublicKey{}, types.ByteSlice{}, errUnknownAddress
 	}
+	if sp.HDKey != nil {
+		return types.PublicKey{}, types.ByteSlice{}, errHDKeyExport
+	}
 	return types.Ed25519PublicKey(sp.PublicKey), types.ByteSlice(sp.SecretKey[:]), nil
 }
 func (w *Wallet) keyExists(address types.UnlockHash) (bool, error) {
@@ -630,6 +630,12 @@ func (css *consensusSetStub) ConsensusSetSubscribe(subscriber modules.ConsensusS
 				break
 			}
 		}
+		if i == len(css.blocks) {
+			delete(css.subscribers, subscriber)
+			return modules.ErrInvalidConsensusChangeID
+		}
+		// resume from the block following the given consensus change
+		i++
 	}
 	for _, block := range css.blocks[i:] {
 		select {
@@ -0,0 +1,369 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+var (
+	errNilWatchOnlyAddress         = errors.New("nil address cannot be watched")
+	errOwnedWatchOnlyAddress       = errors.New("address is owned by the wallet and cannot be watched")
+	errWatchOnlyPublicKeyMismatch  = errors.New("public key does not match the given watch-only address")
+	errUnknownWatchOnlyAddress     = errors.New("given watch-only address is not known")
+	errArbitraryDataTooLarge       = errors.New("arbitrary data too large")
+	errUnsupportedWatchOnlyAddress = errors.New("only public key and multisig addresses can be watched")
+)
+
+// WatchOnlyAddresses returns all addresses tracked by this wallet,
+// for which the wallet does not own the keys required to spend from them.
+// Addresses are returned sorted in byte-order.
+func (w *Wallet) WatchOnlyAddresses() ([]gcmodules.WatchOnlyAddress, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	addresses := make([]gcmodules.WatchOnlyAddress, 0, len(w.watchOnlyAddresses))
+	for _, woa := range w.watchOnlyAddresses {
+		addresses = append(addresses, woa)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Address.Cmp(addresses[j].Address) < 0
+	})
+	return addresses, nil
+}
+
+// AddWatchOnlyAddresses adds the given addresses as watch-only addresses to this wallet.
+// If a public key is given for an address, the address can be omitted, as it will be derived from that key.
+// Addresses that are already watched are ignored, unless a public key is given where none was known before.
+//
+// The consensus set is rescanned in case new addresses were added,
+// such that the history of these addresses is known to the wallet as well.
+// The wallet does not have to be unlocked, nor to have a seed, to track watch-only addresses.
+// While the keys of the wallet are not loaded, it cannot refuse addresses it owns,
+// in which case these are tracked as owned addresses once the keys are loaded.
+func (w *Wallet) AddWatchOnlyAddresses(addresses []gcmodules.WatchOnlyAddress) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	var (
+		added      bool
+		subscribed bool
+	)
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		// validate all addresses prior to adding any of them
+		for idx := range addresses {
+			err := w.validateWatchOnlyAddress(&addresses[idx])
+			if err != nil {
+				return fmt.Errorf("invalid watch-only address #%d: %v", idx+1, err)
+			}
+		}
+		var updated bool
+		for _, woa := range addresses {
+			known, exists := w.watchOnlyAddresses[woa.Address]
+			if !exists {
+				w.watchOnlyAddresses[woa.Address] = woa
+				added, updated = true, true
+				continue
+			}
+			if known.PublicKey == nil && woa.PublicKey != nil {
+				w.watchOnlyAddresses[woa.Address] = woa
+				updated = true
+			}
+		}
+		if !updated {
+			return nil
+		}
+		subscribed = w.subscribed
+		return w.saveWatchOnlyAddresses()
+	}()
+	if err != nil || !added {
+		return err
+	}
+	if subscribed {
+		return w.managedRescan()
+	}
+	// Start tracking the watch-only addresses from the very first block,
+	// dropping any history processed during a previous subscription.
+	err = w.resetDatabase()
+	if err != nil {
+		return err
+	}
+	return w.managedSubscribe()
+}
+
+// validateWatchOnlyAddress validates the given watch-only address,
+// deriving the address from the public key if no address was given.
+func (w *Wallet) validateWatchOnlyAddress(woa *gcmodules.WatchOnlyAddress) error {
+	if woa.PublicKey != nil {
+		uh, err := types.NewPubKeyUnlockHash(*woa.PublicKey)
+		if err != nil {
+			return err
+		}
+		if woa.Address == (types.UnlockHash{}) {
+			woa.Address = uh
+		} else if woa.Address.Cmp(uh) != 0 {
+			return errWatchOnlyPublicKeyMismatch
+		}
+	}
+	switch woa.Address.Type {
+	case types.UnlockTypeNil:
+		return errNilWatchOnlyAddress
+	case types.UnlockTypePubKey, types.UnlockTypeMultiSig:
+	default:
+		return errUnsupportedWatchOnlyAddress
+	}
+	if _, exists := w.keys[woa.Address]; exists {
+		return errOwnedWatchOnlyAddress
+	}
+	return nil
+}
+
+// RemoveWatchOnlyAddresses removes the given addresses from the watch-only addresses of this wallet,
+// deleting the outputs of these addresses from the confirmed set of the wallet,
+// as well as the watch-only transactions which are no longer relevant to any of the remaining watch-only addresses.
+//
+// The consensus set is only rescanned in case a removed address is a multisig address co-signed by the wallet,
+// as the wallet did not track the outputs of such an address as its own multisig outputs while it was watched.
+func (w *Wallet) RemoveWatchOnlyAddresses(addresses []types.UnlockHash) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	var rescan bool
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		for _, uh := range addresses {
+			if _, exists := w.watchOnlyAddresses[uh]; !exists {
+				return errUnknownWatchOnlyAddress
+			}
+		}
+		removed := make(map[types.UnlockHash]struct{}, len(addresses))
+		for _, uh := range addresses {
+			delete(w.watchOnlyAddresses, uh)
+			removed[uh] = struct{}{}
+		}
+		err := w.saveWatchOnlyAddresses()
+		if err != nil || len(removed) == 0 {
+			return err
+		}
+		if w.subscribed && w.coSignsWatchOnlyOutputs(removed) {
+			rescan = true
+			return nil
+		}
+		return w.db.Update(func(tx *bolt.Tx) error {
+			return w.dbRemoveWatchOnlyHistory(tx, removed)
+		})
+	}()
+	if err != nil || !rescan {
+		return err
+	}
+	return w.managedRescan()
+}
+
+// coSignsWatchOnlyOutputs returns true if any of the watch-only outputs of the given addresses
+// is a multisig output of which the wallet owns one of the co-signing keys.
+func (w *Wallet) coSignsWatchOnlyOutputs(addresses map[types.UnlockHash]struct{}) bool {
+	coSigns := func(condition types.UnlockConditionProxy) bool {
+		if _, ok := addresses[condition.UnlockHash()]; !ok {
+			return false
+		}
+		unlockhashes, _ := getMultisigConditionProperties(condition.Condition)
+		for _, uh := range unlockhashes {
+			if _, exists := w.keys[uh]; exists {
+				return true
+			}
+		}
+		return false
+	}
+	for _, co := range w.watchOnlyCoinOutputs {
+		if coSigns(co.Condition) {
+			return true
+		}
+	}
+	for _, bso := range w.watchOnlyBlockStakeOutputs {
+		if coSigns(bso.Condition) {
+			return true
+		}
+	}
+	return false
+}
+
+// dbRemoveWatchOnlyHistory deletes the watch-only outputs of the given addresses,
+// both in memory and in the database, and updates the watch-only transaction history
+// to the current watch-only addresses of the wallet, deleting the transactions no longer relevant to any of them.
+func (w *Wallet) dbRemoveWatchOnlyHistory(tx *bolt.Tx, addresses map[types.UnlockHash]struct{}) error {
+	coBucket := tx.Bucket(bucketWatchOnlyCoinOutputs)
+	for id, co := range w.watchOnlyCoinOutputs {
+		if _, ok := addresses[co.Condition.UnlockHash()]; !ok {
+			continue
+		}
+		delete(w.watchOnlyCoinOutputs, id)
+		err := coBucket.Delete(id[:])
+		if err != nil {
+			return err
+		}
+	}
+	bsoBucket := tx.Bucket(bucketWatchOnlyBlockStakeOutputs)
+	for id, bso := range w.watchOnlyBlockStakeOutputs {
+		if _, ok := addresses[bso.Condition.UnlockHash()]; !ok {
+			continue
+		}
+		delete(w.watchOnlyBlockStakeOutputs, id)
+		err := bsoBucket.Delete(id[:])
+		if err != nil {
+			return err
+		}
+	}
+
+	// the bucket cannot be modified while iterating over it,
+	// hence the updates are collected first
+	var (
+		keys    [][]byte
+		updates []*gcmodules.WalletProcessedTransaction
+	)
+	ptBucket := tx.Bucket(bucketWatchOnlyProcessedTransactions)
+	err := dbForEachProcessedTransaction(ptBucket, nil, types.BlockHeight(math.MaxUint64), func(key []byte, pt gcmodules.WalletProcessedTransaction) (bool, error) {
+		if !processedTransactionRelatesTo(pt, addresses) {
+			return true, nil
+		}
+		keys = append(keys, append([]byte(nil), key...))
+		if wopt, ok := w.watchOnlyProcessedTransaction(pt); ok {
+			updates = append(updates, &wopt)
+		} else {
+			updates = append(updates, nil)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	for idx, key := range keys {
+		if updates[idx] == nil {
+			err = ptBucket.Delete(key)
+		} else {
+			err = dbPut(ptBucket, key, *updates[idx])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processedTransactionRelatesTo returns true if any of the inputs or outputs
+// of the given processed transaction relates to one of the given addresses.
+func processedTransactionRelatesTo(pt gcmodules.WalletProcessedTransaction, addresses map[types.UnlockHash]struct{}) bool {
+	for _, uh := range processedTransactionAddresses(pt) {
+		if _, ok := addresses[uh]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// saveWatchOnlyAddresses stores the in-memory watch-only addresses
+// as part of the wallet's settings file.
+func (w *Wallet) saveWatchOnlyAddresses() error {
+	w.persist.WatchOnlyAddresses = make([]gcmodules.WatchOnlyAddress, 0, len(w.watchOnlyAddresses))
+	for _, woa := range w.watchOnlyAddresses {
+		w.persist.WatchOnlyAddresses = append(w.persist.WatchOnlyAddresses, woa)
+	}
+	sort.Slice(w.persist.WatchOnlyAddresses, func(i, j int) bool {
+		return w.persist.WatchOnlyAddresses[i].Address.Cmp(w.persist.WatchOnlyAddresses[j].Address) < 0
+	})
+	return w.saveSettingsSync()
+}
+
+// watchOnlyProcessedTransaction returns a copy of the given processed transaction,
+// marking the inputs and outputs linked to watch-only addresses, instead of those owned by the wallet.
+// False is returned if the transaction is not relevant to any of the watch-only addresses.
+func (w *Wallet) watchOnlyProcessedTransaction(pt gcmodules.WalletProcessedTransaction) (gcmodules.WalletProcessedTransaction, bool) {
+	if len(w.watchOnlyAddresses) == 0 {
+		return gcmodules.WalletProcessedTransaction{}, false
+	}
+	var relevant bool
+	wopt := pt
+	wopt.Inputs = make([]gcmodules.WalletProcessedInput, 0, len(pt.Inputs))
+	for _, input := range pt.Inputs {
+		_, input.WalletAddress = w.watchOnlyAddresses[input.RelatedAddress]
+		relevant = relevant || input.WalletAddress
+		wopt.Inputs = append(wopt.Inputs, input)
+	}
+	wopt.Outputs = make([]gcmodules.WalletProcessedOutput, 0, len(pt.Outputs))
+	for _, output := range pt.Outputs {
+		_, output.WalletAddress = w.watchOnlyAddresses[output.RelatedAddress]
+		relevant = relevant || output.WalletAddress
+		wopt.Outputs = append(wopt.Outputs, output)
+	}
+	return wopt, relevant
+}
+
+// CreateWatchOnlyCoinTransaction creates an unsigned transaction, sending the given coin outputs,
+// funded by the unspent coin outputs of the watch-only addresses of this wallet.
+// The minimum transaction fee is added as miner fee, as well as the required custody fee.
+// If no refund address is given, the condition of the largest used coin input is reused for the refund.
+//
+// The coin inputs of the returned transaction are not signed, and are to be signed offline,
+// by the owner(s) of the watch-only addresses. The custody fee is computed using the timestamp of
+// the current block, meaning that the transaction has to be published within the custody fee's
+// allowed computation time window.
+func (w *Wallet) CreateWatchOnlyCoinTransaction(coinOutputs []types.CoinOutput, data []byte, refundAddress *types.UnlockHash) (types.Transaction, error) {
+	if len(coinOutputs) == 0 {
+		return types.Transaction{}, ErrNilOutputs
+	}
+	if uint64(len(data)) > w.chainCts.ArbitraryDataSizeLimit {
+		return types.Transaction{}, errArbitraryDataTooLarge
+	}
+
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	minerFee := w.chainCts.MinimumTransactionFee
+	amount := minerFee
+	txn := types.Transaction{
+		Version:       w.chainCts.DefaultTransactionVersion,
+		MinerFees:     []types.Currency{minerFee},
+		ArbitraryData: data,
+	}
+	for _, co := range coinOutputs {
+		txn.CoinOutputs = append(txn.CoinOutputs, co)
+		amount = amount.Add(co.Value)
+	}
+
+	var refundCondition *types.UnlockConditionProxy
+	if refundAddress != nil {
+		condition := types.NewCondition(types.NewUnlockHashCondition(*refundAddress))
+		refundCondition = &condition
+	}
+	_, err := w.fundCoinTransactionFrom(&txn, amount, w.watchOnlyCoinOutputs, refundCondition, func(co types.CoinOutput) types.UnlockFulfillmentProxy {
+		// only add the public key if it is known,
+		// otherwise it is up to the offline signer to define the fulfillment
+		if woa := w.watchOnlyAddresses[co.Condition.UnlockHash()]; woa.PublicKey != nil {
+			return types.NewFulfillment(types.NewSingleSignatureFulfillment(*woa.PublicKey))
+		}
+		return types.NewFulfillment(&types.NilFulfillment{})
+	})
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	return txn, nil
+}
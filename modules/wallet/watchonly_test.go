@@ -0,0 +1,386 @@
+package wallet
+
+import (
+	"testing"
+
+	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+)
+
+// TestAddWatchOnlyAddresses checks that watch-only addresses are validated,
+// stored and can be removed again.
+func TestAddWatchOnlyAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	_, cpk := crypto.GenerateKeyPair()
+	pk := types.Ed25519PublicKey(cpk)
+	pkUH, err := types.NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownedUH, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msUH := types.NewMultiSignatureCondition(types.UnlockHashSlice{pkUH, ownedUH}, 1).UnlockHash()
+
+	// invalid addresses are refused
+	testCases := []struct {
+		Address     gcmodules.WatchOnlyAddress
+		ExpectedErr error
+	}{
+		{gcmodules.WatchOnlyAddress{}, errNilWatchOnlyAddress},
+		{gcmodules.WatchOnlyAddress{Address: ownedUH}, errOwnedWatchOnlyAddress},
+		{gcmodules.WatchOnlyAddress{Address: msUH, PublicKey: &pk}, errWatchOnlyPublicKeyMismatch},
+		{gcmodules.WatchOnlyAddress{Address: types.NewUnlockHash(types.UnlockTypeAtomicSwap, crypto.Hash{1})}, errUnsupportedWatchOnlyAddress},
+	}
+	for idx, testCase := range testCases {
+		woa := testCase.Address
+		err = wt.wallet.validateWatchOnlyAddress(&woa)
+		if err != testCase.ExpectedErr {
+			t.Errorf("test case #%d: expected error %v, received: %v", idx, testCase.ExpectedErr, err)
+		}
+		if err = wt.wallet.AddWatchOnlyAddresses([]gcmodules.WatchOnlyAddress{testCase.Address}); err == nil {
+			t.Errorf("test case #%d: expected an error, received none", idx)
+		}
+	}
+
+	// the address is derived from the public key
+	err = wt.wallet.AddWatchOnlyAddresses([]gcmodules.WatchOnlyAddress{{PublicKey: &pk}, {Address: msUH}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := wt.wallet.WatchOnlyAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 {
+		t.Fatal("expected 2 watch-only addresses, received:", len(addresses))
+	}
+	for _, woa := range addresses {
+		switch woa.Address {
+		case pkUH:
+			if woa.PublicKey == nil || woa.PublicKey.String() != pk.String() {
+				t.Error("unexpected public key for watch-only address", woa.Address, ":", woa.PublicKey)
+			}
+		case msUH:
+			if woa.PublicKey != nil {
+				t.Error("unexpected public key for watch-only address", woa.Address, ":", woa.PublicKey)
+			}
+		default:
+			t.Error("unexpected watch-only address:", woa.Address)
+		}
+	}
+	if len(wt.wallet.persist.WatchOnlyAddresses) != 2 {
+		t.Error("expected 2 persisted watch-only addresses, received:", len(wt.wallet.persist.WatchOnlyAddresses))
+	}
+
+	// unknown addresses cannot be removed
+	err = wt.wallet.RemoveWatchOnlyAddresses([]types.UnlockHash{ownedUH})
+	if err != errUnknownWatchOnlyAddress {
+		t.Error("expected errUnknownWatchOnlyAddress, received:", err)
+	}
+	err = wt.wallet.RemoveWatchOnlyAddresses([]types.UnlockHash{msUH})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, err = wt.wallet.WatchOnlyAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || addresses[0].Address != pkUH {
+		t.Error("unexpected watch-only addresses after removal:", addresses)
+	}
+}
+
+// TestWatchOnlyConfirmedSet checks that the outputs of watch-only addresses
+// are tracked separately from the outputs owned by the wallet.
+func TestWatchOnlyConfirmedSet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	_, cpk := crypto.GenerateKeyPair()
+	pk := types.Ed25519PublicKey(cpk)
+	err = wt.wallet.AddWatchOnlyAddresses([]gcmodules.WatchOnlyAddress{{PublicKey: &pk}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uh, err := types.NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	co := types.CoinOutput{
+		Value:     types.NewCurrency64(42),
+		Condition: types.NewCondition(types.NewUnlockHashCondition(uh)),
+	}
+	diff := modules.CoinOutputDiff{
+		Direction:  modules.DiffApply,
+		ID:         types.CoinOutputID{1},
+		CoinOutput: co,
+	}
+
+	wt.wallet.mu.Lock()
+	wt.wallet.updateConfirmedSet(modules.ConsensusChange{CoinOutputDiffs: []modules.CoinOutputDiff{diff}})
+	_, watched := wt.wallet.watchOnlyCoinOutputs[diff.ID]
+	_, owned := wt.wallet.coinOutputs[diff.ID]
+	wt.wallet.mu.Unlock()
+	if !watched {
+		t.Error("output of watch-only address is not tracked")
+	}
+	if owned {
+		t.Error("output of watch-only address is tracked as an owned output")
+	}
+
+	diff.Direction = modules.DiffRevert
+	wt.wallet.mu.Lock()
+	wt.wallet.updateConfirmedSet(modules.ConsensusChange{CoinOutputDiffs: []modules.CoinOutputDiff{diff}})
+	_, watched = wt.wallet.watchOnlyCoinOutputs[diff.ID]
+	wt.wallet.mu.Unlock()
+	if watched {
+		t.Error("reverted output of watch-only address is still tracked")
+	}
+}
+
+// TestRemoveWatchOnlyAddresses checks that removing a watch-only address only deletes
+// its outputs and the watch-only transactions no longer relevant to any watch-only address,
+// leaving the remaining history of the wallet untouched.
+func TestRemoveWatchOnlyAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	var (
+		pks [2]types.PublicKey
+		uhs [2]types.UnlockHash
+	)
+	for i := range pks {
+		_, cpk := crypto.GenerateKeyPair()
+		pks[i] = types.Ed25519PublicKey(cpk)
+		uhs[i], err = types.NewPubKeyUnlockHash(pks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed, kept := uhs[0], uhs[1]
+	err = wt.wallet.AddWatchOnlyAddresses([]gcmodules.WatchOnlyAddress{{PublicKey: &pks[0]}, {PublicKey: &pks[1]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCoinOutputDiff := func(id byte, uh types.UnlockHash) modules.CoinOutputDiff {
+		return modules.CoinOutputDiff{
+			Direction: modules.DiffApply,
+			ID:        types.CoinOutputID{id},
+			CoinOutput: types.CoinOutput{
+				Value:     types.NewCurrency64(42),
+				Condition: types.NewCondition(types.NewUnlockHashCondition(uh)),
+			},
+		}
+	}
+	newProcessedTransaction := func(id byte, uhs ...types.UnlockHash) gcmodules.WalletProcessedTransaction {
+		pt := gcmodules.WalletProcessedTransaction{TransactionID: types.TransactionID{id}}
+		for _, uh := range uhs {
+			pt.Outputs = append(pt.Outputs, gcmodules.WalletProcessedOutput{RelatedAddress: uh})
+		}
+		return pt
+	}
+	cc := modules.ConsensusChange{CoinOutputDiffs: []modules.CoinOutputDiff{
+		newCoinOutputDiff(1, removed),
+		newCoinOutputDiff(2, kept),
+	}}
+	var (
+		removedKey = dbHistoryKey(1, 1)
+		sharedKey  = dbHistoryKey(1, 2)
+		ownedKey   = dbHistoryKey(1, 3)
+	)
+	wt.wallet.mu.Lock()
+	wt.wallet.updateConfirmedSet(cc)
+	err = wt.wallet.db.Update(func(tx *bolt.Tx) error {
+		err := wt.wallet.dbUpdateConfirmedSet(tx, cc)
+		if err != nil {
+			return err
+		}
+		err = wt.wallet.addProcessedTransaction(tx, removedKey, newProcessedTransaction(1, removed), false)
+		if err != nil {
+			return err
+		}
+		err = wt.wallet.addProcessedTransaction(tx, sharedKey, newProcessedTransaction(2, removed, kept), false)
+		if err != nil {
+			return err
+		}
+		return dbAddProcessedTransaction(tx, ownedKey, newProcessedTransaction(3, removed))
+	})
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wt.wallet.RemoveWatchOnlyAddresses([]types.UnlockHash{removed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wt.wallet.mu.RLock()
+	_, removedWatched := wt.wallet.watchOnlyCoinOutputs[types.CoinOutputID{1}]
+	_, keptWatched := wt.wallet.watchOnlyCoinOutputs[types.CoinOutputID{2}]
+	wt.wallet.mu.RUnlock()
+	if removedWatched || !keptWatched {
+		t.Errorf("unexpected watch-only outputs: removed address tracked: %v, kept address tracked: %v", removedWatched, keptWatched)
+	}
+	err = wt.wallet.db.View(func(tx *bolt.Tx) error {
+		id := types.CoinOutputID{1}
+		if tx.Bucket(bucketWatchOnlyCoinOutputs).Get(id[:]) != nil {
+			t.Error("output of removed address is still stored")
+		}
+		ptBucket := tx.Bucket(bucketWatchOnlyProcessedTransactions)
+		if ptBucket.Get(removedKey) != nil {
+			t.Error("transaction only relevant to the removed address is still stored")
+		}
+		var pt gcmodules.WalletProcessedTransaction
+		err := dbGet(ptBucket, sharedKey, &pt)
+		if err != nil {
+			return err
+		}
+		if len(pt.Outputs) != 2 || pt.Outputs[0].WalletAddress || !pt.Outputs[1].WalletAddress {
+			t.Errorf("unexpected watch-only transaction relevant to the kept address: %+v", pt)
+		}
+		if _, exists, err := dbGetProcessedTransaction(tx, types.TransactionID{3}); err != nil || !exists {
+			t.Errorf("transaction history owned by the wallet was modified: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWatchOnlyWithoutUnlock checks that watch-only addresses are tracked
+// by a wallet which has no seed or is locked, and that the history is
+// rescanned once the keys of the wallet are loaded.
+func TestWatchOnlyWithoutUnlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createBlankWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	_, cpk := crypto.GenerateKeyPair()
+	pk := types.Ed25519PublicKey(cpk)
+	err = wt.wallet.AddWatchOnlyAddresses([]gcmodules.WatchOnlyAddress{{PublicKey: &pk}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uh, err := types.NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	subscribed := wt.wallet.subscribed
+	wt.wallet.mu.RUnlock()
+	if !subscribed {
+		t.Fatal("wallet without seed did not subscribe to track its watch-only addresses")
+	}
+
+	// the watch-only section is available, while the owned section is not
+	balances, err := wt.wallet.ConfirmedBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances.Owned != nil {
+		t.Error("unexpected owned balance for a wallet without seed:", *balances.Owned)
+	}
+	if _, ok := balances.WatchOnlyAddresses[uh]; !ok || len(balances.WatchOnlyAddresses) != 1 {
+		t.Error("unexpected watch-only balances:", balances.WatchOnlyAddresses)
+	}
+	filter := gcmodules.TransactionFilter{EndHeight: 10, WatchOnly: true}
+	_, _, err = wt.wallet.TransactionsWithCustodyInfo(filter)
+	if err != nil {
+		t.Error("failed to list the watch-only transactions of a locked wallet:", err)
+	}
+	filter.WatchOnly = false
+	_, _, err = wt.wallet.TransactionsWithCustodyInfo(filter)
+	if err != modules.ErrLockedWallet {
+		t.Error("expected the owned transactions to require an unlocked wallet, received:", err)
+	}
+
+	// a reopened wallet tracks its watch-only addresses prior to being unlocked,
+	// and consensus changes processed while its keys are not loaded are rescanned on unlock
+	var masterKey crypto.TwofishKey
+	masterKey[0] = 1
+	_, err = wt.wallet.Encrypt(masterKey, modules.Seed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistDir := wt.wallet.persistDir
+	err = wt.wallet.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainCts := types.TestnetChainConstants()
+	plugin := custodyfees.NewPlugin(types.Timestamp(chainCts.BlockFrequency*5), 5)
+	wt.wallet, err = New(wt.cs, wt.tpool, plugin, persistDir, types.DefaultBlockchainInfo(), chainCts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.RLock()
+	subscribed = wt.wallet.subscribed
+	wt.wallet.mu.RUnlock()
+	if !subscribed {
+		t.Fatal("locked wallet did not subscribe to track its watch-only addresses")
+	}
+	wt.wallet.ProcessConsensusChange(modules.ConsensusChange{})
+	if !walletHistoryIncomplete(t, wt.wallet) {
+		t.Fatal("consensus change processed without keys did not mark the history as incomplete")
+	}
+	err = wt.wallet.Unlock(masterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walletHistoryIncomplete(t, wt.wallet) {
+		t.Error("incomplete history was not rescanned on unlock")
+	}
+	addresses, err := wt.wallet.WatchOnlyAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || addresses[0].Address != uh {
+		t.Error("unexpected watch-only addresses after unlock:", addresses)
+	}
+}
+
+func walletHistoryIncomplete(t *testing.T, w *Wallet) (incomplete bool) {
+	err := w.db.View(func(tx *bolt.Tx) error {
+		return dbGetInternal(tx, internalIncompleteHistory, &incomplete)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/modules/consensus"
+	"github.com/threefoldtech/rivine/modules/gateway"
+	"github.com/threefoldtech/rivine/modules/transactionpool"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	gcconsensus "github.com/nbh-digital/goldchain/modules/consensus"
+	"github.com/nbh-digital/goldchain/modules/wallet"
+	"github.com/nbh-digital/goldchain/pkg/config"
+)
+
+// custodyFeePeriod is the time passed since the genesis block,
+// used by the API tester to compute a non-zero custody fee.
+const custodyFeePeriod = types.Timestamp(30 * 24 * 60 * 60)
+
+// apiTester contains the modules used by the API handlers,
+// on top of a chain which genesis block funds the address and multisig wallet of the tester's wallet.
+type apiTester struct {
+	gateway modules.Gateway
+	cs      modules.ConsensusSet
+	plugin  *custodyfees.Plugin
+	tpool   modules.TransactionPool
+	wallet  *wallet.Wallet
+
+	chainCts types.ChainConstants
+
+	// key (and address) of the first address of the wallet's primary seed
+	secretKey crypto.SecretKey
+	publicKey crypto.PublicKey
+	address   types.UnlockHash
+	// multisig wallet of which the wallet's address and another address are the co-signers
+	multiSigCondition types.UnlockConditionProxy
+}
+
+// laterConsensusSet is a consensus set of which the blocks are reported to be created later than they were,
+// such that the wallet computes the custody fee of the genesis coin outputs at a later time.
+type laterConsensusSet struct {
+	modules.ConsensusSet
+	delay types.Timestamp
+}
+
+func (cs laterConsensusSet) BlockAtHeight(height types.BlockHeight) (types.Block, bool) {
+	block, ok := cs.ConsensusSet.BlockAtHeight(height)
+	block.Timestamp += cs.delay
+	return block, ok
+}
+
+// createAPITester creates an API tester, with an encrypted and unlocked wallet.
+func createAPITester(name string) (*apiTester, error) {
+	seed := modules.Seed{1}
+	h, err := crypto.HashAll(seed, uint64(0))
+	if err != nil {
+		return nil, err
+	}
+	sk, pk := crypto.GenerateKeyPairDeterministic(h)
+	address, err := types.NewPubKeyUnlockHash(types.Ed25519PublicKey(pk))
+	if err != nil {
+		return nil, err
+	}
+	multiSigCondition := types.NewCondition(types.NewMultiSignatureCondition(types.UnlockHashSlice{
+		address, types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{2}),
+	}, 2))
+
+	bcInfo := types.DefaultBlockchainInfo()
+	chainCts := config.GetTestnetGenesis()
+	chainCts.GenesisCoinDistribution = []types.CoinOutput{
+		{
+			Value:     chainCts.CurrencyUnits.OneCoin.Mul64(1000),
+			Condition: types.NewCondition(types.NewUnlockHashCondition(address)),
+		},
+		{
+			Value:     chainCts.CurrencyUnits.OneCoin.Mul64(500),
+			Condition: multiSigCondition,
+		},
+	}
+
+	// create the modules
+	testdir := build.TempDir("api", name)
+	g, err := gateway.New("localhost:0", false, 1, filepath.Join(testdir, modules.GatewayDir), bcInfo, chainCts, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := consensus.New(g, false, filepath.Join(testdir, modules.ConsensusDir), bcInfo, chainCts, false, "")
+	if err != nil {
+		return nil, err
+	}
+	cs.SetTransactionValidators(gcconsensus.TransactionValidationFunctions(gcconsensus.GetTestnetTransactionValidators())...)
+	for txVersion, validators := range gcconsensus.GetTestnetTransactionVersionMappedValidators() {
+		cs.SetTransactionVersionMappedValidators(txVersion, gcconsensus.TransactionValidationFunctions(validators)...)
+	}
+	plugin := custodyfees.NewPlugin(types.Timestamp(chainCts.BlockFrequency)*5, 3)
+	err = cs.RegisterPlugin(context.Background(), "custodyfees", plugin)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := transactionpool.New(cs, g, filepath.Join(testdir, modules.TransactionPoolDir), bcInfo, chainCts, false)
+	if err != nil {
+		return nil, err
+	}
+	w, err := wallet.New(laterConsensusSet{ConsensusSet: cs, delay: custodyFeePeriod}, tp, plugin, filepath.Join(testdir, modules.WalletDir), bcInfo, chainCts, false)
+	if err != nil {
+		return nil, err
+	}
+	var masterKey crypto.TwofishKey
+	_, err = rand.Read(masterKey[:])
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Encrypt(masterKey, seed)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Unlock(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiTester{
+		gateway: g,
+		cs:      cs,
+		plugin:  plugin,
+		tpool:   tp,
+		wallet:  w,
+
+		chainCts: chainCts,
+
+		secretKey:         sk,
+		publicKey:         pk,
+		address:           address,
+		multiSigCondition: multiSigCondition,
+	}, nil
+}
+
+// Close closes all modules of the API tester.
+func (at *apiTester) Close() error {
+	for _, closer := range []interface{ Close() error }{at.wallet, at.tpool, at.cs, at.gateway} {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post posts the given body as JSON to the given path of the given router,
+// decoding the JSON response into resp, returning the HTTP status code.
+func post(t *testing.T, router *httprouter.Router, path string, body, resp interface{}) int {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b)))
+	err = json.NewDecoder(w.Body).Decode(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.Code
+}
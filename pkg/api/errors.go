@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/threefoldtech/rivine/modules"
+	rtypes "github.com/threefoldtech/rivine/types"
+
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// Error is the error object returned by the goldchain API endpoints.
+// It is compatible with the rivine API error (rivine/pkg/api.Error), adding a stable error code and machine-readable details,
+// such that clients can react programmatically, rather than having to interpret the error message.
+type Error struct {
+	Message string            `json:"message"`
+	Code    gctypes.ErrorCode `json:"code,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Error implements error.Error
+func (err Error) Error() string {
+	return err.Message
+}
+
+// NewError creates an API error for the given error, prefixing its message with the given context,
+// and attaching the error code and details defined by the error (or any error it wraps).
+func NewError(context string, err error) Error {
+	code, details := errorCodeAndDetails(err)
+	return Error{
+		Message: context + err.Error(),
+		Code:    code,
+		Details: details,
+	}
+}
+
+// WriteError writes an API error to the ResponseWriter,
+// using the given HTTP status code.
+func WriteError(w http.ResponseWriter, err Error, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(err) // ignore error, as it probably means that the status code does not allow a body
+}
+
+// errors known by their value, which do not define an error code themselves
+var knownErrorCodes = []struct {
+	err  error
+	code gctypes.ErrorCode
+}{
+	{modules.ErrLockedWallet, gctypes.ErrorCodeWalletLocked},
+	{modules.ErrLowBalance, gctypes.ErrorCodeInsufficientBalance},
+	{modules.ErrIncompleteTransactions, gctypes.ErrorCodeIncompleteTransactions},
+	{rtypes.ErrZeroOutput, gctypes.ErrorCodeZeroCoinOutput},
+}
+
+func errorCodeAndDetails(err error) (gctypes.ErrorCode, map[string]string) {
+	if cErr, ok := gctypes.AsCodedError(err); ok {
+		return cErr.Code, cErr.Details
+	}
+	for _, known := range knownErrorCodes {
+		if errors.Is(err, known.err) {
+			return known.code, nil
+		}
+	}
+	var clientErr rtypes.ClientError
+	if !errors.As(err, &clientErr) {
+		return "", nil
+	}
+	switch clientErr.Kind {
+	case rtypes.ClientErrorForbidden:
+		// the auth coin tx extension (part of rivine) reports unauthorized addresses
+		// as a forbidden client error, defining the address only as part of its message
+		var address rtypes.UnlockHash
+		var addressStr string
+		if n, _ := fmt.Sscanf(clientErr.Err.Error(), "address %s is not authorized", &addressStr); n == 1 && address.LoadString(addressStr) == nil {
+			return gctypes.ErrorCodeAddressUnauthorized, map[string]string{
+				"address": address.String(),
+			}
+		}
+		return gctypes.ErrorCodeForbidden, nil
+	case rtypes.ClientErrorUnauthorized:
+		return gctypes.ErrorCodeUnauthorized, nil
+	case rtypes.ClientErrorNotFound:
+		return gctypes.ErrorCodeNotFound, nil
+	case rtypes.ClientErrorTimeout:
+		return gctypes.ErrorCodeTimeout, nil
+	default:
+		return gctypes.ErrorCodeBadRequest, nil
+	}
+}
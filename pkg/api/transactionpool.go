@@ -0,0 +1,509 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/extensions/authcointx"
+	"github.com/threefoldtech/rivine/modules"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	rtypes "github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/authexpiry"
+	"github.com/nbh-digital/goldchain/extensions/custodyfees"
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gcconsensus "github.com/nbh-digital/goldchain/modules/consensus"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// the validators to which the rules reported by a transaction simulation belong
+const (
+	simulationValidatorStandalone  = "standalone"
+	simulationValidatorCustodyFees = "custodyfees"
+	simulationValidatorAuthCoinTx  = "authcointx"
+	simulationValidatorAuthExpiry  = "authexpiry"
+	simulationValidatorMinting     = "minting"
+	simulationValidatorConsensus   = "consensus"
+)
+
+// TransactionSimulationConfig defines the validators and extension plugins
+// used to simulate a candidate transaction, these should be the same as the ones used by the consensus set.
+type TransactionSimulationConfig struct {
+	ChainConstants    rtypes.ChainConstants
+	Validators        []gcconsensus.NamedTransactionValidator
+	MappedValidators  map[rtypes.TransactionVersion][]gcconsensus.NamedTransactionValidator
+	CustodyFeesPlugin *custodyfees.Plugin
+	AuthCoinTxPlugin  *authcointx.Plugin
+	AuthExpiryPlugin  *authexpiry.Plugin
+}
+
+type (
+	// TransactionPoolSimulatePOSTResp is the object returned as a response to a POST request to
+	// /transactionpool/simulate. It reports the result of each rule the transaction was validated against,
+	// the custody fee it is expected to pay and the coin balance changes it would cause.
+	TransactionPoolSimulatePOSTResp struct {
+		TransactionID rtypes.TransactionID       `json:"transactionid"`
+		Valid         bool                       `json:"valid"`
+		BlockHeight   rtypes.BlockHeight         `json:"blockheight"`
+		BlockTime     rtypes.Timestamp           `json:"blocktime"`
+		Rules         []TransactionRuleResult    `json:"rules"`
+		CustodyFee    *TransactionCustodyFee     `json:"custodyfee,omitempty"`
+		Balances      []TransactionBalanceChange `json:"balances"`
+	}
+
+	// TransactionRuleResult is the result of validating a transaction against a single rule.
+	TransactionRuleResult struct {
+		Validator string            `json:"validator"`
+		Rule      string            `json:"rule"`
+		Passed    bool              `json:"passed"`
+		Error     string            `json:"error,omitempty"`
+		Code      gctypes.ErrorCode `json:"code,omitempty"`
+		Details   map[string]string `json:"details,omitempty"`
+	}
+
+	// TransactionCustodyFee is the custody fee a transaction is expected to pay,
+	// computed for all coin inputs at the computation time defined by the transaction.
+	TransactionCustodyFee struct {
+		ComputationTime rtypes.Timestamp      `json:"computationtime"`
+		Expected        rtypes.Currency       `json:"expected"`
+		Paid            rtypes.Currency       `json:"paid"`
+		Inputs          []CoinInputCustodyFee `json:"inputs"`
+	}
+
+	// CoinInputCustodyFee is the custody fee information of a single coin input.
+	// The custody fee of a coin output created by an unconfirmed transaction is always zero.
+	CoinInputCustodyFee struct {
+		ParentID    rtypes.CoinOutputID `json:"parentid"`
+		Unconfirmed bool                `json:"unconfirmed"`
+		Custody     CustodyFeeInfo      `json:"custody"`
+	}
+
+	// TransactionBalanceChange is the change a transaction causes to the coin balance of an address.
+	// The custody fee is paid from the spent value, the resulting balance change is Received - Spent.
+	TransactionBalanceChange struct {
+		Address    rtypes.UnlockHash `json:"address"`
+		Spent      rtypes.Currency   `json:"spent"`
+		CustodyFee rtypes.Currency   `json:"custodyfee"`
+		Received   rtypes.Currency   `json:"received"`
+	}
+)
+
+// RegisterTransactionPoolHTTPHandlers registers the (goldchain-specific) handlers for all TransactionPool HTTP endpoints,
+// replacing the rivine handlers.
+func RegisterTransactionPoolHTTPHandlers(router rapi.Router, cs modules.ConsensusSet, tpool modules.TransactionPool, cfg TransactionSimulationConfig, requiredPassword string) {
+	if cs == nil {
+		panic("no ConsensusSet API given")
+	}
+	if tpool == nil {
+		panic("no TransactionPool API given")
+	}
+	if router == nil {
+		panic("no router given")
+	}
+	router.GET("/transactionpool/transactions", rapi.NewTransactionPoolGetTransactionsHandler(cs, tpool))
+	router.POST("/transactionpool/transactions", rapi.RequirePasswordHandler(NewTransactionPoolPostTransactionHandler(tpool), requiredPassword))
+	router.OPTIONS("/transactionpool/transactions", rapi.RequirePasswordHandler(rapi.NewTransactionPoolOptionsTransactionHandler(), requiredPassword))
+	router.POST("/transactionpool/simulate", rapi.RequirePasswordHandler(NewTransactionPoolSimulateHandler(cs, tpool, cfg), requiredPassword))
+}
+
+// NewTransactionPoolPostTransactionHandler creates a handler to handle API calls to POST /transactionpool/transactions.
+// It is the same as the rivine handler, except that errors are returned with an error code.
+func NewTransactionPoolPostTransactionHandler(tpool modules.TransactionPool) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var txn rtypes.Transaction
+		err := json.NewDecoder(req.Body).Decode(&txn)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: "error decoding the supplied transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = tpool.AcceptTransactionSet([]rtypes.Transaction{txn})
+		if err != nil {
+			WriteError(w, NewError("error after call to /transactionpool/transactions: ", err), transactionPoolErrorToHTTPStatus(err))
+			return
+		}
+		rapi.WriteJSON(w, rapi.TransactionPoolPOST{TransactionID: txn.ID()})
+	}
+}
+
+// NewTransactionPoolSimulateHandler creates a handler to handle API calls to /transactionpool/simulate.
+// The given transaction is validated against all rules, but is never given to the transaction pool.
+func NewTransactionPoolSimulateHandler(cs modules.ConsensusSet, tpool modules.TransactionPool, cfg TransactionSimulationConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var txn rtypes.Transaction
+		err := json.NewDecoder(req.Body).Decode(&txn)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: "error decoding the supplied transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		rapi.WriteJSON(w, simulateTransaction(cs, tpool, cfg, txn))
+	}
+}
+
+// simulateTransaction validates the given transaction against each rule individually,
+// rather than stopping at the first failed rule as the consensus set does.
+func simulateTransaction(cs modules.ConsensusSet, tpool modules.TransactionPool, cfg TransactionSimulationConfig, txn rtypes.Transaction) TransactionPoolSimulatePOSTResp {
+	resp := TransactionPoolSimulatePOSTResp{
+		TransactionID: txn.ID(),
+		BlockHeight:   cs.Height(),
+		BlockTime:     cs.CurrentBlock().Timestamp,
+	}
+	addRule := func(validator, rule string, err error) {
+		result := TransactionRuleResult{
+			Validator: validator,
+			Rule:      rule,
+			Passed:    err == nil,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.Code, result.Details = errorCodeAndDetails(err)
+		}
+		resp.Rules = append(resp.Rules, result)
+	}
+
+	// the candidate transaction can spend outputs created by unconfirmed transactions,
+	// in which case those have to be validated together with the candidate transaction
+	ancestors := unconfirmedAncestors(tpool, txn)
+	unconfirmedCoinOutputs := make(map[rtypes.CoinOutputID]rtypes.CoinOutput)
+	unconfirmedBlockStakeOutputs := make(map[rtypes.BlockStakeOutputID]rtypes.BlockStakeOutput)
+	for _, ancestor := range ancestors {
+		for idx, co := range ancestor.CoinOutputs {
+			unconfirmedCoinOutputs[ancestor.CoinOutputID(uint64(idx))] = co
+		}
+		for idx, bso := range ancestor.BlockStakeOutputs {
+			unconfirmedBlockStakeOutputs[ancestor.BlockStakeOutputID(uint64(idx))] = bso
+		}
+	}
+
+	ctxn := modules.ConsensusTransaction{
+		Transaction:            txn,
+		BlockHeight:            resp.BlockHeight,
+		BlockTime:              resp.BlockTime,
+		SequenceID:             uint16(len(ancestors)),
+		SpentCoinOutputs:       make(map[rtypes.CoinOutputID]rtypes.CoinOutput, len(txn.CoinInputs)),
+		SpentBlockStakeOutputs: make(map[rtypes.BlockStakeOutputID]rtypes.BlockStakeOutput, len(txn.BlockStakeInputs)),
+	}
+	var missingOutputs []string
+	for _, ci := range txn.CoinInputs {
+		if co, ok := unconfirmedCoinOutputs[ci.ParentID]; ok {
+			ctxn.SpentCoinOutputs[ci.ParentID] = co
+			continue
+		}
+		co, err := cs.GetCoinOutput(ci.ParentID)
+		if err != nil {
+			missingOutputs = append(missingOutputs, "coin output "+ci.ParentID.String())
+			continue
+		}
+		ctxn.SpentCoinOutputs[ci.ParentID] = co
+	}
+	for _, bsi := range txn.BlockStakeInputs {
+		if bso, ok := unconfirmedBlockStakeOutputs[bsi.ParentID]; ok {
+			ctxn.SpentBlockStakeOutputs[bsi.ParentID] = bso
+			continue
+		}
+		bso, err := cs.GetBlockStakeOutput(bsi.ParentID)
+		if err != nil {
+			missingOutputs = append(missingOutputs, "block stake output "+bsi.ParentID.String())
+			continue
+		}
+		ctxn.SpentBlockStakeOutputs[bsi.ParentID] = bso
+	}
+	if len(missingOutputs) > 0 {
+		addRule(simulationValidatorConsensus, "spent outputs are unspent", fmt.Errorf(
+			"failed to find %s as unspent output(s) in the consensus state or transaction pool", strings.Join(missingOutputs, ", ")))
+	} else {
+		addRule(simulationValidatorConsensus, "spent outputs are unspent", nil)
+
+		// validate using the stand alone validators, both version-specific as well as global
+		ctx := rtypes.TransactionValidationContext{
+			ValidationContext: rtypes.ValidationContext{
+				Confirmed:   true,
+				BlockHeight: resp.BlockHeight,
+				BlockTime:   resp.BlockTime,
+			},
+			BlockSizeLimit:         cfg.ChainConstants.BlockSizeLimit,
+			ArbitraryDataSizeLimit: cfg.ChainConstants.ArbitraryDataSizeLimit,
+			MinimumMinerFee:        cfg.ChainConstants.MinimumTransactionFee,
+		}
+		for _, validator := range cfg.MappedValidators[txn.Version] {
+			addRule(simulationValidatorStandalone, validator.Name, validator.Fn(ctxn, ctx))
+		}
+		for _, validator := range cfg.Validators {
+			addRule(simulationValidatorStandalone, validator.Name, validator.Fn(ctxn, ctx))
+		}
+
+		// validate the plugin rules which can be checked against the plugin state
+		if cfg.CustodyFeesPlugin != nil && len(txn.CoinInputs) > 0 {
+			resp.CustodyFee = simulateCustodyFee(cfg.CustodyFeesPlugin, ctxn, unconfirmedCoinOutputs)
+			addRule(simulationValidatorCustodyFees, "custody fee is paid",
+				cfg.CustodyFeesPlugin.ValidateTransaction(ctxn, ctx, unconfirmedCoinOutputs))
+		}
+		if cfg.AuthCoinTxPlugin != nil {
+			addRule(simulationValidatorAuthCoinTx, "coin flow is authorized", simulateAuthorizedCoinFlow(cfg.AuthCoinTxPlugin, ctxn))
+		}
+		if cfg.AuthExpiryPlugin != nil {
+			addRule(simulationValidatorAuthExpiry, "authorization is not expired", simulateUnexpiredAuthorization(cfg.AuthExpiryPlugin, ctxn))
+		}
+		resp.Balances = transactionBalanceChanges(ctxn, resp.CustodyFee)
+	}
+
+	// apply the transaction (and its unconfirmed ancestors) to the consensus state,
+	// the only way to validate the plugin rules which require the plugin state to be modified
+	_, consensusErr := cs.TryTransactionSet(append(ancestors, txn))
+	allPassed := true
+	for _, rule := range resp.Rules {
+		allPassed = allPassed && rule.Passed
+	}
+	// the version-specific plugin rules can only be attributed the consensus result,
+	// if it did not fail because of any of the other rules
+	if consensusErr == nil || allPassed {
+		switch txn.Version {
+		case gctypes.TransactionVersionMinterDefinition, gctypes.TransactionVersionCoinCreation, gctypes.TransactionVersionCoinDestruction:
+			addRule(simulationValidatorMinting, "minting transaction is valid", consensusErr)
+		case gctypes.TransactionVersionAuthAddressUpdate, gctypes.TransactionVersionAuthConditionUpdate:
+			addRule(simulationValidatorAuthCoinTx, "auth update transaction is valid", consensusErr)
+		}
+	}
+	addRule(simulationValidatorConsensus, "transaction applies to the consensus state", consensusErr)
+
+	resp.Valid = true
+	for _, rule := range resp.Rules {
+		resp.Valid = resp.Valid && rule.Passed
+	}
+	return resp
+}
+
+// unconfirmedAncestors returns all transactions of the transaction pool which create
+// the outputs spent by the given transaction, directly or indirectly, in transaction pool order.
+func unconfirmedAncestors(tpool modules.TransactionPool, txn rtypes.Transaction) []rtypes.Transaction {
+	pool := tpool.TransactionList()
+	if len(pool) == 0 {
+		return nil
+	}
+	coinOutputCreators := make(map[rtypes.CoinOutputID]int)
+	blockStakeOutputCreators := make(map[rtypes.BlockStakeOutputID]int)
+	for idx, ptxn := range pool {
+		for i := range ptxn.CoinOutputs {
+			coinOutputCreators[ptxn.CoinOutputID(uint64(i))] = idx
+		}
+		for i := range ptxn.BlockStakeOutputs {
+			blockStakeOutputCreators[ptxn.BlockStakeOutputID(uint64(i))] = idx
+		}
+	}
+
+	included := make(map[int]struct{})
+	queue := []rtypes.Transaction{txn}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		var parents []int
+		for _, ci := range next.CoinInputs {
+			if idx, ok := coinOutputCreators[ci.ParentID]; ok {
+				parents = append(parents, idx)
+			}
+		}
+		for _, bsi := range next.BlockStakeInputs {
+			if idx, ok := blockStakeOutputCreators[bsi.ParentID]; ok {
+				parents = append(parents, idx)
+			}
+		}
+		for _, idx := range parents {
+			if _, ok := included[idx]; ok {
+				continue
+			}
+			included[idx] = struct{}{}
+			queue = append(queue, pool[idx])
+		}
+	}
+	if len(included) == 0 {
+		return nil
+	}
+	ancestors := make([]rtypes.Transaction, 0, len(included))
+	for idx, ptxn := range pool {
+		if _, ok := included[idx]; ok {
+			ancestors = append(ancestors, ptxn)
+		}
+	}
+	return ancestors
+}
+
+// simulateCustodyFee computes the custody fee the given transaction is expected to pay,
+// as well as the custody fee info of each coin input, such that it can be compared with the custody fee it pays.
+// Whether or not the transaction pays the expected custody fee is validated by the custody fees plugin.
+// Nil is returned if the custody fee info of any coin input cannot be found.
+func simulateCustodyFee(plugin *custodyfees.Plugin, ctxn modules.ConsensusTransaction, unconfirmedCoinOutputs map[rtypes.CoinOutputID]rtypes.CoinOutput) *TransactionCustodyFee {
+	var (
+		fee        TransactionCustodyFee
+		conditions int
+	)
+	for _, co := range ctxn.CoinOutputs {
+		cfc, ok := co.Condition.Condition.(*cftypes.CustodyFeeCondition)
+		if !ok {
+			continue
+		}
+		conditions++
+		fee.ComputationTime = cfc.ComputationTime
+		fee.Paid = co.Value
+	}
+	if conditions == 0 {
+		// compute the fee as the wallet would, at the time of the current block
+		fee.ComputationTime = ctxn.BlockTime
+	}
+
+	err := plugin.ViewCoinOutputInfo(func(view custodyfees.CoinOutputInfoView) error {
+		for _, ci := range ctxn.CoinInputs {
+			input := CoinInputCustodyFee{ParentID: ci.ParentID}
+			var info custodyfees.CoinOutputInfo
+			if co, ok := unconfirmedCoinOutputs[ci.ParentID]; ok {
+				input.Unconfirmed = true
+				info = custodyfees.UnconfirmedCoinOutputInfo(co, ctxn.BlockTime, fee.ComputationTime)
+			} else {
+				var err error
+				info, err = view.GetCoinOutputInfo(ci.ParentID, fee.ComputationTime)
+				if err != nil {
+					return err
+				}
+			}
+			input.Custody = CustodyFeeInfo{
+				CreationTime:       info.CreationTime,
+				CreationValue:      info.CreationValue,
+				IsCustodyFee:       info.IsCustodyFee,
+				Spent:              info.Spent,
+				FeeComputationTime: info.FeeComputationTime,
+				CustodyFee:         info.CustodyFee,
+				SpendableValue:     info.SpendableValue,
+			}
+			fee.Expected = fee.Expected.Add(info.CustodyFee)
+			fee.Inputs = append(fee.Inputs, input)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return &fee
+}
+
+// simulateAuthorizedCoinFlow ensures that all addresses sending or receiving coins
+// in the given transaction are currently authorized, unless the transaction is exempted from this rule.
+func simulateAuthorizedCoinFlow(plugin *authcointx.Plugin, ctxn modules.ConsensusTransaction) error {
+	addresses := authorizedCoinFlowAddresses(ctxn)
+	if len(addresses) == 0 {
+		return nil
+	}
+	states, err := plugin.GetAddressesAuthStateNow(addresses, nil)
+	if err != nil {
+		return fmt.Errorf("failed to check if the addresses are authorized at the moment: %v", err)
+	}
+	var unauthorized []string
+	for idx, state := range states {
+		if !state {
+			unauthorized = append(unauthorized, addresses[idx].String())
+		}
+	}
+	if len(unauthorized) > 0 {
+		return gctypes.NewCodedError(gctypes.ErrorCodeAddressUnauthorized,
+			fmt.Errorf("address(es) %s are not authorized", strings.Join(unauthorized, ", ")),
+			map[string]string{"address": strings.Join(unauthorized, ",")})
+	}
+	return nil
+}
+
+// simulateUnexpiredAuthorization ensures that the authorization of none of the addresses sending or receiving coins
+// in the given transaction is expired at the block height and time of the transaction, unless the transaction is exempted from this rule.
+func simulateUnexpiredAuthorization(plugin *authexpiry.Plugin, ctxn modules.ConsensusTransaction) error {
+	addresses := authorizedCoinFlowAddresses(ctxn)
+	if len(addresses) == 0 {
+		return nil
+	}
+	records, err := plugin.GetExpiredAddresses(addresses, ctxn.BlockHeight, ctxn.BlockTime)
+	if err != nil {
+		return fmt.Errorf("failed to check if the authorization of the addresses is expired: %v", err)
+	}
+	return authexpiry.ExpiredAuthorizationError(records)
+}
+
+// authorizedCoinFlowAddresses returns the addresses sending or receiving coins in the given transaction
+// which are required to be authorized, none if the transaction is exempted from this rule.
+func authorizedCoinFlowAddresses(ctxn modules.ConsensusTransaction) []rtypes.UnlockHash {
+	var (
+		dedupAddresses []rtypes.UnlockHash
+		seen           = make(map[rtypes.UnlockHash]struct{})
+	)
+	addAddress := func(uh rtypes.UnlockHash) {
+		if _, ok := seen[uh]; ok {
+			return
+		}
+		seen[uh] = struct{}{}
+		dedupAddresses = append(dedupAddresses, uh)
+	}
+	for _, co := range ctxn.CoinOutputs {
+		addAddress(co.Condition.UnlockHash())
+	}
+	for _, ci := range ctxn.CoinInputs {
+		addAddress(ctxn.SpentCoinOutputs[ci.ParentID].Condition.UnlockHash())
+	}
+	if len(dedupAddresses) == 0 {
+		return nil // nothing to do
+	}
+	if gctypes.IsUnauthorizedCoinTransactionException(ctxn.Version, dedupAddresses, len(ctxn.CoinOutputs)) {
+		return nil
+	}
+
+	var addresses []rtypes.UnlockHash
+	for _, uh := range dedupAddresses {
+		if gctypes.AuthCoinUnlockHashFilter(uh) {
+			addresses = append(addresses, uh)
+		}
+	}
+	return addresses
+}
+
+// transactionBalanceChanges returns the coin balance changes caused by the given transaction,
+// for each address spending or receiving coins, in order of appearance.
+func transactionBalanceChanges(ctxn modules.ConsensusTransaction, fee *TransactionCustodyFee) []TransactionBalanceChange {
+	var (
+		changes []TransactionBalanceChange
+		indices = make(map[rtypes.UnlockHash]int)
+	)
+	getChange := func(uh rtypes.UnlockHash) *TransactionBalanceChange {
+		idx, ok := indices[uh]
+		if !ok {
+			idx = len(changes)
+			indices[uh] = idx
+			changes = append(changes, TransactionBalanceChange{Address: uh})
+		}
+		return &changes[idx]
+	}
+	inputFees := make(map[rtypes.CoinOutputID]rtypes.Currency)
+	if fee != nil {
+		for _, input := range fee.Inputs {
+			inputFees[input.ParentID] = input.Custody.CustodyFee
+		}
+	}
+	for _, ci := range ctxn.CoinInputs {
+		co := ctxn.SpentCoinOutputs[ci.ParentID]
+		change := getChange(co.Condition.UnlockHash())
+		change.Spent = change.Spent.Add(co.Value)
+		change.CustodyFee = change.CustodyFee.Add(inputFees[ci.ParentID])
+	}
+	for _, co := range ctxn.CoinOutputs {
+		if co.Condition.ConditionType() == cftypes.ConditionTypeCustodyFee {
+			continue // reported as part of the custody fee
+		}
+		change := getChange(co.Condition.UnlockHash())
+		change.Received = change.Received.Add(co.Value)
+	}
+	return changes
+}
+
+func transactionPoolErrorToHTTPStatus(err error) int {
+	var cErr rtypes.ClientError
+	if errors.As(err, &cErr) {
+		return cErr.Kind.AsHTTPStatusCode()
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gcconsensus "github.com/nbh-digital/goldchain/modules/consensus"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// TestTransactionPoolSimulateHandler checks the rules reported by a transaction simulation,
+// as well as the custody fee the simulated transaction is expected to pay.
+func TestTransactionPoolSimulateHandler(t *testing.T) {
+	at, err := createAPITester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer at.Close()
+
+	router := httprouter.New()
+	router.POST("/transactionpool/simulate", NewTransactionPoolSimulateHandler(at.cs, at.tpool, TransactionSimulationConfig{
+		ChainConstants:    at.chainCts,
+		Validators:        gcconsensus.GetTestnetTransactionValidators(),
+		MappedValidators:  gcconsensus.GetTestnetTransactionVersionMappedValidators(),
+		CustodyFeesPlugin: at.plugin,
+	}))
+
+	// the custody fee of the genesis coin output of the tester's address,
+	// computed at a time later than the genesis block
+	genesis, ok := at.cs.BlockAtHeight(0)
+	if !ok {
+		t.Fatal("genesis block not found")
+	}
+	parentID := genesis.Transactions[0].CoinOutputID(0)
+	computationTime := at.chainCts.GenesisTimestamp + custodyFeePeriod
+	info, err := at.plugin.GetCoinOutputInfo(parentID, computationTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CustodyFee.IsZero() {
+		t.Fatal("expected a non-zero custody fee")
+	}
+	minerFee := at.chainCts.MinimumTransactionFee
+	receiver := types.NewCondition(types.NewUnlockHashCondition(types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{3})))
+
+	// newTransaction creates a signed transaction spending the genesis coin output,
+	// paying the given custody fee, if any, and sending the remainder to the receiver
+	newTransaction := func(parentID types.CoinOutputID, custodyFee *types.Currency) types.Transaction {
+		txn := types.Transaction{
+			Version: at.chainCts.DefaultTransactionVersion,
+			CoinInputs: []types.CoinInput{{
+				ParentID:    parentID,
+				Fulfillment: types.NewFulfillment(types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(at.publicKey))),
+			}},
+			MinerFees: []types.Currency{minerFee},
+		}
+		value := info.CreationValue.Sub(minerFee)
+		if custodyFee != nil {
+			txn.CoinOutputs = append(txn.CoinOutputs, types.CoinOutput{
+				Value:     *custodyFee,
+				Condition: types.NewCondition(&cftypes.CustodyFeeCondition{ComputationTime: computationTime}),
+			})
+			value = value.Sub(*custodyFee)
+		}
+		txn.CoinOutputs = append(txn.CoinOutputs, types.CoinOutput{Value: value, Condition: receiver})
+		err := txn.CoinInputs[0].Fulfillment.Sign(types.FulfillmentSignContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			Transaction:  txn,
+			Key:          at.secretKey,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txn
+	}
+	simulate := func(txn types.Transaction) TransactionPoolSimulatePOSTResp {
+		var resp TransactionPoolSimulatePOSTResp
+		if status := post(t, router, "/transactionpool/simulate", txn, &resp); status != http.StatusOK {
+			t.Fatalf("unexpected status %d", status)
+		}
+		if resp.TransactionID != txn.ID() {
+			t.Errorf("unexpected transaction ID %s, expected %s", resp.TransactionID.String(), txn.ID().String())
+		}
+		return resp
+	}
+	findRule := func(resp TransactionPoolSimulatePOSTResp, validator, rule string) TransactionRuleResult {
+		for _, result := range resp.Rules {
+			if result.Validator == validator && result.Rule == rule {
+				return result
+			}
+		}
+		t.Fatalf("rule %q of validator %q not reported: %+v", rule, validator, resp.Rules)
+		return TransactionRuleResult{}
+	}
+
+	// a transaction paying the expected custody fee passes all rules
+	resp := simulate(newTransaction(parentID, &info.CustodyFee))
+	if !resp.Valid {
+		t.Errorf("expected a valid transaction: %+v", resp.Rules)
+	}
+	for _, rule := range resp.Rules {
+		if !rule.Passed {
+			t.Errorf("rule %q of validator %q failed: %s", rule.Rule, rule.Validator, rule.Error)
+		}
+	}
+	if rule := findRule(resp, simulationValidatorStandalone, "coin outputs are valid"); !rule.Passed {
+		t.Errorf("unexpected standalone rule result %+v", rule)
+	}
+	if resp.CustodyFee == nil {
+		t.Fatal("expected a custody fee to be reported")
+	}
+	if resp.CustodyFee.ComputationTime != computationTime || !resp.CustodyFee.Expected.Equals(info.CustodyFee) || !resp.CustodyFee.Paid.Equals(info.CustodyFee) {
+		t.Errorf("unexpected custody fee %+v, expected %s", *resp.CustodyFee, info.CustodyFee.String())
+	}
+	if len(resp.CustodyFee.Inputs) != 1 || resp.CustodyFee.Inputs[0].ParentID != parentID || resp.CustodyFee.Inputs[0].Unconfirmed ||
+		!resp.CustodyFee.Inputs[0].Custody.CustodyFee.Equals(info.CustodyFee) {
+		t.Errorf("unexpected custody fee inputs %+v", resp.CustodyFee.Inputs)
+	}
+	if len(resp.Balances) == 0 || resp.Balances[0].Address.Cmp(at.address) != 0 ||
+		!resp.Balances[0].Spent.Equals(info.CreationValue) || !resp.Balances[0].CustodyFee.Equals(info.CustodyFee) {
+		t.Errorf("unexpected balance changes %+v", resp.Balances)
+	}
+
+	// a transaction paying another custody fee fails with the expected custody fee as detail
+	wrongFee := info.CustodyFee.Add(types.NewCurrency64(1))
+	resp = simulate(newTransaction(parentID, &wrongFee))
+	if resp.Valid {
+		t.Error("expected a transaction paying the wrong custody fee to be invalid")
+	}
+	rule := findRule(resp, simulationValidatorCustodyFees, "custody fee is paid")
+	if rule.Passed || rule.Code != gctypes.ErrorCodeCustodyFeeMismatch ||
+		rule.Details["expected"] != info.CustodyFee.String() || rule.Details["provided"] != wrongFee.String() {
+		t.Errorf("unexpected custody fee rule result %+v", rule)
+	}
+	if resp.CustodyFee == nil || !resp.CustodyFee.Expected.Equals(info.CustodyFee) || !resp.CustodyFee.Paid.Equals(wrongFee) {
+		t.Errorf("unexpected custody fee %+v", resp.CustodyFee)
+	}
+	if rule = findRule(resp, simulationValidatorConsensus, "transaction applies to the consensus state"); rule.Passed {
+		t.Error("expected the transaction not to apply to the consensus state")
+	}
+
+	// a transaction without custody fee output fails, reporting the custody fee at the time of the current block
+	resp = simulate(newTransaction(parentID, nil))
+	rule = findRule(resp, simulationValidatorCustodyFees, "custody fee is paid")
+	if resp.Valid || rule.Passed || rule.Code != gctypes.ErrorCodeCustodyFeeMissing {
+		t.Errorf("unexpected custody fee rule result %+v", rule)
+	}
+	if resp.CustodyFee == nil || resp.CustodyFee.ComputationTime != at.chainCts.GenesisTimestamp {
+		t.Errorf("unexpected custody fee %+v", resp.CustodyFee)
+	}
+
+	// a transaction spending an unknown coin output fails, without validating any other rule against it
+	resp = simulate(newTransaction(types.CoinOutputID{1}, &info.CustodyFee))
+	rule = findRule(resp, simulationValidatorConsensus, "spent outputs are unspent")
+	if resp.Valid || rule.Passed {
+		t.Errorf("unexpected rule result %+v for an unknown coin output", rule)
+	}
+	for _, result := range resp.Rules {
+		if result.Validator == simulationValidatorStandalone || result.Validator == simulationValidatorCustodyFees {
+			t.Errorf("unexpected rule %q of validator %q reported for an unknown coin output", result.Rule, result.Validator)
+		}
+	}
+	if resp.CustodyFee != nil {
+		t.Errorf("unexpected custody fee %+v for an unknown coin output", resp.CustodyFee)
+	}
+}
@@ -1,11 +1,18 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/pkg/api"
 	"github.com/threefoldtech/rivine/types"
@@ -15,6 +22,7 @@ import (
 	"github.com/nbh-digital/goldchain/extensions/custodyfees"
 	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
 	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
 type (
@@ -33,6 +41,89 @@ type (
 		LockedBlockStakeBalance types.Currency `json:"lockedblockstakebalance"`
 
 		MultiSigWallets []gcmodules.MultiSigWallet `json:"multisigwallets"`
+
+		// WatchOnly is only defined if the wallet has watch-only addresses
+		WatchOnly *WalletWatchOnlyGET `json:"watchonly,omitempty"`
+	}
+
+	// WalletWatchOnlyGET contains the confirmed balances and custody fee debt
+	// of all watch-only addresses of the wallet, in total and per address.
+	WalletWatchOnlyGET struct {
+		gcmodules.Balance
+		Addresses []WalletWatchOnlyAddressGET `json:"addresses"`
+	}
+
+	// WalletWatchOnlyAddressGET contains the confirmed balances and custody fee debt
+	// of a single watch-only address.
+	WalletWatchOnlyAddressGET struct {
+		gcmodules.WatchOnlyAddress
+		gcmodules.Balance
+	}
+
+	// WalletWatchOnlyPOST is the body used to add or remove watch-only addresses.
+	// Public keys can only be given when adding watch-only addresses.
+	WalletWatchOnlyPOST struct {
+		Addresses  []types.UnlockHash `json:"addresses,omitempty"`
+		PublicKeys []types.PublicKey  `json:"publickeys,omitempty"`
+	}
+
+	// WalletWatchOnlyTransactionPOST is the body used to create
+	// an unsigned transaction funded by the watch-only addresses of the wallet.
+	WalletWatchOnlyTransactionPOST struct {
+		CoinOutputs   []types.CoinOutput `json:"coinoutputs"`
+		Data          []byte             `json:"data,omitempty"`
+		RefundAddress *types.UnlockHash  `json:"refundaddress,omitempty"`
+	}
+
+	// WalletLabelsGET contains the labels, notes and tags of the labeled addresses of the wallet.
+	WalletLabelsGET struct {
+		Labels []gcmodules.AddressLabel `json:"labels"`
+	}
+
+	// WalletLabelsPOST is the body used to label addresses.
+	WalletLabelsPOST struct {
+		Labels []gcmodules.AddressLabel `json:"labels"`
+	}
+
+	// WalletLabelsRemovePOST is the body used to remove the labels of addresses.
+	WalletLabelsRemovePOST struct {
+		Addresses []types.UnlockHash `json:"addresses"`
+	}
+
+	// WalletHDGET contains the derivation progress of all HD accounts of the wallet.
+	WalletHDGET struct {
+		Accounts []gcmodules.HDAccount `json:"accounts"`
+	}
+
+	// WalletHDAddressesPOST is the body used to reserve
+	// the next deposit addresses of an HD account.
+	WalletHDAddressesPOST struct {
+		Account uint32 `json:"account"`
+		Count   uint64 `json:"count"`
+	}
+
+	// WalletHDAddressesPOSTResp is the response returned
+	// when reserving the next deposit addresses of an HD account.
+	WalletHDAddressesPOSTResp struct {
+		Addresses []gcmodules.HDAddress `json:"addresses"`
+	}
+
+	// WalletScanGET contains the keys and funds discovered for each seed of the wallet,
+	// as well as the address gap limit used to discover them.
+	WalletScanGET struct {
+		GapLimit uint64                     `json:"gaplimit"`
+		Seeds    []gcmodules.SeedScanReport `json:"seeds"`
+	}
+
+	// WalletGapLimitPOST is the body used to configure the address gap limit of the wallet.
+	WalletGapLimitPOST struct {
+		GapLimit uint64 `json:"gaplimit"`
+	}
+
+	// WalletWatchOnlyTransactionPOSTResp is the response returned
+	// for the creation of an unsigned watch-only transaction.
+	WalletWatchOnlyTransactionPOSTResp struct {
+		Transaction types.Transaction `json:"transaction"`
 	}
 
 	// WalletListUnlockedGET contains the set of unspent, unlocked coin
@@ -57,10 +148,159 @@ type (
 	}
 
 	// WalletTransactionsGET contains the specified set of confirmed and
-	// unconfirmed transactions.
+	// unconfirmed transactions, as returned by /wallet/history.
 	WalletTransactionsGET struct {
 		ConfirmedTransactions   []gcmodules.ProcessedTransaction `json:"confirmedtransactions"`
 		UnconfirmedTransactions []modules.ProcessedTransaction   `json:"unconfirmedtransactions"`
+		// WatchOnlyTransactions are the confirmed transactions related to the watch-only addresses of the wallet
+		WatchOnlyTransactions []gcmodules.ProcessedTransaction `json:"watchonlytransactions,omitempty"`
+		// NextCursor and WatchOnlyNextCursor are only defined if more confirmed (watch-only) transactions
+		// are available than the defined limit, and can be used as the cursor to fetch the next page
+		NextCursor          string `json:"nextcursor,omitempty"`
+		WatchOnlyNextCursor string `json:"watchonlynextcursor,omitempty"`
+	}
+
+	// WalletExportGET contains the coin movements of the wallet,
+	// confirmed within the requested time range, to be used for accounting purposes.
+	WalletExportGET struct {
+		Movements []gcmodules.WalletMovement `json:"movements"`
+	}
+
+	// WalletStatementGET contains the statement of the wallet,
+	// summarizing its coin movements and custody fees per period.
+	WalletStatementGET struct {
+		Periods []gcmodules.WalletStatementPeriod `json:"periods"`
+	}
+
+	// WalletPSTCreatePOST is the body used to wrap a transaction into a partially signed transaction (PST).
+	WalletPSTCreatePOST struct {
+		Transaction types.Transaction `json:"transaction"`
+	}
+
+	// WalletPSTCombinePOST is the body used to combine the signatures of multiple PSTs,
+	// all created for the same transaction.
+	WalletPSTCombinePOST struct {
+		PSTs []gcmodules.PartiallySignedTransaction `json:"psts"`
+	}
+
+	// WalletPSTPOSTResp is the response returned for the creation, signing, combination or inspection of a PST.
+	WalletPSTPOSTResp struct {
+		PST gcmodules.PartiallySignedTransaction `json:"pst"`
+		// TransactionID is the ID of the transaction in its current state,
+		// as the ID of a transaction changes with every signature added
+		TransactionID types.TransactionID `json:"transactionid"`
+		Complete      bool                `json:"complete"`
+		// MissingSigners are the addresses that can still sign an incomplete input of the PST
+		MissingSigners []types.UnlockHash `json:"missingsigners,omitempty"`
+	}
+
+	// WalletMultiSigFundPOST is the body used to send coins from a multisig wallet,
+	// which this wallet is a co-signer of.
+	WalletMultiSigFundPOST struct {
+		CoinOutputs []types.CoinOutput `json:"coinoutputs"`
+		Data        []byte             `json:"data,omitempty"`
+	}
+
+	// WalletAtomicSwapInitiatePOST is the body used to initiate an atomic swap,
+	// creating a contract of which the secret is generated by the wallet.
+	WalletAtomicSwapInitiatePOST struct {
+		Receiver types.UnlockHash `json:"receiver"`
+		Amount   types.Currency   `json:"amount"`
+		// Receivers optionally defines additional receivers, creating a multi-output contract,
+		// the receiver above is optional in case at least one additional receiver is given
+		Receivers []gcmodules.AtomicSwapReceiver `json:"receivers,omitempty"`
+		// Duration is optional and defaults to 48h, it is to be formatted as a Go duration string (e.g. "48h")
+		Duration string `json:"duration,omitempty"`
+		// Sender is optional, a new address of the wallet is used if not defined
+		Sender *types.UnlockHash `json:"sender,omitempty"`
+		// EscrowCustodyFee defines whether or not the custody fee of the contract is pre-funded,
+		// in which case the receiver receives at least the given amount when redeeming the contract in time
+		EscrowCustodyFee bool `json:"escrowcustodyfee,omitempty"`
+	}
+
+	// WalletAtomicSwapParticipatePOST is the body used to participate in an atomic swap,
+	// creating a contract using the hashed secret of the initiator.
+	WalletAtomicSwapParticipatePOST struct {
+		Receiver     types.UnlockHash             `json:"receiver"`
+		Amount       types.Currency               `json:"amount"`
+		HashedSecret types.AtomicSwapHashedSecret `json:"hashedsecret"`
+		// Receivers optionally defines additional receivers, creating a multi-output contract,
+		// the receiver above is optional in case at least one additional receiver is given
+		Receivers []gcmodules.AtomicSwapReceiver `json:"receivers,omitempty"`
+		// Duration is optional and defaults to 24h, it is to be formatted as a Go duration string (e.g. "24h")
+		Duration string `json:"duration,omitempty"`
+		// Sender is optional, a new address of the wallet is used if not defined
+		Sender *types.UnlockHash `json:"sender,omitempty"`
+		// EscrowCustodyFee defines whether or not the custody fee of the contract is pre-funded,
+		// in which case the receiver receives at least the given amount when redeeming the contract in time
+		EscrowCustodyFee bool `json:"escrowcustodyfee,omitempty"`
+	}
+
+	// WalletAtomicSwapContractPOSTResp is the response returned for a created atomic swap contract.
+	WalletAtomicSwapContractPOSTResp struct {
+		// Contract is the contract of the first receiver,
+		// Contracts contains the contracts of all receivers, in the order they were given
+		Contract      gcmodules.AtomicSwapContract   `json:"contract"`
+		Contracts     []gcmodules.AtomicSwapContract `json:"contracts"`
+		TransactionID types.TransactionID            `json:"transactionid"`
+		// Secret is only defined for an initiated contract,
+		// and is not to be shared until the contract of the participant has been audited
+		Secret *types.AtomicSwapSecret `json:"secret,omitempty"`
+	}
+
+	// WalletAtomicSwapRedeemPOST is the body used to redeem an atomic swap contract,
+	// or to create the PST redeeming the atomic swap contract of a multisig receiver.
+	WalletAtomicSwapRedeemPOST struct {
+		OutputID types.CoinOutputID `json:"outputid"`
+		// OutputIDs optionally defines additional contracts, redeemed in the same transaction,
+		// the output ID above is optional in case at least one additional output ID is given
+		OutputIDs []types.CoinOutputID   `json:"outputids,omitempty"`
+		Secret    types.AtomicSwapSecret `json:"secret"`
+	}
+
+	// WalletAtomicSwapRefundPOST is the body used to refund an atomic swap contract.
+	WalletAtomicSwapRefundPOST struct {
+		OutputID types.CoinOutputID `json:"outputid"`
+		// OutputIDs optionally defines additional contracts, refunded in the same transaction,
+		// the output ID above is optional in case at least one additional output ID is given
+		OutputIDs []types.CoinOutputID `json:"outputids,omitempty"`
+	}
+
+	// WalletAtomicSwapSpendPOSTResp is the response returned for a redeemed or refunded atomic swap contract.
+	WalletAtomicSwapSpendPOSTResp struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+	}
+
+	// WalletAtomicSwapAuditGET contains the audited atomic swap contract(s),
+	// Contract being the contract of the first given output ID.
+	WalletAtomicSwapAuditGET struct {
+		Contract  gcmodules.AtomicSwapContract   `json:"contract"`
+		Contracts []gcmodules.AtomicSwapContract `json:"contracts"`
+	}
+
+	// WalletAtomicSwapContractsGET contains the atomic swap contracts tracked by the wallet.
+	WalletAtomicSwapContractsGET struct {
+		Contracts []gcmodules.TrackedAtomicSwapContract `json:"contracts"`
+	}
+
+	// WalletPSTFinalizePOSTResp is the response returned for a PST given to the transaction pool.
+	WalletPSTFinalizePOSTResp struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+	}
+
+	// WalletBurnPOST is the body used to burn coins in a coin destruction transaction.
+	WalletBurnPOST struct {
+		Amount types.Currency `json:"amount"`
+		// Data is optional, and is attached as the arbitrary data of the transaction
+		Data []byte `json:"data,omitempty"`
+		// RefundAddress is optional, an address of the wallet is used if not defined
+		RefundAddress    *types.UnlockHash `json:"refundaddress,omitempty"`
+		RefundAddressNew bool              `json:"refundaddressnew,omitempty"`
+	}
+
+	// WalletBurnPOSTResp is the response returned for burned coins.
+	WalletBurnPOSTResp struct {
+		TransactionID types.TransactionID `json:"transactionid"`
 	}
 
 	// WalletFundCoinsGet is the resulting object that is returned,
@@ -92,64 +332,107 @@ func RegisterWalletHTTPHandlers(router api.Router, wallet gcmodules.Wallet, requ
 	router.GET("/wallet/seeds", api.RequirePasswordHandler(api.NewWalletSeedsHandler(wallet), requiredPassword))
 	router.GET("/wallet/key/:unlockhash", api.RequirePasswordHandler(api.NewWalletKeyHandler(wallet), requiredPassword))
 	router.POST("/wallet/transaction", api.RequirePasswordHandler(api.NewWalletTransactionCreateHandler(wallet), requiredPassword))
-	router.POST("/wallet/coins", api.RequirePasswordHandler(api.NewWalletCoinsHandler(wallet), requiredPassword))
-	router.POST("/wallet/blockstakes", api.RequirePasswordHandler(api.NewWalletBlockStakesHandler(wallet), requiredPassword))
+	router.POST("/wallet/coins", api.RequirePasswordHandler(NewWalletCoinsHandler(wallet), requiredPassword))
+	router.POST("/wallet/blockstakes", api.RequirePasswordHandler(NewWalletBlockStakesHandler(wallet), requiredPassword))
+	router.POST("/wallet/burn", api.RequirePasswordHandler(NewWalletBurnHandler(wallet), requiredPassword))
 	router.GET("/wallet/transaction/:id", api.NewWalletTransactionHandler(wallet))
 	router.GET("/wallet/transactions", api.NewWalletTransactionsHandler(wallet))
 	router.GET("/wallet/transactions/:addr", api.NewWalletTransactionsAddrHandler(wallet))
+	router.GET("/wallet/history", NewWalletHistoryHandler(wallet))
+	router.GET("/wallet/export", api.RequirePasswordHandler(NewWalletExportHandler(wallet), requiredPassword))
+	router.GET("/wallet/statement", api.RequirePasswordHandler(NewWalletStatementHandler(wallet), requiredPassword))
 	router.POST("/wallet/unlock", api.RequirePasswordHandler(api.NewWalletUnlockHandler(wallet), requiredPassword))
 	router.GET("/wallet/unlocked", api.RequirePasswordHandler(NewWalletListUnlockedHandler(wallet), requiredPassword))
 	router.GET("/wallet/locked", api.RequirePasswordHandler(NewWalletListLockedHandler(wallet), requiredPassword))
 	router.POST("/wallet/create/transaction", api.RequirePasswordHandler(api.NewWalletCreateTransactionHandler(wallet), requiredPassword))
+	router.POST("/wallet/create/coins", api.RequirePasswordHandler(NewWalletCreateCoinsHandler(wallet), requiredPassword))
 	router.POST("/wallet/sign", api.RequirePasswordHandler(api.NewWalletSignHandler(wallet), requiredPassword))
 	router.GET("/wallet/publickey", api.RequirePasswordHandler(api.NewWalletGetPublicKeyHandler(wallet), requiredPassword))
 	router.GET("/wallet/fund/coins", api.RequirePasswordHandler(NewWalletFundCoinsHandler(wallet), requiredPassword))
+	router.GET("/wallet/watchonly", api.RequirePasswordHandler(NewWalletWatchOnlyHandler(wallet), requiredPassword))
+	router.POST("/wallet/watchonly/add", api.RequirePasswordHandler(NewWalletWatchOnlyAddHandler(wallet), requiredPassword))
+	router.POST("/wallet/watchonly/remove", api.RequirePasswordHandler(NewWalletWatchOnlyRemoveHandler(wallet), requiredPassword))
+	router.POST("/wallet/watchonly/transaction", api.RequirePasswordHandler(NewWalletWatchOnlyTransactionHandler(wallet), requiredPassword))
+	router.GET("/wallet/labels", api.RequirePasswordHandler(NewWalletLabelsHandler(wallet), requiredPassword))
+	router.POST("/wallet/labels", api.RequirePasswordHandler(NewWalletLabelsSetHandler(wallet), requiredPassword))
+	router.POST("/wallet/labels/remove", api.RequirePasswordHandler(NewWalletLabelsRemoveHandler(wallet), requiredPassword))
+	router.GET("/wallet/hd", api.RequirePasswordHandler(NewWalletHDHandler(wallet), requiredPassword))
+	router.POST("/wallet/hd/enable", api.RequirePasswordHandler(NewWalletHDEnableHandler(wallet), requiredPassword))
+	router.POST("/wallet/hd/addresses", api.RequirePasswordHandler(NewWalletHDAddressesHandler(wallet), requiredPassword))
+	router.GET("/wallet/scan", api.RequirePasswordHandler(NewWalletScanHandler(wallet), requiredPassword))
+	router.POST("/wallet/scan", api.RequirePasswordHandler(NewWalletRescanHandler(wallet), requiredPassword))
+	router.POST("/wallet/gaplimit", api.RequirePasswordHandler(NewWalletGapLimitHandler(wallet), requiredPassword))
+	router.POST("/wallet/multisig/:address/fund", api.RequirePasswordHandler(NewWalletMultiSigFundHandler(wallet), requiredPassword))
+	router.POST("/wallet/pst/create", api.RequirePasswordHandler(NewWalletPSTCreateHandler(wallet), requiredPassword))
+	router.POST("/wallet/pst/sign", api.RequirePasswordHandler(NewWalletPSTSignHandler(wallet), requiredPassword))
+	router.POST("/wallet/pst/combine", api.RequirePasswordHandler(NewWalletPSTCombineHandler(wallet), requiredPassword))
+	router.POST("/wallet/pst/inspect", api.RequirePasswordHandler(NewWalletPSTInspectHandler(wallet), requiredPassword))
+	router.POST("/wallet/pst/finalize", api.RequirePasswordHandler(NewWalletPSTFinalizeHandler(wallet), requiredPassword))
+	router.POST("/wallet/atomicswap/initiate", api.RequirePasswordHandler(NewWalletAtomicSwapInitiateHandler(wallet), requiredPassword))
+	router.POST("/wallet/atomicswap/participate", api.RequirePasswordHandler(NewWalletAtomicSwapParticipateHandler(wallet), requiredPassword))
+	router.POST("/wallet/atomicswap/redeem", api.RequirePasswordHandler(NewWalletAtomicSwapRedeemHandler(wallet), requiredPassword))
+	router.POST("/wallet/atomicswap/redeem/multisig", api.RequirePasswordHandler(NewWalletAtomicSwapRedeemMultiSigHandler(wallet), requiredPassword))
+	router.POST("/wallet/atomicswap/refund", api.RequirePasswordHandler(NewWalletAtomicSwapRefundHandler(wallet), requiredPassword))
+	router.GET("/wallet/atomicswap/audit", NewWalletAtomicSwapAuditHandler(wallet))
+	router.GET("/wallet/atomicswap/contracts", NewWalletAtomicSwapContractsHandler(wallet))
+	router.POST("/wallet/changepassword", api.RequirePasswordHandler(NewWalletChangePasswordHandler(wallet), requiredPassword))
 }
 
 // NewWalletRootHandler creates a handler to handle API calls to /wallet.
+// The watch-only addresses of the wallet are tracked while the wallet is locked as well,
+// such that their section is returned even if the balances owned by the wallet are not.
 func NewWalletRootHandler(wallet gcmodules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-		coinBal, blockstakeBal, err := wallet.ConfirmedBalance()
+		balances, err := wallet.ConfirmedBalances()
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
-		coinLockBal, blockstakeLockBal, err := wallet.ConfirmedLockedBalance()
+		watchOnly, err := getWalletWatchOnly(wallet, balances)
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
-		custodyFeesToBePaid, err := wallet.ConfirmedCustodyFeesToBePaid()
-		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
+		if len(watchOnly.Addresses) == 0 {
+			watchOnly = nil
+		}
+		status := WalletGET{
+			Encrypted: wallet.Encrypted(),
+			Unlocked:  wallet.Unlocked(),
+
+			WatchOnly: watchOnly,
+		}
+		if balances.Owned == nil {
+			if watchOnly == nil {
+				WriteError(w, NewError("error after call to /wallet: ", modules.ErrLockedWallet), walletErrorToHTTPStatus(modules.ErrLockedWallet))
+				return
+			}
+			api.WriteJSON(w, status)
 			return
 		}
+
 		coinsOut, coinsIn, err := wallet.UnconfirmedBalance()
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
 		multiSigWallets, err := wallet.MultiSigWalletsWithCustodyFeeDebt()
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
 
-		api.WriteJSON(w, WalletGET{
-			Encrypted: wallet.Encrypted(),
-			Unlocked:  wallet.Unlocked(),
-
-			ConfirmedCoinBalance:       coinBal,
-			ConfirmedLockedCoinBalance: coinLockBal,
-			ConfirmedCustodyFeeDebt:    custodyFeesToBePaid,
-			UnconfirmedOutgoingCoins:   coinsOut,
-			UnconfirmedIncomingCoins:   coinsIn,
+		status.ConfirmedCoinBalance = balances.Owned.ConfirmedCoinBalance
+		status.ConfirmedLockedCoinBalance = balances.Owned.ConfirmedLockedCoinBalance
+		status.ConfirmedCustodyFeeDebt = balances.Owned.ConfirmedCustodyFeeDebt
+		status.UnconfirmedOutgoingCoins = coinsOut
+		status.UnconfirmedIncomingCoins = coinsIn
 
-			BlockStakeBalance:       blockstakeBal,
-			LockedBlockStakeBalance: blockstakeLockBal,
+		status.BlockStakeBalance = balances.Owned.ConfirmedBlockStakeBalance
+		status.LockedBlockStakeBalance = balances.Owned.ConfirmedLockedBlockStakeBalance
 
-			MultiSigWallets: multiSigWallets,
-		})
+		status.MultiSigWallets = multiSigWallets
+		api.WriteJSON(w, status)
 	}
 }
 
@@ -158,7 +441,7 @@ func NewWalletListUnlockedHandler(wallet gcmodules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 		ucos, ubsos, err := wallet.UnlockedUnspendOutputsWithCustodyFeeInfo()
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet/unlocked: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet/unlocked: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
 		ucor := []UnspentCoinOutput{}
@@ -184,7 +467,7 @@ func NewWalletListLockedHandler(wallet gcmodules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 		ucos, ubsos, err := wallet.LockedUnspendOutputsWithCustodyFeeInfo()
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet/locked: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet/locked: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
 		ucor := []UnspentCoinOutput{}
@@ -205,12 +488,14 @@ func NewWalletListLockedHandler(wallet gcmodules.Wallet) httprouter.Handle {
 	}
 }
 
-// NewWalletTransactionsHandler creates a handler to handle API calls to /wallet/transactions.
-func NewWalletTransactionsHandler(wallet gcmodules.Wallet) httprouter.Handle {
+// NewWalletHistoryHandler creates a handler to handle API calls to /wallet/history.
+// Contrary to /wallet/transactions, the confirmed transactions are listed with their custody fee info,
+// can be filtered and paginated, and the transactions of the watch-only addresses are listed separately.
+func NewWalletHistoryHandler(wallet gcmodules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 		startheightStr, endheightStr := req.FormValue("startheight"), req.FormValue("endheight")
 		if startheightStr == "" || endheightStr == "" {
-			api.WriteError(w, api.Error{Message: "startheight and endheight must be provided to a /wallet/transactions call."}, http.StatusBadRequest)
+			api.WriteError(w, api.Error{Message: "startheight and endheight must be provided to a /wallet/history call."}, http.StatusBadRequest)
 			return
 		}
 		// Get the start and end blocks.
@@ -224,24 +509,174 @@ func NewWalletTransactionsHandler(wallet gcmodules.Wallet) httprouter.Handle {
 			api.WriteError(w, api.Error{Message: "parsing integer value for parameter `endheight` failed: " + err.Error()}, http.StatusBadRequest)
 			return
 		}
-		confirmedTxns, err := wallet.TransactionsWithCustodyInfo(types.BlockHeight(start), types.BlockHeight(end))
+		filter, err := parseTransactionFilter(req)
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet/transactions: " + err.Error()}, walletErrorToHTTPStatus(err))
+			api.WriteError(w, api.Error{Message: err.Error()}, http.StatusBadRequest)
 			return
 		}
-		unconfirmedTxns, err := wallet.UnconfirmedTransactions()
+		filter.StartHeight, filter.EndHeight = types.BlockHeight(start), types.BlockHeight(end)
+
+		var resp WalletTransactionsGET
+		// the transactions of the watch-only addresses are listed while the wallet is locked as well
+		if wallet.Unlocked() {
+			resp.ConfirmedTransactions, resp.NextCursor, err = wallet.TransactionsWithCustodyInfo(filter)
+			if err != nil {
+				WriteError(w, NewError("error after call to /wallet/history: ", err), walletErrorToHTTPStatus(err))
+				return
+			}
+			resp.UnconfirmedTransactions, err = wallet.UnconfirmedTransactions()
+			if err != nil {
+				WriteError(w, NewError("error after call to /wallet/history: ", err), walletErrorToHTTPStatus(err))
+				return
+			}
+		} else {
+			addresses, err := wallet.WatchOnlyAddresses()
+			if err == nil && len(addresses) == 0 {
+				err = modules.ErrLockedWallet
+			}
+			if err != nil {
+				WriteError(w, NewError("error after call to /wallet/history: ", err), walletErrorToHTTPStatus(err))
+				return
+			}
+		}
+		filter.Cursor = req.FormValue("watchonlycursor")
+		filter.WatchOnly = true
+		resp.WatchOnlyTransactions, resp.WatchOnlyNextCursor, err = wallet.TransactionsWithCustodyInfo(filter)
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "error after call to /wallet/transactions: " + err.Error()}, walletErrorToHTTPStatus(err))
+			WriteError(w, NewError("error after call to /wallet/history: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
+		api.WriteJSON(w, resp)
+	}
+}
 
-		api.WriteJSON(w, WalletTransactionsGET{
-			ConfirmedTransactions:   confirmedTxns,
-			UnconfirmedTransactions: unconfirmedTxns,
+// parseTransactionFilter parses the optional filter and pagination parameters
+// of a /wallet/history call. The height range is not parsed.
+func parseTransactionFilter(req *http.Request) (filter gcmodules.TransactionFilter, err error) {
+	if str := req.FormValue("address"); str != "" {
+		var uh types.UnlockHash
+		err = uh.LoadString(str)
+		if err != nil {
+			return filter, fmt.Errorf("invalid address given: %v", err)
+		}
+		filter.Address = &uh
+	}
+	filter.Direction = gcmodules.TransactionDirection(req.FormValue("direction"))
+	if str := req.FormValue("category"); str != "" {
+		for _, category := range strings.Split(str, ",") {
+			filter.Categories = append(filter.Categories, gcmodules.TransactionCategory(strings.TrimSpace(category)))
+		}
+	}
+	for param, ts := range map[string]*types.Timestamp{
+		"starttime": &filter.StartTime,
+		"endtime":   &filter.EndTime,
+	} {
+		if str := req.FormValue(param); str != "" {
+			n, err := strconv.ParseUint(str, 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("parsing integer value for parameter `%s` failed: %v", param, err)
+			}
+			*ts = types.Timestamp(n)
+		}
+	}
+	for param, amount := range map[string]*types.Currency{
+		"minamount": &filter.MinAmount,
+		"maxamount": &filter.MaxAmount,
+	} {
+		if str := req.FormValue(param); str != "" {
+			err = amount.LoadString(str)
+			if err != nil {
+				return filter, fmt.Errorf("invalid value for parameter `%s` given: %v", param, err)
+			}
+		}
+	}
+	if str := req.FormValue("limit"); str != "" {
+		filter.Limit, err = strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("parsing integer value for parameter `limit` failed: %v", err)
+		}
+	}
+	filter.Cursor = req.FormValue("cursor")
+	return filter, nil
+}
+
+// NewWalletExportHandler creates a handler to handle API calls to /wallet/export?starttime=&endtime=.
+// The running balance of the returned movements is computed using the entire history of the wallet.
+func NewWalletExportHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		start, end, err := parseTimeRange(req)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		// the history prior to the start time is required to compute the running balance
+		pts, _, err := wallet.TransactionsWithCustodyInfo(gcmodules.TransactionFilter{
+			EndHeight: types.BlockHeight(math.MaxUint64),
+			EndTime:   end,
 		})
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/export: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		movements := []gcmodules.WalletMovement{}
+		for _, movement := range gcmodules.WalletMovements(pts, types.ZeroCurrency) {
+			if movement.Timestamp >= start {
+				movements = append(movements, movement)
+			}
+		}
+		api.WriteJSON(w, WalletExportGET{Movements: movements})
 	}
 }
 
+// NewWalletStatementHandler creates a handler to handle API calls to /wallet/statement?period=&starttime=&endtime=.
+func NewWalletStatementHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		start, end, err := parseTimeRange(req)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		period := gcmodules.StatementPeriod(req.FormValue("period"))
+		if period == "" {
+			period = gcmodules.StatementPeriodMonth
+		}
+		pts, _, err := wallet.TransactionsWithCustodyInfo(gcmodules.TransactionFilter{
+			EndHeight: types.BlockHeight(math.MaxUint64),
+			EndTime:   end,
+		})
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/statement: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		statement, err := gcmodules.WalletStatement(pts, period, start, end, types.CurrentTimestamp())
+		if err != nil {
+			api.WriteError(w, api.Error{Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		api.WriteJSON(w, WalletStatementGET{Periods: statement})
+	}
+}
+
+// parseTimeRange parses the optional starttime and endtime parameters, as unix epoch timestamps.
+func parseTimeRange(req *http.Request) (start, end types.Timestamp, err error) {
+	for param, ts := range map[string]*types.Timestamp{
+		"starttime": &start,
+		"endtime":   &end,
+	} {
+		if str := req.FormValue(param); str != "" {
+			n, err := strconv.ParseUint(str, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parsing integer value for parameter `%s` failed: %v", param, err)
+			}
+			*ts = types.Timestamp(n)
+		}
+	}
+	if end != 0 && start > end {
+		return 0, 0, errors.New("starttime cannot be after endtime")
+	}
+	return start, end, nil
+}
+
 // NewWalletFundCoinsHandler creates a handler to handle the API calls to /wallet/fund/coins?amount=.
 // While it might be handy for other use cases, it is needed for 3bot registration
 func NewWalletFundCoinsHandler(wallet gcmodules.Wallet) httprouter.Handle {
@@ -288,7 +723,7 @@ func NewWalletFundCoinsHandler(wallet gcmodules.Wallet) httprouter.Handle {
 		txbuilder := wallet.StartTransaction()
 		err = txbuilder.FundCoins(amount, refundAddress, !newRefundAddress)
 		if err != nil {
-			api.WriteError(w, api.Error{Message: "failed to fund the requested coins: " + err.Error()}, http.StatusInternalServerError)
+			WriteError(w, NewError("failed to fund the requested coins: ", err), walletErrorToHTTPStatus(err))
 			return
 		}
 
@@ -328,8 +763,766 @@ func NewWalletFundCoinsHandler(wallet gcmodules.Wallet) httprouter.Handle {
 	}
 }
 
+// NewWalletWatchOnlyHandler creates a handler to handle API calls to /wallet/watchonly.
+func NewWalletWatchOnlyHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		balances, err := wallet.ConfirmedBalances()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/watchonly: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		watchOnly, err := getWalletWatchOnly(wallet, balances)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/watchonly: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, watchOnly)
+	}
+}
+
+func getWalletWatchOnly(wallet gcmodules.Wallet, balances gcmodules.WalletBalances) (*WalletWatchOnlyGET, error) {
+	addresses, err := wallet.WatchOnlyAddresses()
+	if err != nil {
+		return nil, err
+	}
+	result := &WalletWatchOnlyGET{
+		Balance:   balances.WatchOnly,
+		Addresses: make([]WalletWatchOnlyAddressGET, 0, len(addresses)),
+	}
+	for _, woa := range addresses {
+		result.Addresses = append(result.Addresses, WalletWatchOnlyAddressGET{
+			WatchOnlyAddress: woa,
+			Balance:          balances.WatchOnlyAddresses[woa.Address],
+		})
+	}
+	return result, nil
+}
+
+// NewWalletWatchOnlyAddHandler creates a handler to handle API calls to /wallet/watchonly/add.
+func NewWalletWatchOnlyAddHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletWatchOnlyPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied watch-only addresses: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		addresses := make([]gcmodules.WatchOnlyAddress, 0, len(body.Addresses)+len(body.PublicKeys))
+		for _, uh := range body.Addresses {
+			addresses = append(addresses, gcmodules.WatchOnlyAddress{Address: uh})
+		}
+		for idx := range body.PublicKeys {
+			addresses = append(addresses, gcmodules.WatchOnlyAddress{PublicKey: &body.PublicKeys[idx]})
+		}
+		if len(addresses) == 0 {
+			api.WriteError(w, api.Error{Message: "at least one address or public key has to be given"}, http.StatusBadRequest)
+			return
+		}
+		err = wallet.AddWatchOnlyAddresses(addresses)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/watchonly/add: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletWatchOnlyRemoveHandler creates a handler to handle API calls to /wallet/watchonly/remove.
+func NewWalletWatchOnlyRemoveHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletWatchOnlyPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied watch-only addresses: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if len(body.PublicKeys) != 0 {
+			api.WriteError(w, api.Error{Message: "watch-only addresses can only be removed by address"}, http.StatusBadRequest)
+			return
+		}
+		if len(body.Addresses) == 0 {
+			api.WriteError(w, api.Error{Message: "at least one address has to be given"}, http.StatusBadRequest)
+			return
+		}
+		err = wallet.RemoveWatchOnlyAddresses(body.Addresses)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/watchonly/remove: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletLabelsHandler creates a handler to handle API calls to /wallet/labels.
+// The labels can be limited to a single address, using the optional address query parameter.
+func NewWalletLabelsHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var (
+			address      types.UnlockHash
+			addressGiven bool
+		)
+		if str := req.FormValue("address"); str != "" {
+			err := address.LoadString(str)
+			if err != nil {
+				api.WriteError(w, api.Error{Message: "error decoding the supplied address: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			addressGiven = true
+		}
+		labels, err := wallet.AddressLabels()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/labels: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		if addressGiven {
+			filtered := make([]gcmodules.AddressLabel, 0, 1)
+			for _, label := range labels {
+				if label.Address.Cmp(address) == 0 {
+					filtered = append(filtered, label)
+				}
+			}
+			labels = filtered
+		}
+		api.WriteJSON(w, WalletLabelsGET{Labels: labels})
+	}
+}
+
+// NewWalletLabelsSetHandler creates a handler to handle POST API calls to /wallet/labels.
+func NewWalletLabelsSetHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletLabelsPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied labels: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if len(body.Labels) == 0 {
+			api.WriteError(w, api.Error{Message: "at least one label has to be given"}, http.StatusBadRequest)
+			return
+		}
+		err = wallet.SetAddressLabels(body.Labels)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/labels: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletLabelsRemoveHandler creates a handler to handle API calls to /wallet/labels/remove.
+func NewWalletLabelsRemoveHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletLabelsRemovePOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied addresses: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if len(body.Addresses) == 0 {
+			api.WriteError(w, api.Error{Message: "at least one address has to be given"}, http.StatusBadRequest)
+			return
+		}
+		err = wallet.RemoveAddressLabels(body.Addresses)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/labels/remove: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletWatchOnlyTransactionHandler creates a handler to handle API calls to /wallet/watchonly/transaction.
+// The returned transaction is unsigned, and is to be signed offline by the owner(s) of the watch-only addresses.
+func NewWalletWatchOnlyTransactionHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletWatchOnlyTransactionPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied coin outputs: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.CreateWatchOnlyCoinTransaction(body.CoinOutputs, body.Data, body.RefundAddress)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/watchonly/transaction: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletWatchOnlyTransactionPOSTResp{
+			Transaction: txn,
+		})
+	}
+}
+
+// NewWalletCoinsHandler creates a handler to handle API calls to /wallet/coins.
+// It is the same as the rivine handler, except that errors are returned with an error code.
+func NewWalletCoinsHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body api.WalletCoinsPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied coin outputs: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.SendOutputs(body.CoinOutputs, nil, body.Data, body.RefundAddress, !body.GenerateRefundAddress)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/coins: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, api.WalletCoinsPOSTResp{
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+// NewWalletBlockStakesHandler creates a handler to handle API calls to /wallet/blockstakes.
+// It is the same as the rivine handler, except that errors are returned with an error code.
+func NewWalletBlockStakesHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body api.WalletBlockStakesPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied blockstake outputs: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.SendOutputs(nil, body.BlockStakeOutputs, body.Data, body.RefundAddress, !body.GenerateRefundAddress)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/blockstakes: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, api.WalletBlockStakesPOSTResp{
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+// NewWalletBurnHandler creates a handler to handle API calls to /wallet/burn.
+func NewWalletBurnHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletBurnPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied burn request: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.BurnCoins(body.Amount, body.Data, body.RefundAddress, !body.RefundAddressNew)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/burn: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletBurnPOSTResp{
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+// NewWalletCreateCoinsHandler creates a handler to handle API calls to /wallet/create/coins.
+// It takes the same body as /wallet/coins, but returns the signed transaction instead of broadcasting it.
+func NewWalletCreateCoinsHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body api.WalletCoinsPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied coin outputs: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.CreateCoinTransaction(body.CoinOutputs, body.Data, body.RefundAddress, !body.GenerateRefundAddress)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/create/coins: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, api.WalletCreateTransactionRESP{
+			Transaction: txn,
+		})
+	}
+}
+
+// NewWalletHDHandler creates a handler to handle API calls to /wallet/hd.
+func NewWalletHDHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		accounts, err := wallet.HDAccounts()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/hd: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletHDGET{
+			Accounts: accounts,
+		})
+	}
+}
+
+// NewWalletHDEnableHandler creates a handler to handle API calls to /wallet/hd/enable.
+func NewWalletHDEnableHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		err := wallet.EnableHDDerivation()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/hd/enable: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletHDAddressesHandler creates a handler to handle API calls to /wallet/hd/addresses.
+func NewWalletHDAddressesHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletHDAddressesPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied HD account: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if body.Count == 0 {
+			body.Count = 1
+		}
+		addresses, err := wallet.NextHDAddresses(body.Account, body.Count)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/hd/addresses: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletHDAddressesPOSTResp{
+			Addresses: addresses,
+		})
+	}
+}
+
+// NewWalletScanHandler creates a handler to handle GET API calls to /wallet/scan.
+func NewWalletScanHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		reports, err := wallet.SeedScanReports()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/scan: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletScanGET{
+			GapLimit: wallet.AddressGapLimit(),
+			Seeds:    reports,
+		})
+	}
+}
+
+// NewWalletRescanHandler creates a handler to handle POST API calls to /wallet/scan,
+// rescanning the consensus set, optionally using a newly configured address gap limit.
+func NewWalletRescanHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletGapLimitPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil && err != io.EOF {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied address gap limit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if body.GapLimit != 0 {
+			err = wallet.SetAddressGapLimit(body.GapLimit)
+			if err != nil {
+				api.WriteError(w, api.Error{Message: "error after call to /wallet/scan: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+		reports, err := wallet.RescanSeeds()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/scan: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletScanGET{
+			GapLimit: wallet.AddressGapLimit(),
+			Seeds:    reports,
+		})
+	}
+}
+
+// NewWalletGapLimitHandler creates a handler to handle API calls to /wallet/gaplimit.
+func NewWalletGapLimitHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletGapLimitPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied address gap limit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = wallet.SetAddressGapLimit(body.GapLimit)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error after call to /wallet/gaplimit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletChangePasswordHandler creates a handler to handle API calls to /wallet/changepassword.
+// The current and new passphrase are given as the passphrase and newpassphrase form values.
+func NewWalletChangePasswordHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		passphrase := req.FormValue("passphrase")
+		newPassphrase := req.FormValue("newpassphrase")
+		if passphrase == "" || newPassphrase == "" {
+			api.WriteError(w, api.Error{Message: "error after call to /wallet/changepassword: passphrase and newpassphrase are required"}, http.StatusBadRequest)
+			return
+		}
+		ph, err := crypto.HashObject(passphrase)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error after call to /wallet/changepassword: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		newPh, err := crypto.HashObject(newPassphrase)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error after call to /wallet/changepassword: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = wallet.ChangePassword(crypto.TwofishKey(ph), crypto.TwofishKey(newPh))
+		if err == modules.ErrBadEncryptionKey {
+			api.WriteError(w, api.Error{Message: "error after call to /wallet/changepassword: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/changepassword: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteSuccess(w)
+	}
+}
+
+// NewWalletMultiSigFundHandler creates a handler to handle API calls to /wallet/multisig/:address/fund.
+// The returned PST is signed by this wallet, and is to be signed by the other co-signers of the multisig wallet.
+func NewWalletMultiSigFundHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var address types.UnlockHash
+		err := address.LoadString(ps.ByName("address"))
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied multisig address: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if address.Type != types.UnlockTypeMultiSig {
+			api.WriteError(w, api.Error{Message: "the supplied address is not a multisig address"}, http.StatusBadRequest)
+			return
+		}
+		var body WalletMultiSigFundPOST
+		err = json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied coin outputs: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		pst, err := wallet.FundMultiSigCoinTransaction(address, body.CoinOutputs, body.Data)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/multisig/:address/fund: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, newWalletPSTPOSTResp(pst))
+	}
+}
+
+// NewWalletPSTCreateHandler creates a handler to handle API calls to /wallet/pst/create.
+func NewWalletPSTCreateHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletPSTCreatePOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		pst, err := wallet.CreatePartiallySignedTransaction(body.Transaction)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/pst/create: ", err), pstErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, newWalletPSTPOSTResp(pst))
+	}
+}
+
+// NewWalletPSTSignHandler creates a handler to handle API calls to /wallet/pst/sign.
+func NewWalletPSTSignHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var pst gcmodules.PartiallySignedTransaction
+		err := json.NewDecoder(req.Body).Decode(&pst)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied partially signed transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		pst, err = wallet.SignPartiallySignedTransaction(pst)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/pst/sign: ", err), pstErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, newWalletPSTPOSTResp(pst))
+	}
+}
+
+// NewWalletPSTCombineHandler creates a handler to handle API calls to /wallet/pst/combine.
+func NewWalletPSTCombineHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletPSTCombinePOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied partially signed transactions: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		pst, err := gcmodules.CombinePartiallySignedTransactions(body.PSTs...)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/pst/combine: ", err), http.StatusBadRequest)
+			return
+		}
+		api.WriteJSON(w, newWalletPSTPOSTResp(pst))
+	}
+}
+
+// NewWalletPSTInspectHandler creates a handler to handle API calls to /wallet/pst/inspect.
+// The parent outputs, custody fee and signature status of the given PST are refreshed
+// using the current state of the consensus set.
+func NewWalletPSTInspectHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var pst gcmodules.PartiallySignedTransaction
+		err := json.NewDecoder(req.Body).Decode(&pst)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied partially signed transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if pst.Version != gcmodules.PSTVersion {
+			WriteError(w, NewError("error after call to /wallet/pst/inspect: ", gcmodules.ErrUnknownPSTVersion), http.StatusBadRequest)
+			return
+		}
+		pst, err = wallet.CreatePartiallySignedTransaction(pst.Transaction)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/pst/inspect: ", err), pstErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, newWalletPSTPOSTResp(pst))
+	}
+}
+
+// NewWalletPSTFinalizeHandler creates a handler to handle API calls to /wallet/pst/finalize.
+func NewWalletPSTFinalizeHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var pst gcmodules.PartiallySignedTransaction
+		err := json.NewDecoder(req.Body).Decode(&pst)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied partially signed transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.FinalizePartiallySignedTransaction(pst)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/pst/finalize: ", err), pstErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletPSTFinalizePOSTResp{
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+func newWalletPSTPOSTResp(pst gcmodules.PartiallySignedTransaction) WalletPSTPOSTResp {
+	return WalletPSTPOSTResp{
+		PST:            pst,
+		TransactionID:  pst.Transaction.ID(),
+		Complete:       pst.Complete(),
+		MissingSigners: pst.MissingSigners(),
+	}
+}
+
+// pstErrorToHTTPStatus is the same as walletErrorToHTTPStatus,
+// except that a PST missing signatures is considered a bad request.
+func pstErrorToHTTPStatus(err error) int {
+	if cErr, ok := gctypes.AsCodedError(err); ok && cErr.Code == gctypes.ErrorCodeMissingSignatures {
+		return http.StatusBadRequest
+	}
+	return walletErrorToHTTPStatus(err)
+}
+
+// NewWalletAtomicSwapInitiateHandler creates a handler to handle API calls to /wallet/atomicswap/initiate.
+// The secret of the contract is generated by the daemon, and returned as part of the response.
+func NewWalletAtomicSwapInitiateHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletAtomicSwapInitiatePOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied atomic swap contract: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		duration, err := parseAtomicSwapDuration(body.Duration, time.Hour*48)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		secret, err := types.NewAtomicSwapSecret()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/initiate: failed to generate secret: ", err), http.StatusInternalServerError)
+			return
+		}
+		receivers := atomicSwapReceivers(body.Receiver, body.Amount, body.Receivers)
+		contracts, txn, err := wallet.CreateAtomicSwapContracts(receivers, types.NewAtomicSwapHashedSecret(secret), duration, body.Sender, body.EscrowCustodyFee)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/initiate: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletAtomicSwapContractPOSTResp{
+			Contract:      contracts[0],
+			Contracts:     contracts,
+			TransactionID: txn.ID(),
+			Secret:        &secret,
+		})
+	}
+}
+
+// NewWalletAtomicSwapParticipateHandler creates a handler to handle API calls to /wallet/atomicswap/participate.
+func NewWalletAtomicSwapParticipateHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletAtomicSwapParticipatePOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied atomic swap contract: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		duration, err := parseAtomicSwapDuration(body.Duration, time.Hour*24)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		receivers := atomicSwapReceivers(body.Receiver, body.Amount, body.Receivers)
+		contracts, txn, err := wallet.CreateAtomicSwapContracts(receivers, body.HashedSecret, duration, body.Sender, body.EscrowCustodyFee)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/participate: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletAtomicSwapContractPOSTResp{
+			Contract:      contracts[0],
+			Contracts:     contracts,
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+// atomicSwapReceivers returns the receivers of an atomic swap contract,
+// the (optional) single receiver preceding the additional receivers.
+func atomicSwapReceivers(receiver types.UnlockHash, amount types.Currency, additional []gcmodules.AtomicSwapReceiver) []gcmodules.AtomicSwapReceiver {
+	if receiver == (types.UnlockHash{}) && len(additional) > 0 {
+		return additional
+	}
+	return append([]gcmodules.AtomicSwapReceiver{{Address: receiver, Amount: amount}}, additional...)
+}
+
+// atomicSwapOutputIDs returns the output IDs of the atomic swap contracts to spend,
+// the (optional) single output ID preceding the additional output IDs.
+func atomicSwapOutputIDs(outputID types.CoinOutputID, additional []types.CoinOutputID) []types.CoinOutputID {
+	if outputID == (types.CoinOutputID{}) && len(additional) > 0 {
+		return additional
+	}
+	return append([]types.CoinOutputID{outputID}, additional...)
+}
+
+// parseAtomicSwapDuration parses the optional duration of an atomic swap contract.
+func parseAtomicSwapDuration(str string, defaultDuration time.Duration) (time.Duration, error) {
+	if str == "" {
+		return defaultDuration, nil
+	}
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %v", str, err)
+	}
+	return duration, nil
+}
+
+// NewWalletAtomicSwapRedeemHandler creates a handler to handle API calls to /wallet/atomicswap/redeem.
+func NewWalletAtomicSwapRedeemHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletAtomicSwapRedeemPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied output ID and secret: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.RedeemAtomicSwapContracts(atomicSwapOutputIDs(body.OutputID, body.OutputIDs), body.Secret)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/redeem: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletAtomicSwapSpendPOSTResp{
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+// NewWalletAtomicSwapRedeemMultiSigHandler creates a handler to handle API calls to /wallet/atomicswap/redeem/multisig.
+// The returned PST is signed by this wallet, and is to be signed by the other co-signers of the multisig receiver.
+func NewWalletAtomicSwapRedeemMultiSigHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletAtomicSwapRedeemPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied output ID and secret: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		pst, err := wallet.RedeemMultiSigAtomicSwapContracts(atomicSwapOutputIDs(body.OutputID, body.OutputIDs), body.Secret)
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/redeem/multisig: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, newWalletPSTPOSTResp(pst))
+	}
+}
+
+// NewWalletAtomicSwapRefundHandler creates a handler to handle API calls to /wallet/atomicswap/refund.
+func NewWalletAtomicSwapRefundHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var body WalletAtomicSwapRefundPOST
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			api.WriteError(w, api.Error{Message: "error decoding the supplied output ID: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, err := wallet.RefundAtomicSwapContracts(atomicSwapOutputIDs(body.OutputID, body.OutputIDs))
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/refund: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletAtomicSwapSpendPOSTResp{
+			TransactionID: txn.ID(),
+		})
+	}
+}
+
+// NewWalletAtomicSwapAuditHandler creates a handler to handle API calls to /wallet/atomicswap/audit?outputid=.
+// The outputid parameter can be given multiple times, in order to audit all contracts of a multi-output contract.
+func NewWalletAtomicSwapAuditHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		strs := req.URL.Query()["outputid"]
+		if len(strs) == 0 {
+			api.WriteError(w, api.Error{Message: "no output ID given"}, http.StatusBadRequest)
+			return
+		}
+		contracts := make([]gcmodules.AtomicSwapContract, 0, len(strs))
+		for _, str := range strs {
+			var outputID types.CoinOutputID
+			err := outputID.LoadString(str)
+			if err != nil {
+				api.WriteError(w, api.Error{Message: "invalid output ID given: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			contract, err := wallet.AuditAtomicSwapContract(outputID)
+			if err != nil {
+				WriteError(w, NewError("error after call to /wallet/atomicswap/audit: ", err), walletErrorToHTTPStatus(err))
+				return
+			}
+			contracts = append(contracts, contract)
+		}
+		api.WriteJSON(w, WalletAtomicSwapAuditGET{
+			Contract:  contracts[0],
+			Contracts: contracts,
+		})
+	}
+}
+
+// NewWalletAtomicSwapContractsHandler creates a handler to handle API calls to /wallet/atomicswap/contracts.
+func NewWalletAtomicSwapContractsHandler(wallet gcmodules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		contracts, err := wallet.AtomicSwapContracts()
+		if err != nil {
+			WriteError(w, NewError("error after call to /wallet/atomicswap/contracts: ", err), walletErrorToHTTPStatus(err))
+			return
+		}
+		api.WriteJSON(w, WalletAtomicSwapContractsGET{
+			Contracts: contracts,
+		})
+	}
+}
+
 func walletErrorToHTTPStatus(err error) int {
-	if err == modules.ErrLockedWallet {
+	if errors.Is(err, modules.ErrLockedWallet) {
 		return http.StatusForbidden
 	}
 	if cErr, ok := err.(types.ClientError); ok {
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+)
+
+// TestWalletMultiSigFundHandler checks that the PST returned to fund a transaction from a multisig wallet,
+// pays the custody fee, refunds the remainder to the multisig wallet and is only signed by this wallet.
+func TestWalletMultiSigFundHandler(t *testing.T) {
+	at, err := createAPITester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer at.Close()
+
+	router := httprouter.New()
+	router.POST("/wallet/multisig/:address/fund", NewWalletMultiSigFundHandler(at.wallet))
+
+	multiSigAddress := at.multiSigCondition.UnlockHash()
+	receiver := types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{3})
+	amount := at.chainCts.CurrencyUnits.OneCoin.Mul64(100)
+	var resp WalletPSTPOSTResp
+	status := post(t, router, "/wallet/multisig/"+multiSigAddress.String()+"/fund", WalletMultiSigFundPOST{
+		CoinOutputs: []types.CoinOutput{{
+			Value:     amount,
+			Condition: types.NewCondition(types.NewUnlockHashCondition(receiver)),
+		}},
+	}, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d", status)
+	}
+
+	txn := resp.PST.Transaction
+	if resp.TransactionID != txn.ID() {
+		t.Errorf("unexpected transaction ID %s, expected %s", resp.TransactionID.String(), txn.ID().String())
+	}
+	if len(txn.CoinInputs) != 1 || len(txn.CoinOutputs) != 3 {
+		t.Fatalf("unexpected transaction: %d coin inputs and %d coin outputs", len(txn.CoinInputs), len(txn.CoinOutputs))
+	}
+	parentID := txn.CoinInputs[0].ParentID
+	if parent, err := at.cs.GetCoinOutput(parentID); err != nil || parent.Condition.UnlockHash().Cmp(multiSigAddress) != 0 {
+		t.Errorf("coin input %s does not spend a coin output of the multisig wallet: %v", parentID.String(), err)
+	}
+
+	// the custody fee is computed at the time of the latest block as seen by the wallet
+	cfc, ok := txn.CoinOutputs[1].Condition.Condition.(*cftypes.CustodyFeeCondition)
+	if !ok {
+		t.Fatalf("unexpected condition %T for the custody fee output", txn.CoinOutputs[1].Condition.Condition)
+	}
+	if cfc.ComputationTime != at.chainCts.GenesisTimestamp+custodyFeePeriod {
+		t.Errorf("unexpected custody fee computation time %d", cfc.ComputationTime)
+	}
+	info, err := at.plugin.GetCoinOutputInfo(parentID, cfc.ComputationTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CustodyFee.IsZero() || !txn.CoinOutputs[1].Value.Equals(info.CustodyFee) {
+		t.Errorf("unexpected custody fee %s, expected %s", txn.CoinOutputs[1].Value.String(), info.CustodyFee.String())
+	}
+	if resp.PST.CustodyFee == nil || !resp.PST.CustodyFee.Expected.Equals(info.CustodyFee) || !resp.PST.CustodyFee.Paid.Equals(info.CustodyFee) {
+		t.Errorf("unexpected PST custody fee %+v", resp.PST.CustodyFee)
+	}
+
+	// the remainder is refunded to the multisig wallet itself
+	refund := txn.CoinOutputs[2]
+	if !refund.Condition.Equal(at.multiSigCondition) {
+		t.Errorf("refund is sent to %s, instead of the multisig wallet", refund.Condition.UnlockHash().String())
+	}
+	if expected := info.SpendableValue.Sub(amount).Sub(at.chainCts.MinimumTransactionFee); !refund.Value.Equals(expected) {
+		t.Errorf("unexpected refund %s, expected %s", refund.Value.String(), expected.String())
+	}
+
+	// the transaction is only signed by this wallet, the other co-signer still has to sign it
+	if resp.Complete {
+		t.Error("PST funded by a multisig wallet is complete after being signed by a single co-signer")
+	}
+	if len(resp.PST.CoinInputs) != 1 {
+		t.Fatalf("unexpected PST coin inputs %+v", resp.PST.CoinInputs)
+	}
+	signatures := resp.PST.CoinInputs[0].Signatures
+	if signatures.Required != 2 || len(signatures.Signed) != 1 || signatures.Signed[0].Cmp(at.address) != 0 {
+		t.Errorf("unexpected signatures %+v", signatures)
+	}
+	if len(resp.MissingSigners) != 1 || resp.MissingSigners[0].Cmp(at.address) == 0 {
+		t.Errorf("unexpected missing signers %v", resp.MissingSigners)
+	}
+
+	// a regular address cannot be funded as a multisig wallet
+	var apiErr Error
+	status = post(t, router, "/wallet/multisig/"+at.address.String()+"/fund", WalletMultiSigFundPOST{}, &apiErr)
+	if status != http.StatusBadRequest {
+		t.Errorf("unexpected status %d for a regular address: %v", status, apiErr)
+	}
+}
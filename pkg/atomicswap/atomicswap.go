@@ -0,0 +1,147 @@
+// Package atomicswap defines the adapters used to create, audit and spend atomic swap contracts
+// on the counter chain of a cross-chain atomic swap, of which the other leg is created on goldchain.
+package atomicswap
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+var (
+	// ErrUnknownChain is returned when no adapter exists for the given counter chain.
+	ErrUnknownChain = errors.New("unknown counter chain")
+	// ErrSecretMismatch is returned when a secret does not match the hashed secret of a contract.
+	ErrSecretMismatch = errors.New("secret does not match the hashed secret of the atomic swap contract")
+)
+
+type (
+	// Chain is the adapter of a counter chain, used to create, audit and spend
+	// atomic swap contracts on that chain. Addresses and amounts are expressed
+	// in the format native to the counter chain, amounts in coins (e.g. "0.5").
+	Chain interface {
+		// Name returns the name of the counter chain.
+		Name() string
+
+		// Participate creates and publishes an atomic swap contract, locking the given amount of coins,
+		// which can be redeemed by the initiator using the secret of the given hashed secret,
+		// or refunded by the wallet of the counter chain node once the lock time of the contract has been reached.
+		Participate(initiator, amount string, hashedSecret types.AtomicSwapHashedSecret) (Contract, error)
+
+		// Audit returns the details of the given contract, validating it is created by its transaction.
+		Audit(contract Contract) (AuditedContract, error)
+
+		// Redeem redeems the given contract using the given secret, returning the ID of the redeem transaction.
+		Redeem(contract Contract, secret types.AtomicSwapSecret) (string, error)
+
+		// Refund refunds the given contract, once its lock time has been reached,
+		// returning the ID of the refund transaction.
+		Refund(contract Contract) (string, error)
+
+		// ExtractSecret extracts the secret of the given hashed secret
+		// from the given (raw) transaction which redeemed a contract.
+		ExtractSecret(redemptionTransaction string, hashedSecret types.AtomicSwapHashedSecret) (types.AtomicSwapSecret, error)
+	}
+
+	// ChainConfig configures the adapter of a counter chain.
+	ChainConfig struct {
+		// Binary is the atomic swap tool used to interact with the node of the counter chain,
+		// the default tool of the counter chain is used if none is given. For BTC the tool has to
+		// read the RPC password from BTCRPCPasswordEnv, as the default btcatomicswapenv wrapper does.
+		Binary string
+		// RPCServer, RPCUser and RPCPassword are used by the tool to connect to the (wallet of the) node,
+		// the password is passed to the tool using its environment (e.g. BTCRPCPasswordEnv), never as an argument.
+		RPCServer   string
+		RPCUser     string
+		RPCPassword string
+		// Network is the network of the counter chain, its main network is used if none is given.
+		// For BTC only mainnet and testnet are supported, as the btcatomicswap tool knows no other networks.
+		Network string
+	}
+
+	// Contract is an atomic swap contract on a counter chain,
+	// identified by the script of the contract and the (raw) transaction which created it.
+	Contract struct {
+		Address       string `json:"address"`
+		Script        string `json:"contract"`
+		TransactionID string `json:"transactionid"`
+		Transaction   string `json:"transaction"`
+	}
+
+	// AuditedContract describes an atomic swap contract found on a counter chain.
+	AuditedContract struct {
+		Contract
+		Value         string                       `json:"value"`
+		Recipient     string                       `json:"recipient"`
+		RefundAddress string                       `json:"refundaddress"`
+		HashedSecret  types.AtomicSwapHashedSecret `json:"hashedsecret"`
+		LockTime      time.Time                    `json:"locktime"`
+	}
+
+	// ContractExpectations defines the criteria an audited counter chain contract has to meet,
+	// prior to trusting it as the other leg of an atomic swap. Optional criteria are ignored when nil.
+	ContractExpectations struct {
+		HashedSecret types.AtomicSwapHashedSecret
+		// MinimumValue is the minimum amount of coins the contract has to lock
+		MinimumValue string
+		// Recipient is the address which has to be able to redeem the contract, optional
+		Recipient string
+		// LockTimeBefore is the time before which the contract has to be refundable, such that
+		// its sender cannot wait for the secret to be revealed until the other leg can no longer be refunded
+		LockTimeBefore time.Time
+		// MinimumDurationLeft is the minimum time the contract has to be redeemable for,
+		// such that it can still be redeemed before its sender can refund it
+		MinimumDurationLeft time.Duration
+	}
+)
+
+// NewChain creates the adapter for the counter chain with the given name.
+func NewChain(name string, cfg ChainConfig) (Chain, error) {
+	switch strings.ToLower(name) {
+	case ChainBTC:
+		chain, err := newBTCChain(cfg, nil)
+		if err != nil {
+			return nil, err
+		}
+		return chain, nil
+	default:
+		return nil, fmt.Errorf("%v: %q", ErrUnknownChain, name)
+	}
+}
+
+// Validate ensures the audited contract meets the given expectations at the given time.
+func (contract AuditedContract) Validate(expectations ContractExpectations, now time.Time) error {
+	if contract.HashedSecret != expectations.HashedSecret {
+		return fmt.Errorf("unexpected hashed secret %s, expected %s",
+			contract.HashedSecret.String(), expectations.HashedSecret.String())
+	}
+	if expectations.MinimumValue != "" {
+		value, ok := new(big.Rat).SetString(contract.Value)
+		if !ok {
+			return fmt.Errorf("invalid contract value %q", contract.Value)
+		}
+		minimum, ok := new(big.Rat).SetString(expectations.MinimumValue)
+		if !ok {
+			return fmt.Errorf("invalid minimum contract value %q", expectations.MinimumValue)
+		}
+		if value.Cmp(minimum) < 0 {
+			return fmt.Errorf("contract value %s is lower than the expected value %s", contract.Value, expectations.MinimumValue)
+		}
+	}
+	if expectations.Recipient != "" && contract.Recipient != expectations.Recipient {
+		return fmt.Errorf("unexpected recipient %s, expected %s", contract.Recipient, expectations.Recipient)
+	}
+	if !expectations.LockTimeBefore.IsZero() && !contract.LockTime.Before(expectations.LockTimeBefore) {
+		return fmt.Errorf("contract lock time %s is not before %s",
+			contract.LockTime.UTC().Format(time.RFC3339), expectations.LockTimeBefore.UTC().Format(time.RFC3339))
+	}
+	if left := contract.LockTime.Sub(now); left < expectations.MinimumDurationLeft || left <= 0 {
+		return fmt.Errorf("contract can only be redeemed for %s, while at least %s is required",
+			left.Round(time.Second), expectations.MinimumDurationLeft)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package atomicswap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+const (
+	// ChainBTC is the name of the Bitcoin (BTC) counter chain.
+	ChainBTC = "btc"
+
+	// btcDefaultBinary is the atomic swap tool used for the BTC chain if none is configured,
+	// the btcatomicswapenv wrapper (see cmd/btcatomicswapenv) of the btcatomicswap tool of decred/atomicswap,
+	// which creates scriptable HTLC contracts using the RPC interface of a bitcoind (wallet) node.
+	btcDefaultBinary = "btcatomicswapenv"
+	// BTCRPCPasswordEnv is the environment variable from which the tool reads the RPC password
+	// of the bitcoind node, such that the password is not visible in the arguments of the process.
+	// The btcatomicswap tool itself only accepts the password as its --rpcpass argument,
+	// which is why the btcatomicswapenv wrapper is used by default.
+	BTCRPCPasswordEnv = "BTCATOMICSWAP_RPCPASS"
+	// BTCToolEnv is the environment variable from which the btcatomicswapenv wrapper
+	// reads the btcatomicswap tool it runs, btcatomicswap (found in the PATH) is run if it is not defined.
+	BTCToolEnv = "BTCATOMICSWAP_BIN"
+)
+
+var (
+	btcContractRe    = regexp.MustCompile(`(?m)^Contract \([0-9A-Za-z]+\):\s*\n\s*([0-9a-f]+)\s*$`)
+	btcContractTxnRe = regexp.MustCompile(`(?m)^Contract transaction \([0-9a-f]{64}\):\s*\n\s*([0-9a-f]+)\s*$`)
+	btcSpendTxnRe    = regexp.MustCompile(`(?m)^(Redeem|Refund) transaction \([0-9a-f]{64}\):\s*\n\s*([0-9a-f]+)\s*$`)
+	// the publication of a transaction is printed on the line of the question whether or not to publish it
+	btcPublishedRe = regexp.MustCompile(`Published (contract|redeem|refund) transaction \(([0-9a-f]{64})\)`)
+)
+
+type (
+	// commandRunner runs the atomic swap tool of a counter chain, with the given arguments,
+	// writing the given input to its standard input, and returning its standard output.
+	commandRunner interface {
+		Run(stdin string, args ...string) (string, error)
+	}
+
+	// execRunner runs an atomic swap tool installed on the host,
+	// adding the given variables to the environment of the process.
+	execRunner struct {
+		binary string
+		env    []string
+	}
+
+	// btcChain is the adapter of the BTC counter chain. The tool it uses creates the contracts
+	// and signs the transactions spending them, using the keys of the wallet of the node it is connected to,
+	// answering 'yes' to the question whether or not to publish a transaction. The contracts and
+	// the transactions created by the tool are decoded and validated by the adapter itself.
+	btcChain struct {
+		runner  commandRunner
+		flags   []string
+		network btcNetwork
+	}
+)
+
+// Run implements commandRunner.Run
+func (runner execRunner) Run(stdin string, args ...string) (string, error) {
+	cmd := exec.Command(runner.binary, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	if len(runner.env) > 0 {
+		cmd.Env = append(os.Environ(), runner.env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return "", fmt.Errorf("%s failed: %v: %s", runner.binary, err, msg)
+	}
+	return stdout.String(), nil
+}
+
+// newBTCChain creates the adapter of the BTC counter chain,
+// using the given runner, or the configured tool installed on the host if no runner is given.
+// The RPC password is passed to the tool using its environment, rather than as an argument.
+func newBTCChain(cfg ChainConfig, runner commandRunner) (*btcChain, error) {
+	network, ok := btcNetworks[cfg.Network]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s network %q", ChainBTC, cfg.Network)
+	}
+	if runner == nil {
+		binary := cfg.Binary
+		if binary == "" {
+			binary = btcDefaultBinary
+		}
+		var env []string
+		if cfg.RPCPassword != "" {
+			env = append(env, BTCRPCPasswordEnv+"="+cfg.RPCPassword)
+		}
+		runner = execRunner{binary: binary, env: env}
+	}
+	var flags []string
+	if network.flag != "" {
+		flags = append(flags, network.flag)
+	}
+	if cfg.RPCServer != "" {
+		flags = append(flags, "-s", cfg.RPCServer)
+	}
+	if cfg.RPCUser != "" {
+		flags = append(flags, "--rpcuser="+cfg.RPCUser)
+	}
+	return &btcChain{runner: runner, flags: flags, network: network}, nil
+}
+
+// Name implements Chain.Name
+func (chain *btcChain) Name() string {
+	return ChainBTC
+}
+
+// Participate implements Chain.Participate
+func (chain *btcChain) Participate(initiator, amount string, hashedSecret types.AtomicSwapHashedSecret) (Contract, error) {
+	recipientHash, err := chain.network.decodePubKeyHashAddress(initiator)
+	if err != nil {
+		return Contract{}, fmt.Errorf("invalid initiator address %q: %v", initiator, err)
+	}
+	value, err := parseBTCAmount(amount)
+	if err != nil {
+		return Contract{}, err
+	}
+	output, err := chain.run(true, "participate", initiator, amount, hashedSecret.String())
+	if err != nil {
+		return Contract{}, err
+	}
+	match := btcContractRe.FindStringSubmatch(output)
+	if match == nil {
+		return Contract{}, fmt.Errorf("no contract found in output of %s participate", ChainBTC)
+	}
+	var contract Contract
+	contract.Script = match[1]
+	match = btcContractTxnRe.FindStringSubmatch(output)
+	if match == nil {
+		return Contract{}, fmt.Errorf("no contract transaction found in output of %s participate", ChainBTC)
+	}
+	contract.Transaction = match[1]
+	// never trust the tool blindly, the created contract has to be the one requested
+	script, txn, outputIndex, err := chain.decodeContract(contract)
+	if err != nil {
+		return Contract{}, fmt.Errorf("invalid contract created by %s participate: %v", ChainBTC, err)
+	}
+	if script.RecipientHash != recipientHash || script.HashedSecret != hashedSecret || txn.Outputs[outputIndex].Value != value {
+		return Contract{}, fmt.Errorf("contract created by %s participate does not match the requested contract", ChainBTC)
+	}
+	contract.Address, contract.TransactionID = chain.contractAddress(contract), txn.ID()
+	txnID, err := btcPublishedTransactionID(output, "contract")
+	if err != nil {
+		return Contract{}, err
+	}
+	if txnID != contract.TransactionID {
+		return Contract{}, fmt.Errorf("published %s contract transaction %s differs from the created transaction %s",
+			ChainBTC, txnID, contract.TransactionID)
+	}
+	return contract, nil
+}
+
+// Audit implements Chain.Audit
+func (chain *btcChain) Audit(contract Contract) (AuditedContract, error) {
+	script, txn, outputIndex, err := chain.decodeContract(contract)
+	if err != nil {
+		return AuditedContract{}, err
+	}
+	audited := AuditedContract{
+		Contract:      contract,
+		Value:         btcAmountString(txn.Outputs[outputIndex].Value),
+		Recipient:     chain.network.encodeAddress(chain.network.pubKeyHashID, script.RecipientHash),
+		RefundAddress: chain.network.encodeAddress(chain.network.pubKeyHashID, script.RefundHash),
+		HashedSecret:  types.AtomicSwapHashedSecret(script.HashedSecret),
+		LockTime:      time.Unix(script.LockTime, 0),
+	}
+	audited.Address, audited.TransactionID = chain.contractAddress(contract), txn.ID()
+	return audited, nil
+}
+
+// Redeem implements Chain.Redeem
+func (chain *btcChain) Redeem(contract Contract, secret types.AtomicSwapSecret) (string, error) {
+	_, _, _, err := chain.decodeContract(contract)
+	if err != nil {
+		return "", err
+	}
+	output, err := chain.run(true, "redeem", contract.Script, contract.Transaction, secret.String())
+	if err != nil {
+		return "", err
+	}
+	return chain.spendTransactionID(contract, output, "redeem")
+}
+
+// Refund implements Chain.Refund
+func (chain *btcChain) Refund(contract Contract) (string, error) {
+	_, _, _, err := chain.decodeContract(contract)
+	if err != nil {
+		return "", err
+	}
+	output, err := chain.run(true, "refund", contract.Script, contract.Transaction)
+	if err != nil {
+		return "", err
+	}
+	return chain.spendTransactionID(contract, output, "refund")
+}
+
+// ExtractSecret implements Chain.ExtractSecret
+func (chain *btcChain) ExtractSecret(redemptionTransaction string, hashedSecret types.AtomicSwapHashedSecret) (types.AtomicSwapSecret, error) {
+	txn, err := decodeBTCTransaction(redemptionTransaction)
+	if err != nil {
+		return types.AtomicSwapSecret{}, err
+	}
+	// the signature script redeeming a contract is: <signature> <public key> <secret> OP_TRUE <contract>
+	for _, input := range txn.Inputs {
+		instructions, err := parseBTCScript(input.SignatureScript)
+		if err != nil || len(instructions) != 5 || instructions[3].Opcode != btcOp1 {
+			continue
+		}
+		script, err := decodeBTCContract(instructions[4].Data)
+		if err != nil || script.HashedSecret != hashedSecret {
+			continue
+		}
+		if len(instructions[2].Data) != len(types.AtomicSwapSecret{}) {
+			return types.AtomicSwapSecret{}, ErrSecretMismatch
+		}
+		var secret types.AtomicSwapSecret
+		copy(secret[:], instructions[2].Data)
+		if types.AtomicSwapHashedSecret(sha256.Sum256(secret[:])) != hashedSecret {
+			return types.AtomicSwapSecret{}, ErrSecretMismatch
+		}
+		return secret, nil
+	}
+	return types.AtomicSwapSecret{}, fmt.Errorf("%s transaction does not redeem a contract of hashed secret %s", ChainBTC, hashedSecret.String())
+}
+
+// run runs the given command of the tool, confirming the publication of the created transaction if required.
+func (chain *btcChain) run(publish bool, command string, args ...string) (string, error) {
+	var stdin string
+	if publish {
+		stdin = "y\n"
+	}
+	return chain.runner.Run(stdin, append(append(append([]string(nil), chain.flags...), command), args...)...)
+}
+
+// decodeContract decodes the given contract and the transaction which created it,
+// returning the index of the output of the transaction paying to the contract.
+func (chain *btcChain) decodeContract(contract Contract) (btcContract, btcTransaction, int, error) {
+	scriptBytes, err := hex.DecodeString(contract.Script)
+	if err != nil {
+		return btcContract{}, btcTransaction{}, 0, errBTCInvalidContract
+	}
+	script, err := decodeBTCContract(scriptBytes)
+	if err != nil {
+		return btcContract{}, btcTransaction{}, 0, err
+	}
+	txn, err := decodeBTCTransaction(contract.Transaction)
+	if err != nil {
+		return btcContract{}, btcTransaction{}, 0, err
+	}
+	if contract.TransactionID != "" && contract.TransactionID != txn.ID() {
+		return btcContract{}, btcTransaction{}, 0, fmt.Errorf("contract transaction has ID %s, expected %s", txn.ID(), contract.TransactionID)
+	}
+	pkScript := btcScriptHashPkScript(scriptBytes)
+	for idx, output := range txn.Outputs {
+		if bytes.Equal(output.PkScript, pkScript) {
+			return script, txn, idx, nil
+		}
+	}
+	return btcContract{}, btcTransaction{}, 0, fmt.Errorf("contract is not created by the given %s transaction", ChainBTC)
+}
+
+// contractAddress returns the (P2SH) address of the given (decoded) contract.
+func (chain *btcChain) contractAddress(contract Contract) string {
+	script, _ := hex.DecodeString(contract.Script)
+	return chain.network.encodeAddress(chain.network.scriptHashID, btcHash160(script))
+}
+
+// spendTransactionID returns the ID of the published transaction of the given kind, found in the given output,
+// ensuring the transaction created by the tool spends the given contract.
+func (chain *btcChain) spendTransactionID(contract Contract, output, kind string) (string, error) {
+	match := btcSpendTxnRe.FindStringSubmatch(output)
+	if match == nil || strings.ToLower(match[1]) != kind {
+		return "", fmt.Errorf("no %s transaction found in output of %s %s", kind, ChainBTC, kind)
+	}
+	txn, err := decodeBTCTransaction(match[2])
+	if err != nil {
+		return "", err
+	}
+	_, contractTxn, outputIndex, _ := chain.decodeContract(contract)
+	contractTxnID := btcDoubleHash(contractTxn.Encode(false))
+	var spent bool
+	for _, input := range txn.Inputs {
+		if input.PreviousID == contractTxnID && input.PreviousIndex == uint32(outputIndex) {
+			spent = true
+			break
+		}
+	}
+	if !spent {
+		return "", fmt.Errorf("%s %s transaction does not spend the contract", ChainBTC, kind)
+	}
+	txnID, err := btcPublishedTransactionID(output, kind)
+	if err != nil {
+		return "", err
+	}
+	if txnID != txn.ID() {
+		return "", fmt.Errorf("published %s %s transaction %s differs from the created transaction %s", ChainBTC, kind, txnID, txn.ID())
+	}
+	return txnID, nil
+}
+
+// btcPublishedTransactionID returns the ID of the published transaction of the given kind, found in the given output.
+func btcPublishedTransactionID(output, kind string) (string, error) {
+	for _, match := range btcPublishedRe.FindAllStringSubmatch(output, -1) {
+		if match[1] == kind {
+			return match[2], nil
+		}
+	}
+	return "", fmt.Errorf("%s %s transaction was not published", ChainBTC, kind)
+}
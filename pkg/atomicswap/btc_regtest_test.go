@@ -0,0 +1,158 @@
+//go:build btcregtest
+// +build btcregtest
+
+package atomicswap
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+// The integration tests of the BTC adapter, run against the btcatomicswap tool (using the btcatomicswapenv wrapper)
+// and a bitcoind regtest node, using:
+//
+//	go test -tags btcregtest ./pkg/atomicswap
+//
+// The btcatomicswapenv and btcatomicswap binaries have to be found in the PATH.
+// The regtest node has to be started with a (loaded) wallet, creating legacy addresses,
+// as the tool connects to it as a testnet node, which shares its legacy address prefixes with regtest:
+//
+//	bitcoind -regtest -addresstype=legacy -changetype=legacy -fallbackfee=0.0002 -rpcuser=user -rpcpassword=pass
+//
+// Its RPC server, user and password can be configured using the environment variables
+// BTC_REGTEST_RPCSERVER (localhost:18443 by default), BTC_REGTEST_RPCUSER and BTC_REGTEST_RPCPASS.
+
+// btcRegtestNode is a minimal JSON-RPC client of the bitcoind regtest node,
+// used to fund its wallet and mine the blocks confirming the transactions of the tool.
+type btcRegtestNode struct {
+	cfg ChainConfig
+}
+
+func newBTCRegtestNode(t *testing.T) btcRegtestNode {
+	cfg := ChainConfig{
+		Network:     "testnet",
+		RPCServer:   os.Getenv("BTC_REGTEST_RPCSERVER"),
+		RPCUser:     os.Getenv("BTC_REGTEST_RPCUSER"),
+		RPCPassword: os.Getenv("BTC_REGTEST_RPCPASS"),
+	}
+	if cfg.RPCServer == "" {
+		cfg.RPCServer = "localhost:18443"
+	}
+	return btcRegtestNode{cfg: cfg}
+}
+
+func (node btcRegtestNode) call(t *testing.T, result interface{}, method string, params ...interface{}) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "1.0", "id": "goldchain", "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://"+node.cfg.RPCServer, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(node.cfg.RPCUser, node.cfg.RPCPassword)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to call %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		t.Fatalf("failed to decode reply of %s (%s): %v", method, resp.Status, err)
+	}
+	if reply.Error != nil {
+		t.Fatalf("%s failed: %s", method, reply.Error.Message)
+	}
+	if result != nil {
+		if err = json.Unmarshal(reply.Result, result); err != nil {
+			t.Fatalf("failed to decode result of %s: %v", method, err)
+		}
+	}
+}
+
+func (node btcRegtestNode) newAddress(t *testing.T) string {
+	var address string
+	node.call(t, &address, "getnewaddress", "", "legacy")
+	return address
+}
+
+func (node btcRegtestNode) mine(t *testing.T, blocks int) {
+	node.call(t, nil, "generatetoaddress", blocks, node.newAddress(t))
+}
+
+// TestBTCChainRegtest checks that a contract created by the btcatomicswap tool on a regtest node
+// is audited and validated, and that it can be redeemed, revealing its secret.
+// The wallet of the node is both the participant and the initiator of the swap.
+func TestBTCChainRegtest(t *testing.T) {
+	node := newBTCRegtestNode(t)
+	var balance float64
+	node.call(t, &balance, "getbalance")
+	if balance < 1 {
+		// coinbase outputs can only be spent after 100 confirmations
+		node.mine(t, 101)
+	}
+	chain, err := NewChain(ChainBTC, node.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := types.NewAtomicSwapSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashedSecret := types.NewAtomicSwapHashedSecret(secret)
+	initiator := node.newAddress(t)
+	contract, err := chain.Participate(initiator, "0.5", hashedSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.mine(t, 1)
+
+	audited, err := chain.Audit(Contract{Script: contract.Script, Transaction: contract.Transaction})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audited.Address != contract.Address || audited.TransactionID != contract.TransactionID ||
+		audited.Value != "0.5" || audited.Recipient != initiator || audited.HashedSecret != hashedSecret {
+		t.Fatalf("unexpected audited contract: %+v", audited)
+	}
+	expectations := ContractExpectations{HashedSecret: hashedSecret, MinimumValue: "0.5", Recipient: initiator}
+	if err = audited.Validate(expectations, audited.LockTime.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	txnID, err := chain.Redeem(contract, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.mine(t, 1)
+	var redemption struct {
+		Confirmations int    `json:"confirmations"`
+		Hex           string `json:"hex"`
+	}
+	node.call(t, &redemption, "gettransaction", txnID)
+	if redemption.Confirmations < 1 {
+		t.Fatalf("redeem transaction %s is not confirmed", txnID)
+	}
+	extracted, err := chain.ExtractSecret(redemption.Hex, hashedSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extracted != secret {
+		t.Errorf("extracted secret %s, expected %s", extracted.String(), secret.String())
+	}
+}
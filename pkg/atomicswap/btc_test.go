@@ -0,0 +1,764 @@
+package atomicswap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/threefoldtech/rivine/types"
+	"golang.org/x/crypto/ed25519"
+)
+
+// btcRegtest is an in-process stand-in of a BTC regtest network, using testnet addresses,
+// shared by the wallets of the participants of a swap. Like a node, it only accepts a transaction if its inputs are unspent, and if the signature script of each input,
+// executed together with the (P2SH) output script it spends, succeeds. Contracts are therefore redeemed and refunded
+// according to the semantics of their HTLC script. As no secp256k1 implementation is available to the tests,
+// OP_CHECKSIG verifies ed25519 signatures of the (legacy) signature hash instead.
+type btcRegtest struct {
+	now          time.Time
+	network      btcNetwork
+	outputs      map[btcOutPoint]btcOutput
+	transactions map[string]btcTransaction // by ID
+	coinbase     uint32
+}
+
+type btcOutPoint struct {
+	id    [32]byte
+	index uint32
+}
+
+// btcStandIn is an in-process stand-in of the BTC atomic swap tool, connected to the wallet of a regtest node.
+// It creates and signs the contracts and the transactions spending them as the tool does, publishing them on the
+// regtest network once confirmed. It does not validate the spending of a contract itself, leaving that to the network.
+type btcStandIn struct {
+	chain *btcRegtest
+	keys  map[[20]byte]ed25519.PrivateKey
+	args  [][]string
+	// cheat makes the stand-in create contracts which can be redeemed by its own wallet, rather than the initiator
+	cheat bool
+}
+
+func newBTCRegtest() *btcRegtest {
+	return &btcRegtest{
+		now:          time.Now(),
+		network:      btcNetworks["testnet"],
+		outputs:      make(map[btcOutPoint]btcOutput),
+		transactions: make(map[string]btcTransaction),
+	}
+}
+
+// wallet creates the wallet of a regtest node, funded with a single coinbase output of the given amount of satoshis.
+func (chain *btcRegtest) wallet(funds int64) *btcStandIn {
+	standIn := &btcStandIn{chain: chain, keys: make(map[[20]byte]ed25519.PrivateKey)}
+	chain.coinbase++
+	txn := btcTransaction{
+		Version:  1,
+		Inputs:   []btcInput{{PreviousIndex: chain.coinbase, SignatureScript: []byte{btcOp1}, Sequence: 0xffffffff}},
+		Outputs:  []btcOutput{{Value: funds, PkScript: btcPubKeyHashPkScript(standIn.newKey())}},
+		LockTime: 0,
+	}
+	chain.outputs[btcOutPoint{id: btcDoubleHash(txn.Encode(false))}] = txn.Outputs[0]
+	chain.transactions[txn.ID()] = txn
+	return standIn
+}
+
+// publish validates the transaction, as a node would, adding it to the chain if valid.
+func (chain *btcRegtest) publish(txn btcTransaction) error {
+	if txn.LockTime != 0 {
+		for _, input := range txn.Inputs {
+			if input.Sequence != 0xffffffff && (txn.LockTime < btcLockTimeThreshold || int64(txn.LockTime) >= chain.now.Unix()) {
+				return errors.New("non-final transaction")
+			}
+		}
+	}
+	var inputValue, outputValue int64
+	spent := make(map[btcOutPoint]struct{})
+	for idx, input := range txn.Inputs {
+		outPoint := btcOutPoint{id: input.PreviousID, index: input.PreviousIndex}
+		output, ok := chain.outputs[outPoint]
+		if _, double := spent[outPoint]; !ok || double {
+			return errors.New("missing inputs or inputs already spent")
+		}
+		spent[outPoint] = struct{}{}
+		inputValue += output.Value
+		err := btcVerifyInput(txn, idx, output.PkScript)
+		if err != nil {
+			return fmt.Errorf("script of input #%d failed: %v", idx, err)
+		}
+	}
+	for _, output := range txn.Outputs {
+		outputValue += output.Value
+	}
+	if outputValue > inputValue {
+		return errors.New("transaction spends more than its inputs")
+	}
+	for outPoint := range spent {
+		delete(chain.outputs, outPoint)
+	}
+	id := btcDoubleHash(txn.Encode(false))
+	for idx, output := range txn.Outputs {
+		chain.outputs[btcOutPoint{id: id, index: uint32(idx)}] = output
+	}
+	chain.transactions[txn.ID()] = txn
+	return nil
+}
+
+// btcVerifyInput executes the signature script of the given input, followed by the output script it spends,
+// and the redeem script provided by the signature script if the output pays to a script hash.
+func btcVerifyInput(txn btcTransaction, idx int, pkScript []byte) error {
+	instructions, err := parseBTCScript(txn.Inputs[idx].SignatureScript)
+	if err != nil {
+		return err
+	}
+	var stack [][]byte
+	for _, instruction := range instructions {
+		if instruction.Opcode > btcOp16 {
+			return errors.New("signature script is not push only")
+		}
+		stack, err = btcExecute([]btcInstruction{instruction}, nil, stack, txn, idx)
+		if err != nil {
+			return err
+		}
+	}
+	p2sh := len(pkScript) == 23 && pkScript[0] == btcOpHash160 && pkScript[1] == 20 && pkScript[22] == btcOpEqual
+	var redeemScript []byte
+	if p2sh && len(stack) > 0 {
+		redeemScript = stack[len(stack)-1]
+	}
+	if _, err = btcExecuteScript(pkScript, stack, txn, idx); err != nil || !p2sh {
+		return err
+	}
+	_, err = btcExecuteScript(redeemScript, stack[:len(stack)-1], txn, idx)
+	return err
+}
+
+// btcExecuteScript executes the given script, starting with (a copy of) the given stack,
+// succeeding only if it leaves a true value on top of the stack.
+func btcExecuteScript(script []byte, stack [][]byte, txn btcTransaction, idx int) ([][]byte, error) {
+	instructions, err := parseBTCScript(script)
+	if err != nil {
+		return nil, err
+	}
+	stack, err = btcExecute(instructions, script, append([][]byte(nil), stack...), txn, idx)
+	if err != nil {
+		return nil, err
+	}
+	if len(stack) == 0 || !btcCastToBool(stack[len(stack)-1]) {
+		return nil, errors.New("script evaluated to false")
+	}
+	return stack, nil
+}
+
+// btcExecute executes the instructions of a script, starting with the given stack,
+// limited to the opcodes used by pay-to-public-key-hash outputs and atomic swap contracts.
+func btcExecute(instructions []btcInstruction, script []byte, stack [][]byte, txn btcTransaction, idx int) ([][]byte, error) {
+	pop := func() ([]byte, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("stack is empty")
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return top, nil
+	}
+	var conditions []bool
+	for _, instruction := range instructions {
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+		switch instruction.Opcode {
+		case btcOpIf:
+			condition := false
+			if executing {
+				top, err := pop()
+				if err != nil {
+					return nil, err
+				}
+				condition = btcCastToBool(top)
+			}
+			conditions = append(conditions, condition)
+			continue
+		case btcOpElse, btcOpEndIf:
+			if len(conditions) == 0 {
+				return nil, errors.New("unbalanced conditional")
+			}
+			if instruction.Opcode == btcOpElse {
+				conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			} else {
+				conditions = conditions[:len(conditions)-1]
+			}
+			continue
+		}
+		if !executing {
+			continue
+		}
+		switch op := instruction.Opcode; {
+		case op == btcOp0:
+			stack = append(stack, nil)
+		case op > btcOp0 && op <= btcOpPushData4:
+			stack = append(stack, instruction.Data)
+		case op >= btcOp1 && op <= btcOp16:
+			stack = append(stack, []byte{op - btcOp1 + 1})
+		case op == btcOpSize:
+			if len(stack) == 0 {
+				return nil, errors.New("stack is empty")
+			}
+			stack = append(stack, btcScriptNumber(int64(len(stack[len(stack)-1]))))
+		case op == btcOpDup:
+			if len(stack) == 0 {
+				return nil, errors.New("stack is empty")
+			}
+			stack = append(stack, stack[len(stack)-1])
+		case op == btcOpDrop:
+			if _, err := pop(); err != nil {
+				return nil, err
+			}
+		case op == btcOpSHA256, op == btcOpHash160:
+			top, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			if op == btcOpSHA256 {
+				h := sha256.Sum256(top)
+				stack = append(stack, h[:])
+			} else {
+				h := btcHash160(top)
+				stack = append(stack, h[:])
+			}
+		case op == btcOpEqual, op == btcOpEqualVerify:
+			a, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			b, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			if op == btcOpEqualVerify {
+				if !bytes.Equal(a, b) {
+					return nil, errors.New("OP_EQUALVERIFY failed")
+				}
+				continue
+			}
+			if bytes.Equal(a, b) {
+				stack = append(stack, []byte{1})
+			} else {
+				stack = append(stack, nil)
+			}
+		case op == btcOpCheckLockTimeVerify:
+			if len(stack) == 0 {
+				return nil, errors.New("stack is empty")
+			}
+			lockTime, err := decodeBTCScriptNumber(btcInstruction{Opcode: byte(len(stack[len(stack)-1])), Data: stack[len(stack)-1]})
+			if err != nil || lockTime < 0 {
+				return nil, errors.New("invalid lock time")
+			}
+			if (lockTime < btcLockTimeThreshold) != (txn.LockTime < btcLockTimeThreshold) ||
+				int64(txn.LockTime) < lockTime || txn.Inputs[idx].Sequence == 0xffffffff {
+				return nil, errors.New("locktime requirement not satisfied")
+			}
+		case op == btcOpCheckSig:
+			publicKey, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			signature, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			hash := btcSignatureHash(txn, idx, script)
+			if len(publicKey) == ed25519.PublicKeySize && ed25519.Verify(publicKey, hash[:], signature) {
+				stack = append(stack, []byte{1})
+			} else {
+				stack = append(stack, nil)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported opcode 0x%02x", op)
+		}
+	}
+	if len(conditions) != 0 {
+		return nil, errors.New("unbalanced conditional")
+	}
+	return stack, nil
+}
+
+func btcCastToBool(b []byte) bool {
+	for i, c := range b {
+		if c != 0 {
+			// negative zero is false
+			return i != len(b)-1 || c != 0x80
+		}
+	}
+	return false
+}
+
+// btcSignatureHash returns the (legacy, SIGHASH_ALL) signature hash of the given input, spending the given script.
+func btcSignatureHash(txn btcTransaction, idx int, script []byte) [32]byte {
+	inputs := make([]btcInput, len(txn.Inputs))
+	for i, input := range txn.Inputs {
+		inputs[i] = btcInput{PreviousID: input.PreviousID, PreviousIndex: input.PreviousIndex, Sequence: input.Sequence}
+	}
+	inputs[idx].SignatureScript = script
+	txn.Inputs = inputs
+	return btcDoubleHash(append(txn.Encode(false), 1, 0, 0, 0))
+}
+
+// btcScriptNumber returns the minimal encoding of a script number.
+func btcScriptNumber(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append(b, byte(n))
+	}
+	if b[len(b)-1]&0x80 != 0 {
+		b = append(b, 0)
+	}
+	if negative {
+		b[len(b)-1] |= 0x80
+	}
+	return b
+}
+
+func btcPushData(data []byte) []byte {
+	if len(data) < btcOpPushData1 {
+		return append([]byte{byte(len(data))}, data...)
+	}
+	return append([]byte{btcOpPushData1, byte(len(data))}, data...)
+}
+
+func btcPubKeyHashPkScript(hash [20]byte) []byte {
+	script := append([]byte{btcOpDup, btcOpHash160}, btcPushData(hash[:])...)
+	return append(script, btcOpEqualVerify, btcOpCheckSig)
+}
+
+// newBTCContractScript creates the atomic swap contract script as created by the tool.
+func newBTCContractScript(contract btcContract) []byte {
+	script := []byte{btcOpIf, btcOpSize}
+	script = append(script, btcPushData(btcScriptNumber(32))...)
+	script = append(script, btcOpEqualVerify, btcOpSHA256)
+	script = append(script, btcPushData(contract.HashedSecret[:])...)
+	script = append(script, btcOpEqualVerify, btcOpDup, btcOpHash160)
+	script = append(script, btcPushData(contract.RecipientHash[:])...)
+	script = append(script, btcOpElse)
+	script = append(script, btcPushData(btcScriptNumber(contract.LockTime))...)
+	script = append(script, btcOpCheckLockTimeVerify, btcOpDrop, btcOpDup, btcOpHash160)
+	script = append(script, btcPushData(contract.RefundHash[:])...)
+	return append(script, btcOpEndIf, btcOpEqualVerify, btcOpCheckSig)
+}
+
+// newKey adds a new key to the wallet, returning its public key hash.
+func (standIn *btcStandIn) newKey() [20]byte {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	hash := btcHash160(key.Public().(ed25519.PublicKey))
+	standIn.keys[hash] = key
+	return hash
+}
+
+// address returns a new address of the wallet.
+func (standIn *btcStandIn) address() string {
+	return standIn.chain.network.encodeAddress(standIn.chain.network.pubKeyHashID, standIn.newKey())
+}
+
+// sign signs the given input of the transaction, spending the given script using the key of the given public key hash.
+func (standIn *btcStandIn) sign(txn btcTransaction, idx int, script []byte, hash [20]byte) ([]byte, []byte, error) {
+	key, ok := standIn.keys[hash]
+	if !ok {
+		return nil, nil, errors.New("wallet does not own the key")
+	}
+	sigHash := btcSignatureHash(txn, idx, script)
+	return ed25519.Sign(key, sigHash[:]), key.Public().(ed25519.PublicKey), nil
+}
+
+// Run implements commandRunner.Run
+func (standIn *btcStandIn) Run(stdin string, args ...string) (string, error) {
+	standIn.args = append(standIn.args, args)
+	// skip the flags preceding the command
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "-s" {
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return "", errors.New("no command given")
+	}
+	const fee = 1000
+	publish := stdin == "y\n"
+	network := standIn.chain.network
+	switch command, args := args[0], args[1:]; command {
+	case "participate":
+		recipientHash, err := network.decodePubKeyHashAddress(args[0])
+		if err != nil {
+			return "", err
+		}
+		value, err := parseBTCAmount(args[1])
+		if err != nil {
+			return "", err
+		}
+		hashedSecret, err := hex.DecodeString(args[2])
+		if err != nil || len(hashedSecret) != 32 {
+			return "", errors.New("invalid secret hash")
+		}
+		contract := btcContract{
+			RecipientHash: recipientHash,
+			RefundHash:    standIn.newKey(),
+			LockTime:      standIn.chain.now.Add(time.Hour * 24).Unix(),
+		}
+		copy(contract.HashedSecret[:], hashedSecret)
+		if standIn.cheat {
+			contract.RecipientHash = standIn.newKey()
+		}
+		script := newBTCContractScript(contract)
+		// fund the contract using an output owned by the wallet, returning the change to the wallet
+		txn := btcTransaction{Version: 2}
+		var funding btcOutput
+		for outPoint, output := range standIn.chain.outputs {
+			if len(output.PkScript) != 25 || output.Value < value+fee {
+				continue
+			}
+			var hash [20]byte
+			copy(hash[:], output.PkScript[3:])
+			if _, ok := standIn.keys[hash]; ok {
+				txn.Inputs = []btcInput{{PreviousID: outPoint.id, PreviousIndex: outPoint.index, Sequence: 0xffffffff}}
+				funding = output
+				break
+			}
+		}
+		if len(txn.Inputs) == 0 {
+			return "", errors.New("insufficient funds available to construct transaction")
+		}
+		txn.Outputs = []btcOutput{
+			{Value: value, PkScript: btcScriptHashPkScript(script)},
+			{Value: funding.Value - value - fee, PkScript: btcPubKeyHashPkScript(standIn.newKey())},
+		}
+		var fundingHash [20]byte
+		copy(fundingHash[:], funding.PkScript[3:])
+		signature, publicKey, err := standIn.sign(txn, 0, funding.PkScript, fundingHash)
+		if err != nil {
+			return "", err
+		}
+		txn.Inputs[0].SignatureScript = append(btcPushData(signature), btcPushData(publicKey)...)
+		output := fmt.Sprintf(`Contract fee: 0.00001 BTC (0.00003623 BTC/kB)
+Refund fee:   0.00000297 BTC (0.00001020 BTC/kB)
+
+Contract (%s):
+%x
+
+Contract transaction (%s):
+%x
+
+Publish contract transaction? [y/N] `, network.encodeAddress(network.scriptHashID, btcHash160(script)), script, txn.ID(), txn.Encode(true))
+		if !publish {
+			return output, nil
+		}
+		if err = standIn.chain.publish(txn); err != nil {
+			return "", fmt.Errorf("sendrawtransaction: %v", err)
+		}
+		return output + fmt.Sprintf("Published contract transaction (%s)\n", txn.ID()), nil
+
+	case "redeem", "refund":
+		script, err := hex.DecodeString(args[0])
+		if err != nil {
+			return "", err
+		}
+		contract, err := decodeBTCContract(script)
+		if err != nil {
+			return "", err
+		}
+		contractTxn, err := decodeBTCTransaction(args[1])
+		if err != nil {
+			return "", err
+		}
+		outputIndex := -1
+		for idx, output := range contractTxn.Outputs {
+			if bytes.Equal(output.PkScript, btcScriptHashPkScript(script)) {
+				outputIndex = idx
+			}
+		}
+		if outputIndex < 0 {
+			return "", errors.New("transaction does not contain the contract output")
+		}
+		txn := btcTransaction{
+			Version: 2,
+			Inputs: []btcInput{{
+				PreviousID:    btcDoubleHash(contractTxn.Encode(false)),
+				PreviousIndex: uint32(outputIndex),
+				Sequence:      0xffffffff,
+			}},
+			Outputs: []btcOutput{{
+				Value:    contractTxn.Outputs[outputIndex].Value - fee,
+				PkScript: btcPubKeyHashPkScript(standIn.newKey()),
+			}},
+		}
+		keyHash := contract.RecipientHash
+		if command == "refund" {
+			keyHash = contract.RefundHash
+			txn.LockTime, txn.Inputs[0].Sequence = uint32(contract.LockTime), 0
+		}
+		signature, publicKey, err := standIn.sign(txn, 0, script, keyHash)
+		if err != nil {
+			return "", err
+		}
+		sigScript := append(btcPushData(signature), btcPushData(publicKey)...)
+		if command == "redeem" {
+			secret, err := hex.DecodeString(args[2])
+			if err != nil {
+				return "", err
+			}
+			sigScript = append(append(sigScript, btcPushData(secret)...), btcOp1)
+		} else {
+			sigScript = append(sigScript, btcOp0)
+		}
+		txn.Inputs[0].SignatureScript = append(sigScript, btcPushData(script)...)
+		title := strings.Title(command)
+		output := fmt.Sprintf("%s fee: 0.00001 BTC (0.00003226 BTC/kB)\n\n%s transaction (%s):\n%x\n\nPublish %s transaction? [y/N] ",
+			title, title, txn.ID(), txn.Encode(true), command)
+		if !publish {
+			return output, nil
+		}
+		if err = standIn.chain.publish(txn); err != nil {
+			return "", fmt.Errorf("sendrawtransaction: %v", err)
+		}
+		return output + fmt.Sprintf("Published %s transaction (%s)\n", command, txn.ID()), nil
+	}
+	return "", fmt.Errorf("unknown command %q", args[0])
+}
+
+// TestBTCChainRedeem checks that a contract created on the BTC chain is audited and validated,
+// that it can only be redeemed by its recipient using the secret, and that the secret
+// can be extracted once the contract is redeemed.
+func TestBTCChainRedeem(t *testing.T) {
+	regtest := newBTCRegtest()
+	participant, initiator := regtest.wallet(btcSatoshisPerCoin), regtest.wallet(0)
+	cfg := ChainConfig{Network: "testnet", RPCServer: "localhost:18443", RPCUser: "user", RPCPassword: "pass"}
+	participantChain, err := newBTCChain(cfg, participant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initiatorChain, err := newBTCChain(cfg, initiator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := types.NewAtomicSwapSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashedSecret := types.NewAtomicSwapHashedSecret(secret)
+	initiatorAddress := initiator.address()
+	contract, err := participantChain.Participate(initiatorAddress, "0.5", hashedSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := participant.args[0]; strings.Join(args[:4], " ") != "--testnet -s localhost:18443 --rpcuser=user" ||
+		strings.Contains(strings.Join(args, " "), "pass") {
+		t.Errorf("unexpected tool arguments: %v", args)
+	}
+	if _, ok := regtest.transactions[contract.TransactionID]; !ok {
+		t.Fatal("contract transaction is not published")
+	}
+
+	audited, err := initiatorChain.Audit(Contract{Script: contract.Script, Transaction: contract.Transaction})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audited.Value != "0.5" || audited.Recipient != initiatorAddress || audited.HashedSecret != hashedSecret ||
+		audited.Address != contract.Address || audited.TransactionID != contract.TransactionID ||
+		audited.LockTime.Unix() != regtest.now.Add(time.Hour*24).Unix() {
+		t.Fatalf("unexpected audited contract: %+v", audited)
+	}
+	var refundHash [20]byte
+	for hash := range participant.keys {
+		if regtest.network.encodeAddress(regtest.network.pubKeyHashID, hash) == audited.RefundAddress {
+			refundHash = hash
+		}
+	}
+	if refundHash == ([20]byte{}) {
+		t.Fatalf("refund address %s is not owned by the participant", audited.RefundAddress)
+	}
+	expectations := ContractExpectations{
+		HashedSecret:        hashedSecret,
+		MinimumValue:        "0.5",
+		Recipient:           initiatorAddress,
+		LockTimeBefore:      regtest.now.Add(time.Hour * 48),
+		MinimumDurationLeft: time.Hour,
+	}
+	if err = audited.Validate(expectations, regtest.now); err != nil {
+		t.Fatal("expected contract to be valid:", err)
+	}
+	for idx, modify := range []func(*ContractExpectations){
+		func(e *ContractExpectations) { e.HashedSecret = types.AtomicSwapHashedSecret{1} },
+		func(e *ContractExpectations) { e.MinimumValue = "0.50000001" },
+		func(e *ContractExpectations) { e.Recipient = audited.RefundAddress },
+		func(e *ContractExpectations) { e.LockTimeBefore = regtest.now.Add(time.Hour * 12) },
+		func(e *ContractExpectations) { e.MinimumDurationLeft = time.Hour * 25 },
+	} {
+		invalid := expectations
+		modify(&invalid)
+		if err = audited.Validate(invalid, regtest.now); err == nil {
+			t.Errorf("expectations #%d: expected contract to be invalid", idx)
+		}
+	}
+
+	if _, err = initiatorChain.Redeem(contract, types.AtomicSwapSecret{1}); err == nil {
+		t.Fatal("expected contract not to be redeemed using the wrong secret")
+	}
+	if _, err = participantChain.Redeem(contract, secret); err == nil {
+		t.Fatal("expected contract not to be redeemed by its sender")
+	}
+	txnID, err := initiatorChain.Redeem(contract, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redemption, ok := regtest.transactions[txnID]
+	if !ok {
+		t.Fatalf("redeem transaction %s is not published", txnID)
+	}
+	extracted, err := participantChain.ExtractSecret(hex.EncodeToString(redemption.Encode(true)), hashedSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extracted != secret {
+		t.Fatalf("extracted secret %s does not match %s", extracted.String(), secret.String())
+	}
+	if _, err = participantChain.ExtractSecret(hex.EncodeToString(redemption.Encode(true)), types.AtomicSwapHashedSecret{1}); err == nil {
+		t.Fatal("expected no secret to be extracted for another hashed secret")
+	}
+	if _, err = initiatorChain.Redeem(contract, secret); err == nil {
+		t.Fatal("expected contract not to be redeemed twice")
+	}
+	regtest.now = regtest.now.Add(time.Hour * 25)
+	if _, err = participantChain.Refund(contract); err == nil {
+		t.Fatal("expected redeemed contract not to be refunded")
+	}
+}
+
+// TestBTCChainRefund checks that a contract created on the BTC chain
+// can only be refunded by its sender, once its lock time has been reached.
+func TestBTCChainRefund(t *testing.T) {
+	regtest := newBTCRegtest()
+	participant, initiator := regtest.wallet(btcSatoshisPerCoin), regtest.wallet(0)
+	participantChain, err := newBTCChain(ChainConfig{Network: "testnet"}, participant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initiatorChain, err := newBTCChain(ChainConfig{Network: "testnet"}, initiator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := types.NewAtomicSwapSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract, err := participantChain.Participate(initiator.address(), "1", types.NewAtomicSwapHashedSecret(secret))
+	if err == nil {
+		t.Fatal("expected contract not to be created without sufficient funds")
+	}
+	contract, err = participantChain.Participate(initiator.address(), "0.25", types.NewAtomicSwapHashedSecret(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = participantChain.Refund(contract); err == nil {
+		t.Fatal("expected contract not to be refunded before its lock time")
+	}
+	regtest.now = regtest.now.Add(time.Hour*24 + time.Second)
+	if _, err = initiatorChain.Refund(contract); err == nil {
+		t.Fatal("expected contract not to be refunded by its recipient")
+	}
+	txnID, err := participantChain.Refund(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := regtest.transactions[txnID]; !ok {
+		t.Fatalf("refund transaction %s is not published", txnID)
+	}
+	if _, err = initiatorChain.Redeem(contract, secret); err == nil {
+		t.Fatal("expected refunded contract not to be redeemed")
+	}
+
+	if _, err = participantChain.Audit(Contract{Script: contract.Script, Transaction: "00"}); err == nil {
+		t.Fatal("expected contract of an invalid transaction not to be audited")
+	}
+	other, err := participantChain.Participate(initiator.address(), "0.25", types.AtomicSwapHashedSecret{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = participantChain.Audit(Contract{Script: other.Script, Transaction: contract.Transaction}); err == nil {
+		t.Fatal("expected contract not to be audited using the transaction of another contract")
+	}
+	if _, err = participantChain.Audit(Contract{Script: contract.Script, Transaction: contract.Transaction, TransactionID: other.TransactionID}); err == nil {
+		t.Fatal("expected contract not to be audited using an unexpected transaction ID")
+	}
+}
+
+// TestBTCChainParticipateValidation checks that a contract created by the tool is refused,
+// if it cannot be redeemed by the initiator.
+func TestBTCChainParticipateValidation(t *testing.T) {
+	regtest := newBTCRegtest()
+	participant := regtest.wallet(btcSatoshisPerCoin)
+	participant.cheat = true
+	chain, err := newBTCChain(ChainConfig{Network: "testnet"}, participant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = chain.Participate(regtest.wallet(0).address(), "0.5", types.AtomicSwapHashedSecret{1}); err == nil {
+		t.Fatal("expected contract not to be accepted")
+	}
+	if _, err = chain.Participate("1111111111111111111114oLvT2", "0.5", types.AtomicSwapHashedSecret{1}); err == nil {
+		t.Fatal("expected mainnet initiator address to be refused")
+	}
+}
+
+// TestNewChain checks that adapters only exist for supported counter chains,
+// and that the RPC password is passed to the tool using its environment.
+func TestNewChain(t *testing.T) {
+	chain, err := NewChain("BTC", ChainConfig{RPCUser: "user", RPCPassword: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.Name() != ChainBTC {
+		t.Errorf("unexpected chain %q", chain.Name())
+	}
+	btc := chain.(*btcChain)
+	if runner := btc.runner.(execRunner); len(runner.env) != 1 || runner.env[0] != BTCRPCPasswordEnv+"=secret" {
+		t.Errorf("unexpected tool environment: %v", runner.env)
+	} else if runner.binary != btcDefaultBinary {
+		t.Errorf("unexpected tool %q", runner.binary)
+	}
+	for _, flag := range btc.flags {
+		if strings.Contains(flag, "secret") {
+			t.Errorf("RPC password is passed as argument %q", flag)
+		}
+	}
+	if _, err = NewChain("doge", ChainConfig{}); err == nil {
+		t.Error("expected unknown chain to be refused")
+	}
+	// the btcatomicswap tool only knows the main network and testnet
+	for _, network := range []string{"simnet", "regtest"} {
+		if _, err = NewChain(ChainBTC, ChainConfig{Network: network}); err == nil {
+			t.Errorf("expected unsupported network %q to be refused", network)
+		}
+	}
+	chain, err = NewChain(ChainBTC, ChainConfig{Network: "testnet3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags := chain.(*btcChain).flags; len(flags) != 1 || flags[0] != "--testnet" {
+		t.Errorf("unexpected tool flags: %v", flags)
+	}
+}
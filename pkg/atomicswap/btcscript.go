@@ -0,0 +1,516 @@
+package atomicswap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+// The Bitcoin transactions, scripts and addresses required to audit the atomic swap contracts
+// created by the BTC atomic swap tool, such that the contracts are validated against the
+// actual transaction which created them, rather than trusting the (human-readable) output of the tool.
+
+// Bitcoin script opcodes used by the atomic swap contract and the scripts spending it.
+const (
+	btcOp0                   = 0x00
+	btcOpPushData1           = 0x4c
+	btcOpPushData2           = 0x4d
+	btcOpPushData4           = 0x4e
+	btcOp1                   = 0x51
+	btcOp16                  = 0x60
+	btcOpIf                  = 0x63
+	btcOpElse                = 0x67
+	btcOpEndIf               = 0x68
+	btcOpDrop                = 0x75
+	btcOpDup                 = 0x76
+	btcOpSize                = 0x82
+	btcOpEqual               = 0x87
+	btcOpEqualVerify         = 0x88
+	btcOpSHA256              = 0xa8
+	btcOpHash160             = 0xa9
+	btcOpCheckSig            = 0xac
+	btcOpCheckLockTimeVerify = 0xb1
+)
+
+const (
+	// btcSatoshisPerCoin is the amount of satoshis in a single bitcoin.
+	btcSatoshisPerCoin = 100000000
+	// btcLockTimeThreshold is the lock time from which it is interpreted as a unix timestamp, rather than a block height.
+	btcLockTimeThreshold = 500000000
+)
+
+var (
+	errBTCInvalidTransaction = errors.New("invalid BTC transaction")
+	errBTCInvalidScript      = errors.New("invalid BTC script")
+	errBTCInvalidContract    = errors.New("script is not an atomic swap contract")
+	errBTCInvalidAddress     = errors.New("invalid BTC address")
+)
+
+type (
+	// btcNetwork defines the version bytes of the addresses of a BTC network.
+	btcNetwork struct {
+		pubKeyHashID byte
+		scriptHashID byte
+		// flag selects the network when passed to the tool, none is required for the main network
+		flag string
+	}
+
+	// btcTransaction is a (decoded) Bitcoin transaction.
+	btcTransaction struct {
+		Version  int32
+		Inputs   []btcInput
+		Outputs  []btcOutput
+		LockTime uint32
+	}
+
+	// btcInput is an input of a Bitcoin transaction, the previous transaction ID is stored in its internal byte order.
+	btcInput struct {
+		PreviousID      [32]byte
+		PreviousIndex   uint32
+		SignatureScript []byte
+		Sequence        uint32
+		Witness         [][]byte
+	}
+
+	// btcOutput is an output of a Bitcoin transaction.
+	btcOutput struct {
+		Value    int64
+		PkScript []byte
+	}
+
+	// btcInstruction is a single (parsed) instruction of a Bitcoin script.
+	btcInstruction struct {
+		Opcode byte
+		Data   []byte
+	}
+
+	// btcContract is the decoded atomic swap contract script of the BTC atomic swap tool:
+	//
+	//	OP_IF
+	//		OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <hashed secret> OP_EQUALVERIFY OP_DUP OP_HASH160 <recipient hash>
+	//	OP_ELSE
+	//		<lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 <refund hash>
+	//	OP_ENDIF
+	//	OP_EQUALVERIFY OP_CHECKSIG
+	btcContract struct {
+		HashedSecret  [32]byte
+		RecipientHash [20]byte
+		RefundHash    [20]byte
+		LockTime      int64
+	}
+)
+
+// btcNetworks are the networks supported by the btcatomicswap tool,
+// which only knows the main network and testnet (using its --testnet flag).
+var btcNetworks = map[string]btcNetwork{
+	"":         {pubKeyHashID: 0x00, scriptHashID: 0x05},
+	"mainnet":  {pubKeyHashID: 0x00, scriptHashID: 0x05},
+	"testnet":  {pubKeyHashID: 0x6f, scriptHashID: 0xc4, flag: "--testnet"},
+	"testnet3": {pubKeyHashID: 0x6f, scriptHashID: 0xc4, flag: "--testnet"},
+}
+
+// decodeBTCTransaction decodes a hex-encoded Bitcoin transaction, serialized with or without witness data.
+func decodeBTCTransaction(str string) (btcTransaction, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(str))
+	if err != nil {
+		return btcTransaction{}, errBTCInvalidTransaction
+	}
+	r := bytes.NewReader(b)
+	var txn btcTransaction
+	err = binary.Read(r, binary.LittleEndian, &txn.Version)
+	if err != nil {
+		return btcTransaction{}, errBTCInvalidTransaction
+	}
+	inputCount, err := readBTCVarInt(r)
+	if err != nil {
+		return btcTransaction{}, errBTCInvalidTransaction
+	}
+	var witness bool
+	if inputCount == 0 {
+		// segregated witness marker, followed by its flag
+		flag, err := r.ReadByte()
+		if err != nil || flag != 1 {
+			return btcTransaction{}, errBTCInvalidTransaction
+		}
+		witness = true
+		inputCount, err = readBTCVarInt(r)
+		if err != nil {
+			return btcTransaction{}, errBTCInvalidTransaction
+		}
+	}
+	if inputCount > uint64(len(b)) {
+		return btcTransaction{}, errBTCInvalidTransaction
+	}
+	txn.Inputs = make([]btcInput, inputCount)
+	for i := range txn.Inputs {
+		input := &txn.Inputs[i]
+		_, err = io.ReadFull(r, input.PreviousID[:])
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &input.PreviousIndex)
+		}
+		if err == nil {
+			input.SignatureScript, err = readBTCVarBytes(r)
+		}
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &input.Sequence)
+		}
+		if err != nil {
+			return btcTransaction{}, errBTCInvalidTransaction
+		}
+	}
+	outputCount, err := readBTCVarInt(r)
+	if err != nil || outputCount > uint64(len(b)) {
+		return btcTransaction{}, errBTCInvalidTransaction
+	}
+	txn.Outputs = make([]btcOutput, outputCount)
+	for i := range txn.Outputs {
+		output := &txn.Outputs[i]
+		err = binary.Read(r, binary.LittleEndian, &output.Value)
+		if err == nil {
+			output.PkScript, err = readBTCVarBytes(r)
+		}
+		if err != nil {
+			return btcTransaction{}, errBTCInvalidTransaction
+		}
+	}
+	if witness {
+		for i := range txn.Inputs {
+			itemCount, err := readBTCVarInt(r)
+			if err != nil || itemCount > uint64(len(b)) {
+				return btcTransaction{}, errBTCInvalidTransaction
+			}
+			for j := uint64(0); j < itemCount; j++ {
+				item, err := readBTCVarBytes(r)
+				if err != nil {
+					return btcTransaction{}, errBTCInvalidTransaction
+				}
+				txn.Inputs[i].Witness = append(txn.Inputs[i].Witness, item)
+			}
+		}
+	}
+	err = binary.Read(r, binary.LittleEndian, &txn.LockTime)
+	if err != nil || r.Len() != 0 {
+		return btcTransaction{}, errBTCInvalidTransaction
+	}
+	return txn, nil
+}
+
+// Encode returns the serialization of the transaction, including its witness data if requested and available.
+func (txn btcTransaction) Encode(witness bool) []byte {
+	if witness {
+		witness = false
+		for _, input := range txn.Inputs {
+			if len(input.Witness) > 0 {
+				witness = true
+				break
+			}
+		}
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, txn.Version)
+	if witness {
+		buf.Write([]byte{0, 1})
+	}
+	writeBTCVarInt(&buf, uint64(len(txn.Inputs)))
+	for _, input := range txn.Inputs {
+		buf.Write(input.PreviousID[:])
+		binary.Write(&buf, binary.LittleEndian, input.PreviousIndex)
+		writeBTCVarBytes(&buf, input.SignatureScript)
+		binary.Write(&buf, binary.LittleEndian, input.Sequence)
+	}
+	writeBTCVarInt(&buf, uint64(len(txn.Outputs)))
+	for _, output := range txn.Outputs {
+		binary.Write(&buf, binary.LittleEndian, output.Value)
+		writeBTCVarBytes(&buf, output.PkScript)
+	}
+	if witness {
+		for _, input := range txn.Inputs {
+			writeBTCVarInt(&buf, uint64(len(input.Witness)))
+			for _, item := range input.Witness {
+				writeBTCVarBytes(&buf, item)
+			}
+		}
+	}
+	binary.Write(&buf, binary.LittleEndian, txn.LockTime)
+	return buf.Bytes()
+}
+
+// ID returns the (hex-encoded) ID of the transaction, the reversed double SHA-256 hash of its serialization without witness data.
+func (txn btcTransaction) ID() string {
+	h := btcDoubleHash(txn.Encode(false))
+	return btcHashString(h)
+}
+
+// parseBTCScript parses the instructions of a Bitcoin script.
+func parseBTCScript(script []byte) ([]btcInstruction, error) {
+	var instructions []btcInstruction
+	for len(script) > 0 {
+		opcode := script[0]
+		script = script[1:]
+		var size int
+		switch {
+		case opcode > btcOp0 && opcode < btcOpPushData1:
+			size = int(opcode)
+		case opcode == btcOpPushData1:
+			if len(script) < 1 {
+				return nil, errBTCInvalidScript
+			}
+			size, script = int(script[0]), script[1:]
+		case opcode == btcOpPushData2:
+			if len(script) < 2 {
+				return nil, errBTCInvalidScript
+			}
+			size, script = int(binary.LittleEndian.Uint16(script)), script[2:]
+		case opcode == btcOpPushData4:
+			if len(script) < 4 {
+				return nil, errBTCInvalidScript
+			}
+			size, script = int(binary.LittleEndian.Uint32(script)), script[4:]
+		}
+		if size < 0 || size > len(script) {
+			return nil, errBTCInvalidScript
+		}
+		instruction := btcInstruction{Opcode: opcode}
+		if size > 0 {
+			instruction.Data, script = script[:size], script[size:]
+		}
+		instructions = append(instructions, instruction)
+	}
+	return instructions, nil
+}
+
+// decodeBTCContract decodes an atomic swap contract script.
+func decodeBTCContract(script []byte) (btcContract, error) {
+	instructions, err := parseBTCScript(script)
+	if err != nil {
+		return btcContract{}, err
+	}
+	if len(instructions) != 20 {
+		return btcContract{}, errBTCInvalidContract
+	}
+	// the expected opcodes, pushes of data are checked separately
+	for idx, opcode := range []byte{
+		btcOpIf, btcOpSize, 0, btcOpEqualVerify, btcOpSHA256, 0, btcOpEqualVerify, btcOpDup, btcOpHash160, 0,
+		btcOpElse, 0, btcOpCheckLockTimeVerify, btcOpDrop, btcOpDup, btcOpHash160, 0,
+		btcOpEndIf, btcOpEqualVerify, btcOpCheckSig,
+	} {
+		if opcode != 0 && (instructions[idx].Opcode != opcode || instructions[idx].Data != nil) {
+			return btcContract{}, errBTCInvalidContract
+		}
+	}
+	secretSize, err := decodeBTCScriptNumber(instructions[2])
+	if err != nil || secretSize != 32 || len(instructions[5].Data) != 32 ||
+		len(instructions[9].Data) != 20 || len(instructions[16].Data) != 20 {
+		return btcContract{}, errBTCInvalidContract
+	}
+	var contract btcContract
+	copy(contract.HashedSecret[:], instructions[5].Data)
+	copy(contract.RecipientHash[:], instructions[9].Data)
+	copy(contract.RefundHash[:], instructions[16].Data)
+	contract.LockTime, err = decodeBTCScriptNumber(instructions[11])
+	if err != nil || contract.LockTime < btcLockTimeThreshold {
+		// the tool only creates contracts locked until a point in time
+		return btcContract{}, errBTCInvalidContract
+	}
+	return contract, nil
+}
+
+// decodeBTCScriptNumber decodes a number pushed by a script instruction, limited to 5 bytes as done by OP_CHECKLOCKTIMEVERIFY.
+func decodeBTCScriptNumber(instruction btcInstruction) (int64, error) {
+	switch {
+	case instruction.Opcode == btcOp0:
+		return 0, nil
+	case instruction.Opcode >= btcOp1 && instruction.Opcode <= btcOp16:
+		return int64(instruction.Opcode-btcOp1) + 1, nil
+	case len(instruction.Data) == 0 || len(instruction.Data) > 5:
+		return 0, errBTCInvalidScript
+	}
+	data := instruction.Data
+	var n int64
+	for i, b := range data {
+		n |= int64(b) << uint(8*i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		// sign bit
+		n &^= int64(0x80) << uint(8*(len(data)-1))
+		n = -n
+	}
+	return n, nil
+}
+
+// btcScriptHashPkScript returns the (P2SH) output script paying to the given script.
+func btcScriptHashPkScript(script []byte) []byte {
+	h := btcHash160(script)
+	return append(append([]byte{btcOpHash160, 20}, h[:]...), btcOpEqual)
+}
+
+// btcHash160 returns the RIPEMD-160 hash of the SHA-256 hash of the given data.
+func btcHash160(data []byte) (h [20]byte) {
+	sha := sha256.Sum256(data)
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	copy(h[:], hasher.Sum(nil))
+	return
+}
+
+// btcDoubleHash returns the double SHA-256 hash of the given data.
+func btcDoubleHash(data []byte) [32]byte {
+	h := sha256.Sum256(data)
+	return sha256.Sum256(h[:])
+}
+
+// btcHashString returns the hex encoding of a hash, in the reversed byte order used to display hashes.
+func btcHashString(h [32]byte) string {
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	return hex.EncodeToString(h[:])
+}
+
+// btcAmountString formats an amount of satoshis as a decimal amount of bitcoins.
+func btcAmountString(satoshis int64) string {
+	str := new(big.Rat).SetFrac64(satoshis, btcSatoshisPerCoin).FloatString(8)
+	return strings.TrimSuffix(strings.TrimRight(str, "0"), ".")
+}
+
+// parseBTCAmount parses a decimal amount of bitcoins as an amount of satoshis.
+func parseBTCAmount(str string) (int64, error) {
+	amount, ok := new(big.Rat).SetString(str)
+	if !ok || amount.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid BTC amount %q", str)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt64(btcSatoshisPerCoin))
+	if !amount.IsInt() || !amount.Num().IsInt64() {
+		return 0, fmt.Errorf("invalid BTC amount %q", str)
+	}
+	return amount.Num().Int64(), nil
+}
+
+// encodeAddress returns the base58check encoding of the given hash, using the given version byte.
+func (network btcNetwork) encodeAddress(version byte, hash [20]byte) string {
+	b := append([]byte{version}, hash[:]...)
+	checksum := btcDoubleHash(b)
+	return btcBase58Encode(append(b, checksum[:4]...))
+}
+
+// decodePubKeyHashAddress decodes a pay-to-public-key-hash address of the network.
+func (network btcNetwork) decodePubKeyHashAddress(address string) ([20]byte, error) {
+	b, err := btcBase58Decode(address)
+	if err != nil || len(b) != 25 || b[0] != network.pubKeyHashID {
+		return [20]byte{}, errBTCInvalidAddress
+	}
+	checksum := btcDoubleHash(b[:21])
+	if !bytes.Equal(checksum[:4], b[21:]) {
+		return [20]byte{}, errBTCInvalidAddress
+	}
+	var hash [20]byte
+	copy(hash[:], b[1:21])
+	return hash, nil
+}
+
+const btcBase58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var btcBase58Radix = big.NewInt(58)
+
+func btcBase58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	var encoded []byte
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, btcBase58Radix, mod)
+		encoded = append(encoded, btcBase58Alphabet[mod.Int64()])
+	}
+	// leading zero bytes are encoded as leading ones
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		encoded = append(encoded, btcBase58Alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+func btcBase58Decode(str string) ([]byte, error) {
+	n := new(big.Int)
+	for _, c := range str {
+		idx := strings.IndexRune(btcBase58Alphabet, c)
+		if idx < 0 {
+			return nil, errBTCInvalidAddress
+		}
+		n.Mul(n, btcBase58Radix)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+	var zeros int
+	for zeros < len(str) && str[zeros] == btcBase58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+func readBTCVarInt(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch prefix {
+	case 0xfd:
+		var n uint16
+		err = binary.Read(r, binary.LittleEndian, &n)
+		return uint64(n), err
+	case 0xfe:
+		var n uint32
+		err = binary.Read(r, binary.LittleEndian, &n)
+		return uint64(n), err
+	case 0xff:
+		var n uint64
+		err = binary.Read(r, binary.LittleEndian, &n)
+		return n, err
+	default:
+		return uint64(prefix), nil
+	}
+}
+
+func readBTCVarBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := readBTCVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, size)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+func writeBTCVarInt(w *bytes.Buffer, n uint64) {
+	switch {
+	case n < 0xfd:
+		w.WriteByte(byte(n))
+	case n <= 0xffff:
+		w.WriteByte(0xfd)
+		binary.Write(w, binary.LittleEndian, uint16(n))
+	case n <= 0xffffffff:
+		w.WriteByte(0xfe)
+		binary.Write(w, binary.LittleEndian, uint32(n))
+	default:
+		w.WriteByte(0xff)
+		binary.Write(w, binary.LittleEndian, n)
+	}
+}
+
+func writeBTCVarBytes(w *bytes.Buffer, b []byte) {
+	writeBTCVarInt(w, uint64(len(b)))
+	w.Write(b)
+}
@@ -0,0 +1,88 @@
+package atomicswap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBTCAddressEncoding(t *testing.T) {
+	mainnet := btcNetworks["mainnet"]
+	if address := mainnet.encodeAddress(mainnet.pubKeyHashID, [20]byte{}); address != "1111111111111111111114oLvT2" {
+		t.Errorf("unexpected address %s", address)
+	}
+	testnet := btcNetworks["testnet"]
+	hash := [20]byte{1, 2, 3, 4, 5}
+	address := testnet.encodeAddress(testnet.pubKeyHashID, hash)
+	decoded, err := testnet.decodePubKeyHashAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != hash {
+		t.Errorf("decoded hash %x, expected %x", decoded, hash)
+	}
+	for _, invalid := range []string{
+		"", "0OIl", address[:len(address)-1] + "1",
+		testnet.encodeAddress(testnet.scriptHashID, hash),
+		mainnet.encodeAddress(mainnet.pubKeyHashID, hash),
+	} {
+		if _, err = testnet.decodePubKeyHashAddress(invalid); err == nil {
+			t.Errorf("decoded invalid address %q", invalid)
+		}
+	}
+}
+
+func TestBTCTransactionEncoding(t *testing.T) {
+	txn := btcTransaction{
+		Version: 2,
+		Inputs: []btcInput{
+			{PreviousID: [32]byte{1}, PreviousIndex: 1, SignatureScript: []byte{btcOp1}, Sequence: 0xfffffffe},
+			{PreviousID: [32]byte{2}, Sequence: 0xffffffff, Witness: [][]byte{{1, 2}, {3}}},
+		},
+		Outputs:  []btcOutput{{Value: 5000, PkScript: btcScriptHashPkScript([]byte{btcOp1})}},
+		LockTime: 1500000000,
+	}
+	for _, witness := range []bool{false, true} {
+		decoded, err := decodeBTCTransaction(hex.EncodeToString(txn.Encode(witness)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded.Encode(true), txn.Encode(witness)) {
+			t.Errorf("witness %v: decoded %+v, expected %+v", witness, decoded, txn)
+		}
+		if decoded.ID() != txn.ID() {
+			t.Errorf("witness %v: decoded transaction has ID %s, expected %s", witness, decoded.ID(), txn.ID())
+		}
+	}
+	for _, invalid := range []string{"", "zz", "02000000", hex.EncodeToString(txn.Encode(true)) + "00"} {
+		if _, err := decodeBTCTransaction(invalid); err == nil {
+			t.Errorf("decoded invalid transaction %q", invalid)
+		}
+	}
+}
+
+func TestDecodeBTCContract(t *testing.T) {
+	contract := btcContract{
+		HashedSecret:  [32]byte{1},
+		RecipientHash: [20]byte{2},
+		RefundHash:    [20]byte{3},
+		LockTime:      1600000000,
+	}
+	script := newBTCContractScript(contract)
+	decoded, err := decodeBTCContract(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != contract {
+		t.Errorf("decoded contract %+v, expected %+v", decoded, contract)
+	}
+	// a contract locked until a block height, a modified opcode and a truncated contract are refused
+	contract.LockTime = 1000
+	modified := append([]byte(nil), script...)
+	modified[len(modified)-1] = btcOpEqual
+	for _, invalid := range [][]byte{newBTCContractScript(contract), modified, script[:len(script)-1]} {
+		if _, err = decodeBTCContract(invalid); err == nil {
+			t.Errorf("decoded invalid contract %x", invalid)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package client
 
 import (
-	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -9,6 +8,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -18,7 +18,10 @@ import (
 	"github.com/threefoldtech/rivine/types"
 
 	cfapi "github.com/nbh-digital/goldchain/extensions/custodyfees/api"
-	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+	gcatomicswap "github.com/nbh-digital/goldchain/pkg/atomicswap"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
 )
 
 func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
@@ -38,6 +41,18 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 			Long: `Create an atomic swap contract as a participant,
 		using the secret hash given by the initiator.
 		
+		Additional receivers can be given using the --receiver flag, creating a multi-output contract,
+		locking a contract for each receiver, all redeemable using the same secret.
+		A JSON list of contracts is returned when using the '--encoding json' flag.
+		
+		A receiver can be a multisig wallet, given by the addresses of its co-signers
+		and its minimum amount of signatures, formatted as <address>,<address>[,...]/<minimumsignatures>,
+		in which case its contract is redeemed by its co-signers using 'atomicswap redeem --multisig'.
+		
+		When a counter chain is given using the --counterchain flag,
+		the contract is created on that chain instead, using its wallet node,
+		locking the amount (in coins of that chain) for the initiator's address on that chain.
+		
 		Returned status codes:
 		
 		  0: contract created successfully as participant
@@ -83,6 +98,21 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		randonly generating a secret for you, and deriving the secret hash from it.
 		The used secret is returned through the STDOUT with the rest of the contract details.
 		
+		Additional receivers can be given using the --receiver flag, creating a multi-output contract,
+		locking a contract for each receiver, all redeemable using the same secret.
+		A JSON list of contracts is returned when using the '--encoding json' flag.
+		
+		A receiver can be a multisig wallet, given by the addresses of its co-signers
+		and its minimum amount of signatures, formatted as <address>,<address>[,...]/<minimumsignatures>,
+		in which case its contract is redeemed by its co-signers using 'atomicswap redeem --multisig'.
+		
+		When a counter chain is given using the --counterchain flag, both legs of
+		a cross-chain atomic swap are orchestrated. Once the goldchain contract is created,
+		the contract and contract transaction created by the participant on the counter chain
+		are read from STDIN, audited against the --counter-amount, --counter-address and
+		--counter-min-duration flags, and redeemed, revealing the secret to the participant.
+		The counter contract has to be refundable before the goldchain contract.
+		
 		Returned status codes:
 		
 		  0: contract created successfully as initiator
@@ -120,7 +150,7 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		}
 
 		auditCmd = &cobra.Command{
-			Use:   "auditcontract outputid [transactionid|jsonTransaction]",
+			Use:   "auditcontract outputid[,outputid...] [transactionid|jsonTransaction]",
 			Short: "Audit a created atomic swap contract.",
 			Long: `Audit a created atomic swap contract.
 		
@@ -138,6 +168,16 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		Optionally the participant's address, currency amount and secret hash are validated,
 		by giving one, some or all of them as flag arguments, for both quick and full audits.
 		
+		Audit all contracts of a multi-output contract by giving their comma-separated outputids,
+		in which case the contracts have to share the same sender, secret hash and time lock,
+		the currency amount is validated against the total value of the contracts,
+		and the participant's address has to be the receiver of at least one contract.
+		A JSON list of contracts is returned when using the '--encoding json' flag.
+		
+		When a counter chain is given using the --counterchain flag, the contract
+		and contract transaction (instead of the outputid) of a contract on that chain are audited,
+		validating the receiver's address given using the --counter-receiver flag instead.
+		
 		Returned status codes:
 		
 		  0: contract found and validated successfully
@@ -188,6 +228,9 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		Optionally, the extracted secret is validated,
 		by comparing its hashed version to the secret hash given using the --secrethash flag.
 		
+		When a counter chain is given using the --counterchain flag, the secret is extracted
+		from the given raw redemption transaction on that chain, in which case the --secrethash flag is required.
+		
 		Returned status codes:
 		
 		  0: contract found and secret extracted successfully
@@ -212,10 +255,21 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		}
 
 		redeemCmd = &cobra.Command{
-			Use:   "redeem outputid secret",
+			Use:   "redeem outputid[,outputid...] secret",
 			Short: "Redeem the coins locked in an atomic swap contract.",
 			Long: `Redeem the coins locked in an atomic swap contract intended for you.
 		
+		Multiple contracts, such as the contracts of a multi-output contract,
+		are redeemed in a single transaction by giving their comma-separated outputids.
+		
+		Contracts of a multisig receiver are redeemed by its co-signers using the --multisig flag,
+		in which case the partially signed transaction, signed by this wallet, is printed to STDOUT,
+		to be signed by the other co-signers using 'wallet pst sign',
+		and published using 'wallet pst finalize' once complete.
+		
+		When a counter chain is given using the --counterchain flag, the contract
+		and contract transaction (instead of the outputid) of a contract on that chain are expected.
+		
 		Returned status codes:
 		
 		  0: contract found and redeemed successfully
@@ -234,14 +288,20 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		Note that this output is only returned in case the command
 		was successful, and thus exited with status code 0.
 		`,
-			Run: rivinecli.Wrap(atomicSwapCmd.redeemCmd),
+			Run: atomicSwapCmd.redeemCmd,
 		}
 
 		refundCmd = &cobra.Command{
-			Use:   "refund outputid",
+			Use:   "refund outputid[,outputid...]",
 			Short: "Refund the coins locked in an atomic swap contract.",
 			Long: `Refund the coins locked in an atomic swap contract created by you.
 		
+		Multiple contracts, such as the contracts of a multi-output contract,
+		are refunded in a single transaction by giving their comma-separated outputids.
+		
+		When a counter chain is given using the --counterchain flag, the contract
+		and contract transaction (instead of the outputid) of a contract on that chain are expected.
+		
 		Returned status codes:
 		
 		  0: contract found and refunded successfully
@@ -260,7 +320,28 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		Note that this output is only returned in case the command
 		was successful, and thus exited with status code 0.
 		`,
-			Run: rivinecli.Wrap(atomicSwapCmd.refundCmd),
+			Run: atomicSwapCmd.refundCmd,
+		}
+
+		listCmd = &cobra.Command{
+			Use:   "list",
+			Short: "List the atomic swap contracts tracked by the wallet.",
+			Long: `List the confirmed atomic swap contracts of which the wallet
+		owns the sender (refund) and/or receiver (redeem) address, in the order they were confirmed.
+		
+		The daemon redeems an open contract, of which the wallet owns the receiver address,
+		as soon as its secret is revealed on chain, and refunds an open contract,
+		of which the wallet owns the sender address, as soon as its time lock has been reached.
+		The secret of a contract is listed once revealed, such that it can be used
+		to redeem the matching contract on the other chain.
+		
+		Returned status codes:
+		
+		  0: contracts listed successfully
+		  1: generic error, automatically recovering is not possible or recommended
+		  64: misusage of the command, see --help on how to use the command
+		`,
+			Run: rivinecli.Wrap(atomicSwapCmd.listCmd),
 		}
 	)
 	rootCmd.AddCommand(
@@ -270,6 +351,7 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 		extractSecretCmd,
 		redeemCmd,
 		refundCmd,
+		listCmd,
 	)
 
 	// create flags
@@ -278,18 +360,52 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 	rootCmd.PersistentFlags().Var(
 		cli.NewEncodingTypeFlag(0, &atomicSwapCmd.rootCfg.EncodingType, cli.EncodingTypeHuman|cli.EncodingTypeJSON),
 		"encoding", cli.EncodingTypeFlagDescription(cli.EncodingTypeHuman|cli.EncodingTypeJSON))
+	rootCmd.PersistentFlags().StringVar(&atomicSwapCmd.rootCfg.CounterChain, "counterchain", "",
+		"the counter chain of a cross-chain atomic swap (supported: "+gcatomicswap.ChainBTC+"), on which the contract is created or spent")
+	rootCmd.PersistentFlags().StringVar(&atomicSwapCmd.rootCfg.CounterChainCfg.Binary, "counterchain-bin", "",
+		"the atomic swap tool used to interact with the counter chain, the btcatomicswapenv wrapper of btcatomicswap is used for "+gcatomicswap.ChainBTC+" if none is given")
+	rootCmd.PersistentFlags().StringVar(&atomicSwapCmd.rootCfg.CounterChainCfg.RPCServer, "counterchain-rpcserver", "",
+		"the RPC server of the counter chain (wallet) node")
+	rootCmd.PersistentFlags().StringVar(&atomicSwapCmd.rootCfg.CounterChainCfg.RPCUser, "counterchain-rpcuser", "",
+		"the RPC user of the counter chain (wallet) node")
+	rootCmd.PersistentFlags().StringVar(&atomicSwapCmd.rootCfg.CounterChainCfg.RPCPassword, "counterchain-rpcpass", "",
+		"the RPC password of the counter chain (wallet) node, for "+gcatomicswap.ChainBTC+" it can be given using the "+
+			gcatomicswap.BTCRPCPasswordEnv+" environment variable instead, such that it is not visible in the process list")
+	rootCmd.PersistentFlags().StringVar(&atomicSwapCmd.rootCfg.CounterChainCfg.Network, "counterchain-network", "",
+		"the network of the counter chain (e.g. testnet), its main network is used if none is given")
 
 	participateCmd.Flags().DurationVarP(
 		&atomicSwapCmd.participateCfg.Duration, "duration", "d",
 		time.Hour*24, "the duration of the atomic swap contract, the amount of time the initiator has to collect")
 	participateCmd.Flags().Var(cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.participateCfg.SourceUnlockHash}, "initiator",
 		"optionally define a wallet address (unlockhash) that is to be used for refunding purposes, one will be generated for you if none is given")
+	participateCmd.Flags().BoolVar(
+		&atomicSwapCmd.participateCfg.EscrowCustodyFee, "escrow-custody-fee", false,
+		"pre-fund the custody and miner fee on top of the amount, such that the initiator receives at least the amount when redeeming before the contract expires")
+	participateCmd.Flags().StringArrayVar(
+		&atomicSwapCmd.participateCfg.Receivers, "receiver", nil,
+		"an additional receiver formatted as <address>:<amount>, or as <address>,<address>[,...]/<minimumsignatures>:<amount> for a multisig receiver, can be given multiple times to create a multi-output contract redeemable using the same secret")
 
 	initiateCmd.Flags().DurationVarP(
 		&atomicSwapCmd.initiateCfg.Duration, "duration", "d",
 		time.Hour*48, "the duration of the atomic swap contract, the amount of time the participant has to collect")
 	initiateCmd.Flags().Var(cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.initiateCfg.SourceUnlockHash}, "initiator",
 		"optionally define a wallet address (unlockhash) that is to be used for refunding purposes, one will be generated for you if none is given")
+	initiateCmd.Flags().BoolVar(
+		&atomicSwapCmd.initiateCfg.EscrowCustodyFee, "escrow-custody-fee", false,
+		"pre-fund the custody and miner fee on top of the amount, such that the participant receives at least the amount when redeeming before the contract expires")
+	initiateCmd.Flags().StringArrayVar(
+		&atomicSwapCmd.initiateCfg.Receivers, "receiver", nil,
+		"an additional receiver formatted as <address>:<amount>, or as <address>,<address>[,...]/<minimumsignatures>:<amount> for a multisig receiver, can be given multiple times to create a multi-output contract redeemable using the same secret")
+	initiateCmd.Flags().StringVar(
+		&atomicSwapCmd.initiateCfg.CounterAmount, "counter-amount", "",
+		"the amount of coins the participant has to lock on the counter chain, required when a counter chain is given")
+	initiateCmd.Flags().StringVar(
+		&atomicSwapCmd.initiateCfg.CounterAddress, "counter-address", "",
+		"optionally validate that the contract of the participant on the counter chain can be redeemed by this address")
+	initiateCmd.Flags().DurationVar(
+		&atomicSwapCmd.initiateCfg.CounterMinDuration, "counter-min-duration", time.Hour,
+		"the minimum duration the contract of the participant on the counter chain has to be redeemable for")
 
 	auditCmd.Flags().Var(
 		cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.auditCfg.HashedSecret}, "secrethash",
@@ -303,11 +419,18 @@ func CreateAtomicSwapCmd(client *rivinecli.CommandLineClient) *cobra.Command {
 	auditCmd.Flags().DurationVar(
 		&atomicSwapCmd.auditCfg.MinDurationLeft, "min-duration", 0,
 		"optionally validate the given contract has sufficient duration left, as defined by the timelock in the found atomic swap contract condition")
+	auditCmd.Flags().StringVar(
+		&atomicSwapCmd.auditCfg.CounterReceiverAddress, "counter-receiver", "",
+		"optionally validate the given receiver's address to the one found in the atomic swap contract on the counter chain")
 
 	extractSecretCmd.Flags().Var(
 		cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.extractSecretCfg.HashedSecret}, "secrethash",
 		"optionally validate the secret of the found atomic swap contract condition by comparing its hashed version with this secret hash")
 
+	redeemCmd.Flags().BoolVar(
+		&atomicSwapCmd.redeemCfg.MultiSig, "multisig", false,
+		"co-sign the redemption of contracts of a multisig receiver, printing the partially signed transaction to be signed by the other co-signers")
+
 	// return root command
 	return rootCmd
 }
@@ -318,44 +441,69 @@ type atomicSwapCmd struct {
 	rootCfg struct {
 		EncodingType cli.EncodingType
 		YesToAll     bool
+		// CounterChain is the chain of the other leg of a cross-chain atomic swap,
+		// the contract being created or spent on that chain if defined
+		CounterChain    string
+		CounterChainCfg gcatomicswap.ChainConfig
 	}
 	participateCfg struct {
 		Duration         time.Duration
 		SourceUnlockHash types.UnlockHash
+		EscrowCustodyFee bool
+		Receivers        []string
 	}
 	initiateCfg struct {
-		Duration         time.Duration
-		SourceUnlockHash types.UnlockHash
+		Duration           time.Duration
+		SourceUnlockHash   types.UnlockHash
+		EscrowCustodyFee   bool
+		Receivers          []string
+		CounterAmount      string
+		CounterAddress     string
+		CounterMinDuration time.Duration
 	}
 	auditCfg struct {
 		ReceiverAddress  types.UnlockHash
 		CoinAmountString string
 		HashedSecret     types.AtomicSwapHashedSecret
 		MinDurationLeft  time.Duration
+		// CounterReceiverAddress is the receiver's address of a contract on a counter chain
+		CounterReceiverAddress string
 	}
 	extractSecretCfg struct {
 		HashedSecret types.AtomicSwapHashedSecret
 	}
+	redeemCfg struct {
+		// MultiSig defines whether the contracts are redeemed by the co-signers of a multisig receiver
+		MultiSig bool
+	}
 }
 
 type (
 	// AtomicSwapOutputCreation represents the formatted output
 	// of the atomic swap creation commands (initiate and participate).
 	AtomicSwapOutputCreation struct {
-		Coins         types.Currency            `json:"coins"`
-		Contract      types.AtomicSwapCondition `json:"contract"`
-		ContractID    types.UnlockHash          `json:"contractid"`
-		Secret        *types.AtomicSwapSecret   `json:"secret,omitempty"`
-		OutputID      types.CoinOutputID        `json:"outputid"`
-		TransactionID types.TransactionID       `json:"transactionid"`
+		Coins    types.Currency            `json:"coins"`
+		Contract types.AtomicSwapCondition `json:"contract"`
+		// MultiSigReceiver is only defined for a contract redeemed by the co-signers of a multisig receiver
+		MultiSigReceiver *types.MultiSignatureCondition `json:"multisigreceiver,omitempty"`
+		ContractID       types.UnlockHash               `json:"contractid"`
+		Secret           *types.AtomicSwapSecret        `json:"secret,omitempty"`
+		OutputID         types.CoinOutputID             `json:"outputid"`
+		TransactionID    types.TransactionID            `json:"transactionid"`
 	}
 	// AtomicSwapOutputAudit represents the formatted output
 	// of the atomic swap audit command
 	AtomicSwapOutputAudit struct {
-		Coins          types.Currency            `json:"coins"`
-		SpendableCoins types.Currency            `json:"spendablecoins"`
-		CustodyFeeDebt types.Currency            `json:"custodyfeedebt"`
-		Contract       types.AtomicSwapCondition `json:"contract"`
+		Coins          types.Currency `json:"coins"`
+		SpendableCoins types.Currency `json:"spendablecoins"`
+		CustodyFeeDebt types.Currency `json:"custodyfeedebt"`
+		// GuaranteedCoins are the coins received at least when redeeming the contract before its time lock,
+		// WorstCaseCustodyFee is the custody fee to be paid when redeeming or refunding it at its time lock
+		GuaranteedCoins     types.Currency            `json:"guaranteedcoins"`
+		WorstCaseCustodyFee types.Currency            `json:"worstcasecustodyfee"`
+		Contract            types.AtomicSwapCondition `json:"contract"`
+		// MultiSigReceiver is only defined for a contract redeemed by the co-signers of a multisig receiver
+		MultiSigReceiver *types.MultiSignatureCondition `json:"multisigreceiver,omitempty"`
 	}
 	// AtomicSwapOutputExtractSecret represents the formatted output
 	// of the atomic swap extract secret command
@@ -370,17 +518,19 @@ type (
 )
 
 func (atomicSwapCmd *atomicSwapCmd) participateCmd(participantAddress, amount, hashedSecret string) {
+	if atomicSwapCmd.rootCfg.CounterChain != "" {
+		atomicSwapCmd.participateCounterChainCmd(participantAddress, amount, hashedSecret)
+		return
+	}
+
 	// parse hastings
 	hastings := parseCoinArg(atomicSwapCmd.cli.CreateCurrencyConvertor(), amount)
 
 	// parse receiver (=participant) and sender (=initiator)
-	var (
-		receiver, sender types.UnlockHash
-	)
-	err := receiver.LoadString(participantAddress)
-	if err != nil {
-		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse participant address (unlock hash):", err)
-	}
+	var sender types.UnlockHash
+	firstReceiver := parseAtomicSwapReceiver(participantAddress)
+	firstReceiver.Amount = hastings
+	receiver := firstReceiver.Address
 	if atomicSwapCmd.participateCfg.SourceUnlockHash.Type != 0 {
 		// use the hash given by the user explicitly
 		sender = atomicSwapCmd.participateCfg.SourceUnlockHash
@@ -399,13 +549,23 @@ func (atomicSwapCmd *atomicSwapCmd) participateCmd(participantAddress, amount, h
 		cli.DieWithExitCode(cli.ExitCodeUsage, "invalid secret hash length")
 	}
 	var hash types.AtomicSwapHashedSecret
-	_, err = hex.Decode(hash[:], []byte(hashedSecret))
+	_, err := hex.Decode(hash[:], []byte(hashedSecret))
 	if err != nil {
 		cli.DieWithExitCode(cli.ExitCodeUsage, "invalid secret hash:", err)
 	}
 
+	if len(atomicSwapCmd.participateCfg.Receivers) > 0 || firstReceiver.MultiSigCondition != nil {
+		// create a multi-output contract, or the contract of a multisig receiver
+		receivers := append([]gcmodules.AtomicSwapReceiver{firstReceiver},
+			atomicSwapCmd.parseAtomicSwapReceivers(atomicSwapCmd.participateCfg.Receivers)...)
+		atomicSwapCmd.createAtomicSwapContracts(receivers, sender, hash, types.AtomicSwapSecret{},
+			atomicSwapCmd.participateCfg.Duration, atomicSwapCmd.participateCfg.EscrowCustodyFee)
+		return
+	}
+
 	// create the contract
-	atomicSwapCmd.createAtomicSwapContract(hastings, sender, receiver, hash, atomicSwapCmd.participateCfg.Duration)
+	atomicSwapCmd.createAtomicSwapContract(hastings, sender, receiver, hash,
+		atomicSwapCmd.participateCfg.Duration, atomicSwapCmd.participateCfg.EscrowCustodyFee)
 }
 
 func (atomicSwapCmd *atomicSwapCmd) initiateCmd(participantAddress, amount string) {
@@ -413,13 +573,10 @@ func (atomicSwapCmd *atomicSwapCmd) initiateCmd(participantAddress, amount strin
 	hastings := parseCoinArg(atomicSwapCmd.cli.CreateCurrencyConvertor(), amount)
 
 	// parse receiver (=participant) and sender (=initiator)
-	var (
-		receiver, sender types.UnlockHash
-	)
-	err := receiver.LoadString(participantAddress)
-	if err != nil {
-		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse participant address (unlock hash):", err)
-	}
+	var sender types.UnlockHash
+	firstReceiver := parseAtomicSwapReceiver(participantAddress)
+	firstReceiver.Amount = hastings
+	receiver := firstReceiver.Address
 	if atomicSwapCmd.initiateCfg.SourceUnlockHash.Type != 0 {
 		// use the hash given by the user explicitly
 		sender = atomicSwapCmd.initiateCfg.SourceUnlockHash
@@ -433,13 +590,46 @@ func (atomicSwapCmd *atomicSwapCmd) initiateCmd(participantAddress, amount strin
 		sender = resp.Addresses[0]
 	}
 
+	if len(atomicSwapCmd.initiateCfg.Receivers) > 0 || firstReceiver.MultiSigCondition != nil {
+		if atomicSwapCmd.rootCfg.CounterChain != "" {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "a cross-chain atomic swap cannot have additional or multisig receivers")
+		}
+		// create a multi-output contract, or the contract of a multisig receiver
+		receivers := append([]gcmodules.AtomicSwapReceiver{firstReceiver},
+			atomicSwapCmd.parseAtomicSwapReceivers(atomicSwapCmd.initiateCfg.Receivers)...)
+		secret, err := types.NewAtomicSwapSecret()
+		if err != nil {
+			cli.Die("failed to crypto-generate secret:", err)
+		}
+		atomicSwapCmd.createAtomicSwapContracts(receivers, sender, types.NewAtomicSwapHashedSecret(secret), secret,
+			atomicSwapCmd.initiateCfg.Duration, atomicSwapCmd.initiateCfg.EscrowCustodyFee)
+		return
+	}
+
+	if atomicSwapCmd.rootCfg.CounterChain != "" {
+		// orchestrate both legs of the swap
+		atomicSwapCmd.initiateCrossChainSwap(hastings, sender, receiver)
+		return
+	}
+
 	// create the contract
 	atomicSwapCmd.createAtomicSwapContract(hastings, sender, receiver,
-		types.AtomicSwapHashedSecret{}, atomicSwapCmd.initiateCfg.Duration)
+		types.AtomicSwapHashedSecret{}, atomicSwapCmd.initiateCfg.Duration, atomicSwapCmd.initiateCfg.EscrowCustodyFee)
 }
 
-func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Currency, sender, receiver types.UnlockHash, hash types.AtomicSwapHashedSecret, duration time.Duration) {
-	if hastings.Cmp(atomicSwapCmd.cli.Config.MinimumTransactionFee) != 1 {
+func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Currency, sender, receiver types.UnlockHash, hash types.AtomicSwapHashedSecret, duration time.Duration, escrowCustodyFee bool) {
+	output := atomicSwapCmd.publishAtomicSwapContract(hastings, sender, receiver, hash, duration, escrowCustodyFee)
+	atomicSwapCmd.printAtomicSwapContractCreation(output)
+}
+
+// publishAtomicSwapContract creates an atomic swap contract, generating a secret if no hashed secret is given,
+// and publishes it, once confirmed by the user.
+func (atomicSwapCmd *atomicSwapCmd) publishAtomicSwapContract(hastings types.Currency, sender, receiver types.UnlockHash, hash types.AtomicSwapHashedSecret, duration time.Duration, escrowCustodyFee bool) AtomicSwapOutputCreation {
+	if escrowCustodyFee {
+		if hastings.IsZero() {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "an atomic swap contract has to have a coin value greater than 0")
+		}
+	} else if hastings.Cmp(atomicSwapCmd.cli.Config.MinimumTransactionFee) != 1 {
 		cli.DieWithExitCode(cli.ExitCodeUsage, "an atomic swap contract has to have a coin value higher than the minimum transaction fee of 1")
 	}
 
@@ -466,6 +656,10 @@ func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Curr
 		HashedSecret: hash,
 		TimeLock:     types.OffsetTimestamp(duration),
 	}
+	if escrowCustodyFee {
+		// lock the custody fee to be paid at the time lock, as well as the miner fee, on top of the given amount
+		hastings = gcmodules.AtomicSwapEscrowValue(hastings, types.CurrentTimestamp(), condition.TimeLock, atomicSwapCmd.cli.Config.MinimumTransactionFee)
+	}
 	if !atomicSwapCmd.rootCfg.YesToAll {
 		// print contract for review
 		atomicSwapCmd.printContractInfo(os.Stderr, hastings, condition, secret, nil)
@@ -500,40 +694,62 @@ func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Curr
 		cli.Die("didn't find atomic swap contract registered in any returned coin output")
 	}
 
+	output := AtomicSwapOutputCreation{
+		Coins:         hastings,
+		Contract:      condition,
+		ContractID:    condition.UnlockHash(),
+		OutputID:      response.Transaction.CoinOutputID(uint64(coinOutputIndex)),
+		TransactionID: response.Transaction.ID(),
+	}
+	if secret != (types.AtomicSwapSecret{}) {
+		output.Secret = &secret
+	}
+	return output
+}
+
+// printAtomicSwapContractCreation prints a published atomic swap contract.
+func (atomicSwapCmd *atomicSwapCmd) printAtomicSwapContractCreation(output AtomicSwapOutputCreation) {
 	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
 		// if encoding type is JSON, simply print all information as JSON
-		output := AtomicSwapOutputCreation{
-			Coins:         hastings,
-			Contract:      condition,
-			ContractID:    condition.UnlockHash(),
-			OutputID:      response.Transaction.CoinOutputID(uint64(coinOutputIndex)),
-			TransactionID: response.Transaction.ID(),
-		}
-		if secret != (types.AtomicSwapSecret{}) {
-			output.Secret = &secret
-		}
 		json.NewEncoder(os.Stdout).Encode(output)
 		return
 	}
 
 	// otherwise print it for a human, in a more verbose and friendly way
+	var secret types.AtomicSwapSecret
+	if output.Secret != nil {
+		secret = *output.Secret
+	}
 	fmt.Println("")
 	fmt.Println("published contract transaction")
 	fmt.Println("")
-	fmt.Println("OutputID:", response.Transaction.CoinOutputID(uint64(coinOutputIndex)))
-	fmt.Println("TransactionID:", response.Transaction.ID())
+	fmt.Println("OutputID:", output.OutputID)
+	fmt.Println("TransactionID:", output.TransactionID)
 	fmt.Println("")
 	fmt.Println("Contract Info:")
 	fmt.Println("")
-	atomicSwapCmd.printContractInfo(os.Stdout, hastings, condition, secret, nil)
+	atomicSwapCmd.printContractInfo(os.Stdout, output.Coins, output.Contract, secret, nil)
 }
 
 func (atomicSwapCmd *atomicSwapCmd) auditCmd(cmd *cobra.Command, args []string) {
+	if atomicSwapCmd.rootCfg.CounterChain != "" {
+		atomicSwapCmd.auditCounterChainCmd(cmd, args)
+		return
+	}
 	argn := len(args)
 	if argn < 1 || argn > 2 {
 		cmd.UsageFunc()(cmd)
 		os.Exit(cli.ExitCodeUsage)
 	}
+	if strings.Contains(args[0], ",") {
+		// audit the contracts of a multi-output contract
+		if argn != 1 {
+			cmd.UsageFunc()(cmd)
+			os.Exit(cli.ExitCodeUsage)
+		}
+		atomicSwapCmd.auditAtomicSwapContracts(parseAtomicSwapOutputIDs(args[0]))
+		return
+	}
 
 	var (
 		outputID              types.CoinOutputID
@@ -644,10 +860,10 @@ const (
 func (atomicSwapCmd *atomicSwapCmd) auditAtomicSwapContract(coid types.CoinOutputID, co types.CoinOutput, source auditSource) {
 	currencyConverter := atomicSwapCmd.cli.CreateCurrencyConvertor()
 
-	condition, ok := co.Condition.Condition.(*types.AtomicSwapCondition)
+	condition, multiSigReceiver, ok := gcmodules.AtomicSwapContractCondition(co.Condition)
 	if !ok {
 		cli.Die(fmt.Sprintf(
-			"received unexpected condition of type %T, while type *types.AtomicSwapCondition was expected in order to be able to audit",
+			"received unexpected condition of type %T, while an atomic swap condition was expected in order to be able to audit",
 			co.Condition.Condition))
 	}
 	durationLeft := time.Unix(int64(condition.TimeLock), 0).Sub(computeTimeNow())
@@ -673,13 +889,23 @@ func (atomicSwapCmd *atomicSwapCmd) auditAtomicSwapContract(coid types.CoinOutpu
 	if coinInfoResp.CustodyFee != nil {
 		custodyFee = *coinInfoResp.CustodyFee
 	}
+	creationTime := coinInfoResp.CreationTime
+	if creationTime == 0 {
+		// the contract is not yet confirmed, and will be created at the earliest now
+		creationTime = types.CurrentTimestamp()
+	}
+	guaranteedValue, worstCaseCustodyFee := gcmodules.AtomicSwapContractGuarantees(
+		co.Value, creationTime, condition, atomicSwapCmd.cli.Config.MinimumTransactionFee)
 
 	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
 		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputAudit{
-			Coins:          co.Value,
-			SpendableCoins: spendableValue,
-			CustodyFeeDebt: custodyFee,
-			Contract:       *condition,
+			Coins:               co.Value,
+			SpendableCoins:      spendableValue,
+			CustodyFeeDebt:      custodyFee,
+			GuaranteedCoins:     guaranteedValue,
+			WorstCaseCustodyFee: worstCaseCustodyFee,
+			Contract:            condition,
+			MultiSigReceiver:    multiSigReceiver,
 		})
 	} else {
 		fmt.Printf(`Atomic Swap Contract (condition) found:
@@ -688,15 +914,23 @@ Contract creation value:           %s
 Contract spendable value:          %s
 Contract custody fee to be paid:   %s
 
+Guaranteed value when redeemed before the time lock:   %s
+Worst-case custody fee (at the time lock):             %s
+
 Receiver's address: %s
 Sender's (contract creator) address: %s
 Secret Hash: %s
-TimeLock: %[5]d (%[5]s)
+TimeLock: %[9]d (%[9]s)
 TimeLock reached in: %s
 
 `, currencyConverter.ToCoinStringWithUnit(co.Value),
 			currencyConverter.ToCoinStringWithUnit(spendableValue), currencyConverter.ToCoinStringWithUnit(custodyFee),
+			currencyConverter.ToCoinStringWithUnit(guaranteedValue), currencyConverter.ToCoinStringWithUnit(worstCaseCustodyFee),
 			condition.Receiver, condition.Sender, condition.HashedSecret, condition.TimeLock, durationLeft)
+		if multiSigReceiver != nil {
+			printMultiSigReceiver(os.Stdout, *multiSigReceiver)
+			fmt.Println("")
+		}
 	}
 
 	var invalidContract bool
@@ -768,6 +1002,10 @@ const (
 
 // extractsecret transactionid [outputid]
 func (atomicSwapCmd *atomicSwapCmd) extractSecretCmd(cmd *cobra.Command, args []string) {
+	if atomicSwapCmd.rootCfg.CounterChain != "" {
+		atomicSwapCmd.extractSecretCounterChainCmd(cmd, args)
+		return
+	}
 	argn := len(args)
 	if argn < 1 || argn > 2 {
 		cmd.UsageFunc()(cmd)
@@ -810,7 +1048,7 @@ func (atomicSwapCmd *atomicSwapCmd) extractSecretCmd(cmd *cobra.Command, args []
 			if outputIDGiven && ci.ParentID != outputID {
 				continue
 			}
-			if ft := ci.Fulfillment.FulfillmentType(); ft != types.FulfillmentTypeAtomicSwap {
+			if ft := ci.Fulfillment.FulfillmentType(); !isAtomicSwapFulfillmentType(ft) {
 				if outputIDGiven && ci.ParentID == outputID {
 					cli.Die(fmt.Sprintf(
 						"received unexpected fulfillment type of type %d (%T)", ft, ci.Fulfillment.Fulfillment))
@@ -844,7 +1082,7 @@ func (atomicSwapCmd *atomicSwapCmd) extractSecretCmd(cmd *cobra.Command, args []
 		if outputIDGiven && ci.ParentID != outputID {
 			continue
 		}
-		if ft := ci.Fulfillment.FulfillmentType(); ft != types.FulfillmentTypeAtomicSwap {
+		if ft := ci.Fulfillment.FulfillmentType(); !isAtomicSwapFulfillmentType(ft) {
 			if outputIDGiven && ci.ParentID == outputID {
 				cli.Die(fmt.Sprintf(
 					"received unexpected fulfillment type of type %d (%T)", ft, ci.Fulfillment.Fulfillment))
@@ -891,19 +1129,30 @@ type atomicSwapSecretGetter interface {
 	AtomicSwapSecret() types.AtomicSwapSecret
 }
 
+// isAtomicSwapFulfillmentType returns true for the fulfillment types
+// of the (legacy) and multisig atomic swap contracts.
+func isAtomicSwapFulfillmentType(ft types.FulfillmentType) bool {
+	return ft == types.FulfillmentTypeAtomicSwap || ft == gctypes.FulfillmentTypeMultiSignatureAtomicSwap
+}
+
 // redeem outputid secret
-func (atomicSwapCmd *atomicSwapCmd) redeemCmd(outputIDStr, secretStr string) {
+func (atomicSwapCmd *atomicSwapCmd) redeemCmd(cmd *cobra.Command, args []string) {
+	if atomicSwapCmd.rootCfg.CounterChain != "" {
+		atomicSwapCmd.spendCounterChainCmd(cmd, args, true)
+		return
+	}
+	if len(args) != 2 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(cli.ExitCodeUsage)
+	}
+	outputIDStr, secretStr := args[0], args[1]
 	var (
-		err      error
-		outputID types.CoinOutputID
-		secret   types.AtomicSwapSecret
+		err    error
+		secret types.AtomicSwapSecret
 	)
 
 	// parse pos args
-	err = outputID.LoadString(outputIDStr)
-	if err != nil {
-		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse outputid-argument:", err)
-	}
+	outputIDs := parseAtomicSwapOutputIDs(outputIDStr)
 	err = secret.LoadString(secretStr)
 	if err != nil {
 		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse secret-argument:", err)
@@ -912,23 +1161,35 @@ func (atomicSwapCmd *atomicSwapCmd) redeemCmd(outputIDStr, secretStr string) {
 		cli.DieWithExitCode(cli.ExitCodeUsage, "secret cannot be all-nil when redeeming an atomic swap contract")
 	}
 
-	atomicSwapCmd.spendAtomicSwapContract(outputID, secret)
+	if atomicSwapCmd.redeemCfg.MultiSig {
+		atomicSwapCmd.redeemMultiSigAtomicSwapContracts(outputIDs, secret)
+		return
+	}
+	if len(outputIDs) > 1 {
+		atomicSwapCmd.spendAtomicSwapContracts(outputIDs, secret)
+		return
+	}
+	atomicSwapCmd.spendAtomicSwapContract(outputIDs[0], secret)
 }
 
 // refund outputid
-func (atomicSwapCmd *atomicSwapCmd) refundCmd(outputIDStr string) {
-	var (
-		err      error
-		outputID types.CoinOutputID
-	)
-
-	// parse pos arg
-	err = outputID.LoadString(outputIDStr)
-	if err != nil {
-		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse outputid-argument:", err)
+func (atomicSwapCmd *atomicSwapCmd) refundCmd(cmd *cobra.Command, args []string) {
+	if atomicSwapCmd.rootCfg.CounterChain != "" {
+		atomicSwapCmd.spendCounterChainCmd(cmd, args, false)
+		return
+	}
+	if len(args) != 1 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(cli.ExitCodeUsage)
 	}
+	// parse pos arg
+	outputIDs := parseAtomicSwapOutputIDs(args[0])
 
-	atomicSwapCmd.spendAtomicSwapContract(outputID, types.AtomicSwapSecret{})
+	if len(outputIDs) > 1 {
+		atomicSwapCmd.spendAtomicSwapContracts(outputIDs, types.AtomicSwapSecret{})
+		return
+	}
+	atomicSwapCmd.spendAtomicSwapContract(outputIDs[0], types.AtomicSwapSecret{})
 }
 
 func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinOutputID, secret types.AtomicSwapSecret) {
@@ -955,8 +1216,13 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 		}
 	}
 
-	// step 2: get correct spendable key from wallet
-	if ct := unspentCoinOutputResp.Output.Condition.ConditionType(); ct != types.ConditionTypeAtomicSwap {
+	switch ct := unspentCoinOutputResp.Output.Condition.ConditionType(); ct {
+	case types.ConditionTypeAtomicSwap:
+	case gctypes.ConditionTypeMultiSignatureAtomicSwap:
+		// the contract of a multisig receiver is spent using the contract description of the wallet
+		atomicSwapCmd.spendAtomicSwapContracts([]types.CoinOutputID{outputID}, secret)
+		return
+	default:
 		cli.Die("only atomic swap conditions are supported, while referenced output is of type: ", ct)
 	}
 	condition, ok := unspentCoinOutputResp.Output.Condition.Condition.(*types.AtomicSwapCondition)
@@ -965,21 +1231,6 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 			"received unexpected condition of type %T, while type *types.AtomicSwapCondition was expected",
 			unspentCoinOutputResp.Output.Condition.Condition))
 	}
-	var ourUH types.UnlockHash
-	if isSender {
-		ourUH = condition.Sender
-	} else {
-		ourUH = condition.Receiver
-	}
-	pk, sk := atomicSwapCmd.getSpendableKey(ourUH)
-	// quickly validate if returned sk matches the known unlock hash (sanity check)
-	uh, err := types.NewPubKeyUnlockHash(pk)
-	if err != nil {
-		cli.Die("error while creating new public-key-based unlockhash", err)
-	}
-	if uh.Cmp(ourUH) != 0 {
-		cli.Die("unexpected wallet public key returned:", sk)
-	}
 
 	if unspentCoinOutputResp.Output.Value.Cmp(atomicSwapCmd.cli.Config.MinimumTransactionFee) != 1 {
 		cli.Die("failed to " + keyWord + " atomic swap contract, as it locks a value less than or equal to the minimum transaction fee of 1")
@@ -996,18 +1247,6 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 			"unspected coin info creation value found for contract: %s != %s",
 			unspentCoinOutputResp.Output.Value.String(), coinInfoResp.CreationValue.String()))
 	}
-	var (
-		spendableValue  types.Currency
-		custodyFeeValue types.Currency
-	)
-	if coinInfoResp.SpendableValue != nil {
-		spendableValue = *coinInfoResp.SpendableValue
-	} else {
-		spendableValue = coinInfoResp.CreationValue
-	}
-	if coinInfoResp.CustodyFee != nil {
-		custodyFeeValue = *coinInfoResp.CustodyFee
-	}
 
 	// step 3: confirm contract details with user, before continuing
 	// print contract for review
@@ -1018,48 +1257,28 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 			cli.DieWithExitCode(cli.ExitCodeCancelled, "atomic swap "+keyWord+" transaction cancelled")
 		}
 	}
-	// step 4: create a transaction
-	txn := types.Transaction{
-		Version: atomicSwapCmd.cli.Config.DefaultTransactionVersion,
-		CoinInputs: []types.CoinInput{
-			{
-				ParentID: outputID,
-				Fulfillment: types.NewFulfillment(&types.AtomicSwapFulfillment{
-					PublicKey: pk,
-					Secret:    secret,
-				}),
-			},
-		},
-		CoinOutputs: []types.CoinOutput{
-			{
-				Condition: types.NewCondition(types.NewUnlockHashCondition(uh)),
-				Value:     spendableValue.Sub(atomicSwapCmd.cli.Config.MinimumTransactionFee),
-			},
-			{
-				Condition: types.NewCondition(&cftypes.CustodyFeeCondition{
-					ComputationTime: coinInfoResp.FeeComputationTime,
-				}),
-				Value: custodyFeeValue,
-			},
-		},
-		MinerFees: []types.Currency{atomicSwapCmd.cli.Config.MinimumTransactionFee},
+	// step 4: have the wallet create, sign and publish the transaction,
+	// such that the key used to sign it never leaves the wallet
+	var (
+		body []byte
+		path string
+	)
+	if isSender {
+		path = "/wallet/atomicswap/refund"
+		body, err = json.Marshal(gcapi.WalletAtomicSwapRefundPOST{OutputID: outputID})
+	} else {
+		path = "/wallet/atomicswap/redeem"
+		body, err = json.Marshal(gcapi.WalletAtomicSwapRedeemPOST{OutputID: outputID, Secret: secret})
 	}
-
-	// step 5: sign transaction's only input
-	err = txn.CoinInputs[0].Fulfillment.Sign(types.FulfillmentSignContext{
-		ExtraObjects: []interface{}{uint64(0)},
-		Transaction:  txn,
-		Key:          sk,
-	})
 	if err != nil {
-		cli.Die("failed to "+keyWord+" atomic swap's locked coins, couldn't sign transaction:", err)
+		cli.Die("failed to create/marshal JSON body:", err)
 	}
-
-	// step 6: submit transaction to transaction pool and celebrate if possible
-	txnid, err := atomicSwapCmd.commitTxn(txn)
+	var resp gcapi.WalletAtomicSwapSpendPOSTResp
+	err = atomicSwapCmd.cli.PostWithResponse(path, string(body), &resp)
 	if err != nil {
-		cli.Die("failed to "+keyWord+" atomic swaps locked tokens, as transaction couldn't commit:", err)
+		cli.DieWithError("failed to "+keyWord+" atomic swap's locked coins:", err)
 	}
+	txnid := resp.TransactionID
 
 	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
 		// if encoding type is JSON, simply print all information as JSON
@@ -1080,49 +1299,48 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 > your payment went through. If not, try to audit the contract (again).`)
 }
 
-// get public- and private key from wallet module
-func (atomicSwapCmd *atomicSwapCmd) getSpendableKey(unlockHash types.UnlockHash) (types.PublicKey, types.ByteSlice) {
-	resp := new(api.WalletKeyGet)
-	err := atomicSwapCmd.cli.GetWithResponse("/wallet/key/"+unlockHash.String(), resp)
-	if err != nil {
-		cli.DieWithError("failed to get a matching wallet public/secret key pair for the given unlock hash:", err)
-	}
-	if isNilByteSlice(resp.PublicKey) {
-		cli.Die("failed to get a wallet public key pair for the given unlock hash")
-	}
-	if isNilByteSlice(resp.SecretKey) {
-		cli.Die("received matching public key, but no secret key was returned")
-	}
-	pk := types.PublicKey{
-		Key: resp.PublicKey,
-	}
-	err = pk.Algorithm.LoadSpecifier(resp.AlgorithmSpecifier)
+// list
+func (atomicSwapCmd *atomicSwapCmd) listCmd() {
+	var resp gcapi.WalletAtomicSwapContractsGET
+	err := atomicSwapCmd.cli.GetWithResponse("/wallet/atomicswap/contracts", &resp)
 	if err != nil {
-		cli.DieWithError("invalid public key algorithm specifier loaded:", err)
+		cli.DieWithError("failed to get the atomic swap contracts tracked by the wallet", err)
 	}
-	return pk, resp.SecretKey
-}
 
-func isNilByteSlice(bs types.ByteSlice) bool {
-	for _, b := range bs {
-		if b != 0 {
-			return false
-		}
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(resp)
+		return
 	}
-	return true
-}
 
-// commitTxn sends a transaction to the used node's transaction pool
-func (atomicSwapCmd *atomicSwapCmd) commitTxn(txn types.Transaction) (types.TransactionID, error) {
-	bodyBuff := bytes.NewBuffer(nil)
-	err := json.NewEncoder(bodyBuff).Encode(&txn)
-	if err != nil {
-		return types.TransactionID{}, err
+	if len(resp.Contracts) == 0 {
+		fmt.Println("This wallet does not track any atomic swap contracts.")
+		return
 	}
-
-	resp := new(api.TransactionPoolPOST)
-	err = atomicSwapCmd.cli.PostWithResponse("/transactionpool/transactions", bodyBuff.String(), resp)
-	return resp.TransactionID, err
+	currencyConvertor := atomicSwapCmd.cli.CreateCurrencyConvertor()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "outputid\trole\tvalue\ttimelock\tstate\tsecret")
+	for _, contract := range resp.Contracts {
+		var roles []string
+		if contract.IsSender {
+			roles = append(roles, "sender")
+		}
+		if contract.IsReceiver {
+			roles = append(roles, "receiver")
+		}
+		state := string(contract.State)
+		if contract.Refundable {
+			state += " (refundable)"
+		}
+		secret := "-"
+		if contract.Secret != nil {
+			secret = contract.Secret.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", contract.OutputID.String(), strings.Join(roles, ","),
+			currencyConvertor.ToCoinStringWithUnit(contract.Value),
+			time.Unix(int64(contract.Contract.TimeLock), 0).Format(time.RFC822), state, secret)
+	}
+	w.Flush()
 }
 
 func (atomicSwapCmd *atomicSwapCmd) printContractInfo(w io.Writer, hastings types.Currency, condition types.AtomicSwapCondition, secret types.AtomicSwapSecret, cfInfo *cfapi.CoinOutputInfoGet) {
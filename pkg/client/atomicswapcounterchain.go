@@ -0,0 +1,303 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/types"
+
+	gcatomicswap "github.com/nbh-digital/goldchain/pkg/atomicswap"
+)
+
+type (
+	// AtomicSwapOutputCounterChainContract represents the formatted output
+	// of the atomic swap participate command, when creating the contract on a counter chain.
+	AtomicSwapOutputCounterChainContract struct {
+		Chain    string                `json:"chain"`
+		Contract gcatomicswap.Contract `json:"contract"`
+	}
+	// AtomicSwapOutputCounterChainSpendContract represents the formatted output
+	// of the atomic swap spend commands (redeem and refund), when spending a contract on a counter chain.
+	AtomicSwapOutputCounterChainSpendContract struct {
+		Chain         string `json:"chain"`
+		TransactionID string `json:"transactionid"`
+	}
+	// AtomicSwapOutputCrossChainInitiation represents the formatted output
+	// of the atomic swap initiate command, when orchestrating both legs of a cross-chain atomic swap.
+	AtomicSwapOutputCrossChainInitiation struct {
+		Initiation                 AtomicSwapOutputCreation     `json:"initiation"`
+		CounterChain               string                       `json:"counterchain"`
+		CounterContract            gcatomicswap.AuditedContract `json:"countercontract"`
+		CounterRedeemTransactionID string                       `json:"counterredeemtransactionid"`
+	}
+)
+
+// counterChain creates the adapter of the counter chain given using the --counterchain flag.
+func (atomicSwapCmd *atomicSwapCmd) counterChain() gcatomicswap.Chain {
+	chain, err := gcatomicswap.NewChain(atomicSwapCmd.rootCfg.CounterChain, atomicSwapCmd.rootCfg.CounterChainCfg)
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "invalid counter chain:", err)
+	}
+	return chain
+}
+
+// initiateCrossChainSwap orchestrates both legs of a cross-chain atomic swap as initiator:
+// the goldchain contract is created, after which the contract created by the participant
+// on the counter chain is audited and redeemed, revealing the secret to the participant.
+func (atomicSwapCmd *atomicSwapCmd) initiateCrossChainSwap(hastings types.Currency, sender, receiver types.UnlockHash) {
+	if atomicSwapCmd.initiateCfg.CounterAmount == "" {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "the --counter-amount flag is required when a counter chain is given")
+	}
+	chain := atomicSwapCmd.counterChain()
+
+	// create the goldchain leg of the swap
+	initiation := atomicSwapCmd.publishAtomicSwapContract(hastings, sender, receiver,
+		types.AtomicSwapHashedSecret{}, atomicSwapCmd.initiateCfg.Duration, atomicSwapCmd.initiateCfg.EscrowCustodyFee)
+	fmt.Fprintln(os.Stderr, "published goldchain contract", initiation.OutputID.String())
+	fmt.Fprintln(os.Stderr, "keep the secret private until redeeming the counter contract:", initiation.Secret.String())
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "share the following with the participant:")
+	fmt.Fprintln(os.Stderr, "  outputid:   ", initiation.OutputID.String())
+	fmt.Fprintln(os.Stderr, "  secret hash:", initiation.Contract.HashedSecret.String())
+	fmt.Fprintln(os.Stderr, "")
+
+	// audit the counter chain leg of the swap
+	var contract gcatomicswap.Contract
+	fmt.Fprintf(os.Stderr, "%s contract of the participant: ", chain.Name())
+	if _, err := fmt.Scanln(&contract.Script); err != nil {
+		cli.Die("failed to read the contract of the participant:", err)
+	}
+	fmt.Fprintf(os.Stderr, "%s contract transaction of the participant: ", chain.Name())
+	if _, err := fmt.Scanln(&contract.Transaction); err != nil {
+		cli.Die("failed to read the contract transaction of the participant:", err)
+	}
+	audited, err := chain.Audit(contract)
+	if err != nil {
+		cli.Die("failed to audit the contract of the participant:", err)
+	}
+	err = audited.Validate(gcatomicswap.ContractExpectations{
+		HashedSecret:        initiation.Contract.HashedSecret,
+		MinimumValue:        atomicSwapCmd.initiateCfg.CounterAmount,
+		Recipient:           atomicSwapCmd.initiateCfg.CounterAddress,
+		LockTimeBefore:      time.Unix(int64(initiation.Contract.TimeLock), 0),
+		MinimumDurationLeft: atomicSwapCmd.initiateCfg.CounterMinDuration,
+	}, time.Now())
+	if err != nil {
+		cli.DieWithExitCode(AuditContractExitCodeInvalidContract,
+			"contract of the participant is invalid, the goldchain contract will be refunded once its time lock has been reached:", err)
+	}
+
+	// redeem the counter chain leg of the swap, revealing the secret
+	if !atomicSwapCmd.rootCfg.YesToAll {
+		printCounterChainContractInfo(os.Stderr, chain.Name(), audited)
+		if !askYesNoQuestion(fmt.Sprintf("Redeem %s contract?", chain.Name())) {
+			cli.DieWithExitCode(cli.ExitCodeCancelled, "cancelled redeeming the contract of the participant")
+		}
+	}
+	txnID, err := chain.Redeem(contract, *initiation.Secret)
+	if err != nil {
+		cli.Die("failed to redeem the contract of the participant:", err)
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputCrossChainInitiation{
+			Initiation:                 initiation,
+			CounterChain:               chain.Name(),
+			CounterContract:            audited,
+			CounterRedeemTransactionID: txnID,
+		})
+		return
+	}
+
+	// otherwise print it for a human, in a more verbose and friendly way
+	atomicSwapCmd.printAtomicSwapContractCreation(initiation)
+	fmt.Println("")
+	fmt.Printf("redeemed %s contract of the participant\n", chain.Name())
+	fmt.Println("")
+	printCounterChainContractInfo(os.Stdout, chain.Name(), audited)
+	fmt.Println("")
+	fmt.Println("Redeem TransactionID:", txnID)
+}
+
+// participateCounterChainCmd creates an atomic swap contract as participant on the counter chain.
+func (atomicSwapCmd *atomicSwapCmd) participateCounterChainCmd(initiatorAddress, amount, hashedSecret string) {
+	chain := atomicSwapCmd.counterChain()
+	var hash types.AtomicSwapHashedSecret
+	err := hash.LoadString(hashedSecret)
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "invalid secret hash:", err)
+	}
+	if !atomicSwapCmd.rootCfg.YesToAll {
+		if !askYesNoQuestion(fmt.Sprintf("Publish %s contract locking %s coins for %s?", chain.Name(), amount, initiatorAddress)) {
+			cli.DieWithExitCode(cli.ExitCodeCancelled, "cancelled atomic swap contract")
+		}
+	}
+	contract, err := chain.Participate(initiatorAddress, amount, hash)
+	if err != nil {
+		cli.Die("failed to create the contract:", err)
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputCounterChainContract{
+			Chain:    chain.Name(),
+			Contract: contract,
+		})
+		return
+	}
+
+	// otherwise print it for a human, in a more verbose and friendly way
+	fmt.Println("")
+	fmt.Printf("published %s contract transaction\n", chain.Name())
+	fmt.Println("")
+	fmt.Println("Contract Address:", contract.Address)
+	fmt.Println("Contract:", contract.Script)
+	fmt.Println("TransactionID:", contract.TransactionID)
+	fmt.Println("Transaction:", contract.Transaction)
+}
+
+// auditcontract contract contracttransaction
+func (atomicSwapCmd *atomicSwapCmd) auditCounterChainCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(cli.ExitCodeUsage)
+	}
+	chain := atomicSwapCmd.counterChain()
+	audited, err := chain.Audit(gcatomicswap.Contract{Script: args[0], Transaction: args[1]})
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeNotFound, "failed to audit the contract:", err)
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(audited)
+	} else {
+		// otherwise print it for a human, in a more verbose and friendly way
+		printCounterChainContractInfo(os.Stdout, chain.Name(), audited)
+	}
+
+	// the hashed secret is only validated when given
+	expectations := gcatomicswap.ContractExpectations{
+		HashedSecret:        audited.HashedSecret,
+		MinimumValue:        atomicSwapCmd.auditCfg.CoinAmountString,
+		Recipient:           atomicSwapCmd.auditCfg.CounterReceiverAddress,
+		MinimumDurationLeft: atomicSwapCmd.auditCfg.MinDurationLeft,
+	}
+	if atomicSwapCmd.auditCfg.HashedSecret != (types.AtomicSwapHashedSecret{}) {
+		expectations.HashedSecret = atomicSwapCmd.auditCfg.HashedSecret
+	}
+	err = audited.Validate(expectations, time.Now())
+	if err != nil {
+		cli.DieWithExitCode(AuditContractExitCodeInvalidContract, "contract is invalid:", err)
+	}
+}
+
+// extractsecret redemptiontransaction
+func (atomicSwapCmd *atomicSwapCmd) extractSecretCounterChainCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(cli.ExitCodeUsage)
+	}
+	if atomicSwapCmd.extractSecretCfg.HashedSecret == (types.AtomicSwapHashedSecret{}) {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "the --secrethash flag is required when a counter chain is given")
+	}
+	chain := atomicSwapCmd.counterChain()
+	secret, err := chain.ExtractSecret(args[0], atomicSwapCmd.extractSecretCfg.HashedSecret)
+	if err != nil {
+		if err == gcatomicswap.ErrSecretMismatch {
+			cli.DieWithExitCode(AuditContractExitCodeInvalidContract, "failed to extract the secret:", err)
+		}
+		cli.DieWithExitCode(cli.ExitCodeNotFound, "failed to extract the secret:", err)
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputExtractSecret{
+			Secret: secret,
+		})
+		return
+	}
+
+	// otherwise print it for a human, in a more verbose and friendly way
+	fmt.Printf("%s atomic swap contract was redeemed\n", chain.Name())
+	fmt.Println("extracted secret:", secret.String())
+}
+
+// redeem contract contracttransaction secret
+// refund contract contracttransaction
+func (atomicSwapCmd *atomicSwapCmd) spendCounterChainCmd(cmd *cobra.Command, args []string, redeem bool) {
+	argn := 2
+	if redeem {
+		argn = 3
+	}
+	if len(args) != argn {
+		cmd.UsageFunc()(cmd)
+		os.Exit(cli.ExitCodeUsage)
+	}
+	chain := atomicSwapCmd.counterChain()
+	contract := gcatomicswap.Contract{Script: args[0], Transaction: args[1]}
+	var secret types.AtomicSwapSecret
+	keyWord := "refund"
+	if redeem {
+		keyWord = "redeem"
+		err := secret.LoadString(args[2])
+		if err != nil {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse secret-argument:", err)
+		}
+	}
+	if !atomicSwapCmd.rootCfg.YesToAll {
+		audited, err := chain.Audit(contract)
+		if err != nil {
+			cli.DieWithExitCode(cli.ExitCodeNotFound, "failed to audit the contract:", err)
+		}
+		printCounterChainContractInfo(os.Stdout, chain.Name(), audited)
+		if !askYesNoQuestion(fmt.Sprintf("Publish %s %s transaction?", chain.Name(), keyWord)) {
+			cli.DieWithExitCode(cli.ExitCodeCancelled, "atomic swap "+keyWord+" transaction cancelled")
+		}
+	}
+
+	var (
+		txnID string
+		err   error
+	)
+	if redeem {
+		txnID, err = chain.Redeem(contract, secret)
+	} else {
+		txnID, err = chain.Refund(contract)
+	}
+	if err != nil {
+		cli.Die("failed to "+keyWord+" the contract:", err)
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputCounterChainSpendContract{
+			Chain:         chain.Name(),
+			TransactionID: txnID,
+		})
+		return
+	}
+
+	// otherwise print it for a human, in a more verbose and friendly way
+	fmt.Println("")
+	fmt.Printf("published %s %s transaction\n", chain.Name(), keyWord)
+	fmt.Println("transaction ID:", txnID)
+}
+
+// printCounterChainContractInfo prints an audited counter chain contract for a human.
+func printCounterChainContractInfo(w io.Writer, chain string, contract gcatomicswap.AuditedContract) {
+	fmt.Fprintf(w, "%s Contract Address: %s\n", chain, contract.Address)
+	fmt.Fprintf(w, "Contract Value: %s %s\n", contract.Value, strings.ToUpper(chain))
+	fmt.Fprintln(w, "Recipient Address:", contract.Recipient)
+	fmt.Fprintln(w, "Refund Address:", contract.RefundAddress)
+	fmt.Fprintln(w, "Secret Hash:", contract.HashedSecret.String())
+	fmt.Fprintf(w, "Locktime: %s (in %s)\n",
+		contract.LockTime.UTC().Format(time.RFC3339), time.Until(contract.LockTime).Round(time.Second))
+}
@@ -0,0 +1,417 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+)
+
+// parseAtomicSwapReceivers parses the additional receivers of a multi-output atomic swap contract,
+// each formatted as <address>:<amount>, or as <address>,<address>[,...]/<minimumsignatures>:<amount>
+// for a multisig receiver, defined by the addresses of its co-signers.
+func (atomicSwapCmd *atomicSwapCmd) parseAtomicSwapReceivers(strs []string) []gcmodules.AtomicSwapReceiver {
+	currencyConvertor := atomicSwapCmd.cli.CreateCurrencyConvertor()
+	receivers := make([]gcmodules.AtomicSwapReceiver, 0, len(strs))
+	for _, str := range strs {
+		idx := strings.LastIndex(str, ":")
+		if idx == -1 {
+			cli.DieWithExitCode(cli.ExitCodeUsage, fmt.Sprintf(
+				"invalid receiver %q: expected <address>:<amount> or <address>,<address>[,...]/<minimumsignatures>:<amount>", str))
+		}
+		receiver := parseAtomicSwapReceiver(str[:idx])
+		receiver.Amount = parseCoinArg(currencyConvertor, str[idx+1:])
+		receivers = append(receivers, receiver)
+	}
+	return receivers
+}
+
+// parseAtomicSwapReceiver parses the address of a receiver of an atomic swap contract,
+// or the multisig condition of a multisig receiver, formatted as <address>,<address>[,...]/<minimumsignatures>.
+func parseAtomicSwapReceiver(str string) gcmodules.AtomicSwapReceiver {
+	var receiver gcmodules.AtomicSwapReceiver
+	if strings.Contains(str, "/") {
+		condition := parseMultiSigReceiver(str)
+		receiver.MultiSigCondition = &condition
+		receiver.Address = condition.UnlockHash()
+		return receiver
+	}
+	err := receiver.Address.LoadString(str)
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse receiver address (unlock hash):", err)
+	}
+	return receiver
+}
+
+// parseMultiSigReceiver parses the multisig condition of a multisig receiver,
+// formatted as <address>,<address>[,...]/<minimumsignatures>.
+func parseMultiSigReceiver(str string) types.MultiSignatureCondition {
+	idx := strings.LastIndex(str, "/")
+	minimumSignatureCount, err := strconv.ParseUint(str[idx+1:], 10, 64)
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse minimum signature count of multisig receiver:", err)
+	}
+	var condition types.MultiSignatureCondition
+	for _, addressStr := range strings.Split(str[:idx], ",") {
+		var uh types.UnlockHash
+		err = uh.LoadString(strings.TrimSpace(addressStr))
+		if err != nil {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse co-signer address (unlock hash) of multisig receiver:", err)
+		}
+		condition.UnlockHashes = append(condition.UnlockHashes, uh)
+	}
+	condition.MinimumSignatureCount = minimumSignatureCount
+	err = condition.IsStandardCondition(types.ValidationContext{})
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "invalid multisig receiver:", err)
+	}
+	return condition
+}
+
+// printMultiSigReceiver prints the co-signers of a multisig receiver of an atomic swap contract.
+func printMultiSigReceiver(w io.Writer, condition types.MultiSignatureCondition) {
+	fmt.Fprintf(w, "Receiver is a multisig address, redeemed using %d signatures of the co-signers:\n", condition.MinimumSignatureCount)
+	for _, uh := range condition.UnlockHashes {
+		fmt.Fprintln(w, "  "+uh.String())
+	}
+}
+
+// parseAtomicSwapOutputIDs parses one or multiple comma-separated output IDs of atomic swap contracts.
+func parseAtomicSwapOutputIDs(str string) []types.CoinOutputID {
+	strs := strings.Split(str, ",")
+	outputIDs := make([]types.CoinOutputID, 0, len(strs))
+	for _, str := range strs {
+		var outputID types.CoinOutputID
+		err := outputID.LoadString(strings.TrimSpace(str))
+		if err != nil {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "failed to parse outputid-argument:", err)
+		}
+		outputIDs = append(outputIDs, outputID)
+	}
+	return outputIDs
+}
+
+// createAtomicSwapContracts creates a multi-output atomic swap contract, locking a contract for each receiver,
+// all sharing the given sender, hashed secret and duration, and publishes it, once confirmed by the user.
+// The secret is only given for an initiated contract.
+func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContracts(receivers []gcmodules.AtomicSwapReceiver, sender types.UnlockHash, hash types.AtomicSwapHashedSecret, secret types.AtomicSwapSecret, duration time.Duration, escrowCustodyFee bool) {
+	if duration == 0 {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "duration is required and has to be greater than 0")
+	}
+	if !atomicSwapCmd.rootCfg.YesToAll {
+		// print contracts for review
+		timeLock := types.OffsetTimestamp(duration)
+		for _, receiver := range receivers {
+			value := receiver.Amount
+			if escrowCustodyFee {
+				value = gcmodules.AtomicSwapEscrowValue(value, types.CurrentTimestamp(), timeLock, atomicSwapCmd.cli.Config.MinimumTransactionFee)
+			}
+			atomicSwapCmd.printContractInfo(os.Stderr, value, types.AtomicSwapCondition{
+				Sender:       sender,
+				Receiver:     receiver.Address,
+				HashedSecret: hash,
+				TimeLock:     timeLock,
+			}, secret, nil)
+			if receiver.MultiSigCondition != nil {
+				printMultiSigReceiver(os.Stderr, *receiver.MultiSigCondition)
+			}
+			fmt.Fprintln(os.Stderr, "")
+		}
+		// ensure user wants to continue with creating the contracts as they are (aka publishing them)
+		if !askYesNoQuestion(fmt.Sprintf("Publish atomic swap transaction, locking %d contracts?", len(receivers))) {
+			cli.DieWithExitCode(cli.ExitCodeCancelled, "cancelled atomic swap contract")
+		}
+	}
+	// publish contracts, using the hashed secret generated by this client or given by the initiator
+	body, err := json.Marshal(gcapi.WalletAtomicSwapParticipatePOST{
+		Receivers:        receivers,
+		HashedSecret:     hash,
+		Duration:         duration.String(),
+		Sender:           &sender,
+		EscrowCustodyFee: escrowCustodyFee,
+	})
+	if err != nil {
+		cli.Die("failed to create/marshal JSON body:", err)
+	}
+	var resp gcapi.WalletAtomicSwapContractPOSTResp
+	err = atomicSwapCmd.cli.PostWithResponse("/wallet/atomicswap/participate", string(body), &resp)
+	if err != nil {
+		cli.DieWithError("failed to create atomic swap contracts:", err)
+	}
+
+	outputs := make([]AtomicSwapOutputCreation, 0, len(resp.Contracts))
+	for _, contract := range resp.Contracts {
+		output := AtomicSwapOutputCreation{
+			Coins:            contract.Value,
+			Contract:         contract.Contract,
+			MultiSigReceiver: contract.MultiSigReceiver,
+			ContractID:       contract.ContractAddress,
+			OutputID:         contract.OutputID,
+			TransactionID:    resp.TransactionID,
+		}
+		if secret != (types.AtomicSwapSecret{}) {
+			output.Secret = &secret
+		}
+		outputs = append(outputs, output)
+	}
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(outputs)
+		return
+	}
+
+	// otherwise print it for a human, in a more verbose and friendly way
+	fmt.Println("")
+	fmt.Println("published contract transaction")
+	fmt.Println("")
+	fmt.Println("TransactionID:", resp.TransactionID)
+	for _, output := range outputs {
+		fmt.Println("")
+		fmt.Println("OutputID:", output.OutputID)
+		fmt.Println("Contract Info:")
+		fmt.Println("")
+		atomicSwapCmd.printContractInfo(os.Stdout, output.Coins, output.Contract, secret, nil)
+		if output.MultiSigReceiver != nil {
+			printMultiSigReceiver(os.Stdout, *output.MultiSigReceiver)
+		}
+	}
+}
+
+// auditAtomicSwapContracts audits the contracts of a multi-output atomic swap contract,
+// which have to share the same sender, hashed secret and time lock.
+// The coin amount given as flag is validated against the total value of the contracts,
+// while the receiver's address given as flag has to be the receiver of at least one contract.
+func (atomicSwapCmd *atomicSwapCmd) auditAtomicSwapContracts(outputIDs []types.CoinOutputID) {
+	currencyConvertor := atomicSwapCmd.cli.CreateCurrencyConvertor()
+
+	query := url.Values{}
+	for _, outputID := range outputIDs {
+		query.Add("outputid", outputID.String())
+	}
+	var resp gcapi.WalletAtomicSwapAuditGET
+	err := atomicSwapCmd.cli.GetWithResponse("/wallet/atomicswap/audit?"+query.Encode(), &resp)
+	if err != nil {
+		if err == api.ErrStatusNotFound {
+			cli.DieWithExitCode(cli.ExitCodeNotFound, "not all atomic swap contracts could be found as unspent coin outputs:", err)
+		}
+		cli.DieWithError("failed to audit atomic swap contracts:", err)
+	}
+
+	var (
+		totalValue      types.Currency
+		confirmed       = true
+		invalidContract bool
+		first           = resp.Contracts[0].Contract
+		durationLeft    = time.Unix(int64(first.TimeLock), 0).Sub(computeTimeNow())
+	)
+	outputs := make([]AtomicSwapOutputAudit, 0, len(resp.Contracts))
+	for _, contract := range resp.Contracts {
+		totalValue = totalValue.Add(contract.Value)
+		confirmed = confirmed && contract.Confirmed
+		outputs = append(outputs, AtomicSwapOutputAudit{
+			Coins:               contract.Value,
+			SpendableCoins:      contract.SpendableValue,
+			CustodyFeeDebt:      contract.CustodyFee,
+			GuaranteedCoins:     contract.GuaranteedValue,
+			WorstCaseCustodyFee: contract.WorstCaseCustodyFee,
+			Contract:            contract.Contract,
+			MultiSigReceiver:    contract.MultiSigReceiver,
+		})
+		if contract.Contract.Sender != first.Sender || contract.Contract.HashedSecret != first.HashedSecret ||
+			contract.Contract.TimeLock != first.TimeLock {
+			invalidContract = true
+			fmt.Fprintln(os.Stderr, "contract "+contract.OutputID.String()+
+				" does not share the sender, secret hash and time lock of contract "+resp.Contracts[0].OutputID.String())
+		}
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		json.NewEncoder(os.Stdout).Encode(outputs)
+	} else {
+		fmt.Printf("%d Atomic Swap Contracts found, locking a total value of %s:\n\n",
+			len(resp.Contracts), currencyConvertor.ToCoinStringWithUnit(totalValue))
+		for _, contract := range resp.Contracts {
+			fmt.Printf(`OutputID: %s
+Contract creation value:   %s
+Contract spendable value:  %s
+Guaranteed value when redeemed before the time lock:  %s
+Receiver's address: %s
+`, contract.OutputID, currencyConvertor.ToCoinStringWithUnit(contract.Value),
+				currencyConvertor.ToCoinStringWithUnit(contract.SpendableValue),
+				currencyConvertor.ToCoinStringWithUnit(contract.GuaranteedValue), contract.Contract.Receiver)
+			if contract.MultiSigReceiver != nil {
+				printMultiSigReceiver(os.Stdout, *contract.MultiSigReceiver)
+			}
+			fmt.Println("")
+		}
+		fmt.Printf(`Sender's (contract creator) address: %s
+Secret Hash: %s
+TimeLock: %[3]d (%[3]s)
+TimeLock reached in: %s
+
+`, first.Sender, first.HashedSecret, first.TimeLock, durationLeft)
+	}
+
+	if atomicSwapCmd.auditCfg.CoinAmountString != "" && atomicSwapCmd.auditCfg.CoinAmountString != "0" {
+		amount, err := currencyConvertor.ParseCoinString(atomicSwapCmd.auditCfg.CoinAmountString)
+		if err != nil {
+			cli.DieWithError("failed to parse amount string: ", err)
+		}
+		// optionally validate the total coin amount
+		if !amount.Equals(totalValue) {
+			invalidContract = true
+			fmt.Fprintln(os.Stderr, "total value of the contracts "+
+				currencyConvertor.ToCoinStringWithUnit(totalValue)+
+				" does not match the expected value "+
+				currencyConvertor.ToCoinStringWithUnit(amount))
+		}
+	}
+	if atomicSwapCmd.auditCfg.HashedSecret != (types.AtomicSwapHashedSecret{}) {
+		// optionally validate hashed secret
+		if atomicSwapCmd.auditCfg.HashedSecret != first.HashedSecret {
+			invalidContract = true
+			fmt.Fprintln(os.Stderr, "found contracts' secret hash "+
+				first.HashedSecret.String()+
+				" does not match the expected secret hash "+
+				atomicSwapCmd.auditCfg.HashedSecret.String())
+		}
+	}
+	if atomicSwapCmd.auditCfg.ReceiverAddress != (types.UnlockHash{}) {
+		// optionally validate that the receiver's address (unlockhash) can redeem one of the contracts
+		found := false
+		for _, contract := range resp.Contracts {
+			if atomicSwapCmd.auditCfg.ReceiverAddress.Cmp(contract.Contract.Receiver) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			invalidContract = true
+			fmt.Fprintln(os.Stderr, "expected receiver's address "+
+				atomicSwapCmd.auditCfg.ReceiverAddress.String()+
+				" is not the receiver of any of the found contracts")
+		}
+	}
+	if atomicSwapCmd.auditCfg.MinDurationLeft != 0 {
+		// optionally validate locktime
+		if durationLeft < atomicSwapCmd.auditCfg.MinDurationLeft {
+			invalidContract = true
+			fmt.Fprintln(os.Stderr, "found contracts' duration left "+
+				durationLeft.String()+
+				" is not sufficient, when compared the expected duration left of "+
+				atomicSwapCmd.auditCfg.MinDurationLeft.String())
+		}
+	}
+	if invalidContract {
+		cli.DieWithExitCode(AuditContractExitCodeInvalidContract,
+			"found Atomic Swap Contracts do not meet the given expectations")
+	}
+	fmt.Fprintln(os.Stderr, "found Atomic Swap Contracts are valid")
+	if !confirmed {
+		fmt.Fprintln(os.Stderr, "NOTE: some of these contracts are still in the transaction pool and thus unconfirmed")
+		cli.DieWithExitCode(cli.ExitCodeTemporaryError, "contracts are not yet confirmed")
+	}
+}
+
+// spendAtomicSwapContracts redeems the given atomic swap contracts in case a secret is given,
+// and refunds them otherwise, all in a single transaction, once confirmed by the user.
+func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) {
+	isSender, keyWord := secret == (types.AtomicSwapSecret{}), "redeem"
+	if isSender {
+		keyWord = "refund"
+	}
+
+	if !atomicSwapCmd.rootCfg.YesToAll {
+		// print contracts for review
+		query := url.Values{}
+		for _, outputID := range outputIDs {
+			query.Add("outputid", outputID.String())
+		}
+		var resp gcapi.WalletAtomicSwapAuditGET
+		err := atomicSwapCmd.cli.GetWithResponse("/wallet/atomicswap/audit?"+query.Encode(), &resp)
+		if err != nil {
+			if err == api.ErrStatusNotFound {
+				cli.DieWithExitCode(cli.ExitCodeNotFound, "not all atomic swap contracts could be found as unspent coin outputs:", err)
+			}
+			cli.DieWithError("failed to audit atomic swap contracts:", err)
+		}
+		for _, contract := range resp.Contracts {
+			fmt.Fprintln(os.Stderr, "OutputID:", contract.OutputID)
+			atomicSwapCmd.printContractInfo(os.Stderr, contract.SpendableValue, contract.Contract, secret, nil)
+			if contract.MultiSigReceiver != nil {
+				printMultiSigReceiver(os.Stderr, *contract.MultiSigReceiver)
+			}
+			fmt.Fprintln(os.Stderr, "")
+		}
+		// ensure user wants to continue with spending the contracts!
+		if !askYesNoQuestion(fmt.Sprintf("Publish atomic swap %s transaction, spending %d contracts?", keyWord, len(outputIDs))) {
+			cli.DieWithExitCode(cli.ExitCodeCancelled, "atomic swap "+keyWord+" transaction cancelled")
+		}
+	}
+
+	// have the wallet create, sign and publish the transaction,
+	// such that the keys used to sign it never leave the wallet
+	var (
+		body []byte
+		path string
+		err  error
+	)
+	if isSender {
+		path = "/wallet/atomicswap/refund"
+		body, err = json.Marshal(gcapi.WalletAtomicSwapRefundPOST{OutputIDs: outputIDs})
+	} else {
+		path = "/wallet/atomicswap/redeem"
+		body, err = json.Marshal(gcapi.WalletAtomicSwapRedeemPOST{OutputIDs: outputIDs, Secret: secret})
+	}
+	if err != nil {
+		cli.Die("failed to create/marshal JSON body:", err)
+	}
+	var resp gcapi.WalletAtomicSwapSpendPOSTResp
+	err = atomicSwapCmd.cli.PostWithResponse(path, string(body), &resp)
+	if err != nil {
+		cli.DieWithError("failed to "+keyWord+" atomic swaps' locked coins:", err)
+	}
+
+	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
+		// if encoding type is JSON, simply print all information as JSON
+		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputSpendContract{
+			TransactionID: resp.TransactionID,
+		})
+		return
+	}
+
+	// otherwise print it for a human, in a more verbose and friendly way
+	fmt.Println("")
+	fmt.Printf("published atomic swap %s transaction, spending %d contracts\n", keyWord, len(outputIDs))
+	fmt.Println("transaction ID:", resp.TransactionID)
+}
+
+// redeemMultiSigAtomicSwapContracts co-signs the redemption of the given atomic swap contracts of a multisig receiver,
+// printing the partially signed transaction, signed by this wallet, to be signed by the other co-signers.
+func (atomicSwapCmd *atomicSwapCmd) redeemMultiSigAtomicSwapContracts(outputIDs []types.CoinOutputID, secret types.AtomicSwapSecret) {
+	body, err := json.Marshal(gcapi.WalletAtomicSwapRedeemPOST{OutputIDs: outputIDs, Secret: secret})
+	if err != nil {
+		cli.Die("failed to create/marshal JSON body:", err)
+	}
+	var resp gcapi.WalletPSTPOSTResp
+	err = atomicSwapCmd.cli.PostWithResponse("/wallet/atomicswap/redeem/multisig", string(body), &resp)
+	if err != nil {
+		cli.DieWithError("failed to co-sign the redemption of the atomic swaps' locked coins:", err)
+	}
+	json.NewEncoder(os.Stdout).Encode(resp.PST)
+	// report the status on STDERR, such that STDOUT only contains the signed PST
+	printPSTStatus(os.Stderr, resp)
+	fmt.Fprintln(os.Stderr, "Have the co-signers sign the partially signed transaction using 'wallet pst sign',",
+		"and publish it using 'wallet pst finalize' once complete")
+}
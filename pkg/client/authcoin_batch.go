@@ -0,0 +1,496 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threefoldtech/rivine/extensions/authcointx"
+	authcointxcli "github.com/threefoldtech/rivine/extensions/authcointx/client"
+	"github.com/threefoldtech/rivine/pkg/cli"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/types"
+
+	aetypes "github.com/nbh-digital/goldchain/extensions/authexpiry/types"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+	"github.com/nbh-digital/goldchain/pkg/config"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// CreateAuthCoinWalletCmds adds the wallet cli subcommands used to (de)authorize
+// many addresses at once, optionally with an authorization expiry.
+func CreateAuthCoinWalletCmds(ccli *client.CommandLineClient) error {
+	walletCmd := &authCoinWalletCmd{
+		cli:                        ccli,
+		authAddressUpdateTxVersion: gctypes.TransactionVersionAuthAddressUpdate,
+	}
+	var (
+		createAuthAddressBatchCmd = &cobra.Command{
+			Use:   "authaddressbatch <csv>|-",
+			Short: "Create a bundle of auth address update transactions for a CSV of addresses",
+			Long: `Create a signing bundle of auth address update transactions, authorizing
+all addresses defined in the given CSV file (or STDIN if '-' is given),
+or deauthorizing them if the --deauthorize flag is given.
+
+Each line of the CSV file defines an address as its first field, other fields are ignored.
+The first line is skipped as a header if it does not define an address,
+lines starting with '#' are ignored. Duplicate addresses are only used once,
+and addresses which are already (de)authorized are skipped.
+
+An authorization can be made to expire at a given block height and/or block time,
+using the --valid-until-height, --valid-until or --valid-for flags, in which case no description can be given.
+Addresses of which the authorization expired can no longer send or receive coins,
+until they are deauthorized and authorized again.
+
+The addresses are split over as few transactions as possible, each transaction fitting within the
+transaction size limit once signed by all co-signers of the auth condition.
+
+The returned bundle wraps each transaction in a partially signed transaction, and still
+has to be signed using 'wallet authaddressbatch sign', prior to being published.
+	`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.createAuthAddressBatchCmd,
+		}
+		authAddressBatchCmd = &cobra.Command{
+			Use:   "authaddressbatch",
+			Short: "Sign, publish and track a bundle of auth address update transactions",
+			Long: `Sign, publish and track a bundle of auth address update transactions,
+as created using 'wallet create authaddressbatch'.
+
+Bundles are given and returned as JSON. Each <bundle> argument
+can be given as raw JSON or as the path of a file containing the JSON.
+`,
+			// Run field is not set, as the authaddressbatch command itself is not a valid command.
+		}
+		signAuthAddressBatchCmd = &cobra.Command{
+			Use:   "sign <bundle>",
+			Short: "Sign all transactions of an auth address bundle",
+			Long: `Sign all transactions of an auth address bundle, using the keys of this wallet.
+The signed bundle is printed to STDOUT, its status to STDERR.`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.signAuthAddressBatchCmd,
+		}
+		combineAuthAddressBatchCmd = &cobra.Command{
+			Use:   "combine <bundle> <bundle> [<bundle>]...",
+			Short: "Combine the signatures of auth address bundles",
+			Long: `Combine the signatures of auth address bundles, each signed by one or multiple co-signers,
+into a single bundle. All bundles have to be created for the same transactions.`,
+			Args: cobra.MinimumNArgs(2),
+			Run:  walletCmd.combineAuthAddressBatchCmd,
+		}
+		publishAuthAddressBatchCmd = &cobra.Command{
+			Use:   "publish <bundle>",
+			Short: "Publish the transactions of a signed auth address bundle",
+			Long:  `Publish the transactions of a signed auth address bundle, skipping transactions already published.`,
+			Args:  cobra.ExactArgs(1),
+			Run:   walletCmd.publishAuthAddressBatchCmd,
+		}
+		authAddressBatchStatusCmd = &cobra.Command{
+			Use:   "status <bundle>",
+			Short: "Report which transactions of an auth address bundle are confirmed",
+			Args:  cobra.ExactArgs(1),
+			Run:   walletCmd.authAddressBatchStatusCmd,
+		}
+	)
+
+	// add commands as wallet sub commands
+	ccli.WalletCmd.RootCmdCreate.AddCommand(createAuthAddressBatchCmd)
+	ccli.WalletCmd.AddCommand(authAddressBatchCmd)
+	authAddressBatchCmd.AddCommand(
+		signAuthAddressBatchCmd,
+		combineAuthAddressBatchCmd,
+		publishAuthAddressBatchCmd,
+		authAddressBatchStatusCmd,
+	)
+
+	// set the flags
+	createAuthAddressBatchCmd.Flags().BoolVar(
+		&walletCmd.authAddressBatchCfg.Deauthorize, "deauthorize", false,
+		"deauthorize the addresses instead of authorizing them")
+	createAuthAddressBatchCmd.Flags().Uint64Var(
+		&walletCmd.authAddressBatchCfg.ValidUntilHeight, "valid-until-height", 0,
+		"optionally expire the authorization as of the given block height")
+	createAuthAddressBatchCmd.Flags().StringVar(
+		&walletCmd.authAddressBatchCfg.ValidUntil, "valid-until", "",
+		"optionally expire the authorization as of the given block time, RFC3339 date or unix epoch timestamp")
+	createAuthAddressBatchCmd.Flags().DurationVar(
+		&walletCmd.authAddressBatchCfg.ValidFor, "valid-for", 0,
+		"optionally expire the authorization after the given duration from now (e.g. 8760h)")
+	cli.ArbitraryDataFlagVar(createAuthAddressBatchCmd.Flags(), &walletCmd.authAddressBatchCfg.Description,
+		"description", "optionally add a description to describe the reason of the (de)authorization, added as arbitrary data to each transaction")
+
+	return nil
+}
+
+type authCoinWalletCmd struct {
+	cli *client.CommandLineClient
+
+	authAddressUpdateTxVersion types.TransactionVersion
+	authAddressBatchCfg        struct {
+		Deauthorize      bool
+		ValidUntilHeight uint64
+		ValidUntil       string
+		ValidFor         time.Duration
+		Description      []byte
+	}
+}
+
+// authAddressEntry is an address parsed from a line of an auth address CSV file.
+type authAddressEntry struct {
+	Line    int
+	Address types.UnlockHash
+}
+
+// parseAuthAddressCSV parses the addresses of an auth address batch from the given CSV data,
+// each record defining an address as its first field.
+// The first record is skipped as a header if its address cannot be parsed,
+// empty lines and lines starting with a '#' are ignored.
+func parseAuthAddressCSV(r io.Reader) ([]authAddressEntry, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var entries []authAddressEntry
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		var uh types.UnlockHash
+		err = uh.LoadString(strings.TrimSpace(record[0]))
+		if err != nil {
+			if first {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid address %q: %v", line, record[0], err)
+		}
+		entries = append(entries, authAddressEntry{Line: line, Address: uh})
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no addresses defined")
+	}
+	return entries, nil
+}
+
+// authAddressBatchExpiry returns the authorization expiry defined by the flags of the create command,
+// undefined if no expiry flag is given.
+func (walletCmd *authCoinWalletCmd) authAddressBatchExpiry(cmd *cobra.Command) aetypes.Expiry {
+	cfg := walletCmd.authAddressBatchCfg
+	expiry := aetypes.Expiry{ValidUntilHeight: types.BlockHeight(cfg.ValidUntilHeight)}
+	if cfg.ValidUntil != "" && cfg.ValidFor != 0 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("the --valid-until and --valid-for flags cannot be combined")
+	}
+	if cfg.ValidUntil != "" {
+		timestamp, _ := strconv.ParseUint(parseTransactionsTimeFlag("valid-until", cfg.ValidUntil), 10, 64)
+		expiry.ValidUntilTime = types.Timestamp(timestamp)
+	}
+	if cfg.ValidFor < 0 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("the --valid-for flag cannot be negative")
+	}
+	if cfg.ValidFor > 0 {
+		expiry.ValidUntilTime = types.Timestamp(time.Now().Add(cfg.ValidFor).Unix())
+	}
+	if !expiry.Defined() {
+		return expiry
+	}
+	if cfg.Deauthorize {
+		cmd.UsageFunc()(cmd)
+		cli.Die("an authorization expiry cannot be defined when deauthorizing addresses")
+	}
+	if len(cfg.Description) > 0 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("an authorization expiry cannot be combined with a description")
+	}
+	return expiry
+}
+
+func (walletCmd *authCoinWalletCmd) createAuthAddressBatchCmd(cmd *cobra.Command, args []string) {
+	expiry := walletCmd.authAddressBatchExpiry(cmd)
+	deauthorize := walletCmd.authAddressBatchCfg.Deauthorize
+
+	// parse the addresses from the CSV file (or STDIN)
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			cli.Die("failed to open CSV file:", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	entries, err := parseAuthAddressCSV(r)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("invalid CSV file:", err)
+	}
+	addresses := walletCmd.authAddressBatchAddresses(entries, !deauthorize)
+
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(walletCmd.cli)
+	if err != nil {
+		cli.DieWithError("failed to create base client", err)
+	}
+	authCondition, err := authcointxcli.NewPluginConsensusClient(bc).GetActiveAuthCondition()
+	if err != nil {
+		cli.DieWithError("failed to get the active auth condition", err)
+	}
+	arbitraryData := walletCmd.authAddressBatchCfg.Description
+	if expiry.Defined() {
+		arbitraryData, err = expiry.MarshalArbitraryData()
+		if err != nil {
+			cli.DieWithError("failed to encode the authorization expiry", err)
+		}
+	}
+
+	transactions, err := walletCmd.authAddressBatchTransactions(addresses, authCondition, arbitraryData)
+	if err != nil {
+		cli.DieWithError("failed to split the addresses over auth address update transactions", err)
+	}
+
+	// wrap each transaction in a PST, to be signed by the co-signers of the auth condition
+	bundle := gcmodules.AuthAddressBundle{
+		Version:      gcmodules.AuthAddressBundleVersion,
+		Transactions: make([]gcmodules.PartiallySignedTransaction, 0, len(transactions)),
+	}
+	for _, tx := range transactions {
+		b, err := json.Marshal(gcapi.WalletPSTCreatePOST{
+			Transaction: tx.Transaction(walletCmd.authAddressUpdateTxVersion),
+		})
+		if err != nil {
+			cli.Die("Failed to JSON Marshal the transaction:", err)
+		}
+		var resp gcapi.WalletPSTPOSTResp
+		err = walletCmd.cli.PostWithResponse("/wallet/pst/create", string(b), &resp)
+		if err != nil {
+			cli.DieWithError("Failed to create partially signed transaction:", err)
+		}
+		bundle.Transactions = append(bundle.Transactions, resp.PST)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(bundle)
+	// report the summary on STDERR, such that STDOUT only contains the bundle
+	action := "authorizing"
+	if deauthorize {
+		action = "deauthorizing"
+	}
+	fmt.Fprintf(os.Stderr, "Created %d auth address update transaction(s), %s %d address(es)", len(bundle.Transactions), action, len(addresses))
+	if expiry.Defined() {
+		fmt.Fprintf(os.Stderr, " until %s", expiry.String())
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Have the co-signers of the auth condition sign the bundle using 'wallet authaddressbatch sign',",
+		"and publish it using 'wallet authaddressbatch publish' once complete")
+}
+
+// authAddressBatchTransactions creates the (unsigned) auth address update transactions (de)authorizing the given addresses,
+// split over as few transactions as possible, each fitting within the transaction size limit
+// once signed by all co-signers of the given auth condition.
+func (walletCmd *authCoinWalletCmd) authAddressBatchTransactions(addresses []types.UnlockHash, authCondition types.UnlockConditionProxy, arbitraryData []byte) ([]authcointx.AuthAddressUpdateTransaction, error) {
+	deauthorize := walletCmd.authAddressBatchCfg.Deauthorize
+	newAuthAddressUpdateTx := func(uhs []types.UnlockHash) authcointx.AuthAddressUpdateTransaction {
+		tx := authcointx.AuthAddressUpdateTransaction{
+			Nonce: types.RandomTransactionNonce(),
+		}
+		if deauthorize {
+			tx.DeauthAddresses = uhs
+		} else {
+			tx.AuthAddresses = uhs
+		}
+		if n := len(arbitraryData); n > 0 {
+			tx.ArbitraryData = make([]byte, n)
+			copy(tx.ArbitraryData[:], arbitraryData[:])
+		}
+		return tx
+	}
+	// transactions are split using the size they have once signed by all co-signers of the auth condition
+	authFulfillment := gcmodules.LargestFulfillment(authCondition)
+	split, err := gcmodules.SplitAuthAddresses(addresses, func(uhs []types.UnlockHash) types.Transaction {
+		tx := newAuthAddressUpdateTx(uhs)
+		tx.AuthFulfillment = authFulfillment
+		return tx.Transaction(walletCmd.authAddressUpdateTxVersion)
+	}, config.GetDefaultGenesis().TransactionPool.TransactionSizeLimit)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]authcointx.AuthAddressUpdateTransaction, 0, len(split))
+	for _, uhs := range split {
+		transactions = append(transactions, newAuthAddressUpdateTx(uhs))
+	}
+	return transactions, nil
+}
+
+// authAddressBatchAddresses returns the unique addresses of the given entries,
+// skipping the addresses which are already in the desired authorization state.
+// The command dies if no addresses remain.
+func (walletCmd *authCoinWalletCmd) authAddressBatchAddresses(entries []authAddressEntry, authorize bool) []types.UnlockHash {
+	var (
+		addresses  []types.UnlockHash
+		seen       = make(map[types.UnlockHash]struct{}, len(entries))
+		duplicates int
+	)
+	for _, entry := range entries {
+		if _, ok := seen[entry.Address]; ok {
+			duplicates++
+			continue
+		}
+		seen[entry.Address] = struct{}{}
+		addresses = append(addresses, entry.Address)
+	}
+	if duplicates > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d duplicate address(es)\n", duplicates)
+	}
+
+	states, err := getAddressesAuthStates(walletCmd.cli, addresses)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not check the current authorization of the addresses:", err)
+		return addresses
+	}
+	filtered := addresses[:0]
+	for _, uh := range addresses {
+		if authorized, ok := states[uh]; ok && authorized == authorize {
+			continue
+		}
+		filtered = append(filtered, uh)
+	}
+	if skipped := len(addresses) - len(filtered); skipped > 0 {
+		if authorize {
+			fmt.Fprintf(os.Stderr, "Skipped %d address(es) which are already authorized, "+
+				"these have to be deauthorized first in order to renew their authorization\n", skipped)
+		} else {
+			fmt.Fprintf(os.Stderr, "Skipped %d address(es) which are already deauthorized\n", skipped)
+		}
+	}
+	if len(filtered) == 0 {
+		cli.Die("No addresses left to be (de)authorized")
+	}
+	return filtered
+}
+
+// readAuthAddressBundle reads an auth address bundle, given as raw JSON or as the path of a JSON file.
+func readAuthAddressBundle(arg string) gcmodules.AuthAddressBundle {
+	var bundle gcmodules.AuthAddressBundle
+	err := json.Unmarshal([]byte(readJSONArgument(arg)), &bundle)
+	if err != nil {
+		cli.Die("Invalid auth address bundle:", err)
+	}
+	if bundle.Version != gcmodules.AuthAddressBundleVersion {
+		cli.Die("Invalid auth address bundle:", gcmodules.ErrUnknownAuthAddressBundleVersion)
+	}
+	return bundle
+}
+
+func (walletCmd *authCoinWalletCmd) signAuthAddressBatchCmd(cmd *cobra.Command, args []string) {
+	bundle := readAuthAddressBundle(args[0])
+	for txnIdx, pst := range bundle.Transactions {
+		b, err := json.Marshal(pst)
+		if err != nil {
+			cli.Die("Failed to JSON Marshal the partially signed transaction:", err)
+		}
+		var resp gcapi.WalletPSTPOSTResp
+		err = walletCmd.cli.PostWithResponse("/wallet/pst/sign", string(b), &resp)
+		if err != nil {
+			cli.DieWithError(fmt.Sprintf("Failed to sign transaction #%d:", txnIdx+1), err)
+		}
+		bundle.Transactions[txnIdx] = resp.PST
+	}
+	json.NewEncoder(os.Stdout).Encode(bundle)
+	// report the status on STDERR, such that STDOUT only contains the signed bundle
+	printBundleStatus(os.Stderr, bundle.Transactions)
+}
+
+func (walletCmd *authCoinWalletCmd) combineAuthAddressBatchCmd(cmd *cobra.Command, args []string) {
+	bundles := make([]gcmodules.AuthAddressBundle, 0, len(args))
+	for _, arg := range args {
+		bundles = append(bundles, readAuthAddressBundle(arg))
+	}
+	bundle, err := gcmodules.CombineAuthAddressBundles(bundles...)
+	if err != nil {
+		cli.DieWithError("Failed to combine auth address bundles:", err)
+	}
+	json.NewEncoder(os.Stdout).Encode(bundle)
+	printBundleStatus(os.Stderr, bundle.Transactions)
+}
+
+func (walletCmd *authCoinWalletCmd) publishAuthAddressBatchCmd(cmd *cobra.Command, args []string) {
+	bundle := readAuthAddressBundle(args[0])
+	states := getBundleTransactionStates(walletCmd.cli, bundle.Transactions)
+
+	// ensure all transactions are signed, prior to publishing any of them
+	for txnIdx, pst := range bundle.Transactions {
+		if states[txnIdx].ID == nil {
+			cli.Die(fmt.Sprintf("transaction #%d is missing signatures of: %s", txnIdx+1,
+				formatUnlockHashes(pst.MissingSigners())))
+		}
+	}
+
+	published := 0
+	for txnIdx, pst := range bundle.Transactions {
+		if state := states[txnIdx]; state.Pending || state.Confirmed {
+			continue // already published
+		}
+		b, err := json.Marshal(pst)
+		if err != nil {
+			cli.Die("Failed to JSON Marshal the partially signed transaction:", err)
+		}
+		var resp gcapi.WalletPSTFinalizePOSTResp
+		err = walletCmd.cli.PostWithResponse("/wallet/pst/finalize", string(b), &resp)
+		if err != nil {
+			cli.DieWithError(fmt.Sprintf("Failed to publish transaction #%d:", txnIdx+1), err)
+		}
+		fmt.Printf("Published transaction #%d as %s\n", txnIdx+1, resp.TransactionID.String())
+		published++
+	}
+	if published == 0 {
+		fmt.Println("All transactions are already published")
+	}
+}
+
+func (walletCmd *authCoinWalletCmd) authAddressBatchStatusCmd(cmd *cobra.Command, args []string) {
+	bundle := readAuthAddressBundle(args[0])
+	states := getBundleTransactionStates(walletCmd.cli, bundle.Transactions)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "transaction\tid\tauthorized\tdeauthorized\texpiry\tstate")
+	confirmed := 0
+	for txnIdx, pst := range bundle.Transactions {
+		state := states[txnIdx]
+		if state.Confirmed {
+			confirmed++
+		}
+		id := "-"
+		if state.ID != nil {
+			id = state.ID.String()
+		}
+		tx, err := authcointx.AuthAddressUpdateTransactionFromTransaction(pst.Transaction, walletCmd.authAddressUpdateTxVersion)
+		if err != nil {
+			cli.DieWithError(fmt.Sprintf("Invalid transaction #%d:", txnIdx+1), err)
+		}
+		expiry := "-"
+		if aetypes.IsExpiryArbitraryData(tx.ArbitraryData) {
+			e, err := aetypes.UnmarshalExpiryArbitraryData(tx.ArbitraryData)
+			if err != nil {
+				cli.DieWithError(fmt.Sprintf("Invalid authorization expiry of transaction #%d:", txnIdx+1), err)
+			}
+			expiry = e.String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\n", txnIdx+1, id,
+			len(tx.AuthAddresses), len(tx.DeauthAddresses), expiry, state.String())
+	}
+	w.Flush()
+	fmt.Printf("\n%d of %d transaction(s) confirmed\n", confirmed, len(bundle.Transactions))
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/threefoldtech/rivine/extensions/authcointx"
+	"github.com/threefoldtech/rivine/types"
+
+	aetypes "github.com/nbh-digital/goldchain/extensions/authexpiry/types"
+	"github.com/nbh-digital/goldchain/pkg/config"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// TestAuthAddressBatchTransactions checks that the addresses parsed from an auth address CSV file
+// are split, in order, over auth address update transactions which fit within the transaction size limit
+// once signed by all co-signers of the auth condition.
+func TestAuthAddressBatchTransactions(t *testing.T) {
+	var csv strings.Builder
+	csv.WriteString("address,name\n# KYC approvals\n")
+	var expected []types.UnlockHash
+	for i := 0; i < 1000; i++ {
+		var uh types.UnlockHash
+		uh.Type = types.UnlockTypePubKey
+		uh.Hash[0], uh.Hash[1] = byte(i), byte(i>>8)
+		expected = append(expected, uh)
+		fmt.Fprintf(&csv, "%s,customer %d\n", uh.String(), i)
+	}
+	entries, err := parseAuthAddressCSV(strings.NewReader(csv.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(expected) || entries[0].Line != 3 {
+		t.Fatalf("unexpected entries: %d entries, first at line %d", len(entries), entries[0].Line)
+	}
+	addresses := make([]types.UnlockHash, 0, len(entries))
+	for _, entry := range entries {
+		addresses = append(addresses, entry.Address)
+	}
+
+	// the transaction version is registered by the client binary
+	types.RegisterTransactionVersion(gctypes.TransactionVersionAuthAddressUpdate, authcointx.AuthAddressUpdateTransactionController{
+		TransactionVersion: gctypes.TransactionVersionAuthAddressUpdate,
+	})
+	authCondition, authFulfillment := newTestMultiSigCondition(t, 5)
+	expiry := aetypes.Expiry{ValidUntilHeight: 100000}
+	arbitraryData, err := expiry.MarshalArbitraryData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizeLimit := config.GetDefaultGenesis().TransactionPool.TransactionSizeLimit
+	for _, deauthorize := range []bool{false, true} {
+		walletCmd := &authCoinWalletCmd{authAddressUpdateTxVersion: gctypes.TransactionVersionAuthAddressUpdate}
+		walletCmd.authAddressBatchCfg.Deauthorize = deauthorize
+		transactions, err := walletCmd.authAddressBatchTransactions(addresses, authCondition, arbitraryData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) < 2 {
+			t.Fatalf("deauthorize %v: expected the addresses to be split over multiple transactions, got %d", deauthorize, len(transactions))
+		}
+		var offset int
+		for txnIdx, tx := range transactions {
+			uhs := tx.AuthAddresses
+			if deauthorize {
+				uhs = tx.DeauthAddresses
+			}
+			if len(tx.AuthAddresses)+len(tx.DeauthAddresses) != len(uhs) {
+				t.Errorf("deauthorize %v: transaction #%d both authorizes and deauthorizes addresses", deauthorize, txnIdx+1)
+			}
+			for _, uh := range uhs {
+				if offset >= len(expected) || uh.Cmp(expected[offset]) != 0 {
+					t.Fatalf("deauthorize %v: unexpected address %s at index %d", deauthorize, uh.String(), offset)
+				}
+				offset++
+			}
+			if string(tx.ArbitraryData) != string(arbitraryData) {
+				t.Errorf("deauthorize %v: unexpected arbitrary data of transaction #%d", deauthorize, txnIdx+1)
+			}
+			if tx.AuthFulfillment.Fulfillment != nil {
+				t.Errorf("deauthorize %v: transaction #%d is returned signed", deauthorize, txnIdx+1)
+			}
+
+			// each transaction fits once signed, while it would not fit with an additional address
+			tx.AuthFulfillment = authFulfillment
+			if size := signedTransactionSize(t, tx.Transaction(walletCmd.authAddressUpdateTxVersion)); size > sizeLimit {
+				t.Errorf("deauthorize %v: signed transaction #%d has size %d, exceeding the limit of %d", deauthorize, txnIdx+1, size, sizeLimit)
+			}
+			if txnIdx == len(transactions)-1 {
+				continue
+			}
+			uhs = append(append([]types.UnlockHash(nil), uhs...), expected[offset])
+			if deauthorize {
+				tx.DeauthAddresses = uhs
+			} else {
+				tx.AuthAddresses = uhs
+			}
+			if size := signedTransactionSize(t, tx.Transaction(walletCmd.authAddressUpdateTxVersion)); size <= sizeLimit {
+				t.Errorf("deauthorize %v: transaction #%d is not filled as much as possible", deauthorize, txnIdx+1)
+			}
+		}
+		if offset != len(expected) {
+			t.Errorf("deauthorize %v: %d of %d addresses are part of a transaction", deauthorize, offset, len(expected))
+		}
+	}
+}
+
+// TestParseAuthAddressCSV checks that invalid auth address CSV files are refused.
+func TestParseAuthAddressCSV(t *testing.T) {
+	var uh types.UnlockHash
+	uh.Type = types.UnlockTypePubKey
+	for _, invalid := range []string{
+		"",
+		"address\n",
+		"# only a comment\n",
+		uh.String() + "\nfoo\n",
+	} {
+		if _, err := parseAuthAddressCSV(strings.NewReader(invalid)); err == nil {
+			t.Errorf("parsed invalid CSV %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	portypes "github.com/nbh-digital/goldchain/extensions/proofofreserve/types"
+)
+
+// attestationCfg is the configuration of the proof-of-reserve attestation
+// that can be attached to coin creation and coin destruction transactions.
+type attestationCfg struct {
+	VaultID              string
+	BarSerials           []string
+	Weight               string
+	AssayerSignatureHash string
+}
+
+func registerAttestationFlags(flags *pflag.FlagSet, cfg *attestationCfg) {
+	flags.StringVar(&cfg.VaultID, "vault", "",
+		"attach a proof-of-reserve attestation for the given vault, requires all other attestation flags")
+	flags.StringSliceVar(&cfg.BarSerials, "bar-serial", nil,
+		"serial of a gold bar of the attestation, can be given multiple times or as a comma-separated list")
+	flags.StringVar(&cfg.Weight, "weight", "",
+		"total weight of fine gold of the attestation, in grams (with at most 3 decimals)")
+	flags.StringVar(&cfg.AssayerSignatureHash, "assayer-signature-hash", "",
+		"hash of the signature of the assayer on the assay report of the bars of the attestation")
+}
+
+// defined returns true if any of the attestation flags is used.
+func (cfg *attestationCfg) defined() bool {
+	return cfg.VaultID != "" || len(cfg.BarSerials) > 0 || cfg.Weight != "" || cfg.AssayerSignatureHash != ""
+}
+
+// arbitraryData returns the arbitrary data of the transaction, being either the encoded attestation,
+// if one is configured, or the given description otherwise.
+func (cfg *attestationCfg) arbitraryData(description []byte) ([]byte, error) {
+	if !cfg.defined() {
+		return description, nil
+	}
+	if len(description) > 0 {
+		return nil, errors.New("a description cannot be combined with a proof-of-reserve attestation")
+	}
+	attestation := portypes.Attestation{
+		VaultID:    cfg.VaultID,
+		BarSerials: cfg.BarSerials,
+	}
+	if cfg.Weight != "" {
+		err := attestation.Weight.LoadString(cfg.Weight)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.AssayerSignatureHash != "" {
+		err := attestation.AssayerSignatureHash.LoadString(cfg.AssayerSignatureHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid assayer signature hash: %v", err)
+		}
+	}
+	err := attestation.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return attestation.MarshalArbitraryData()
+}
@@ -0,0 +1,507 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	minting "github.com/threefoldtech/rivine/extensions/minting"
+	mintingcli "github.com/threefoldtech/rivine/extensions/minting/client"
+	rivmodules "github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/pkg/cli"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+	"github.com/nbh-digital/goldchain/pkg/config"
+)
+
+// blockOverheadSize is the size reserved in each block for data other than its transactions,
+// as done by the block creator when filling a block with transactions.
+const blockOverheadSize = 5e3
+
+// coinCreationRecipient is a coin output parsed from a line of a coin creation CSV file.
+type coinCreationRecipient struct {
+	Line   int
+	Output types.CoinOutput
+}
+
+// parseCoinCreationCSV parses the recipients of a coin creation batch from the given CSV data,
+// each record defining an address (or JSON-encoded condition) and an amount.
+// The first record is skipped as a header if its amount cannot be parsed,
+// empty lines and lines starting with a '#' are ignored.
+func parseCoinCreationCSV(r io.Reader, parseCoinString func(string) (types.Currency, error)) ([]coinCreationRecipient, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	var recipients []coinCreationRecipient
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		value, err := parseCoinString(strings.TrimSpace(record[1]))
+		if err != nil {
+			if first {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid amount %q: %v", line, record[1], err)
+		}
+		if value.IsZero() {
+			return nil, fmt.Errorf("line %d: amount cannot be zero", line)
+		}
+		condition, err := parseConditionString(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		recipients = append(recipients, coinCreationRecipient{
+			Line:   line,
+			Output: types.CoinOutput{Value: value, Condition: condition},
+		})
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients defined")
+	}
+	return recipients, nil
+}
+
+func (walletCmd *mintingWalletCmd) createCoinCreationBatchCmd(cmd *cobra.Command, args []string) {
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+
+	// parse the recipients from the CSV file (or STDIN)
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			cli.Die("failed to open CSV file:", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	recipients, err := parseCoinCreationCSV(r, currencyConvertor.ParseCoinString)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("invalid CSV file:", err)
+	}
+	walletCmd.checkCoinCreationRecipientsAuthorization(recipients)
+
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(walletCmd.cli)
+	if err != nil {
+		cli.DieWithError("failed to create base client", err)
+	}
+	mintCondition, err := mintingcli.NewPluginConsensusClient(bc).GetActiveMintCondition()
+	if err != nil {
+		cli.DieWithError("failed to get the active mint condition", err)
+	}
+	var constants rivmodules.DaemonConstants
+	err = walletCmd.cli.GetWithResponse("/daemon/constants", &constants)
+	if err != nil {
+		cli.DieWithError("failed to get the daemon constants", err)
+	}
+	limits := gcmodules.CoinCreationBatchLimits{
+		// the transaction size limit is not exposed by the daemon, and is the same for all networks
+		TransactionSizeLimit: config.GetDefaultGenesis().TransactionPool.TransactionSizeLimit,
+		BatchSizeLimit:       int(constants.BlockSizeLimit - blockOverheadSize),
+	}
+
+	outputs := make([]types.CoinOutput, 0, len(recipients))
+	for _, recipient := range recipients {
+		outputs = append(outputs, recipient.Output)
+	}
+	batches, err := walletCmd.coinCreationBatchTransactions(outputs, mintCondition, limits)
+	if err != nil {
+		cli.DieWithError("failed to split the recipients over coin creation transactions", err)
+	}
+
+	// wrap each transaction in a PST, to be signed by the co-signers of the mint condition
+	bundle := gcmodules.CoinCreationBundle{
+		Version: gcmodules.CoinCreationBundleVersion,
+		Batches: make([]gcmodules.CoinCreationBatch, 0, len(batches)),
+	}
+	var (
+		txnCount int
+		total    types.Currency
+	)
+	for _, batch := range batches {
+		var bundleBatch gcmodules.CoinCreationBatch
+		for _, tx := range batch {
+			b, err := json.Marshal(gcapi.WalletPSTCreatePOST{
+				Transaction: tx.Transaction(walletCmd.coinCreationTxVersion),
+			})
+			if err != nil {
+				cli.Die("Failed to JSON Marshal the transaction:", err)
+			}
+			var resp gcapi.WalletPSTPOSTResp
+			err = walletCmd.cli.PostWithResponse("/wallet/pst/create", string(b), &resp)
+			if err != nil {
+				cli.DieWithError("Failed to create partially signed transaction:", err)
+			}
+			bundleBatch.Transactions = append(bundleBatch.Transactions, resp.PST)
+			for _, co := range tx.CoinOutputs {
+				total = total.Add(co.Value)
+			}
+			txnCount++
+		}
+		bundle.Batches = append(bundle.Batches, bundleBatch)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(bundle)
+	// report the summary on STDERR, such that STDOUT only contains the bundle
+	fmt.Fprintf(os.Stderr, "Created %d coin creation transaction(s) in %d batch(es), creating %s for %d recipient(s)\n",
+		txnCount, len(bundle.Batches), currencyConvertor.ToCoinStringWithUnit(total), len(recipients))
+	fmt.Fprintln(os.Stderr, "Have the co-signers of the mint condition sign the bundle using 'wallet coincreationbatch sign',",
+		"and publish it using 'wallet coincreationbatch publish' once complete")
+}
+
+// coinCreationBatchTransactions creates the (unsigned) coin creation transactions for the given coin outputs,
+// split over as few transactions as possible, each fitting within the transaction size limit
+// once signed by all co-signers of the given mint condition, and grouped into batches fitting within the batch size limit.
+func (walletCmd *mintingWalletCmd) coinCreationBatchTransactions(outputs []types.CoinOutput, mintCondition types.UnlockConditionProxy, limits gcmodules.CoinCreationBatchLimits) ([][]minting.CoinCreationTransaction, error) {
+	newCoinCreationTx := func(cos []types.CoinOutput) minting.CoinCreationTransaction {
+		tx := minting.CoinCreationTransaction{
+			Nonce:       types.RandomTransactionNonce(),
+			CoinOutputs: cos,
+		}
+		if walletCmd.requireMinerFees {
+			tx.MinerFees = []types.Currency{walletCmd.cli.Config.MinimumTransactionFee}
+		}
+		if n := len(walletCmd.coinCreationBatchCfg.Description); n > 0 {
+			tx.ArbitraryData = make([]byte, n)
+			copy(tx.ArbitraryData[:], walletCmd.coinCreationBatchCfg.Description[:])
+		}
+		return tx
+	}
+	// transactions are split using the size they have once signed by all co-signers of the mint condition
+	mintFulfillment := gcmodules.LargestFulfillment(mintCondition)
+	split, err := gcmodules.SplitCoinCreationOutputs(outputs, func(cos []types.CoinOutput) types.Transaction {
+		tx := newCoinCreationTx(cos)
+		tx.MintFulfillment = mintFulfillment
+		return tx.Transaction(walletCmd.coinCreationTxVersion)
+	}, limits)
+	if err != nil {
+		return nil, err
+	}
+	batches := make([][]minting.CoinCreationTransaction, 0, len(split))
+	for _, batch := range split {
+		transactions := make([]minting.CoinCreationTransaction, 0, len(batch))
+		for _, cos := range batch {
+			transactions = append(transactions, newCoinCreationTx(cos))
+		}
+		batches = append(batches, transactions)
+	}
+	return batches, nil
+}
+
+// checkCoinCreationRecipientsAuthorization ensures that all recipients of a coin creation batch are authorized,
+// prior to creating its transactions. The command dies if any address is unauthorized,
+// unless explicitly allowed, in which case only a warning is printed.
+func (walletCmd *mintingWalletCmd) checkCoinCreationRecipientsAuthorization(recipients []coinCreationRecipient) {
+	addresses := make([]types.UnlockHash, 0, len(recipients))
+	for _, recipient := range recipients {
+		addresses = append(addresses, recipient.Output.Condition.UnlockHash())
+	}
+	states, err := getAddressesAuthStates(walletCmd.cli, addresses)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not check the authorization of the recipients:", err)
+		return
+	}
+	var unauthorized []string
+	for idx, uh := range addresses {
+		if authorized, ok := states[uh]; ok && !authorized {
+			unauthorized = append(unauthorized, fmt.Sprintf("  line %d: %s", recipients[idx].Line, uh.String()))
+		}
+	}
+	if len(unauthorized) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("The following recipient(s) are not authorized to receive coins:\n%s\n", strings.Join(unauthorized, "\n"))
+	if walletCmd.coinCreationBatchCfg.AllowUnauthorized {
+		fmt.Fprint(os.Stderr, "Warning: "+msg)
+		return
+	}
+	cli.Die(msg + "Authorize these addresses first, or use the --allow-unauthorized flag to create the batch regardless.")
+}
+
+// readCoinCreationBundle reads a coin creation bundle, given as raw JSON or as the path of a JSON file.
+func readCoinCreationBundle(arg string) gcmodules.CoinCreationBundle {
+	var bundle gcmodules.CoinCreationBundle
+	err := json.Unmarshal([]byte(readJSONArgument(arg)), &bundle)
+	if err != nil {
+		cli.Die("Invalid coin creation bundle:", err)
+	}
+	if bundle.Version != gcmodules.CoinCreationBundleVersion {
+		cli.Die("Invalid coin creation bundle:", gcmodules.ErrUnknownCoinCreationBundleVersion)
+	}
+	return bundle
+}
+
+func (walletCmd *mintingWalletCmd) signCoinCreationBatchCmd(cmd *cobra.Command, args []string) {
+	bundle := readCoinCreationBundle(args[0])
+	for batchIdx, batch := range bundle.Batches {
+		for txnIdx, pst := range batch.Transactions {
+			b, err := json.Marshal(pst)
+			if err != nil {
+				cli.Die("Failed to JSON Marshal the partially signed transaction:", err)
+			}
+			var resp gcapi.WalletPSTPOSTResp
+			err = walletCmd.cli.PostWithResponse("/wallet/pst/sign", string(b), &resp)
+			if err != nil {
+				cli.DieWithError(fmt.Sprintf("Failed to sign transaction #%d of batch #%d:", txnIdx+1, batchIdx+1), err)
+			}
+			batch.Transactions[txnIdx] = resp.PST
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(bundle)
+	// report the status on STDERR, such that STDOUT only contains the signed bundle
+	printCoinCreationBundleStatus(os.Stderr, bundle)
+}
+
+func (walletCmd *mintingWalletCmd) combineCoinCreationBatchCmd(cmd *cobra.Command, args []string) {
+	bundles := make([]gcmodules.CoinCreationBundle, 0, len(args))
+	for _, arg := range args {
+		bundles = append(bundles, readCoinCreationBundle(arg))
+	}
+	bundle, err := gcmodules.CombineCoinCreationBundles(bundles...)
+	if err != nil {
+		cli.DieWithError("Failed to combine coin creation bundles:", err)
+	}
+	json.NewEncoder(os.Stdout).Encode(bundle)
+	printCoinCreationBundleStatus(os.Stderr, bundle)
+}
+
+// printCoinCreationBundleStatus prints whether or not the bundle is complete,
+// and which signers are still missing if not.
+func printCoinCreationBundleStatus(w *os.File, bundle gcmodules.CoinCreationBundle) {
+	var psts []gcmodules.PartiallySignedTransaction
+	for _, batch := range bundle.Batches {
+		psts = append(psts, batch.Transactions...)
+	}
+	printBundleStatus(w, psts)
+}
+
+// printBundleStatus prints whether or not all given transactions of a signing bundle are complete,
+// and which signers are still missing if not.
+func printBundleStatus(w *os.File, psts []gcmodules.PartiallySignedTransaction) {
+	var (
+		incomplete int
+		missing    []string
+		known      = make(map[types.UnlockHash]struct{})
+	)
+	for _, pst := range psts {
+		if pst.Complete() {
+			continue
+		}
+		incomplete++
+		for _, uh := range pst.MissingSigners() {
+			if _, ok := known[uh]; !ok {
+				known[uh] = struct{}{}
+				missing = append(missing, uh.String())
+			}
+		}
+	}
+	if incomplete == 0 {
+		fmt.Fprintln(w, "Status: complete, ready to be published")
+		return
+	}
+	if len(missing) == 0 {
+		fmt.Fprintf(w, "Status: incomplete (%d transaction(s))\n", incomplete)
+		return
+	}
+	fmt.Fprintf(w, "Status: incomplete (%d transaction(s)), missing signatures of: %s\n", incomplete, strings.Join(missing, ", "))
+}
+
+// bundleTransactionState is the state of a transaction of a signing bundle.
+type bundleTransactionState struct {
+	// ID is only defined for a complete transaction,
+	// as the ID of a transaction covers its signatures
+	ID        *types.TransactionID
+	Pending   bool
+	Confirmed bool
+	// Height is only defined for a confirmed transaction,
+	// if the daemon has the explorer module enabled
+	Height *types.BlockHeight
+}
+
+// String returns the state of the transaction as a human-readable string.
+func (state bundleTransactionState) String() string {
+	switch {
+	case state.ID == nil:
+		return "unsigned"
+	case state.Confirmed && state.Height != nil:
+		return fmt.Sprintf("confirmed at height %d", *state.Height)
+	case state.Confirmed:
+		return "confirmed"
+	case state.Pending:
+		return "pending"
+	default:
+		return "unpublished"
+	}
+}
+
+// coinCreationTransactionStates looks up the state of all transactions of the given bundle,
+// using the consensus set for confirmed transactions and the transaction pool for pending ones.
+func (walletCmd *mintingWalletCmd) coinCreationTransactionStates(bundle gcmodules.CoinCreationBundle) [][]bundleTransactionState {
+	var psts []gcmodules.PartiallySignedTransaction
+	for _, batch := range bundle.Batches {
+		psts = append(psts, batch.Transactions...)
+	}
+	flatStates := getBundleTransactionStates(walletCmd.cli, psts)
+	states := make([][]bundleTransactionState, len(bundle.Batches))
+	for batchIdx, batch := range bundle.Batches {
+		states[batchIdx], flatStates = flatStates[:len(batch.Transactions)], flatStates[len(batch.Transactions):]
+	}
+	return states
+}
+
+// getBundleTransactionStates looks up the state of the given transactions of a signing bundle,
+// using the consensus set for confirmed transactions and the transaction pool for pending ones.
+func getBundleTransactionStates(ccli *client.CommandLineClient, psts []gcmodules.PartiallySignedTransaction) []bundleTransactionState {
+	var txnPoolGetResp api.TransactionPoolGET
+	err := ccli.GetWithResponse("/transactionpool/transactions", &txnPoolGetResp)
+	if err != nil {
+		cli.DieWithError("failed to get unconfirmed transactions from the transactionpool", err)
+	}
+	pending := make(map[types.TransactionID]struct{}, len(txnPoolGetResp.Transactions))
+	for _, txn := range txnPoolGetResp.Transactions {
+		pending[txn.ID()] = struct{}{}
+	}
+
+	states := make([]bundleTransactionState, len(psts))
+	for idx, pst := range psts {
+		if !pst.Complete() {
+			continue
+		}
+		state := &states[idx]
+		txnID := pst.Transaction.ID()
+		state.ID = &txnID
+		if _, ok := pending[txnID]; ok {
+			state.Pending = true
+			continue
+		}
+		var txnResp api.ConsensusGetTransaction
+		err = ccli.GetWithResponse("/consensus/transactions/"+txnID.String(), &txnResp)
+		if err == nil {
+			// the daemon can return another transaction for an ID it does not know
+			if txnResp.Transaction.ID() != txnID {
+				continue
+			}
+			state.Confirmed = true
+			// the short ID is not returned by the consensus set,
+			// the height of the transaction is therefore looked up in the explorer, if available
+			var hashResp gcapi.ExplorerHashGET
+			if ccli.GetWithResponse("/explorer/hashes/"+txnID.String(), &hashResp) == nil {
+				state.Height = &hashResp.Transaction.Height
+			}
+			continue
+		}
+		if err != api.ErrStatusNotFound {
+			cli.DieWithError("failed to get transaction "+txnID.String(), err)
+		}
+	}
+	return states
+}
+
+func (walletCmd *mintingWalletCmd) publishCoinCreationBatchCmd(cmd *cobra.Command, args []string) {
+	bundle := readCoinCreationBundle(args[0])
+	batchNumber := walletCmd.coinCreationBatchCfg.Batch
+	if batchNumber < 0 || batchNumber > len(bundle.Batches) {
+		cmd.UsageFunc()(cmd)
+		cli.Die(fmt.Sprintf("invalid batch number %d: the bundle defines %d batch(es)", batchNumber, len(bundle.Batches)))
+	}
+	states := walletCmd.coinCreationTransactionStates(bundle)
+
+	// ensure all transactions to be published are signed, prior to publishing any of them
+	for batchIdx, batch := range bundle.Batches {
+		if batchNumber != 0 && batchIdx+1 != batchNumber {
+			continue
+		}
+		for txnIdx := range batch.Transactions {
+			if states[batchIdx][txnIdx].ID == nil {
+				cli.Die(fmt.Sprintf("transaction #%d of batch #%d is missing signatures of: %s", txnIdx+1, batchIdx+1,
+					formatUnlockHashes(batch.Transactions[txnIdx].MissingSigners())))
+			}
+		}
+	}
+
+	published := 0
+	for batchIdx, batch := range bundle.Batches {
+		if batchNumber != 0 && batchIdx+1 != batchNumber {
+			continue
+		}
+		for txnIdx, pst := range batch.Transactions {
+			if state := states[batchIdx][txnIdx]; state.Pending || state.Confirmed {
+				continue // already published
+			}
+			b, err := json.Marshal(pst)
+			if err != nil {
+				cli.Die("Failed to JSON Marshal the partially signed transaction:", err)
+			}
+			var resp gcapi.WalletPSTFinalizePOSTResp
+			err = walletCmd.cli.PostWithResponse("/wallet/pst/finalize", string(b), &resp)
+			if err != nil {
+				cli.DieWithError(fmt.Sprintf("Failed to publish transaction #%d of batch #%d:", txnIdx+1, batchIdx+1), err)
+			}
+			fmt.Printf("Published transaction #%d of batch #%d as %s\n", txnIdx+1, batchIdx+1, resp.TransactionID.String())
+			published++
+		}
+	}
+	if published == 0 {
+		fmt.Println("All transactions are already published")
+	}
+}
+
+func (walletCmd *mintingWalletCmd) coinCreationBatchStatusCmd(cmd *cobra.Command, args []string) {
+	bundle := readCoinCreationBundle(args[0])
+	states := walletCmd.coinCreationTransactionStates(bundle)
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "batch\ttransaction\tid\toutputs\tvalue\tstate")
+	confirmedBatches := 0
+	for batchIdx, batch := range bundle.Batches {
+		confirmed := 0
+		for txnIdx, pst := range batch.Transactions {
+			state := states[batchIdx][txnIdx]
+			if state.Confirmed {
+				confirmed++
+			}
+			id := "-"
+			if state.ID != nil {
+				id = state.ID.String()
+			}
+			var value types.Currency
+			for _, co := range pst.Transaction.CoinOutputs {
+				value = value.Add(co.Value)
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%d\t%s\t%s\n", batchIdx+1, txnIdx+1, id,
+				len(pst.Transaction.CoinOutputs), currencyConvertor.ToCoinStringWithUnit(value), state.String())
+		}
+		if confirmed == len(batch.Transactions) {
+			confirmedBatches++
+		}
+	}
+	w.Flush()
+	fmt.Printf("\n%d of %d batch(es) confirmed\n", confirmedBatches, len(bundle.Batches))
+}
+
+// formatUnlockHashes formats the given addresses as a comma-separated list.
+func formatUnlockHashes(uhs []types.UnlockHash) string {
+	strs := make([]string, 0, len(uhs))
+	for _, uh := range uhs {
+		strs = append(strs, uh.String())
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/extensions/minting"
+	"github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	"github.com/nbh-digital/goldchain/pkg/config"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// newTestMultiSigCondition creates a multisig condition of the given amount of co-signers,
+// as well as the fulfillment of that condition once signed by all co-signers.
+func newTestMultiSigCondition(t *testing.T, cosigners int) (types.UnlockConditionProxy, types.UnlockFulfillmentProxy) {
+	var (
+		uhs         types.UnlockHashSlice
+		fulfillment types.MultiSignatureFulfillment
+	)
+	for i := 0; i < cosigners; i++ {
+		sk, pk := crypto.GenerateKeyPair()
+		uh, err := types.NewPubKeyUnlockHash(types.Ed25519PublicKey(pk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		uhs = append(uhs, uh)
+		signature := crypto.SignHash(crypto.Hash{byte(i)}, sk)
+		fulfillment.Pairs = append(fulfillment.Pairs, types.PublicKeySignaturePair{
+			PublicKey: types.Ed25519PublicKey(pk),
+			Signature: signature[:],
+		})
+	}
+	return types.NewCondition(types.NewMultiSignatureCondition(uhs, uint64(cosigners-1))), types.NewFulfillment(&fulfillment)
+}
+
+// signedTransactionSize returns the size of the given transaction as it is validated against the transaction size limit.
+func signedTransactionSize(t *testing.T, txn types.Transaction) int {
+	b, err := siabin.Marshal(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(b)
+}
+
+// TestCoinCreationBatchTransactions checks that the recipients parsed from a coin creation CSV file
+// are split, in order, over coin creation transactions which fit within the transaction size limit
+// once signed by all co-signers of the mint condition, grouped into batches which fit within the batch size limit.
+func TestCoinCreationBatchTransactions(t *testing.T) {
+	chainCts := config.GetDefaultGenesis()
+	currencyConvertor := client.NewCurrencyConvertor(chainCts.CurrencyUnits, "GFT")
+	var csv strings.Builder
+	csv.WriteString("recipient,amount\n# monthly payout\n")
+	var expected []types.CoinOutput
+	for i := 0; i < 2000; i++ {
+		var uh types.UnlockHash
+		uh.Type = types.UnlockTypePubKey
+		uh.Hash[0], uh.Hash[1] = byte(i), byte(i>>8)
+		expected = append(expected, types.CoinOutput{
+			Value:     chainCts.CurrencyUnits.OneCoin.Mul64(uint64(i + 1)),
+			Condition: types.NewCondition(types.NewUnlockHashCondition(uh)),
+		})
+		fmt.Fprintf(&csv, "%s,%d\n", uh.String(), i+1)
+	}
+	recipients, err := parseCoinCreationCSV(strings.NewReader(csv.String()), currencyConvertor.ParseCoinString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != len(expected) || recipients[0].Line != 3 {
+		t.Fatalf("unexpected recipients: %d recipients, first at line %d", len(recipients), recipients[0].Line)
+	}
+	outputs := make([]types.CoinOutput, 0, len(recipients))
+	for _, recipient := range recipients {
+		outputs = append(outputs, recipient.Output)
+	}
+
+	// the transaction version is registered by the client binary
+	types.RegisterTransactionVersion(gctypes.TransactionVersionCoinCreation, minting.CoinCreationTransactionController{
+		TransactionVersion: gctypes.TransactionVersionCoinCreation,
+	})
+	walletCmd := &mintingWalletCmd{coinCreationTxVersion: gctypes.TransactionVersionCoinCreation}
+	walletCmd.coinCreationBatchCfg.Description = []byte("monthly payout")
+	mintCondition, mintFulfillment := newTestMultiSigCondition(t, 5)
+	limits := gcmodules.CoinCreationBatchLimits{
+		TransactionSizeLimit: chainCts.TransactionPool.TransactionSizeLimit,
+		// a batch limit lower than the block size limit, such that multiple batches are created
+		BatchSizeLimit: chainCts.TransactionPool.TransactionSizeLimit * 3,
+	}
+	batches, err := walletCmd.coinCreationBatchTransactions(outputs, mintCondition, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) < 2 {
+		t.Fatalf("expected the transactions to be grouped into multiple batches, got %d", len(batches))
+	}
+
+	var offset int
+	for batchIdx, batch := range batches {
+		if len(batch) == 0 {
+			t.Fatalf("batch #%d is empty", batchIdx+1)
+		}
+		var batchSize int
+		for txnIdx, tx := range batch {
+			for _, co := range tx.CoinOutputs {
+				if offset >= len(expected) || !co.Value.Equals(expected[offset].Value) || !co.Condition.Equal(expected[offset].Condition) {
+					t.Fatalf("unexpected coin output %v at index %d", co, offset)
+				}
+				offset++
+			}
+			if string(tx.ArbitraryData) != "monthly payout" {
+				t.Errorf("unexpected arbitrary data of transaction #%d of batch #%d", txnIdx+1, batchIdx+1)
+			}
+			if tx.MintFulfillment.Fulfillment != nil {
+				t.Errorf("transaction #%d of batch #%d is returned signed", txnIdx+1, batchIdx+1)
+			}
+
+			// each transaction fits once signed, while it would not fit with an additional coin output
+			tx.MintFulfillment = mintFulfillment
+			size := signedTransactionSize(t, tx.Transaction(walletCmd.coinCreationTxVersion))
+			if size > limits.TransactionSizeLimit {
+				t.Errorf("signed transaction #%d of batch #%d has size %d, exceeding the limit of %d", txnIdx+1, batchIdx+1, size, limits.TransactionSizeLimit)
+			}
+			batchSize += size
+			if offset == len(expected) {
+				continue
+			}
+			tx.CoinOutputs = append(append([]types.CoinOutput(nil), tx.CoinOutputs...), expected[offset])
+			if size := signedTransactionSize(t, tx.Transaction(walletCmd.coinCreationTxVersion)); size <= limits.TransactionSizeLimit {
+				t.Errorf("transaction #%d of batch #%d is not filled as much as possible", txnIdx+1, batchIdx+1)
+			}
+		}
+		if batchSize > limits.BatchSizeLimit {
+			t.Errorf("signed batch #%d has size %d, exceeding the limit of %d", batchIdx+1, batchSize, limits.BatchSizeLimit)
+		}
+	}
+	if offset != len(expected) {
+		t.Errorf("%d of %d coin outputs are part of a transaction", offset, len(expected))
+	}
+}
+
+// TestParseCoinCreationCSV checks that invalid coin creation CSV files are refused.
+func TestParseCoinCreationCSV(t *testing.T) {
+	currencyConvertor := client.NewCurrencyConvertor(config.GetDefaultGenesis().CurrencyUnits, "GFT")
+	var uh types.UnlockHash
+	uh.Type = types.UnlockTypePubKey
+	for _, invalid := range []string{
+		"",
+		"recipient,amount\n",
+		uh.String() + ",0\n",
+		uh.String() + ",1," + uh.String() + "\n",
+		uh.String() + ",1\n" + uh.String() + ",foo\n",
+		"foo,1\n",
+	} {
+		if _, err := parseCoinCreationCSV(strings.NewReader(invalid), currencyConvertor.ParseCoinString); err == nil {
+			t.Errorf("parsed invalid CSV %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	rdtypes "github.com/nbh-digital/goldchain/extensions/redemption/types"
+)
+
+// redemptionCfg is the configuration of the redemption
+// that can be attached to coin destruction transactions.
+type redemptionCfg struct {
+	Reference      string
+	CustomerID     string
+	CustomerIDKey  string
+	CustomerIDHash string
+	DeliveryVault  string
+}
+
+func registerRedemptionFlags(flags *pflag.FlagSet, cfg *redemptionCfg) {
+	flags.StringVar(&cfg.Reference, "redemption-reference", "",
+		"attach a redemption for the given (unique) reference, requires a customer ID (hash) and delivery vault")
+	flags.StringVar(&cfg.CustomerID, "customer-id", "",
+		"ID of the customer who requested the redemption, only its keyed hash is attached to the transaction")
+	flags.StringVar(&cfg.CustomerIDKey, "customer-id-key-file", "",
+		"file containing the hex-encoded secret issuer key used to hash the customer ID, required by --customer-id")
+	flags.StringVar(&cfg.CustomerIDHash, "customer-id-hash", "",
+		"keyed hash of the ID of the customer who requested the redemption, as an alternative to --customer-id")
+	flags.StringVar(&cfg.DeliveryVault, "delivery-vault", "",
+		"vault from which the redeemed gold is to be delivered")
+}
+
+// defined returns true if any of the redemption flags is used.
+func (cfg *redemptionCfg) defined() bool {
+	return cfg.Reference != "" || cfg.CustomerID != "" || cfg.CustomerIDKey != "" || cfg.CustomerIDHash != "" || cfg.DeliveryVault != ""
+}
+
+// arbitraryData returns the encoded redemption.
+func (cfg *redemptionCfg) arbitraryData() ([]byte, error) {
+	redemption := rdtypes.Redemption{
+		Reference:     cfg.Reference,
+		DeliveryVault: cfg.DeliveryVault,
+	}
+	switch {
+	case cfg.CustomerID != "" && cfg.CustomerIDHash != "":
+		return nil, errors.New("a customer ID cannot be combined with a customer ID hash")
+	case cfg.CustomerID != "":
+		if cfg.CustomerIDKey == "" {
+			return nil, errors.New("a customer ID requires the issuer key used to hash it (--customer-id-key-file)")
+		}
+		key, err := readIssuerKey(cfg.CustomerIDKey)
+		if err != nil {
+			return nil, err
+		}
+		redemption.CustomerIDHash, err = rdtypes.HashCustomerID(key, cfg.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.CustomerIDKey != "":
+		return nil, errors.New("an issuer key can only be used in combination with a customer ID")
+	case cfg.CustomerIDHash != "":
+		err := redemption.CustomerIDHash.LoadString(cfg.CustomerIDHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid customer ID hash: %v", err)
+		}
+	}
+	err := redemption.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return redemption.MarshalArbitraryData()
+}
+
+// readIssuerKey reads the hex-encoded secret issuer key from the given file,
+// such that the key does not have to be passed on the command line.
+func readIssuerKey(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer key: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer key: %v", err)
+	}
+	return key, nil
+}
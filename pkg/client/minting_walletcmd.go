@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -24,11 +25,9 @@ func CreateMintingWalletCmds(ccli *client.CommandLineClient) error {
 	walletCmd := &mintingWalletCmd{
 		cli:                        ccli,
 		walletClient:               NewWalletClient(bc),
-		txPoolClient:               client.NewTransactionPoolClient(bc),
 		mintingDefinitionTxVersion: gctypes.TransactionVersionMinterDefinition,
 		coinCreationTxVersion:      gctypes.TransactionVersionCoinCreation,
 		requireMinerFees:           false,
-		coinDestructionTxVersion:   gctypes.TransactionVersionCoinDestruction,
 	}
 	// create root explore command and all subs
 	var (
@@ -55,15 +54,97 @@ Decimals are possible and have to be defined using the decimal point.
 
 The Minimum Miner Fee will be added on top of the total given amount automatically.
 
+A proof-of-reserve attestation of the vaulted gold backing the created coins
+can be attached using the --vault, --bar-serial, --weight and --assayer-signature-hash flags,
+in which case no description can be given.
+
 The returned (raw) CoinCreationTransaction still has to be signed, prior to sending.
 	`,
 			Run: walletCmd.createCoinCreationTxCmd,
 		}
+		createCoinCreationBatchCmd = &cobra.Command{
+			Use:   "coincreationbatch <csv>|-",
+			Short: "Create a bundle of coin creation transactions for a CSV of recipients",
+			Long: `Create a signing bundle of coin creation transactions, creating coins
+for all recipients defined in the given CSV file (or STDIN if '-' is given).
+
+Each line of the CSV file defines an address (or raw output condition) and an amount,
+expressed in the OneCoin unit and without the unit of currency. The first line is
+skipped as a header if its amount cannot be parsed, lines starting with '#' are ignored.
+
+All recipients have to be authorized to receive coins, unless the --allow-unauthorized flag is given.
+
+The recipients are split over as few transactions as possible, each transaction fitting within the
+transaction size limit once signed by all co-signers of the mint condition. The transactions are
+grouped in batches, each batch fitting in a single block.
+
+The returned bundle wraps each transaction in a partially signed transaction, and still
+has to be signed using 'wallet coincreationbatch sign', prior to being published.
+	`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.createCoinCreationBatchCmd,
+		}
+		coinCreationBatchCmd = &cobra.Command{
+			Use:   "coincreationbatch",
+			Short: "Sign, publish and track a bundle of coin creation transactions",
+			Long: `Sign, publish and track a bundle of coin creation transactions,
+as created using 'wallet create coincreationbatch'.
+
+Bundles are given and returned as JSON. Each <bundle> argument
+can be given as raw JSON or as the path of a file containing the JSON.
+`,
+			// Run field is not set, as the coincreationbatch command itself is not a valid command.
+		}
+		signCoinCreationBatchCmd = &cobra.Command{
+			Use:   "sign <bundle>",
+			Short: "Sign all transactions of a coin creation bundle",
+			Long: `Sign all transactions of a coin creation bundle, using the keys of this wallet.
+The signed bundle is printed to STDOUT, its status to STDERR.`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.signCoinCreationBatchCmd,
+		}
+		combineCoinCreationBatchCmd = &cobra.Command{
+			Use:   "combine <bundle> <bundle> [<bundle>]...",
+			Short: "Combine the signatures of coin creation bundles",
+			Long: `Combine the signatures of coin creation bundles, each signed by one or multiple co-signers,
+into a single bundle. All bundles have to be created for the same transactions.`,
+			Args: cobra.MinimumNArgs(2),
+			Run:  walletCmd.combineCoinCreationBatchCmd,
+		}
+		publishCoinCreationBatchCmd = &cobra.Command{
+			Use:   "publish <bundle>",
+			Short: "Publish the transactions of a signed coin creation bundle",
+			Long: `Publish the transactions of a signed coin creation bundle, skipping transactions already published.
+All batches are published, unless a single batch is selected using the --batch flag.`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.publishCoinCreationBatchCmd,
+		}
+		coinCreationBatchStatusCmd = &cobra.Command{
+			Use:   "status <bundle>",
+			Short: "Report which transactions and batches of a coin creation bundle are confirmed",
+			Args:  cobra.ExactArgs(1),
+			Run:   walletCmd.coinCreationBatchStatusCmd,
+		}
 		burnCoinsCmd = &cobra.Command{
 			Use:   "coins <amount>",
 			Short: "burn the given amount of coins",
-			Args:  cobra.ExactArgs(1),
-			Run:   walletCmd.burnCoinsCmd,
+			Long: `Burn the given amount of coins, destroying them in a coin destruction transaction.
+
+A proof-of-reserve attestation of the vaulted gold released by the burned coins
+can be attached using the --vault, --bar-serial, --weight and --assayer-signature-hash flags,
+in which case no description can be given.
+
+A redemption request of physical gold can be attached instead, using the
+--redemption-reference, --customer-id (or --customer-id-hash) and --delivery-vault flags,
+such that the burn can be matched to the request it redeems. The customer ID is hashed
+using the secret issuer key found in the file given by the --customer-id-key-file flag.
+Each redemption reference can only be redeemed once.
+
+The custody fee of the spent coins and the miner fee are paid on top of the given amount,
+such that exactly the given amount of coins is burned.
+`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.burnCoinsCmd,
 		}
 	)
 
@@ -71,6 +152,14 @@ The returned (raw) CoinCreationTransaction still has to be signed, prior to send
 	ccli.WalletCmd.RootCmdCreate.AddCommand(
 		createMinterDefinitionTxCmd,
 		createCoinCreationTxCmd,
+		createCoinCreationBatchCmd,
+	)
+	ccli.WalletCmd.AddCommand(coinCreationBatchCmd)
+	coinCreationBatchCmd.AddCommand(
+		signCoinCreationBatchCmd,
+		combineCoinCreationBatchCmd,
+		publishCoinCreationBatchCmd,
+		coinCreationBatchStatusCmd,
 	)
 
 	// client.ExploreCmd.AddCommand(getMintConditionCmd)
@@ -78,10 +167,21 @@ The returned (raw) CoinCreationTransaction still has to be signed, prior to send
 		"description", "optionally add a description to describe the reasons of transfer of minting power, added as arbitrary data")
 	cli.ArbitraryDataFlagVar(createCoinCreationTxCmd.Flags(), &walletCmd.coinCreationTxCfg.Description,
 		"description", "optionally add a description to describe the origins of the coin creation, added as arbitrary data")
+	registerAttestationFlags(createCoinCreationTxCmd.Flags(), &walletCmd.coinCreationTxCfg.Attestation)
+	cli.ArbitraryDataFlagVar(createCoinCreationBatchCmd.Flags(), &walletCmd.coinCreationBatchCfg.Description,
+		"description", "optionally add a description to describe the origins of the coin creation, added as arbitrary data to each transaction")
+	createCoinCreationBatchCmd.Flags().BoolVar(
+		&walletCmd.coinCreationBatchCfg.AllowUnauthorized, "allow-unauthorized", false,
+		"create the bundle even if some recipients are not authorized to receive coins")
+	publishCoinCreationBatchCmd.Flags().IntVar(
+		&walletCmd.coinCreationBatchCfg.Batch, "batch", 0,
+		"only publish the transactions of the given batch number, starting from 1")
 
 	// set the flags
 	cli.ArbitraryDataFlagVar(burnCoinsCmd.Flags(), &walletCmd.coinDestructionTxCfg.Description,
 		"description", "optionally add a description to describe the reasons of transfer of minting power, added as arbitrary data")
+	registerAttestationFlags(burnCoinsCmd.Flags(), &walletCmd.coinDestructionTxCfg.Attestation)
+	registerRedemptionFlags(burnCoinsCmd.Flags(), &walletCmd.coinDestructionTxCfg.Redemption)
 	burnCoinsCmd.Flags().StringVar(
 		&walletCmd.coinDestructionTxCfg.RefundAddress,
 		"refund-address", "", "define a custom refund address")
@@ -113,7 +213,6 @@ The returned (raw) CoinCreationTransaction still has to be signed, prior to send
 type mintingWalletCmd struct {
 	cli          *client.CommandLineClient
 	walletClient *WalletClient
-	txPoolClient *client.TransactionPoolClient
 
 	mintingDefinitionTxVersion, coinCreationTxVersion types.TransactionVersion
 	minterDefinitionTxCfg                             struct {
@@ -121,11 +220,18 @@ type mintingWalletCmd struct {
 	}
 	coinCreationTxCfg struct {
 		Description []byte
+		Attestation attestationCfg
+	}
+	coinCreationBatchCfg struct {
+		Description       []byte
+		AllowUnauthorized bool
+		Batch             int
 	}
 
-	coinDestructionTxVersion types.TransactionVersion
-	coinDestructionTxCfg     struct {
+	coinDestructionTxCfg struct {
 		Description      []byte
+		Attestation      attestationCfg
+		Redemption       redemptionCfg
 		RefundAddress    string
 		RefundAddressNew bool
 	}
@@ -187,9 +293,14 @@ func (walletCmd *mintingWalletCmd) createCoinCreationTxCmd(cmd *cobra.Command, a
 		tx.MinerFees = []types.Currency{walletCmd.cli.Config.MinimumTransactionFee}
 	}
 
-	if n := len(walletCmd.coinCreationTxCfg.Description); n > 0 {
+	arbitraryData, err := walletCmd.coinCreationTxCfg.Attestation.arbitraryData(walletCmd.coinCreationTxCfg.Description)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
+	if n := len(arbitraryData); n > 0 {
 		tx.ArbitraryData = make([]byte, n)
-		copy(tx.ArbitraryData[:], walletCmd.coinCreationTxCfg.Description[:])
+		copy(tx.ArbitraryData[:], arbitraryData[:])
 	}
 
 	for _, pair := range pairs {
@@ -211,8 +322,24 @@ func (walletCmd *mintingWalletCmd) burnCoinsCmd(cmd *cobra.Command, args []strin
 		cmd.UsageFunc()(cmd)
 		cli.Die(err)
 	}
-	// add the minimum miner fee
-	amount = amount.Add(walletCmd.cli.Config.MinimumTransactionFee)
+
+	// define the arbitrary data, validating the optional attestation or redemption prior to funding the transaction
+	var arbitraryData []byte
+	if walletCmd.coinDestructionTxCfg.Redemption.defined() {
+		if len(walletCmd.coinDestructionTxCfg.Description) > 0 {
+			err = errors.New("a description cannot be combined with a redemption")
+		} else if walletCmd.coinDestructionTxCfg.Attestation.defined() {
+			err = errors.New("a proof-of-reserve attestation cannot be combined with a redemption")
+		} else {
+			arbitraryData, err = walletCmd.coinDestructionTxCfg.Redemption.arbitraryData()
+		}
+	} else {
+		arbitraryData, err = walletCmd.coinDestructionTxCfg.Attestation.arbitraryData(walletCmd.coinDestructionTxCfg.Description)
+	}
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
 
 	// define the optional user-defined refund address
 	var refundAddress *types.UnlockHash
@@ -224,39 +351,13 @@ func (walletCmd *mintingWalletCmd) burnCoinsCmd(cmd *cobra.Command, args []strin
 			cli.Die(err)
 		}
 	}
-	// fund the burn Tx
-	coinInputs, custodyFeeCondition, refundCoinOutput, err := walletCmd.walletClient.FundCoins(amount, refundAddress, walletCmd.coinDestructionTxCfg.RefundAddressNew)
-	if err != nil {
-		cli.DieWithError("failed to fund burn transaction", err)
-	}
-
-	// assemble the transaction
-	cdTx := minting.CoinDestructionTransaction{
-		CoinInputs: coinInputs,
-		CoinOutputs: []types.CoinOutput{
-			custodyFeeCondition,
-		},
-		MinerFees: []types.Currency{walletCmd.cli.Config.MinimumTransactionFee},
-	}
-	if refundCoinOutput != nil {
-		cdTx.CoinOutputs = append(cdTx.CoinOutputs, *refundCoinOutput)
-	}
-	if n := len(walletCmd.coinCreationTxCfg.Description); n > 0 {
-		cdTx.ArbitraryData = make([]byte, n)
-		copy(cdTx.ArbitraryData[:], walletCmd.coinCreationTxCfg.Description[:])
-	}
-
-	// sign the transaction
-	tx := cdTx.Transaction(walletCmd.coinDestructionTxVersion)
-	err = walletCmd.walletClient.GreedySignTx(&tx)
-	if err != nil {
-		cli.DieWithError("failed to sign burn transaction", err)
-	}
 
-	// send the transaction
-	txID, err := walletCmd.txPoolClient.AddTransactiom(tx)
+	// fund, sign and send the burn Tx, all within the wallet,
+	// such that the custody fee is paid on top of the burned amount,
+	// and the coin inputs remain reserved until the transaction is accepted by the transaction pool
+	txID, err := walletCmd.walletClient.BurnCoins(amount, arbitraryData, refundAddress, walletCmd.coinDestructionTxCfg.RefundAddressNew)
 	if err != nil {
-		cli.DieWithError("failed to send burn transaction", err)
+		cli.DieWithError("failed to burn coins", err)
 	}
 
 	// print transaction ID
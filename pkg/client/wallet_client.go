@@ -54,6 +54,26 @@ func (wallet *WalletClient) FundCoins(amount types.Currency, refundAddress *type
 	return result.CoinInputs, result.CustodyFeeCondition, result.RefundCoinOutput, nil
 }
 
+// BurnCoins burns the given amount of coins in a coin destruction transaction, funded and signed by this daemon's wallet,
+// with the custody fee and miner fee paid on top of the burned amount, optionally returning the refund to the given address.
+func (wallet *WalletClient) BurnCoins(amount types.Currency, data []byte, refundAddress *types.UnlockHash, newRefundAddress bool) (types.TransactionID, error) {
+	b, err := json.Marshal(gcapi.WalletBurnPOST{
+		Amount:           amount,
+		Data:             data,
+		RefundAddress:    refundAddress,
+		RefundAddressNew: newRefundAddress,
+	})
+	if err != nil {
+		return types.TransactionID{}, err
+	}
+	var result gcapi.WalletBurnPOSTResp
+	err = wallet.bc.HTTP().PostWithResponse("/wallet/burn", string(b), &result)
+	if err != nil {
+		return types.TransactionID{}, fmt.Errorf("failed to burn coins: %v", err)
+	}
+	return result.TransactionID, nil
+}
+
 // GreedySignTx signs the given transactions greedy,
 // meaning that all fulfillments that can be signed, will be signed.
 func (wallet *WalletClient) GreedySignTx(t *types.Transaction) error {
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	authcointxcli "github.com/threefoldtech/rivine/extensions/authcointx/client"
+	"github.com/threefoldtech/rivine/pkg/cli"
+	clientpkg "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/types"
+
+	aeclient "github.com/nbh-digital/goldchain/extensions/authexpiry/client"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+	gctypes "github.com/nbh-digital/goldchain/pkg/types"
+)
+
+// the authorization states reported for the entries of the address book
+const (
+	addressAuthStateAuthorized   = "authorized"
+	addressAuthStateUnauthorized = "unauthorized"
+	addressAuthStateNotRequired  = "not required"
+	addressAuthStateUnknown      = "unknown"
+)
+
+// getAddressesAuthStates returns, for each of the given addresses that requires authorization,
+// whether or not it is currently authorized according to the auth coin state of the consensus set.
+// An address of which the authorization is expired is not authorized, as the network refuses
+// coin transactions involving such an address, even though it is still authorized according to the auth coin state.
+func getAddressesAuthStates(ccli *clientpkg.CommandLineClient, addresses []types.UnlockHash) (map[types.UnlockHash]bool, error) {
+	var required []types.UnlockHash
+	for _, uh := range addresses {
+		if gctypes.AuthCoinUnlockHashFilter(uh) {
+			required = append(required, uh)
+		}
+	}
+	if len(required) == 0 {
+		return nil, nil
+	}
+	bc, err := clientpkg.NewLazyBaseClientFromCommandLineClient(ccli)
+	if err != nil {
+		return nil, err
+	}
+	states, err := authcointxcli.NewPluginConsensusClient(bc).GetAddressesAuthStateNow(required, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) != len(required) {
+		return nil, fmt.Errorf("expected %d authorization states, received %d", len(required), len(states))
+	}
+	var authorized []types.UnlockHash
+	result := make(map[types.UnlockHash]bool, len(required))
+	for idx, uh := range required {
+		result[uh] = states[idx]
+		if states[idx] {
+			authorized = append(authorized, uh)
+		}
+	}
+	if len(authorized) == 0 {
+		return result, nil
+	}
+	expiries, err := aeclient.NewPluginConsensusClient(bc).GetExpiredAddresses(authorized)
+	if err != nil {
+		return nil, err
+	}
+	for _, expiry := range expiries.Expiries {
+		if _, ok := result[expiry.Address]; ok && expiry.Expired {
+			result[expiry.Address] = false
+		}
+	}
+	return result, nil
+}
+
+// addressBookCmd lists the counterparty addresses labeled by the wallet,
+// as well as whether or not they are currently authorized to send and receive coins.
+func (walletCmd *walletCmd) addressBookCmd() {
+	var resp gcapi.WalletLabelsGET
+	err := walletCmd.cli.GetWithResponse("/wallet/labels", &resp)
+	if err != nil {
+		cli.DieWithError("Could not get address labels:", err)
+	}
+	var (
+		entries   []gcmodules.AddressLabel
+		addresses []types.UnlockHash
+	)
+	for _, label := range resp.Labels {
+		if label.Owned {
+			continue
+		}
+		entries = append(entries, label)
+		addresses = append(addresses, label.Address)
+	}
+	if len(entries) == 0 {
+		fmt.Println("This wallet has no counterparty addresses in its address book.")
+		return
+	}
+
+	states, err := getAddressesAuthStates(walletCmd.cli, addresses)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not get the authorization state of the address book entries:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "address\tlabel\ttags\tauthorization")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Address.String(), entry.Label, strings.Join(entry.Tags, ","), addressAuthState(entry.Address, states))
+	}
+	w.Flush()
+}
+
+// addressAuthState returns the authorization state reported for the given address,
+// using the authorization states as returned by getAddressesAuthStates.
+func addressAuthState(uh types.UnlockHash, states map[types.UnlockHash]bool) string {
+	if !gctypes.AuthCoinUnlockHashFilter(uh) {
+		return addressAuthStateNotRequired
+	}
+	authorized, ok := states[uh]
+	switch {
+	case !ok:
+		return addressAuthStateUnknown
+	case authorized:
+		return addressAuthStateAuthorized
+	default:
+		return addressAuthStateUnauthorized
+	}
+}
+
+// unauthorizedAddresses returns the unique addresses, in order, which are unauthorized
+// according to the authorization states as returned by getAddressesAuthStates.
+func unauthorizedAddresses(addresses []types.UnlockHash, states map[types.UnlockHash]bool) []types.UnlockHash {
+	var (
+		unauthorized []types.UnlockHash
+		seen         = make(map[types.UnlockHash]struct{})
+	)
+	for _, uh := range addresses {
+		if authorized, ok := states[uh]; !ok || authorized {
+			continue
+		}
+		if _, ok := seen[uh]; ok {
+			continue // report each address only once
+		}
+		seen[uh] = struct{}{}
+		unauthorized = append(unauthorized, uh)
+	}
+	return unauthorized
+}
+
+// checkCoinOutputsAuthorization ensures that all addresses receiving coins, as defined by the given coin outputs
+// and optional refund address, are authorized, prior to the wallet building the coin transaction.
+// The command dies if any address is unauthorized, unless explicitly allowed, in which case only a warning is printed.
+func (walletCmd *walletCmd) checkCoinOutputsAuthorization(coinOutputs []types.CoinOutput, refundAddress *types.UnlockHash, allowUnauthorized bool) {
+	addresses := make([]types.UnlockHash, 0, len(coinOutputs)+1)
+	for _, co := range coinOutputs {
+		addresses = append(addresses, co.Condition.UnlockHash())
+	}
+	if refundAddress != nil {
+		addresses = append(addresses, *refundAddress)
+	}
+	states, err := getAddressesAuthStates(walletCmd.cli, addresses)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not check the authorization of the receiving addresses:", err)
+		return
+	}
+
+	uhs := unauthorizedAddresses(addresses, states)
+	if len(uhs) == 0 {
+		return
+	}
+	labels := walletCmd.fetchAddressLabels()
+	unauthorized := make([]string, 0, len(uhs))
+	for _, uh := range uhs {
+		unauthorized = append(unauthorized, "  "+labels.Annotate(uh))
+	}
+	msg := fmt.Sprintf(`The following address(es) are not authorized to receive coins:
+%s
+The network refuses coin transactions involving unauthorized addresses, unless
+%s,
+which is never the case for coins sent by this wallet to another address.
+`, strings.Join(unauthorized, "\n"), gctypes.UnauthorizedCoinTransactionExceptionRule)
+	if allowUnauthorized {
+		fmt.Fprint(os.Stderr, "Warning: "+msg)
+		return
+	}
+	cli.Die(msg + "Use the --allow-unauthorized flag to send the transaction regardless.")
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	authcointxapi "github.com/threefoldtech/rivine/extensions/authcointx/api"
+	"github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/nbh-digital/goldchain/extensions/authexpiry"
+	aeapi "github.com/nbh-digital/goldchain/extensions/authexpiry/api"
+	aetypes "github.com/nbh-digital/goldchain/extensions/authexpiry/types"
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+)
+
+// authCoinStatusServer serves the authorization state of addresses, as the daemon does on /consensus/authcoin/status,
+// as well as their expired authorizations, as the daemon does on /consensus/authexpiries/expired,
+// recording the addresses of which the state and expiry are requested.
+type authCoinStatusServer struct {
+	*httptest.Server
+
+	mu                sync.Mutex
+	authorized        map[types.UnlockHash]bool
+	expired           map[types.UnlockHash]bool
+	requested         [][]types.UnlockHash
+	requestedExpiries [][]types.UnlockHash
+}
+
+func newAuthCoinStatusServer(t *testing.T, authorized, expired map[types.UnlockHash]bool) *authCoinStatusServer {
+	s := &authCoinStatusServer{authorized: authorized, expired: expired}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var uhs []types.UnlockHash
+		for _, str := range req.URL.Query()["addr"] {
+			var uh types.UnlockHash
+			if err := uh.LoadString(str); err != nil {
+				t.Errorf("invalid address %q requested: %v", str, err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			uhs = append(uhs, uh)
+		}
+		switch req.URL.Path {
+		case "/consensus/authcoin/status":
+			var resp authcointxapi.GetAddressesAuthStateResponse
+			for _, uh := range uhs {
+				resp.AuthStates = append(resp.AuthStates, s.authorized[uh])
+			}
+			s.mu.Lock()
+			s.requested = append(s.requested, uhs)
+			s.mu.Unlock()
+			json.NewEncoder(w).Encode(resp)
+		case "/consensus/authexpiries/expired":
+			resp := aeapi.AuthExpiriesGet{Expiries: []aeapi.AuthExpiry{}}
+			for _, uh := range uhs {
+				if s.expired[uh] {
+					resp.Expiries = append(resp.Expiries, aeapi.AuthExpiry{
+						ExpiryRecord: authexpiry.ExpiryRecord{Address: uh, Expiry: aetypes.Expiry{ValidUntilHeight: 1}},
+						Expired:      true,
+					})
+				}
+			}
+			s.mu.Lock()
+			s.requestedExpiries = append(s.requestedExpiries, uhs)
+			s.mu.Unlock()
+			json.NewEncoder(w).Encode(resp)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	return s
+}
+
+func (s *authCoinStatusServer) CommandLineClient() *client.CommandLineClient {
+	return &client.CommandLineClient{
+		HTTPClient: &api.HTTPClient{RootURL: s.URL},
+		Config:     &client.Config{},
+	}
+}
+
+func newTestUnlockHash(t types.UnlockType, b byte) types.UnlockHash {
+	uh := types.UnlockHash{Type: t}
+	uh.Hash[0] = b
+	return uh
+}
+
+// TestGetAddressesAuthStates checks that the authorization state is only requested
+// for the addresses which require authorization.
+func TestGetAddressesAuthStates(t *testing.T) {
+	var (
+		authorized   = newTestUnlockHash(types.UnlockTypePubKey, 1)
+		unauthorized = newTestUnlockHash(types.UnlockTypePubKey, 2)
+		multisig     = newTestUnlockHash(types.UnlockTypeMultiSig, 3)
+		atomicSwap   = newTestUnlockHash(types.UnlockTypeAtomicSwap, 4)
+		custodyFee   = cftypes.CustodyFeeUnlockHash
+		expired      = newTestUnlockHash(types.UnlockTypePubKey, 5)
+	)
+	server := newAuthCoinStatusServer(t,
+		map[types.UnlockHash]bool{authorized: true, multisig: true, expired: true},
+		map[types.UnlockHash]bool{expired: true, unauthorized: true})
+	defer server.Close()
+	ccli := server.CommandLineClient()
+
+	states, err := getAddressesAuthStates(ccli, []types.UnlockHash{atomicSwap, authorized, custodyFee, unauthorized, multisig, expired, types.NilUnlockHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an address of which the authorization is expired is not authorized
+	expected := map[types.UnlockHash]bool{authorized: true, unauthorized: false, multisig: true, expired: false}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("unexpected states %v, expected %v", states, expected)
+	}
+	if len(server.requested) != 1 || !reflect.DeepEqual(server.requested[0], []types.UnlockHash{authorized, unauthorized, multisig, expired}) {
+		t.Errorf("unexpected addresses requested: %v", server.requested)
+	}
+	// only the expiry of the authorized addresses is requested
+	if len(server.requestedExpiries) != 1 || !reflect.DeepEqual(server.requestedExpiries[0], []types.UnlockHash{authorized, multisig, expired}) {
+		t.Errorf("unexpected address expiries requested: %v", server.requestedExpiries)
+	}
+	if state := addressAuthState(expired, states); state != addressAuthStateUnauthorized {
+		t.Errorf("unexpected state %q for an address of which the authorization is expired", state)
+	}
+
+	// no request is made if none of the addresses require authorization
+	states, err = getAddressesAuthStates(ccli, []types.UnlockHash{atomicSwap, custodyFee, types.NilUnlockHash})
+	if err != nil || states != nil {
+		t.Errorf("unexpected states %v: %v", states, err)
+	}
+	if len(server.requested) != 1 {
+		t.Errorf("unexpected request for addresses which do not require authorization: %v", server.requested[1:])
+	}
+
+	// no expiries are requested if none of the addresses are authorized
+	states, err = getAddressesAuthStates(ccli, []types.UnlockHash{unauthorized})
+	if err != nil || !reflect.DeepEqual(states, map[types.UnlockHash]bool{unauthorized: false}) {
+		t.Errorf("unexpected states %v: %v", states, err)
+	}
+	if len(server.requestedExpiries) != 1 {
+		t.Errorf("unexpected expiry request for unauthorized addresses: %v", server.requestedExpiries[1:])
+	}
+
+	// an unreachable daemon is reported as an error, rather than assuming the addresses are (un)authorized
+	server.Close()
+	if states, err = getAddressesAuthStates(ccli, []types.UnlockHash{authorized}); err == nil {
+		t.Errorf("expected an error for an unreachable daemon, got states %v", states)
+	}
+}
+
+// TestAddressAuthState checks the authorization state reported for the entries of the address book.
+func TestAddressAuthState(t *testing.T) {
+	var (
+		authorized   = newTestUnlockHash(types.UnlockTypePubKey, 1)
+		unauthorized = newTestUnlockHash(types.UnlockTypePubKey, 2)
+		unknown      = newTestUnlockHash(types.UnlockTypePubKey, 3)
+		atomicSwap   = newTestUnlockHash(types.UnlockTypeAtomicSwap, 4)
+	)
+	states := map[types.UnlockHash]bool{authorized: true, unauthorized: false}
+	for uh, expected := range map[types.UnlockHash]string{
+		authorized:   addressAuthStateAuthorized,
+		unauthorized: addressAuthStateUnauthorized,
+		unknown:      addressAuthStateUnknown,
+		atomicSwap:   addressAuthStateNotRequired,
+	} {
+		if state := addressAuthState(uh, states); state != expected {
+			t.Errorf("address %s: unexpected state %q, expected %q", uh.String(), state, expected)
+		}
+	}
+	// the state of all addresses is unknown if it could not be fetched
+	if state := addressAuthState(authorized, nil); state != addressAuthStateUnknown {
+		t.Errorf("unexpected state %q without authorization states", state)
+	}
+}
+
+// TestUnauthorizedAddresses checks that the receiving addresses which are unauthorized are reported once, in order.
+func TestUnauthorizedAddresses(t *testing.T) {
+	var (
+		authorized = newTestUnlockHash(types.UnlockTypePubKey, 1)
+		first      = newTestUnlockHash(types.UnlockTypePubKey, 2)
+		second     = newTestUnlockHash(types.UnlockTypePubKey, 3)
+		atomicSwap = newTestUnlockHash(types.UnlockTypeAtomicSwap, 4)
+	)
+	states := map[types.UnlockHash]bool{authorized: true, first: false, second: false}
+	uhs := unauthorizedAddresses([]types.UnlockHash{second, authorized, atomicSwap, first, second}, states)
+	if expected := []types.UnlockHash{second, first}; !reflect.DeepEqual(uhs, expected) {
+		t.Errorf("unexpected unauthorized addresses %v, expected %v", uhs, expected)
+	}
+	if uhs = unauthorizedAddresses([]types.UnlockHash{authorized, atomicSwap}, states); len(uhs) != 0 {
+		t.Errorf("unexpected unauthorized addresses %v", uhs)
+	}
+}
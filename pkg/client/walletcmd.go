@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"net/url"
 	"os"
 	"strconv"
 	"text/tabwriter"
@@ -14,6 +15,7 @@ import (
 	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
 
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/pkg/api"
 	"github.com/threefoldtech/rivine/pkg/cli"
@@ -23,6 +25,7 @@ import (
 
 	gcmodules "github.com/nbh-digital/goldchain/modules"
 	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+	"github.com/nbh-digital/goldchain/pkg/hdkeys"
 )
 
 func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand {
@@ -64,8 +67,10 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 		recoverCmd = &cobra.Command{
 			Use:   "recover",
 			Short: "Recover a wallet",
-			Long:  `Recover a wallet from the given mnemonic, to be used as primary seed and by default encrypt it.`,
-			Run:   clientpkg.Wrap(walletCmd.recoverCmd),
+			Long: `Recover a wallet from the given mnemonic, to be used as primary seed and by default encrypt it.
+	All addresses used by the seed are discovered while scanning the blockchain,
+	as long as no more than the address gap limit of consecutive addresses are unused.`,
+			Run: clientpkg.Wrap(walletCmd.recoverCmd),
 		}
 		lockCmd = &cobra.Command{
 			Use:   "lock",
@@ -105,12 +110,40 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 	providing a net flow of coins and blockstakes for each transaction.`,
 			Run: clientpkg.Wrap(walletCmd.listTransactionsCmd),
 		}
+		exportCmd = &cobra.Command{
+			Use:   "export",
+			Short: "Export the wallet history for accounting purposes",
+			Long: `Export the coin movements of the wallet, one row per movement, for accounting purposes.
+	Each movement contains the timestamp, transaction id, counterparty address, gross amount,
+	custody fee paid per input, miner fee and the resulting running balance of the wallet.
+
+	The csv and ofx formats express all amounts in the OneCoin unit,
+	while the json format exports the movements as returned by the daemon.
+	`,
+			Run: clientpkg.Wrap(walletCmd.exportCmd),
+		}
+		statementCmd = &cobra.Command{
+			Use:   "statement",
+			Short: "View the wallet statement per period",
+			Long: `View the statement of the wallet per day or month, listing for each period the opening balance,
+	incoming and outgoing coins, custody and miner fees paid, custody fees accrued but unpaid
+	at the end of the period, and closing balance.
+	`,
+			Run: clientpkg.Wrap(walletCmd.statementCmd),
+		}
 		unlockCmd = &cobra.Command{
 			Use:   `unlock`,
 			Short: "Unlock the wallet",
 			Long:  "Decrypt and load the wallet into memory",
 			Run:   clientpkg.Wrap(walletCmd.unlockCmd),
 		}
+		changePasswordCmd = &cobra.Command{
+			Use:   "changepassword",
+			Short: "Change the wallet password",
+			Long: `Change the wallet password, re-encrypting all seeds of the wallet.
+	The wallet remains locked or unlocked, as it was prior to the password change.`,
+			Run: clientpkg.Wrap(walletCmd.changePasswordCmd),
+		}
 
 		loadCmd = &cobra.Command{
 			Use:   "load",
@@ -147,6 +180,14 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 	Decimals are possible and have to be defined using the decimal point.
 	
 	The Minimum Miner Fee will be added on top of the total given amount automatically.
+	
+	The receiving addresses are checked to be authorized prior to sending,
+	as coin transactions involving unauthorized addresses are refused by the network.
+	
+	Using --from-multisig the coins are sent from a multisig wallet this wallet is a co-signer of,
+	refunding any change back to that multisig wallet. The transaction is signed by this wallet,
+	and printed as a partially signed transaction to be signed by the other co-signers
+	(see the 'wallet pst' commands), unless no other signatures are required.
 	`,
 			Run: walletCmd.sendCoinsCmd,
 		}
@@ -241,6 +282,199 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 	`,
 			Run: walletCmd.createBlockStakeTxCmd,
 		}
+
+		watchOnlyCmd = &cobra.Command{
+			Use:   "watchonly",
+			Short: "Manage the watch-only addresses of the wallet",
+			// Run field is not set, as the watchonly command itself is not a valid command.
+			// A subcommand must be provided.
+		}
+		watchOnlyListCmd = &cobra.Command{
+			Use:   "list",
+			Short: "List the watch-only addresses and their balance",
+			Run:   clientpkg.Wrap(walletCmd.watchOnlyListCmd),
+		}
+		watchOnlyAddCmd = &cobra.Command{
+			Use:   "add <address>|<publickey> [<address>|<publickey>]...",
+			Short: "Add one or multiple watch-only addresses",
+			Long: `Add one or multiple watch-only addresses to the wallet,
+	identified either by their address or by their public key.
+	Only single signature and multisig addresses can be watched.
+	
+	Giving the public key of a single signature address allows
+	the watch-only transactions created by this wallet to already
+	contain that public key, such that an offline signer only has to sign.
+	
+	Adding a new address triggers a rescan of the blockchain.
+	`,
+			Args: cobra.MinimumNArgs(1),
+			Run:  walletCmd.watchOnlyAddCmd,
+		}
+		watchOnlyRemoveCmd = &cobra.Command{
+			Use:   "remove <address> [<address>]...",
+			Short: "Remove one or multiple watch-only addresses",
+			Args:  cobra.MinimumNArgs(1),
+			Run:   walletCmd.watchOnlyRemoveCmd,
+		}
+		watchOnlyTransactionsCmd = &cobra.Command{
+			Use:   "transactions",
+			Short: "List the transactions related to the watch-only addresses",
+			Run:   clientpkg.Wrap(walletCmd.watchOnlyTransactionsCmd),
+		}
+		watchOnlyCreateCoinTxCmd = &cobra.Command{
+			Use:   "cointransaction <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...",
+			Short: "Create an unsigned coin transaction funded by the watch-only addresses",
+			Long: `Create an unsigned coin transaction funded by the watch-only addresses,
+	to be signed offline by the owner(s) of those addresses.
+	The outputs can be given as a pair of value and a raw output condition (or
+	address, which resolved to a singlesignature condition).
+	
+	Amounts have to be given expressed in the OneCoin unit, and without the unit of currency.
+	Decimals are possible and have to be defined using the decimal point.
+	
+	The Minimum Miner Fee and the custody fees to be paid are added automatically.
+	`,
+			Run: walletCmd.watchOnlyCreateCoinTxCmd,
+		}
+
+		pstCmd = &cobra.Command{
+			Use:   "pst",
+			Short: "Create, sign and finalize partially signed transactions",
+			Long: `Create, sign and finalize partially signed transactions (PSTs).
+	A PST contains a transaction that has to be signed by multiple parties,
+	such as the co-signers of a multisig wallet, together with the parent outputs
+	of its inputs, the custody fee inputs and the signatures still missing.
+	
+	PSTs are given and returned as JSON. Each <pst> argument
+	can be given either as raw JSON or as the path to a JSON file.
+	`,
+			// Run field is not set, as the pst command itself is not a valid command.
+			// A subcommand must be provided.
+		}
+		pstCreateCmd = &cobra.Command{
+			Use:   "create <txnjson>",
+			Short: "Create a PST for a transaction",
+			Long: `Create a PST for an (unsigned) transaction, as created by the
+	create or watchonly commands. The transaction can be given either as raw JSON
+	or as the path to a JSON file. Fulfillments already defined are kept.
+	`,
+			Args: cobra.ExactArgs(1),
+			Run:  walletCmd.pstCreateCmd,
+		}
+		pstSignCmd = &cobra.Command{
+			Use:   "sign <pst>",
+			Short: "Add the signatures this wallet can provide to a PST",
+			Args:  cobra.ExactArgs(1),
+			Run:   walletCmd.pstSignCmd,
+		}
+		pstCombineCmd = &cobra.Command{
+			Use:   "combine <pst> <pst> [<pst>]...",
+			Short: "Combine the signatures of multiple PSTs",
+			Long: `Combine the signatures of multiple PSTs, each signed by one or multiple co-signers,
+	into a single PST. All PSTs have to be created for the same transaction.
+	`,
+			Args: cobra.MinimumNArgs(2),
+			Run:  walletCmd.pstCombineCmd,
+		}
+		pstInspectCmd = &cobra.Command{
+			Use:   "inspect <pst>",
+			Short: "Show the inputs, outputs, custody fee and missing signatures of a PST",
+			Args:  cobra.ExactArgs(1),
+			Run:   walletCmd.pstInspectCmd,
+		}
+		pstFinalizeCmd = &cobra.Command{
+			Use:   "finalize <pst>",
+			Short: "Publish the transaction of a fully signed PST",
+			Args:  cobra.ExactArgs(1),
+			Run:   walletCmd.pstFinalizeCmd,
+		}
+
+		labelsCmd = &cobra.Command{
+			Use:   "labels",
+			Short: "Manage the labels, notes and tags of addresses",
+			Long: `List the labels, notes and tags of the addresses labeled by the wallet,
+	which can be addresses owned by the wallet, as well as counterparty addresses.
+	Labels are shown in the output of other wallet commands, and are included in the wallet backup.
+	`,
+			Run: clientpkg.Wrap(walletCmd.labelsListCmd),
+		}
+		addressBookCmd = &cobra.Command{
+			Use:   "addressbook",
+			Short: "List the address book and the authorization state of its entries",
+			Long: `List the counterparty addresses labeled by the wallet, as well as
+	whether or not they are currently authorized to send and receive coins.
+	Entries are added to and removed from the address book using the labels command.
+	`,
+			Run: clientpkg.Wrap(walletCmd.addressBookCmd),
+		}
+		labelsSetCmd = &cobra.Command{
+			Use:   "set <address> [<label>]",
+			Short: "Label an address",
+			Long: `Label an address, owned by the wallet or not, replacing the label,
+	notes and tags previously defined for that address.
+	At least one of a label, notes or tags has to be given.
+	`,
+			Args: cobra.RangeArgs(1, 2),
+			Run:  walletCmd.labelsSetCmd,
+		}
+		labelsRemoveCmd = &cobra.Command{
+			Use:   "remove <address> [<address>]...",
+			Short: "Remove the labels of one or multiple addresses",
+			Args:  cobra.MinimumNArgs(1),
+			Run:   walletCmd.labelsRemoveCmd,
+		}
+
+		scanCmd = &cobra.Command{
+			Use:   "scan",
+			Short: "Rescan the blockchain for the addresses of all seeds",
+			Long: `Rescan the blockchain for the addresses of all seeds of the wallet,
+	and report the addresses and funds discovered for each seed.
+	Addresses are discovered as long as no more than the address gap limit
+	of consecutive addresses are unused.`,
+			Run: clientpkg.Wrap(walletCmd.scanCmd),
+		}
+
+		hdCmd = &cobra.Command{
+			Use:   "hd",
+			Short: "Manage the hierarchical deterministic (HD) key derivation of the wallet",
+			// Run field is not set, as the hd command itself is not a valid command.
+			// A subcommand must be provided.
+		}
+		hdEnableCmd = &cobra.Command{
+			Use:   "enable",
+			Short: "Migrate the primary seed to HD key derivation",
+			Long: `Migrate the primary seed of the wallet to HD key derivation,
+	deriving all new addresses per account, using separate deposit and change chains.
+	All addresses generated prior to this migration remain tracked and spendable.
+	This migration cannot be undone.
+	`,
+			Run: clientpkg.Wrap(walletCmd.hdEnableCmd),
+		}
+		hdAccountsCmd = &cobra.Command{
+			Use:   "accounts",
+			Short: "List the HD accounts of the wallet and their progress",
+			Run:   clientpkg.Wrap(walletCmd.hdAccountsCmd),
+		}
+		hdAddressesCmd = &cobra.Command{
+			Use:   "addresses <account> [<count>]",
+			Short: "Reserve the next deposit addresses of an HD account",
+			Long: `Reserve the next deposit address(es) of an HD account, one by default.
+	A new account can only be used once the previous account has handed out at least one address.
+	`,
+			Args: cobra.RangeArgs(1, 2),
+			Run:  walletCmd.hdAddressesCmd,
+		}
+		hdDeriveCmd = &cobra.Command{
+			Use:   "derive <extendedpublickey> [<start> [<count>]]",
+			Short: "Derive addresses from the extended public key of an HD account",
+			Long: `Derive the deposit address(es) of an HD account from its extended public key,
+	as listed by the accounts command, one address starting at index 0 by default.
+	No daemon or seed is required, such that deposit addresses can be derived on a separate machine.
+	The wallet only discovers funds on addresses within the address gap limit of its last used address.
+	`,
+			Args: cobra.RangeArgs(1, 3),
+			Run:  walletCmd.hdDeriveCmd,
+		}
 	)
 
 	// define wallet command tree
@@ -251,16 +485,25 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 		recoverCmd,
 		lockCmd,
 		unlockCmd,
+		changePasswordCmd,
 		loadCmd,
 		seedsCmd,
 		sendCmd,
 		balanceCmd,
 		listTransactionsCmd,
+		exportCmd,
+		statementCmd,
 		blockStakeStatCmd,
 		registerDataCmd,
 		listCmd,
 		createCmd,
-		signTxCmd)
+		signTxCmd,
+		watchOnlyCmd,
+		pstCmd,
+		labelsCmd,
+		addressBookCmd,
+		hdCmd,
+		scanCmd)
 
 	sendCmd.AddCommand(
 		sendCoinsCmd,
@@ -278,6 +521,30 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 		createCoinTxCmd,
 		createBlockStakeTxCmd)
 
+	watchOnlyCmd.AddCommand(
+		watchOnlyListCmd,
+		watchOnlyAddCmd,
+		watchOnlyRemoveCmd,
+		watchOnlyTransactionsCmd,
+		watchOnlyCreateCoinTxCmd)
+
+	pstCmd.AddCommand(
+		pstCreateCmd,
+		pstSignCmd,
+		pstCombineCmd,
+		pstInspectCmd,
+		pstFinalizeCmd)
+
+	labelsCmd.AddCommand(
+		labelsSetCmd,
+		labelsRemoveCmd)
+
+	hdCmd.AddCommand(
+		hdEnableCmd,
+		hdAccountsCmd,
+		hdAddressesCmd,
+		hdDeriveCmd)
+
 	// define config of commands that have a config
 	initCmd.Flags().BoolVar(
 		&walletCmd.walletInitCfg.Plain,
@@ -295,11 +562,28 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 		&walletCmd.walletLoadSeedCfg.Seed,
 		"seed", "", "define the seed to be loaded as a flag instead of the STDIN")
 
+	// address gap limit flags
+	recoverCmd.Flags().Uint64Var(
+		&walletCmd.walletRecoverCfg.GapLimit,
+		"gap-limit", 0, "define the amount of consecutive unused addresses after which to stop discovering addresses")
+	loadSeedCmd.Flags().Uint64Var(
+		&walletCmd.walletLoadSeedCfg.GapLimit,
+		"gap-limit", 0, "define the amount of consecutive unused addresses after which to stop discovering addresses")
+	scanCmd.Flags().Uint64Var(
+		&walletCmd.walletScanCfg.GapLimit,
+		"gap-limit", 0, "define the amount of consecutive unused addresses after which to stop discovering addresses")
+
+	hdDeriveCmd.Flags().BoolVar(
+		&walletCmd.hdDeriveCfg.Change,
+		"change", false, "derive change addresses instead of deposit addresses")
+
 	// custom arbitrarydata flag
 	clipkg.ArbitraryDataFlagVar(sendCoinsCmd.Flags(), &walletCmd.sendCoinsCfg.Data,
 		"data", "optional arbitrary data (or description) to attach to transaction")
 	clipkg.ArbitraryDataFlagVar(sendBlockStakesCmd.Flags(), &walletCmd.sendBlockStakesCfg.Data,
 		"data", "optional arbitrary data (or description) to attach to transaction")
+	clipkg.ArbitraryDataFlagVar(watchOnlyCreateCoinTxCmd.Flags(), &walletCmd.watchOnlyCreateCoinTxCfg.Data,
+		"data", "optional arbitrary data (or description) to attach to transaction")
 
 	// other custom send coins flags
 	sendCoinsCmd.Flags().StringVar(
@@ -308,6 +592,15 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 	sendCoinsCmd.Flags().BoolVar(
 		&walletCmd.sendCoinsCfg.RefundAddressNew,
 		"refund-address-new", false, "generate a new refund address if a refund needs to happen")
+	sendCoinsCmd.Flags().BoolVar(
+		&walletCmd.sendCoinsCfg.AllowUnauthorized,
+		"allow-unauthorized", false, "only warn, instead of refusing, when sending to an unauthorized address")
+	sendCoinsCmd.Flags().BoolVar(
+		&walletCmd.sendCoinsCfg.DryRun,
+		"dry-run", false, "validate the transaction against all rules of the network, without sending it")
+	sendCoinsCmd.Flags().StringVar(
+		&walletCmd.sendCoinsCfg.FromMultiSig,
+		"from-multisig", "", "send the coins from the given multisig wallet, which this wallet is a co-signer of")
 
 	// other custom send blockstkars flags
 	sendBlockStakesCmd.Flags().StringVar(
@@ -317,6 +610,72 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 		&walletCmd.sendBlockStakesCfg.RefundAddressNew,
 		"refund-address-new", false, "generate a new refund address if a refund needs to happen")
 
+	// other custom watch-only coin transaction flags
+	watchOnlyCreateCoinTxCmd.Flags().StringVar(
+		&walletCmd.watchOnlyCreateCoinTxCfg.RefundAddress,
+		"refund-address", "", "define a custom refund address")
+
+	// transaction listing filter and pagination flags
+	for _, cmd := range []*cobra.Command{listTransactionsCmd, watchOnlyTransactionsCmd} {
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.Address,
+			"address", "", "only list the transactions related to the given address")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.Direction,
+			"direction", "", "only list the incoming or outgoing transactions")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.Categories,
+			"category", "", "only list the transactions of the given comma-separated categories (regular, minting, auth)")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.StartTime,
+			"start-time", "", "only list the transactions confirmed at or after the given date, RFC3339 date or unix epoch timestamp")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.EndTime,
+			"end-time", "", "only list the transactions confirmed at or before the given date, RFC3339 date or unix epoch timestamp")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.MinAmount,
+			"min-amount", "", "only list the transactions with a net coin amount of at least the given amount")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.MaxAmount,
+			"max-amount", "", "only list the transactions with a net coin amount of at most the given amount")
+		cmd.Flags().Uint64Var(
+			&walletCmd.listTransactionsCfg.Limit,
+			"limit", 0, "list at most the given amount of confirmed transactions, listing all if 0")
+		cmd.Flags().StringVar(
+			&walletCmd.listTransactionsCfg.Cursor,
+			"cursor", "", "continue listing the transactions from the cursor returned by a previous call")
+	}
+
+	// export cmd flags
+	exportCmd.Flags().StringVar(
+		&walletCmd.walletExportCfg.Format,
+		"format", walletExportFormatCSV, "the format of the export, one of csv, json or ofx")
+	exportCmd.Flags().StringVar(
+		&walletCmd.walletExportCfg.From,
+		"from", "", "only export the movements confirmed at or after the given date, RFC3339 date or unix epoch timestamp")
+	exportCmd.Flags().StringVar(
+		&walletCmd.walletExportCfg.To,
+		"to", "", "only export the movements confirmed at or before the given date, RFC3339 date or unix epoch timestamp")
+
+	// statement cmd flags
+	statementCmd.Flags().StringVar(
+		&walletCmd.walletStatementCfg.Period,
+		"period", string(gcmodules.StatementPeriodMonth), "the period of the statement, one of day or month")
+	statementCmd.Flags().StringVar(
+		&walletCmd.walletStatementCfg.From,
+		"from", "", "only list the periods at or after the given date, RFC3339 date or unix epoch timestamp")
+	statementCmd.Flags().StringVar(
+		&walletCmd.walletStatementCfg.To,
+		"to", "", "only list the periods at or before the given date, RFC3339 date or unix epoch timestamp")
+
+	// labels cmd flags
+	labelsSetCmd.Flags().StringVar(
+		&walletCmd.walletLabelsCfg.Notes,
+		"notes", "", "optional notes to attach to the address")
+	labelsSetCmd.Flags().StringVar(
+		&walletCmd.walletLabelsCfg.Tags,
+		"tags", "", "optional comma-separated tags to attach to the address")
+
 	// all addresses cmd flags
 	addressesCmd.Flags().BoolVarP(
 		&walletCmd.walletAddressesCfg.ShowIndices, "index", "i", false,
@@ -335,9 +694,12 @@ func CreateWalletCmd(cli *clientpkg.CommandLineClient) *clientpkg.WalletCommand
 type walletCmd struct {
 	cli          *clientpkg.CommandLineClient
 	sendCoinsCfg struct {
-		Data             []byte
-		RefundAddress    string
-		RefundAddressNew bool
+		Data              []byte
+		RefundAddress     string
+		RefundAddressNew  bool
+		AllowUnauthorized bool
+		DryRun            bool
+		FromMultiSig      string
 	}
 	sendBlockStakesCfg struct {
 		Data             []byte
@@ -348,16 +710,53 @@ type walletCmd struct {
 		Plain bool
 	}
 	walletRecoverCfg struct {
-		Plain bool
-		Seed  string
+		Plain    bool
+		Seed     string
+		GapLimit uint64
 	}
 	walletLoadSeedCfg struct {
-		Plain bool
-		Seed  string
+		Plain    bool
+		Seed     string
+		GapLimit uint64
+	}
+	walletScanCfg struct {
+		GapLimit uint64
+	}
+	hdDeriveCfg struct {
+		Change bool
 	}
 	walletAddressesCfg struct {
 		ShowIndices bool
 	}
+	watchOnlyCreateCoinTxCfg struct {
+		Data          []byte
+		RefundAddress string
+	}
+	walletExportCfg struct {
+		Format string
+		From   string
+		To     string
+	}
+	walletLabelsCfg struct {
+		Notes string
+		Tags  string
+	}
+	walletStatementCfg struct {
+		Period string
+		From   string
+		To     string
+	}
+	listTransactionsCfg struct {
+		Address    string
+		Direction  string
+		Categories string
+		StartTime  string
+		EndTime    string
+		MinAmount  string
+		MaxAmount  string
+		Limit      uint64
+		Cursor     string
+	}
 }
 
 // addressCmd fetches a new address from the wallet that will be able to
@@ -378,9 +777,10 @@ func (walletCmd *walletCmd) addressesCmd() {
 	if err != nil {
 		cli.DieWithError("Failed to fetch addresses:", err)
 	}
+	labels := walletCmd.fetchAddressLabels()
 	if !walletCmd.walletAddressesCfg.ShowIndices {
 		for _, addr := range addrs.Addresses {
-			fmt.Println(addr)
+			fmt.Println(labels.Annotate(addr))
 		}
 		return
 	}
@@ -389,7 +789,7 @@ func (walletCmd *walletCmd) addressesCmd() {
 		fmtStr = "%05d\t%s\r\n"
 	}
 	for idx, addr := range addrs.Addresses {
-		fmt.Printf(fmtStr, idx, addr.String())
+		fmt.Printf(fmtStr, idx, labels.Annotate(addr))
 	}
 }
 
@@ -481,6 +881,7 @@ func (walletCmd *walletCmd) recoverCmd() {
 	}
 	data += fmt.Sprintf("seed=%s", seed.String())
 
+	walletCmd.setAddressGapLimit(walletCmd.walletRecoverCfg.GapLimit)
 	err = walletCmd.cli.PostWithResponse("/wallet/init", data, &er)
 	if err != nil {
 		if walletCmd.walletRecoverCfg.Plain {
@@ -497,7 +898,10 @@ func (walletCmd *walletCmd) recoverCmd() {
 	fmt.Printf("Mnemonic of primary seed:\n%s\n\n", er.PrimarySeed)
 	if !walletCmd.walletRecoverCfg.Plain {
 		fmt.Printf("Wallet encrypted with given passphrase\n")
+		fmt.Println("Unlock the wallet to discover the funds of the recovered seed, and use `wallet scan` to view them.")
+		return
 	}
+	walletCmd.printSeedScanReports()
 }
 
 // loadSeedCmd adds a seed to the wallet's list of seeds
@@ -519,11 +923,71 @@ func (walletCmd *walletCmd) loadSeedCmd() {
 		}
 	}
 	data += fmt.Sprintf("mnemonic=%s", seed)
+	walletCmd.setAddressGapLimit(walletCmd.walletLoadSeedCfg.GapLimit)
 	err := walletCmd.cli.Post("/wallet/seed", data)
 	if err != nil {
 		cli.DieWithError("Could not add seed:", err)
 	}
 	fmt.Println("Added Key")
+	fmt.Println()
+	walletCmd.printSeedScanReports()
+}
+
+// scanCmd rescans the blockchain for the addresses of all seeds of the wallet.
+func (walletCmd *walletCmd) scanCmd() {
+	walletCmd.setAddressGapLimit(walletCmd.walletScanCfg.GapLimit)
+	fmt.Println("Rescanning the blockchain, this might take a while...")
+	var resp gcapi.WalletScanGET
+	err := walletCmd.cli.PostWithResponse("/wallet/scan", "", &resp)
+	if err != nil {
+		cli.DieWithError("Could not rescan the blockchain:", err)
+	}
+	walletCmd.printSeedScanReport(resp)
+}
+
+// setAddressGapLimit configures the address gap limit of the wallet, if one is given.
+func (walletCmd *walletCmd) setAddressGapLimit(limit uint64) {
+	if limit == 0 {
+		return
+	}
+	err := walletCmd.cli.Post("/wallet/gaplimit", fmt.Sprintf(`{"gaplimit":%d}`, limit))
+	if err != nil {
+		cli.DieWithError("Could not set the address gap limit:", err)
+	}
+}
+
+func (walletCmd *walletCmd) printSeedScanReports() {
+	var resp gcapi.WalletScanGET
+	err := walletCmd.cli.GetWithResponse("/wallet/scan", &resp)
+	if err != nil {
+		cli.DieWithError("Could not get the discovered funds:", err)
+	}
+	walletCmd.printSeedScanReport(resp)
+}
+
+func (walletCmd *walletCmd) printSeedScanReport(resp gcapi.WalletScanGET) {
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	fmt.Println("Address gap limit:", resp.GapLimit)
+	for _, report := range resp.Seeds {
+		fmt.Println()
+		if report.Primary {
+			fmt.Println("Primary Seed:")
+		} else {
+			fmt.Printf("Seed #%d:\n", report.SeedIndex)
+		}
+		fmt.Printf("Addresses Used:                %d (of %d tracked)\n", report.AddressesUsed, report.AddressesLoaded)
+		fmt.Printf("Confirmed Balance:             %v\n", currencyConvertor.ToCoinStringWithUnit(report.ConfirmedCoinBalance))
+		fmt.Printf("Confirmed Custody Fees To Pay: %v\n", currencyConvertor.ToCoinStringWithUnit(report.ConfirmedCustodyFeeDebt))
+		if !report.ConfirmedLockedCoinBalance.IsZero() {
+			fmt.Printf("Locked Balance:                %v\n", currencyConvertor.ToCoinStringWithUnit(report.ConfirmedLockedCoinBalance))
+		}
+		if !report.ConfirmedBlockStakeBalance.IsZero() {
+			fmt.Printf("BlockStakes:                   %v BS\n", report.ConfirmedBlockStakeBalance)
+		}
+		if !report.ConfirmedLockedBlockStakeBalance.IsZero() {
+			fmt.Printf("Locked BlockStakes:            %v BS\n", report.ConfirmedLockedBlockStakeBalance)
+		}
+	}
 }
 
 // lockCmd locks the wallet
@@ -568,6 +1032,14 @@ func (walletCmd *walletCmd) sendCoinsCmd(cmd *cobra.Command, args []string) {
 			Condition: pair.Condition,
 		}
 	}
+	if walletCmd.sendCoinsCfg.FromMultiSig != "" {
+		if walletCmd.sendCoinsCfg.RefundAddress != "" || walletCmd.sendCoinsCfg.RefundAddressNew || walletCmd.sendCoinsCfg.DryRun {
+			cmd.UsageFunc()(cmd)
+			cli.Die("--from-multisig cannot be combined with --refund-address, --refund-address-new or --dry-run")
+		}
+		walletCmd.sendMultiSigCoins(body.CoinOutputs, body.Data)
+		return
+	}
 	if walletCmd.sendCoinsCfg.RefundAddress != "" {
 		// use the specified address as the refund address if a refund has to happen
 		var uh types.UnlockHash
@@ -580,6 +1052,12 @@ func (walletCmd *walletCmd) sendCoinsCmd(cmd *cobra.Command, args []string) {
 		// ensure the daemon generates a new refund address if a refund needs to happen
 		body.GenerateRefundAddress = true
 	}
+	if walletCmd.sendCoinsCfg.DryRun {
+		// the simulation reports unauthorized addresses as well
+		walletCmd.simulateCoinTransaction(body)
+		return
+	}
+	walletCmd.checkCoinOutputsAuthorization(body.CoinOutputs, body.RefundAddress, walletCmd.sendCoinsCfg.AllowUnauthorized)
 
 	bytes, err := json.Marshal(&body)
 	if err != nil {
@@ -701,10 +1179,19 @@ func (walletCmd *walletCmd) balanceCmd() {
 		encStatus = "Encrypted"
 	}
 	if !status.Unlocked {
-		cli.DieWithExitCode(cli.ExitCodeUsage, fmt.Sprintf(`Wallet status:
+		if status.WatchOnly == nil {
+			cli.DieWithExitCode(cli.ExitCodeUsage, fmt.Sprintf(`Wallet status:
 %v, Locked
 Unlock the wallet to view balance
 `, encStatus))
+		}
+		// the watch-only addresses are tracked while the wallet is locked as well
+		fmt.Printf(`Wallet status:
+%v, Locked
+Unlock the wallet to view the balance of its own addresses
+`, encStatus)
+		printWalletWatchOnly(currencyConvertor, status.WatchOnly)
+		return
 	}
 
 	unconfirmedBalance := status.ConfirmedCoinBalance.Add(status.UnconfirmedIncomingCoins).Sub(status.UnconfirmedOutgoingCoins)
@@ -730,6 +1217,11 @@ BlockStakes:         %v BS
 		fmt.Printf("Locked BlockStakes:  %v BS\n", status.LockedBlockStakeBalance)
 	}
 
+	var labels addressLabels
+	if len(status.MultiSigWallets) > 0 {
+		labels = walletCmd.fetchAddressLabels()
+	}
+
 	// multisig wallets are not enabled at the moment
 	if len(status.MultiSigWallets) > 0 {
 		fmt.Println()
@@ -758,7 +1250,7 @@ BlockStakes:         %v BS
 			bsdelta = "- " + wallet.ConfirmedBlockStakeBalance.Sub(unconfirmedBlockStakeBalance).String()
 		}
 
-		fmt.Printf("%v\n", wallet.Address)
+		fmt.Printf("%v\n", labels.Annotate(wallet.Address))
 		fmt.Printf("Confirmed Balance:             %v\n", currencyConvertor.ToCoinStringWithUnit(wallet.ConfirmedCoinBalance))
 		fmt.Printf("Confirmed Custody Fees To Pay: %v\n", currencyConvertor.ToCoinStringWithUnit(wallet.ConfirmedCustodyFeeDebt))
 		if !wallet.ConfirmedLockedCoinBalance.IsZero() {
@@ -780,30 +1272,59 @@ BlockStakes:         %v BS
 		fmt.Println()
 		fmt.Println("Possible signatories:")
 		for _, uh := range wallet.Owners {
-			fmt.Println(uh)
+			fmt.Println(labels.Annotate(uh))
 		}
 		fmt.Println()
 		fmt.Println("Minimum signatures required:", wallet.MinSigs)
 	}
+
+	if status.WatchOnly != nil {
+		printWalletWatchOnly(currencyConvertor, status.WatchOnly)
+	}
+}
+
+// printWalletWatchOnly prints the watch-only section of the wallet status.
+func printWalletWatchOnly(currencyConvertor clientpkg.CurrencyConvertor, watchOnly *gcapi.WalletWatchOnlyGET) {
+	fmt.Println()
+	fmt.Println("Watch-only Addresses:")
+	fmt.Println()
+	printWatchOnlyBalance(currencyConvertor, watchOnly.Balance)
 }
 
 // listTransactionsCmd lists all of the transactions related to the wallet,
 // providing a net flow of siacoins and siafunds for each.
 func (walletCmd *walletCmd) listTransactionsCmd() {
+	qs, filtered := walletCmd.transactionsQuery("cursor")
 	wtg := new(gcapi.WalletTransactionsGET)
-	err := walletCmd.cli.GetWithResponse("/wallet/transactions?startheight=0&endheight=10000000", wtg)
+	err := walletCmd.cli.GetWithResponse("/wallet/history?"+qs, wtg)
 	if err != nil {
 		cli.DieWithError("Could not fetch transaction history:", err)
 	}
+	defer printNextTransactionsCursor(wtg.NextCursor)
+	if !filtered && len(wtg.WatchOnlyTransactions) > 0 {
+		// the transactions of the watch-only addresses are listed in a separate section,
+		// use the watchonly transactions command to filter or paginate them
+		defer func() {
+			fmt.Println()
+			fmt.Println("Watch-only Addresses:")
+			fmt.Println()
+			walletCmd.printWatchOnlyTransactions(wtg.WatchOnlyTransactions)
+		}()
+	}
 
 	multiSigWalletTxns := make(map[types.UnlockHash][]gcmodules.ProcessedTransaction)
-	txns := append(wtg.ConfirmedTransactions, rivineProcessedTransactionsAsGoldchainProcessedTransactions(wtg.UnconfirmedTransactions)...)
+	txns := wtg.ConfirmedTransactions
+	if !filtered {
+		// unconfirmed transactions are only listed when not filtering or paginating
+		txns = append(txns, rivineProcessedTransactionsAsGoldchainProcessedTransactions(wtg.UnconfirmedTransactions)...)
+	}
 
 	if len(txns) == 0 {
 		fmt.Println("This wallet has no transaction related to it.")
 		return
 	}
 
+	labels := walletCmd.fetchAddressLabels()
 	fmt.Println("    [height]                                                   [transaction id]       [net coins]   [net blockstakes]")
 	for _, txn := range txns {
 		var relatedMultiSigUnlockHashes []types.UnlockHash
@@ -869,6 +1390,7 @@ func (walletCmd *walletCmd) listTransactionsCmd() {
 		incomingBlockStakeBigInt := incomingBlockStakes.Big()
 		outgoingBlockStakeBigInt := outgoingBlockStakes.Big()
 		fmt.Printf("%14s BS\n", new(big.Int).Sub(incomingBlockStakeBigInt, outgoingBlockStakeBigInt).String())
+		printTransactionLabels(labels, txn)
 	}
 
 	if len(multiSigWalletTxns) > 0 {
@@ -878,7 +1400,7 @@ func (walletCmd *walletCmd) listTransactionsCmd() {
 				fmt.Println("=====================================================================================================================")
 				fmt.Println()
 
-				fmt.Println("Wallet Address:", uh)
+				fmt.Println("Wallet Address:", labels.Annotate(uh))
 				fmt.Println()
 				fmt.Println("    [height]                                             [transaction/block id]       [net coins]   [net blockstakes]")
 
@@ -984,6 +1506,379 @@ func rivineProcessedTransactionsAsGoldchainProcessedTransactions(pts []modules.P
 	return epts
 }
 
+// watchOnlyListCmd lists all watch-only addresses of the wallet,
+// as well as their balance.
+func (walletCmd *walletCmd) watchOnlyListCmd() {
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+
+	var resp gcapi.WalletWatchOnlyGET
+	err := walletCmd.cli.GetWithResponse("/wallet/watchonly", &resp)
+	if err != nil {
+		cli.DieWithError("Could not get watch-only addresses:", err)
+	}
+	if len(resp.Addresses) == 0 {
+		fmt.Println("This wallet has no watch-only addresses.")
+		return
+	}
+
+	for _, woa := range resp.Addresses {
+		fmt.Printf("%v\n", woa.Address)
+		if woa.PublicKey != nil {
+			fmt.Printf("Public Key:                    %v\n", woa.PublicKey.String())
+		}
+		printWatchOnlyBalance(currencyConvertor, woa.Balance)
+		fmt.Println()
+	}
+	fmt.Println("Total:")
+	printWatchOnlyBalance(currencyConvertor, resp.Balance)
+}
+
+func printWatchOnlyBalance(currencyConvertor clientpkg.CurrencyConvertor, balance gcmodules.Balance) {
+	fmt.Printf("Confirmed Balance:             %v\n", currencyConvertor.ToCoinStringWithUnit(balance.ConfirmedCoinBalance))
+	fmt.Printf("Confirmed Custody Fees To Pay: %v\n", currencyConvertor.ToCoinStringWithUnit(balance.ConfirmedCustodyFeeDebt))
+	if !balance.ConfirmedLockedCoinBalance.IsZero() {
+		fmt.Printf("Locked Balance:                %v\n", currencyConvertor.ToCoinStringWithUnit(balance.ConfirmedLockedCoinBalance))
+	}
+	if !balance.ConfirmedBlockStakeBalance.IsZero() {
+		fmt.Printf("BlockStakes:                   %v BS\n", balance.ConfirmedBlockStakeBalance)
+	}
+	if !balance.ConfirmedLockedBlockStakeBalance.IsZero() {
+		fmt.Printf("Locked BlockStakes:            %v BS\n", balance.ConfirmedLockedBlockStakeBalance)
+	}
+}
+
+// watchOnlyAddCmd adds one or multiple addresses as watch-only addresses to the wallet.
+func (walletCmd *walletCmd) watchOnlyAddCmd(cmd *cobra.Command, args []string) {
+	var body gcapi.WalletWatchOnlyPOST
+	for _, arg := range args {
+		var uh types.UnlockHash
+		if err := uh.LoadString(arg); err == nil {
+			body.Addresses = append(body.Addresses, uh)
+			continue
+		}
+		var pk types.PublicKey
+		if err := pk.LoadString(arg); err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.Die(fmt.Sprintf("%q is neither a valid address nor a valid public key", arg))
+		}
+		body.PublicKeys = append(body.PublicKeys, pk)
+	}
+	walletCmd.postWatchOnlyAddresses("/wallet/watchonly/add", body)
+	fmt.Println("Added", len(args), "watch-only address(es) to the wallet")
+}
+
+// watchOnlyRemoveCmd removes one or multiple watch-only addresses from the wallet.
+func (walletCmd *walletCmd) watchOnlyRemoveCmd(cmd *cobra.Command, args []string) {
+	var body gcapi.WalletWatchOnlyPOST
+	for _, arg := range args {
+		var uh types.UnlockHash
+		if err := uh.LoadString(arg); err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.Die(fmt.Sprintf("%q is not a valid address: %v", arg, err))
+		}
+		body.Addresses = append(body.Addresses, uh)
+	}
+	walletCmd.postWatchOnlyAddresses("/wallet/watchonly/remove", body)
+	fmt.Println("Removed", len(args), "watch-only address(es) from the wallet")
+}
+
+func (walletCmd *walletCmd) postWatchOnlyAddresses(call string, body gcapi.WalletWatchOnlyPOST) {
+	buffer := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buffer).Encode(body)
+	if err != nil {
+		cli.Die("Could not encode watch-only addresses:", err)
+	}
+	err = walletCmd.cli.Post(call, buffer.String())
+	if err != nil {
+		cli.DieWithError("Failed to update watch-only addresses:", err)
+	}
+}
+
+// watchOnlyTransactionsCmd lists all of the transactions related to the watch-only addresses,
+// providing a net flow of coins and blockstakes for each.
+func (walletCmd *walletCmd) watchOnlyTransactionsCmd() {
+	qs, _ := walletCmd.transactionsQuery("watchonlycursor")
+	wtg := new(gcapi.WalletTransactionsGET)
+	err := walletCmd.cli.GetWithResponse("/wallet/history?"+qs, wtg)
+	if err != nil {
+		cli.DieWithError("Could not fetch transaction history:", err)
+	}
+	defer printNextTransactionsCursor(wtg.WatchOnlyNextCursor)
+	if len(wtg.WatchOnlyTransactions) == 0 {
+		fmt.Println("This wallet has no transaction related to its watch-only addresses.")
+		return
+	}
+	walletCmd.printWatchOnlyTransactions(wtg.WatchOnlyTransactions)
+}
+
+// printWatchOnlyTransactions prints the net flow of coins and blockstakes
+// of the given transactions, related to the watch-only addresses of the wallet.
+func (walletCmd *walletCmd) printWatchOnlyTransactions(txns []gcmodules.ProcessedTransaction) {
+	labels := walletCmd.fetchAddressLabels()
+	fmt.Println("    [height]                                                   [transaction id]       [net coins]   [net blockstakes]")
+	for _, txn := range txns {
+		// Determine the number of outgoing coins and blockstakes.
+		var outgoingCoins types.Currency
+		var outgoingBlockStakes types.Currency
+		for _, input := range txn.Inputs {
+			if input.FundType == types.SpecifierCoinInput && input.WalletAddress {
+				outgoingCoins = outgoingCoins.Add(spendableProcessedCoinInputValue(&input))
+			}
+			if input.FundType == types.SpecifierBlockStakeInput && input.WalletAddress {
+				outgoingBlockStakes = outgoingBlockStakes.Add(input.Value)
+			}
+		}
+
+		// Determine the number of incoming coins and blockstakes.
+		var incomingCoins types.Currency
+		var incomingBlockStakes types.Currency
+		for _, output := range txn.Outputs {
+			if (output.FundType == types.SpecifierCoinOutput || output.FundType == types.SpecifierMinerPayout) && output.WalletAddress {
+				incomingCoins = incomingCoins.Add(spendableProcessedCoinOutputValue(&output))
+			}
+			if output.FundType == types.SpecifierBlockStakeOutput && output.WalletAddress {
+				incomingBlockStakes = incomingBlockStakes.Add(output.Value)
+			}
+		}
+
+		// Convert the coins to a float.
+		incomingCoinsFloat, _ := new(big.Rat).SetFrac(incomingCoins.Big(), walletCmd.cli.Config.CurrencyUnits.OneCoin.Big()).Float64()
+		outgoingCoinsFloat, _ := new(big.Rat).SetFrac(outgoingCoins.Big(), walletCmd.cli.Config.CurrencyUnits.OneCoin.Big()).Float64()
+
+		// Print the results.
+		fmt.Printf("%12v", txn.ConfirmationHeight-1)
+		fmt.Printf("%67v%15.2f %s", txn.TransactionID, incomingCoinsFloat-outgoingCoinsFloat, walletCmd.cli.Config.CurrencyCoinUnit)
+		fmt.Printf("%14s BS\n", new(big.Int).Sub(incomingBlockStakes.Big(), outgoingBlockStakes.Big()).String())
+		printTransactionLabels(labels, txn)
+	}
+}
+
+// transactionsQuery returns the query string used to list the transactions of the wallet,
+// filtered and paginated as defined by the transaction listing flags.
+// The cursor flag is passed using the given query parameter.
+// True is returned if any filter or pagination flag is defined.
+func (walletCmd *walletCmd) transactionsQuery(cursorParam string) (string, bool) {
+	cfg := walletCmd.listTransactionsCfg
+	values := url.Values{
+		"startheight": []string{"0"},
+		"endheight":   []string{"10000000"},
+	}
+	setValue := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	setValue("address", cfg.Address)
+	setValue("direction", cfg.Direction)
+	setValue("category", cfg.Categories)
+	setValue("starttime", parseTransactionsTimeFlag("start-time", cfg.StartTime))
+	setValue("endtime", parseTransactionsTimeFlag("end-time", cfg.EndTime))
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	for key, str := range map[string]string{"minamount": cfg.MinAmount, "maxamount": cfg.MaxAmount} {
+		if str == "" {
+			continue
+		}
+		amount, err := currencyConvertor.ParseCoinString(str)
+		if err != nil {
+			cli.DieWithError("Could not parse amount "+str+":", err)
+		}
+		values.Set(key, amount.String())
+	}
+	if cfg.Limit > 0 {
+		values.Set("limit", strconv.FormatUint(cfg.Limit, 10))
+	}
+	setValue(cursorParam, cfg.Cursor)
+	return values.Encode(), len(values) > 2
+}
+
+// parseTransactionsTimeFlag parses the given time flag, defined as either
+// a RFC3339 date, a YYYY-MM-DD date or an unix epoch timestamp,
+// and returns it as an unix epoch timestamp string.
+func parseTransactionsTimeFlag(flag, str string) string {
+	if str == "" {
+		return ""
+	}
+	if _, err := strconv.ParseUint(str, 10, 64); err == nil {
+		return str
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return strconv.FormatInt(t.Unix(), 10)
+		}
+	}
+	cli.Die("Invalid --" + flag + " flag: " + str)
+	return ""
+}
+
+// printNextTransactionsCursor prints the cursor to be used
+// to list the next page of transactions, if any.
+func printNextTransactionsCursor(cursor string) {
+	if cursor == "" {
+		return
+	}
+	fmt.Println()
+	fmt.Println("More transactions are available, list them using: --cursor", cursor)
+}
+
+// watchOnlyCreateCoinTxCmd creates an unsigned coin transaction,
+// funded by the watch-only addresses of the wallet, such that it can be signed offline.
+func (walletCmd *walletCmd) watchOnlyCreateCoinTxCmd(cmd *cobra.Command, args []string) {
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+
+	if len(args) == 0 || len(args)%2 != 0 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid arguments. Arguments must be of the form <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...")
+	}
+	pairs, err := parsePairedOutputs(args, currencyConvertor.ParseCoinString)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
+
+	body := gcapi.WalletWatchOnlyTransactionPOST{
+		Data: walletCmd.watchOnlyCreateCoinTxCfg.Data,
+	}
+	for _, pair := range pairs {
+		body.CoinOutputs = append(body.CoinOutputs, types.CoinOutput{Value: pair.Value, Condition: pair.Condition})
+	}
+	if walletCmd.watchOnlyCreateCoinTxCfg.RefundAddress != "" {
+		var uh types.UnlockHash
+		err = uh.LoadString(walletCmd.watchOnlyCreateCoinTxCfg.RefundAddress)
+		if err != nil {
+			cli.Die("Invalid refund address:", err)
+		}
+		body.RefundAddress = &uh
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = json.NewEncoder(buffer).Encode(body)
+	if err != nil {
+		cli.Die("Could not create raw transaction from outputs: ", err)
+	}
+	var resp gcapi.WalletWatchOnlyTransactionPOSTResp
+	err = walletCmd.cli.PostWithResponse("/wallet/watchonly/transaction", buffer.String(), &resp)
+	if err != nil {
+		cli.DieWithError("Failed to create transaction:", err)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(resp.Transaction)
+}
+
+// hdEnableCmd migrates the primary seed of the wallet to HD key derivation.
+func (walletCmd *walletCmd) hdEnableCmd() {
+	err := walletCmd.cli.Post("/wallet/hd/enable", "")
+	if err != nil {
+		cli.DieWithError("Could not enable HD key derivation:", err)
+	}
+	fmt.Println("HD key derivation enabled")
+}
+
+// hdAccountsCmd lists the HD accounts of the wallet and their progress.
+func (walletCmd *walletCmd) hdAccountsCmd() {
+	var resp gcapi.WalletHDGET
+	err := walletCmd.cli.GetWithResponse("/wallet/hd", &resp)
+	if err != nil {
+		cli.DieWithError("Could not get HD accounts:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "account\tpath\tdeposit addresses\tchange addresses\textended public key")
+	for _, account := range resp.Accounts {
+		fmt.Fprintf(w, "%d\t%s\t%d (used: %d)\t%d (used: %d)\t%s\n",
+			account.Index, account.Path,
+			account.ExternalAddresses, account.ExternalUsed,
+			account.InternalAddresses, account.InternalUsed,
+			account.ExtendedPublicKey.String())
+	}
+	w.Flush()
+}
+
+// hdAddressesCmd reserves the next deposit addresses of an HD account.
+func (walletCmd *walletCmd) hdAddressesCmd(cmd *cobra.Command, args []string) {
+	account, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid HD account:", err)
+	}
+	body := gcapi.WalletHDAddressesPOST{
+		Account: uint32(account),
+		Count:   1,
+	}
+	if len(args) == 2 {
+		body.Count, err = strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.Die("Invalid amount of addresses:", err)
+		}
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = json.NewEncoder(buffer).Encode(body)
+	if err != nil {
+		cli.Die("Could not encode HD addresses request:", err)
+	}
+	var resp gcapi.WalletHDAddressesPOSTResp
+	err = walletCmd.cli.PostWithResponse("/wallet/hd/addresses", buffer.String(), &resp)
+	if err != nil {
+		cli.DieWithError("Could not reserve HD addresses:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, addr := range resp.Addresses {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", addr.Path, addr.Address, addr.PublicKey.String())
+	}
+	w.Flush()
+}
+
+// hdDeriveCmd derives addresses from the extended public key of an HD account,
+// without contacting the daemon.
+func (walletCmd *walletCmd) hdDeriveCmd(cmd *cobra.Command, args []string) {
+	var accountKey hdkeys.ExtendedPublicKey
+	err := accountKey.LoadString(args[0])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid extended public key:", err)
+	}
+	start, count := uint64(0), uint64(1)
+	if len(args) >= 2 {
+		start, err = strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.Die("Invalid start index:", err)
+		}
+	}
+	if len(args) == 3 {
+		count, err = strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.Die("Invalid amount of addresses:", err)
+		}
+	}
+	if start+count > uint64(hdkeys.HardenedOffset) {
+		cli.Die("Index out of range, the last index can be at most", hdkeys.HardenedOffset-1)
+	}
+	chain := uint32(0)
+	if walletCmd.hdDeriveCfg.Change {
+		chain = 1
+	}
+	chainKey, err := accountKey.Child(chain)
+	if err != nil {
+		cli.Die("Could not derive chain key:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for index := start; index < start+count; index++ {
+		key, err := chainKey.Child(uint32(index))
+		if err != nil {
+			cli.Die("Could not derive address", index, ":", err)
+		}
+		pk := types.Ed25519PublicKey(crypto.PublicKey(key.PublicKey))
+		uh, err := types.NewPubKeyUnlockHash(pk)
+		if err != nil {
+			cli.Die("Could not create address", index, ":", err)
+		}
+		fmt.Fprintf(w, "%d/%d\t%s\t%s\n", chain, index, uh, pk.String())
+	}
+	w.Flush()
+}
+
 // unlockCmd unlocks a saved wallet
 func (walletCmd *walletCmd) unlockCmd() {
 	password, err := speakeasy.Ask("Wallet password: ")
@@ -999,6 +1894,38 @@ func (walletCmd *walletCmd) unlockCmd() {
 	fmt.Println("Wallet unlocked")
 }
 
+// changePasswordCmd re-encrypts the wallet using a new password
+func (walletCmd *walletCmd) changePasswordCmd() {
+	password, err := speakeasy.Ask("Current wallet password: ")
+	if err != nil {
+		cli.Die("Reading password failed:", err)
+	}
+	newPassword, err := speakeasy.Ask("New wallet password: ")
+	if err != nil {
+		cli.Die("Reading password failed:", err)
+	}
+	if newPassword == "" {
+		cli.Die("new password is required and cannot be empty")
+	}
+	reNewPassword, err := speakeasy.Ask("Reenter new wallet password: ")
+	if err != nil {
+		cli.Die("Reading password failed:", err)
+	}
+	if reNewPassword != newPassword {
+		cli.Die("Given passwords do not match !!")
+	}
+	fmt.Println("Changing the wallet password. This may take a while...")
+	qs := url.Values{
+		"passphrase":    []string{password},
+		"newpassphrase": []string{newPassword},
+	}.Encode()
+	err = walletCmd.cli.Post("/wallet/changepassword", qs)
+	if err != nil {
+		cli.DieWithError("Could not change the wallet password:", err)
+	}
+	fmt.Println("Wallet password changed")
+}
+
 // sendTxCmd sends commits a transaction in json format
 // to the transaction pool
 func (walletCmd *walletCmd) sendTxCmd(txnjson string) {
@@ -1064,10 +1991,18 @@ func (walletCmd *walletCmd) listUnlockedCmd(_ *cobra.Command, args []string) {
 
 	jsonOutput := json.NewEncoder(os.Stdout)
 
+	labels := walletCmd.fetchAddressLabels()
+	printOutputLabel := func(uh types.UnlockHash) {
+		if name := labels.Name(uh); name != "" {
+			fmt.Println("Label:", name)
+		}
+	}
+
 	if len(resp.UnlockedCoinOutputs) > 0 {
 		fmt.Println("Unlocked unspent coin outputs:")
 		for _, uco := range resp.UnlockedCoinOutputs {
 			fmt.Println("ID:", uco.ID)
+			printOutputLabel(uco.Output.Condition.UnlockHash())
 			fmt.Println("Creation Value:", currencyConvertor.ToCoinStringWithUnit(uco.CoinInfo.CreationValue))
 			fmt.Println("Current Age:", time.Second*time.Duration(uco.CoinInfo.FeeComputationTime-uco.CoinInfo.CreationTime))
 			fmt.Println("Spendable Value:", currencyConvertor.ToCoinStringWithUnit(uco.CoinInfo.SpendableValue))
@@ -1082,6 +2017,7 @@ func (walletCmd *walletCmd) listUnlockedCmd(_ *cobra.Command, args []string) {
 		fmt.Println("Unlocked unspent blockstake outputs:")
 		for _, ubso := range resp.UnlockedBlockstakeOutputs {
 			fmt.Println("ID:", ubso.ID)
+			printOutputLabel(ubso.Output.Condition.UnlockHash())
 			fmt.Println("Value:", ubso.Output.Value, "BS")
 			fmt.Println("Condition:")
 			jsonOutput.Encode(ubso.Output)
@@ -1144,10 +2080,18 @@ func (walletCmd *walletCmd) listLockedCmd(_ *cobra.Command, args []string) {
 
 	jsonOutput := json.NewEncoder(os.Stdout)
 
+	labels := walletCmd.fetchAddressLabels()
+	printOutputLabel := func(uh types.UnlockHash) {
+		if name := labels.Name(uh); name != "" {
+			fmt.Println("Label:", name)
+		}
+	}
+
 	if len(resp.LockedCoinOutputs) > 0 {
 		fmt.Println("Locked unspent coin outputs:")
 		for _, uco := range resp.LockedCoinOutputs {
 			fmt.Println("ID:", uco.ID)
+			printOutputLabel(uco.Output.Condition.UnlockHash())
 			fmt.Println("Creation Value:", currencyConvertor.ToCoinStringWithUnit(uco.CoinInfo.CreationValue))
 			fmt.Println("Current Age:", time.Second*time.Duration(uco.CoinInfo.FeeComputationTime-uco.CoinInfo.CreationTime))
 			fmt.Println("Spendable Value:", currencyConvertor.ToCoinStringWithUnit(uco.CoinInfo.SpendableValue))
@@ -1162,6 +2106,7 @@ func (walletCmd *walletCmd) listLockedCmd(_ *cobra.Command, args []string) {
 		fmt.Println("Locked unspent blockstake outputs:")
 		for _, ubso := range resp.LockedBlockstakeOutputs {
 			fmt.Println("ID:", ubso.ID)
+			printOutputLabel(ubso.Output.Condition.UnlockHash())
 			fmt.Println("Value:", ubso.Output.Value, "BS")
 			fmt.Println("Condition:")
 			jsonOutput.Encode(ubso.Output)
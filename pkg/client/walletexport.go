@@ -0,0 +1,273 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	clientpkg "github.com/threefoldtech/rivine/pkg/client"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+)
+
+// the formats supported by the wallet export command
+const (
+	walletExportFormatCSV  = "csv"
+	walletExportFormatJSON = "json"
+	walletExportFormatOFX  = "ofx"
+)
+
+// exportCmd exports the coin movements of the wallet, for accounting purposes,
+// in the format defined by the format flag.
+func (walletCmd *walletCmd) exportCmd() {
+	cfg := walletCmd.walletExportCfg
+	switch cfg.Format {
+	case walletExportFormatCSV, walletExportFormatJSON, walletExportFormatOFX:
+	default:
+		cli.Die("Invalid --format flag: " + cfg.Format + " (expected csv, json or ofx)")
+	}
+	values := url.Values{}
+	if from := parseTransactionsTimeFlag("from", cfg.From); from != "" {
+		values.Set("starttime", from)
+	}
+	if to := parseTransactionsTimeFlag("to", cfg.To); to != "" {
+		values.Set("endtime", to)
+	}
+	weg := new(gcapi.WalletExportGET)
+	err := walletCmd.cli.GetWithResponse("/wallet/export?"+values.Encode(), weg)
+	if err != nil {
+		cli.DieWithError("Could not export the wallet history:", err)
+	}
+
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	switch cfg.Format {
+	case walletExportFormatCSV:
+		err = writeWalletMovementsCSV(os.Stdout, currencyConvertor, weg.Movements)
+	case walletExportFormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(weg.Movements)
+	case walletExportFormatOFX:
+		err = writeWalletMovementsOFX(os.Stdout, currencyConvertor, walletCmd.cli.Config.CurrencyCoinUnit, weg.Movements)
+	}
+	if err != nil {
+		cli.DieWithError("Could not write the wallet history:", err)
+	}
+}
+
+// writeWalletMovementsCSV writes the given movements as CSV, one row per movement,
+// with all amounts expressed in the OneCoin unit.
+func writeWalletMovementsCSV(w io.Writer, cc clientpkg.CurrencyConvertor, movements []gcmodules.WalletMovement) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"timestamp", "transactionid", "direction", "counterparty",
+		"grossamount", "inputid", "custodyfee", "minerfee", "balance",
+	})
+	if err != nil {
+		return err
+	}
+	for _, movement := range movements {
+		var counterparty, inputID string
+		if movement.Counterparty != nil {
+			counterparty = movement.Counterparty.String()
+		}
+		if movement.InputID != nil {
+			inputID = movement.InputID.String()
+		}
+		err = writer.Write([]string{
+			time.Unix(int64(movement.Timestamp), 0).UTC().Format(time.RFC3339),
+			movement.TransactionID.String(),
+			string(movement.Direction),
+			counterparty,
+			exportCoinString(cc, movement.GrossAmount),
+			inputID,
+			exportCoinString(cc, movement.CustodyFee),
+			exportCoinString(cc, movement.MinerFee),
+			exportCoinString(cc, movement.Balance),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type (
+	// ofxDocument is the (minimal) OFX 2.2 bank statement document,
+	// used to export the movements of the wallet.
+	ofxDocument struct {
+		XMLName xml.Name `xml:"OFX"`
+		SignOn  struct {
+			Status   ofxStatus `xml:"SONRS>STATUS"`
+			DTServer string    `xml:"SONRS>DTSERVER"`
+			Language string    `xml:"SONRS>LANGUAGE"`
+		} `xml:"SIGNONMSGSRSV1"`
+		Statement struct {
+			TrnUID      string           `xml:"TRNUID"`
+			Status      ofxStatus        `xml:"STATUS"`
+			CurDef      string           `xml:"STMTRS>CURDEF"`
+			BankID      string           `xml:"STMTRS>BANKACCTFROM>BANKID"`
+			AcctID      string           `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+			AcctType    string           `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+			DTStart     string           `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+			DTEnd       string           `xml:"STMTRS>BANKTRANLIST>DTEND"`
+			Entries     []ofxTransaction `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+			BalanceAmt  string           `xml:"STMTRS>LEDGERBAL>BALAMT"`
+			BalanceAsOf string           `xml:"STMTRS>LEDGERBAL>DTASOF"`
+		} `xml:"BANKMSGSRSV1>STMTTRNRS"`
+	}
+	ofxStatus struct {
+		Code     int    `xml:"CODE"`
+		Severity string `xml:"SEVERITY"`
+	}
+	ofxTransaction struct {
+		TrnType  string `xml:"TRNTYPE"`
+		DTPosted string `xml:"DTPOSTED"`
+		TrnAmt   string `xml:"TRNAMT"`
+		FitID    string `xml:"FITID"`
+		Name     string `xml:"NAME"`
+		Memo     string `xml:"MEMO,omitempty"`
+	}
+)
+
+// writeWalletMovementsOFX writes the given movements as an OFX bank statement,
+// one statement transaction per movement, with all amounts expressed in the OneCoin unit.
+func writeWalletMovementsOFX(w io.Writer, cc clientpkg.CurrencyConvertor, coinUnit string, movements []gcmodules.WalletMovement) error {
+	now := ofxTime(types.CurrentTimestamp())
+	var doc ofxDocument
+	doc.SignOn.Status = ofxStatus{Severity: "INFO"}
+	doc.SignOn.DTServer = now
+	doc.SignOn.Language = "ENG"
+	doc.Statement.TrnUID = "0"
+	doc.Statement.Status = ofxStatus{Severity: "INFO"}
+	doc.Statement.CurDef = strings.ToUpper(coinUnit)
+	doc.Statement.BankID = "goldchain"
+	doc.Statement.AcctID = "wallet"
+	doc.Statement.AcctType = "CHECKING"
+	doc.Statement.DTStart, doc.Statement.DTEnd = now, now
+	doc.Statement.BalanceAmt, doc.Statement.BalanceAsOf = "0", now
+	if len(movements) > 0 {
+		doc.Statement.DTStart = ofxTime(movements[0].Timestamp)
+		doc.Statement.DTEnd = ofxTime(movements[len(movements)-1].Timestamp)
+		doc.Statement.BalanceAmt = exportCoinString(cc, movements[len(movements)-1].Balance)
+		doc.Statement.BalanceAsOf = doc.Statement.DTEnd
+	}
+
+	// movements of the same transaction share the transaction ID,
+	// hence the index of the movement within its transaction is added to make the FITID unique
+	var (
+		lastTxID types.TransactionID
+		index    int
+	)
+	for _, movement := range movements {
+		if movement.TransactionID == lastTxID {
+			index++
+		} else {
+			lastTxID, index = movement.TransactionID, 0
+		}
+		entry := ofxTransaction{
+			DTPosted: ofxTime(movement.Timestamp),
+			FitID:    movement.TransactionID.String() + "-" + strconv.Itoa(index),
+		}
+		switch {
+		case movement.Direction == gcmodules.TransactionDirectionIncoming:
+			entry.TrnType, entry.Name = "CREDIT", "Incoming coins"
+			entry.TrnAmt = exportCoinString(cc, movement.GrossAmount)
+		case movement.InputID != nil:
+			entry.TrnType, entry.Name = "FEE", "Custody fee"
+			entry.TrnAmt = "-" + exportCoinString(cc, movement.CustodyFee)
+			entry.Memo = "input " + movement.InputID.String()
+		case !movement.MinerFee.IsZero():
+			entry.TrnType, entry.Name = "FEE", "Miner fee"
+			entry.TrnAmt = "-" + exportCoinString(cc, movement.MinerFee)
+		default:
+			entry.TrnType, entry.Name = "DEBIT", "Outgoing coins"
+			entry.TrnAmt = "-" + exportCoinString(cc, movement.GrossAmount)
+		}
+		if movement.Counterparty != nil {
+			entry.Memo = movement.Counterparty.String()
+		}
+		doc.Statement.Entries = append(doc.Statement.Entries, entry)
+	}
+
+	_, err := fmt.Fprintln(w, xml.Header+`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// ofxTime formats the given timestamp as an OFX UTC datetime.
+func ofxTime(ts types.Timestamp) string {
+	return time.Unix(int64(ts), 0).UTC().Format("20060102150405")
+}
+
+// exportCoinString formats the given currency in the OneCoin unit,
+// without the thousands separators, such that it can be parsed by accounting software.
+func exportCoinString(cc clientpkg.CurrencyConvertor, c types.Currency) string {
+	return strings.Replace(cc.ToCoinString(c), ",", "", -1)
+}
+
+// statementCmd prints the statement of the wallet, summarizing
+// its coin movements and custody fees per period.
+func (walletCmd *walletCmd) statementCmd() {
+	cfg := walletCmd.walletStatementCfg
+	values := url.Values{"period": []string{cfg.Period}}
+	if from := parseTransactionsTimeFlag("from", cfg.From); from != "" {
+		values.Set("starttime", from)
+	}
+	if to := parseTransactionsTimeFlag("to", cfg.To); to != "" {
+		values.Set("endtime", to)
+	}
+	wsg := new(gcapi.WalletStatementGET)
+	err := walletCmd.cli.GetWithResponse("/wallet/statement?"+values.Encode(), wsg)
+	if err != nil {
+		cli.DieWithError("Could not fetch the wallet statement:", err)
+	}
+	if len(wsg.Periods) == 0 {
+		fmt.Println("This wallet has no statement for the given period.")
+		return
+	}
+
+	layout := "2006-01"
+	if cfg.Period == string(gcmodules.StatementPeriodDay) {
+		layout = "2006-01-02"
+	}
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "period\topening\tincoming\toutgoing\tcustody fees paid\tminer fees paid\tcustody fees accrued\tclosing\t")
+	for _, period := range wsg.Periods {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			time.Unix(int64(period.Start), 0).UTC().Format(layout),
+			currencyConvertor.ToCoinString(period.OpeningBalance),
+			currencyConvertor.ToCoinString(period.Incoming),
+			currencyConvertor.ToCoinString(period.Outgoing),
+			currencyConvertor.ToCoinString(period.CustodyFeesPaid),
+			currencyConvertor.ToCoinString(period.MinerFeesPaid),
+			currencyConvertor.ToCoinString(period.CustodyFeesAccrued),
+			currencyConvertor.ToCoinString(period.ClosingBalance))
+	}
+	w.Flush()
+	fmt.Println()
+	fmt.Println("All amounts are expressed in", walletCmd.cli.Config.CurrencyCoinUnit+",",
+		"custody fees accrued are not yet paid at the end of each period.")
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/types"
+
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+)
+
+// addressLabels maps addresses to the labels, notes and tags defined for them by the wallet.
+type addressLabels map[types.UnlockHash]gcmodules.AddressLabel
+
+// fetchAddressLabels fetches the labels of all labeled addresses of the wallet.
+// Labels are only used to annotate the output of other commands,
+// hence no labels are returned if they cannot be fetched.
+func (walletCmd *walletCmd) fetchAddressLabels() addressLabels {
+	var resp gcapi.WalletLabelsGET
+	err := walletCmd.cli.GetWithResponse("/wallet/labels", &resp)
+	if err != nil {
+		return nil
+	}
+	labels := make(addressLabels, len(resp.Labels))
+	for _, label := range resp.Labels {
+		labels[label.Address] = label
+	}
+	return labels
+}
+
+// Name returns the label of the given address,
+// or its tags if it has no label, and an empty string if the address is not labeled.
+func (labels addressLabels) Name(uh types.UnlockHash) string {
+	label, ok := labels[uh]
+	if !ok {
+		return ""
+	}
+	if label.Label != "" {
+		return label.Label
+	}
+	return strings.Join(label.Tags, ",")
+}
+
+// Annotate returns the given address, followed by its label between parentheses if it is labeled.
+func (labels addressLabels) Annotate(uh types.UnlockHash) string {
+	if name := labels.Name(uh); name != "" {
+		return uh.String() + " (" + name + ")"
+	}
+	return uh.String()
+}
+
+// TransactionNames returns the unique names of all labeled addresses
+// related to the inputs and outputs of the given transaction.
+func (labels addressLabels) TransactionNames(txn gcmodules.ProcessedTransaction) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	var (
+		names []string
+		known = make(map[types.UnlockHash]struct{})
+	)
+	addName := func(uh types.UnlockHash) {
+		if _, ok := known[uh]; ok {
+			return
+		}
+		known[uh] = struct{}{}
+		if name := labels.Name(uh); name != "" {
+			names = append(names, name)
+		}
+	}
+	for _, input := range txn.Inputs {
+		addName(input.RelatedAddress)
+	}
+	for _, output := range txn.Outputs {
+		addName(output.RelatedAddress)
+	}
+	return names
+}
+
+// printTransactionLabels prints the names of the labeled addresses related to the given transaction, if any,
+// indented such that they are aligned with the transaction ID printed by the transaction listing commands.
+func printTransactionLabels(labels addressLabels, txn gcmodules.ProcessedTransaction) {
+	if names := labels.TransactionNames(txn); len(names) > 0 {
+		fmt.Printf("%12s %s\n", "", "labels: "+strings.Join(names, ", "))
+	}
+}
+
+// labelsListCmd lists all labeled addresses of the wallet.
+func (walletCmd *walletCmd) labelsListCmd() {
+	var resp gcapi.WalletLabelsGET
+	err := walletCmd.cli.GetWithResponse("/wallet/labels", &resp)
+	if err != nil {
+		cli.DieWithError("Could not get address labels:", err)
+	}
+	if len(resp.Labels) == 0 {
+		fmt.Println("This wallet has no labeled addresses.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "address\towned\tlabel\ttags\tnotes")
+	for _, label := range resp.Labels {
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n",
+			label.Address.String(), label.Owned, label.Label,
+			strings.Join(label.Tags, ","), strings.Replace(label.Notes, "\n", " ", -1))
+	}
+	w.Flush()
+}
+
+// labelsSetCmd labels an address, owned by the wallet or not,
+// replacing the label, notes and tags previously defined for it.
+func (walletCmd *walletCmd) labelsSetCmd(cmd *cobra.Command, args []string) {
+	label := gcmodules.AddressLabel{Notes: walletCmd.walletLabelsCfg.Notes}
+	if err := label.Address.LoadString(args[0]); err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(fmt.Sprintf("%q is not a valid address: %v", args[0], err))
+	}
+	if len(args) == 2 {
+		label.Label = args[1]
+	}
+	if walletCmd.walletLabelsCfg.Tags != "" {
+		label.Tags = strings.Split(walletCmd.walletLabelsCfg.Tags, ",")
+	}
+	walletCmd.postAddressLabels("/wallet/labels", gcapi.WalletLabelsPOST{
+		Labels: []gcmodules.AddressLabel{label},
+	})
+	fmt.Println("Labeled address", label.Address.String())
+}
+
+// labelsRemoveCmd removes the labels of one or multiple addresses.
+func (walletCmd *walletCmd) labelsRemoveCmd(cmd *cobra.Command, args []string) {
+	var body gcapi.WalletLabelsRemovePOST
+	for _, arg := range args {
+		var uh types.UnlockHash
+		if err := uh.LoadString(arg); err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.Die(fmt.Sprintf("%q is not a valid address: %v", arg, err))
+		}
+		body.Addresses = append(body.Addresses, uh)
+	}
+	walletCmd.postAddressLabels("/wallet/labels/remove", body)
+	fmt.Println("Removed the labels of", len(args), "address(es)")
+}
+
+func (walletCmd *walletCmd) postAddressLabels(call string, body interface{}) {
+	buffer := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buffer).Encode(body)
+	if err != nil {
+		cli.Die("Could not encode address labels:", err)
+	}
+	err = walletCmd.cli.Post(call, buffer.String())
+	if err != nil {
+		cli.DieWithError("Failed to update address labels:", err)
+	}
+}
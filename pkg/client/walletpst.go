@@ -0,0 +1,252 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+	gcmodules "github.com/nbh-digital/goldchain/modules"
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+)
+
+// readJSONArgument returns the given argument as is in case it is raw JSON,
+// and the content of the file it refers to otherwise.
+func readJSONArgument(arg string) string {
+	if trimmed := strings.TrimSpace(arg); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return arg
+	}
+	b, err := ioutil.ReadFile(arg)
+	if err != nil {
+		cli.Die(fmt.Sprintf("%q is neither raw JSON nor a readable JSON file: %v", arg, err))
+	}
+	return string(b)
+}
+
+func (walletCmd *walletCmd) pstCreateCmd(cmd *cobra.Command, args []string) {
+	var txn types.Transaction
+	err := json.Unmarshal([]byte(readJSONArgument(args[0])), &txn)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid transaction:", err)
+	}
+	b, err := json.Marshal(gcapi.WalletPSTCreatePOST{Transaction: txn})
+	if err != nil {
+		cli.Die("Failed to JSON Marshal the transaction:", err)
+	}
+	var resp gcapi.WalletPSTPOSTResp
+	err = walletCmd.cli.PostWithResponse("/wallet/pst/create", string(b), &resp)
+	if err != nil {
+		cli.DieWithError("Failed to create partially signed transaction:", err)
+	}
+	json.NewEncoder(os.Stdout).Encode(resp.PST)
+}
+
+func (walletCmd *walletCmd) pstSignCmd(cmd *cobra.Command, args []string) {
+	var resp gcapi.WalletPSTPOSTResp
+	err := walletCmd.cli.PostWithResponse("/wallet/pst/sign", readJSONArgument(args[0]), &resp)
+	if err != nil {
+		cli.DieWithError("Failed to sign partially signed transaction:", err)
+	}
+	json.NewEncoder(os.Stdout).Encode(resp.PST)
+	// report the status on STDERR, such that STDOUT only contains the signed PST
+	printPSTStatus(os.Stderr, resp)
+}
+
+func (walletCmd *walletCmd) pstCombineCmd(cmd *cobra.Command, args []string) {
+	var body struct {
+		PSTs []json.RawMessage `json:"psts"`
+	}
+	for _, arg := range args {
+		body.PSTs = append(body.PSTs, json.RawMessage(readJSONArgument(arg)))
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid partially signed transactions:", err)
+	}
+	var resp gcapi.WalletPSTPOSTResp
+	err = walletCmd.cli.PostWithResponse("/wallet/pst/combine", string(b), &resp)
+	if err != nil {
+		cli.DieWithError("Failed to combine partially signed transactions:", err)
+	}
+	json.NewEncoder(os.Stdout).Encode(resp.PST)
+	printPSTStatus(os.Stderr, resp)
+}
+
+func (walletCmd *walletCmd) pstInspectCmd(cmd *cobra.Command, args []string) {
+	var resp gcapi.WalletPSTPOSTResp
+	err := walletCmd.cli.PostWithResponse("/wallet/pst/inspect", readJSONArgument(args[0]), &resp)
+	if err != nil {
+		cli.DieWithError("Failed to inspect partially signed transaction:", err)
+	}
+
+	pst := resp.PST
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	labels := walletCmd.fetchAddressLabels()
+	fmt.Printf("Partially signed transaction %s (version %d)\n", resp.TransactionID.String(), pst.Version)
+	printPSTStatus(os.Stdout, resp)
+
+	if len(pst.CoinInputs) > 0 {
+		fmt.Println("\nCoin inputs:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tparent\taddress\tvalue\tcustody fee\tsignatures")
+		for idx, ci := range pst.CoinInputs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", idx+1,
+				ci.ParentID.String(),
+				labels.Annotate(ci.Parent.Condition.UnlockHash()),
+				currencyConvertor.ToCoinStringWithUnit(ci.Parent.Value),
+				currencyConvertor.ToCoinStringWithUnit(ci.CustodyFee),
+				formatPSTSignatureStatus(ci.Signatures))
+		}
+		w.Flush()
+	}
+	if len(pst.BlockStakeInputs) > 0 {
+		fmt.Println("\nBlock stake inputs:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tparent\taddress\tvalue\tsignatures")
+		for idx, bsi := range pst.BlockStakeInputs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s BS\t%s\n", idx+1,
+				bsi.ParentID.String(),
+				labels.Annotate(bsi.Parent.Condition.UnlockHash()),
+				bsi.Parent.Value.String(),
+				formatPSTSignatureStatus(bsi.Signatures))
+		}
+		w.Flush()
+	}
+	if pst.Extension != nil {
+		fmt.Printf("\nExtension condition %s: %s\n",
+			labels.Annotate(pst.Extension.Condition.UnlockHash()),
+			formatPSTSignatureStatus(pst.Extension.Signatures))
+	}
+
+	if len(pst.Transaction.CoinOutputs) > 0 {
+		fmt.Println("\nCoin outputs:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, co := range pst.Transaction.CoinOutputs {
+			receiver := labels.Annotate(co.Condition.UnlockHash())
+			if _, ok := co.Condition.Condition.(*cftypes.CustodyFeeCondition); ok {
+				receiver = "custody fee"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", receiver, currencyConvertor.ToCoinStringWithUnit(co.Value))
+		}
+		w.Flush()
+	}
+	if len(pst.Transaction.BlockStakeOutputs) > 0 {
+		fmt.Println("\nBlock stake outputs:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, bso := range pst.Transaction.BlockStakeOutputs {
+			fmt.Fprintf(w, "%s\t%s BS\n", labels.Annotate(bso.Condition.UnlockHash()), bso.Value.String())
+		}
+		w.Flush()
+	}
+	var minerFees types.Currency
+	for _, fee := range pst.Transaction.MinerFees {
+		minerFees = minerFees.Add(fee)
+	}
+	fmt.Println("\nMiner fees:", currencyConvertor.ToCoinStringWithUnit(minerFees))
+
+	if pst.CustodyFee != nil {
+		fmt.Printf("\nCustody fee (computed at %s):\n", pst.CustodyFee.ComputationTime.String())
+		fmt.Println("  expected:", currencyConvertor.ToCoinStringWithUnit(pst.CustodyFee.Expected))
+		fmt.Println("  paid:    ", currencyConvertor.ToCoinStringWithUnit(pst.CustodyFee.Paid))
+		if !pst.CustodyFee.Expected.Equals(pst.CustodyFee.Paid) {
+			fmt.Println("  WARNING: the paid custody fee does not match the expected custody fee")
+		}
+	}
+}
+
+func (walletCmd *walletCmd) pstFinalizeCmd(cmd *cobra.Command, args []string) {
+	var resp gcapi.WalletPSTFinalizePOSTResp
+	err := walletCmd.cli.PostWithResponse("/wallet/pst/finalize", readJSONArgument(args[0]), &resp)
+	if err != nil {
+		cli.DieWithError("Failed to finalize partially signed transaction:", err)
+	}
+	fmt.Println("Published transaction", resp.TransactionID.String())
+}
+
+// sendMultiSigCoins sends the given coin outputs from the multisig wallet defined by the --from-multisig flag,
+// publishing the transaction immediately if it is signed completely by this wallet.
+func (walletCmd *walletCmd) sendMultiSigCoins(coinOutputs []types.CoinOutput, data []byte) {
+	var address types.UnlockHash
+	err := address.LoadString(walletCmd.sendCoinsCfg.FromMultiSig)
+	if err != nil {
+		cli.DieWithError("invalid multisig address specified", err)
+	}
+	// any refund goes back to the multisig wallet
+	walletCmd.checkCoinOutputsAuthorization(coinOutputs, &address, walletCmd.sendCoinsCfg.AllowUnauthorized)
+
+	b, err := json.Marshal(gcapi.WalletMultiSigFundPOST{
+		CoinOutputs: coinOutputs,
+		Data:        data,
+	})
+	if err != nil {
+		cli.Die("Failed to JSON Marshal the input body:", err)
+	}
+	var resp gcapi.WalletPSTPOSTResp
+	err = walletCmd.cli.PostWithResponse(fmt.Sprintf("/wallet/multisig/%s/fund", address.String()), string(b), &resp)
+	if err != nil {
+		cli.DieWithError("Could not send coins from multisig wallet:", err)
+	}
+	if !resp.Complete {
+		json.NewEncoder(os.Stdout).Encode(resp.PST)
+		// report the status on STDERR, such that STDOUT only contains the signed PST
+		printPSTStatus(os.Stderr, resp)
+		fmt.Fprintln(os.Stderr, "Have the co-signers sign the partially signed transaction using 'wallet pst sign',",
+			"and publish it using 'wallet pst finalize' once complete")
+		return
+	}
+
+	b, err = json.Marshal(resp.PST)
+	if err != nil {
+		cli.Die("Failed to JSON Marshal the partially signed transaction:", err)
+	}
+	var finalizeResp gcapi.WalletPSTFinalizePOSTResp
+	err = walletCmd.cli.PostWithResponse("/wallet/pst/finalize", string(b), &finalizeResp)
+	if err != nil {
+		cli.DieWithError("Could not send coins from multisig wallet:", err)
+	}
+	fmt.Println("Succesfully sent coins as transaction " + finalizeResp.TransactionID.String())
+}
+
+// printPSTStatus prints whether or not the PST is complete, and which signers are still missing if not.
+func printPSTStatus(w *os.File, resp gcapi.WalletPSTPOSTResp) {
+	if resp.Complete {
+		fmt.Fprintf(w, "Status: complete (%d signatures), ready to be finalized\n", resp.PST.SignatureCount())
+		return
+	}
+	if len(resp.MissingSigners) == 0 {
+		fmt.Fprintf(w, "Status: incomplete (%d signatures)\n", resp.PST.SignatureCount())
+		return
+	}
+	missing := make([]string, 0, len(resp.MissingSigners))
+	for _, uh := range resp.MissingSigners {
+		missing = append(missing, uh.String())
+	}
+	fmt.Fprintf(w, "Status: incomplete (%d signatures), missing signatures of: %s\n", resp.PST.SignatureCount(), strings.Join(missing, ", "))
+}
+
+// formatPSTSignatureStatus formats the amount of signatures provided for a fulfillment,
+// as well as the addresses that can still sign it if it is incomplete.
+func formatPSTSignatureStatus(status gcmodules.PSTSignatureStatus) string {
+	str := fmt.Sprintf("%d/%d", len(status.Signed), status.Required)
+	if status.Complete {
+		return str + " complete"
+	}
+	if len(status.Missing) == 0 {
+		return str + " incomplete"
+	}
+	missing := make([]string, 0, len(status.Missing))
+	for _, uh := range status.Missing {
+		missing = append(missing, uh.String())
+	}
+	return str + " missing: " + strings.Join(missing, ", ")
+}
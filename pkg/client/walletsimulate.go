@@ -0,0 +1,84 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/pkg/cli"
+
+	gcapi "github.com/nbh-digital/goldchain/pkg/api"
+)
+
+// simulateCoinTransaction creates the coin transaction defined by the given body, without sending it,
+// and prints the result of validating it against all rules of the network.
+// The command dies if the transaction would be refused by the network.
+func (walletCmd *walletCmd) simulateCoinTransaction(body api.WalletCoinsPOST) {
+	bytes, err := json.Marshal(&body)
+	if err != nil {
+		cli.Die("Failed to JSON Marshal the input body:", err)
+	}
+	var txnResp api.WalletCreateTransactionRESP
+	err = walletCmd.cli.PostWithResponse("/wallet/create/coins", string(bytes), &txnResp)
+	if err != nil {
+		cli.DieWithError("Could not create coin transaction:", err)
+	}
+	bytes, err = json.Marshal(&txnResp.Transaction)
+	if err != nil {
+		cli.Die("Failed to JSON Marshal the created transaction:", err)
+	}
+	var resp gcapi.TransactionPoolSimulatePOSTResp
+	err = walletCmd.cli.PostWithResponse("/transactionpool/simulate", string(bytes), &resp)
+	if err != nil {
+		cli.DieWithError("Could not simulate coin transaction:", err)
+	}
+
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	fmt.Printf("Simulated transaction %s at block height %d:\n\n", resp.TransactionID.String(), resp.BlockHeight)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "validator\trule\tresult")
+	for _, rule := range resp.Rules {
+		result := "passed"
+		if !rule.Passed {
+			result = "FAILED: " + rule.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rule.Validator, rule.Rule, result)
+	}
+	w.Flush()
+
+	if resp.CustodyFee != nil {
+		fmt.Printf("\nCustody fee (computed at %s):\n", resp.CustodyFee.ComputationTime.String())
+		fmt.Println("  expected:", currencyConvertor.ToCoinStringWithUnit(resp.CustodyFee.Expected))
+		fmt.Println("  paid:    ", currencyConvertor.ToCoinStringWithUnit(resp.CustodyFee.Paid))
+	}
+
+	if len(resp.Balances) > 0 {
+		labels := walletCmd.fetchAddressLabels()
+		fmt.Println("\nBalance changes:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "address\tspent\tcustody fee\treceived\tnet")
+		for _, change := range resp.Balances {
+			var net string
+			if change.Received.Cmp(change.Spent) >= 0 {
+				net = currencyConvertor.ToCoinStringWithUnit(change.Received.Sub(change.Spent))
+			} else {
+				net = "-" + currencyConvertor.ToCoinStringWithUnit(change.Spent.Sub(change.Received))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				labels.Annotate(change.Address),
+				currencyConvertor.ToCoinStringWithUnit(change.Spent),
+				currencyConvertor.ToCoinStringWithUnit(change.CustodyFee),
+				currencyConvertor.ToCoinStringWithUnit(change.Received),
+				net)
+		}
+		w.Flush()
+	}
+
+	fmt.Println()
+	if !resp.Valid {
+		cli.Die("The transaction would be refused by the network, no coins were sent.")
+	}
+	fmt.Println("The transaction would be accepted by the network, no coins were sent.")
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"math"
 	"math/big"
 
 	"github.com/threefoldtech/rivine/build"
@@ -31,6 +32,25 @@ const (
 	NetworkNameTestnet = "testnet"
 )
 
+// FeatureNotActivated is the activation height of a feature,
+// which is not (yet) scheduled to be activated on a network.
+const FeatureNotActivated = types.BlockHeight(math.MaxUint64)
+
+// FeatureActivationHeights defines the block heights at which the consensus features,
+// added during the lifetime of a network, get activated.
+type FeatureActivationHeights struct {
+	// ExtendedArbitraryData activates the proof-of-reserve attestations and redemptions
+	// carried as arbitrary data by coin creation and coin destruction transactions,
+	// which can exceed the arbitrary data size limit of the chain
+	ExtendedArbitraryData types.BlockHeight
+	// AuthorizationExpiry activates the expiry of authorizations,
+	// carried as arbitrary data by auth address update transactions
+	AuthorizationExpiry types.BlockHeight
+	// MultiSignatureAtomicSwap activates the atomic swap contracts
+	// which are redeemed by the co-signers of a multisig receiver
+	MultiSignatureAtomicSwap types.BlockHeight
+}
+
 func GetDefaultGenesis() types.ChainConstants {
 	return GetTestnetGenesis()
 }
@@ -127,6 +147,14 @@ func GetDevnetGenesisAuthCoinCondition() types.UnlockConditionProxy {
 	return types.NewCondition(types.NewUnlockHashCondition(unlockHashFromHex("015a080a9259b9d4aaa550e2156f49b1a79a64c7ea463d810d4493e8242e6791584fbdac553e6f")))
 }
 
+func GetDevnetFeatureActivationHeights() FeatureActivationHeights {
+	return FeatureActivationHeights{
+		ExtendedArbitraryData:    0,
+		AuthorizationExpiry:      0,
+		MultiSignatureAtomicSwap: 0,
+	}
+}
+
 func GetTestnetGenesis() types.ChainConstants {
 	cfg := types.TestnetChainConstants()
 
@@ -211,6 +239,16 @@ func GetTestnetGenesisAuthCoinCondition() types.UnlockConditionProxy {
 	return types.NewCondition(types.NewUnlockHashCondition(unlockHashFromHex("01215a03f0098c4fcd801854da4d7bb2e9c78b4d3598fec89f42bc19fb79889bbf7a6aabdbe95f")))
 }
 
+// GetTestnetFeatureActivationHeights returns the activation heights of the testnet features,
+// features which are not yet scheduled remain inactive until a height is defined here.
+func GetTestnetFeatureActivationHeights() FeatureActivationHeights {
+	return FeatureActivationHeights{
+		ExtendedArbitraryData:    FeatureNotActivated,
+		AuthorizationExpiry:      FeatureNotActivated,
+		MultiSignatureAtomicSwap: FeatureNotActivated,
+	}
+}
+
 func init() {
 	Version = build.MustParse(rawVersion)
 }
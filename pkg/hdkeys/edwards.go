@@ -0,0 +1,76 @@
+package hdkeys
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+
+	"filippo.io/edwards25519"
+)
+
+// The edwards25519 curve arithmetic required by the BIP32-Ed25519 derivation,
+// which cannot be done using the (standard) ed25519 package, as it only exposes
+// keys derived from a seed. The curve and scalar arithmetic is done by the
+// (constant time) filippo.io/edwards25519 package, the unreduced 256-bit additions
+// of the derivation itself are done byte by byte, such that secrets never end up in a big.Int.
+
+var errInvalidPoint = errors.New("invalid ed25519 point")
+
+// newScalar returns the given (little endian) 256-bit integer as a scalar, modulo the order of the base point.
+func newScalar(k []byte) *edwards25519.Scalar {
+	var wide [64]byte
+	copy(wide[:], k)
+	s, err := edwards25519.NewScalar().SetUniformBytes(wide[:])
+	if err != nil {
+		panic(err) // cannot happen, as the input is always 64 bytes long
+	}
+	for i := range wide {
+		wide[i] = 0
+	}
+	return s
+}
+
+// isZeroScalar returns true if the scalar equals zero.
+func isZeroScalar(s *edwards25519.Scalar) bool {
+	return s.Equal(edwards25519.NewScalar()) == 1
+}
+
+// scalarBaseMult returns the encoded public key of the given (little endian) 256-bit integer.
+func scalarBaseMult(k []byte) (publicKey [32]byte) {
+	copy(publicKey[:], new(edwards25519.Point).ScalarBaseMult(newScalar(k)).Bytes())
+	return
+}
+
+// reduceHash returns the SHA-512 hash of the given data, as a scalar modulo the order of the base point.
+func reduceHash(data ...[]byte) *edwards25519.Scalar {
+	h := sha512.New()
+	for _, b := range data {
+		h.Write(b)
+	}
+	s, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		panic(err) // cannot happen, as a SHA-512 hash is always 64 bytes long
+	}
+	return s
+}
+
+// decodePoint decodes the 32-byte (ed25519 public key) encoding of a point,
+// rejecting non-canonical encodings.
+func decodePoint(b [32]byte) (*edwards25519.Point, error) {
+	point, err := new(edwards25519.Point).SetBytes(b[:])
+	if err != nil || !bytes.Equal(point.Bytes(), b[:]) {
+		return nil, errInvalidPoint
+	}
+	return point, nil
+}
+
+// add256 returns a + b modulo 2^256, both being (little endian) 256-bit integers.
+func add256(a, b [32]byte) (sum [32]byte) {
+	var carry uint16
+	for i := range sum {
+		carry += uint16(a[i]) + uint16(b[i])
+		sum[i] = byte(carry)
+		carry >>= 8
+	}
+	return
+}
@@ -0,0 +1,201 @@
+// Package hdkeys implements the (non-hardened) BIP32-Ed25519 key derivation,
+// as specified by Khovratovich and Law, allowing child public keys
+// to be derived from an extended public key, without knowledge of the private key.
+package hdkeys
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"filippo.io/edwards25519"
+)
+
+// HardenedOffset is the first hardened child index,
+// hardened children cannot be derived using this package.
+const HardenedOffset = uint32(1) << 31
+
+var (
+	// ErrHardenedIndex is returned when deriving a child using a hardened index.
+	ErrHardenedIndex = errors.New("hardened child indices are not supported")
+	// ErrInvalidChild is returned in the (negligible) case that a child index results in an invalid key,
+	// in which case the next index should be used instead.
+	ErrInvalidChild = errors.New("child index results in an invalid key")
+	// ErrInvalidExtendedPublicKey is returned when an extended public key cannot be decoded.
+	ErrInvalidExtendedPublicKey = errors.New("invalid extended public key")
+)
+
+// ExtendedPrivateKey is a BIP32-Ed25519 extended private key.
+// KL is the (little endian) secret scalar, KR the nonce key used for signing.
+type ExtendedPrivateKey struct {
+	KL        [32]byte
+	KR        [32]byte
+	ChainCode [32]byte
+}
+
+// ExtendedPublicKey is a BIP32-Ed25519 extended public key,
+// from which non-hardened child public keys can be derived.
+type ExtendedPublicKey struct {
+	PublicKey [32]byte
+	ChainCode [32]byte
+}
+
+// NewExtendedPrivateKey creates a root extended private key,
+// from a 32-byte secret and chain code (e.g. a SLIP-10 derived node).
+func NewExtendedPrivateKey(secret, chainCode [32]byte) ExtendedPrivateKey {
+	k := sha512.Sum512(secret[:])
+	var key ExtendedPrivateKey
+	copy(key.KL[:], k[:32])
+	copy(key.KR[:], k[32:])
+	key.KL[0] &= 0xf8
+	key.KL[31] &= 0x1f
+	key.KL[31] |= 0x40
+	key.ChainCode = chainCode
+	for i := range k {
+		k[i] = 0
+	}
+	return key
+}
+
+// PublicKey returns the ed25519 public key of the extended private key.
+func (key ExtendedPrivateKey) PublicKey() [32]byte {
+	return scalarBaseMult(key.KL[:])
+}
+
+// Public returns the extended public key of the extended private key.
+func (key ExtendedPrivateKey) Public() ExtendedPublicKey {
+	return ExtendedPublicKey{
+		PublicKey: key.PublicKey(),
+		ChainCode: key.ChainCode,
+	}
+}
+
+// Child derives the non-hardened child extended private key at the given index.
+func (key ExtendedPrivateKey) Child(index uint32) (ExtendedPrivateKey, error) {
+	if index >= HardenedOffset {
+		return ExtendedPrivateKey{}, ErrHardenedIndex
+	}
+	zL, zR, chainCode := childTweak(key.PublicKey(), key.ChainCode, index)
+	// kL' = kL + 8*zL
+	kL := add256(key.KL, zL)
+	if isZeroScalar(newScalar(kL[:])) {
+		return ExtendedPrivateKey{}, ErrInvalidChild
+	}
+	// kR' = kR + zR mod 2^256
+	return ExtendedPrivateKey{
+		KL:        kL,
+		KR:        add256(key.KR, zR),
+		ChainCode: chainCode,
+	}, nil
+}
+
+// Sign signs the message, producing a signature
+// which can be verified as a regular ed25519 signature.
+func (key ExtendedPrivateKey) Sign(message []byte) []byte {
+	publicKey := key.PublicKey()
+	r := reduceHash(key.KR[:], message)
+	encodedR := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+	h := reduceHash(encodedR, publicKey[:], message)
+	// S = r + h*kL mod l
+	s := edwards25519.NewScalar().MultiplyAdd(h, newScalar(key.KL[:]), r)
+	signature := make([]byte, 64)
+	copy(signature, encodedR)
+	copy(signature[32:], s.Bytes())
+	return signature
+}
+
+// Wipe erases the secret material of the extended private key.
+func (key *ExtendedPrivateKey) Wipe() {
+	*key = ExtendedPrivateKey{}
+}
+
+// Child derives the non-hardened child extended public key at the given index.
+func (key ExtendedPublicKey) Child(index uint32) (ExtendedPublicKey, error) {
+	if index >= HardenedOffset {
+		return ExtendedPublicKey{}, ErrHardenedIndex
+	}
+	point, err := decodePoint(key.PublicKey)
+	if err != nil {
+		return ExtendedPublicKey{}, err
+	}
+	zL, _, chainCode := childTweak(key.PublicKey, key.ChainCode, index)
+	// A' = A + 8*zL*B
+	child := new(edwards25519.Point).Add(point, new(edwards25519.Point).ScalarBaseMult(newScalar(zL[:])))
+	if child.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return ExtendedPublicKey{}, ErrInvalidChild
+	}
+	extendedKey := ExtendedPublicKey{ChainCode: chainCode}
+	copy(extendedKey.PublicKey[:], child.Bytes())
+	return extendedKey, nil
+}
+
+// String returns the extended public key as a hex-encoded string.
+func (key ExtendedPublicKey) String() string {
+	return hex.EncodeToString(key.PublicKey[:]) + hex.EncodeToString(key.ChainCode[:])
+}
+
+// LoadString loads the extended public key from a hex-encoded string.
+func (key *ExtendedPublicKey) LoadString(str string) error {
+	b, err := hex.DecodeString(str)
+	if err != nil || len(b) != 64 {
+		return ErrInvalidExtendedPublicKey
+	}
+	var loaded ExtendedPublicKey
+	copy(loaded.PublicKey[:], b[:32])
+	copy(loaded.ChainCode[:], b[32:])
+	if _, err = decodePoint(loaded.PublicKey); err != nil {
+		return ErrInvalidExtendedPublicKey
+	}
+	*key = loaded
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON
+func (key ExtendedPublicKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(key.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON
+func (key *ExtendedPublicKey) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	if err := key.LoadString(str); err != nil {
+		return fmt.Errorf("failed to decode extended public key %q: %v", str, err)
+	}
+	return nil
+}
+
+// childTweak returns 8*zL, zR and the child chain code for the given non-hardened index,
+// the former two as (little endian) 256-bit integers.
+func childTweak(publicKey, chainCode [32]byte, index uint32) (zL, zR, childChainCode [32]byte) {
+	var data [1 + 32 + 4]byte
+	copy(data[1:], publicKey[:])
+	binary.LittleEndian.PutUint32(data[33:], index)
+
+	data[0] = 0x02
+	mac := hmac.New(sha512.New, chainCode[:])
+	mac.Write(data[:])
+	z := mac.Sum(nil)
+
+	data[0] = 0x03
+	mac = hmac.New(sha512.New, chainCode[:])
+	mac.Write(data[:])
+	copy(childChainCode[:], mac.Sum(nil)[32:])
+
+	// 8*zL, zL being the first 28 bytes of z
+	for i := 0; i < 28; i++ {
+		zL[i] |= z[i] << 3
+		zL[i+1] = z[i] >> 5
+	}
+	copy(zR[:], z[32:])
+	for i := range z {
+		z[i] = 0
+	}
+	return
+}
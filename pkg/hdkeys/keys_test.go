@@ -0,0 +1,263 @@
+package hdkeys
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func testRootKey() ExtendedPrivateKey {
+	var secret, chainCode [32]byte
+	for i := range secret {
+		secret[i] = byte(i)
+		chainCode[i] = byte(255 - i)
+	}
+	return NewExtendedPrivateKey(secret, chainCode)
+}
+
+// the public key of a root key equals the ed25519 public key of the same secret,
+// as both clamp the SHA-512 hash of the secret the same way (apart from the cleared third highest bit)
+func TestScalarBaseMult(t *testing.T) {
+	for _, seed := range [][]byte{bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{0xab}, 32)} {
+		h := sha512.Sum512(seed)
+		h[0] &= 0xf8
+		h[31] &= 0x7f
+		h[31] |= 0x40
+		publicKey := scalarBaseMult(h[:32])
+		expected := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+		if !bytes.Equal(publicKey[:], expected) {
+			t.Errorf("unexpected public key %x, expected %x", publicKey, expected)
+		}
+		point, err := decodePoint(publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(point.Bytes(), publicKey[:]) {
+			t.Error("decoded point does not encode to the same public key")
+		}
+	}
+}
+
+func TestPublicChildDerivation(t *testing.T) {
+	key := testRootKey()
+	for _, path := range [][]uint32{{0}, {1}, {0, 0}, {0, 7}, {1, 42}, {HardenedOffset - 1}} {
+		private, public := key, key.Public()
+		var err error
+		for _, index := range path {
+			private, err = private.Child(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			public, err = public.Child(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if private.Public() != public {
+			t.Errorf("path %v: public derivation %v differs from private derivation %v", path, public, private.Public())
+		}
+	}
+	sibling, _ := key.Child(1)
+	child, _ := key.Child(0)
+	if sibling.PublicKey() == child.PublicKey() {
+		t.Error("different indices derived the same key")
+	}
+}
+
+// known answer vectors of the BIP32-Ed25519 (non-hardened) derivation and signing,
+// computed using a (math/big based) implementation independent of the edwards25519 package,
+// the public key of the root key equals the public key of the first test vector of RFC 8032
+func TestDerivationVectors(t *testing.T) {
+	var secret, chainCode [32]byte
+	copy(secret[:], mustDecodeHex(t, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"))
+	copy(chainCode[:], mustDecodeHex(t, "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6"))
+	root := NewExtendedPrivateKey(secret, chainCode)
+	testCases := []struct {
+		Path                 []uint32
+		KL, KR, ChainCode    string
+		PublicKey, Signature string
+	}{
+		{
+			nil,
+			"307c83864f2833cb427a2ef1c00a013cfdff2768d980c0a3a520f006904de94f",
+			"9b4f0afe280b746a778684e75442502057b7473a03f08f96f5a38e9287e01f8f",
+			"2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6",
+			"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			"c84cd57d90af93060a9622944a869a516a9d2f5d6e53ec2c80709cf096256ee740790e025f1afc42fdaab2063a108184d26cbe5691a1596b9d692d45ed144305",
+		},
+		{
+			[]uint32{0},
+			"20035f952cdabca1c67ccd0eb5586801f02f2f2431beca1728f9f090934de94f",
+			"bf7f1c98faaca7b283b31eac0c738716540a279e3b003a2cf77a6308c0f1b203",
+			"e0306b971b61b88b9dfe353a6f267d93fdbbe230c6b3ef5f1b655ab9fec90258",
+			"113ae7feaae8b670c8e07780525853c72990cb6c3306bc4262c77d76f1469933",
+			"e3493e6d1be989c41990e35325cb0258f20a96ab3a6ceb0d373da7a89bf902cdc23f1ebdc054a614c5c9b3147fb5e12473e808a9adc63310519610dbb9483104",
+		},
+		{
+			[]uint32{1},
+			"0082de5103979b05123fd4c3427cff7b6483a06695b8c71fba662afc904de94f",
+			"25c550c640a417dff680ee8df006672ab2a9605cc65ecbba1a0d6c47ebb8d30d",
+			"fa93434834b8f695849fb0557113b1a6bcfaa87104a39ebdc1850b7bc9aa081d",
+			"81e02ac07df952a0a9a6c78cf72902c3f65a653633dff5864440dcf415f34701",
+			"9ab63102dc3042d67eb286eb600ffdf5c69a4c65f99c5c447f997de56c3fa39668754d2b1069bf5d65a48b4edda06fbbe392dea4b2422e19c45591d4c8e0f409",
+		},
+		{
+			[]uint32{0, 7},
+			"20eac866159c58ca0d7c2612dd9aa90777301010602578ad4460d853984de94f",
+			"44e085948def56d9d201cfdaef6e7cb2519cd285756d38e0ab161e42f6549f18",
+			"94963f416046a3f4471c1aff609a45bc7703949b40acc15aa152fed6777ffc33",
+			"0118da3f8d1730cb980ba0a3f1c78e79b034520bda130a402fbcf9c9e5b56038",
+			"d78dd288daefff3b2b778d145acbbc49040aa04ec5fc5521d671cb15b18184830d7e240c5a3329be9c3097bf52ce635a1fd7443fadea51da28fe795945a2e308",
+		},
+		{
+			[]uint32{HardenedOffset - 1},
+			"4846ae5fbc9048fe45206d5bbe04bb405c29f9e91cdeff66954723d2934de94f",
+			"f7e25975e344beca48fefd514b9d2756242a82e0fbd594bb77c6f673ceedf4f4",
+			"be5908da3b98d811f7e8819560baf2f30b0b2a485845dc6b19d1a709c8e546e6",
+			"8c8de586b654ee5fae1f43175a2217dfcede8df4f8e331808277cad1c6c7b6de",
+			"42326751e4335eaf119f0d59405bc79657a255e3fbc93e3c1ecd46a8bd51f71a8dfffdf2bbd671603a31183d8b4fd592c41553461c86c0f841f35a1d4671f60c",
+		},
+	}
+	for _, testCase := range testCases {
+		key, public := root, root.Public()
+		var err error
+		for _, index := range testCase.Path {
+			if key, err = key.Child(index); err != nil {
+				t.Fatal(err)
+			}
+			if public, err = public.Child(index); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if str := hex.EncodeToString(key.KL[:]); str != testCase.KL {
+			t.Errorf("path %v: unexpected kL %s, expected %s", testCase.Path, str, testCase.KL)
+		}
+		if str := hex.EncodeToString(key.KR[:]); str != testCase.KR {
+			t.Errorf("path %v: unexpected kR %s, expected %s", testCase.Path, str, testCase.KR)
+		}
+		if str := hex.EncodeToString(key.ChainCode[:]); str != testCase.ChainCode {
+			t.Errorf("path %v: unexpected chain code %s, expected %s", testCase.Path, str, testCase.ChainCode)
+		}
+		publicKey := key.PublicKey()
+		if str := hex.EncodeToString(publicKey[:]); str != testCase.PublicKey {
+			t.Errorf("path %v: unexpected public key %s, expected %s", testCase.Path, str, testCase.PublicKey)
+		}
+		if public.PublicKey != publicKey || public.ChainCode != key.ChainCode {
+			t.Errorf("path %v: public derivation %v differs from private derivation %v", testCase.Path, public, key.Public())
+		}
+		if str := hex.EncodeToString(key.Sign([]byte("goldchain"))); str != testCase.Signature {
+			t.Errorf("path %v: unexpected signature %s, expected %s", testCase.Path, str, testCase.Signature)
+		}
+	}
+}
+
+func TestHardenedIndex(t *testing.T) {
+	key := testRootKey()
+	if _, err := key.Child(HardenedOffset); err != ErrHardenedIndex {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := key.Public().Child(HardenedOffset); err != ErrHardenedIndex {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	key := testRootKey()
+	for _, index := range []uint32{0, 1, 1000} {
+		child, err := key.Child(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey := child.PublicKey()
+		message := []byte("goldchain")
+		signature := child.Sign(message)
+		if !ed25519.Verify(publicKey[:], message, signature) {
+			t.Errorf("index %d: signature does not verify", index)
+		}
+		if ed25519.Verify(publicKey[:], []byte("silverchain"), signature) {
+			t.Errorf("index %d: signature verifies for a different message", index)
+		}
+	}
+}
+
+// signing using the clamped SHA-512 hash of an ed25519 seed as extended private key
+// produces the signatures of the test vectors of RFC 8032, section 7.1
+func TestSignRFC8032(t *testing.T) {
+	testCases := []struct {
+		Seed, PublicKey, Message, Signature string
+	}{
+		{
+			"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+			"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			"",
+			"e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+		},
+		{
+			"4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+			"3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			"72",
+			"92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+		},
+		{
+			"c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+			"fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+			"af82",
+			"6291d657deec24024827e69c3abe01a30ce548a284743a445e3680d7db5ac3ac18ff9b538d16f290ae67f760984dc6594a7c15e9716ed28dc027beceea1ec40a",
+		},
+	}
+	for _, testCase := range testCases {
+		h := sha512.Sum512(mustDecodeHex(t, testCase.Seed))
+		h[0] &= 0xf8
+		h[31] &= 0x7f
+		h[31] |= 0x40
+		var key ExtendedPrivateKey
+		copy(key.KL[:], h[:32])
+		copy(key.KR[:], h[32:])
+		publicKey := key.PublicKey()
+		if str := hex.EncodeToString(publicKey[:]); str != testCase.PublicKey {
+			t.Errorf("seed %s: unexpected public key %s, expected %s", testCase.Seed, str, testCase.PublicKey)
+		}
+		if str := hex.EncodeToString(key.Sign(mustDecodeHex(t, testCase.Message))); str != testCase.Signature {
+			t.Errorf("seed %s: unexpected signature %s, expected %s", testCase.Seed, str, testCase.Signature)
+		}
+	}
+}
+
+func TestExtendedPublicKeyEncoding(t *testing.T) {
+	key := testRootKey().Public()
+	var loaded ExtendedPublicKey
+	if err := loaded.LoadString(key.String()); err != nil {
+		t.Fatal(err)
+	}
+	if loaded != key {
+		t.Errorf("loaded %v, expected %v", loaded, key)
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded = ExtendedPublicKey{}
+	if err = json.Unmarshal(b, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded != key {
+		t.Errorf("unmarshaled %v, expected %v", loaded, key)
+	}
+	for _, str := range []string{"", "00", key.String()[2:], key.String() + "00"} {
+		if err = loaded.LoadString(str); err == nil {
+			t.Errorf("loaded invalid extended public key %q", str)
+		}
+	}
+}
+
+func mustDecodeHex(t *testing.T, str string) []byte {
+	b, err := hex.DecodeString(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
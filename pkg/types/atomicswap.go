@@ -0,0 +1,334 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+const (
+	// ConditionTypeMultiSignatureAtomicSwap defines the MultiSignatureAtomicSwapCondition,
+	// an atomic swap condition of which the receiver is a multisig wallet.
+	//
+	// Implemented by the MultiSignatureAtomicSwapCondition type.
+	ConditionTypeMultiSignatureAtomicSwap types.ConditionType = 129
+
+	// FulfillmentTypeMultiSignatureAtomicSwap defines the MultiSignatureAtomicSwapFulfillment,
+	// the fulfillment used to redeem or refund a MultiSignatureAtomicSwapCondition.
+	//
+	// Implemented by the MultiSignatureAtomicSwapFulfillment type.
+	FulfillmentTypeMultiSignatureAtomicSwap types.FulfillmentType = 129
+)
+
+const (
+	// UnlockTypeMultiSignatureAtomicSwap is the unlock type of the unlock hash used for the MultiSignatureAtomicSwap condition.
+	UnlockTypeMultiSignatureAtomicSwap types.UnlockType = 129
+)
+
+// RegisterMultiSignatureAtomicSwapTypes registers the multisig atomic swap condition and fulfillment types,
+// such that they can be decoded as part of a transaction.
+func RegisterMultiSignatureAtomicSwapTypes() {
+	types.RegisterUnlockConditionType(ConditionTypeMultiSignatureAtomicSwap,
+		func() types.MarshalableUnlockCondition { return &MultiSignatureAtomicSwapCondition{} })
+	types.RegisterUnlockFulfillmentType(FulfillmentTypeMultiSignatureAtomicSwap,
+		func() types.MarshalableUnlockFulfillment { return &MultiSignatureAtomicSwapFulfillment{} })
+}
+
+// MultiSignatureAtomicSwapCondition implements the ConditionTypeMultiSignatureAtomicSwap (unlock) ConditionType.
+// Just like the regular atomic swap condition, it can be redeemed by revealing the secret matching the hashed secret,
+// or refunded by the sender once the time lock has been reached. Redeeming it however requires
+// at least the minimum amount of signatures of its receivers, the co-signers of a multisig wallet.
+type MultiSignatureAtomicSwapCondition struct {
+	Sender                types.UnlockHash             `json:"sender"`
+	Receivers             []types.UnlockHash           `json:"receivers"`
+	MinimumSignatureCount uint64                       `json:"minimumsignaturecount"`
+	HashedSecret          types.AtomicSwapHashedSecret `json:"hashedsecret"`
+	TimeLock              types.Timestamp              `json:"timelock"`
+}
+
+// NewMultiSignatureAtomicSwapCondition creates an atomic swap condition,
+// redeemable by the co-signers of the given multisig receiver.
+func NewMultiSignatureAtomicSwapCondition(sender types.UnlockHash, receiver types.MultiSignatureCondition, hashedSecret types.AtomicSwapHashedSecret, timeLock types.Timestamp) *MultiSignatureAtomicSwapCondition {
+	return &MultiSignatureAtomicSwapCondition{
+		Sender:                sender,
+		Receivers:             append([]types.UnlockHash(nil), receiver.UnlockHashes...),
+		MinimumSignatureCount: receiver.MinimumSignatureCount,
+		HashedSecret:          hashedSecret,
+		TimeLock:              timeLock,
+	}
+}
+
+// ReceiverCondition returns the multisig condition of the receiver,
+// to which the coins of a redeemed contract are sent.
+func (as *MultiSignatureAtomicSwapCondition) ReceiverCondition() types.MultiSignatureCondition {
+	return types.MultiSignatureCondition{
+		UnlockHashes:          append(types.UnlockHashSlice(nil), as.Receivers...),
+		MinimumSignatureCount: as.MinimumSignatureCount,
+	}
+}
+
+// AtomicSwapCondition returns the condition as a regular atomic swap condition,
+// the receiver being the address of the multisig receiver.
+func (as *MultiSignatureAtomicSwapCondition) AtomicSwapCondition() types.AtomicSwapCondition {
+	receiver := as.ReceiverCondition()
+	return types.AtomicSwapCondition{
+		Sender:       as.Sender,
+		Receiver:     receiver.UnlockHash(),
+		HashedSecret: as.HashedSecret,
+		TimeLock:     as.TimeLock,
+	}
+}
+
+// Fulfill implements UnlockCondition.Fulfill
+func (as *MultiSignatureAtomicSwapCondition) Fulfill(fulfillment types.UnlockFulfillment, ctx types.FulfillContext) error {
+	msf, ok := fulfillment.(*MultiSignatureAtomicSwapFulfillment)
+	if !ok {
+		return types.ErrUnexpectedUnlockFulfillment
+	}
+
+	// if a secret is given, the receivers want to redeem the contract,
+	// otherwise the sender wants to refund it, which is only possible once the contract has expired
+	signers, required := []types.UnlockHash{as.Sender}, uint64(1)
+	if msf.Secret != (types.AtomicSwapSecret{}) {
+		if types.NewAtomicSwapHashedSecret(msf.Secret) != as.HashedSecret {
+			return types.ErrInvalidPreImageSha256
+		}
+		signers, required = as.Receivers, as.MinimumSignatureCount
+	} else if ctx.BlockTime <= as.TimeLock {
+		return types.ErrPrematureRefund
+	}
+	if uint64(len(msf.Pairs)) < required {
+		return types.ErrInsufficientSignatures
+	}
+
+	// every signer can only sign once
+	remaining := append([]types.UnlockHash(nil), signers...)
+	for _, pair := range msf.Pairs {
+		uh, err := types.NewPubKeyUnlockHash(pair.PublicKey)
+		if err != nil {
+			return err
+		}
+		idx := -1
+		for i, signer := range remaining {
+			if signer.Cmp(uh) == 0 {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return types.ErrUnauthorizedPubKey
+		}
+		remaining = append(remaining[:idx], remaining[idx+1:]...)
+
+		err = verifySignature(pair, ctx.Transaction, msf.SignatureObjects(ctx.ExtraObjects, pair.PublicKey))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConditionType implements UnlockCondition.ConditionType
+func (as *MultiSignatureAtomicSwapCondition) ConditionType() types.ConditionType {
+	return ConditionTypeMultiSignatureAtomicSwap
+}
+
+// IsStandardCondition implements UnlockCondition.IsStandardCondition
+func (as *MultiSignatureAtomicSwapCondition) IsStandardCondition(types.ValidationContext) error {
+	if as.Sender.Type != types.UnlockTypePubKey {
+		return fmt.Errorf("unsupported unlock hash sender type: %d", as.Sender.Type)
+	}
+	if as.Sender.Hash == (crypto.Hash{}) {
+		return errors.New("nil crypto hash cannot be used as unlock hash")
+	}
+	if len(as.Receivers) < 2 {
+		return errors.New("at least two receivers have to be defined")
+	}
+	if as.MinimumSignatureCount == 0 || as.MinimumSignatureCount > uint64(len(as.Receivers)) {
+		return errors.New("the minimum amount of signatures has to be at least one and at most the amount of receivers")
+	}
+	unique := make(map[types.UnlockHash]struct{}, len(as.Receivers))
+	for idx, uh := range as.Receivers {
+		if uh.Type != types.UnlockTypePubKey {
+			return fmt.Errorf("unsupported unlock hash receiver #%d type: %d", idx, uh.Type)
+		}
+		if uh.Hash == (crypto.Hash{}) {
+			return errors.New("nil crypto hash cannot be used as unlock hash")
+		}
+		if _, ok := unique[uh]; ok {
+			return fmt.Errorf("receiver #%d is defined more than once", idx)
+		}
+		unique[uh] = struct{}{}
+	}
+	if as.HashedSecret == (types.AtomicSwapHashedSecret{}) {
+		return errors.New("nil hashed secret not allowed")
+	}
+	return nil
+}
+
+// UnlockHash implements UnlockCondition.UnlockHash
+func (as *MultiSignatureAtomicSwapCondition) UnlockHash() types.UnlockHash {
+	cb, _ := as.Marshal(siabin.MarshalAll)
+	h, _ := crypto.HashObject(cb)
+	return types.NewUnlockHash(UnlockTypeMultiSignatureAtomicSwap, h)
+}
+
+// Equal implements UnlockCondition.Equal
+func (as *MultiSignatureAtomicSwapCondition) Equal(c types.UnlockCondition) bool {
+	oas, ok := c.(*MultiSignatureAtomicSwapCondition)
+	if !ok {
+		return false
+	}
+	if as.Sender.Cmp(oas.Sender) != 0 || as.MinimumSignatureCount != oas.MinimumSignatureCount ||
+		as.HashedSecret != oas.HashedSecret || as.TimeLock != oas.TimeLock || len(as.Receivers) != len(oas.Receivers) {
+		return false
+	}
+	for idx, uh := range as.Receivers {
+		if uh.Cmp(oas.Receivers[idx]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Fulfillable implements UnlockCondition.Fulfillable
+func (as *MultiSignatureAtomicSwapCondition) Fulfillable(types.FulfillableContext) bool { return true }
+
+// Marshal implements MarshalableUnlockCondition.Marshal
+func (as *MultiSignatureAtomicSwapCondition) Marshal(f types.MarshalFunc) ([]byte, error) {
+	return f(as.Sender, as.Receivers, as.MinimumSignatureCount, as.HashedSecret, as.TimeLock)
+}
+
+// Unmarshal implements MarshalableUnlockCondition.Unmarshal
+func (as *MultiSignatureAtomicSwapCondition) Unmarshal(b []byte, f types.UnmarshalFunc) error {
+	return f(b, &as.Sender, &as.Receivers, &as.MinimumSignatureCount, &as.HashedSecret, &as.TimeLock)
+}
+
+// MultiSignatureAtomicSwapFulfillment implements the FulfillmentTypeMultiSignatureAtomicSwap (unlock) FulfillmentType.
+// It redeems a MultiSignatureAtomicSwapCondition when the secret is defined, in which case each
+// co-signer signs the transaction together with the secret, and refunds it otherwise.
+type MultiSignatureAtomicSwapFulfillment struct {
+	Pairs  []types.PublicKeySignaturePair `json:"pairs"`
+	Secret types.AtomicSwapSecret         `json:"secret"`
+}
+
+// SignatureObjects returns the objects, next to the given extra objects,
+// signed by the given public key as part of the fulfillment.
+func (as *MultiSignatureAtomicSwapFulfillment) SignatureObjects(extraObjects []interface{}, pk types.PublicKey) []interface{} {
+	objects := append(append([]interface{}(nil), extraObjects...), pk)
+	if as.Secret != (types.AtomicSwapSecret{}) {
+		objects = append(objects, as.Secret)
+	}
+	return objects
+}
+
+// Sign implements UnlockFulfillment.Sign,
+// adding the signature of the given key pair to the fulfillment.
+func (as *MultiSignatureAtomicSwapFulfillment) Sign(ctx types.FulfillmentSignContext) error {
+	keyPair, ok := ctx.Key.(types.KeyPair)
+	if !ok {
+		return errors.New("invalid key pair to sign a multisig atomic swap fulfillment")
+	}
+	if keyPair.PublicKey.Algorithm != types.SignatureAlgoEd25519 {
+		return types.ErrUnknownSignAlgorithmType
+	}
+	if len(keyPair.PrivateKey) != crypto.SecretKeySize {
+		return errors.New("invalid secret key size")
+	}
+	sigHash, err := ctx.Transaction.SignatureHash(as.SignatureObjects(ctx.ExtraObjects, keyPair.PublicKey)...)
+	if err != nil {
+		return err
+	}
+	var sk crypto.SecretKey
+	copy(sk[:], keyPair.PrivateKey)
+	signature := crypto.SignHash(sigHash, sk)
+	as.Pairs = append(as.Pairs, types.PublicKeySignaturePair{
+		PublicKey: keyPair.PublicKey,
+		Signature: signature[:],
+	})
+	return nil
+}
+
+// FulfillmentType implements UnlockFulfillment.FulfillmentType
+func (as *MultiSignatureAtomicSwapFulfillment) FulfillmentType() types.FulfillmentType {
+	return FulfillmentTypeMultiSignatureAtomicSwap
+}
+
+// IsStandardFulfillment implements UnlockFulfillment.IsStandardFulfillment
+func (as *MultiSignatureAtomicSwapFulfillment) IsStandardFulfillment(types.ValidationContext) error {
+	if len(as.Pairs) == 0 {
+		return errors.New("at least one pair must be provided")
+	}
+	for _, pair := range as.Pairs {
+		if pair.PublicKey.Algorithm != types.SignatureAlgoEd25519 {
+			return errors.New("unrecognized public key type in transaction")
+		}
+		if len(pair.PublicKey.Key) != crypto.PublicKeySize {
+			return errors.New("invalid public key size in transaction")
+		}
+		if len(pair.Signature) != crypto.SignatureSize {
+			return errors.New("invalid signature size in transaction")
+		}
+	}
+	return nil
+}
+
+// Equal implements UnlockFulfillment.Equal
+func (as *MultiSignatureAtomicSwapFulfillment) Equal(f types.UnlockFulfillment) bool {
+	oas, ok := f.(*MultiSignatureAtomicSwapFulfillment)
+	if !ok {
+		return false
+	}
+	if as.Secret != oas.Secret || len(as.Pairs) != len(oas.Pairs) {
+		return false
+	}
+	for idx, pair := range as.Pairs {
+		other := oas.Pairs[idx]
+		if pair.PublicKey.Algorithm != other.PublicKey.Algorithm ||
+			!bytes.Equal(pair.PublicKey.Key, other.PublicKey.Key) || !bytes.Equal(pair.Signature, other.Signature) {
+			return false
+		}
+	}
+	return true
+}
+
+// Marshal implements MarshalableUnlockFulfillment.Marshal
+func (as *MultiSignatureAtomicSwapFulfillment) Marshal(f types.MarshalFunc) ([]byte, error) {
+	return f(as.Pairs, as.Secret)
+}
+
+// Unmarshal implements MarshalableUnlockFulfillment.Unmarshal
+func (as *MultiSignatureAtomicSwapFulfillment) Unmarshal(b []byte, f types.UnmarshalFunc) error {
+	return f(b, &as.Pairs, &as.Secret)
+}
+
+// AtomicSwapSecret returns the secret revealed by the fulfillment,
+// which is nil for a fulfillment refunding a contract.
+func (as *MultiSignatureAtomicSwapFulfillment) AtomicSwapSecret() types.AtomicSwapSecret {
+	return as.Secret
+}
+
+// verifySignature verifies the signature of the given pair, signing the given transaction and objects.
+func verifySignature(pair types.PublicKeySignaturePair, txn types.Transaction, objects []interface{}) error {
+	if pair.PublicKey.Algorithm != types.SignatureAlgoEd25519 {
+		return types.ErrUnknownSignAlgorithmType
+	}
+	var (
+		pk  crypto.PublicKey
+		sig crypto.Signature
+	)
+	copy(pk[:], pair.PublicKey.Key)
+	copy(sig[:], pair.Signature)
+	if pk.IsNil() {
+		return crypto.ErrPublicNilKey
+	}
+	sigHash, err := txn.SignatureHash(objects...)
+	if err != nil {
+		return err
+	}
+	return crypto.VerifyHash(sigHash, pk, sig)
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+
+	cftypes "github.com/nbh-digital/goldchain/extensions/custodyfees/types"
+)
+
+// UnauthorizedCoinTransactionExceptionRule explains, in human language,
+// which coin transactions are exempted from the authorization check of the auth coin tx extension.
+const UnauthorizedCoinTransactionExceptionRule = "a regular (v0 or v1) coin transaction is only exempted from the authorization check " +
+	"if all its coin inputs and outputs belong to one and the same address, with at most two coin outputs " +
+	"(e.g. an unauthorized address sending coins back to itself)"
+
+// AuthCoinUnlockHashFilter returns true if the given unlock hash requires authorization
+// in order to send or receive coins. The nil, (multisig) atomic swap and custody fee unlock hashes do not.
+func AuthCoinUnlockHashFilter(uh types.UnlockHash) bool {
+	return uh.Type != types.UnlockTypeNil && uh.Type != types.UnlockTypeAtomicSwap &&
+		uh.Type != UnlockTypeMultiSignatureAtomicSwap && uh.Type != cftypes.UnlockTypeCustodyFee
+}
+
+// UnauthorizedCoinTransactionExceptionCallback is used by the auth coin tx extension
+// to define which coin transactions do not require their addresses to be authorized,
+// see UnauthorizedCoinTransactionExceptionRule.
+func UnauthorizedCoinTransactionExceptionCallback(tx modules.ConsensusTransaction, dedupAddresses []types.UnlockHash, _ types.TransactionValidationContext) (bool, error) {
+	return IsUnauthorizedCoinTransactionException(tx.Version, dedupAddresses, len(tx.CoinOutputs)), nil
+}
+
+// IsUnauthorizedCoinTransactionException returns true if a coin transaction of the given version,
+// using the given (deduplicated) addresses and amount of coin outputs, is exempted from the authorization check.
+func IsUnauthorizedCoinTransactionException(version types.TransactionVersion, dedupAddresses []types.UnlockHash, coinOutputs int) bool {
+	if version != types.TransactionVersionZero && version != types.TransactionVersionOne {
+		return false
+	}
+	return len(dedupAddresses) == 1 && coinOutputs <= 2
+}
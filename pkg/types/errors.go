@@ -0,0 +1,102 @@
+package types
+
+import (
+	"errors"
+)
+
+// ErrorCode is a stable, machine-readable code identifying why a transaction was refused
+// or a wallet operation failed, allowing clients to react programmatically rather than
+// having to interpret the (free-form) error message.
+type ErrorCode string
+
+// Custody fee error codes
+const (
+	ErrorCodeCustodyFeeMissing     ErrorCode = "CUSTODY_FEE_MISSING"
+	ErrorCodeCustodyFeeDuplicate   ErrorCode = "CUSTODY_FEE_DUPLICATE"
+	ErrorCodeCustodyFeeMismatch    ErrorCode = "CUSTODY_FEE_MISMATCH"
+	ErrorCodeComputationTimeTooOld ErrorCode = "COMPUTATION_TIME_TOO_OLD"
+	ErrorCodeCoinOutputSpent       ErrorCode = "COIN_OUTPUT_SPENT"
+)
+
+// Transaction validation error codes
+const (
+	ErrorCodeZeroCoinOutput         ErrorCode = "ZERO_COIN_OUTPUT"
+	ErrorCodeNonStandardCondition   ErrorCode = "NON_STANDARD_CONDITION"
+	ErrorCodeNonStandardFulfillment ErrorCode = "NON_STANDARD_FULFILLMENT"
+	ErrorCodeAddressUnauthorized    ErrorCode = "ADDRESS_UNAUTHORIZED"
+	ErrorCodeMissingSignatures      ErrorCode = "MISSING_SIGNATURES"
+)
+
+// Proof-of-reserve error codes
+const (
+	ErrorCodeInvalidReserveAttestation ErrorCode = "INVALID_RESERVE_ATTESTATION"
+)
+
+// Redemption error codes
+const (
+	ErrorCodeInvalidRedemption   ErrorCode = "INVALID_REDEMPTION"
+	ErrorCodeDuplicateRedemption ErrorCode = "DUPLICATE_REDEMPTION"
+)
+
+// Authorization expiry error codes
+const (
+	ErrorCodeInvalidAuthorizationExpiry ErrorCode = "INVALID_AUTHORIZATION_EXPIRY"
+	ErrorCodeAuthorizationExpired       ErrorCode = "AUTHORIZATION_EXPIRED"
+)
+
+// Wallet error codes
+const (
+	ErrorCodeWalletLocked                   ErrorCode = "WALLET_LOCKED"
+	ErrorCodeInsufficientBalance            ErrorCode = "INSUFFICIENT_BALANCE"
+	ErrorCodeInsufficientSpendableAfterFees ErrorCode = "INSUFFICIENT_SPENDABLE_AFTER_FEES"
+	ErrorCodeInsufficientBlockStakes        ErrorCode = "INSUFFICIENT_BLOCK_STAKES"
+	ErrorCodeIncompleteTransactions         ErrorCode = "INCOMPLETE_TRANSACTIONS"
+)
+
+// Generic error codes, used for errors that have no specific code,
+// based on the kind of client error they are.
+const (
+	ErrorCodeBadRequest   ErrorCode = "BAD_REQUEST"
+	ErrorCodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	ErrorCodeForbidden    ErrorCode = "FORBIDDEN"
+	ErrorCodeNotFound     ErrorCode = "NOT_FOUND"
+	ErrorCodeTimeout      ErrorCode = "TIMEOUT"
+)
+
+// CodedError wraps an error with a stable error code and optional machine-readable details,
+// such as the expected and provided custody fee. Details are encoded as strings,
+// using the same encoding as their values would have in JSON (e.g. currencies and timestamps).
+type CodedError struct {
+	Code    ErrorCode
+	Err     error
+	Details map[string]string
+}
+
+// NewCodedError creates a new coded error, wrapping the given error.
+func NewCodedError(code ErrorCode, err error, details map[string]string) CodedError {
+	return CodedError{
+		Code:    code,
+		Err:     err,
+		Details: details,
+	}
+}
+
+// Error implements error.Error
+func (err CodedError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the wrapped error,
+// such that the coded error can still be compared with the error it wraps.
+func (err CodedError) Unwrap() error {
+	return err.Err
+}
+
+// AsCodedError returns the first coded error found in the chain of the given error, if any.
+func AsCodedError(err error) (CodedError, bool) {
+	var cErr CodedError
+	if errors.As(err, &cErr) {
+		return cErr, true
+	}
+	return CodedError{}, false
+}